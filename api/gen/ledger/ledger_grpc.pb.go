@@ -0,0 +1,1651 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             v3.21.12
+// source: ledger.proto
+
+package ledgerpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	LedgerService_CreateAccount_FullMethodName              = "/ledger.LedgerService/CreateAccount"
+	LedgerService_Credit_FullMethodName                     = "/ledger.LedgerService/Credit"
+	LedgerService_Debit_FullMethodName                      = "/ledger.LedgerService/Debit"
+	LedgerService_Transfer_FullMethodName                   = "/ledger.LedgerService/Transfer"
+	LedgerService_PostTransaction_FullMethodName            = "/ledger.LedgerService/PostTransaction"
+	LedgerService_GetBalance_FullMethodName                 = "/ledger.LedgerService/GetBalance"
+	LedgerService_GetStatement_FullMethodName               = "/ledger.LedgerService/GetStatement"
+	LedgerService_GetAccountBalances_FullMethodName         = "/ledger.LedgerService/GetAccountBalances"
+	LedgerService_AuthorizePending_FullMethodName           = "/ledger.LedgerService/AuthorizePending"
+	LedgerService_CapturePending_FullMethodName             = "/ledger.LedgerService/CapturePending"
+	LedgerService_VoidPending_FullMethodName                = "/ledger.LedgerService/VoidPending"
+	LedgerService_GetPendingTransaction_FullMethodName      = "/ledger.LedgerService/GetPendingTransaction"
+	LedgerService_ReverseTransaction_FullMethodName         = "/ledger.LedgerService/ReverseTransaction"
+	LedgerService_GetTransaction_FullMethodName             = "/ledger.LedgerService/GetTransaction"
+	LedgerService_SetFXRate_FullMethodName                  = "/ledger.LedgerService/SetFXRate"
+	LedgerService_GetFXRate_FullMethodName                  = "/ledger.LedgerService/GetFXRate"
+	LedgerService_SetFXAccounts_FullMethodName              = "/ledger.LedgerService/SetFXAccounts"
+	LedgerService_FXTransfer_FullMethodName                 = "/ledger.LedgerService/FXTransfer"
+	LedgerService_FreezeAccount_FullMethodName              = "/ledger.LedgerService/FreezeAccount"
+	LedgerService_UnfreezeAccount_FullMethodName            = "/ledger.LedgerService/UnfreezeAccount"
+	LedgerService_BlockAccount_FullMethodName               = "/ledger.LedgerService/BlockAccount"
+	LedgerService_CloseAccount_FullMethodName               = "/ledger.LedgerService/CloseAccount"
+	LedgerService_GetAccountStatusHistory_FullMethodName    = "/ledger.LedgerService/GetAccountStatusHistory"
+	LedgerService_GetAccountTree_FullMethodName             = "/ledger.LedgerService/GetAccountTree"
+	LedgerService_CreatePeriod_FullMethodName               = "/ledger.LedgerService/CreatePeriod"
+	LedgerService_ListPeriods_FullMethodName                = "/ledger.LedgerService/ListPeriods"
+	LedgerService_GetPeriod_FullMethodName                  = "/ledger.LedgerService/GetPeriod"
+	LedgerService_SoftClosePeriod_FullMethodName            = "/ledger.LedgerService/SoftClosePeriod"
+	LedgerService_HardClosePeriod_FullMethodName            = "/ledger.LedgerService/HardClosePeriod"
+	LedgerService_SetRetainedEarningsAccount_FullMethodName = "/ledger.LedgerService/SetRetainedEarningsAccount"
+	LedgerService_Reconcile_FullMethodName                  = "/ledger.LedgerService/Reconcile"
+	LedgerService_ListAccounts_FullMethodName               = "/ledger.LedgerService/ListAccounts"
+	LedgerService_GetAccount_FullMethodName                 = "/ledger.LedgerService/GetAccount"
+	LedgerService_ValidateConsistency_FullMethodName        = "/ledger.LedgerService/ValidateConsistency"
+	LedgerService_StreamEvents_FullMethodName               = "/ledger.LedgerService/StreamEvents"
+	LedgerService_ImportBatch_FullMethodName                = "/ledger.LedgerService/ImportBatch"
+	LedgerService_GetBatch_FullMethodName                   = "/ledger.LedgerService/GetBatch"
+	LedgerService_ListBatchLines_FullMethodName             = "/ledger.LedgerService/ListBatchLines"
+	LedgerService_SetCreditLimit_FullMethodName             = "/ledger.LedgerService/SetCreditLimit"
+	LedgerService_GetCreditLimit_FullMethodName             = "/ledger.LedgerService/GetCreditLimit"
+	LedgerService_ListCreditLimits_FullMethodName           = "/ledger.LedgerService/ListCreditLimits"
+)
+
+// LedgerServiceClient is the client API for LedgerService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type LedgerServiceClient interface {
+	CreateAccount(ctx context.Context, in *CreateAccountRequest, opts ...grpc.CallOption) (*CreateAccountResponse, error)
+	Credit(ctx context.Context, in *CreditRequest, opts ...grpc.CallOption) (*CreditResponse, error)
+	Debit(ctx context.Context, in *DebitRequest, opts ...grpc.CallOption) (*DebitResponse, error)
+	Transfer(ctx context.Context, in *TransferRequest, opts ...grpc.CallOption) (*TransferResponse, error)
+	PostTransaction(ctx context.Context, in *PostTransactionRequest, opts ...grpc.CallOption) (*PostTransactionResponse, error)
+	GetBalance(ctx context.Context, in *GetBalanceRequest, opts ...grpc.CallOption) (*GetBalanceResponse, error)
+	GetStatement(ctx context.Context, in *GetStatementRequest, opts ...grpc.CallOption) (*GetStatementResponse, error)
+	GetAccountBalances(ctx context.Context, in *GetAccountBalancesRequest, opts ...grpc.CallOption) (*GetAccountBalancesResponse, error)
+	AuthorizePending(ctx context.Context, in *AuthorizePendingRequest, opts ...grpc.CallOption) (*PendingTransactionResponse, error)
+	CapturePending(ctx context.Context, in *CapturePendingRequest, opts ...grpc.CallOption) (*PendingTransactionResponse, error)
+	VoidPending(ctx context.Context, in *VoidPendingRequest, opts ...grpc.CallOption) (*PendingTransactionResponse, error)
+	GetPendingTransaction(ctx context.Context, in *GetPendingTransactionRequest, opts ...grpc.CallOption) (*PendingTransactionResponse, error)
+	ReverseTransaction(ctx context.Context, in *ReverseTransactionRequest, opts ...grpc.CallOption) (*ReversalResponse, error)
+	GetTransaction(ctx context.Context, in *GetTransactionRequest, opts ...grpc.CallOption) (*GetTransactionResponse, error)
+	SetFXRate(ctx context.Context, in *SetFXRateRequest, opts ...grpc.CallOption) (*FXRate, error)
+	GetFXRate(ctx context.Context, in *GetFXRateRequest, opts ...grpc.CallOption) (*FXRate, error)
+	SetFXAccounts(ctx context.Context, in *SetFXAccountsRequest, opts ...grpc.CallOption) (*SetFXAccountsResponse, error)
+	FXTransfer(ctx context.Context, in *FXTransferRequest, opts ...grpc.CallOption) (*FXTransferResponse, error)
+	FreezeAccount(ctx context.Context, in *AccountStatusRequest, opts ...grpc.CallOption) (*AccountStatusTransition, error)
+	UnfreezeAccount(ctx context.Context, in *AccountStatusRequest, opts ...grpc.CallOption) (*AccountStatusTransition, error)
+	BlockAccount(ctx context.Context, in *AccountStatusRequest, opts ...grpc.CallOption) (*AccountStatusTransition, error)
+	CloseAccount(ctx context.Context, in *AccountStatusRequest, opts ...grpc.CallOption) (*AccountStatusTransition, error)
+	GetAccountStatusHistory(ctx context.Context, in *GetAccountStatusHistoryRequest, opts ...grpc.CallOption) (*GetAccountStatusHistoryResponse, error)
+	GetAccountTree(ctx context.Context, in *GetAccountTreeRequest, opts ...grpc.CallOption) (*AccountNode, error)
+	CreatePeriod(ctx context.Context, in *CreatePeriodRequest, opts ...grpc.CallOption) (*AccountingPeriod, error)
+	ListPeriods(ctx context.Context, in *ListPeriodsRequest, opts ...grpc.CallOption) (*ListPeriodsResponse, error)
+	GetPeriod(ctx context.Context, in *GetPeriodRequest, opts ...grpc.CallOption) (*AccountingPeriod, error)
+	SoftClosePeriod(ctx context.Context, in *ClosePeriodRequest, opts ...grpc.CallOption) (*AccountingPeriod, error)
+	HardClosePeriod(ctx context.Context, in *ClosePeriodRequest, opts ...grpc.CallOption) (*AccountingPeriod, error)
+	SetRetainedEarningsAccount(ctx context.Context, in *SetRetainedEarningsAccountRequest, opts ...grpc.CallOption) (*SetRetainedEarningsAccountResponse, error)
+	Reconcile(ctx context.Context, in *ReconcileRequest, opts ...grpc.CallOption) (*ReconcileResponse, error)
+	ListAccounts(ctx context.Context, in *ListAccountsRequest, opts ...grpc.CallOption) (*ListAccountsResponse, error)
+	GetAccount(ctx context.Context, in *GetAccountRequest, opts ...grpc.CallOption) (*GetAccountResponse, error)
+	ValidateConsistency(ctx context.Context, in *ValidateConsistencyRequest, opts ...grpc.CallOption) (*ValidateConsistencyResponse, error)
+	StreamEvents(ctx context.Context, in *StreamEventsRequest, opts ...grpc.CallOption) (LedgerService_StreamEventsClient, error)
+	ImportBatch(ctx context.Context, opts ...grpc.CallOption) (LedgerService_ImportBatchClient, error)
+	GetBatch(ctx context.Context, in *GetBatchRequest, opts ...grpc.CallOption) (*PostingBatch, error)
+	ListBatchLines(ctx context.Context, in *ListBatchLinesRequest, opts ...grpc.CallOption) (*ListBatchLinesResponse, error)
+	SetCreditLimit(ctx context.Context, in *SetCreditLimitRequest, opts ...grpc.CallOption) (*CreditLimit, error)
+	GetCreditLimit(ctx context.Context, in *GetCreditLimitRequest, opts ...grpc.CallOption) (*CreditLimit, error)
+	ListCreditLimits(ctx context.Context, in *ListCreditLimitsRequest, opts ...grpc.CallOption) (*ListCreditLimitsResponse, error)
+}
+
+type ledgerServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewLedgerServiceClient(cc grpc.ClientConnInterface) LedgerServiceClient {
+	return &ledgerServiceClient{cc}
+}
+
+func (c *ledgerServiceClient) CreateAccount(ctx context.Context, in *CreateAccountRequest, opts ...grpc.CallOption) (*CreateAccountResponse, error) {
+	out := new(CreateAccountResponse)
+	err := c.cc.Invoke(ctx, LedgerService_CreateAccount_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) Credit(ctx context.Context, in *CreditRequest, opts ...grpc.CallOption) (*CreditResponse, error) {
+	out := new(CreditResponse)
+	err := c.cc.Invoke(ctx, LedgerService_Credit_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) Debit(ctx context.Context, in *DebitRequest, opts ...grpc.CallOption) (*DebitResponse, error) {
+	out := new(DebitResponse)
+	err := c.cc.Invoke(ctx, LedgerService_Debit_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) Transfer(ctx context.Context, in *TransferRequest, opts ...grpc.CallOption) (*TransferResponse, error) {
+	out := new(TransferResponse)
+	err := c.cc.Invoke(ctx, LedgerService_Transfer_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) PostTransaction(ctx context.Context, in *PostTransactionRequest, opts ...grpc.CallOption) (*PostTransactionResponse, error) {
+	out := new(PostTransactionResponse)
+	err := c.cc.Invoke(ctx, LedgerService_PostTransaction_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) GetBalance(ctx context.Context, in *GetBalanceRequest, opts ...grpc.CallOption) (*GetBalanceResponse, error) {
+	out := new(GetBalanceResponse)
+	err := c.cc.Invoke(ctx, LedgerService_GetBalance_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) GetStatement(ctx context.Context, in *GetStatementRequest, opts ...grpc.CallOption) (*GetStatementResponse, error) {
+	out := new(GetStatementResponse)
+	err := c.cc.Invoke(ctx, LedgerService_GetStatement_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) GetAccountBalances(ctx context.Context, in *GetAccountBalancesRequest, opts ...grpc.CallOption) (*GetAccountBalancesResponse, error) {
+	out := new(GetAccountBalancesResponse)
+	err := c.cc.Invoke(ctx, LedgerService_GetAccountBalances_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) AuthorizePending(ctx context.Context, in *AuthorizePendingRequest, opts ...grpc.CallOption) (*PendingTransactionResponse, error) {
+	out := new(PendingTransactionResponse)
+	err := c.cc.Invoke(ctx, LedgerService_AuthorizePending_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) CapturePending(ctx context.Context, in *CapturePendingRequest, opts ...grpc.CallOption) (*PendingTransactionResponse, error) {
+	out := new(PendingTransactionResponse)
+	err := c.cc.Invoke(ctx, LedgerService_CapturePending_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) VoidPending(ctx context.Context, in *VoidPendingRequest, opts ...grpc.CallOption) (*PendingTransactionResponse, error) {
+	out := new(PendingTransactionResponse)
+	err := c.cc.Invoke(ctx, LedgerService_VoidPending_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) GetPendingTransaction(ctx context.Context, in *GetPendingTransactionRequest, opts ...grpc.CallOption) (*PendingTransactionResponse, error) {
+	out := new(PendingTransactionResponse)
+	err := c.cc.Invoke(ctx, LedgerService_GetPendingTransaction_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) ReverseTransaction(ctx context.Context, in *ReverseTransactionRequest, opts ...grpc.CallOption) (*ReversalResponse, error) {
+	out := new(ReversalResponse)
+	err := c.cc.Invoke(ctx, LedgerService_ReverseTransaction_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) GetTransaction(ctx context.Context, in *GetTransactionRequest, opts ...grpc.CallOption) (*GetTransactionResponse, error) {
+	out := new(GetTransactionResponse)
+	err := c.cc.Invoke(ctx, LedgerService_GetTransaction_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) SetFXRate(ctx context.Context, in *SetFXRateRequest, opts ...grpc.CallOption) (*FXRate, error) {
+	out := new(FXRate)
+	err := c.cc.Invoke(ctx, LedgerService_SetFXRate_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) GetFXRate(ctx context.Context, in *GetFXRateRequest, opts ...grpc.CallOption) (*FXRate, error) {
+	out := new(FXRate)
+	err := c.cc.Invoke(ctx, LedgerService_GetFXRate_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) SetFXAccounts(ctx context.Context, in *SetFXAccountsRequest, opts ...grpc.CallOption) (*SetFXAccountsResponse, error) {
+	out := new(SetFXAccountsResponse)
+	err := c.cc.Invoke(ctx, LedgerService_SetFXAccounts_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) FXTransfer(ctx context.Context, in *FXTransferRequest, opts ...grpc.CallOption) (*FXTransferResponse, error) {
+	out := new(FXTransferResponse)
+	err := c.cc.Invoke(ctx, LedgerService_FXTransfer_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) FreezeAccount(ctx context.Context, in *AccountStatusRequest, opts ...grpc.CallOption) (*AccountStatusTransition, error) {
+	out := new(AccountStatusTransition)
+	err := c.cc.Invoke(ctx, LedgerService_FreezeAccount_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) UnfreezeAccount(ctx context.Context, in *AccountStatusRequest, opts ...grpc.CallOption) (*AccountStatusTransition, error) {
+	out := new(AccountStatusTransition)
+	err := c.cc.Invoke(ctx, LedgerService_UnfreezeAccount_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) BlockAccount(ctx context.Context, in *AccountStatusRequest, opts ...grpc.CallOption) (*AccountStatusTransition, error) {
+	out := new(AccountStatusTransition)
+	err := c.cc.Invoke(ctx, LedgerService_BlockAccount_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) CloseAccount(ctx context.Context, in *AccountStatusRequest, opts ...grpc.CallOption) (*AccountStatusTransition, error) {
+	out := new(AccountStatusTransition)
+	err := c.cc.Invoke(ctx, LedgerService_CloseAccount_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) GetAccountStatusHistory(ctx context.Context, in *GetAccountStatusHistoryRequest, opts ...grpc.CallOption) (*GetAccountStatusHistoryResponse, error) {
+	out := new(GetAccountStatusHistoryResponse)
+	err := c.cc.Invoke(ctx, LedgerService_GetAccountStatusHistory_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) GetAccountTree(ctx context.Context, in *GetAccountTreeRequest, opts ...grpc.CallOption) (*AccountNode, error) {
+	out := new(AccountNode)
+	err := c.cc.Invoke(ctx, LedgerService_GetAccountTree_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) CreatePeriod(ctx context.Context, in *CreatePeriodRequest, opts ...grpc.CallOption) (*AccountingPeriod, error) {
+	out := new(AccountingPeriod)
+	err := c.cc.Invoke(ctx, LedgerService_CreatePeriod_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) ListPeriods(ctx context.Context, in *ListPeriodsRequest, opts ...grpc.CallOption) (*ListPeriodsResponse, error) {
+	out := new(ListPeriodsResponse)
+	err := c.cc.Invoke(ctx, LedgerService_ListPeriods_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) GetPeriod(ctx context.Context, in *GetPeriodRequest, opts ...grpc.CallOption) (*AccountingPeriod, error) {
+	out := new(AccountingPeriod)
+	err := c.cc.Invoke(ctx, LedgerService_GetPeriod_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) SoftClosePeriod(ctx context.Context, in *ClosePeriodRequest, opts ...grpc.CallOption) (*AccountingPeriod, error) {
+	out := new(AccountingPeriod)
+	err := c.cc.Invoke(ctx, LedgerService_SoftClosePeriod_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) HardClosePeriod(ctx context.Context, in *ClosePeriodRequest, opts ...grpc.CallOption) (*AccountingPeriod, error) {
+	out := new(AccountingPeriod)
+	err := c.cc.Invoke(ctx, LedgerService_HardClosePeriod_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) SetRetainedEarningsAccount(ctx context.Context, in *SetRetainedEarningsAccountRequest, opts ...grpc.CallOption) (*SetRetainedEarningsAccountResponse, error) {
+	out := new(SetRetainedEarningsAccountResponse)
+	err := c.cc.Invoke(ctx, LedgerService_SetRetainedEarningsAccount_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) Reconcile(ctx context.Context, in *ReconcileRequest, opts ...grpc.CallOption) (*ReconcileResponse, error) {
+	out := new(ReconcileResponse)
+	err := c.cc.Invoke(ctx, LedgerService_Reconcile_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) ListAccounts(ctx context.Context, in *ListAccountsRequest, opts ...grpc.CallOption) (*ListAccountsResponse, error) {
+	out := new(ListAccountsResponse)
+	err := c.cc.Invoke(ctx, LedgerService_ListAccounts_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) GetAccount(ctx context.Context, in *GetAccountRequest, opts ...grpc.CallOption) (*GetAccountResponse, error) {
+	out := new(GetAccountResponse)
+	err := c.cc.Invoke(ctx, LedgerService_GetAccount_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) ValidateConsistency(ctx context.Context, in *ValidateConsistencyRequest, opts ...grpc.CallOption) (*ValidateConsistencyResponse, error) {
+	out := new(ValidateConsistencyResponse)
+	err := c.cc.Invoke(ctx, LedgerService_ValidateConsistency_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) StreamEvents(ctx context.Context, in *StreamEventsRequest, opts ...grpc.CallOption) (LedgerService_StreamEventsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &LedgerService_ServiceDesc.Streams[0], LedgerService_StreamEvents_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &ledgerServiceStreamEventsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type LedgerService_StreamEventsClient interface {
+	Recv() (*LedgerEvent, error)
+	grpc.ClientStream
+}
+
+type ledgerServiceStreamEventsClient struct {
+	grpc.ClientStream
+}
+
+func (x *ledgerServiceStreamEventsClient) Recv() (*LedgerEvent, error) {
+	m := new(LedgerEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *ledgerServiceClient) ImportBatch(ctx context.Context, opts ...grpc.CallOption) (LedgerService_ImportBatchClient, error) {
+	stream, err := c.cc.NewStream(ctx, &LedgerService_ServiceDesc.Streams[1], LedgerService_ImportBatch_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &ledgerServiceImportBatchClient{stream}
+	return x, nil
+}
+
+type LedgerService_ImportBatchClient interface {
+	Send(*ImportBatchRequest) error
+	CloseAndRecv() (*PostingBatch, error)
+	grpc.ClientStream
+}
+
+type ledgerServiceImportBatchClient struct {
+	grpc.ClientStream
+}
+
+func (x *ledgerServiceImportBatchClient) Send(m *ImportBatchRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *ledgerServiceImportBatchClient) CloseAndRecv() (*PostingBatch, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(PostingBatch)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *ledgerServiceClient) GetBatch(ctx context.Context, in *GetBatchRequest, opts ...grpc.CallOption) (*PostingBatch, error) {
+	out := new(PostingBatch)
+	err := c.cc.Invoke(ctx, LedgerService_GetBatch_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) ListBatchLines(ctx context.Context, in *ListBatchLinesRequest, opts ...grpc.CallOption) (*ListBatchLinesResponse, error) {
+	out := new(ListBatchLinesResponse)
+	err := c.cc.Invoke(ctx, LedgerService_ListBatchLines_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) SetCreditLimit(ctx context.Context, in *SetCreditLimitRequest, opts ...grpc.CallOption) (*CreditLimit, error) {
+	out := new(CreditLimit)
+	err := c.cc.Invoke(ctx, LedgerService_SetCreditLimit_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) GetCreditLimit(ctx context.Context, in *GetCreditLimitRequest, opts ...grpc.CallOption) (*CreditLimit, error) {
+	out := new(CreditLimit)
+	err := c.cc.Invoke(ctx, LedgerService_GetCreditLimit_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) ListCreditLimits(ctx context.Context, in *ListCreditLimitsRequest, opts ...grpc.CallOption) (*ListCreditLimitsResponse, error) {
+	out := new(ListCreditLimitsResponse)
+	err := c.cc.Invoke(ctx, LedgerService_ListCreditLimits_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// LedgerServiceServer is the server API for LedgerService service.
+// All implementations must embed UnimplementedLedgerServiceServer
+// for forward compatibility
+type LedgerServiceServer interface {
+	CreateAccount(context.Context, *CreateAccountRequest) (*CreateAccountResponse, error)
+	Credit(context.Context, *CreditRequest) (*CreditResponse, error)
+	Debit(context.Context, *DebitRequest) (*DebitResponse, error)
+	Transfer(context.Context, *TransferRequest) (*TransferResponse, error)
+	PostTransaction(context.Context, *PostTransactionRequest) (*PostTransactionResponse, error)
+	GetBalance(context.Context, *GetBalanceRequest) (*GetBalanceResponse, error)
+	GetStatement(context.Context, *GetStatementRequest) (*GetStatementResponse, error)
+	GetAccountBalances(context.Context, *GetAccountBalancesRequest) (*GetAccountBalancesResponse, error)
+	AuthorizePending(context.Context, *AuthorizePendingRequest) (*PendingTransactionResponse, error)
+	CapturePending(context.Context, *CapturePendingRequest) (*PendingTransactionResponse, error)
+	VoidPending(context.Context, *VoidPendingRequest) (*PendingTransactionResponse, error)
+	GetPendingTransaction(context.Context, *GetPendingTransactionRequest) (*PendingTransactionResponse, error)
+	ReverseTransaction(context.Context, *ReverseTransactionRequest) (*ReversalResponse, error)
+	GetTransaction(context.Context, *GetTransactionRequest) (*GetTransactionResponse, error)
+	SetFXRate(context.Context, *SetFXRateRequest) (*FXRate, error)
+	GetFXRate(context.Context, *GetFXRateRequest) (*FXRate, error)
+	SetFXAccounts(context.Context, *SetFXAccountsRequest) (*SetFXAccountsResponse, error)
+	FXTransfer(context.Context, *FXTransferRequest) (*FXTransferResponse, error)
+	FreezeAccount(context.Context, *AccountStatusRequest) (*AccountStatusTransition, error)
+	UnfreezeAccount(context.Context, *AccountStatusRequest) (*AccountStatusTransition, error)
+	BlockAccount(context.Context, *AccountStatusRequest) (*AccountStatusTransition, error)
+	CloseAccount(context.Context, *AccountStatusRequest) (*AccountStatusTransition, error)
+	GetAccountStatusHistory(context.Context, *GetAccountStatusHistoryRequest) (*GetAccountStatusHistoryResponse, error)
+	GetAccountTree(context.Context, *GetAccountTreeRequest) (*AccountNode, error)
+	CreatePeriod(context.Context, *CreatePeriodRequest) (*AccountingPeriod, error)
+	ListPeriods(context.Context, *ListPeriodsRequest) (*ListPeriodsResponse, error)
+	GetPeriod(context.Context, *GetPeriodRequest) (*AccountingPeriod, error)
+	SoftClosePeriod(context.Context, *ClosePeriodRequest) (*AccountingPeriod, error)
+	HardClosePeriod(context.Context, *ClosePeriodRequest) (*AccountingPeriod, error)
+	SetRetainedEarningsAccount(context.Context, *SetRetainedEarningsAccountRequest) (*SetRetainedEarningsAccountResponse, error)
+	Reconcile(context.Context, *ReconcileRequest) (*ReconcileResponse, error)
+	ListAccounts(context.Context, *ListAccountsRequest) (*ListAccountsResponse, error)
+	GetAccount(context.Context, *GetAccountRequest) (*GetAccountResponse, error)
+	ValidateConsistency(context.Context, *ValidateConsistencyRequest) (*ValidateConsistencyResponse, error)
+	StreamEvents(*StreamEventsRequest, LedgerService_StreamEventsServer) error
+	ImportBatch(LedgerService_ImportBatchServer) error
+	GetBatch(context.Context, *GetBatchRequest) (*PostingBatch, error)
+	ListBatchLines(context.Context, *ListBatchLinesRequest) (*ListBatchLinesResponse, error)
+	SetCreditLimit(context.Context, *SetCreditLimitRequest) (*CreditLimit, error)
+	GetCreditLimit(context.Context, *GetCreditLimitRequest) (*CreditLimit, error)
+	ListCreditLimits(context.Context, *ListCreditLimitsRequest) (*ListCreditLimitsResponse, error)
+	mustEmbedUnimplementedLedgerServiceServer()
+}
+
+// UnimplementedLedgerServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedLedgerServiceServer struct {
+}
+
+func (UnimplementedLedgerServiceServer) CreateAccount(context.Context, *CreateAccountRequest) (*CreateAccountResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateAccount not implemented")
+}
+func (UnimplementedLedgerServiceServer) Credit(context.Context, *CreditRequest) (*CreditResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Credit not implemented")
+}
+func (UnimplementedLedgerServiceServer) Debit(context.Context, *DebitRequest) (*DebitResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Debit not implemented")
+}
+func (UnimplementedLedgerServiceServer) Transfer(context.Context, *TransferRequest) (*TransferResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Transfer not implemented")
+}
+func (UnimplementedLedgerServiceServer) PostTransaction(context.Context, *PostTransactionRequest) (*PostTransactionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PostTransaction not implemented")
+}
+func (UnimplementedLedgerServiceServer) GetBalance(context.Context, *GetBalanceRequest) (*GetBalanceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetBalance not implemented")
+}
+func (UnimplementedLedgerServiceServer) GetStatement(context.Context, *GetStatementRequest) (*GetStatementResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetStatement not implemented")
+}
+func (UnimplementedLedgerServiceServer) GetAccountBalances(context.Context, *GetAccountBalancesRequest) (*GetAccountBalancesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAccountBalances not implemented")
+}
+func (UnimplementedLedgerServiceServer) AuthorizePending(context.Context, *AuthorizePendingRequest) (*PendingTransactionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AuthorizePending not implemented")
+}
+func (UnimplementedLedgerServiceServer) CapturePending(context.Context, *CapturePendingRequest) (*PendingTransactionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CapturePending not implemented")
+}
+func (UnimplementedLedgerServiceServer) VoidPending(context.Context, *VoidPendingRequest) (*PendingTransactionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method VoidPending not implemented")
+}
+func (UnimplementedLedgerServiceServer) GetPendingTransaction(context.Context, *GetPendingTransactionRequest) (*PendingTransactionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPendingTransaction not implemented")
+}
+func (UnimplementedLedgerServiceServer) ReverseTransaction(context.Context, *ReverseTransactionRequest) (*ReversalResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReverseTransaction not implemented")
+}
+func (UnimplementedLedgerServiceServer) GetTransaction(context.Context, *GetTransactionRequest) (*GetTransactionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetTransaction not implemented")
+}
+func (UnimplementedLedgerServiceServer) SetFXRate(context.Context, *SetFXRateRequest) (*FXRate, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetFXRate not implemented")
+}
+func (UnimplementedLedgerServiceServer) GetFXRate(context.Context, *GetFXRateRequest) (*FXRate, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetFXRate not implemented")
+}
+func (UnimplementedLedgerServiceServer) SetFXAccounts(context.Context, *SetFXAccountsRequest) (*SetFXAccountsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetFXAccounts not implemented")
+}
+func (UnimplementedLedgerServiceServer) FXTransfer(context.Context, *FXTransferRequest) (*FXTransferResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method FXTransfer not implemented")
+}
+func (UnimplementedLedgerServiceServer) FreezeAccount(context.Context, *AccountStatusRequest) (*AccountStatusTransition, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method FreezeAccount not implemented")
+}
+func (UnimplementedLedgerServiceServer) UnfreezeAccount(context.Context, *AccountStatusRequest) (*AccountStatusTransition, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UnfreezeAccount not implemented")
+}
+func (UnimplementedLedgerServiceServer) BlockAccount(context.Context, *AccountStatusRequest) (*AccountStatusTransition, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BlockAccount not implemented")
+}
+func (UnimplementedLedgerServiceServer) CloseAccount(context.Context, *AccountStatusRequest) (*AccountStatusTransition, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CloseAccount not implemented")
+}
+func (UnimplementedLedgerServiceServer) GetAccountStatusHistory(context.Context, *GetAccountStatusHistoryRequest) (*GetAccountStatusHistoryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAccountStatusHistory not implemented")
+}
+func (UnimplementedLedgerServiceServer) GetAccountTree(context.Context, *GetAccountTreeRequest) (*AccountNode, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAccountTree not implemented")
+}
+func (UnimplementedLedgerServiceServer) CreatePeriod(context.Context, *CreatePeriodRequest) (*AccountingPeriod, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreatePeriod not implemented")
+}
+func (UnimplementedLedgerServiceServer) ListPeriods(context.Context, *ListPeriodsRequest) (*ListPeriodsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListPeriods not implemented")
+}
+func (UnimplementedLedgerServiceServer) GetPeriod(context.Context, *GetPeriodRequest) (*AccountingPeriod, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPeriod not implemented")
+}
+func (UnimplementedLedgerServiceServer) SoftClosePeriod(context.Context, *ClosePeriodRequest) (*AccountingPeriod, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SoftClosePeriod not implemented")
+}
+func (UnimplementedLedgerServiceServer) HardClosePeriod(context.Context, *ClosePeriodRequest) (*AccountingPeriod, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method HardClosePeriod not implemented")
+}
+func (UnimplementedLedgerServiceServer) SetRetainedEarningsAccount(context.Context, *SetRetainedEarningsAccountRequest) (*SetRetainedEarningsAccountResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetRetainedEarningsAccount not implemented")
+}
+func (UnimplementedLedgerServiceServer) Reconcile(context.Context, *ReconcileRequest) (*ReconcileResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Reconcile not implemented")
+}
+func (UnimplementedLedgerServiceServer) ListAccounts(context.Context, *ListAccountsRequest) (*ListAccountsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListAccounts not implemented")
+}
+func (UnimplementedLedgerServiceServer) GetAccount(context.Context, *GetAccountRequest) (*GetAccountResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAccount not implemented")
+}
+func (UnimplementedLedgerServiceServer) ValidateConsistency(context.Context, *ValidateConsistencyRequest) (*ValidateConsistencyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ValidateConsistency not implemented")
+}
+func (UnimplementedLedgerServiceServer) StreamEvents(*StreamEventsRequest, LedgerService_StreamEventsServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamEvents not implemented")
+}
+func (UnimplementedLedgerServiceServer) ImportBatch(LedgerService_ImportBatchServer) error {
+	return status.Errorf(codes.Unimplemented, "method ImportBatch not implemented")
+}
+func (UnimplementedLedgerServiceServer) GetBatch(context.Context, *GetBatchRequest) (*PostingBatch, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetBatch not implemented")
+}
+func (UnimplementedLedgerServiceServer) ListBatchLines(context.Context, *ListBatchLinesRequest) (*ListBatchLinesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListBatchLines not implemented")
+}
+func (UnimplementedLedgerServiceServer) SetCreditLimit(context.Context, *SetCreditLimitRequest) (*CreditLimit, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetCreditLimit not implemented")
+}
+func (UnimplementedLedgerServiceServer) GetCreditLimit(context.Context, *GetCreditLimitRequest) (*CreditLimit, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetCreditLimit not implemented")
+}
+func (UnimplementedLedgerServiceServer) ListCreditLimits(context.Context, *ListCreditLimitsRequest) (*ListCreditLimitsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListCreditLimits not implemented")
+}
+func (UnimplementedLedgerServiceServer) mustEmbedUnimplementedLedgerServiceServer() {}
+
+// UnsafeLedgerServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to LedgerServiceServer will
+// result in compilation errors.
+type UnsafeLedgerServiceServer interface {
+	mustEmbedUnimplementedLedgerServiceServer()
+}
+
+func RegisterLedgerServiceServer(s grpc.ServiceRegistrar, srv LedgerServiceServer) {
+	s.RegisterService(&LedgerService_ServiceDesc, srv)
+}
+
+func _LedgerService_CreateAccount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateAccountRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).CreateAccount(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_CreateAccount_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).CreateAccount(ctx, req.(*CreateAccountRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_Credit_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreditRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).Credit(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_Credit_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).Credit(ctx, req.(*CreditRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_Debit_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DebitRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).Debit(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_Debit_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).Debit(ctx, req.(*DebitRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_Transfer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TransferRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).Transfer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_Transfer_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).Transfer(ctx, req.(*TransferRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_PostTransaction_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PostTransactionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).PostTransaction(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_PostTransaction_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).PostTransaction(ctx, req.(*PostTransactionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_GetBalance_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetBalanceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).GetBalance(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_GetBalance_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).GetBalance(ctx, req.(*GetBalanceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_GetStatement_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetStatementRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).GetStatement(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_GetStatement_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).GetStatement(ctx, req.(*GetStatementRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_GetAccountBalances_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAccountBalancesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).GetAccountBalances(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_GetAccountBalances_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).GetAccountBalances(ctx, req.(*GetAccountBalancesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_AuthorizePending_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AuthorizePendingRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).AuthorizePending(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_AuthorizePending_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).AuthorizePending(ctx, req.(*AuthorizePendingRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_CapturePending_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CapturePendingRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).CapturePending(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_CapturePending_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).CapturePending(ctx, req.(*CapturePendingRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_VoidPending_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VoidPendingRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).VoidPending(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_VoidPending_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).VoidPending(ctx, req.(*VoidPendingRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_GetPendingTransaction_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPendingTransactionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).GetPendingTransaction(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_GetPendingTransaction_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).GetPendingTransaction(ctx, req.(*GetPendingTransactionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_ReverseTransaction_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReverseTransactionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).ReverseTransaction(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_ReverseTransaction_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).ReverseTransaction(ctx, req.(*ReverseTransactionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_GetTransaction_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTransactionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).GetTransaction(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_GetTransaction_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).GetTransaction(ctx, req.(*GetTransactionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_SetFXRate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetFXRateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).SetFXRate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_SetFXRate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).SetFXRate(ctx, req.(*SetFXRateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_GetFXRate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetFXRateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).GetFXRate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_GetFXRate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).GetFXRate(ctx, req.(*GetFXRateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_SetFXAccounts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetFXAccountsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).SetFXAccounts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_SetFXAccounts_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).SetFXAccounts(ctx, req.(*SetFXAccountsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_FXTransfer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FXTransferRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).FXTransfer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_FXTransfer_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).FXTransfer(ctx, req.(*FXTransferRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_FreezeAccount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AccountStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).FreezeAccount(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_FreezeAccount_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).FreezeAccount(ctx, req.(*AccountStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_UnfreezeAccount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AccountStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).UnfreezeAccount(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_UnfreezeAccount_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).UnfreezeAccount(ctx, req.(*AccountStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_BlockAccount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AccountStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).BlockAccount(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_BlockAccount_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).BlockAccount(ctx, req.(*AccountStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_CloseAccount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AccountStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).CloseAccount(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_CloseAccount_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).CloseAccount(ctx, req.(*AccountStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_GetAccountStatusHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAccountStatusHistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).GetAccountStatusHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_GetAccountStatusHistory_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).GetAccountStatusHistory(ctx, req.(*GetAccountStatusHistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_GetAccountTree_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAccountTreeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).GetAccountTree(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_GetAccountTree_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).GetAccountTree(ctx, req.(*GetAccountTreeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_CreatePeriod_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreatePeriodRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).CreatePeriod(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_CreatePeriod_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).CreatePeriod(ctx, req.(*CreatePeriodRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_ListPeriods_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListPeriodsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).ListPeriods(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_ListPeriods_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).ListPeriods(ctx, req.(*ListPeriodsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_GetPeriod_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPeriodRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).GetPeriod(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_GetPeriod_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).GetPeriod(ctx, req.(*GetPeriodRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_SoftClosePeriod_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ClosePeriodRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).SoftClosePeriod(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_SoftClosePeriod_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).SoftClosePeriod(ctx, req.(*ClosePeriodRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_HardClosePeriod_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ClosePeriodRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).HardClosePeriod(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_HardClosePeriod_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).HardClosePeriod(ctx, req.(*ClosePeriodRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_SetRetainedEarningsAccount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetRetainedEarningsAccountRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).SetRetainedEarningsAccount(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_SetRetainedEarningsAccount_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).SetRetainedEarningsAccount(ctx, req.(*SetRetainedEarningsAccountRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_Reconcile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReconcileRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).Reconcile(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_Reconcile_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).Reconcile(ctx, req.(*ReconcileRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_ListAccounts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListAccountsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).ListAccounts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_ListAccounts_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).ListAccounts(ctx, req.(*ListAccountsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_GetAccount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAccountRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).GetAccount(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_GetAccount_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).GetAccount(ctx, req.(*GetAccountRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_ValidateConsistency_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ValidateConsistencyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).ValidateConsistency(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_ValidateConsistency_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).ValidateConsistency(ctx, req.(*ValidateConsistencyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_StreamEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamEventsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(LedgerServiceServer).StreamEvents(m, &ledgerServiceStreamEventsServer{stream})
+}
+
+type LedgerService_StreamEventsServer interface {
+	Send(*LedgerEvent) error
+	grpc.ServerStream
+}
+
+type ledgerServiceStreamEventsServer struct {
+	grpc.ServerStream
+}
+
+func (x *ledgerServiceStreamEventsServer) Send(m *LedgerEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _LedgerService_ImportBatch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(LedgerServiceServer).ImportBatch(&ledgerServiceImportBatchServer{stream})
+}
+
+type LedgerService_ImportBatchServer interface {
+	SendAndClose(*PostingBatch) error
+	Recv() (*ImportBatchRequest, error)
+	grpc.ServerStream
+}
+
+type ledgerServiceImportBatchServer struct {
+	grpc.ServerStream
+}
+
+func (x *ledgerServiceImportBatchServer) SendAndClose(m *PostingBatch) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *ledgerServiceImportBatchServer) Recv() (*ImportBatchRequest, error) {
+	m := new(ImportBatchRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _LedgerService_GetBatch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetBatchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).GetBatch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_GetBatch_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).GetBatch(ctx, req.(*GetBatchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_ListBatchLines_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListBatchLinesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).ListBatchLines(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_ListBatchLines_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).ListBatchLines(ctx, req.(*ListBatchLinesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_SetCreditLimit_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetCreditLimitRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).SetCreditLimit(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_SetCreditLimit_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).SetCreditLimit(ctx, req.(*SetCreditLimitRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_GetCreditLimit_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetCreditLimitRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).GetCreditLimit(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_GetCreditLimit_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).GetCreditLimit(ctx, req.(*GetCreditLimitRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_ListCreditLimits_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListCreditLimitsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).ListCreditLimits(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_ListCreditLimits_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).ListCreditLimits(ctx, req.(*ListCreditLimitsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// LedgerService_ServiceDesc is the grpc.ServiceDesc for LedgerService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var LedgerService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "ledger.LedgerService",
+	HandlerType: (*LedgerServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreateAccount",
+			Handler:    _LedgerService_CreateAccount_Handler,
+		},
+		{
+			MethodName: "Credit",
+			Handler:    _LedgerService_Credit_Handler,
+		},
+		{
+			MethodName: "Debit",
+			Handler:    _LedgerService_Debit_Handler,
+		},
+		{
+			MethodName: "Transfer",
+			Handler:    _LedgerService_Transfer_Handler,
+		},
+		{
+			MethodName: "PostTransaction",
+			Handler:    _LedgerService_PostTransaction_Handler,
+		},
+		{
+			MethodName: "GetBalance",
+			Handler:    _LedgerService_GetBalance_Handler,
+		},
+		{
+			MethodName: "GetStatement",
+			Handler:    _LedgerService_GetStatement_Handler,
+		},
+		{
+			MethodName: "GetAccountBalances",
+			Handler:    _LedgerService_GetAccountBalances_Handler,
+		},
+		{
+			MethodName: "AuthorizePending",
+			Handler:    _LedgerService_AuthorizePending_Handler,
+		},
+		{
+			MethodName: "CapturePending",
+			Handler:    _LedgerService_CapturePending_Handler,
+		},
+		{
+			MethodName: "VoidPending",
+			Handler:    _LedgerService_VoidPending_Handler,
+		},
+		{
+			MethodName: "GetPendingTransaction",
+			Handler:    _LedgerService_GetPendingTransaction_Handler,
+		},
+		{
+			MethodName: "ReverseTransaction",
+			Handler:    _LedgerService_ReverseTransaction_Handler,
+		},
+		{
+			MethodName: "GetTransaction",
+			Handler:    _LedgerService_GetTransaction_Handler,
+		},
+		{
+			MethodName: "SetFXRate",
+			Handler:    _LedgerService_SetFXRate_Handler,
+		},
+		{
+			MethodName: "GetFXRate",
+			Handler:    _LedgerService_GetFXRate_Handler,
+		},
+		{
+			MethodName: "SetFXAccounts",
+			Handler:    _LedgerService_SetFXAccounts_Handler,
+		},
+		{
+			MethodName: "FXTransfer",
+			Handler:    _LedgerService_FXTransfer_Handler,
+		},
+		{
+			MethodName: "FreezeAccount",
+			Handler:    _LedgerService_FreezeAccount_Handler,
+		},
+		{
+			MethodName: "UnfreezeAccount",
+			Handler:    _LedgerService_UnfreezeAccount_Handler,
+		},
+		{
+			MethodName: "BlockAccount",
+			Handler:    _LedgerService_BlockAccount_Handler,
+		},
+		{
+			MethodName: "CloseAccount",
+			Handler:    _LedgerService_CloseAccount_Handler,
+		},
+		{
+			MethodName: "GetAccountStatusHistory",
+			Handler:    _LedgerService_GetAccountStatusHistory_Handler,
+		},
+		{
+			MethodName: "GetAccountTree",
+			Handler:    _LedgerService_GetAccountTree_Handler,
+		},
+		{
+			MethodName: "CreatePeriod",
+			Handler:    _LedgerService_CreatePeriod_Handler,
+		},
+		{
+			MethodName: "ListPeriods",
+			Handler:    _LedgerService_ListPeriods_Handler,
+		},
+		{
+			MethodName: "GetPeriod",
+			Handler:    _LedgerService_GetPeriod_Handler,
+		},
+		{
+			MethodName: "SoftClosePeriod",
+			Handler:    _LedgerService_SoftClosePeriod_Handler,
+		},
+		{
+			MethodName: "HardClosePeriod",
+			Handler:    _LedgerService_HardClosePeriod_Handler,
+		},
+		{
+			MethodName: "SetRetainedEarningsAccount",
+			Handler:    _LedgerService_SetRetainedEarningsAccount_Handler,
+		},
+		{
+			MethodName: "Reconcile",
+			Handler:    _LedgerService_Reconcile_Handler,
+		},
+		{
+			MethodName: "ListAccounts",
+			Handler:    _LedgerService_ListAccounts_Handler,
+		},
+		{
+			MethodName: "GetAccount",
+			Handler:    _LedgerService_GetAccount_Handler,
+		},
+		{
+			MethodName: "ValidateConsistency",
+			Handler:    _LedgerService_ValidateConsistency_Handler,
+		},
+		{
+			MethodName: "GetBatch",
+			Handler:    _LedgerService_GetBatch_Handler,
+		},
+		{
+			MethodName: "ListBatchLines",
+			Handler:    _LedgerService_ListBatchLines_Handler,
+		},
+		{
+			MethodName: "SetCreditLimit",
+			Handler:    _LedgerService_SetCreditLimit_Handler,
+		},
+		{
+			MethodName: "GetCreditLimit",
+			Handler:    _LedgerService_GetCreditLimit_Handler,
+		},
+		{
+			MethodName: "ListCreditLimits",
+			Handler:    _LedgerService_ListCreditLimits_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamEvents",
+			Handler:       _LedgerService_StreamEvents_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "ImportBatch",
+			Handler:       _LedgerService_ImportBatch_Handler,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "ledger.proto",
+}
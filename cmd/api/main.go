@@ -4,6 +4,7 @@ import (
     "context"
     "crypto/tls"
     "errors"
+    "log"
     "log/slog"
     "net"
     "net/http"
@@ -19,8 +20,16 @@ import (
 
     "github.com/example/pci-infra/internal/api"
     "github.com/example/pci-infra/internal/auth"
+    "github.com/example/pci-infra/internal/crypto"
+    "github.com/example/pci-infra/internal/disputes"
+    "github.com/example/pci-infra/internal/fees"
     "github.com/example/pci-infra/internal/ledger"
+    "github.com/example/pci-infra/internal/money"
+    "github.com/example/pci-infra/internal/payouts"
+    "github.com/example/pci-infra/internal/reconciliation"
+    "github.com/example/pci-infra/internal/reporting"
     "github.com/example/pci-infra/internal/security"
+    "github.com/example/pci-infra/internal/webhooks"
     "github.com/example/pci-infra/pkg/audit"
 )
 
@@ -73,6 +82,11 @@ func main() {
 
     pl := ledger.NewPostgresLedger(pool)
     ls := ledger.NewLedgerService(pl)
+    ls.PendingTTL = getenvDuration("LEDGER_PENDING_TTL", ledger.DefaultPendingTTL)
+    if ls.MaxFXSpread, err = money.ParseRate(getenv("LEDGER_MAX_FX_SPREAD", ledger.DefaultMaxFXSpread.String())); err != nil {
+        logger.Error("invalid LEDGER_MAX_FX_SPREAD", "error", err)
+        os.Exit(1)
+    }
 
     auditor := audit.NewChainLogger()
 
@@ -83,16 +97,48 @@ func main() {
         RefillRate: float64(getenvInt("API_RATE_LIMIT_REFILL_PER_SEC", 10)),
     }
 
+    feeService := fees.NewService(pool, ls)
+
+    // Dispute evidence and webhook signing secrets are envelope-encrypted
+    // under a KMS master key
+    kms, err := crypto.NewFileBasedKMS(crypto.FileBasedKMSConfig{KeyStorePath: getenv("KMS_KEY_STORE", "/tmp/vault-keys")})
+    if err != nil {
+        logger.Error("failed to initialize KMS", "error", err)
+        os.Exit(1)
+    }
+    masterKeyID, err := kms.GetKeyID(context.Background())
+    if err != nil {
+        logger.Error("failed to get master key ID", "error", err)
+        os.Exit(1)
+    }
+    encryptor := crypto.NewAEADEncryptor(kms)
+    evidenceStore := disputes.NewEvidenceStore(pool, encryptor, masterKeyID)
+
+    reserveRate, err := money.ParseRate(getenv("DISPUTE_RESERVE_RATE", "0.10"))
+    if err != nil {
+        logger.Error("invalid DISPUTE_RESERVE_RATE", "error", err)
+        os.Exit(1)
+    }
+    disputesService := disputes.NewDisputesService(pool, ls, feeService, reserveRate)
+
     router, err := api.NewRouter(api.Dependencies{
-        Logger:       logger,
-        OAuth:        oauthServer,
-        JWTValidator: jwtValidator,
-        LedgerReader: ls,
-        LedgerWriter: ls,
-        Auditor:      auditor,
-        RateLimiter:  rateLimiter,
-        IPAllowlist:  allowlist,
-        MaxBodyBytes: maxBody,
+        Logger:         logger,
+        OAuth:          oauthServer,
+        JWTValidator:   jwtValidator,
+        LedgerReader:   ls,
+        LedgerWriter:   ls,
+        Reports:        reporting.NewReporter(pl),
+        Reconciliation: reconciliation.NewReconciler(pl),
+        Webhooks:       webhooks.NewService(pool, encryptor, masterKeyID),
+        Payouts:        payouts.NewService(pool, ls, feeService),
+        Fees:           feeService,
+        DisputesService: disputesService,
+        DisputeEvidence: evidenceStore,
+        Auditor:        auditor,
+        Idempotency:    &api.PostgresIdempotencyStore{Pool: pool},
+        RateLimiter:    rateLimiter,
+        IPAllowlist:    allowlist,
+        MaxBodyBytes:   maxBody,
     })
     if err != nil {
         logger.Error("failed to build router", "error", err)
@@ -128,8 +174,22 @@ func main() {
     sigCh := make(chan os.Signal, 1)
     signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
 
+    // Accept or escalate disputes whose network response window has closed,
+    // and release dispute holds as they expire
+    schedulerCtx, stopScheduler := context.WithCancel(context.Background())
+    defer stopScheduler()
+    go (&disputes.DeadlineScheduler{
+        Service:  disputesService,
+        Interval: getenvDuration("DISPUTE_DEADLINE_INTERVAL", disputes.DefaultDeadlineInterval),
+    }).Run(schedulerCtx)
+    go (&disputes.HoldSweeper{
+        Service:  disputesService,
+        Interval: getenvDuration("DISPUTE_HOLD_SWEEP_INTERVAL", disputes.DefaultHoldSweepInterval),
+    }).Run(schedulerCtx)
+
     go func() {
         <-sigCh
+        stopScheduler()
         ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
         defer cancel()
         _ = srv.Shutdown(ctx)
@@ -162,3 +222,14 @@ func getenvInt(key string, def int) int {
     return i
 }
 
+func getenvDuration(key string, def time.Duration) time.Duration {
+    v := os.Getenv(key)
+    if v == "" {
+        return def
+    }
+    d, err := time.ParseDuration(v)
+    if err != nil || d <= 0 {
+        log.Fatalf("Invalid %s %q", key, v)
+    }
+    return d
+}
@@ -1,7 +1,200 @@
 package main
 
-import "log"
+import (
+	"context"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+
+	ledgerpb "github.com/example/pci-infra/api/gen/ledger"
+	"github.com/example/pci-infra/internal/config"
+	"github.com/example/pci-infra/internal/crypto"
+	"github.com/example/pci-infra/internal/fees"
+	"github.com/example/pci-infra/internal/ledger"
+	"github.com/example/pci-infra/internal/money"
+	"github.com/example/pci-infra/internal/outbox"
+	"github.com/example/pci-infra/internal/payouts"
+	"github.com/example/pci-infra/internal/security"
+	"github.com/example/pci-infra/internal/webhooks"
+)
+
+// shutdownTimeout bounds how long in-flight RPCs may run after a shutdown signal
+const shutdownTimeout = 30 * time.Second
+
+// pendingExpiryInterval is how often expired pending transactions are marked expired
+const pendingExpiryInterval = time.Minute
 
 func main() {
-	log.Println("Ledger Service Placeholder")
+	cfg, err := config.LoadFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	log.Printf("Starting ledger in %s environment", cfg.Environment)
+
+	tlsConfig := security.TLSConfig{
+		CertFile:          os.Getenv("LEDGER_TLS_CERT"),
+		KeyFile:           os.Getenv("LEDGER_TLS_KEY"),
+		CAFile:            os.Getenv("LEDGER_TLS_CA"),
+		RequireClientAuth: true,
+	}
+	if err := security.VerifyTLSFiles(tlsConfig.CertFile, tlsConfig.KeyFile, tlsConfig.CAFile); err != nil {
+		log.Fatalf("TLS verification failed: %v", err)
+	}
+
+	serverTLSConfig, err := security.LoadServerTLSConfig(tlsConfig)
+	if err != nil {
+		log.Fatalf("Failed to load TLS configuration: %v", err)
+	}
+
+	pool, err := pgxpool.New(context.Background(), cfg.DatabaseURL)
+	if err != nil {
+		log.Fatalf("Failed to create postgres pool: %v", err)
+	}
+	defer pool.Close()
+
+	// Webhook signing secrets are envelope-encrypted under the KMS master key
+	// the API encrypts them with
+	kmsPath := os.Getenv("KMS_KEY_STORE")
+	if kmsPath == "" {
+		kmsPath = "/tmp/vault-keys"
+	}
+	kms, err := crypto.NewFileBasedKMS(crypto.FileBasedKMSConfig{KeyStorePath: kmsPath})
+	if err != nil {
+		log.Fatalf("Failed to initialize KMS: %v", err)
+	}
+
+	ls := ledger.NewLedgerService(ledger.NewPostgresLedger(pool))
+	if v := os.Getenv("LEDGER_PENDING_TTL"); v != "" {
+		ttl, err := time.ParseDuration(v)
+		if err != nil || ttl <= 0 {
+			log.Fatalf("Invalid LEDGER_PENDING_TTL %q", v)
+		}
+		ls.PendingTTL = ttl
+	}
+	if v := os.Getenv("LEDGER_BATCH_CHUNK_SIZE"); v != "" {
+		size, err := strconv.Atoi(v)
+		if err != nil || size <= 0 {
+			log.Fatalf("Invalid LEDGER_BATCH_CHUNK_SIZE %q", v)
+		}
+		ls.BatchChunkSize = size
+	}
+	if v := os.Getenv("LEDGER_MAX_FX_SPREAD"); v != "" {
+		spread, err := money.ParseRate(v)
+		if err != nil || spread.IsZero() {
+			log.Fatalf("Invalid LEDGER_MAX_FX_SPREAD %q", v)
+		}
+		ls.MaxFXSpread = spread
+	}
+
+	addr := os.Getenv("LEDGER_GRPC_ADDR")
+	if addr == "" {
+		addr = ":50053"
+	}
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.Fatalf("failed to listen: %v", err)
+	}
+
+	s := grpc.NewServer(
+		grpc.Creds(credentials.NewTLS(serverTLSConfig)),
+		grpc.UnaryInterceptor(rbacUnaryInterceptor),
+		grpc.StreamInterceptor(rbacStreamInterceptor),
+	)
+	events := &outbox.PostgresStore{Pool: pool}
+	ledgerpb.RegisterLedgerServiceServer(s, newServer(ls, events))
+
+	log.Printf("server listening at %v with mutual TLS", lis.Addr())
+
+	sweepCtx, stopSweep := context.WithCancel(context.Background())
+	defer stopSweep()
+	go expirePendingTransactions(sweepCtx, ls, pendingExpiryInterval)
+	go resumeBatches(sweepCtx, ls)
+
+	for _, relay := range outboxRelays(events) {
+		go relay.Run(sweepCtx)
+	}
+	webhookRelay := &outbox.Relay{Source: events, Sink: &webhooks.Dispatcher{Pool: pool}, Offsets: events, Consumer: "webhooks"}
+	go webhookRelay.Run(sweepCtx)
+	go (&webhooks.Deliverer{Pool: pool, Encryptor: crypto.NewAEADEncryptor(kms)}).Run(sweepCtx)
+	go (&payouts.Scheduler{Service: payouts.NewService(pool, ls, fees.NewService(pool, ls))}).Run(sweepCtx)
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		<-sigCh
+		log.Println("Shutting down...")
+		stopSweep()
+
+		// Force-close connections if in-flight RPCs do not drain in time
+		timer := time.AfterFunc(shutdownTimeout, s.Stop)
+		defer timer.Stop()
+		s.GracefulStop()
+	}()
+
+	if err := s.Serve(lis); err != nil {
+		log.Fatalf("failed to serve: %v", err)
+	}
+}
+
+// expirePendingTransactions periodically records expired authorizations. Funds
+// are released as soon as an authorization expires; this keeps statuses current.
+func expirePendingTransactions(ctx context.Context, ls *ledger.LedgerService, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := ls.ExpirePendingTransactions(ctx)
+			if err != nil {
+				log.Printf("failed to expire pending transactions: %v", err)
+				continue
+			}
+			if n > 0 {
+				log.Printf("expired %d pending transactions", n)
+			}
+		}
+	}
+}
+
+// resumeBatches finishes posting batches left unfinished by an earlier run
+func resumeBatches(ctx context.Context, ls *ledger.LedgerService) {
+	n, err := ls.ResumeBatches(ctx)
+	if err != nil {
+		log.Printf("failed to resume posting batches: %v", err)
+	}
+	if n > 0 {
+		log.Printf("resumed %d posting batches", n)
+	}
+}
+
+// outboxRelays builds the outbox relays configured in the environment: to a
+// JSON lines file (OUTBOX_FILE_PATH) and to a webhook (OUTBOX_WEBHOOK_URL).
+// Each keeps its own offset, so one sink being down does not hold back the
+// other.
+func outboxRelays(events *outbox.PostgresStore) []*outbox.Relay {
+	var relays []*outbox.Relay
+	if path := os.Getenv("OUTBOX_FILE_PATH"); path != "" {
+		sink, err := outbox.NewFileSink(path)
+		if err != nil {
+			log.Fatalf("Failed to open outbox file: %v", err)
+		}
+		relays = append(relays, &outbox.Relay{Source: events, Sink: sink, Offsets: events, Consumer: "file"})
+	}
+	if url := os.Getenv("OUTBOX_WEBHOOK_URL"); url != "" {
+		sink := &outbox.WebhookSink{URL: url}
+		relays = append(relays, &outbox.Relay{Source: events, Sink: sink, Offsets: events, Consumer: "webhook"})
+	}
+	return relays
 }
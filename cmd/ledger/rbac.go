@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	ledgerpb "github.com/example/pci-infra/api/gen/ledger"
+	"github.com/example/pci-infra/internal/ledger"
+	"github.com/example/pci-infra/internal/security"
+)
+
+// methodPermissions lists the client certificate permission each RPC requires.
+// Permissions reuse the HTTP API scope names and are carried in the certificate
+// subject Organization (see security.ExtractRBACClaims).
+var methodPermissions = map[string]string{
+	ledgerpb.LedgerService_CreateAccount_FullMethodName:              "accounts:write",
+	ledgerpb.LedgerService_ListAccounts_FullMethodName:               "accounts:read",
+	ledgerpb.LedgerService_GetAccount_FullMethodName:                 "accounts:read",
+	ledgerpb.LedgerService_GetAccountStatusHistory_FullMethodName:    "accounts:read",
+	ledgerpb.LedgerService_GetAccountTree_FullMethodName:             "accounts:read",
+	ledgerpb.LedgerService_FreezeAccount_FullMethodName:              "accounts:write",
+	ledgerpb.LedgerService_UnfreezeAccount_FullMethodName:            "accounts:write",
+	ledgerpb.LedgerService_BlockAccount_FullMethodName:               "accounts:write",
+	ledgerpb.LedgerService_CloseAccount_FullMethodName:               "accounts:write",
+	ledgerpb.LedgerService_Credit_FullMethodName:                     "ledger:write",
+	ledgerpb.LedgerService_Debit_FullMethodName:                      "ledger:write",
+	ledgerpb.LedgerService_Transfer_FullMethodName:                   "ledger:write",
+	ledgerpb.LedgerService_PostTransaction_FullMethodName:            "ledger:write",
+	ledgerpb.LedgerService_GetBalance_FullMethodName:                 "ledger:read",
+	ledgerpb.LedgerService_Reconcile_FullMethodName:                  "ledger:read",
+	ledgerpb.LedgerService_GetStatement_FullMethodName:               "ledger:read",
+	ledgerpb.LedgerService_GetAccountBalances_FullMethodName:         "ledger:read",
+	ledgerpb.LedgerService_GetPendingTransaction_FullMethodName:      "ledger:read",
+	ledgerpb.LedgerService_AuthorizePending_FullMethodName:           "ledger:write",
+	ledgerpb.LedgerService_CapturePending_FullMethodName:             "ledger:write",
+	ledgerpb.LedgerService_VoidPending_FullMethodName:                "ledger:write",
+	ledgerpb.LedgerService_GetTransaction_FullMethodName:             "ledger:read",
+	ledgerpb.LedgerService_ReverseTransaction_FullMethodName:         "ledger:write",
+	ledgerpb.LedgerService_GetFXRate_FullMethodName:                  "ledger:read",
+	ledgerpb.LedgerService_SetFXRate_FullMethodName:                  "ledger:fx",
+	ledgerpb.LedgerService_SetFXAccounts_FullMethodName:              "accounts:write",
+	ledgerpb.LedgerService_FXTransfer_FullMethodName:                 "ledger:write",
+	ledgerpb.LedgerService_ValidateConsistency_FullMethodName:        "ledger:read",
+	ledgerpb.LedgerService_CreatePeriod_FullMethodName:               "ledger:close",
+	ledgerpb.LedgerService_ListPeriods_FullMethodName:                "ledger:read",
+	ledgerpb.LedgerService_GetPeriod_FullMethodName:                  "ledger:read",
+	ledgerpb.LedgerService_SoftClosePeriod_FullMethodName:            "ledger:close",
+	ledgerpb.LedgerService_HardClosePeriod_FullMethodName:            "ledger:close",
+	ledgerpb.LedgerService_SetRetainedEarningsAccount_FullMethodName: "accounts:write",
+	ledgerpb.LedgerService_StreamEvents_FullMethodName:               "ledger:read",
+	ledgerpb.LedgerService_ImportBatch_FullMethodName:                "ledger:write",
+	ledgerpb.LedgerService_GetBatch_FullMethodName:                   "ledger:read",
+	ledgerpb.LedgerService_ListBatchLines_FullMethodName:             "ledger:read",
+	ledgerpb.LedgerService_SetCreditLimit_FullMethodName:             "accounts:write",
+	ledgerpb.LedgerService_GetCreditLimit_FullMethodName:             "accounts:read",
+	ledgerpb.LedgerService_ListCreditLimits_FullMethodName:           "accounts:read",
+}
+
+// adjustPermission lets a caller post into soft-closed accounting periods
+const adjustPermission = "ledger:adjust"
+
+// fxPermission lets a caller apply quoted FX rates outside the configured
+// spread of the market rate
+const fxPermission = "ledger:fx"
+
+// callerIdentity is the authenticated service behind a request
+type callerIdentity struct {
+	Service     string
+	Permissions []string
+}
+
+type callerKey struct{}
+
+func callerFromContext(ctx context.Context) (callerIdentity, bool) {
+	id, ok := ctx.Value(callerKey{}).(callerIdentity)
+	return id, ok
+}
+
+// rbacUnaryInterceptor authorizes each call against the verified client certificate.
+// Methods without an entry in methodPermissions are denied. Callers holding
+// adjustPermission may also post into soft-closed periods, and callers holding
+// fxPermission may apply off-market FX quotes.
+func rbacUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	required, ok := methodPermissions[info.FullMethod]
+	if !ok {
+		return nil, status.Errorf(codes.PermissionDenied, "method %s is not permitted", info.FullMethod)
+	}
+
+	id, err := identityFromPeer(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if !hasPermission(id.Permissions, required) {
+		return nil, status.Errorf(codes.PermissionDenied, "service %s lacks permission %s", id.Service, required)
+	}
+
+	ctx = context.WithValue(ctx, callerKey{}, id)
+	if hasPermission(id.Permissions, adjustPermission) {
+		ctx = ledger.WithSoftClosedPostings(ctx)
+	}
+	if hasPermission(id.Permissions, fxPermission) {
+		ctx = ledger.WithOffMarketFXQuotes(ctx)
+	}
+	return handler(ctx, req)
+}
+
+// rbacStreamInterceptor authorizes streaming calls as rbacUnaryInterceptor
+// does unary ones
+func rbacStreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	required, ok := methodPermissions[info.FullMethod]
+	if !ok {
+		return status.Errorf(codes.PermissionDenied, "method %s is not permitted", info.FullMethod)
+	}
+
+	id, err := identityFromPeer(ss.Context())
+	if err != nil {
+		return err
+	}
+
+	if !hasPermission(id.Permissions, required) {
+		return status.Errorf(codes.PermissionDenied, "service %s lacks permission %s", id.Service, required)
+	}
+
+	ctx := context.WithValue(ss.Context(), callerKey{}, id)
+	if hasPermission(id.Permissions, adjustPermission) {
+		ctx = ledger.WithSoftClosedPostings(ctx)
+	}
+	if hasPermission(id.Permissions, fxPermission) {
+		ctx = ledger.WithOffMarketFXQuotes(ctx)
+	}
+	return handler(srv, &identifiedStream{ServerStream: ss, ctx: ctx})
+}
+
+// identifiedStream carries the caller identity in its context
+type identifiedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *identifiedStream) Context() context.Context {
+	return s.ctx
+}
+
+// identityFromPeer extracts RBAC claims from the verified mTLS client certificate
+func identityFromPeer(ctx context.Context) (callerIdentity, error) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return callerIdentity{}, status.Error(codes.Unauthenticated, "no peer information")
+	}
+
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok {
+		return callerIdentity{}, status.Error(codes.Unauthenticated, "connection is not TLS")
+	}
+
+	chains := tlsInfo.State.VerifiedChains
+	if len(chains) == 0 || len(chains[0]) == 0 {
+		return callerIdentity{}, status.Error(codes.Unauthenticated, "client certificate not verified")
+	}
+
+	service, permissions, err := security.ExtractRBACClaims(chains[0][0])
+	if err != nil {
+		return callerIdentity{}, status.Errorf(codes.Unauthenticated, "invalid client certificate: %v", err)
+	}
+
+	return callerIdentity{Service: service, Permissions: permissions}, nil
+}
+
+func hasPermission(granted []string, required string) bool {
+	for _, p := range granted {
+		if p == required {
+			return true
+		}
+	}
+	return false
+}
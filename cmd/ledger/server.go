@@ -0,0 +1,1331 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	ledgerpb "github.com/example/pci-infra/api/gen/ledger"
+	moneypb "github.com/example/pci-infra/api/gen/money"
+	"github.com/example/pci-infra/internal/ledger"
+	"github.com/example/pci-infra/internal/money"
+	"github.com/example/pci-infra/internal/outbox"
+)
+
+// server adapts ledger.LedgerService to the LedgerService gRPC definition
+type server struct {
+	ledgerpb.UnimplementedLedgerServiceServer
+	ledger *ledger.LedgerService
+	events outbox.Source
+}
+
+func newServer(ls *ledger.LedgerService, events outbox.Source) *server {
+	return &server{ledger: ls, events: events}
+}
+
+func (s *server) CreateAccount(ctx context.Context, req *ledgerpb.CreateAccountRequest) (*ledgerpb.CreateAccountResponse, error) {
+	account, err := s.ledger.CreateAccount(ctx, ledger.CreateAccountRequest{
+		ParentID:      req.ParentId,
+		AccountNumber: req.AccountNumber,
+		AccountType:   req.AccountType,
+		Name:          req.Name,
+		CurrencyCode:  req.CurrencyCode,
+		CreatedBy:     createdBy(ctx, req.CreatedBy),
+		Metadata:      fromProtoMetadata(req.Metadata),
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	pbAccount := toProtoAccount(account)
+	return &ledgerpb.CreateAccountResponse{
+		AccountId:           pbAccount.Id,
+		AccountNumber:       pbAccount.AccountNumber,
+		AccountType:         pbAccount.AccountType,
+		Name:                pbAccount.Name,
+		CurrencyCode:        pbAccount.CurrencyCode,
+		IsActive:            pbAccount.IsActive,
+		CreatedAt:           pbAccount.CreatedAt,
+		CreatedBy:           pbAccount.CreatedBy,
+		Metadata:            pbAccount.Metadata,
+		CurrentBalanceMoney: pbAccount.CurrentBalanceMoney,
+		Status:              pbAccount.Status,
+		ParentId:            pbAccount.ParentId,
+	}, nil
+}
+
+func (s *server) Credit(ctx context.Context, req *ledgerpb.CreditRequest) (*ledgerpb.CreditResponse, error) {
+	amount, err := fromProtoMoney(req.AmountMoney)
+	if err != nil {
+		return nil, err
+	}
+
+	txn := transactionRequest(ctx, req.TransactionId, req.Description, req.ReferenceType, req.ReferenceId, req.CurrencyCode, req.CreatedBy, req.Metadata)
+	err = s.ledger.Credit(ctx, ledger.CreditRequest{
+		TransactionRequest: txn,
+		AccountID:          req.AccountId,
+		Amount:             amount,
+		Description:        req.Description,
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &ledgerpb.CreditResponse{
+		Success:       true,
+		TransactionId: txn.TransactionID,
+		AccountId:     req.AccountId,
+		AmountMoney:   toProtoMoney(amount),
+		Description:   req.Description,
+		CreatedAt:     time.Now().UTC().Format(time.RFC3339),
+	}, nil
+}
+
+func (s *server) Debit(ctx context.Context, req *ledgerpb.DebitRequest) (*ledgerpb.DebitResponse, error) {
+	amount, err := fromProtoMoney(req.AmountMoney)
+	if err != nil {
+		return nil, err
+	}
+
+	txn := transactionRequest(ctx, req.TransactionId, req.Description, req.ReferenceType, req.ReferenceId, req.CurrencyCode, req.CreatedBy, req.Metadata)
+	err = s.ledger.Debit(ctx, ledger.DebitRequest{
+		TransactionRequest: txn,
+		AccountID:          req.AccountId,
+		Amount:             amount,
+		Description:        req.Description,
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &ledgerpb.DebitResponse{
+		Success:       true,
+		TransactionId: txn.TransactionID,
+		AccountId:     req.AccountId,
+		AmountMoney:   toProtoMoney(amount),
+		Description:   req.Description,
+		CreatedAt:     time.Now().UTC().Format(time.RFC3339),
+	}, nil
+}
+
+func (s *server) Transfer(ctx context.Context, req *ledgerpb.TransferRequest) (*ledgerpb.TransferResponse, error) {
+	amount, err := fromProtoMoney(req.AmountMoney)
+	if err != nil {
+		return nil, err
+	}
+	if req.CurrencyCode != "" && req.CurrencyCode != amount.Currency() {
+		return nil, status.Errorf(codes.InvalidArgument, "currency_code %s does not match amount currency %s", req.CurrencyCode, amount.Currency())
+	}
+
+	transactionID := uuid.NewString()
+	err = s.ledger.Transfer(ctx, ledger.TransferRequest{
+		TransactionID: transactionID,
+		FromAccountID: req.FromAccountId,
+		ToAccountID:   req.ToAccountId,
+		Amount:        amount,
+		Description:   req.Description,
+		CurrencyCode:  amount.Currency(),
+		CreatedBy:     createdBy(ctx, req.CreatedBy),
+		ReferenceType: req.ReferenceType,
+		ReferenceID:   req.ReferenceId,
+		Metadata:      fromProtoMetadata(req.Metadata),
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &ledgerpb.TransferResponse{
+		Success:       true,
+		TransactionId: transactionID,
+		FromAccountId: req.FromAccountId,
+		ToAccountId:   req.ToAccountId,
+		AmountMoney:   toProtoMoney(amount),
+		Description:   req.Description,
+		CreatedAt:     time.Now().UTC().Format(time.RFC3339),
+	}, nil
+}
+
+func (s *server) PostTransaction(ctx context.Context, req *ledgerpb.PostTransactionRequest) (*ledgerpb.PostTransactionResponse, error) {
+	txn, err := fromProtoTransaction(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	posted, err := s.ledger.PostTransaction(ctx, txn)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &ledgerpb.PostTransactionResponse{
+		Success:       true,
+		TransactionId: posted.TransactionID,
+		Entries:       toProtoPostedEntries(posted.Entries),
+	}, nil
+}
+
+func (s *server) GetBalance(ctx context.Context, req *ledgerpb.GetBalanceRequest) (*ledgerpb.GetBalanceResponse, error) {
+	if req.At != "" {
+		at, err := time.Parse(time.RFC3339, req.At)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "at must be RFC3339: %v", err)
+		}
+		balance, err := s.ledger.GetBalanceAt(ctx, req.AccountId, at)
+		if err != nil {
+			return nil, toStatus(err)
+		}
+		return &ledgerpb.GetBalanceResponse{
+			AccountId:    req.AccountId,
+			BalanceMoney: toProtoMoney(balance),
+			CurrencyCode: balance.Currency(),
+			UpdatedAt:    at.UTC().Format(time.RFC3339),
+		}, nil
+	}
+
+	balance, err := s.ledger.GetBalance(ctx, req.AccountId)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &ledgerpb.GetBalanceResponse{
+		AccountId:    req.AccountId,
+		BalanceMoney: toProtoMoney(balance),
+		CurrencyCode: balance.Currency(),
+	}, nil
+}
+
+func (s *server) GetStatement(ctx context.Context, req *ledgerpb.GetStatementRequest) (*ledgerpb.GetStatementResponse, error) {
+	from, err := time.Parse(time.RFC3339, req.From)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "from must be RFC3339: %v", err)
+	}
+	to, err := time.Parse(time.RFC3339, req.To)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "to must be RFC3339: %v", err)
+	}
+
+	stmt, err := s.ledger.GetStatement(ctx, ledger.StatementRequest{
+		AccountID: req.AccountId,
+		From:      from,
+		To:        to,
+		Cursor:    req.Cursor,
+		Limit:     int(req.Limit),
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	resp := &ledgerpb.GetStatementResponse{
+		AccountId:      stmt.AccountID,
+		CurrencyCode:   stmt.CurrencyCode,
+		From:           stmt.From,
+		To:             stmt.To,
+		OpeningBalance: toProtoMoney(stmt.OpeningBalance),
+		ClosingBalance: toProtoMoney(stmt.ClosingBalance),
+		NextCursor:     stmt.NextCursor,
+	}
+	for _, e := range stmt.Entries {
+		resp.Entries = append(resp.Entries, &ledgerpb.StatementEntry{
+			EntryId:        e.EntryID,
+			TransactionId:  e.TransactionID,
+			EntryType:      e.EntryType,
+			Amount:         toProtoMoney(e.Amount),
+			BalanceChange:  toProtoMoney(e.BalanceChange),
+			RunningBalance: toProtoMoney(e.RunningBalance),
+			Description:    e.Description,
+			ReferenceType:  e.ReferenceType,
+			ReferenceId:    e.ReferenceID,
+			PostedAt:       e.PostedAt,
+		})
+	}
+	return resp, nil
+}
+
+func (s *server) GetAccountBalances(ctx context.Context, req *ledgerpb.GetAccountBalancesRequest) (*ledgerpb.GetAccountBalancesResponse, error) {
+	balances, err := s.ledger.GetAccountBalances(ctx, req.AccountId)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &ledgerpb.GetAccountBalancesResponse{
+		AccountId:      balances.AccountID,
+		CurrencyCode:   balances.CurrencyCode,
+		Posted:         toProtoMoney(balances.Posted),
+		Pending:        toProtoMoney(balances.Pending),
+		Held:           toProtoMoney(balances.Held),
+		Available:      toProtoMoney(balances.Available),
+		OverdraftLimit: toProtoMoney(balances.OverdraftLimit),
+	}, nil
+}
+
+func (s *server) AuthorizePending(ctx context.Context, req *ledgerpb.AuthorizePendingRequest) (*ledgerpb.PendingTransactionResponse, error) {
+	amount, err := fromProtoMoney(req.Amount)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := s.ledger.AuthorizePending(ctx, ledger.AuthorizeRequest{
+		TransactionID: req.TransactionId,
+		FromAccountID: req.FromAccountId,
+		ToAccountID:   req.ToAccountId,
+		Amount:        amount,
+		Description:   req.Description,
+		ReferenceType: req.ReferenceType,
+		ReferenceID:   req.ReferenceId,
+		CreatedBy:     createdBy(ctx, req.CreatedBy),
+		Metadata:      fromProtoMetadata(req.Metadata),
+		TTL:           time.Duration(req.TtlSeconds) * time.Second,
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return toProtoPending(p), nil
+}
+
+func (s *server) CapturePending(ctx context.Context, req *ledgerpb.CapturePendingRequest) (*ledgerpb.PendingTransactionResponse, error) {
+	// An unset amount captures the full authorized amount
+	var amount money.Money
+	if req.Amount != nil {
+		var err error
+		if amount, err = fromProtoMoney(req.Amount); err != nil {
+			return nil, err
+		}
+	}
+
+	p, err := s.ledger.CapturePending(ctx, ledger.CaptureRequest{
+		PendingID:  req.PendingId,
+		Amount:     amount,
+		CapturedBy: createdBy(ctx, req.CapturedBy),
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return toProtoPending(p), nil
+}
+
+func (s *server) VoidPending(ctx context.Context, req *ledgerpb.VoidPendingRequest) (*ledgerpb.PendingTransactionResponse, error) {
+	p, err := s.ledger.VoidPending(ctx, ledger.VoidRequest{
+		PendingID: req.PendingId,
+		VoidedBy:  createdBy(ctx, req.VoidedBy),
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return toProtoPending(p), nil
+}
+
+func (s *server) GetPendingTransaction(ctx context.Context, req *ledgerpb.GetPendingTransactionRequest) (*ledgerpb.PendingTransactionResponse, error) {
+	p, err := s.ledger.GetPendingTransaction(ctx, req.PendingId)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return toProtoPending(p), nil
+}
+
+func (s *server) ReverseTransaction(ctx context.Context, req *ledgerpb.ReverseTransactionRequest) (*ledgerpb.ReversalResponse, error) {
+	// An unset amount reverses whatever is left of the transaction
+	var amount money.Money
+	if req.Amount != nil {
+		var err error
+		if amount, err = fromProtoMoney(req.Amount); err != nil {
+			return nil, err
+		}
+	}
+
+	r, err := s.ledger.ReverseTransaction(ctx, ledger.ReverseRequest{
+		TransactionID: req.TransactionId,
+		ReversalID:    req.ReversalId,
+		Amount:        amount,
+		Reason:        req.Reason,
+		ReversedBy:    createdBy(ctx, req.ReversedBy),
+		ApprovedBy:    req.ApprovedBy,
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return toProtoReversal(r), nil
+}
+
+func (s *server) GetTransaction(ctx context.Context, req *ledgerpb.GetTransactionRequest) (*ledgerpb.GetTransactionResponse, error) {
+	detail, err := s.ledger.GetTransaction(ctx, req.TransactionId)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	resp := &ledgerpb.GetTransactionResponse{
+		TransactionId: detail.TransactionID,
+		Entries:       toProtoPostedEntries(detail.Entries),
+		ReversalOf:    detail.ReversalOf,
+		FullyReversed: detail.FullyReversed,
+	}
+	for i := range detail.Reversals {
+		resp.Reversals = append(resp.Reversals, toProtoReversal(&detail.Reversals[i]))
+	}
+	return resp, nil
+}
+
+func (s *server) SetFXRate(ctx context.Context, req *ledgerpb.SetFXRateRequest) (*ledgerpb.FXRate, error) {
+	rate, err := money.ParseFXRate(req.Rate)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid rate: %v", err)
+	}
+
+	// An empty effective_at makes the rate effective immediately
+	var effectiveAt time.Time
+	if req.EffectiveAt != "" {
+		if effectiveAt, err = time.Parse(time.RFC3339, req.EffectiveAt); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "effective_at must be RFC3339: %v", err)
+		}
+	}
+
+	r, err := s.ledger.SetFXRate(ctx, ledger.SetFXRateRequest{
+		BaseCurrency:  req.BaseCurrency,
+		QuoteCurrency: req.QuoteCurrency,
+		Rate:          rate,
+		EffectiveAt:   effectiveAt,
+		Source:        req.Source,
+		CreatedBy:     createdBy(ctx, req.CreatedBy),
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return toProtoFXRate(r), nil
+}
+
+func (s *server) GetFXRate(ctx context.Context, req *ledgerpb.GetFXRateRequest) (*ledgerpb.FXRate, error) {
+	var at time.Time
+	if req.At != "" {
+		var err error
+		if at, err = time.Parse(time.RFC3339, req.At); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "at must be RFC3339: %v", err)
+		}
+	}
+
+	r, err := s.ledger.GetFXRate(ctx, req.BaseCurrency, req.QuoteCurrency, at)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return toProtoFXRate(r), nil
+}
+
+func (s *server) SetFXAccounts(ctx context.Context, req *ledgerpb.SetFXAccountsRequest) (*ledgerpb.SetFXAccountsResponse, error) {
+	err := s.ledger.SetFXAccounts(ctx, ledger.FXAccounts{
+		CurrencyCode:      req.CurrencyCode,
+		ClearingAccountID: req.ClearingAccountId,
+		GainAccountID:     req.GainAccountId,
+		LossAccountID:     req.LossAccountId,
+		UpdatedBy:         createdBy(ctx, req.UpdatedBy),
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &ledgerpb.SetFXAccountsResponse{Success: true}, nil
+}
+
+func (s *server) FXTransfer(ctx context.Context, req *ledgerpb.FXTransferRequest) (*ledgerpb.FXTransferResponse, error) {
+	amount, err := fromProtoMoney(req.Amount)
+	if err != nil {
+		return nil, err
+	}
+
+	// An empty quoted rate applies the market rate
+	var quoted money.FXRate
+	if req.QuotedRate != "" {
+		if quoted, err = money.ParseFXRate(req.QuotedRate); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid quoted_rate: %v", err)
+		}
+	}
+
+	c, err := s.ledger.FXTransfer(ctx, ledger.FXTransferRequest{
+		TransactionID: req.TransactionId,
+		FromAccountID: req.FromAccountId,
+		ToAccountID:   req.ToAccountId,
+		Amount:        amount,
+		QuotedRate:    quoted,
+		Description:   req.Description,
+		ReferenceType: req.ReferenceType,
+		ReferenceID:   req.ReferenceId,
+		CreatedBy:     createdBy(ctx, req.CreatedBy),
+		Metadata:      fromProtoMetadata(req.Metadata),
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &ledgerpb.FXTransferResponse{
+		Id:               c.ID,
+		TransactionId:    c.TransactionID,
+		FromAccountId:    c.FromAccountID,
+		ToAccountId:      c.ToAccountID,
+		SourceAmount:     toProtoMoney(c.SourceAmount),
+		TargetAmount:     toProtoMoney(c.TargetAmount),
+		FxRateId:         c.FXRateID,
+		MarketRate:       c.MarketRate.String(),
+		AppliedRate:      c.AppliedRate.String(),
+		RealisedGainLoss: toProtoMoney(c.RealisedGainLoss),
+		CreatedAt:        c.CreatedAt,
+		CreatedBy:        c.CreatedBy,
+		Entries:          toProtoPostedEntries(c.Entries),
+	}, nil
+}
+
+func (s *server) FreezeAccount(ctx context.Context, req *ledgerpb.AccountStatusRequest) (*ledgerpb.AccountStatusTransition, error) {
+	t, err := s.ledger.FreezeAccount(ctx, accountStatusRequest(ctx, req))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toProtoStatusTransition(t), nil
+}
+
+func (s *server) UnfreezeAccount(ctx context.Context, req *ledgerpb.AccountStatusRequest) (*ledgerpb.AccountStatusTransition, error) {
+	t, err := s.ledger.UnfreezeAccount(ctx, accountStatusRequest(ctx, req))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toProtoStatusTransition(t), nil
+}
+
+func (s *server) BlockAccount(ctx context.Context, req *ledgerpb.AccountStatusRequest) (*ledgerpb.AccountStatusTransition, error) {
+	t, err := s.ledger.BlockAccount(ctx, accountStatusRequest(ctx, req))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toProtoStatusTransition(t), nil
+}
+
+func (s *server) CloseAccount(ctx context.Context, req *ledgerpb.AccountStatusRequest) (*ledgerpb.AccountStatusTransition, error) {
+	t, err := s.ledger.CloseAccount(ctx, accountStatusRequest(ctx, req))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toProtoStatusTransition(t), nil
+}
+
+func (s *server) GetAccountStatusHistory(ctx context.Context, req *ledgerpb.GetAccountStatusHistoryRequest) (*ledgerpb.GetAccountStatusHistoryResponse, error) {
+	history, err := s.ledger.GetAccountStatusHistory(ctx, req.AccountId)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	resp := &ledgerpb.GetAccountStatusHistoryResponse{AccountId: req.AccountId}
+	for i := range history {
+		resp.Transitions = append(resp.Transitions, toProtoStatusTransition(&history[i]))
+	}
+	return resp, nil
+}
+
+func (s *server) GetAccountTree(ctx context.Context, req *ledgerpb.GetAccountTreeRequest) (*ledgerpb.AccountNode, error) {
+	tree, err := s.ledger.GetAccountTree(ctx, req.AccountId)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toProtoAccountNode(tree), nil
+}
+
+func (s *server) CreatePeriod(ctx context.Context, req *ledgerpb.CreatePeriodRequest) (*ledgerpb.AccountingPeriod, error) {
+	period, err := s.ledger.CreatePeriod(ctx, ledger.CreatePeriodRequest{
+		Name:      req.Name,
+		StartsOn:  req.StartsOn,
+		EndsOn:    req.EndsOn,
+		CreatedBy: createdBy(ctx, req.CreatedBy),
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toProtoPeriod(period), nil
+}
+
+func (s *server) ListPeriods(ctx context.Context, req *ledgerpb.ListPeriodsRequest) (*ledgerpb.ListPeriodsResponse, error) {
+	periods, err := s.ledger.ListPeriods(ctx)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	resp := &ledgerpb.ListPeriodsResponse{}
+	for i := range periods {
+		resp.Periods = append(resp.Periods, toProtoPeriod(&periods[i]))
+	}
+	return resp, nil
+}
+
+func (s *server) GetPeriod(ctx context.Context, req *ledgerpb.GetPeriodRequest) (*ledgerpb.AccountingPeriod, error) {
+	period, err := s.ledger.GetPeriod(ctx, req.PeriodId)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toProtoPeriod(period), nil
+}
+
+func (s *server) SoftClosePeriod(ctx context.Context, req *ledgerpb.ClosePeriodRequest) (*ledgerpb.AccountingPeriod, error) {
+	period, err := s.ledger.SoftClosePeriod(ctx, closePeriodRequest(ctx, req))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toProtoPeriod(period), nil
+}
+
+func (s *server) HardClosePeriod(ctx context.Context, req *ledgerpb.ClosePeriodRequest) (*ledgerpb.AccountingPeriod, error) {
+	period, err := s.ledger.HardClosePeriod(ctx, closePeriodRequest(ctx, req))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toProtoPeriod(period), nil
+}
+
+func (s *server) SetRetainedEarningsAccount(ctx context.Context, req *ledgerpb.SetRetainedEarningsAccountRequest) (*ledgerpb.SetRetainedEarningsAccountResponse, error) {
+	err := s.ledger.SetRetainedEarningsAccount(ctx, ledger.RetainedEarningsAccount{
+		CurrencyCode: req.CurrencyCode,
+		AccountID:    req.AccountId,
+		UpdatedBy:    createdBy(ctx, req.UpdatedBy),
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &ledgerpb.SetRetainedEarningsAccountResponse{Success: true}, nil
+}
+
+func (s *server) Reconcile(ctx context.Context, req *ledgerpb.ReconcileRequest) (*ledgerpb.ReconcileResponse, error) {
+	startTime, err := time.Parse(time.RFC3339, req.StartTime)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "start_time must be RFC3339: %v", err)
+	}
+	endTime, err := time.Parse(time.RFC3339, req.EndTime)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "end_time must be RFC3339: %v", err)
+	}
+
+	snapshots, err := s.ledger.Reconcile(ctx, ledger.ReconcileRequest{
+		AccountID: req.AccountId,
+		StartTime: startTime,
+		EndTime:   endTime,
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	resp := &ledgerpb.ReconcileResponse{
+		ReconciliationTime: time.Now().UTC().Format(time.RFC3339),
+	}
+
+	// Drift is any gap between one snapshot's closing balance and the next one's opening balance
+	var drift money.Money
+	for i, snapshot := range snapshots {
+		resp.Snapshots = append(resp.Snapshots, toProtoSnapshot(&snapshot))
+		if i == 0 {
+			if drift, err = money.Zero(snapshot.BalanceAfter.Currency()); err != nil {
+				return nil, toStatus(err)
+			}
+			continue
+		}
+		gap, err := snapshot.BalanceBefore.Sub(snapshots[i-1].BalanceAfter)
+		if err != nil {
+			return nil, toStatus(err)
+		}
+		if drift, err = drift.Add(gap.Abs()); err != nil {
+			return nil, toStatus(err)
+		}
+	}
+	if len(snapshots) > 0 {
+		resp.HasDrift = !drift.IsZero()
+		resp.DriftAmountMoney = toProtoMoney(drift)
+	}
+
+	return resp, nil
+}
+
+func (s *server) ListAccounts(ctx context.Context, req *ledgerpb.ListAccountsRequest) (*ledgerpb.ListAccountsResponse, error) {
+	filter := ledger.AccountFilter{
+		AccountType:  req.AccountType,
+		Status:       req.Status,
+		CurrencyCode: req.CurrencyCode,
+		ParentID:     req.ParentId,
+		TopLevel:     req.TopLevel,
+		Limit:        int(req.Limit),
+		Offset:       int(req.Offset),
+	}
+	// proto3 cannot distinguish an unset bool, so only filter when active accounts are requested
+	if req.IsActive {
+		filter.IsActive = &req.IsActive
+	}
+
+	accounts, err := s.ledger.ListAccounts(ctx, filter)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	resp := &ledgerpb.ListAccountsResponse{Total: int32(len(accounts))}
+	for _, account := range accounts {
+		resp.Accounts = append(resp.Accounts, toProtoAccount(account))
+	}
+	return resp, nil
+}
+
+func (s *server) GetAccount(ctx context.Context, req *ledgerpb.GetAccountRequest) (*ledgerpb.GetAccountResponse, error) {
+	var account *ledger.Account
+	var err error
+	switch {
+	case req.AccountId != "":
+		account, err = s.ledger.GetAccountByID(ctx, req.AccountId)
+	case req.AccountNumber != "":
+		account, err = s.ledger.GetAccount(ctx, req.AccountNumber)
+	default:
+		return nil, status.Error(codes.InvalidArgument, "account_id or account_number is required")
+	}
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &ledgerpb.GetAccountResponse{Account: toProtoAccount(account)}, nil
+}
+
+func (s *server) ValidateConsistency(ctx context.Context, req *ledgerpb.ValidateConsistencyRequest) (*ledgerpb.ValidateConsistencyResponse, error) {
+	results, err := s.ledger.ValidateBalanceConsistency(ctx)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	resp := &ledgerpb.ValidateConsistencyResponse{IsFullyValid: true}
+	for _, result := range results {
+		accountID, _ := result["account_id"].(string)
+		if req.AccountId != "" && accountID != req.AccountId {
+			continue
+		}
+
+		isConsistent, _ := result["is_consistent"].(bool)
+		message := "account balance matches journal entries"
+		if !isConsistent {
+			message = "account balance drifted from journal entries"
+			resp.IsFullyValid = false
+			resp.ErrorCount++
+		}
+
+		details := make(map[string]string)
+		for _, key := range []string{"account_number", "expected_balance", "actual_balance", "drift_amount"} {
+			if v, ok := result[key]; ok {
+				details[key] = fmt.Sprint(v)
+			}
+		}
+
+		resp.Results = append(resp.Results, &ledgerpb.ValidationResult{
+			IsValid:        isConsistent,
+			ValidationType: "balance_consistency",
+			Message:        message,
+			AccountId:      accountID,
+			Timestamp:      now,
+			Details:        details,
+		})
+	}
+
+	return resp, nil
+}
+
+// StreamEvents relays outbox events to the client until it disconnects. The
+// stream keeps no offset of its own; clients resume from the last sequence
+// they processed.
+func (s *server) StreamEvents(req *ledgerpb.StreamEventsRequest, stream ledgerpb.LedgerService_StreamEventsServer) error {
+	if s.events == nil {
+		return status.Error(codes.Unavailable, "event stream is not configured")
+	}
+	if req.AfterSequence < 0 {
+		return status.Error(codes.InvalidArgument, "after_sequence must not be negative")
+	}
+
+	consumer := "stream"
+	if id, ok := callerFromContext(stream.Context()); ok {
+		consumer = "stream:" + id.Service
+	}
+	relay := &outbox.Relay{
+		Source:     s.events,
+		Sink:       streamSink{stream},
+		Consumer:   consumer,
+		After:      req.AfterSequence,
+		EventTypes: req.EventTypes,
+	}
+	err := relay.Run(stream.Context())
+	if errors.Is(err, context.Canceled) {
+		return nil
+	}
+	return toStatus(err)
+}
+
+// streamSink sends events on a StreamEvents stream
+type streamSink struct {
+	stream ledgerpb.LedgerService_StreamEventsServer
+}
+
+func (s streamSink) Publish(ctx context.Context, events []outbox.Event) error {
+	for _, e := range events {
+		if err := s.stream.Send(toProtoEvent(e)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ImportBatch stores a settlement batch sent as PostTransaction requests or as
+// the content of a JSON lines file, then posts it in the background. The
+// reference and creator are taken from the first message.
+func (s *server) ImportBatch(stream ledgerpb.LedgerService_ImportBatchServer) error {
+	ctx := stream.Context()
+	req := ledger.BatchRequest{}
+	var content []byte
+	for first := true; ; first = false {
+		msg, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if first {
+			req.Reference = msg.Reference
+			req.CreatedBy = createdBy(ctx, msg.CreatedBy)
+		}
+		if len(msg.Content) > 0 && (len(msg.Transactions) > 0 || len(req.Transactions) > 0) ||
+			len(msg.Transactions) > 0 && len(content) > 0 {
+			return status.Error(codes.InvalidArgument, "send either transactions or file content, not both")
+		}
+		for _, t := range msg.Transactions {
+			txn, err := fromProtoTransaction(ctx, t)
+			if err != nil {
+				return status.Errorf(codes.InvalidArgument, "line %d: %s", len(req.Transactions)+1, status.Convert(err).Message())
+			}
+			// Lines without a creator take the batch's
+			txn.CreatedBy = t.CreatedBy
+			req.Transactions = append(req.Transactions, txn)
+		}
+		content = append(content, msg.Content...)
+	}
+
+	if len(content) > 0 {
+		txns, err := ledger.ReadBatchFile(bytes.NewReader(content))
+		if err != nil {
+			return toStatus(err)
+		}
+		req.Transactions = txns
+	}
+
+	batch, err := s.ledger.SubmitBatch(ctx, req)
+	if err != nil {
+		return batchStatus(err)
+	}
+
+	// The batch outlives the call; if the server stops first it is resumed
+	// on the next start
+	go s.runBatch(batch.ID)
+
+	return stream.SendAndClose(toProtoBatch(batch))
+}
+
+// runBatch posts a submitted batch
+func (s *server) runBatch(batchID string) {
+	batch, err := s.ledger.RunBatch(context.Background(), batchID)
+	if err != nil {
+		log.Printf("failed to run batch %s: %v", batchID, err)
+		return
+	}
+	log.Printf("batch %s completed: %d posted, %d failed", batch.ID, batch.PostedCount, batch.FailedCount)
+}
+
+func (s *server) GetBatch(ctx context.Context, req *ledgerpb.GetBatchRequest) (*ledgerpb.PostingBatch, error) {
+	batch, err := s.ledger.GetBatch(ctx, req.BatchId)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toProtoBatch(batch), nil
+}
+
+func (s *server) ListBatchLines(ctx context.Context, req *ledgerpb.ListBatchLinesRequest) (*ledgerpb.ListBatchLinesResponse, error) {
+	lines, err := s.ledger.ListBatchLines(ctx, ledger.BatchLineFilter{
+		BatchID: req.BatchId,
+		Status:  req.Status,
+		Limit:   int(req.Limit),
+		Offset:  int(req.Offset),
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	resp := &ledgerpb.ListBatchLinesResponse{}
+	for _, l := range lines {
+		resp.Lines = append(resp.Lines, toProtoBatchLine(l))
+	}
+	return resp, nil
+}
+
+func (s *server) SetCreditLimit(ctx context.Context, req *ledgerpb.SetCreditLimitRequest) (*ledgerpb.CreditLimit, error) {
+	overdraft, err := fromProtoMoney(req.OverdraftLimit)
+	if err != nil {
+		return nil, err
+	}
+	var maxAmount *money.Money
+	if req.MaxTransactionAmount != nil {
+		m, err := fromProtoMoney(req.MaxTransactionAmount)
+		if err != nil {
+			return nil, err
+		}
+		maxAmount = &m
+	}
+
+	// An empty effective_from makes the limit effective immediately
+	var effectiveFrom time.Time
+	if req.EffectiveFrom != "" {
+		if effectiveFrom, err = time.Parse(time.RFC3339, req.EffectiveFrom); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "effective_from must be RFC3339: %v", err)
+		}
+	}
+
+	limit, err := s.ledger.SetCreditLimit(ctx, ledger.SetCreditLimitRequest{
+		AccountID:            req.AccountId,
+		OverdraftLimit:       overdraft,
+		MaxTransactionAmount: maxAmount,
+		EffectiveFrom:        effectiveFrom,
+		Reason:               req.Reason,
+		SetBy:                createdBy(ctx, req.SetBy),
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toProtoCreditLimit(limit), nil
+}
+
+func (s *server) GetCreditLimit(ctx context.Context, req *ledgerpb.GetCreditLimitRequest) (*ledgerpb.CreditLimit, error) {
+	var at time.Time
+	if req.At != "" {
+		var err error
+		if at, err = time.Parse(time.RFC3339, req.At); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "at must be RFC3339: %v", err)
+		}
+	}
+
+	limit, err := s.ledger.GetCreditLimit(ctx, req.AccountId, at)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toProtoCreditLimit(limit), nil
+}
+
+func (s *server) ListCreditLimits(ctx context.Context, req *ledgerpb.ListCreditLimitsRequest) (*ledgerpb.ListCreditLimitsResponse, error) {
+	limits, err := s.ledger.ListCreditLimits(ctx, req.AccountId)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	resp := &ledgerpb.ListCreditLimitsResponse{}
+	for i := range limits {
+		resp.Limits = append(resp.Limits, toProtoCreditLimit(&limits[i]))
+	}
+	return resp, nil
+}
+
+// batchStatus reports the invalid lines of a rejected batch as a BadRequest
+// detail, one field violation per line
+func batchStatus(err error) error {
+	var batchErr *ledger.BatchError
+	if !errors.As(err, &batchErr) {
+		return toStatus(err)
+	}
+
+	br := &errdetails.BadRequest{}
+	for _, l := range batchErr.Lines {
+		br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       fmt.Sprintf("transactions[%d]", l.LineNumber-1),
+			Description: l.Error,
+		})
+	}
+	st, detailErr := status.New(codes.InvalidArgument, err.Error()).WithDetails(br)
+	if detailErr != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return st.Err()
+}
+
+// toStatus maps ledger domain errors to gRPC status codes. Unrecognised errors
+// are logged and reported as Internal so database details are not leaked.
+func toStatus(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var pgErr *pgconn.PgError
+	switch {
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	case errors.Is(err, ledger.ErrInvalidRequest),
+		errors.Is(err, ledger.ErrInvalidEntry),
+		errors.Is(err, ledger.ErrUnbalancedTransaction),
+		errors.Is(err, ledger.ErrCurrencyMismatch),
+		errors.Is(err, money.ErrCurrencyMismatch),
+		errors.Is(err, money.ErrOverflow):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, ledger.ErrAccountNotFound),
+		errors.Is(err, ledger.ErrPendingNotFound),
+		errors.Is(err, ledger.ErrTransactionNotFound),
+		errors.Is(err, ledger.ErrFXRateNotFound),
+		errors.Is(err, ledger.ErrPeriodNotFound),
+		errors.Is(err, ledger.ErrBatchNotFound),
+		errors.Is(err, ledger.ErrCreditLimitNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, ledger.ErrAccountExists),
+		errors.Is(err, ledger.ErrBatchExists):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, ledger.ErrAccountInactive),
+		errors.Is(err, ledger.ErrInsufficientFunds),
+		errors.Is(err, ledger.ErrTransactionLimitExceeded),
+		errors.Is(err, ledger.ErrPendingResolved),
+		errors.Is(err, ledger.ErrPendingExpired),
+		errors.Is(err, ledger.ErrAlreadyReversed),
+		errors.Is(err, ledger.ErrFXNotConfigured),
+		errors.Is(err, ledger.ErrQuoteOffMarket),
+		errors.Is(err, ledger.ErrInvalidStatusTransition),
+		errors.Is(err, ledger.ErrAccountNotEmpty),
+		errors.Is(err, ledger.ErrPeriodClosed),
+		errors.Is(err, ledger.ErrInvalidPeriodTransition),
+		errors.Is(err, ledger.ErrRetainedEarningsNotConfigured):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.As(err, &pgErr) && pgErr.Code == "40001":
+		return status.Error(codes.Aborted, "serialization failure, retry the request")
+	}
+
+	log.Printf("ledger request failed: %v", err)
+	return status.Error(codes.Internal, "internal error")
+}
+
+// fromProtoTransaction converts a PostTransaction request
+func fromProtoTransaction(ctx context.Context, req *ledgerpb.PostTransactionRequest) (ledger.Transaction, error) {
+	entries := make([]ledger.JournalEntry, 0, len(req.Entries))
+	for i, leg := range req.Entries {
+		amount, err := fromProtoMoney(leg.Amount)
+		if err != nil {
+			return ledger.Transaction{}, status.Errorf(codes.InvalidArgument, "entry %d: %s", i, status.Convert(err).Message())
+		}
+		entries = append(entries, ledger.JournalEntry{
+			AccountID:   leg.AccountId,
+			EntryType:   leg.EntryType,
+			Amount:      amount,
+			Description: leg.Description,
+			Metadata:    fromProtoMetadata(leg.Metadata),
+		})
+	}
+
+	return ledger.Transaction{
+		TransactionID: req.TransactionId,
+		Entries:       entries,
+		Description:   req.Description,
+		ReferenceType: req.ReferenceType,
+		ReferenceID:   req.ReferenceId,
+		CreatedBy:     createdBy(ctx, req.CreatedBy),
+		Metadata:      fromProtoMetadata(req.Metadata),
+		EffectiveDate: req.EffectiveDate,
+	}, nil
+}
+
+func transactionRequest(ctx context.Context, transactionID, description, referenceType, referenceID, currencyCode, creator string, metadata map[string]string) ledger.TransactionRequest {
+	if transactionID == "" {
+		transactionID = uuid.NewString()
+	}
+	return ledger.TransactionRequest{
+		TransactionID: transactionID,
+		Description:   description,
+		ReferenceType: referenceType,
+		ReferenceID:   referenceID,
+		CurrencyCode:  currencyCode,
+		CreatedBy:     createdBy(ctx, creator),
+		Metadata:      fromProtoMetadata(metadata),
+	}
+}
+
+func accountStatusRequest(ctx context.Context, req *ledgerpb.AccountStatusRequest) ledger.AccountStatusRequest {
+	return ledger.AccountStatusRequest{
+		AccountID: req.AccountId,
+		Reason:    req.Reason,
+		ChangedBy: createdBy(ctx, req.ChangedBy),
+	}
+}
+
+func closePeriodRequest(ctx context.Context, req *ledgerpb.ClosePeriodRequest) ledger.ClosePeriodRequest {
+	return ledger.ClosePeriodRequest{
+		PeriodID:      req.PeriodId,
+		TransactionID: req.TransactionId,
+		ClosedBy:      createdBy(ctx, req.ClosedBy),
+	}
+}
+
+// createdBy defaults the actor to the calling service from its client certificate
+func createdBy(ctx context.Context, requested string) string {
+	if requested != "" {
+		return requested
+	}
+	if id, ok := callerFromContext(ctx); ok {
+		return id.Service
+	}
+	return ""
+}
+
+func fromProtoMoney(m *moneypb.Money) (money.Money, error) {
+	if m == nil {
+		return money.Money{}, status.Error(codes.InvalidArgument, "amount is required")
+	}
+	amount, err := money.New(m.MinorUnits, m.CurrencyCode)
+	if err != nil {
+		return money.Money{}, status.Errorf(codes.InvalidArgument, "invalid amount: %v", err)
+	}
+	return amount, nil
+}
+
+func toProtoMoney(m money.Money) *moneypb.Money {
+	return &moneypb.Money{
+		MinorUnits:   m.MinorUnits(),
+		CurrencyCode: m.Currency(),
+	}
+}
+
+func fromProtoMetadata(md map[string]string) map[string]interface{} {
+	if md == nil {
+		return nil
+	}
+	out := make(map[string]interface{}, len(md))
+	for k, v := range md {
+		out[k] = v
+	}
+	return out
+}
+
+func toProtoMetadata(md map[string]interface{}) map[string]string {
+	if md == nil {
+		return nil
+	}
+	out := make(map[string]string, len(md))
+	for k, v := range md {
+		out[k] = fmt.Sprint(v)
+	}
+	return out
+}
+
+func toProtoPending(p *ledger.PendingTransaction) *ledgerpb.PendingTransactionResponse {
+	resp := &ledgerpb.PendingTransactionResponse{
+		Id:            p.ID,
+		TransactionId: p.TransactionID,
+		FromAccountId: p.FromAccountID,
+		ToAccountId:   p.ToAccountID,
+		Amount:        toProtoMoney(p.Amount),
+		Status:        p.Status,
+		Description:   p.Description,
+		ReferenceType: p.ReferenceType,
+		ReferenceId:   p.ReferenceID,
+		ExpiresAt:     p.ExpiresAt,
+		CreatedAt:     p.CreatedAt,
+		CreatedBy:     p.CreatedBy,
+		ResolvedAt:    p.ResolvedAt,
+		ResolvedBy:    p.ResolvedBy,
+	}
+	if p.CapturedAmount != nil {
+		resp.CapturedAmount = toProtoMoney(*p.CapturedAmount)
+	}
+	return resp
+}
+
+func toProtoPostedEntries(entries []ledger.JournalEntry) []*ledgerpb.PostedEntry {
+	out := make([]*ledgerpb.PostedEntry, 0, len(entries))
+	for _, entry := range entries {
+		out = append(out, &ledgerpb.PostedEntry{
+			EntryId:     entry.ID,
+			EntryNumber: entry.EntryNumber,
+			AccountId:   entry.AccountID,
+			EntryType:   entry.EntryType,
+			Amount:      toProtoMoney(entry.Amount),
+		})
+	}
+	return out
+}
+
+func toProtoReversal(r *ledger.Reversal) *ledgerpb.ReversalResponse {
+	resp := &ledgerpb.ReversalResponse{
+		Id:                    r.ID,
+		TransactionId:         r.TransactionID,
+		OriginalTransactionId: r.OriginalTransactionID,
+		Full:                  r.Full,
+		Reason:                r.Reason,
+		ReversedBy:            r.ReversedBy,
+		ApprovedBy:            r.ApprovedBy,
+		CreatedAt:             r.CreatedAt,
+		Entries:               toProtoPostedEntries(r.Entries),
+	}
+	if r.Amount != nil {
+		resp.Amount = toProtoMoney(*r.Amount)
+	}
+	return resp
+}
+
+func toProtoFXRate(r *ledger.FXRate) *ledgerpb.FXRate {
+	return &ledgerpb.FXRate{
+		Id:            r.ID,
+		BaseCurrency:  r.BaseCurrency,
+		QuoteCurrency: r.QuoteCurrency,
+		Rate:          r.Rate.String(),
+		EffectiveAt:   r.EffectiveAt,
+		Source:        r.Source,
+		CreatedAt:     r.CreatedAt,
+		CreatedBy:     r.CreatedBy,
+	}
+}
+
+func toProtoStatusTransition(t *ledger.AccountStatusTransition) *ledgerpb.AccountStatusTransition {
+	return &ledgerpb.AccountStatusTransition{
+		Id:         t.ID,
+		AccountId:  t.AccountID,
+		FromStatus: t.FromStatus,
+		ToStatus:   t.ToStatus,
+		Reason:     t.Reason,
+		ChangedBy:  t.ChangedBy,
+		CreatedAt:  t.CreatedAt,
+	}
+}
+
+func toProtoPeriod(p *ledger.AccountingPeriod) *ledgerpb.AccountingPeriod {
+	period := &ledgerpb.AccountingPeriod{
+		Id:           p.ID,
+		Name:         p.Name,
+		StartsOn:     p.StartsOn,
+		EndsOn:       p.EndsOn,
+		Status:       p.Status,
+		CreatedAt:    p.CreatedAt,
+		CreatedBy:    p.CreatedBy,
+		SoftClosedAt: p.SoftClosedAt,
+		SoftClosedBy: p.SoftClosedBy,
+		HardClosedAt: p.HardClosedAt,
+		HardClosedBy: p.HardClosedBy,
+	}
+	for _, b := range p.ClosingBalances {
+		period.ClosingBalances = append(period.ClosingBalances, &ledgerpb.PeriodClosingBalance{
+			AccountId:     b.AccountID,
+			AccountNumber: b.AccountNumber,
+			AccountType:   b.AccountType,
+			Balance:       toProtoMoney(b.Balance),
+		})
+	}
+	return period
+}
+
+func toProtoBatch(b *ledger.PostingBatch) *ledgerpb.PostingBatch {
+	return &ledgerpb.PostingBatch{
+		Id:          b.ID,
+		Reference:   b.Reference,
+		Status:      b.Status,
+		LineCount:   int32(b.LineCount),
+		PostedCount: int32(b.PostedCount),
+		FailedCount: int32(b.FailedCount),
+		CreatedBy:   b.CreatedBy,
+		CreatedAt:   b.CreatedAt,
+		CompletedAt: b.CompletedAt,
+	}
+}
+
+func toProtoBatchLine(l ledger.BatchLine) *ledgerpb.BatchLine {
+	return &ledgerpb.BatchLine{
+		LineNumber:    int32(l.LineNumber),
+		TransactionId: l.TransactionID,
+		Status:        l.Status,
+		Error:         l.Error,
+		PostedAt:      l.PostedAt,
+	}
+}
+
+func toProtoCreditLimit(l *ledger.CreditLimit) *ledgerpb.CreditLimit {
+	limit := &ledgerpb.CreditLimit{
+		Id:             l.ID,
+		AccountId:      l.AccountID,
+		OverdraftLimit: toProtoMoney(l.OverdraftLimit),
+		EffectiveFrom:  l.EffectiveFrom,
+		Reason:         l.Reason,
+		SetBy:          l.SetBy,
+		CreatedAt:      l.CreatedAt,
+	}
+	if l.MaxTransactionAmount != nil {
+		limit.MaxTransactionAmount = toProtoMoney(*l.MaxTransactionAmount)
+	}
+	return limit
+}
+
+func toProtoAccount(a *ledger.Account) *ledgerpb.Account {
+	return &ledgerpb.Account{
+		Id:                  a.ID,
+		AccountNumber:       a.AccountNumber,
+		AccountType:         a.AccountType,
+		Name:                a.Name,
+		CurrencyCode:        a.CurrencyCode,
+		IsActive:            a.IsActive,
+		CreatedAt:           a.CreatedAt,
+		CreatedBy:           a.CreatedBy,
+		Metadata:            toProtoMetadata(a.Metadata),
+		CurrentBalanceMoney: toProtoMoney(a.CurrentBalance),
+		Status:              a.Status,
+		ParentId:            a.ParentID,
+	}
+}
+
+func toProtoAccountNode(n *ledger.AccountNode) *ledgerpb.AccountNode {
+	node := &ledgerpb.AccountNode{
+		Account:       toProtoAccount(n.Account),
+		Depth:         int32(n.Depth),
+		RollupBalance: toProtoMoney(n.RollupBalance),
+	}
+	for _, child := range n.Children {
+		node.Children = append(node.Children, toProtoAccountNode(child))
+	}
+	return node
+}
+
+func toProtoSnapshot(s *ledger.BalanceSnapshot) *ledgerpb.BalanceSnapshot {
+	return &ledgerpb.BalanceSnapshot{
+		Id:                 s.ID,
+		AccountId:          s.AccountID,
+		TransactionId:      s.TransactionID,
+		SnapshotTime:       s.SnapshotTime,
+		BalanceBeforeMoney: toProtoMoney(s.BalanceBefore),
+		BalanceAfterMoney:  toProtoMoney(s.BalanceAfter),
+		BalanceChangeMoney: toProtoMoney(s.BalanceChange),
+		AccountType:        s.AccountType,
+		CurrencyCode:       s.CurrencyCode,
+		EntryId:            s.EntryID,
+		EntryType:          s.EntryType,
+		AmountMoney:        toProtoMoney(s.Amount),
+		Description:        s.Description,
+		ReferenceType:      s.ReferenceType,
+		ReferenceId:        s.ReferenceID,
+		CreatedAt:          s.CreatedAt,
+	}
+}
+
+func toProtoEvent(e outbox.Event) *ledgerpb.LedgerEvent {
+	return &ledgerpb.LedgerEvent{
+		Sequence:      e.Sequence,
+		Id:            e.ID,
+		EventType:     e.EventType,
+		AggregateType: e.AggregateType,
+		AggregateId:   e.AggregateID,
+		Payload:       string(e.Payload),
+		CreatedAt:     e.CreatedAt.UTC().Format(time.RFC3339Nano),
+	}
+}
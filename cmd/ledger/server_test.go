@@ -0,0 +1,311 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	ledgerpb "github.com/example/pci-infra/api/gen/ledger"
+	moneypb "github.com/example/pci-infra/api/gen/money"
+	"github.com/example/pci-infra/internal/ledger"
+	"github.com/example/pci-infra/internal/money"
+	"github.com/example/pci-infra/internal/outbox"
+)
+
+func TestToStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want codes.Code
+	}{
+		{"invalid request", fmt.Errorf("%w: account ID is required", ledger.ErrInvalidRequest), codes.InvalidArgument},
+		{"unbalanced", fmt.Errorf("%w: USD debits 1.00 != credits 2.00", ledger.ErrUnbalancedTransaction), codes.InvalidArgument},
+		{"currency mismatch", fmt.Errorf("failed to post: %w", money.ErrCurrencyMismatch), codes.InvalidArgument},
+		{"not found", fmt.Errorf("failed to post journal entry: %w", fmt.Errorf("%w: acc-1", ledger.ErrAccountNotFound)), codes.NotFound},
+		{"exists", fmt.Errorf("%w: account number A1", ledger.ErrAccountExists), codes.AlreadyExists},
+		{"inactive", fmt.Errorf("%w: acc-1", ledger.ErrAccountInactive), codes.FailedPrecondition},
+		{"insufficient funds", fmt.Errorf("failed to transfer: %w", ledger.ErrInsufficientFunds), codes.FailedPrecondition},
+		{"pending not found", fmt.Errorf("failed to capture: %w", ledger.ErrPendingNotFound), codes.NotFound},
+		{"pending resolved", fmt.Errorf("%w: p-1 is posted", ledger.ErrPendingResolved), codes.FailedPrecondition},
+		{"pending expired", fmt.Errorf("%w: p-1", ledger.ErrPendingExpired), codes.FailedPrecondition},
+		{"transaction not found", fmt.Errorf("failed to reverse: %w", ledger.ErrTransactionNotFound), codes.NotFound},
+		{"already reversed", fmt.Errorf("%w: txn-1 by rev-1", ledger.ErrAlreadyReversed), codes.FailedPrecondition},
+		{"fx rate not found", fmt.Errorf("%w: USD/EUR", ledger.ErrFXRateNotFound), codes.NotFound},
+		{"fx not configured", fmt.Errorf("%w: JPY", ledger.ErrFXNotConfigured), codes.FailedPrecondition},
+		{"quote off market", fmt.Errorf("%w: 1.2000000000 vs 0.9200000000", ledger.ErrQuoteOffMarket), codes.FailedPrecondition},
+		{"account frozen", fmt.Errorf("failed to post transaction: %w: acc-1", ledger.ErrAccountFrozen), codes.FailedPrecondition},
+		{"account closed", fmt.Errorf("%w: acc-1", ledger.ErrAccountClosed), codes.FailedPrecondition},
+		{"status unchanged", fmt.Errorf("%w: acc-1 is already frozen", ledger.ErrInvalidStatusTransition), codes.FailedPrecondition},
+		{"account not empty", fmt.Errorf("%w: acc-1 has a non-zero balance", ledger.ErrAccountNotEmpty), codes.FailedPrecondition},
+		{"period not found", fmt.Errorf("%w: p-1", ledger.ErrPeriodNotFound), codes.NotFound},
+		{"period soft closed", fmt.Errorf("failed to post transaction: %w: 2024-01", ledger.ErrPeriodSoftClosed), codes.FailedPrecondition},
+		{"period hard closed", fmt.Errorf("%w: 2024-01", ledger.ErrPeriodHardClosed), codes.FailedPrecondition},
+		{"period transition", fmt.Errorf("%w: 2024-01 is already soft_closed", ledger.ErrInvalidPeriodTransition), codes.FailedPrecondition},
+		{"retained earnings", fmt.Errorf("%w: USD", ledger.ErrRetainedEarningsNotConfigured), codes.FailedPrecondition},
+		{"batch not found", fmt.Errorf("%w: b-1", ledger.ErrBatchNotFound), codes.NotFound},
+		{"batch exists", fmt.Errorf("%w: settle-2024-01-02", ledger.ErrBatchExists), codes.AlreadyExists},
+		{"invalid batch", &ledger.BatchError{}, codes.InvalidArgument},
+		{"transaction limit", fmt.Errorf("failed to post transaction: %w: account acc-1", ledger.ErrTransactionLimitExceeded), codes.FailedPrecondition},
+		{"credit limit not found", fmt.Errorf("%w: acc-1", ledger.ErrCreditLimitNotFound), codes.NotFound},
+		{"serialization failure", fmt.Errorf("failed to transfer: %w", &pgconn.PgError{Code: "40001"}), codes.Aborted},
+		{"deadline", fmt.Errorf("failed to query: %w", context.DeadlineExceeded), codes.DeadlineExceeded},
+		{"unknown", errors.New("connection refused to 10.0.0.5"), codes.Internal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := status.Convert(toStatus(tt.err))
+			assert.Equal(t, tt.want, st.Code())
+		})
+	}
+
+	assert.NoError(t, toStatus(nil))
+	assert.Equal(t, "internal error", status.Convert(toStatus(errors.New("pq: secret detail"))).Message())
+}
+
+func TestFromProtoMoney(t *testing.T) {
+	amount, err := fromProtoMoney(&moneypb.Money{MinorUnits: 1234, CurrencyCode: "USD"})
+	require.NoError(t, err)
+	assert.Equal(t, money.MustParse("12.34", "USD"), amount)
+
+	_, err = fromProtoMoney(nil)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = fromProtoMoney(&moneypb.Money{MinorUnits: 1, CurrencyCode: "XXX1"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func peerContext(cert *x509.Certificate) context.Context {
+	state := tls.ConnectionState{}
+	if cert != nil {
+		state.VerifiedChains = [][]*x509.Certificate{{cert}}
+	}
+	return peer.NewContext(context.Background(), &peer.Peer{AuthInfo: credentials.TLSInfo{State: state}})
+}
+
+func clientCert(service string, permissions ...string) *x509.Certificate {
+	return &x509.Certificate{Subject: pkix.Name{CommonName: service, Organization: permissions}}
+}
+
+func TestRBACUnaryInterceptor(t *testing.T) {
+	var seen callerIdentity
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		seen, _ = callerFromContext(ctx)
+		return "ok", nil
+	}
+	info := func(method string) *grpc.UnaryServerInfo {
+		return &grpc.UnaryServerInfo{FullMethod: method}
+	}
+
+	// permitted
+	resp, err := rbacUnaryInterceptor(peerContext(clientCert("settlement", "ledger:write")), nil, info(ledgerpb.LedgerService_PostTransaction_FullMethodName), handler)
+	require.NoError(t, err)
+	assert.Equal(t, "ok", resp)
+	assert.Equal(t, "settlement", seen.Service)
+
+	// read permission does not grant writes
+	_, err = rbacUnaryInterceptor(peerContext(clientCert("reporting", "ledger:read")), nil, info(ledgerpb.LedgerService_Debit_FullMethodName), handler)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// closing periods needs its own permission
+	_, err = rbacUnaryInterceptor(peerContext(clientCert("settlement", "ledger:write", "ledger:read")), nil, info(ledgerpb.LedgerService_HardClosePeriod_FullMethodName), handler)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = rbacUnaryInterceptor(peerContext(clientCert("controller", "ledger:close")), nil, info(ledgerpb.LedgerService_HardClosePeriod_FullMethodName), handler)
+	assert.NoError(t, err)
+
+	// no verified client certificate
+	_, err = rbacUnaryInterceptor(peerContext(nil), nil, info(ledgerpb.LedgerService_GetBalance_FullMethodName), handler)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	// no peer at all
+	_, err = rbacUnaryInterceptor(context.Background(), nil, info(ledgerpb.LedgerService_GetBalance_FullMethodName), handler)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	// unknown methods are denied by default
+	_, err = rbacUnaryInterceptor(peerContext(clientCert("admin", "ledger:write", "ledger:read")), nil, info("/ledger.LedgerService/DropTables"), handler)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+// fakeEventStream is a StreamEvents server stream that records what is sent
+type fakeEventStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	sent []*ledgerpb.LedgerEvent
+	stop context.CancelFunc
+	want int
+}
+
+func (s *fakeEventStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *fakeEventStream) Send(e *ledgerpb.LedgerEvent) error {
+	s.sent = append(s.sent, e)
+	if len(s.sent) == s.want {
+		s.stop()
+	}
+	return nil
+}
+
+type fakeEventSource struct {
+	events []outbox.Event
+}
+
+func (f *fakeEventSource) Sequence(ctx context.Context, limit int) (int, error) {
+	return 0, nil
+}
+
+func (f *fakeEventSource) Events(ctx context.Context, after int64, limit int) ([]outbox.Event, error) {
+	var out []outbox.Event
+	for _, e := range f.events {
+		if e.Sequence > after && len(out) < limit {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+func TestRBACStreamInterceptor(t *testing.T) {
+	var seen callerIdentity
+	handler := func(srv interface{}, ss grpc.ServerStream) error {
+		seen, _ = callerFromContext(ss.Context())
+		return nil
+	}
+	info := &grpc.StreamServerInfo{FullMethod: ledgerpb.LedgerService_StreamEvents_FullMethodName, IsServerStream: true}
+	stream := func(ctx context.Context) grpc.ServerStream {
+		return &fakeEventStream{ctx: ctx}
+	}
+
+	require.NoError(t, rbacStreamInterceptor(nil, stream(peerContext(clientCert("risk", "ledger:read"))), info, handler))
+	assert.Equal(t, "risk", seen.Service)
+
+	err := rbacStreamInterceptor(nil, stream(peerContext(clientCert("settlement", "ledger:write"))), info, handler)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	err = rbacStreamInterceptor(nil, stream(peerContext(nil)), info, handler)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	err = rbacStreamInterceptor(nil, stream(peerContext(clientCert("risk", "ledger:read"))), &grpc.StreamServerInfo{FullMethod: "/ledger.LedgerService/Tail"}, handler)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func TestStreamEvents(t *testing.T) {
+	created := time.Date(2024, 1, 2, 10, 30, 0, 0, time.UTC)
+	source := &fakeEventSource{events: []outbox.Event{
+		{Sequence: 1, ID: "ev-1", EventType: outbox.EventAccountCreated, AggregateType: "account", AggregateID: "acc-1", Payload: []byte(`{}`), CreatedAt: created},
+		{Sequence: 2, ID: "ev-2", EventType: outbox.EventTransactionPosted, AggregateType: "transaction", AggregateID: "txn-1", Payload: []byte(`{"transaction_id":"txn-1"}`), CreatedAt: created},
+		{Sequence: 3, ID: "ev-3", EventType: outbox.EventTransactionPosted, AggregateType: "transaction", AggregateID: "txn-2", Payload: []byte(`{}`), CreatedAt: created},
+	}}
+	s := newServer(nil, source)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	stream := &fakeEventStream{ctx: ctx, stop: cancel, want: 2}
+	err := s.StreamEvents(&ledgerpb.StreamEventsRequest{AfterSequence: 1, EventTypes: []string{outbox.EventTransactionPosted}}, stream)
+	require.NoError(t, err)
+
+	require.Len(t, stream.sent, 2)
+	assert.Equal(t, int64(2), stream.sent[0].Sequence)
+	assert.Equal(t, "txn-1", stream.sent[0].AggregateId)
+	assert.Equal(t, `{"transaction_id":"txn-1"}`, stream.sent[0].Payload)
+	assert.Equal(t, "2024-01-02T10:30:00Z", stream.sent[0].CreatedAt)
+	assert.Equal(t, "ev-3", stream.sent[1].Id)
+
+	err = newServer(nil, nil).StreamEvents(&ledgerpb.StreamEventsRequest{}, stream)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+}
+
+type fakeImportStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	msgs []*ledgerpb.ImportBatchRequest
+}
+
+func (s *fakeImportStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *fakeImportStream) Recv() (*ledgerpb.ImportBatchRequest, error) {
+	if len(s.msgs) == 0 {
+		return nil, io.EOF
+	}
+	msg := s.msgs[0]
+	s.msgs = s.msgs[1:]
+	return msg, nil
+}
+
+func (s *fakeImportStream) SendAndClose(b *ledgerpb.PostingBatch) error {
+	return errors.New("unexpected batch")
+}
+
+func TestImportBatch(t *testing.T) {
+	s := newServer(ledger.NewLedgerService(nil), nil)
+	ctx := context.WithValue(context.Background(), callerKey{}, callerIdentity{Service: "settlement"})
+	leg := func(account, entryType string) *ledgerpb.JournalEntryLeg {
+		return &ledgerpb.JournalEntryLeg{AccountId: account, EntryType: entryType, Amount: &moneypb.Money{MinorUnits: 500, CurrencyCode: "USD"}}
+	}
+	valid := &ledgerpb.PostTransactionRequest{
+		TransactionId: "8c3f2a1e-5b7d-4e9a-9f10-2d6c8b4a7e01",
+		Entries:       []*ledgerpb.JournalEntryLeg{leg("acc-1", "debit"), leg("acc-2", "credit")},
+	}
+	unbalanced := &ledgerpb.PostTransactionRequest{
+		TransactionId: "8c3f2a1e-5b7d-4e9a-9f10-2d6c8b4a7e02",
+		Entries:       []*ledgerpb.JournalEntryLeg{leg("acc-1", "debit")},
+	}
+
+	t.Run("mixed input", func(t *testing.T) {
+		err := s.ImportBatch(&fakeImportStream{ctx: ctx, msgs: []*ledgerpb.ImportBatchRequest{
+			{Reference: "settle-1", Transactions: []*ledgerpb.PostTransactionRequest{valid}},
+			{Content: []byte(`{"transaction_id":"t"}`)},
+		}})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("invalid lines", func(t *testing.T) {
+		err := s.ImportBatch(&fakeImportStream{ctx: ctx, msgs: []*ledgerpb.ImportBatchRequest{
+			{Reference: "settle-1", Transactions: []*ledgerpb.PostTransactionRequest{valid}},
+			{Transactions: []*ledgerpb.PostTransactionRequest{unbalanced, valid}},
+		}})
+		st := status.Convert(err)
+		require.Equal(t, codes.InvalidArgument, st.Code())
+		require.Len(t, st.Details(), 1)
+		br, ok := st.Details()[0].(*errdetails.BadRequest)
+		require.True(t, ok)
+		require.Len(t, br.FieldViolations, 2)
+		assert.Equal(t, "transactions[1]", br.FieldViolations[0].Field)
+		assert.Equal(t, "transactions[2]", br.FieldViolations[1].Field)
+		assert.Contains(t, br.FieldViolations[1].Description, "repeats line 1")
+	})
+
+	t.Run("malformed file", func(t *testing.T) {
+		err := s.ImportBatch(&fakeImportStream{ctx: ctx, msgs: []*ledgerpb.ImportBatchRequest{
+			{Reference: "settle-1", Content: []byte(`{"transaction_id":`)},
+			{Content: []byte(`"t"} {`)},
+		}})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		assert.Contains(t, status.Convert(err).Message(), "line 2")
+	})
+
+	t.Run("missing reference", func(t *testing.T) {
+		err := s.ImportBatch(&fakeImportStream{ctx: ctx, msgs: []*ledgerpb.ImportBatchRequest{
+			{Transactions: []*ledgerpb.PostTransactionRequest{valid}},
+		}})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
@@ -0,0 +1,48 @@
+-- Migration 013: Defer double-entry checks to commit time for multi-leg transactions
+-- The per-row BEFORE INSERT check rejected the first leg of every transaction because
+-- its counterpart legs had not been inserted yet. Running the checks as deferred
+-- constraint triggers lets all legs of a transaction be inserted in one database
+-- transaction and validated once the full set is present.
+
+BEGIN TRANSACTION;
+
+-- Validate that debits equal credits once every leg of the transaction is visible
+CREATE OR REPLACE FUNCTION validate_double_entry()
+RETURNS TRIGGER AS $$
+DECLARE
+    transaction_debits NUMERIC(20, 8);
+    transaction_credits NUMERIC(20, 8);
+BEGIN
+    -- NEW is already part of journal_entries when a deferred AFTER trigger fires
+    SELECT
+        COALESCE(SUM(CASE WHEN entry_type = 'debit' THEN amount ELSE 0 END), 0),
+        COALESCE(SUM(CASE WHEN entry_type = 'credit' THEN amount ELSE 0 END), 0)
+    INTO transaction_debits, transaction_credits
+    FROM journal_entries
+    WHERE transaction_id = NEW.transaction_id;
+
+    IF transaction_debits != transaction_credits THEN
+        RAISE EXCEPTION 'Double-entry violation: debits (%) must equal credits (%) for transaction %',
+            transaction_debits, transaction_credits, NEW.transaction_id;
+    END IF;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trigger_validate_double_entry ON journal_entries;
+CREATE CONSTRAINT TRIGGER trigger_validate_double_entry
+    AFTER INSERT ON journal_entries
+    DEFERRABLE INITIALLY DEFERRED
+    FOR EACH ROW
+    EXECUTE FUNCTION validate_double_entry();
+
+-- Every transaction must still have at least 2 entries at commit time
+DROP TRIGGER IF EXISTS trigger_validate_transaction_entries ON journal_entries;
+CREATE CONSTRAINT TRIGGER trigger_validate_transaction_entries
+    AFTER INSERT ON journal_entries
+    DEFERRABLE INITIALLY DEFERRED
+    FOR EACH ROW
+    EXECUTE FUNCTION validate_transaction_entries();
+
+COMMIT;
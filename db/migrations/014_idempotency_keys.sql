@@ -0,0 +1,34 @@
+-- Migration 014: Idempotency keys for API write endpoints
+-- Each row records the first request made with an Idempotency-Key for a client,
+-- so retries replay the stored response instead of posting twice.
+-- An in-progress row blocks the key until locked_until, which the server
+-- extends while the request is handled; only a claim that lapsed, e.g. after
+-- a crash mid-request, can be taken over.
+
+BEGIN TRANSACTION;
+
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    client_id TEXT NOT NULL,
+    idempotency_key TEXT NOT NULL,
+    request_method TEXT NOT NULL,
+    request_path TEXT NOT NULL,
+    request_fingerprint TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'in_progress' CHECK (status IN ('in_progress', 'completed')),
+    response_status INTEGER,
+    response_content_type TEXT,
+    response_body BYTEA,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP,
+    locked_until TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+
+    PRIMARY KEY (client_id, idempotency_key),
+    CONSTRAINT idempotency_keys_key_length_chk CHECK (length(idempotency_key) BETWEEN 1 AND 255),
+    CONSTRAINT idempotency_keys_completed_chk CHECK (
+        status = 'in_progress' OR (response_status IS NOT NULL AND completed_at IS NOT NULL)
+    )
+);
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
+
+COMMIT;
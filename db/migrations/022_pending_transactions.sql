@@ -0,0 +1,79 @@
+-- Migration 022: Two-phase (pending/posted) transactions
+-- An authorization reserves funds on the paying account without posting journal
+-- entries. Capturing it posts a transfer of the captured amount; voiding or
+-- expiry releases the reservation.
+
+BEGIN TRANSACTION;
+
+CREATE TABLE IF NOT EXISTS pending_transactions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    transaction_id UUID UNIQUE NOT NULL,
+    from_account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE RESTRICT,
+    to_account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE RESTRICT,
+    amount NUMERIC(20, 8) NOT NULL CHECK (amount > 0),
+    captured_amount NUMERIC(20, 8),
+    currency_code TEXT NOT NULL CHECK (length(currency_code) = 3),
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'posted', 'voided', 'expired')),
+    description TEXT NOT NULL,
+    reference_type TEXT,
+    reference_id TEXT,
+    metadata JSONB DEFAULT '{}',
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_by TEXT NOT NULL,
+    resolved_at TIMESTAMP,
+    resolved_by TEXT,
+
+    -- Constraints
+    CONSTRAINT pending_transactions_accounts_chk CHECK (from_account_id <> to_account_id),
+    CONSTRAINT pending_transactions_description_chk CHECK (length(description) > 0),
+    CONSTRAINT pending_transactions_expiry_chk CHECK (expires_at > created_at),
+    CONSTRAINT pending_transactions_captured_chk CHECK (
+        (status = 'posted') = (captured_amount IS NOT NULL)
+        AND (captured_amount IS NULL OR (captured_amount > 0 AND captured_amount <= amount))
+    ),
+    CONSTRAINT pending_transactions_resolved_chk CHECK (
+        (status = 'pending') = (resolved_at IS NULL)
+    )
+);
+
+CREATE INDEX idx_pending_transactions_from_account ON pending_transactions(from_account_id) WHERE status = 'pending';
+CREATE INDEX idx_pending_transactions_expires_at ON pending_transactions(expires_at) WHERE status = 'pending';
+CREATE INDEX idx_pending_transactions_reference ON pending_transactions(reference_type, reference_id);
+
+-- Total reserved by unexpired pending transactions; rows past expires_at stop
+-- reserving immediately, before the sweeper marks them expired
+CREATE OR REPLACE FUNCTION get_pending_amount(account_uuid UUID)
+RETURNS NUMERIC AS $$
+    SELECT COALESCE(SUM(amount), 0)
+    FROM pending_transactions
+    WHERE from_account_id = account_uuid
+    AND status = 'pending'
+    AND expires_at > CURRENT_TIMESTAMP;
+$$ LANGUAGE sql STABLE;
+
+-- Total held by active dispute holds
+CREATE OR REPLACE FUNCTION get_held_amount(account_uuid UUID)
+RETURNS NUMERIC AS $$
+    SELECT COALESCE(SUM(held_amount), 0)
+    FROM holds
+    WHERE account_id = account_uuid AND status = 'ACTIVE';
+$$ LANGUAGE sql STABLE;
+
+-- Available balance now excludes pending authorizations as well as holds
+CREATE OR REPLACE FUNCTION check_available_balance(account_uuid UUID, required_amount NUMERIC)
+RETURNS BOOLEAN AS $$
+DECLARE
+    current_balance NUMERIC;
+BEGIN
+    -- Get current account balance
+    SELECT COALESCE(balance, 0) INTO current_balance
+    FROM account_balances
+    WHERE account_id = account_uuid;
+
+    -- Check if available balance is sufficient
+    RETURN (COALESCE(current_balance, 0) - get_held_amount(account_uuid) - get_pending_amount(account_uuid)) >= required_amount;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMIT;
@@ -0,0 +1,37 @@
+-- Migration 023: Transaction reversals
+-- Journal entries are immutable, so a mistaken posting is undone by posting its
+-- mirror image. Reversal legs carry reference_type = 'reversal' and
+-- reference_id = the original transaction ID; this table records why each
+-- reversal was made and who approved it.
+
+BEGIN TRANSACTION;
+
+CREATE TABLE IF NOT EXISTS transaction_reversals (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    transaction_id UUID UNIQUE NOT NULL,
+    original_transaction_id UUID NOT NULL,
+    is_full BOOLEAN NOT NULL,
+    amount NUMERIC(20, 8),
+    currency_code TEXT CHECK (length(currency_code) = 3),
+    reason TEXT NOT NULL,
+    reversed_by TEXT NOT NULL,
+    approved_by TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    -- Constraints
+    CONSTRAINT transaction_reversals_self_chk CHECK (transaction_id <> original_transaction_id),
+    CONSTRAINT transaction_reversals_reason_chk CHECK (length(reason) > 0),
+    CONSTRAINT transaction_reversals_approver_chk CHECK (approved_by <> reversed_by),
+    CONSTRAINT transaction_reversals_amount_chk CHECK (
+        (amount IS NULL) = (currency_code IS NULL)
+        AND (amount IS NULL OR amount > 0)
+        AND (is_full OR amount IS NOT NULL)
+    )
+);
+
+-- A transaction can be reversed in full at most once; partial reversals may
+-- accumulate until they reach the original amount
+CREATE UNIQUE INDEX idx_transaction_reversals_full ON transaction_reversals(original_transaction_id) WHERE is_full;
+CREATE INDEX idx_transaction_reversals_original ON transaction_reversals(original_transaction_id);
+
+COMMIT;
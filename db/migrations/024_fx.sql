@@ -0,0 +1,165 @@
+-- Migration 024: Multi-currency FX conversion transfers
+-- Cross-currency transfers convert at the rate in fx_rates effective at
+-- posting time. Each currency posts through its own FX clearing account, so
+-- every currency in a transaction balances independently; the difference
+-- between the market rate and the rate quoted to the customer is realised as
+-- FX gain or loss in the target currency.
+
+BEGIN TRANSACTION;
+
+-- Exchange rates: one unit of base_currency buys rate units of quote_currency
+CREATE TABLE IF NOT EXISTS fx_rates (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    base_currency TEXT NOT NULL CHECK (length(base_currency) = 3),
+    quote_currency TEXT NOT NULL CHECK (length(quote_currency) = 3),
+    rate NUMERIC(20, 10) NOT NULL CHECK (rate > 0),
+    effective_at TIMESTAMP NOT NULL,
+    source TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_by TEXT NOT NULL,
+
+    -- Constraints
+    CONSTRAINT fx_rates_pair_chk CHECK (base_currency <> quote_currency),
+    CONSTRAINT fx_rates_effective_unique UNIQUE (base_currency, quote_currency, effective_at)
+);
+
+CREATE INDEX idx_fx_rates_lookup ON fx_rates(base_currency, quote_currency, effective_at DESC);
+
+-- Per-currency accounts used by FX transfers. The clearing account carries the
+-- ledger's open position in the currency and may go negative; realised gains
+-- are credited to a revenue account and losses debited to an expense account.
+CREATE TABLE IF NOT EXISTS fx_accounts (
+    currency_code TEXT PRIMARY KEY CHECK (length(currency_code) = 3),
+    clearing_account_id UUID UNIQUE NOT NULL REFERENCES accounts(id) ON DELETE RESTRICT,
+    gain_account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE RESTRICT,
+    loss_account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE RESTRICT,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_by TEXT NOT NULL
+);
+
+-- One row per posted FX transfer
+CREATE TABLE IF NOT EXISTS fx_conversions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    transaction_id UUID UNIQUE NOT NULL,
+    from_account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE RESTRICT,
+    to_account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE RESTRICT,
+    source_amount NUMERIC(20, 8) NOT NULL CHECK (source_amount > 0),
+    source_currency TEXT NOT NULL CHECK (length(source_currency) = 3),
+    target_amount NUMERIC(20, 8) NOT NULL CHECK (target_amount > 0),
+    target_currency TEXT NOT NULL CHECK (length(target_currency) = 3),
+    fx_rate_id UUID NOT NULL REFERENCES fx_rates(id) ON DELETE RESTRICT,
+    market_rate NUMERIC(20, 10) NOT NULL,
+    applied_rate NUMERIC(20, 10) NOT NULL,
+    -- Positive for a gain, negative for a loss, in target_currency
+    realised_gain_loss NUMERIC(20, 8) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_by TEXT NOT NULL,
+
+    -- Constraints
+    CONSTRAINT fx_conversions_currency_chk CHECK (source_currency <> target_currency)
+);
+
+CREATE INDEX idx_fx_conversions_created_at ON fx_conversions(created_at);
+
+-- Debits must equal credits in every currency of a transaction; totals across
+-- currencies are meaningless
+CREATE OR REPLACE FUNCTION validate_double_entry()
+RETURNS TRIGGER AS $$
+DECLARE
+    unbalanced RECORD;
+BEGIN
+    -- NEW is already part of journal_entries when a deferred AFTER trigger fires
+    SELECT
+        currency_code,
+        COALESCE(SUM(CASE WHEN entry_type = 'debit' THEN amount ELSE 0 END), 0) AS debits,
+        COALESCE(SUM(CASE WHEN entry_type = 'credit' THEN amount ELSE 0 END), 0) AS credits
+    INTO unbalanced
+    FROM journal_entries
+    WHERE transaction_id = NEW.transaction_id
+    GROUP BY currency_code
+    HAVING COALESCE(SUM(CASE WHEN entry_type = 'debit' THEN amount ELSE 0 END), 0)
+        != COALESCE(SUM(CASE WHEN entry_type = 'credit' THEN amount ELSE 0 END), 0)
+    ORDER BY currency_code
+    LIMIT 1;
+
+    IF FOUND THEN
+        RAISE EXCEPTION 'Double-entry violation: % debits (%) must equal credits (%) for transaction %',
+            unbalanced.currency_code, unbalanced.debits, unbalanced.credits, NEW.transaction_id;
+    END IF;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- How far below zero a balance may go: zero, or NULL for no floor. The
+-- non-negative check of migration 010 becomes a check against it, so FX
+-- clearing accounts, posted with no floor, may go negative.
+ALTER TABLE account_balances ADD COLUMN IF NOT EXISTS overdraft_limit NUMERIC(20, 8) DEFAULT 0;
+UPDATE account_balances SET overdraft_limit = NULL
+WHERE account_id IN (SELECT clearing_account_id FROM fx_accounts);
+ALTER TABLE account_balances DROP CONSTRAINT IF EXISTS account_balances_chk;
+ALTER TABLE account_balances ADD CONSTRAINT account_balances_chk
+    CHECK (overdraft_limit IS NULL OR balance >= -overdraft_limit);
+
+-- FX clearing accounts hold a long or short position and are exempt from the
+-- non-negative balance checks; everything else is unchanged from migration 011
+CREATE OR REPLACE FUNCTION update_account_balance_from_entry()
+RETURNS TRIGGER AS $$
+DECLARE
+    balance_change NUMERIC(20, 8);
+    current_balance NUMERIC(20, 8);
+    is_clearing BOOLEAN;
+BEGIN
+    -- Calculate balance change based on entry type and account type
+    IF NEW.entry_type = 'debit' THEN
+        -- Debits increase asset/expense, decrease liability/equity/revenue
+        CASE NEW.account_type
+            WHEN 'asset' THEN balance_change := NEW.amount;
+            WHEN 'expense' THEN balance_change := NEW.amount;
+            WHEN 'liability' THEN balance_change := -NEW.amount;
+            WHEN 'equity' THEN balance_change := -NEW.amount;
+            WHEN 'revenue' THEN balance_change := -NEW.amount;
+            ELSE RAISE EXCEPTION 'Invalid account type: %', NEW.account_type;
+        END CASE;
+    ELSE -- credit
+        -- Credits increase liability/equity/revenue, decrease asset/expense
+        CASE NEW.account_type
+            WHEN 'liability' THEN balance_change := NEW.amount;
+            WHEN 'equity' THEN balance_change := NEW.amount;
+            WHEN 'revenue' THEN balance_change := NEW.amount;
+            WHEN 'asset' THEN balance_change := -NEW.amount;
+            WHEN 'expense' THEN balance_change := -NEW.amount;
+            ELSE RAISE EXCEPTION 'Invalid account type: %', NEW.account_type;
+        END CASE;
+    END IF;
+
+    is_clearing := EXISTS (SELECT 1 FROM fx_accounts WHERE clearing_account_id = NEW.account_id);
+
+    -- Update account balance; account_balances_chk holds every other
+    -- account at or above zero
+    INSERT INTO account_balances (account_id, balance, overdraft_limit, updated_at)
+    VALUES (NEW.account_id, balance_change, CASE WHEN is_clearing THEN NULL ELSE 0 END, CURRENT_TIMESTAMP)
+    ON CONFLICT (account_id)
+    DO UPDATE SET
+        balance = account_balances.balance + balance_change,
+        overdraft_limit = EXCLUDED.overdraft_limit,
+        updated_at = CURRENT_TIMESTAMP;
+
+    IF is_clearing THEN
+        RETURN NEW;
+    END IF;
+
+    -- Validate the new balance doesn't violate account type constraints
+    SELECT balance INTO current_balance
+    FROM account_balances
+    WHERE account_id = NEW.account_id;
+
+    IF NEW.account_type IN ('asset', 'liability', 'equity', 'revenue') AND current_balance < 0 THEN
+        RAISE EXCEPTION '% account balance cannot be negative: %', initcap(NEW.account_type), current_balance;
+    END IF;
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMIT;
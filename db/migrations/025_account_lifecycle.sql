@@ -0,0 +1,80 @@
+-- Migration 025: Account lifecycle
+-- Accounts move between active, frozen (credits only), blocked (no postings)
+-- and closed (no postings, final). is_active is kept for existing readers and
+-- is true only for active accounts. Every status change is recorded with the
+-- actor and reason in account_status_transitions.
+
+BEGIN TRANSACTION;
+
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'active'
+    CHECK (status IN ('active', 'frozen', 'blocked', 'closed'));
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS closed_at TIMESTAMP;
+
+-- Inactive accounts already rejected every posting
+UPDATE accounts SET status = 'blocked' WHERE NOT is_active;
+
+ALTER TABLE accounts ADD CONSTRAINT accounts_status_active_chk CHECK (is_active = (status = 'active'));
+ALTER TABLE accounts ADD CONSTRAINT accounts_closed_at_chk CHECK ((status = 'closed') = (closed_at IS NOT NULL));
+
+CREATE INDEX idx_accounts_status ON accounts(status);
+
+-- Status history (immutable - no UPDATE/DELETE)
+CREATE TABLE IF NOT EXISTS account_status_transitions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE RESTRICT,
+    from_status TEXT NOT NULL,
+    to_status TEXT NOT NULL,
+    reason TEXT NOT NULL,
+    changed_by TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    -- Constraints
+    CONSTRAINT account_status_transitions_change_chk CHECK (from_status <> to_status),
+    CONSTRAINT account_status_transitions_reason_chk CHECK (length(reason) > 0),
+    CONSTRAINT account_status_transitions_changed_by_chk CHECK (length(changed_by) > 0)
+);
+
+CREATE INDEX idx_account_status_transitions_account ON account_status_transitions(account_id, created_at);
+
+-- Closed is final
+CREATE OR REPLACE FUNCTION prevent_reopening_account()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF OLD.status = 'closed' AND NEW.status <> 'closed' THEN
+        RAISE EXCEPTION 'Account % is closed and cannot be reopened', OLD.id;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trigger_prevent_reopening_account ON accounts;
+CREATE TRIGGER trigger_prevent_reopening_account
+    BEFORE UPDATE OF status ON accounts
+    FOR EACH ROW
+    EXECUTE FUNCTION prevent_reopening_account();
+
+-- Backstop for the service checks: frozen accounts take credits only, blocked
+-- and closed accounts take nothing
+CREATE OR REPLACE FUNCTION enforce_account_status_on_entry()
+RETURNS TRIGGER AS $$
+DECLARE
+    account_status TEXT;
+BEGIN
+    SELECT status INTO account_status FROM accounts WHERE id = NEW.account_id;
+
+    IF account_status IN ('blocked', 'closed')
+        OR (account_status = 'frozen' AND NEW.entry_type = 'debit') THEN
+        RAISE EXCEPTION 'Account % is %: % rejected', NEW.account_id, account_status, NEW.entry_type;
+    END IF;
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trigger_enforce_account_status ON journal_entries;
+CREATE TRIGGER trigger_enforce_account_status
+    BEFORE INSERT ON journal_entries
+    FOR EACH ROW
+    EXECUTE FUNCTION enforce_account_status_on_entry();
+
+COMMIT;
@@ -0,0 +1,57 @@
+-- Migration 026: Hierarchical chart of accounts
+-- Accounts may sit under a parent account of the same type and currency, so
+-- balances can be rolled up per subtree. A child's account number extends its
+-- parent's with one '-' separated segment (2100 > 2100-123 > 2100-123-PENDING).
+-- The parent is fixed at creation; moving an account would rewrite history of
+-- every roll-up it contributed to.
+
+BEGIN TRANSACTION;
+
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS parent_id UUID REFERENCES accounts(id) ON DELETE RESTRICT;
+
+ALTER TABLE accounts ADD CONSTRAINT accounts_parent_self_chk CHECK (parent_id <> id);
+
+CREATE INDEX idx_accounts_parent ON accounts(parent_id);
+
+-- Backstop for the service checks on the parent and the account-number template
+CREATE OR REPLACE FUNCTION validate_account_parent()
+RETURNS TRIGGER AS $$
+DECLARE
+    parent RECORD;
+BEGIN
+    IF TG_OP = 'UPDATE' THEN
+        IF NEW.parent_id IS DISTINCT FROM OLD.parent_id THEN
+            RAISE EXCEPTION 'Account % cannot be moved to another parent', OLD.id;
+        END IF;
+        RETURN NEW;
+    END IF;
+
+    IF NEW.parent_id IS NULL THEN
+        RETURN NEW;
+    END IF;
+
+    SELECT account_number, account_type, currency_code, status INTO parent
+    FROM accounts WHERE id = NEW.parent_id;
+
+    IF parent.status = 'closed' THEN
+        RAISE EXCEPTION 'Parent account % is closed', NEW.parent_id;
+    END IF;
+    IF parent.account_type <> NEW.account_type OR parent.currency_code <> NEW.currency_code THEN
+        RAISE EXCEPTION 'Account % must match the type and currency of parent %', NEW.account_number, parent.account_number;
+    END IF;
+    IF left(NEW.account_number, length(parent.account_number) + 1) <> parent.account_number || '-'
+        OR substr(NEW.account_number, length(parent.account_number) + 2) !~ '^[^-]+$' THEN
+        RAISE EXCEPTION 'Account number % must be % followed by one -segment', NEW.account_number, parent.account_number;
+    END IF;
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trigger_validate_account_parent ON accounts;
+CREATE TRIGGER trigger_validate_account_parent
+    BEFORE INSERT OR UPDATE OF parent_id ON accounts
+    FOR EACH ROW
+    EXECUTE FUNCTION validate_account_parent();
+
+COMMIT;
@@ -0,0 +1,106 @@
+-- Migration 027: Accounting periods
+-- Every journal entry carries the date it takes effect in the books. Periods
+-- cover a date range and move from open to soft_closed to hard_closed. Soft
+-- closed periods take adjustments only from sessions that set
+-- ledger.allow_soft_closed; hard closed periods take nothing. Closing a period
+-- rolls revenue and expense into retained earnings and snapshots every
+-- account's balance at the end of the period.
+
+BEGIN TRANSACTION;
+
+ALTER TABLE journal_entries ADD COLUMN IF NOT EXISTS effective_date DATE;
+UPDATE journal_entries SET effective_date = created_at::date WHERE effective_date IS NULL;
+ALTER TABLE journal_entries ALTER COLUMN effective_date SET DEFAULT CURRENT_DATE;
+ALTER TABLE journal_entries ALTER COLUMN effective_date SET NOT NULL;
+
+CREATE INDEX idx_journal_entries_effective_date ON journal_entries(effective_date);
+
+CREATE TABLE IF NOT EXISTS accounting_periods (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name TEXT UNIQUE NOT NULL,
+    starts_on DATE NOT NULL,
+    ends_on DATE NOT NULL,
+    status TEXT NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'soft_closed', 'hard_closed')),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_by TEXT NOT NULL,
+    soft_closed_at TIMESTAMP,
+    soft_closed_by TEXT,
+    hard_closed_at TIMESTAMP,
+    hard_closed_by TEXT,
+
+    -- Constraints
+    CONSTRAINT accounting_periods_range_chk CHECK (ends_on >= starts_on),
+    CONSTRAINT accounting_periods_name_chk CHECK (length(name) > 0),
+    CONSTRAINT accounting_periods_no_overlap EXCLUDE USING gist (daterange(starts_on, ends_on, '[]') WITH &&)
+);
+
+CREATE INDEX idx_accounting_periods_starts_on ON accounting_periods(starts_on);
+
+-- Equity account each currency's revenue and expense roll into at period close
+CREATE TABLE IF NOT EXISTS retained_earnings_accounts (
+    currency_code TEXT PRIMARY KEY CHECK (length(currency_code) = 3),
+    account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE RESTRICT,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_by TEXT NOT NULL
+);
+
+-- Balance of every account at the end of a closed period, by effective date.
+-- Refreshed when a soft closed period is hard closed.
+CREATE TABLE IF NOT EXISTS period_closing_balances (
+    period_id UUID NOT NULL REFERENCES accounting_periods(id) ON DELETE RESTRICT,
+    account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE RESTRICT,
+    account_type TEXT NOT NULL CHECK (account_type IN ('asset', 'liability', 'equity', 'revenue', 'expense')),
+    currency_code TEXT NOT NULL CHECK (length(currency_code) = 3),
+    balance NUMERIC(20, 8) NOT NULL,
+    snapshot_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (period_id, account_id)
+);
+
+-- Hard closed is final
+CREATE OR REPLACE FUNCTION prevent_reopening_period()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF OLD.status = 'hard_closed' THEN
+        RAISE EXCEPTION 'Accounting period % is hard closed', OLD.name;
+    END IF;
+    IF OLD.status = 'soft_closed' AND NEW.status = 'open' THEN
+        RAISE EXCEPTION 'Accounting period % is soft closed and cannot be reopened', OLD.name;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trigger_prevent_reopening_period ON accounting_periods;
+CREATE TRIGGER trigger_prevent_reopening_period
+    BEFORE UPDATE ON accounting_periods
+    FOR EACH ROW
+    EXECUTE FUNCTION prevent_reopening_period();
+
+-- Backstop for the service checks: no entry may take effect in a hard closed
+-- period, or in a soft closed one unless the session allows adjustments
+CREATE OR REPLACE FUNCTION enforce_accounting_period_on_entry()
+RETURNS TRIGGER AS $$
+DECLARE
+    period RECORD;
+BEGIN
+    SELECT name, status INTO period
+    FROM accounting_periods
+    WHERE NEW.effective_date BETWEEN starts_on AND ends_on;
+
+    IF period.status = 'hard_closed'
+        OR (period.status = 'soft_closed' AND current_setting('ledger.allow_soft_closed', true) IS DISTINCT FROM 'on') THEN
+        RAISE EXCEPTION 'Accounting period % is %: entry effective % rejected', period.name, period.status, NEW.effective_date;
+    END IF;
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trigger_enforce_accounting_period ON journal_entries;
+CREATE TRIGGER trigger_enforce_accounting_period
+    BEFORE INSERT ON journal_entries
+    FOR EACH ROW
+    EXECUTE FUNCTION enforce_accounting_period_on_entry();
+
+COMMIT;
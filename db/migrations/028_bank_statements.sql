@@ -0,0 +1,66 @@
+-- Migration 028: Bank statement import and reconciliation
+-- Statements from banks and processors are imported line by line against the
+-- ledger account that mirrors the external account. The matcher pairs each
+-- line with at most one journal entry: confident pairs are matched outright,
+-- the rest are suggested until an operator confirms or rejects them.
+
+BEGIN TRANSACTION;
+
+CREATE TABLE IF NOT EXISTS bank_statements (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE RESTRICT,
+    format TEXT NOT NULL CHECK (format IN ('csv', 'camt053', 'mt940')),
+    statement_reference TEXT NOT NULL,
+    currency_code TEXT NOT NULL CHECK (length(currency_code) = 3),
+    opening_balance NUMERIC(20, 8),
+    closing_balance NUMERIC(20, 8),
+    period_start DATE NOT NULL,
+    period_end DATE NOT NULL,
+    imported_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    imported_by TEXT NOT NULL,
+
+    -- Constraints
+    CONSTRAINT bank_statements_range_chk CHECK (period_end >= period_start),
+    CONSTRAINT bank_statements_reference_unique UNIQUE (account_id, statement_reference)
+);
+
+CREATE INDEX idx_bank_statements_account_id ON bank_statements(account_id);
+
+-- direction is from the statement holder's side: credit is money in
+CREATE TABLE IF NOT EXISTS bank_statement_lines (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    statement_id UUID NOT NULL REFERENCES bank_statements(id) ON DELETE CASCADE,
+    line_number INTEGER NOT NULL CHECK (line_number > 0),
+    booking_date DATE NOT NULL,
+    value_date DATE,
+    amount NUMERIC(20, 8) NOT NULL CHECK (amount > 0),
+    direction TEXT NOT NULL CHECK (direction IN ('credit', 'debit')),
+    reference TEXT NOT NULL DEFAULT '',
+    description TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL DEFAULT 'unmatched' CHECK (status IN ('unmatched', 'suggested', 'matched')),
+
+    CONSTRAINT bank_statement_lines_number_unique UNIQUE (statement_id, line_number)
+);
+
+CREATE INDEX idx_bank_statement_lines_status ON bank_statement_lines(statement_id, status);
+
+CREATE TABLE IF NOT EXISTS reconciliation_matches (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    statement_line_id UUID NOT NULL REFERENCES bank_statement_lines(id) ON DELETE CASCADE,
+    journal_entry_id UUID NOT NULL REFERENCES journal_entries(id) ON DELETE RESTRICT,
+    status TEXT NOT NULL CHECK (status IN ('matched', 'suggested', 'confirmed', 'rejected')),
+    rule TEXT NOT NULL,
+    score INTEGER NOT NULL CHECK (score BETWEEN 0 AND 100),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    decided_at TIMESTAMP,
+    decided_by TEXT
+);
+
+-- A line and a journal entry each take part in at most one live match;
+-- rejected pairs are kept so the matcher does not suggest them again
+CREATE UNIQUE INDEX idx_reconciliation_matches_line_live
+    ON reconciliation_matches(statement_line_id) WHERE status <> 'rejected';
+CREATE UNIQUE INDEX idx_reconciliation_matches_entry_live
+    ON reconciliation_matches(journal_entry_id) WHERE status <> 'rejected';
+
+COMMIT;
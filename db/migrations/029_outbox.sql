@@ -0,0 +1,32 @@
+-- Migration 029: Transactional outbox
+-- Every posted transaction, account change and dispute transition writes an
+-- event row in the same transaction as the change. sequence is left empty by
+-- the writer and filled in once the row has committed, numbering the committed
+-- rows by created_at, so a consumer reading "sequence > my offset" never misses
+-- an event that committed late. Consumers keep their offsets in outbox_offsets.
+
+BEGIN TRANSACTION;
+
+CREATE TABLE IF NOT EXISTS outbox_events (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    sequence BIGINT UNIQUE,
+    event_type TEXT NOT NULL,
+    aggregate_type TEXT NOT NULL,
+    aggregate_id TEXT NOT NULL,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    -- Constraints
+    CONSTRAINT outbox_events_sequence_chk CHECK (sequence IS NULL OR sequence > 0)
+);
+
+CREATE INDEX idx_outbox_events_unsequenced ON outbox_events(created_at, id) WHERE sequence IS NULL;
+CREATE INDEX idx_outbox_events_aggregate ON outbox_events(aggregate_type, aggregate_id);
+
+CREATE TABLE IF NOT EXISTS outbox_offsets (
+    consumer TEXT PRIMARY KEY,
+    sequence BIGINT NOT NULL DEFAULT 0 CHECK (sequence >= 0),
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+COMMIT;
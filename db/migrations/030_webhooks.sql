@@ -0,0 +1,67 @@
+-- Migration 030: Webhook subscriptions
+-- Clients subscribe HTTPS endpoints to outbox events. The dispatcher records
+-- one delivery per matching subscription and event; the deliverer sends it,
+-- signed with the subscription secret, until it succeeds or has failed
+-- max_attempts times and is dead-lettered. Every attempt is logged.
+-- Signing secrets are envelope-encrypted (AES-256-GCM under a KMS master key)
+-- with the subscription ID as additional authenticated data, so a secret
+-- copied onto another subscription fails to decrypt. Endpoints must be https;
+-- the service also refuses loopback, private and link-local targets.
+
+BEGIN TRANSACTION;
+
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    owner TEXT NOT NULL,
+    url TEXT NOT NULL CHECK (url LIKE 'https://%'),
+    description TEXT NOT NULL DEFAULT '',
+    event_types TEXT[] NOT NULL DEFAULT '{}',
+    account_ids TEXT[] NOT NULL DEFAULT '{}',
+    dispute_states TEXT[] NOT NULL DEFAULT '{}',
+    secret_ciphertext BYTEA NOT NULL,
+    secret_encrypted_key BYTEA NOT NULL,
+    secret_nonce BYTEA NOT NULL,
+    secret_key_id TEXT NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_webhook_subscriptions_owner ON webhook_subscriptions(owner);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    subscription_id UUID NOT NULL REFERENCES webhook_subscriptions(id) ON DELETE CASCADE,
+    event_id UUID NOT NULL,
+    event_sequence BIGINT NOT NULL,
+    event_type TEXT NOT NULL,
+    payload JSONB NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'delivered', 'dead_letter')),
+    attempts INTEGER NOT NULL DEFAULT 0 CHECK (attempts >= 0),
+    next_attempt_at TIMESTAMP,
+    last_status_code INTEGER,
+    last_error TEXT,
+    delivered_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    -- The outbox relay is at least once; an event is queued once per subscription
+    CONSTRAINT webhook_deliveries_event_unique UNIQUE (subscription_id, event_id)
+);
+
+CREATE INDEX idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX idx_webhook_deliveries_subscription ON webhook_deliveries(subscription_id, created_at);
+
+CREATE TABLE IF NOT EXISTS webhook_delivery_attempts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    delivery_id UUID NOT NULL REFERENCES webhook_deliveries(id) ON DELETE CASCADE,
+    attempt INTEGER NOT NULL CHECK (attempt > 0),
+    status_code INTEGER,
+    error TEXT NOT NULL DEFAULT '',
+    duration_ms BIGINT NOT NULL DEFAULT 0,
+    attempted_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_webhook_delivery_attempts_delivery ON webhook_delivery_attempts(delivery_id, attempted_at);
+
+COMMIT;
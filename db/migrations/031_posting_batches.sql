@@ -0,0 +1,46 @@
+-- Migration 031: Bulk posting batches
+-- A batch stores every line of a settlement file up front, then posts the
+-- lines in chunks, marking each line posted in the same transaction as its
+-- journal entries. A batch interrupted by a crash resumes from its first
+-- pending line; a line that cannot be posted is marked failed with the reason.
+
+BEGIN TRANSACTION;
+
+CREATE TABLE IF NOT EXISTS posting_batches (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    reference TEXT UNIQUE NOT NULL,
+    status TEXT NOT NULL DEFAULT 'posting' CHECK (status IN ('posting', 'completed')),
+    line_count INTEGER NOT NULL CHECK (line_count > 0),
+    posted_count INTEGER NOT NULL DEFAULT 0 CHECK (posted_count >= 0),
+    failed_count INTEGER NOT NULL DEFAULT 0 CHECK (failed_count >= 0),
+    -- Whether the submitter could post into soft-closed periods
+    allow_soft_closed BOOLEAN NOT NULL DEFAULT FALSE,
+    created_by TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP,
+
+    -- Constraints
+    CONSTRAINT posting_batches_counts_chk CHECK (posted_count + failed_count <= line_count),
+    CONSTRAINT posting_batches_completed_chk CHECK ((status = 'completed') = (completed_at IS NOT NULL))
+);
+
+CREATE INDEX idx_posting_batches_posting ON posting_batches(created_at) WHERE status = 'posting';
+
+CREATE TABLE IF NOT EXISTS posting_batch_lines (
+    batch_id UUID NOT NULL REFERENCES posting_batches(id) ON DELETE RESTRICT,
+    line_number INTEGER NOT NULL CHECK (line_number > 0),
+    transaction_id UUID NOT NULL,
+    -- The transaction as validated at submission, legs and all
+    payload JSONB NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'posted', 'failed')),
+    error TEXT,
+    posted_at TIMESTAMP,
+
+    PRIMARY KEY (batch_id, line_number),
+    CONSTRAINT posting_batch_lines_transaction_uniq UNIQUE (batch_id, transaction_id),
+    CONSTRAINT posting_batch_lines_error_chk CHECK ((status = 'failed') = (error IS NOT NULL))
+);
+
+CREATE INDEX idx_posting_batch_lines_pending ON posting_batch_lines(batch_id, line_number) WHERE status = 'pending';
+
+COMMIT;
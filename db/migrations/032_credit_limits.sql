@@ -0,0 +1,168 @@
+-- Migration 032: Per-account overdraft and credit limits
+-- An account's balance may fall below zero down to minus its overdraft limit,
+-- and one transaction may reduce it by at most its transaction limit. Limits
+-- are effective-dated: the limit in force is the latest one whose
+-- effective_from (UTC) has passed. Rows are never changed; setting a new
+-- limit adds a row, so the table is the audit trail. Accounts without a limit
+-- keep the old rule of no negative balance and no per-transaction maximum.
+
+BEGIN TRANSACTION;
+
+CREATE TABLE IF NOT EXISTS account_credit_limits (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE RESTRICT,
+    overdraft_limit NUMERIC(20, 8) NOT NULL DEFAULT 0 CHECK (overdraft_limit >= 0),
+    -- NULL for no per-transaction maximum
+    max_transaction_amount NUMERIC(20, 8) CHECK (max_transaction_amount > 0),
+    effective_from TIMESTAMP NOT NULL,
+    reason TEXT NOT NULL,
+    set_by TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    -- Constraints
+    CONSTRAINT account_credit_limits_effective_unique UNIQUE (account_id, effective_from),
+    CONSTRAINT account_credit_limits_reason_chk CHECK (length(reason) > 0),
+    CONSTRAINT account_credit_limits_set_by_chk CHECK (length(set_by) > 0)
+);
+
+CREATE INDEX idx_account_credit_limits_lookup ON account_credit_limits(account_id, effective_from DESC);
+
+-- Limits are history: corrections are made by setting a new limit
+CREATE OR REPLACE FUNCTION prevent_credit_limit_changes()
+RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'Credit limits are immutable; set a new limit instead';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trigger_prevent_credit_limit_changes ON account_credit_limits;
+CREATE TRIGGER trigger_prevent_credit_limit_changes
+    BEFORE UPDATE OR DELETE ON account_credit_limits
+    FOR EACH ROW
+    EXECUTE FUNCTION prevent_credit_limit_changes();
+
+-- How far below zero an account's balance may go at a point in time. NULL for
+-- FX clearing accounts, which carry an open position and have no floor.
+CREATE OR REPLACE FUNCTION get_overdraft_limit(account_uuid UUID, at TIMESTAMP)
+RETURNS NUMERIC AS $$
+    SELECT CASE
+        WHEN EXISTS (SELECT 1 FROM fx_accounts WHERE clearing_account_id = account_uuid) THEN NULL
+        ELSE COALESCE((
+            SELECT overdraft_limit
+            FROM account_credit_limits
+            WHERE account_id = account_uuid AND effective_from <= at
+            ORDER BY effective_from DESC
+            LIMIT 1
+        ), 0)
+    END;
+$$ LANGUAGE sql STABLE;
+
+-- The most one transaction may reduce an account's balance by at a point in
+-- time, NULL for no maximum
+CREATE OR REPLACE FUNCTION get_transaction_limit(account_uuid UUID, at TIMESTAMP)
+RETURNS NUMERIC AS $$
+    SELECT max_transaction_amount
+    FROM account_credit_limits
+    WHERE account_id = account_uuid AND effective_from <= at
+    ORDER BY effective_from DESC
+    LIMIT 1;
+$$ LANGUAGE sql STABLE;
+
+-- The balance floor is now the account's overdraft limit rather than zero.
+-- Each balance row carries the limit in force when it was last posted to, so
+-- the check constraint, added by migration 024, enforces the configured limit.
+ALTER TABLE account_balances ADD COLUMN IF NOT EXISTS overdraft_limit NUMERIC(20, 8) DEFAULT 0;
+UPDATE account_balances SET overdraft_limit = get_overdraft_limit(account_id, CURRENT_TIMESTAMP AT TIME ZONE 'UTC');
+ALTER TABLE account_balances DROP CONSTRAINT IF EXISTS account_balances_chk;
+ALTER TABLE account_balances ADD CONSTRAINT account_balances_chk
+    CHECK (overdraft_limit IS NULL OR balance >= -overdraft_limit);
+
+-- Replaces the non-negative balance checks of migration 024 with the
+-- account's limits; the balance arithmetic is unchanged
+CREATE OR REPLACE FUNCTION update_account_balance_from_entry()
+RETURNS TRIGGER AS $$
+DECLARE
+    balance_change NUMERIC(20, 8);
+    floor_limit NUMERIC(20, 8);
+    transaction_limit NUMERIC(20, 8);
+    outflow NUMERIC(20, 8);
+BEGIN
+    -- Calculate balance change based on entry type and account type
+    IF NEW.entry_type = 'debit' THEN
+        -- Debits increase asset/expense, decrease liability/equity/revenue
+        CASE NEW.account_type
+            WHEN 'asset' THEN balance_change := NEW.amount;
+            WHEN 'expense' THEN balance_change := NEW.amount;
+            WHEN 'liability' THEN balance_change := -NEW.amount;
+            WHEN 'equity' THEN balance_change := -NEW.amount;
+            WHEN 'revenue' THEN balance_change := -NEW.amount;
+            ELSE RAISE EXCEPTION 'Invalid account type: %', NEW.account_type;
+        END CASE;
+    ELSE -- credit
+        -- Credits increase liability/equity/revenue, decrease asset/expense
+        CASE NEW.account_type
+            WHEN 'liability' THEN balance_change := NEW.amount;
+            WHEN 'equity' THEN balance_change := NEW.amount;
+            WHEN 'revenue' THEN balance_change := NEW.amount;
+            WHEN 'asset' THEN balance_change := -NEW.amount;
+            WHEN 'expense' THEN balance_change := -NEW.amount;
+            ELSE RAISE EXCEPTION 'Invalid account type: %', NEW.account_type;
+        END CASE;
+    END IF;
+
+    floor_limit := get_overdraft_limit(NEW.account_id, CURRENT_TIMESTAMP AT TIME ZONE 'UTC');
+
+    -- account_balances_chk rejects a balance below the limit
+    INSERT INTO account_balances (account_id, balance, overdraft_limit, updated_at)
+    VALUES (NEW.account_id, balance_change, floor_limit, CURRENT_TIMESTAMP)
+    ON CONFLICT (account_id)
+    DO UPDATE SET
+        balance = account_balances.balance + balance_change,
+        overdraft_limit = EXCLUDED.overdraft_limit,
+        updated_at = CURRENT_TIMESTAMP;
+
+    IF balance_change < 0 THEN
+        transaction_limit := get_transaction_limit(NEW.account_id, CURRENT_TIMESTAMP AT TIME ZONE 'UTC');
+        IF transaction_limit IS NOT NULL THEN
+            -- Net of every leg of the transaction on this account so far
+            SELECT -SUM(CASE WHEN (entry_type = 'debit') = (account_type IN ('asset', 'expense')) THEN amount ELSE -amount END)
+            INTO outflow
+            FROM journal_entries
+            WHERE transaction_id = NEW.transaction_id AND account_id = NEW.account_id;
+
+            IF outflow > transaction_limit THEN
+                RAISE EXCEPTION 'Transaction % reduces account % by %, over its transaction limit of %',
+                    NEW.transaction_id, NEW.account_id, outflow, transaction_limit
+                    USING ERRCODE = 'check_violation';
+            END IF;
+        END IF;
+    END IF;
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Available balance now includes the overdraft limit. FX clearing accounts
+-- have no floor.
+CREATE OR REPLACE FUNCTION check_available_balance(account_uuid UUID, required_amount NUMERIC)
+RETURNS BOOLEAN AS $$
+DECLARE
+    current_balance NUMERIC;
+    floor_limit NUMERIC;
+BEGIN
+    floor_limit := get_overdraft_limit(account_uuid, CURRENT_TIMESTAMP AT TIME ZONE 'UTC');
+    IF floor_limit IS NULL THEN
+        RETURN TRUE;
+    END IF;
+
+    -- Get current account balance
+    SELECT COALESCE(balance, 0) INTO current_balance
+    FROM account_balances
+    WHERE account_id = account_uuid;
+
+    -- Check if available balance is sufficient
+    RETURN (COALESCE(current_balance, 0) + floor_limit - get_held_amount(account_uuid) - get_pending_amount(account_uuid)) >= required_amount;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMIT;
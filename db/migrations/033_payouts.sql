@@ -0,0 +1,66 @@
+-- Migration 033: Merchant payouts
+-- Each merchant's settlement funds sit in a payable (liability) account. A
+-- payout pays out what is left of it after pending authorizations, active
+-- dispute holds and the merchant's fraud reserve, when that reaches the
+-- merchant's minimum. Creating a payout posts its legs: the payable account
+-- is debited and the funding (bank) account credited. A payout that fails or
+-- is returned by the bank is reversed in the ledger.
+
+BEGIN TRANSACTION;
+
+CREATE TABLE IF NOT EXISTS merchant_payout_settings (
+    merchant_id UUID PRIMARY KEY,
+    payable_account_id UUID NOT NULL UNIQUE REFERENCES accounts(id) ON DELETE RESTRICT,
+    funding_account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE RESTRICT,
+    schedule TEXT NOT NULL CHECK (schedule IN ('daily', 'weekly', 'on_demand')),
+    -- Day of the week weekly payouts are made, 0 for Sunday
+    weekday SMALLINT CHECK (weekday BETWEEN 0 AND 6),
+    minimum_amount NUMERIC(20, 8) NOT NULL DEFAULT 0 CHECK (minimum_amount >= 0),
+    currency_code TEXT NOT NULL CHECK (length(currency_code) = 3),
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_by TEXT NOT NULL,
+
+    -- Constraints
+    CONSTRAINT merchant_payout_settings_weekday_chk CHECK ((schedule = 'weekly') = (weekday IS NOT NULL)),
+    CONSTRAINT merchant_payout_settings_accounts_chk CHECK (payable_account_id <> funding_account_id)
+);
+
+CREATE TABLE IF NOT EXISTS payouts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    merchant_id UUID NOT NULL REFERENCES merchant_payout_settings(merchant_id) ON DELETE RESTRICT,
+    payable_account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE RESTRICT,
+    funding_account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE RESTRICT,
+    amount NUMERIC(20, 8) NOT NULL CHECK (amount > 0),
+    currency_code TEXT NOT NULL CHECK (length(currency_code) = 3),
+    -- How the amount was computed
+    posted_balance NUMERIC(20, 8) NOT NULL,
+    pending_amount NUMERIC(20, 8) NOT NULL,
+    held_amount NUMERIC(20, 8) NOT NULL,
+    reserve_amount NUMERIC(20, 8) NOT NULL,
+    -- daily or weekly for scheduled payouts, on_demand for requested ones
+    trigger TEXT NOT NULL CHECK (trigger IN ('daily', 'weekly', 'on_demand')),
+    payout_date DATE NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'sent', 'paid', 'failed')),
+    transaction_id UUID NOT NULL UNIQUE,
+    reversal_id UUID,
+    bank_reference TEXT,
+    failure_reason TEXT,
+    created_by TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    sent_at TIMESTAMP,
+    paid_at TIMESTAMP,
+    failed_at TIMESTAMP,
+    updated_by TEXT NOT NULL,
+
+    -- Constraints
+    CONSTRAINT payouts_failed_chk CHECK ((status = 'failed') = (failed_at IS NOT NULL AND failure_reason IS NOT NULL))
+);
+
+-- One scheduled payout per merchant per day
+CREATE UNIQUE INDEX idx_payouts_scheduled ON payouts(merchant_id, payout_date) WHERE trigger <> 'on_demand';
+CREATE INDEX idx_payouts_merchant ON payouts(merchant_id, created_at DESC);
+CREATE INDEX idx_payouts_status ON payouts(status, created_at);
+
+COMMIT;
@@ -0,0 +1,102 @@
+-- Migration 034: Fee schedules
+-- Processing, chargeback and payout fees are charged by schedule rather than
+-- hard-coded. A schedule applies to one fee type and currency, and optionally
+-- to one merchant and/or card brand. The fee on an amount is the schedule's
+-- fixed amount plus its rate times the amount, clamped between its minimum and
+-- maximum fee. Tiers, when given, replace the fixed amount and rate for amounts
+-- up to each tier's bound. Fees are credited to the schedule's revenue account.
+--
+-- The schedule charged is the most specific one in force: merchant and brand,
+-- then merchant, then brand, then the default. Among equally specific
+-- schedules the latest effective_from (UTC) wins. Rows are never changed;
+-- a new schedule supersedes the old one, so the table is the audit trail.
+
+BEGIN TRANSACTION;
+
+CREATE TABLE IF NOT EXISTS fee_schedules (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    fee_type TEXT NOT NULL CHECK (fee_type IN ('processing', 'chargeback', 'payout')),
+    -- NULL for every merchant
+    merchant_id UUID,
+    -- NULL for every card brand
+    card_brand TEXT,
+    currency_code TEXT NOT NULL CHECK (length(currency_code) = 3),
+    fixed_amount NUMERIC(20, 8) NOT NULL DEFAULT 0 CHECK (fixed_amount >= 0),
+    rate NUMERIC(5, 4) NOT NULL DEFAULT 0 CHECK (rate >= 0),
+    -- [{"up_to": {...} | null, "fixed_amount": {...}, "rate": "0.0150"}, ...]
+    -- ordered by up_to, the last tier unbounded if up_to is null
+    tiers JSONB NOT NULL DEFAULT '[]',
+    minimum_fee NUMERIC(20, 8) CHECK (minimum_fee >= 0),
+    maximum_fee NUMERIC(20, 8) CHECK (maximum_fee >= 0),
+    revenue_account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE RESTRICT,
+    effective_from TIMESTAMP NOT NULL,
+    -- NULL for a schedule with no end date
+    effective_to TIMESTAMP,
+    created_by TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    -- Constraints
+    CONSTRAINT fee_schedules_bounds_chk CHECK (minimum_fee IS NULL OR maximum_fee IS NULL OR minimum_fee <= maximum_fee),
+    CONSTRAINT fee_schedules_effective_chk CHECK (effective_to IS NULL OR effective_to > effective_from),
+    CONSTRAINT fee_schedules_tiers_chk CHECK (jsonb_typeof(tiers) = 'array'),
+    CONSTRAINT fee_schedules_created_by_chk CHECK (length(created_by) > 0)
+);
+
+CREATE UNIQUE INDEX idx_fee_schedules_effective ON fee_schedules(
+    fee_type, COALESCE(merchant_id::text, ''), COALESCE(card_brand, ''), currency_code, effective_from
+);
+CREATE INDEX idx_fee_schedules_lookup ON fee_schedules(fee_type, currency_code, effective_from DESC);
+CREATE INDEX idx_fee_schedules_merchant ON fee_schedules(merchant_id) WHERE merchant_id IS NOT NULL;
+
+-- Schedules are history: changes are made by adding a schedule
+CREATE OR REPLACE FUNCTION prevent_fee_schedule_changes()
+RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'Fee schedules are immutable; add a new schedule instead';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trigger_prevent_fee_schedule_changes ON fee_schedules;
+CREATE TRIGGER trigger_prevent_fee_schedule_changes
+    BEFORE UPDATE OR DELETE ON fee_schedules
+    FOR EACH ROW
+    EXECUTE FUNCTION prevent_fee_schedule_changes();
+
+-- Chargeback fees were hard-coded as 2% of the disputed amount between 5 and
+-- 15 for Visa, 2.5% between 8 and 25 for Mastercard, and 10 for every other
+-- brand. They carry over as the default chargeback schedules for the main
+-- settlement currencies; other currencies charge no chargeback fee until a
+-- schedule is added.
+INSERT INTO accounts (account_number, account_type, name, currency_code, created_by)
+SELECT 'FEE-REVENUE-CHARGEBACK-' || c.code, 'revenue', 'Chargeback fee revenue (' || c.code || ')', c.code, 'migration-034'
+FROM (VALUES ('USD'), ('EUR'), ('GBP')) AS c(code)
+ON CONFLICT (account_number) DO NOTHING;
+
+INSERT INTO account_balances (account_id, balance)
+SELECT id, 0 FROM accounts
+WHERE account_number IN ('FEE-REVENUE-CHARGEBACK-USD', 'FEE-REVENUE-CHARGEBACK-EUR', 'FEE-REVENUE-CHARGEBACK-GBP')
+ON CONFLICT (account_id) DO NOTHING;
+
+INSERT INTO fee_schedules (
+    fee_type, card_brand, currency_code, fixed_amount, rate, minimum_fee, maximum_fee,
+    revenue_account_id, effective_from, created_by
+)
+SELECT 'chargeback', s.card_brand, a.currency_code, s.fixed_amount, s.rate, s.minimum_fee, s.maximum_fee,
+    a.id, TIMESTAMP '2000-01-01', 'migration-034'
+FROM accounts a
+CROSS JOIN (VALUES
+    ('VISA', 0, 0.0200, 5, 15),
+    ('MASTERCARD', 0, 0.0250, 8, 25),
+    (NULL, 10, 0, NULL, NULL)
+) AS s(card_brand, fixed_amount, rate, minimum_fee, maximum_fee)
+WHERE a.account_number IN ('FEE-REVENUE-CHARGEBACK-USD', 'FEE-REVENUE-CHARGEBACK-EUR', 'FEE-REVENUE-CHARGEBACK-GBP')
+ON CONFLICT DO NOTHING;
+
+-- Payout fees are taken out of the payout: the payable account is debited the
+-- full amount, the funding account credited the amount less the fee, and the
+-- fee schedule's revenue account credited the fee.
+ALTER TABLE payouts ADD COLUMN IF NOT EXISTS fee_amount NUMERIC(20, 8) NOT NULL DEFAULT 0 CHECK (fee_amount >= 0);
+ALTER TABLE payouts ADD COLUMN IF NOT EXISTS fee_schedule_id UUID REFERENCES fee_schedules(id) ON DELETE RESTRICT;
+ALTER TABLE payouts ADD CONSTRAINT payouts_fee_chk CHECK (fee_amount < amount AND (fee_amount = 0 OR fee_schedule_id IS NOT NULL));
+
+COMMIT;
@@ -0,0 +1,54 @@
+-- Migration 035: Dispute evidence
+-- Representment needs the receipts, delivery proofs and correspondence that
+-- back the merchant's case. Each document is stored encrypted with a
+-- per-document data key (AES-256-GCM envelope encryption); the plaintext is
+-- never written. The dispute and evidence IDs are the additional
+-- authenticated data, so a ciphertext copied onto another row fails to
+-- decrypt. sha256 is the digest of the plaintext, checked on download.
+--
+-- Evidence is submitted once and never changed or removed: a corrected
+-- document is uploaded as new evidence.
+
+BEGIN TRANSACTION;
+
+CREATE TABLE IF NOT EXISTS dispute_evidence (
+    id UUID PRIMARY KEY,
+    dispute_id UUID NOT NULL REFERENCES disputes(id) ON DELETE RESTRICT,
+    evidence_type TEXT NOT NULL CHECK (evidence_type IN ('receipt', 'delivery_proof', 'correspondence', 'other')),
+    description TEXT NOT NULL,
+    file_name TEXT NOT NULL,
+    mime_type TEXT NOT NULL,
+    size_bytes BIGINT NOT NULL CHECK (size_bytes > 0),
+    sha256 TEXT NOT NULL CHECK (length(sha256) = 64),
+    ciphertext BYTEA NOT NULL,
+    encrypted_key BYTEA NOT NULL,
+    nonce BYTEA NOT NULL,
+    key_id TEXT NOT NULL,
+    -- Masked before it is stored
+    metadata JSONB NOT NULL DEFAULT '{}',
+    uploaded_by TEXT NOT NULL,
+    uploaded_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    -- Constraints
+    CONSTRAINT dispute_evidence_description_chk CHECK (length(description) > 0),
+    CONSTRAINT dispute_evidence_uploaded_by_chk CHECK (length(uploaded_by) > 0)
+);
+
+CREATE INDEX idx_dispute_evidence_dispute ON dispute_evidence(dispute_id, uploaded_at);
+CREATE INDEX idx_dispute_evidence_key_id ON dispute_evidence(key_id);
+
+-- Submitted evidence is part of the dispute record
+CREATE OR REPLACE FUNCTION prevent_dispute_evidence_changes()
+RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'Dispute evidence is immutable once submitted; upload new evidence instead';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trigger_prevent_dispute_evidence_changes ON dispute_evidence;
+CREATE TRIGGER trigger_prevent_dispute_evidence_changes
+    BEFORE UPDATE OR DELETE ON dispute_evidence
+    FOR EACH ROW
+    EXECUTE FUNCTION prevent_dispute_evidence_changes();
+
+COMMIT;
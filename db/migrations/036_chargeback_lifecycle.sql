@@ -0,0 +1,128 @@
+-- Migration 036: Chargeback lifecycle
+-- DISPUTED is the first chargeback. The merchant accepts it (ACCEPTED) or
+-- represents with evidence (REPRESENTED); the issuer takes the representment
+-- (WON) or opens a second cycle (PRE_ARBITRATION), which the merchant accepts
+-- or escalates to network arbitration (ARBITRATION), ruled WON or LOST.
+--
+-- Every outcome releases the dispute's holds, which frees the held amount
+-- without posting anything. A merchant that loses or accepts is debited the
+-- disputed amount, credited to the currency's chargeback settlement account.
+
+BEGIN TRANSACTION;
+
+ALTER TABLE disputes DROP CONSTRAINT IF EXISTS disputes_status_check;
+ALTER TABLE disputes ADD CONSTRAINT disputes_status_check CHECK (status IN (
+    'PENDING', 'AUTHORIZED', 'SETTLED', 'DISPUTED', 'REVERSED',
+    'REPRESENTED', 'PRE_ARBITRATION', 'ARBITRATION', 'WON', 'LOST', 'ACCEPTED'));
+
+ALTER TABLE dispute_transitions DROP CONSTRAINT IF EXISTS dispute_transitions_from_state_check;
+ALTER TABLE dispute_transitions ADD CONSTRAINT dispute_transitions_from_state_check CHECK (from_state IN (
+    'PENDING', 'AUTHORIZED', 'SETTLED', 'DISPUTED', 'REVERSED',
+    'REPRESENTED', 'PRE_ARBITRATION', 'ARBITRATION', 'WON', 'LOST', 'ACCEPTED'));
+
+ALTER TABLE dispute_transitions DROP CONSTRAINT IF EXISTS dispute_transitions_to_state_check;
+ALTER TABLE dispute_transitions ADD CONSTRAINT dispute_transitions_to_state_check CHECK (to_state IN (
+    'PENDING', 'AUTHORIZED', 'SETTLED', 'DISPUTED', 'REVERSED',
+    'REPRESENTED', 'PRE_ARBITRATION', 'ARBITRATION', 'WON', 'LOST', 'ACCEPTED'));
+
+-- Per-currency account a merchant's chargeback debit is credited to
+CREATE TABLE IF NOT EXISTS chargeback_accounts (
+    currency_code TEXT PRIMARY KEY CHECK (length(currency_code) = 3),
+    settlement_account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE RESTRICT,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_by TEXT NOT NULL
+);
+
+-- Must match AllowedTransitions in internal/disputes/state_machine.go
+CREATE OR REPLACE FUNCTION is_valid_dispute_transition(from_status TEXT, to_status TEXT)
+RETURNS BOOLEAN AS $$
+BEGIN
+    CASE from_status
+        WHEN 'PENDING' THEN
+            RETURN to_status IN ('AUTHORIZED', 'REVERSED');
+        WHEN 'AUTHORIZED' THEN
+            RETURN to_status IN ('SETTLED', 'REVERSED');
+        WHEN 'SETTLED' THEN
+            RETURN to_status IN ('DISPUTED', 'REVERSED');
+        WHEN 'DISPUTED' THEN
+            RETURN to_status IN ('REPRESENTED', 'ACCEPTED', 'REVERSED');
+        WHEN 'REPRESENTED' THEN
+            RETURN to_status IN ('PRE_ARBITRATION', 'WON');
+        WHEN 'PRE_ARBITRATION' THEN
+            RETURN to_status IN ('ARBITRATION', 'ACCEPTED', 'WON');
+        WHEN 'ARBITRATION' THEN
+            RETURN to_status IN ('WON', 'LOST');
+        ELSE
+            RETURN FALSE; -- REVERSED, WON, LOST and ACCEPTED are terminal
+    END CASE;
+END;
+$$ LANGUAGE plpgsql;
+
+-- The transition history follows the same rules as the disputes row
+CREATE OR REPLACE FUNCTION validate_transition_sequence()
+RETURNS TRIGGER AS $$
+DECLARE
+    latest_state TEXT;
+    valid BOOLEAN := FALSE;
+BEGIN
+    -- Get the latest state for this dispute
+    SELECT to_state INTO latest_state
+    FROM dispute_transitions
+    WHERE dispute_id = NEW.dispute_id AND id != NEW.id
+    ORDER BY created_at DESC, id DESC
+    LIMIT 1;
+
+    -- If no previous transition, starting state should be empty or PENDING
+    IF latest_state IS NULL THEN
+        valid := (NEW.from_state IS NULL OR NEW.from_state = '' OR NEW.from_state = 'PENDING');
+    ELSE
+        valid := is_valid_dispute_transition(latest_state, NEW.to_state);
+    END IF;
+
+    IF NOT valid THEN
+        RAISE EXCEPTION 'Invalid state transition from % to % for dispute %s',
+            latest_state, NEW.to_state, NEW.dispute_id;
+    END IF;
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Release holds on every outcome, not only on reversal. A hold is placed by
+-- inserting its row, which takes it off the available balance through
+-- get_held_amount; nothing is posted for it, so releasing it posts nothing
+-- either. (A lone credit leg back to the merchant would credit funds that
+-- were never debited, and fails the deferred double-entry check at commit.)
+CREATE OR REPLACE FUNCTION handle_dispute_resolution()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF NEW.status IN ('REVERSED', 'WON', 'LOST', 'ACCEPTED')
+       AND OLD.status NOT IN ('REVERSED', 'WON', 'LOST', 'ACCEPTED') THEN
+        UPDATE holds
+        SET status = 'RELEASED',
+            released_at = CURRENT_TIMESTAMP,
+            released_by = NEW.resolved_by
+        WHERE dispute_id = NEW.id AND status = 'ACTIVE';
+    END IF;
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Disputes in any open chargeback cycle count towards a merchant's exposure
+CREATE OR REPLACE VIEW merchant_reserve_summary AS
+SELECT
+    fr.merchant_id,
+    fr.reserve_account_id,
+    fr.reserve_percentage,
+    fr.minimum_reserve_amount,
+    fr.current_reserve_amount,
+    fr.currency_code,
+    fr.is_active,
+    (SELECT COUNT(*) FROM disputes d WHERE d.merchant_id = fr.merchant_id
+        AND d.status IN ('DISPUTED', 'REPRESENTED', 'PRE_ARBITRATION', 'ARBITRATION')) as active_disputes,
+    (SELECT COALESCE(SUM(d.disputed_amount), 0) FROM disputes d WHERE d.merchant_id = fr.merchant_id
+        AND d.status IN ('DISPUTED', 'REPRESENTED', 'PRE_ARBITRATION', 'ARBITRATION')) as total_disputed_amount
+FROM fraud_reserves fr;
+
+COMMIT;
@@ -0,0 +1,16 @@
+-- Migration 037: Dispute deadlines
+-- Each stage of the chargeback cycle has a response window set by the card
+-- network for the dispute's brand and reason code. stage_due_at is when the
+-- current stage's window closes, NULL for stages without one and once the
+-- dispute is resolved. Disputes opened before this migration have no
+-- deadline until their next transition.
+
+BEGIN TRANSACTION;
+
+ALTER TABLE disputes ADD COLUMN IF NOT EXISTS stage_entered_at TIMESTAMP;
+ALTER TABLE disputes ADD COLUMN IF NOT EXISTS stage_due_at TIMESTAMP;
+
+-- Due soon and overdue queries, and the deadline scheduler
+CREATE INDEX idx_disputes_stage_due_at ON disputes(stage_due_at) WHERE stage_due_at IS NOT NULL;
+
+COMMIT;
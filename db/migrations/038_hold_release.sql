@@ -0,0 +1,168 @@
+-- Migration 038: Hold release
+-- A dispute hold moves the held amount from the merchant's account to the
+-- currency's hold account in hold_accounts, and releasing it moves it back.
+-- Holds placed in a currency with no hold account, and holds placed before
+-- this migration, post nothing: they only reduce the available balance, as
+-- before, and their release posts nothing either.
+--
+-- Holds are released when their dispute is resolved, when they expire (by
+-- the hold sweeper) or early by an administrator. A hold is released once:
+-- release_hold locks it and does nothing unless it is still ACTIVE. Every
+-- placement, extension and release is recorded in hold_events.
+
+BEGIN TRANSACTION;
+
+-- Per-currency account held funds are moved to
+CREATE TABLE IF NOT EXISTS hold_accounts (
+    currency_code TEXT PRIMARY KEY CHECK (length(currency_code) = 3),
+    hold_account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE RESTRICT,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_by TEXT NOT NULL
+);
+
+-- The hold account a hold's amount was moved to, NULL when nothing was posted
+ALTER TABLE holds ADD COLUMN IF NOT EXISTS hold_account_id UUID REFERENCES accounts(id) ON DELETE RESTRICT;
+
+-- Hold history (immutable - no UPDATE/DELETE)
+CREATE TABLE IF NOT EXISTS hold_events (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    hold_id UUID NOT NULL REFERENCES holds(id) ON DELETE RESTRICT,
+    action TEXT NOT NULL CHECK (action IN ('placed', 'extended', 'released')),
+    previous_expires_at TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL,
+    reason TEXT NOT NULL,
+    created_by TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    -- Constraints
+    CONSTRAINT hold_events_reason_chk CHECK (length(reason) > 0),
+    CONSTRAINT hold_events_created_by_chk CHECK (length(created_by) > 0)
+);
+
+CREATE INDEX idx_hold_events_hold ON hold_events(hold_id, created_at);
+
+-- The hold sweeper's queue
+CREATE INDEX idx_holds_active_expiry ON holds(expires_at) WHERE status = 'ACTIVE';
+
+-- Posted holds have already left the balance, so only unposted holds are
+-- taken off it again
+CREATE OR REPLACE FUNCTION get_held_amount(account_uuid UUID)
+RETURNS NUMERIC AS $$
+    SELECT COALESCE(SUM(held_amount), 0)
+    FROM holds
+    WHERE account_id = account_uuid AND status = 'ACTIVE' AND hold_account_id IS NULL;
+$$ LANGUAGE sql STABLE;
+
+-- Move a hold's amount between two accounts as one balanced transaction
+CREATE OR REPLACE FUNCTION post_hold_entries(
+    p_hold holds,
+    p_from_account_id UUID,
+    p_to_account_id UUID,
+    p_entry_number TEXT,
+    p_description TEXT,
+    p_created_by TEXT
+) RETURNS VOID AS $$
+DECLARE
+    txn_id UUID := gen_random_uuid();
+BEGIN
+    INSERT INTO journal_entries (
+        entry_number, transaction_id, entry_type, account_id, account_type,
+        amount, description, reference_type, reference_id, currency_code,
+        created_by, metadata
+    )
+    SELECT p_entry_number || '-' || leg.entry_type, txn_id, leg.entry_type, a.id, a.account_type,
+        p_hold.held_amount, p_description, 'hold', p_hold.hold_id, p_hold.currency_code,
+        p_created_by, jsonb_build_object('dispute_id', p_hold.dispute_id, 'hold_id', p_hold.id)
+    FROM (VALUES ('debit', p_from_account_id), ('credit', p_to_account_id)) AS leg(entry_type, account_id)
+    JOIN accounts a ON a.id = leg.account_id;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Place a hold, moving its amount to the currency's hold account if there is one
+CREATE OR REPLACE FUNCTION create_hold_with_entries(
+    p_hold_id TEXT,
+    p_dispute_id UUID,
+    p_account_id UUID,
+    p_held_amount NUMERIC,
+    p_currency_code TEXT,
+    p_expires_at TIMESTAMP,
+    p_created_by TEXT
+) RETURNS VOID AS $$
+DECLARE
+    v_hold_account_id UUID;
+    v_hold holds;
+BEGIN
+    SELECT hold_account_id INTO v_hold_account_id
+    FROM hold_accounts
+    WHERE currency_code = p_currency_code;
+
+    INSERT INTO holds (
+        hold_id, dispute_id, account_id, held_amount, currency_code,
+        status, expires_at, created_by, prev_hold_hash, hold_account_id
+    ) VALUES (
+        p_hold_id, p_dispute_id, p_account_id, p_held_amount, p_currency_code,
+        'ACTIVE', p_expires_at, p_created_by, '', v_hold_account_id
+    ) RETURNING * INTO v_hold;
+
+    IF v_hold_account_id IS NOT NULL THEN
+        PERFORM post_hold_entries(v_hold, p_account_id, v_hold_account_id,
+            'JE-HOLD-' || p_hold_id, 'Funds held for dispute: ' || p_dispute_id, p_created_by);
+    END IF;
+
+    INSERT INTO hold_events (hold_id, action, expires_at, reason, created_by)
+    VALUES (v_hold.id, 'placed', p_expires_at, 'Dispute authorized', p_created_by);
+END;
+$$ LANGUAGE plpgsql;
+
+-- Release a hold, moving a posted hold's amount back to the merchant.
+-- Returns FALSE, doing nothing, when the hold is no longer ACTIVE.
+CREATE OR REPLACE FUNCTION release_hold(p_hold UUID, p_released_by TEXT, p_reason TEXT)
+RETURNS BOOLEAN AS $$
+DECLARE
+    v_hold holds;
+BEGIN
+    SELECT * INTO v_hold FROM holds WHERE id = p_hold FOR UPDATE;
+    IF NOT FOUND OR v_hold.status <> 'ACTIVE' THEN
+        RETURN FALSE;
+    END IF;
+
+    UPDATE holds
+    SET status = 'RELEASED',
+        released_at = CURRENT_TIMESTAMP,
+        released_by = p_released_by
+    WHERE id = p_hold;
+
+    IF v_hold.hold_account_id IS NOT NULL THEN
+        PERFORM post_hold_entries(v_hold, v_hold.hold_account_id, v_hold.account_id,
+            'JE-HOLD-RELEASE-' || v_hold.hold_id, 'Hold released: ' || p_reason, p_released_by);
+    END IF;
+
+    INSERT INTO hold_events (hold_id, action, expires_at, reason, created_by)
+    VALUES (p_hold, 'released', v_hold.expires_at, p_reason, p_released_by);
+
+    RETURN TRUE;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Resolving a dispute releases its holds through release_hold
+CREATE OR REPLACE FUNCTION handle_dispute_resolution()
+RETURNS TRIGGER AS $$
+DECLARE
+    hold_record RECORD;
+BEGIN
+    IF NEW.status IN ('REVERSED', 'WON', 'LOST', 'ACCEPTED')
+       AND OLD.status NOT IN ('REVERSED', 'WON', 'LOST', 'ACCEPTED') THEN
+        FOR hold_record IN
+            SELECT id FROM holds
+            WHERE dispute_id = NEW.id AND status = 'ACTIVE'
+        LOOP
+            PERFORM release_hold(hold_record.id, NEW.resolved_by,
+                'dispute ' || lower(NEW.status) || ': ' || NEW.dispute_id);
+        END LOOP;
+    END IF;
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMIT;
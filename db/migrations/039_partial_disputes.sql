@@ -0,0 +1,50 @@
+-- Migration 039: Partial disputes
+-- Several disputes may be opened against one journal entry, each for part of
+-- its amount, but together they may not dispute more than the entry.
+-- Reversed disputes no longer count towards the cap. Each dispute has its
+-- own hold for its disputed amount and prorated chargeback fee.
+--
+-- The service checks the cap with the journal entry locked and returns a
+-- clear error; this trigger is the backstop for any other writer.
+
+BEGIN TRANSACTION;
+
+CREATE OR REPLACE FUNCTION check_dispute_cap()
+RETURNS TRIGGER AS $$
+DECLARE
+    v_entry_amount NUMERIC;
+    v_disputed NUMERIC;
+BEGIN
+    -- Serialise disputes against the same entry
+    SELECT amount INTO v_entry_amount
+    FROM journal_entries
+    WHERE id = NEW.journal_entry_id
+    FOR UPDATE;
+
+    IF NOT FOUND THEN
+        RETURN NEW;
+    END IF;
+
+    SELECT COALESCE(SUM(disputed_amount), 0) INTO v_disputed
+    FROM disputes
+    WHERE journal_entry_id = NEW.journal_entry_id
+      AND status <> 'REVERSED'
+      AND id <> NEW.id;
+
+    IF v_disputed + NEW.disputed_amount > v_entry_amount THEN
+        RAISE EXCEPTION 'disputed amount % exceeds the % left undisputed on journal entry %',
+            NEW.disputed_amount, v_entry_amount - v_disputed, NEW.journal_entry_id
+            USING ERRCODE = 'check_violation';
+    END IF;
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trigger_check_dispute_cap
+    BEFORE INSERT OR UPDATE OF disputed_amount ON disputes
+    FOR EACH ROW
+    WHEN (NEW.status <> 'REVERSED')
+    EXECUTE FUNCTION check_dispute_cap();
+
+COMMIT;
@@ -0,0 +1,29 @@
+-- Migration 040: Account owners
+-- An account created through the API belongs to the client that created it.
+-- Webhook subscriptions only receive events for accounts their owner owns,
+-- and may only filter on those accounts. Platform accounts, and accounts
+-- created before owners were recorded, have no owner and are delivered to no
+-- subscription. The owner is fixed at creation.
+
+BEGIN TRANSACTION;
+
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS owner TEXT;
+
+CREATE INDEX idx_accounts_owner ON accounts(owner) WHERE owner IS NOT NULL;
+
+CREATE OR REPLACE FUNCTION prevent_account_owner_change()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF NEW.owner IS DISTINCT FROM OLD.owner THEN
+        RAISE EXCEPTION 'Account % cannot change owner', OLD.id;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trigger_prevent_account_owner_change
+    BEFORE UPDATE OF owner ON accounts
+    FOR EACH ROW
+    EXECUTE FUNCTION prevent_account_owner_change();
+
+COMMIT;
@@ -0,0 +1,20 @@
+-- Migration 041: Hold postings through the ledger
+-- Placing and releasing a hold now posts through the ledger service, within
+-- the dispute's transaction, so the postings meet the same account status,
+-- accounting period and credit limit checks as any other, carry an effective
+-- date and are published as transaction.posted. The functions that inserted
+-- hold entries directly are dropped, and resolving a dispute no longer
+-- releases its holds in a trigger: the service releases them with the
+-- status change. A dispute resolved by any other writer keeps its holds until
+-- the hold sweeper releases them at expiry.
+
+BEGIN TRANSACTION;
+
+DROP TRIGGER IF EXISTS trigger_handle_dispute_resolution ON disputes;
+DROP FUNCTION IF EXISTS handle_dispute_resolution();
+
+DROP FUNCTION IF EXISTS create_hold_with_entries(TEXT, UUID, UUID, NUMERIC, TEXT, TIMESTAMP, TEXT);
+DROP FUNCTION IF EXISTS release_hold(UUID, TEXT, TEXT);
+DROP FUNCTION IF EXISTS post_hold_entries(holds, UUID, UUID, TEXT, TEXT, TEXT);
+
+COMMIT;
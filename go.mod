@@ -13,6 +13,7 @@ require (
     github.com/santhosh-tekuri/jsonschema/v5 v5.3.1
     github.com/stretchr/testify v1.8.4
     golang.org/x/crypto v0.31.0
+    google.golang.org/genproto/googleapis/rpc v0.0.0-20231002182017-d307bd883b97
     google.golang.org/grpc v1.60.0
     google.golang.org/protobuf v1.31.0
 )
@@ -23,5 +24,4 @@ require (
     golang.org/x/net v0.16.0 // indirect
     golang.org/x/sys v0.13.0 // indirect
     golang.org/x/text v0.13.0 // indirect
-    google.golang.org/genproto/googleapis/rpc v0.0.0-20231002182017-d307bd883b97 // indirect
 )
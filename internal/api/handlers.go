@@ -1,7 +1,10 @@
 package api
 
 import (
+    "context"
     "encoding/json"
+    "errors"
+    "io"
     "net/http"
     "strconv"
     "time"
@@ -9,9 +12,17 @@ import (
     "github.com/go-chi/chi/v5"
     "github.com/google/uuid"
 
+    "github.com/example/pci-infra/internal/auth"
     "github.com/example/pci-infra/internal/disputes"
+    "github.com/example/pci-infra/internal/export"
+    "github.com/example/pci-infra/internal/fees"
     "github.com/example/pci-infra/internal/ledger"
+    "github.com/example/pci-infra/internal/money"
+    "github.com/example/pci-infra/internal/payouts"
+    "github.com/example/pci-infra/internal/reconciliation"
+    "github.com/example/pci-infra/internal/reporting"
     "github.com/example/pci-infra/internal/security"
+    "github.com/example/pci-infra/internal/webhooks"
 )
 
 type listAccountsResponse struct {
@@ -20,6 +31,7 @@ type listAccountsResponse struct {
 }
 
 type createAccountRequest struct {
+    ParentID      string                 `json:"parent_id"`
     AccountNumber string                 `json:"account_number"`
     AccountType   string                 `json:"account_type"`
     Name          string                 `json:"name"`
@@ -33,10 +45,15 @@ type createAccountResponse struct {
     Account       *ledger.Account `json:"account"`
 }
 
+type accountTreeResponse struct {
+    CorrelationID string              `json:"correlation_id"`
+    Tree          *ledger.AccountNode `json:"tree"`
+}
+
 type postEntryRequest struct {
     TransactionID string                 `json:"transaction_id"`
     AccountID     string                 `json:"account_id"`
-    Amount        float64                `json:"amount"`
+    Amount        json.Number            `json:"amount"`
     Description   string                 `json:"description"`
     ReferenceType string                 `json:"reference_type"`
     ReferenceID   string                 `json:"reference_id"`
@@ -46,30 +63,417 @@ type postEntryRequest struct {
 }
 
 type postEntryResponse struct {
-    CorrelationID string  `json:"correlation_id"`
-    Success       bool    `json:"success"`
-    Type          string  `json:"type"`
-    TransactionID string  `json:"transaction_id"`
-    AccountID     string  `json:"account_id"`
-    Amount        float64 `json:"amount"`
+    CorrelationID string      `json:"correlation_id"`
+    Success       bool        `json:"success"`
+    Type          string      `json:"type"`
+    TransactionID string      `json:"transaction_id"`
+    AccountID     string      `json:"account_id"`
+    Amount        money.Money `json:"amount"`
 }
 
 type balanceResponse struct {
-    CorrelationID string  `json:"correlation_id"`
-    AccountID     string  `json:"account_id"`
-    Balance       float64 `json:"balance"`
+    CorrelationID string      `json:"correlation_id"`
+    AccountID     string      `json:"account_id"`
+    Balance       money.Money `json:"balance"`
+    At            string      `json:"at,omitempty"`
+}
+
+type accountBalancesResponse struct {
+    CorrelationID string `json:"correlation_id"`
+    *ledger.AccountBalances
+}
+
+type authorizePendingRequest struct {
+    TransactionID string                 `json:"transaction_id"`
+    FromAccountID string                 `json:"from_account_id"`
+    ToAccountID   string                 `json:"to_account_id"`
+    Amount        json.Number            `json:"amount"`
+    Description   string                 `json:"description"`
+    ReferenceType string                 `json:"reference_type"`
+    ReferenceID   string                 `json:"reference_id"`
+    CurrencyCode  string                 `json:"currency_code"`
+    CreatedBy     string                 `json:"created_by"`
+    TTLSeconds    int64                  `json:"ttl_seconds"`
+    Metadata      map[string]interface{} `json:"metadata"`
+}
+
+type capturePendingRequest struct {
+    Amount       json.Number `json:"amount"`
+    CurrencyCode string      `json:"currency_code"`
+    CapturedBy   string      `json:"captured_by"`
+}
+
+type voidPendingRequest struct {
+    VoidedBy string `json:"voided_by"`
+}
+
+type pendingTransactionResponse struct {
+    CorrelationID      string                     `json:"correlation_id"`
+    PendingTransaction *ledger.PendingTransaction `json:"pending_transaction"`
+}
+
+type reverseTransactionRequest struct {
+    ReversalID   string      `json:"reversal_id"`
+    Amount       json.Number `json:"amount"`
+    CurrencyCode string      `json:"currency_code"`
+    Reason       string      `json:"reason"`
+    ReversedBy   string      `json:"reversed_by"`
+    ApprovedBy   string      `json:"approved_by"`
+}
+
+type reversalResponse struct {
+    CorrelationID string           `json:"correlation_id"`
+    Reversal      *ledger.Reversal `json:"reversal"`
+}
+
+type transactionDetailResponse struct {
+    CorrelationID string `json:"correlation_id"`
+    *ledger.TransactionDetail
+}
+
+type accountStatusRequest struct {
+    Reason    string `json:"reason"`
+    ChangedBy string `json:"changed_by"`
+}
+
+type accountStatusResponse struct {
+    CorrelationID string                          `json:"correlation_id"`
+    Transition    *ledger.AccountStatusTransition `json:"transition"`
+}
+
+type accountStatusHistoryResponse struct {
+    CorrelationID string                           `json:"correlation_id"`
+    AccountID     string                           `json:"account_id"`
+    Transitions   []ledger.AccountStatusTransition `json:"transitions"`
+}
+
+type setCreditLimitRequest struct {
+    OverdraftLimit       json.Number `json:"overdraft_limit"`
+    MaxTransactionAmount json.Number `json:"max_transaction_amount"`
+    CurrencyCode         string      `json:"currency_code"`
+    EffectiveFrom        string      `json:"effective_from"`
+    Reason               string      `json:"reason"`
+    SetBy                string      `json:"set_by"`
+}
+
+type creditLimitResponse struct {
+    CorrelationID string              `json:"correlation_id"`
+    CreditLimit   *ledger.CreditLimit `json:"credit_limit"`
+}
+
+type creditLimitsResponse struct {
+    CorrelationID string               `json:"correlation_id"`
+    AccountID     string               `json:"account_id"`
+    Limits        []ledger.CreditLimit `json:"limits"`
+}
+
+type setFXRateRequest struct {
+    BaseCurrency  string      `json:"base_currency"`
+    QuoteCurrency string      `json:"quote_currency"`
+    Rate          json.Number `json:"rate"`
+    EffectiveAt   string      `json:"effective_at"`
+    Source        string      `json:"source"`
+    CreatedBy     string      `json:"created_by"`
+}
+
+type fxRateResponse struct {
+    CorrelationID string         `json:"correlation_id"`
+    FXRate        *ledger.FXRate `json:"fx_rate"`
+}
+
+type setFXAccountsRequest struct {
+    ClearingAccountID string `json:"clearing_account_id"`
+    GainAccountID     string `json:"gain_account_id"`
+    LossAccountID     string `json:"loss_account_id"`
+    UpdatedBy         string `json:"updated_by"`
+}
+
+type fxAccountsResponse struct {
+    CorrelationID string            `json:"correlation_id"`
+    FXAccounts    ledger.FXAccounts `json:"fx_accounts"`
+}
+
+type fxTransferRequest struct {
+    TransactionID string                 `json:"transaction_id"`
+    FromAccountID string                 `json:"from_account_id"`
+    ToAccountID   string                 `json:"to_account_id"`
+    Amount        json.Number            `json:"amount"`
+    CurrencyCode  string                 `json:"currency_code"`
+    QuotedRate    json.Number            `json:"quoted_rate"`
+    Description   string                 `json:"description"`
+    ReferenceType string                 `json:"reference_type"`
+    ReferenceID   string                 `json:"reference_id"`
+    CreatedBy     string                 `json:"created_by"`
+    Metadata      map[string]interface{} `json:"metadata"`
+}
+
+type fxConversionResponse struct {
+    CorrelationID string               `json:"correlation_id"`
+    Conversion    *ledger.FXConversion `json:"conversion"`
+}
+
+type statementResponse struct {
+    CorrelationID string `json:"correlation_id"`
+    *ledger.Statement
+}
+
+type trialBalanceResponse struct {
+    CorrelationID string `json:"correlation_id"`
+    *reporting.TrialBalance
+}
+
+type balanceSheetResponse struct {
+    CorrelationID string `json:"correlation_id"`
+    *reporting.BalanceSheet
+}
+
+type incomeStatementResponse struct {
+    CorrelationID string `json:"correlation_id"`
+    *reporting.IncomeStatement
+}
+
+type transferRequest struct {
+    TransactionID string                 `json:"transaction_id"`
+    FromAccountID string                 `json:"from_account_id"`
+    ToAccountID   string                 `json:"to_account_id"`
+    Amount        json.Number            `json:"amount"`
+    Description   string                 `json:"description"`
+    ReferenceType string                 `json:"reference_type"`
+    ReferenceID   string                 `json:"reference_id"`
+    CurrencyCode  string                 `json:"currency_code"`
+    CreatedBy     string                 `json:"created_by"`
+    Metadata      map[string]interface{} `json:"metadata"`
+}
+
+type transferResponse struct {
+    CorrelationID string      `json:"correlation_id"`
+    Success       bool        `json:"success"`
+    TransactionID string      `json:"transaction_id"`
+    FromAccountID string      `json:"from_account_id"`
+    ToAccountID   string      `json:"to_account_id"`
+    Amount        money.Money `json:"amount"`
+}
+
+type transactionLegRequest struct {
+    AccountID    string                 `json:"account_id"`
+    EntryType    string                 `json:"entry_type"`
+    Amount       json.Number            `json:"amount"`
+    CurrencyCode string                 `json:"currency_code"`
+    Description  string                 `json:"description"`
+    Metadata     map[string]interface{} `json:"metadata"`
+}
+
+type postTransactionRequest struct {
+    TransactionID string                  `json:"transaction_id"`
+    Description   string                  `json:"description"`
+    ReferenceType string                  `json:"reference_type"`
+    ReferenceID   string                  `json:"reference_id"`
+    EffectiveDate string                  `json:"effective_date"`
+    CreatedBy     string                  `json:"created_by"`
+    Metadata      map[string]interface{}  `json:"metadata"`
+    Entries       []transactionLegRequest `json:"entries"`
+}
+
+type postTransactionResponse struct {
+    CorrelationID string              `json:"correlation_id"`
+    Transaction   *ledger.Transaction `json:"transaction"`
+}
+
+type createPeriodRequest struct {
+    Name      string `json:"name"`
+    StartsOn  string `json:"starts_on"`
+    EndsOn    string `json:"ends_on"`
+    CreatedBy string `json:"created_by"`
+}
+
+type closePeriodRequest struct {
+    TransactionID string `json:"transaction_id"`
+    ClosedBy      string `json:"closed_by"`
+}
+
+type periodResponse struct {
+    CorrelationID string                   `json:"correlation_id"`
+    Period        *ledger.AccountingPeriod `json:"period"`
+}
+
+type listPeriodsResponse struct {
+    CorrelationID string                    `json:"correlation_id"`
+    Periods       []ledger.AccountingPeriod `json:"periods"`
+}
+
+type setRetainedEarningsRequest struct {
+    AccountID string `json:"account_id"`
+    UpdatedBy string `json:"updated_by"`
+}
+
+type retainedEarningsResponse struct {
+    CorrelationID    string                         `json:"correlation_id"`
+    RetainedEarnings ledger.RetainedEarningsAccount `json:"retained_earnings"`
+}
+
+type importStatementRequest struct {
+    AccountID  string `json:"account_id"`
+    Format     string `json:"format"`
+    Content    string `json:"content"`
+    Reference  string `json:"reference"`
+    ImportedBy string `json:"imported_by"`
+}
+
+type importStatementResponse struct {
+    CorrelationID string                    `json:"correlation_id"`
+    Statement     *reconciliation.Statement `json:"statement"`
+}
+
+type reconciliationResponse struct {
+    CorrelationID string `json:"correlation_id"`
+    *reconciliation.Result
+}
+
+type decideMatchRequest struct {
+    DecidedBy string `json:"decided_by"`
+}
+
+type matchResponse struct {
+    CorrelationID string                `json:"correlation_id"`
+    Match         *reconciliation.Match `json:"match"`
+}
+
+type webhookRequest struct {
+    URL           string   `json:"url"`
+    Description   string   `json:"description"`
+    EventTypes    []string `json:"event_types"`
+    AccountIDs    []string `json:"account_ids"`
+    DisputeStates []string `json:"dispute_states"`
+    Active        bool     `json:"active"`
+}
+
+func (req webhookRequest) filter() webhooks.Filter {
+    return webhooks.Filter{EventTypes: req.EventTypes, AccountIDs: req.AccountIDs, DisputeStates: req.DisputeStates}
+}
+
+type webhookResponse struct {
+    CorrelationID string                 `json:"correlation_id"`
+    Subscription  *webhooks.Subscription `json:"subscription"`
+}
+
+type listWebhooksResponse struct {
+    CorrelationID string                  `json:"correlation_id"`
+    Subscriptions []webhooks.Subscription `json:"subscriptions"`
+}
+
+type webhookDeliveryResponse struct {
+    CorrelationID string             `json:"correlation_id"`
+    Delivery      *webhooks.Delivery `json:"delivery"`
+}
+
+type listWebhookDeliveriesResponse struct {
+    CorrelationID string              `json:"correlation_id"`
+    Deliveries    []webhooks.Delivery `json:"deliveries"`
+}
+
+type payoutSettingsRequest struct {
+    PayableAccountID string      `json:"payable_account_id"`
+    FundingAccountID string      `json:"funding_account_id"`
+    Schedule         string      `json:"schedule"`
+    Weekday          *int        `json:"weekday"`
+    MinimumAmount    json.Number `json:"minimum_amount"`
+    CurrencyCode     string      `json:"currency_code"`
+    Active           bool        `json:"active"`
+    UpdatedBy        string      `json:"updated_by"`
+}
+
+type payoutSettingsResponse struct {
+    CorrelationID string            `json:"correlation_id"`
+    Settings      *payouts.Settings `json:"settings"`
+}
+
+type payableResponse struct {
+    CorrelationID string               `json:"correlation_id"`
+    Payable       *payouts.Computation `json:"payable"`
+}
+
+type requestPayoutRequest struct {
+    MerchantID string `json:"merchant_id"`
+    CreatedBy  string `json:"created_by"`
+}
+
+type payoutTransitionRequest struct {
+    BankReference string `json:"bank_reference"`
+    Reason        string `json:"reason"`
+    ChangedBy     string `json:"changed_by"`
+}
+
+type payoutResponse struct {
+    CorrelationID string          `json:"correlation_id"`
+    Payout        *payouts.Payout `json:"payout"`
+}
+
+type listPayoutsResponse struct {
+    CorrelationID string           `json:"correlation_id"`
+    Payouts       []payouts.Payout `json:"payouts"`
+}
+
+type feeTierRequest struct {
+    UpTo        json.Number `json:"up_to"`
+    FixedAmount json.Number `json:"fixed_amount"`
+    Rate        json.Number `json:"rate"`
+}
+
+type createFeeScheduleRequest struct {
+    FeeType          string           `json:"fee_type"`
+    MerchantID       string           `json:"merchant_id"`
+    CardBrand        string           `json:"card_brand"`
+    CurrencyCode     string           `json:"currency_code"`
+    FixedAmount      json.Number      `json:"fixed_amount"`
+    Rate             json.Number      `json:"rate"`
+    Tiers            []feeTierRequest `json:"tiers"`
+    MinimumFee       json.Number      `json:"minimum_fee"`
+    MaximumFee       json.Number      `json:"maximum_fee"`
+    RevenueAccountID string           `json:"revenue_account_id"`
+    EffectiveFrom    string           `json:"effective_from"`
+    EffectiveTo      string           `json:"effective_to"`
+    CreatedBy        string           `json:"created_by"`
+}
+
+type feeScheduleResponse struct {
+    CorrelationID string         `json:"correlation_id"`
+    Schedule      *fees.Schedule `json:"schedule"`
+}
+
+type listFeeSchedulesResponse struct {
+    CorrelationID string          `json:"correlation_id"`
+    Schedules     []fees.Schedule `json:"schedules"`
+}
+
+type chargeFeeRequest struct {
+    FeeType        string      `json:"fee_type"`
+    MerchantID     string      `json:"merchant_id"`
+    CardBrand      string      `json:"card_brand"`
+    Amount         json.Number `json:"amount"`
+    CurrencyCode   string      `json:"currency_code"`
+    PayerAccountID string      `json:"payer_account_id"`
+    TransactionID  string      `json:"transaction_id"`
+    ReferenceID    string      `json:"reference_id"`
+    CreatedBy      string      `json:"created_by"`
+}
+
+type feeResponse struct {
+    CorrelationID string    `json:"correlation_id"`
+    Fee           *fees.Fee `json:"fee"`
 }
 
 func handleListAccounts(deps Dependencies) http.HandlerFunc {
     return func(w http.ResponseWriter, r *http.Request) {
         if deps.LedgerReader == nil {
-            security.WriteJSONError(w, r, http.StatusServiceUnavailable, "ledger_unavailable")
+            writeUnavailable(w, r, "ledger_unavailable")
             return
         }
 
         filter := ledger.AccountFilter{}
         filter.AccountType = r.URL.Query().Get("account_type")
         filter.CurrencyCode = r.URL.Query().Get("currency_code")
+        filter.Status = r.URL.Query().Get("status")
+        filter.ParentID = r.URL.Query().Get("parent_id")
 
         if v := r.URL.Query().Get("limit"); v != "" {
             if i, err := strconv.Atoi(v); err == nil {
@@ -87,6 +491,12 @@ func handleListAccounts(deps Dependencies) http.HandlerFunc {
                 filter.IsActive = &b
             }
         }
+        if v := r.URL.Query().Get("top_level"); v != "" {
+            b, err := strconv.ParseBool(v)
+            if err == nil {
+                filter.TopLevel = b
+            }
+        }
 
         accounts, err := deps.LedgerReader.ListAccounts(r.Context(), filter)
         if err != nil {
@@ -104,7 +514,7 @@ func handleListAccounts(deps Dependencies) http.HandlerFunc {
 func handleCreateAccount(deps Dependencies) http.HandlerFunc {
     return func(w http.ResponseWriter, r *http.Request) {
         if deps.LedgerWriter == nil {
-            security.WriteJSONError(w, r, http.StatusServiceUnavailable, "ledger_unavailable")
+            writeUnavailable(w, r, "ledger_unavailable")
             return
         }
 
@@ -115,136 +525,2048 @@ func handleCreateAccount(deps Dependencies) http.HandlerFunc {
         }
 
         account, err := deps.LedgerWriter.CreateAccount(r.Context(), ledger.CreateAccountRequest{
+            ParentID:      req.ParentID,
             AccountNumber: req.AccountNumber,
             AccountType:   req.AccountType,
             Name:          req.Name,
             CurrencyCode:  req.CurrencyCode,
             CreatedBy:     req.CreatedBy,
+            Owner:         requestOwner(r),
             Metadata:      req.Metadata,
         })
         if err != nil {
-            security.WriteJSONError(w, r, http.StatusBadRequest, "invalid_request")
+            writeLedgerError(w, r, err)
             return
         }
 
         writeJSON(w, r, http.StatusCreated, createAccountResponse{
             CorrelationID: security.CorrelationIDFromContext(r.Context()),
-            Account:       account,
+            Account:       account,
+        })
+    }
+}
+
+func handleDebit(deps Dependencies) http.HandlerFunc {
+    return handlePostEntry(deps, "debit")
+}
+
+func handleCredit(deps Dependencies) http.HandlerFunc {
+    return handlePostEntry(deps, "credit")
+}
+
+func handlePostEntry(deps Dependencies, entryType string) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if deps.LedgerWriter == nil {
+            writeUnavailable(w, r, "ledger_unavailable")
+            return
+        }
+
+        var req postEntryRequest
+        if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+            security.WriteJSONError(w, r, http.StatusBadRequest, "invalid_json")
+            return
+        }
+        if req.TransactionID == "" {
+            req.TransactionID = uuid.NewString()
+        }
+
+        amount, err := money.Parse(req.Amount.String(), req.CurrencyCode)
+        if err != nil {
+            security.WriteJSONError(w, r, http.StatusBadRequest, "invalid_amount")
+            return
+        }
+
+        switch entryType {
+        case "debit":
+            err := deps.LedgerWriter.Debit(r.Context(), ledger.DebitRequest{
+                TransactionRequest: ledger.TransactionRequest{
+                    TransactionID: req.TransactionID,
+                    Description:   req.Description,
+                    ReferenceType: req.ReferenceType,
+                    ReferenceID:   req.ReferenceID,
+                    CurrencyCode:  req.CurrencyCode,
+                    CreatedBy:     req.CreatedBy,
+                    Metadata:      req.Metadata,
+                },
+                AccountID:   req.AccountID,
+                Amount:      amount,
+                Description: req.Description,
+            })
+            if err != nil {
+                writeLedgerError(w, r, err)
+                return
+            }
+        case "credit":
+            err := deps.LedgerWriter.Credit(r.Context(), ledger.CreditRequest{
+                TransactionRequest: ledger.TransactionRequest{
+                    TransactionID: req.TransactionID,
+                    Description:   req.Description,
+                    ReferenceType: req.ReferenceType,
+                    ReferenceID:   req.ReferenceID,
+                    CurrencyCode:  req.CurrencyCode,
+                    CreatedBy:     req.CreatedBy,
+                    Metadata:      req.Metadata,
+                },
+                AccountID:   req.AccountID,
+                Amount:      amount,
+                Description: req.Description,
+            })
+            if err != nil {
+                writeLedgerError(w, r, err)
+                return
+            }
+        default:
+            security.WriteJSONError(w, r, http.StatusInternalServerError, "internal_error")
+            return
+        }
+
+        writeJSON(w, r, http.StatusOK, postEntryResponse{
+            CorrelationID: security.CorrelationIDFromContext(r.Context()),
+            Success:       true,
+            Type:          entryType,
+            TransactionID: req.TransactionID,
+            AccountID:     req.AccountID,
+            Amount:        amount,
+        })
+    }
+}
+
+func handleTransfer(deps Dependencies) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if deps.LedgerWriter == nil {
+            writeUnavailable(w, r, "ledger_unavailable")
+            return
+        }
+
+        var req transferRequest
+        if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+            security.WriteJSONError(w, r, http.StatusBadRequest, "invalid_json")
+            return
+        }
+        if req.TransactionID == "" {
+            req.TransactionID = uuid.NewString()
+        }
+
+        amount, err := money.Parse(req.Amount.String(), req.CurrencyCode)
+        if err != nil {
+            security.WriteJSONError(w, r, http.StatusBadRequest, "invalid_amount")
+            return
+        }
+
+        err = deps.LedgerWriter.Transfer(r.Context(), ledger.TransferRequest{
+            TransactionID: req.TransactionID,
+            FromAccountID: req.FromAccountID,
+            ToAccountID:   req.ToAccountID,
+            Amount:        amount,
+            Description:   req.Description,
+            CurrencyCode:  req.CurrencyCode,
+            CreatedBy:     req.CreatedBy,
+            ReferenceType: req.ReferenceType,
+            ReferenceID:   req.ReferenceID,
+            Metadata:      req.Metadata,
+        })
+        if err != nil {
+            writeLedgerError(w, r, err)
+            return
+        }
+
+        writeJSON(w, r, http.StatusOK, transferResponse{
+            CorrelationID: security.CorrelationIDFromContext(r.Context()),
+            Success:       true,
+            TransactionID: req.TransactionID,
+            FromAccountID: req.FromAccountID,
+            ToAccountID:   req.ToAccountID,
+            Amount:        amount,
+        })
+    }
+}
+
+func handlePostTransaction(deps Dependencies) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if deps.LedgerWriter == nil {
+            writeUnavailable(w, r, "ledger_unavailable")
+            return
+        }
+
+        var req postTransactionRequest
+        if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+            security.WriteJSONError(w, r, http.StatusBadRequest, "invalid_json")
+            return
+        }
+        if req.TransactionID == "" {
+            req.TransactionID = uuid.NewString()
+        }
+
+        entries := make([]ledger.JournalEntry, 0, len(req.Entries))
+        for _, leg := range req.Entries {
+            amount, err := money.Parse(leg.Amount.String(), leg.CurrencyCode)
+            if err != nil {
+                security.WriteJSONError(w, r, http.StatusBadRequest, "invalid_amount")
+                return
+            }
+            entries = append(entries, ledger.JournalEntry{
+                AccountID:    leg.AccountID,
+                EntryType:    leg.EntryType,
+                Amount:       amount,
+                CurrencyCode: leg.CurrencyCode,
+                Description:  leg.Description,
+                Metadata:     leg.Metadata,
+            })
+        }
+
+        txn, err := deps.LedgerWriter.PostTransaction(r.Context(), ledger.Transaction{
+            TransactionID: req.TransactionID,
+            Entries:       entries,
+            Description:   req.Description,
+            ReferenceType: req.ReferenceType,
+            ReferenceID:   req.ReferenceID,
+            EffectiveDate: req.EffectiveDate,
+            CreatedBy:     req.CreatedBy,
+            Metadata:      req.Metadata,
+        })
+        if err != nil {
+            if errors.Is(err, ledger.ErrUnbalancedTransaction) {
+                security.WriteJSONError(w, r, http.StatusUnprocessableEntity, "unbalanced_transaction")
+                return
+            }
+            writeLedgerError(w, r, err)
+            return
+        }
+
+        writeJSON(w, r, http.StatusCreated, postTransactionResponse{
+            CorrelationID: security.CorrelationIDFromContext(r.Context()),
+            Transaction:   txn,
+        })
+    }
+}
+
+func handleBalance(deps Dependencies) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if deps.LedgerReader == nil {
+            writeUnavailable(w, r, "ledger_unavailable")
+            return
+        }
+
+        accountID := r.URL.Query().Get("account_id")
+        if accountID == "" {
+            security.WriteJSONError(w, r, http.StatusBadRequest, "validation_error")
+            return
+        }
+
+        // at selects the balance as of a point in time, e.g. month-end
+        if v := r.URL.Query().Get("at"); v != "" {
+            at, err := time.Parse(time.RFC3339, v)
+            if err != nil {
+                security.WriteJSONError(w, r, http.StatusBadRequest, "validation_error")
+                return
+            }
+
+            bal, err := deps.LedgerReader.GetBalanceAt(r.Context(), accountID, at)
+            if err != nil {
+                writeLedgerError(w, r, err)
+                return
+            }
+
+            writeJSON(w, r, http.StatusOK, balanceResponse{
+                CorrelationID: security.CorrelationIDFromContext(r.Context()),
+                AccountID:     accountID,
+                Balance:       bal,
+                At:            at.UTC().Format(time.RFC3339),
+            })
+            return
+        }
+
+        bal, err := deps.LedgerReader.GetBalance(r.Context(), accountID)
+        if err != nil {
+            security.WriteJSONError(w, r, http.StatusBadRequest, "invalid_request")
+            return
+        }
+
+        writeJSON(w, r, http.StatusOK, balanceResponse{
+            CorrelationID: security.CorrelationIDFromContext(r.Context()),
+            AccountID:     accountID,
+            Balance:       bal,
+        })
+    }
+}
+
+func handleStatement(deps Dependencies) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if deps.LedgerReader == nil {
+            writeUnavailable(w, r, "ledger_unavailable")
+            return
+        }
+
+        q := r.URL.Query()
+        req := ledger.StatementRequest{
+            AccountID: chi.URLParam(r, "id"),
+            Cursor:    q.Get("cursor"),
+        }
+
+        var err error
+        if req.From, err = time.Parse(time.RFC3339, q.Get("from")); err != nil {
+            security.WriteJSONError(w, r, http.StatusBadRequest, "validation_error")
+            return
+        }
+        if req.To, err = time.Parse(time.RFC3339, q.Get("to")); err != nil {
+            security.WriteJSONError(w, r, http.StatusBadRequest, "validation_error")
+            return
+        }
+        if v := q.Get("limit"); v != "" {
+            if req.Limit, err = strconv.Atoi(v); err != nil {
+                security.WriteJSONError(w, r, http.StatusBadRequest, "validation_error")
+                return
+            }
+        }
+
+        format := q.Get("format")
+        switch format {
+        case "", "json":
+        case export.FormatCSV, export.FormatCAMT053, export.FormatOFX:
+            // Exports hold the whole period, so cursor and limit do not apply
+            stmt, err := export.Collect(r.Context(), deps.LedgerReader.GetStatement, req)
+            if err != nil {
+                writeLedgerError(w, r, err)
+                return
+            }
+            opts := export.Options{
+                MessageID:   security.CorrelationIDFromContext(r.Context()),
+                GeneratedAt: time.Now(),
+            }
+            writeDownload(w, r, export.ContentType(format), export.Filename(format, stmt), func(out io.Writer) error {
+                return export.Write(out, format, stmt, opts)
+            })
+            return
+        default:
+            security.WriteJSONError(w, r, http.StatusBadRequest, "validation_error")
+            return
+        }
+
+        stmt, err := deps.LedgerReader.GetStatement(r.Context(), req)
+        if err != nil {
+            writeLedgerError(w, r, err)
+            return
+        }
+
+        writeJSON(w, r, http.StatusOK, statementResponse{
+            CorrelationID: security.CorrelationIDFromContext(r.Context()),
+            Statement:     stmt,
+        })
+    }
+}
+
+func handleAccountBalances(deps Dependencies) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if deps.LedgerReader == nil {
+            writeUnavailable(w, r, "ledger_unavailable")
+            return
+        }
+
+        balances, err := deps.LedgerReader.GetAccountBalances(r.Context(), chi.URLParam(r, "id"))
+        if err != nil {
+            writeLedgerError(w, r, err)
+            return
+        }
+
+        writeJSON(w, r, http.StatusOK, accountBalancesResponse{
+            CorrelationID:   security.CorrelationIDFromContext(r.Context()),
+            AccountBalances: balances,
+        })
+    }
+}
+
+// handleChangeAccountStatus moves the account to status: frozen, blocked,
+// closed, or active to unfreeze it
+func handleChangeAccountStatus(deps Dependencies, status string) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if deps.LedgerWriter == nil {
+            writeUnavailable(w, r, "ledger_unavailable")
+            return
+        }
+
+        var req accountStatusRequest
+        if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+            security.WriteJSONError(w, r, http.StatusBadRequest, "invalid_json")
+            return
+        }
+
+        change := deps.LedgerWriter.UnfreezeAccount
+        switch status {
+        case ledger.AccountStatusFrozen:
+            change = deps.LedgerWriter.FreezeAccount
+        case ledger.AccountStatusBlocked:
+            change = deps.LedgerWriter.BlockAccount
+        case ledger.AccountStatusClosed:
+            change = deps.LedgerWriter.CloseAccount
+        }
+
+        transition, err := change(r.Context(), ledger.AccountStatusRequest{
+            AccountID: chi.URLParam(r, "id"),
+            Reason:    req.Reason,
+            ChangedBy: req.ChangedBy,
+        })
+        if err != nil {
+            writeLedgerError(w, r, err)
+            return
+        }
+
+        writeJSON(w, r, http.StatusOK, accountStatusResponse{
+            CorrelationID: security.CorrelationIDFromContext(r.Context()),
+            Transition:    transition,
+        })
+    }
+}
+
+func handleAccountStatusHistory(deps Dependencies) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if deps.LedgerReader == nil {
+            writeUnavailable(w, r, "ledger_unavailable")
+            return
+        }
+
+        accountID := chi.URLParam(r, "id")
+        history, err := deps.LedgerReader.GetAccountStatusHistory(r.Context(), accountID)
+        if err != nil {
+            writeLedgerError(w, r, err)
+            return
+        }
+
+        writeJSON(w, r, http.StatusOK, accountStatusHistoryResponse{
+            CorrelationID: security.CorrelationIDFromContext(r.Context()),
+            AccountID:     accountID,
+            Transitions:   history,
+        })
+    }
+}
+
+func handleSetCreditLimit(deps Dependencies) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if deps.LedgerWriter == nil {
+            writeUnavailable(w, r, "ledger_unavailable")
+            return
+        }
+
+        var req setCreditLimitRequest
+        if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+            security.WriteJSONError(w, r, http.StatusBadRequest, "invalid_json")
+            return
+        }
+
+        overdraft, err := money.Parse(req.OverdraftLimit.String(), req.CurrencyCode)
+        if err != nil {
+            security.WriteJSONError(w, r, http.StatusBadRequest, "invalid_amount")
+            return
+        }
+        // Without max_transaction_amount transactions are not capped
+        var maxAmount *money.Money
+        if req.MaxTransactionAmount != "" {
+            m, err := money.Parse(req.MaxTransactionAmount.String(), req.CurrencyCode)
+            if err != nil {
+                security.WriteJSONError(w, r, http.StatusBadRequest, "invalid_amount")
+                return
+            }
+            maxAmount = &m
+        }
+
+        // Without effective_from the limit takes effect immediately
+        var effectiveFrom time.Time
+        if req.EffectiveFrom != "" {
+            if effectiveFrom, err = time.Parse(time.RFC3339, req.EffectiveFrom); err != nil {
+                security.WriteJSONError(w, r, http.StatusBadRequest, "validation_error")
+                return
+            }
+        }
+
+        limit, err := deps.LedgerWriter.SetCreditLimit(r.Context(), ledger.SetCreditLimitRequest{
+            AccountID:            chi.URLParam(r, "id"),
+            OverdraftLimit:       overdraft,
+            MaxTransactionAmount: maxAmount,
+            EffectiveFrom:        effectiveFrom,
+            Reason:               req.Reason,
+            SetBy:                req.SetBy,
+        })
+        if err != nil {
+            writeLedgerError(w, r, err)
+            return
+        }
+
+        writeJSON(w, r, http.StatusCreated, creditLimitResponse{
+            CorrelationID: security.CorrelationIDFromContext(r.Context()),
+            CreditLimit:   limit,
+        })
+    }
+}
+
+// handleGetCreditLimit returns the limit in force at the optional at
+// parameter, or now
+func handleGetCreditLimit(deps Dependencies) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if deps.LedgerReader == nil {
+            writeUnavailable(w, r, "ledger_unavailable")
+            return
+        }
+
+        var at time.Time
+        if v := r.URL.Query().Get("at"); v != "" {
+            var err error
+            if at, err = time.Parse(time.RFC3339, v); err != nil {
+                security.WriteJSONError(w, r, http.StatusBadRequest, "validation_error")
+                return
+            }
+        }
+
+        limit, err := deps.LedgerReader.GetCreditLimit(r.Context(), chi.URLParam(r, "id"), at)
+        if err != nil {
+            writeLedgerError(w, r, err)
+            return
+        }
+
+        writeJSON(w, r, http.StatusOK, creditLimitResponse{
+            CorrelationID: security.CorrelationIDFromContext(r.Context()),
+            CreditLimit:   limit,
+        })
+    }
+}
+
+func handleListCreditLimits(deps Dependencies) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if deps.LedgerReader == nil {
+            writeUnavailable(w, r, "ledger_unavailable")
+            return
+        }
+
+        accountID := chi.URLParam(r, "id")
+        limits, err := deps.LedgerReader.ListCreditLimits(r.Context(), accountID)
+        if err != nil {
+            writeLedgerError(w, r, err)
+            return
+        }
+
+        writeJSON(w, r, http.StatusOK, creditLimitsResponse{
+            CorrelationID: security.CorrelationIDFromContext(r.Context()),
+            AccountID:     accountID,
+            Limits:        limits,
+        })
+    }
+}
+
+func handleAccountTree(deps Dependencies) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if deps.LedgerReader == nil {
+            writeUnavailable(w, r, "ledger_unavailable")
+            return
+        }
+
+        tree, err := deps.LedgerReader.GetAccountTree(r.Context(), chi.URLParam(r, "id"))
+        if err != nil {
+            writeLedgerError(w, r, err)
+            return
+        }
+
+        writeJSON(w, r, http.StatusOK, accountTreeResponse{
+            CorrelationID: security.CorrelationIDFromContext(r.Context()),
+            Tree:          tree,
+        })
+    }
+}
+
+func handleAuthorizePending(deps Dependencies) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if deps.LedgerWriter == nil {
+            writeUnavailable(w, r, "ledger_unavailable")
+            return
+        }
+
+        var req authorizePendingRequest
+        if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+            security.WriteJSONError(w, r, http.StatusBadRequest, "invalid_json")
+            return
+        }
+
+        amount, err := money.Parse(req.Amount.String(), req.CurrencyCode)
+        if err != nil {
+            security.WriteJSONError(w, r, http.StatusBadRequest, "invalid_amount")
+            return
+        }
+
+        pending, err := deps.LedgerWriter.AuthorizePending(r.Context(), ledger.AuthorizeRequest{
+            TransactionID: req.TransactionID,
+            FromAccountID: req.FromAccountID,
+            ToAccountID:   req.ToAccountID,
+            Amount:        amount,
+            Description:   req.Description,
+            ReferenceType: req.ReferenceType,
+            ReferenceID:   req.ReferenceID,
+            CreatedBy:     req.CreatedBy,
+            Metadata:      req.Metadata,
+            TTL:           time.Duration(req.TTLSeconds) * time.Second,
+        })
+        if err != nil {
+            writeLedgerError(w, r, err)
+            return
+        }
+
+        writeJSON(w, r, http.StatusCreated, pendingTransactionResponse{
+            CorrelationID:      security.CorrelationIDFromContext(r.Context()),
+            PendingTransaction: pending,
+        })
+    }
+}
+
+func handleGetPending(deps Dependencies) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if deps.LedgerReader == nil {
+            writeUnavailable(w, r, "ledger_unavailable")
+            return
+        }
+
+        pending, err := deps.LedgerReader.GetPendingTransaction(r.Context(), chi.URLParam(r, "pending_id"))
+        if err != nil {
+            writeLedgerError(w, r, err)
+            return
+        }
+
+        writeJSON(w, r, http.StatusOK, pendingTransactionResponse{
+            CorrelationID:      security.CorrelationIDFromContext(r.Context()),
+            PendingTransaction: pending,
+        })
+    }
+}
+
+func handleCapturePending(deps Dependencies) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if deps.LedgerWriter == nil {
+            writeUnavailable(w, r, "ledger_unavailable")
+            return
+        }
+
+        var req capturePendingRequest
+        if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+            security.WriteJSONError(w, r, http.StatusBadRequest, "invalid_json")
+            return
+        }
+
+        // Without an amount the full authorized amount is captured
+        var amount money.Money
+        if req.Amount != "" {
+            var err error
+            amount, err = money.Parse(req.Amount.String(), req.CurrencyCode)
+            if err != nil {
+                security.WriteJSONError(w, r, http.StatusBadRequest, "invalid_amount")
+                return
+            }
+        }
+
+        pending, err := deps.LedgerWriter.CapturePending(r.Context(), ledger.CaptureRequest{
+            PendingID:  chi.URLParam(r, "pending_id"),
+            Amount:     amount,
+            CapturedBy: req.CapturedBy,
+        })
+        if err != nil {
+            writeLedgerError(w, r, err)
+            return
+        }
+
+        writeJSON(w, r, http.StatusOK, pendingTransactionResponse{
+            CorrelationID:      security.CorrelationIDFromContext(r.Context()),
+            PendingTransaction: pending,
+        })
+    }
+}
+
+func handleVoidPending(deps Dependencies) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if deps.LedgerWriter == nil {
+            writeUnavailable(w, r, "ledger_unavailable")
+            return
+        }
+
+        var req voidPendingRequest
+        if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+            security.WriteJSONError(w, r, http.StatusBadRequest, "invalid_json")
+            return
+        }
+
+        pending, err := deps.LedgerWriter.VoidPending(r.Context(), ledger.VoidRequest{
+            PendingID: chi.URLParam(r, "pending_id"),
+            VoidedBy:  req.VoidedBy,
+        })
+        if err != nil {
+            writeLedgerError(w, r, err)
+            return
+        }
+
+        writeJSON(w, r, http.StatusOK, pendingTransactionResponse{
+            CorrelationID:      security.CorrelationIDFromContext(r.Context()),
+            PendingTransaction: pending,
+        })
+    }
+}
+
+func handleGetTransaction(deps Dependencies) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if deps.LedgerReader == nil {
+            writeUnavailable(w, r, "ledger_unavailable")
+            return
+        }
+
+        detail, err := deps.LedgerReader.GetTransaction(r.Context(), chi.URLParam(r, "id"))
+        if err != nil {
+            writeLedgerError(w, r, err)
+            return
+        }
+
+        writeJSON(w, r, http.StatusOK, transactionDetailResponse{
+            CorrelationID:     security.CorrelationIDFromContext(r.Context()),
+            TransactionDetail: detail,
+        })
+    }
+}
+
+func handleReverseTransaction(deps Dependencies) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if deps.LedgerWriter == nil {
+            writeUnavailable(w, r, "ledger_unavailable")
+            return
+        }
+
+        var req reverseTransactionRequest
+        if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+            security.WriteJSONError(w, r, http.StatusBadRequest, "invalid_json")
+            return
+        }
+
+        // Without an amount whatever is left of the transaction is reversed
+        var amount money.Money
+        if req.Amount != "" {
+            var err error
+            amount, err = money.Parse(req.Amount.String(), req.CurrencyCode)
+            if err != nil {
+                security.WriteJSONError(w, r, http.StatusBadRequest, "invalid_amount")
+                return
+            }
+        }
+
+        reversal, err := deps.LedgerWriter.ReverseTransaction(r.Context(), ledger.ReverseRequest{
+            TransactionID: chi.URLParam(r, "id"),
+            ReversalID:    req.ReversalID,
+            Amount:        amount,
+            Reason:        req.Reason,
+            ReversedBy:    req.ReversedBy,
+            ApprovedBy:    req.ApprovedBy,
+        })
+        if err != nil {
+            writeLedgerError(w, r, err)
+            return
+        }
+
+        writeJSON(w, r, http.StatusCreated, reversalResponse{
+            CorrelationID: security.CorrelationIDFromContext(r.Context()),
+            Reversal:      reversal,
+        })
+    }
+}
+
+func handleSetFXRate(deps Dependencies) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if deps.LedgerWriter == nil {
+            writeUnavailable(w, r, "ledger_unavailable")
+            return
+        }
+
+        var req setFXRateRequest
+        if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+            security.WriteJSONError(w, r, http.StatusBadRequest, "invalid_json")
+            return
+        }
+
+        rate, err := money.ParseFXRate(req.Rate.String())
+        if err != nil {
+            security.WriteJSONError(w, r, http.StatusBadRequest, "invalid_rate")
+            return
+        }
+
+        // Without effective_at the rate takes effect immediately
+        var effectiveAt time.Time
+        if req.EffectiveAt != "" {
+            if effectiveAt, err = time.Parse(time.RFC3339, req.EffectiveAt); err != nil {
+                security.WriteJSONError(w, r, http.StatusBadRequest, "validation_error")
+                return
+            }
+        }
+
+        fxRate, err := deps.LedgerWriter.SetFXRate(r.Context(), ledger.SetFXRateRequest{
+            BaseCurrency:  req.BaseCurrency,
+            QuoteCurrency: req.QuoteCurrency,
+            Rate:          rate,
+            EffectiveAt:   effectiveAt,
+            Source:        req.Source,
+            CreatedBy:     req.CreatedBy,
+        })
+        if err != nil {
+            writeLedgerError(w, r, err)
+            return
+        }
+
+        writeJSON(w, r, http.StatusCreated, fxRateResponse{
+            CorrelationID: security.CorrelationIDFromContext(r.Context()),
+            FXRate:        fxRate,
+        })
+    }
+}
+
+func handleGetFXRate(deps Dependencies) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if deps.LedgerReader == nil {
+            writeUnavailable(w, r, "ledger_unavailable")
+            return
+        }
+
+        q := r.URL.Query()
+        var at time.Time
+        if v := q.Get("at"); v != "" {
+            var err error
+            if at, err = time.Parse(time.RFC3339, v); err != nil {
+                security.WriteJSONError(w, r, http.StatusBadRequest, "validation_error")
+                return
+            }
+        }
+
+        fxRate, err := deps.LedgerReader.GetFXRate(r.Context(), q.Get("base"), q.Get("quote"), at)
+        if err != nil {
+            writeLedgerError(w, r, err)
+            return
+        }
+
+        writeJSON(w, r, http.StatusOK, fxRateResponse{
+            CorrelationID: security.CorrelationIDFromContext(r.Context()),
+            FXRate:        fxRate,
+        })
+    }
+}
+
+func handleSetFXAccounts(deps Dependencies) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if deps.LedgerWriter == nil {
+            writeUnavailable(w, r, "ledger_unavailable")
+            return
+        }
+
+        var req setFXAccountsRequest
+        if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+            security.WriteJSONError(w, r, http.StatusBadRequest, "invalid_json")
+            return
+        }
+
+        accts := ledger.FXAccounts{
+            CurrencyCode:      chi.URLParam(r, "currency"),
+            ClearingAccountID: req.ClearingAccountID,
+            GainAccountID:     req.GainAccountID,
+            LossAccountID:     req.LossAccountID,
+            UpdatedBy:         req.UpdatedBy,
+        }
+        if err := deps.LedgerWriter.SetFXAccounts(r.Context(), accts); err != nil {
+            writeLedgerError(w, r, err)
+            return
+        }
+
+        writeJSON(w, r, http.StatusOK, fxAccountsResponse{
+            CorrelationID: security.CorrelationIDFromContext(r.Context()),
+            FXAccounts:    accts,
+        })
+    }
+}
+
+func handleFXTransfer(deps Dependencies) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if deps.LedgerWriter == nil {
+            writeUnavailable(w, r, "ledger_unavailable")
+            return
+        }
+
+        var req fxTransferRequest
+        if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+            security.WriteJSONError(w, r, http.StatusBadRequest, "invalid_json")
+            return
+        }
+
+        amount, err := money.Parse(req.Amount.String(), req.CurrencyCode)
+        if err != nil {
+            security.WriteJSONError(w, r, http.StatusBadRequest, "invalid_amount")
+            return
+        }
+
+        // Without a quoted rate the market rate is applied
+        var quoted money.FXRate
+        if req.QuotedRate != "" {
+            if quoted, err = money.ParseFXRate(req.QuotedRate.String()); err != nil {
+                security.WriteJSONError(w, r, http.StatusBadRequest, "invalid_rate")
+                return
+            }
+        }
+
+        conversion, err := deps.LedgerWriter.FXTransfer(r.Context(), ledger.FXTransferRequest{
+            TransactionID: req.TransactionID,
+            FromAccountID: req.FromAccountID,
+            ToAccountID:   req.ToAccountID,
+            Amount:        amount,
+            QuotedRate:    quoted,
+            Description:   req.Description,
+            ReferenceType: req.ReferenceType,
+            ReferenceID:   req.ReferenceID,
+            CreatedBy:     req.CreatedBy,
+            Metadata:      req.Metadata,
+        })
+        if err != nil {
+            writeLedgerError(w, r, err)
+            return
+        }
+
+        writeJSON(w, r, http.StatusCreated, fxConversionResponse{
+            CorrelationID: security.CorrelationIDFromContext(r.Context()),
+            Conversion:    conversion,
+        })
+    }
+}
+
+func handleCreatePeriod(deps Dependencies) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if deps.LedgerWriter == nil {
+            writeUnavailable(w, r, "ledger_unavailable")
+            return
+        }
+
+        var req createPeriodRequest
+        if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+            security.WriteJSONError(w, r, http.StatusBadRequest, "invalid_json")
+            return
+        }
+
+        period, err := deps.LedgerWriter.CreatePeriod(r.Context(), ledger.CreatePeriodRequest{
+            Name:      req.Name,
+            StartsOn:  req.StartsOn,
+            EndsOn:    req.EndsOn,
+            CreatedBy: req.CreatedBy,
+        })
+        if err != nil {
+            writeLedgerError(w, r, err)
+            return
+        }
+
+        writeJSON(w, r, http.StatusCreated, periodResponse{
+            CorrelationID: security.CorrelationIDFromContext(r.Context()),
+            Period:        period,
+        })
+    }
+}
+
+func handleListPeriods(deps Dependencies) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if deps.LedgerReader == nil {
+            writeUnavailable(w, r, "ledger_unavailable")
+            return
+        }
+
+        periods, err := deps.LedgerReader.ListPeriods(r.Context())
+        if err != nil {
+            writeLedgerError(w, r, err)
+            return
+        }
+
+        writeJSON(w, r, http.StatusOK, listPeriodsResponse{
+            CorrelationID: security.CorrelationIDFromContext(r.Context()),
+            Periods:       periods,
+        })
+    }
+}
+
+func handleGetPeriod(deps Dependencies) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if deps.LedgerReader == nil {
+            writeUnavailable(w, r, "ledger_unavailable")
+            return
+        }
+
+        period, err := deps.LedgerReader.GetPeriod(r.Context(), chi.URLParam(r, "id"))
+        if err != nil {
+            writeLedgerError(w, r, err)
+            return
+        }
+
+        writeJSON(w, r, http.StatusOK, periodResponse{
+            CorrelationID: security.CorrelationIDFromContext(r.Context()),
+            Period:        period,
+        })
+    }
+}
+
+func handleClosePeriod(deps Dependencies, status string) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if deps.LedgerWriter == nil {
+            writeUnavailable(w, r, "ledger_unavailable")
+            return
+        }
+
+        var req closePeriodRequest
+        if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+            security.WriteJSONError(w, r, http.StatusBadRequest, "invalid_json")
+            return
+        }
+
+        closePeriod := deps.LedgerWriter.SoftClosePeriod
+        if status == ledger.PeriodStatusHardClosed {
+            closePeriod = deps.LedgerWriter.HardClosePeriod
+        }
+
+        period, err := closePeriod(r.Context(), ledger.ClosePeriodRequest{
+            PeriodID:      chi.URLParam(r, "id"),
+            TransactionID: req.TransactionID,
+            ClosedBy:      req.ClosedBy,
+        })
+        if err != nil {
+            writeLedgerError(w, r, err)
+            return
+        }
+
+        writeJSON(w, r, http.StatusOK, periodResponse{
+            CorrelationID: security.CorrelationIDFromContext(r.Context()),
+            Period:        period,
+        })
+    }
+}
+
+func handleSetRetainedEarnings(deps Dependencies) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if deps.LedgerWriter == nil {
+            writeUnavailable(w, r, "ledger_unavailable")
+            return
+        }
+
+        var req setRetainedEarningsRequest
+        if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+            security.WriteJSONError(w, r, http.StatusBadRequest, "invalid_json")
+            return
+        }
+
+        acct := ledger.RetainedEarningsAccount{
+            CurrencyCode: chi.URLParam(r, "currency"),
+            AccountID:    req.AccountID,
+            UpdatedBy:    req.UpdatedBy,
+        }
+        if err := deps.LedgerWriter.SetRetainedEarningsAccount(r.Context(), acct); err != nil {
+            writeLedgerError(w, r, err)
+            return
+        }
+
+        writeJSON(w, r, http.StatusOK, retainedEarningsResponse{
+            CorrelationID:    security.CorrelationIDFromContext(r.Context()),
+            RetainedEarnings: acct,
+        })
+    }
+}
+
+func handleImportStatement(deps Dependencies) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if deps.Reconciliation == nil {
+            writeUnavailable(w, r, "reconciliation_unavailable")
+            return
+        }
+
+        var req importStatementRequest
+        if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+            security.WriteJSONError(w, r, http.StatusBadRequest, "invalid_json")
+            return
+        }
+
+        st, err := deps.Reconciliation.ImportStatement(r.Context(), reconciliation.ImportRequest{
+            AccountID:  req.AccountID,
+            Format:     req.Format,
+            Content:    req.Content,
+            Reference:  req.Reference,
+            ImportedBy: req.ImportedBy,
+        })
+        if err != nil {
+            writeLedgerError(w, r, err)
+            return
+        }
+
+        writeJSON(w, r, http.StatusCreated, importStatementResponse{
+            CorrelationID: security.CorrelationIDFromContext(r.Context()),
+            Statement:     st,
+        })
+    }
+}
+
+func handleGetReconciliation(deps Dependencies) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if deps.Reconciliation == nil {
+            writeUnavailable(w, r, "reconciliation_unavailable")
+            return
+        }
+
+        result, err := deps.Reconciliation.Result(r.Context(), chi.URLParam(r, "id"))
+        if err != nil {
+            writeLedgerError(w, r, err)
+            return
+        }
+
+        writeJSON(w, r, http.StatusOK, reconciliationResponse{
+            CorrelationID: security.CorrelationIDFromContext(r.Context()),
+            Result:        result,
+        })
+    }
+}
+
+func handleMatchStatement(deps Dependencies) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if deps.Reconciliation == nil {
+            writeUnavailable(w, r, "reconciliation_unavailable")
+            return
+        }
+
+        result, err := deps.Reconciliation.Match(r.Context(), chi.URLParam(r, "id"))
+        if err != nil {
+            writeLedgerError(w, r, err)
+            return
+        }
+
+        writeJSON(w, r, http.StatusOK, reconciliationResponse{
+            CorrelationID: security.CorrelationIDFromContext(r.Context()),
+            Result:        result,
+        })
+    }
+}
+
+func handleDecideMatch(deps Dependencies, confirm bool) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if deps.Reconciliation == nil {
+            writeUnavailable(w, r, "reconciliation_unavailable")
+            return
+        }
+
+        var req decideMatchRequest
+        if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+            security.WriteJSONError(w, r, http.StatusBadRequest, "invalid_json")
+            return
+        }
+
+        decide := deps.Reconciliation.RejectMatch
+        if confirm {
+            decide = deps.Reconciliation.ConfirmMatch
+        }
+
+        match, err := decide(r.Context(), reconciliation.DecisionRequest{
+            MatchID:   chi.URLParam(r, "id"),
+            DecidedBy: req.DecidedBy,
+        })
+        if err != nil {
+            writeLedgerError(w, r, err)
+            return
+        }
+
+        writeJSON(w, r, http.StatusOK, matchResponse{
+            CorrelationID: security.CorrelationIDFromContext(r.Context()),
+            Match:         match,
+        })
+    }
+}
+
+// requestOwner is the client a request acts for. Accounts and webhook
+// subscriptions belong to the client that created them.
+func requestOwner(r *http.Request) string {
+    if ai, ok := auth.AuthInfoFromContext(r.Context()); ok {
+        return ai.ClientID
+    }
+    return ""
+}
+
+func handleCreateWebhook(deps Dependencies) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if deps.Webhooks == nil {
+            writeUnavailable(w, r, "webhooks_unavailable")
+            return
+        }
+
+        var req webhookRequest
+        if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+            security.WriteJSONError(w, r, http.StatusBadRequest, "invalid_json")
+            return
+        }
+
+        sub, err := deps.Webhooks.CreateSubscription(r.Context(), webhooks.CreateRequest{
+            Owner:       requestOwner(r),
+            URL:         req.URL,
+            Description: req.Description,
+            Filter:      req.filter(),
+        })
+        if err != nil {
+            writeLedgerError(w, r, err)
+            return
+        }
+
+        writeJSON(w, r, http.StatusCreated, webhookResponse{
+            CorrelationID: security.CorrelationIDFromContext(r.Context()),
+            Subscription:  sub,
+        })
+    }
+}
+
+func handleListWebhooks(deps Dependencies) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if deps.Webhooks == nil {
+            writeUnavailable(w, r, "webhooks_unavailable")
+            return
+        }
+
+        subs, err := deps.Webhooks.ListSubscriptions(r.Context(), requestOwner(r))
+        if err != nil {
+            writeLedgerError(w, r, err)
+            return
+        }
+
+        writeJSON(w, r, http.StatusOK, listWebhooksResponse{
+            CorrelationID: security.CorrelationIDFromContext(r.Context()),
+            Subscriptions: subs,
+        })
+    }
+}
+
+func handleGetWebhook(deps Dependencies) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if deps.Webhooks == nil {
+            writeUnavailable(w, r, "webhooks_unavailable")
+            return
+        }
+
+        sub, err := deps.Webhooks.GetSubscription(r.Context(), requestOwner(r), chi.URLParam(r, "id"))
+        if err != nil {
+            writeLedgerError(w, r, err)
+            return
+        }
+
+        writeJSON(w, r, http.StatusOK, webhookResponse{
+            CorrelationID: security.CorrelationIDFromContext(r.Context()),
+            Subscription:  sub,
+        })
+    }
+}
+
+func handleUpdateWebhook(deps Dependencies) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if deps.Webhooks == nil {
+            writeUnavailable(w, r, "webhooks_unavailable")
+            return
+        }
+
+        var req webhookRequest
+        if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+            security.WriteJSONError(w, r, http.StatusBadRequest, "invalid_json")
+            return
+        }
+
+        sub, err := deps.Webhooks.UpdateSubscription(r.Context(), webhooks.UpdateRequest{
+            ID:          chi.URLParam(r, "id"),
+            Owner:       requestOwner(r),
+            URL:         req.URL,
+            Description: req.Description,
+            Filter:      req.filter(),
+            Active:      req.Active,
+        })
+        if err != nil {
+            writeLedgerError(w, r, err)
+            return
+        }
+
+        writeJSON(w, r, http.StatusOK, webhookResponse{
+            CorrelationID: security.CorrelationIDFromContext(r.Context()),
+            Subscription:  sub,
+        })
+    }
+}
+
+func handleDeleteWebhook(deps Dependencies) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if deps.Webhooks == nil {
+            writeUnavailable(w, r, "webhooks_unavailable")
+            return
+        }
+
+        if err := deps.Webhooks.DeleteSubscription(r.Context(), requestOwner(r), chi.URLParam(r, "id")); err != nil {
+            writeLedgerError(w, r, err)
+            return
+        }
+
+        w.WriteHeader(http.StatusNoContent)
+    }
+}
+
+func handleRotateWebhookSecret(deps Dependencies) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if deps.Webhooks == nil {
+            writeUnavailable(w, r, "webhooks_unavailable")
+            return
+        }
+
+        sub, err := deps.Webhooks.RotateSecret(r.Context(), requestOwner(r), chi.URLParam(r, "id"))
+        if err != nil {
+            writeLedgerError(w, r, err)
+            return
+        }
+
+        writeJSON(w, r, http.StatusOK, webhookResponse{
+            CorrelationID: security.CorrelationIDFromContext(r.Context()),
+            Subscription:  sub,
+        })
+    }
+}
+
+func handleListWebhookDeliveries(deps Dependencies) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if deps.Webhooks == nil {
+            writeUnavailable(w, r, "webhooks_unavailable")
+            return
+        }
+
+        filter := webhooks.DeliveryFilter{
+            SubscriptionID: chi.URLParam(r, "id"),
+            Owner:          requestOwner(r),
+            Status:         r.URL.Query().Get("status"),
+        }
+        if v := r.URL.Query().Get("limit"); v != "" {
+            if i, err := strconv.Atoi(v); err == nil {
+                filter.Limit = i
+            }
+        }
+        if v := r.URL.Query().Get("offset"); v != "" {
+            if i, err := strconv.Atoi(v); err == nil {
+                filter.Offset = i
+            }
+        }
+
+        deliveries, err := deps.Webhooks.ListDeliveries(r.Context(), filter)
+        if err != nil {
+            writeLedgerError(w, r, err)
+            return
+        }
+
+        writeJSON(w, r, http.StatusOK, listWebhookDeliveriesResponse{
+            CorrelationID: security.CorrelationIDFromContext(r.Context()),
+            Deliveries:    deliveries,
+        })
+    }
+}
+
+func handleGetWebhookDelivery(deps Dependencies) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if deps.Webhooks == nil {
+            writeUnavailable(w, r, "webhooks_unavailable")
+            return
+        }
+
+        delivery, err := deps.Webhooks.GetDelivery(r.Context(), requestOwner(r), chi.URLParam(r, "delivery_id"))
+        if err != nil {
+            writeLedgerError(w, r, err)
+            return
+        }
+
+        writeJSON(w, r, http.StatusOK, webhookDeliveryResponse{
+            CorrelationID: security.CorrelationIDFromContext(r.Context()),
+            Delivery:      delivery,
+        })
+    }
+}
+
+func handleReplayWebhookDelivery(deps Dependencies) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if deps.Webhooks == nil {
+            writeUnavailable(w, r, "webhooks_unavailable")
+            return
+        }
+
+        delivery, err := deps.Webhooks.ReplayDelivery(r.Context(), requestOwner(r), chi.URLParam(r, "delivery_id"))
+        if err != nil {
+            writeLedgerError(w, r, err)
+            return
+        }
+
+        writeJSON(w, r, http.StatusAccepted, webhookDeliveryResponse{
+            CorrelationID: security.CorrelationIDFromContext(r.Context()),
+            Delivery:      delivery,
+        })
+    }
+}
+
+func handleSetPayoutSettings(deps Dependencies) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if deps.Payouts == nil {
+            writeUnavailable(w, r, "payouts_unavailable")
+            return
+        }
+
+        var req payoutSettingsRequest
+        if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+            security.WriteJSONError(w, r, http.StatusBadRequest, "invalid_json")
+            return
+        }
+
+        minimum, err := money.Parse(req.MinimumAmount.String(), req.CurrencyCode)
+        if err != nil {
+            security.WriteJSONError(w, r, http.StatusBadRequest, "invalid_amount")
+            return
+        }
+
+        settings, err := deps.Payouts.SetSettings(r.Context(), payouts.Settings{
+            MerchantID:       chi.URLParam(r, "merchant_id"),
+            PayableAccountID: req.PayableAccountID,
+            FundingAccountID: req.FundingAccountID,
+            Schedule:         req.Schedule,
+            Weekday:          req.Weekday,
+            MinimumAmount:    minimum,
+            Active:           req.Active,
+            UpdatedBy:        req.UpdatedBy,
+        })
+        if err != nil {
+            writeLedgerError(w, r, err)
+            return
+        }
+
+        writeJSON(w, r, http.StatusOK, payoutSettingsResponse{
+            CorrelationID: security.CorrelationIDFromContext(r.Context()),
+            Settings:      settings,
+        })
+    }
+}
+
+func handleGetPayoutSettings(deps Dependencies) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if deps.Payouts == nil {
+            writeUnavailable(w, r, "payouts_unavailable")
+            return
+        }
+
+        settings, err := deps.Payouts.GetSettings(r.Context(), chi.URLParam(r, "merchant_id"))
+        if err != nil {
+            writeLedgerError(w, r, err)
+            return
+        }
+
+        writeJSON(w, r, http.StatusOK, payoutSettingsResponse{
+            CorrelationID: security.CorrelationIDFromContext(r.Context()),
+            Settings:      settings,
+        })
+    }
+}
+
+func handleGetPayable(deps Dependencies) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if deps.Payouts == nil {
+            writeUnavailable(w, r, "payouts_unavailable")
+            return
+        }
+
+        payable, err := deps.Payouts.Compute(r.Context(), chi.URLParam(r, "merchant_id"))
+        if err != nil {
+            writeLedgerError(w, r, err)
+            return
+        }
+
+        writeJSON(w, r, http.StatusOK, payableResponse{
+            CorrelationID: security.CorrelationIDFromContext(r.Context()),
+            Payable:       payable,
+        })
+    }
+}
+
+func handleRequestPayout(deps Dependencies) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if deps.Payouts == nil {
+            writeUnavailable(w, r, "payouts_unavailable")
+            return
+        }
+
+        var req requestPayoutRequest
+        if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+            security.WriteJSONError(w, r, http.StatusBadRequest, "invalid_json")
+            return
+        }
+
+        payout, err := deps.Payouts.RequestPayout(r.Context(), payouts.PayoutRequest{
+            MerchantID: req.MerchantID,
+            CreatedBy:  req.CreatedBy,
+        })
+        if err != nil {
+            writeLedgerError(w, r, err)
+            return
+        }
+
+        writeJSON(w, r, http.StatusCreated, payoutResponse{
+            CorrelationID: security.CorrelationIDFromContext(r.Context()),
+            Payout:        payout,
+        })
+    }
+}
+
+func handleListPayouts(deps Dependencies) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if deps.Payouts == nil {
+            writeUnavailable(w, r, "payouts_unavailable")
+            return
+        }
+
+        filter := payouts.PayoutFilter{
+            MerchantID: r.URL.Query().Get("merchant_id"),
+            Status:     r.URL.Query().Get("status"),
+        }
+        if v := r.URL.Query().Get("limit"); v != "" {
+            if i, err := strconv.Atoi(v); err == nil {
+                filter.Limit = i
+            }
+        }
+        if v := r.URL.Query().Get("offset"); v != "" {
+            if i, err := strconv.Atoi(v); err == nil {
+                filter.Offset = i
+            }
+        }
+
+        list, err := deps.Payouts.ListPayouts(r.Context(), filter)
+        if err != nil {
+            writeLedgerError(w, r, err)
+            return
+        }
+
+        writeJSON(w, r, http.StatusOK, listPayoutsResponse{
+            CorrelationID: security.CorrelationIDFromContext(r.Context()),
+            Payouts:       list,
+        })
+    }
+}
+
+func handleGetPayout(deps Dependencies) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if deps.Payouts == nil {
+            writeUnavailable(w, r, "payouts_unavailable")
+            return
+        }
+
+        payout, err := deps.Payouts.GetPayout(r.Context(), chi.URLParam(r, "id"))
+        if err != nil {
+            writeLedgerError(w, r, err)
+            return
+        }
+
+        writeJSON(w, r, http.StatusOK, payoutResponse{
+            CorrelationID: security.CorrelationIDFromContext(r.Context()),
+            Payout:        payout,
+        })
+    }
+}
+
+// handleTransitionPayout moves a payout on to the status given by the route:
+// sent, paid or failed
+func handleTransitionPayout(deps Dependencies, status string) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if deps.Payouts == nil {
+            writeUnavailable(w, r, "payouts_unavailable")
+            return
+        }
+
+        var req payoutTransitionRequest
+        if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+            security.WriteJSONError(w, r, http.StatusBadRequest, "invalid_json")
+            return
+        }
+
+        transition := deps.Payouts.MarkSent
+        switch status {
+        case payouts.StatusPaid:
+            transition = deps.Payouts.MarkPaid
+        case payouts.StatusFailed:
+            transition = deps.Payouts.MarkFailed
+        }
+
+        payout, err := transition(r.Context(), payouts.TransitionRequest{
+            PayoutID:      chi.URLParam(r, "id"),
+            BankReference: req.BankReference,
+            Reason:        req.Reason,
+            ChangedBy:     req.ChangedBy,
+        })
+        if err != nil {
+            writeLedgerError(w, r, err)
+            return
+        }
+
+        writeJSON(w, r, http.StatusOK, payoutResponse{
+            CorrelationID: security.CorrelationIDFromContext(r.Context()),
+            Payout:        payout,
+        })
+    }
+}
+
+// parseAmount parses an optional amount, zero when empty
+func parseAmount(n json.Number, currency string) (money.Money, error) {
+    if n == "" {
+        return money.Zero(currency)
+    }
+    return money.Parse(n.String(), currency)
+}
+
+// parseRate parses an optional rate, zero when empty
+func parseRate(n json.Number) (money.Rate, error) {
+    if n == "" {
+        return money.Rate{}, nil
+    }
+    return money.ParseRate(n.String())
+}
+
+// parseBound parses an optional bound, nil when empty
+func parseBound(n json.Number, currency string) (*money.Money, error) {
+    if n == "" {
+        return nil, nil
+    }
+    m, err := money.Parse(n.String(), currency)
+    if err != nil {
+        return nil, err
+    }
+    return &m, nil
+}
+
+// feeScheduleRequest converts a create request into the fee engine's form. It
+// returns the error code to answer with when the request cannot be read.
+func feeScheduleRequest(req createFeeScheduleRequest) (fees.ScheduleRequest, string) {
+    out := fees.ScheduleRequest{
+        FeeType:          req.FeeType,
+        MerchantID:       req.MerchantID,
+        CardBrand:        req.CardBrand,
+        RevenueAccountID: req.RevenueAccountID,
+        CreatedBy:        req.CreatedBy,
+    }
+    var err error
+    if out.FixedAmount, err = parseAmount(req.FixedAmount, req.CurrencyCode); err != nil {
+        return out, "invalid_amount"
+    }
+    if out.MinimumFee, err = parseBound(req.MinimumFee, req.CurrencyCode); err != nil {
+        return out, "invalid_amount"
+    }
+    if out.MaximumFee, err = parseBound(req.MaximumFee, req.CurrencyCode); err != nil {
+        return out, "invalid_amount"
+    }
+    if out.Rate, err = parseRate(req.Rate); err != nil {
+        return out, "validation_error"
+    }
+    for _, t := range req.Tiers {
+        var tier fees.Tier
+        if tier.UpTo, err = parseBound(t.UpTo, req.CurrencyCode); err != nil {
+            return out, "invalid_amount"
+        }
+        if tier.FixedAmount, err = parseAmount(t.FixedAmount, req.CurrencyCode); err != nil {
+            return out, "invalid_amount"
+        }
+        if tier.Rate, err = parseRate(t.Rate); err != nil {
+            return out, "validation_error"
+        }
+        out.Tiers = append(out.Tiers, tier)
+    }
+
+    // Without effective_from the schedule takes effect immediately
+    if req.EffectiveFrom != "" {
+        if out.EffectiveFrom, err = time.Parse(time.RFC3339, req.EffectiveFrom); err != nil {
+            return out, "validation_error"
+        }
+    }
+    if req.EffectiveTo != "" {
+        to, err := time.Parse(time.RFC3339, req.EffectiveTo)
+        if err != nil {
+            return out, "validation_error"
+        }
+        out.EffectiveTo = &to
+    }
+    return out, ""
+}
+
+func handleCreateFeeSchedule(deps Dependencies) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if deps.Fees == nil {
+            writeUnavailable(w, r, "fees_unavailable")
+            return
+        }
+
+        var req createFeeScheduleRequest
+        if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+            security.WriteJSONError(w, r, http.StatusBadRequest, "invalid_json")
+            return
+        }
+        scheduleReq, code := feeScheduleRequest(req)
+        if code != "" {
+            security.WriteJSONError(w, r, http.StatusBadRequest, code)
+            return
+        }
+
+        schedule, err := deps.Fees.CreateSchedule(r.Context(), scheduleReq)
+        if err != nil {
+            writeLedgerError(w, r, err)
+            return
+        }
+
+        writeJSON(w, r, http.StatusCreated, feeScheduleResponse{
+            CorrelationID: security.CorrelationIDFromContext(r.Context()),
+            Schedule:      schedule,
+        })
+    }
+}
+
+func handleListFeeSchedules(deps Dependencies) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if deps.Fees == nil {
+            writeUnavailable(w, r, "fees_unavailable")
+            return
+        }
+
+        q := r.URL.Query()
+        filter := fees.ScheduleFilter{
+            FeeType:      q.Get("fee_type"),
+            MerchantID:   q.Get("merchant_id"),
+            CardBrand:    q.Get("card_brand"),
+            CurrencyCode: q.Get("currency_code"),
+        }
+        if v := q.Get("at"); v != "" {
+            at, err := time.Parse(time.RFC3339, v)
+            if err != nil {
+                security.WriteJSONError(w, r, http.StatusBadRequest, "validation_error")
+                return
+            }
+            filter.At = at
+        }
+        if v := q.Get("limit"); v != "" {
+            if i, err := strconv.Atoi(v); err == nil {
+                filter.Limit = i
+            }
+        }
+        if v := q.Get("offset"); v != "" {
+            if i, err := strconv.Atoi(v); err == nil {
+                filter.Offset = i
+            }
+        }
+
+        list, err := deps.Fees.ListSchedules(r.Context(), filter)
+        if err != nil {
+            writeLedgerError(w, r, err)
+            return
+        }
+
+        writeJSON(w, r, http.StatusOK, listFeeSchedulesResponse{
+            CorrelationID: security.CorrelationIDFromContext(r.Context()),
+            Schedules:     list,
+        })
+    }
+}
+
+func handleGetFeeSchedule(deps Dependencies) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if deps.Fees == nil {
+            writeUnavailable(w, r, "fees_unavailable")
+            return
+        }
+
+        schedule, err := deps.Fees.GetSchedule(r.Context(), chi.URLParam(r, "id"))
+        if err != nil {
+            writeLedgerError(w, r, err)
+            return
+        }
+
+        writeJSON(w, r, http.StatusOK, feeScheduleResponse{
+            CorrelationID: security.CorrelationIDFromContext(r.Context()),
+            Schedule:      schedule,
+        })
+    }
+}
+
+// handleQuoteFee works out a fee without charging it. The fee is as of at,
+// or now.
+func handleQuoteFee(deps Dependencies) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if deps.Fees == nil {
+            writeUnavailable(w, r, "fees_unavailable")
+            return
+        }
+
+        q := r.URL.Query()
+        amount, err := money.Parse(q.Get("amount"), q.Get("currency_code"))
+        if err != nil {
+            security.WriteJSONError(w, r, http.StatusBadRequest, "invalid_amount")
+            return
+        }
+        req := fees.QuoteRequest{
+            FeeType:    q.Get("fee_type"),
+            MerchantID: q.Get("merchant_id"),
+            CardBrand:  q.Get("card_brand"),
+            Amount:     amount,
+        }
+        if v := q.Get("at"); v != "" {
+            if req.At, err = time.Parse(time.RFC3339, v); err != nil {
+                security.WriteJSONError(w, r, http.StatusBadRequest, "validation_error")
+                return
+            }
+        }
+
+        fee, err := deps.Fees.Quote(r.Context(), req)
+        if err != nil {
+            writeLedgerError(w, r, err)
+            return
+        }
+
+        writeJSON(w, r, http.StatusOK, feeResponse{
+            CorrelationID: security.CorrelationIDFromContext(r.Context()),
+            Fee:           fee,
+        })
+    }
+}
+
+func handleChargeFee(deps Dependencies) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if deps.Fees == nil {
+            writeUnavailable(w, r, "fees_unavailable")
+            return
+        }
+
+        var req chargeFeeRequest
+        if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+            security.WriteJSONError(w, r, http.StatusBadRequest, "invalid_json")
+            return
+        }
+        amount, err := money.Parse(req.Amount.String(), req.CurrencyCode)
+        if err != nil {
+            security.WriteJSONError(w, r, http.StatusBadRequest, "invalid_amount")
+            return
+        }
+
+        fee, err := deps.Fees.Charge(r.Context(), fees.ChargeRequest{
+            QuoteRequest: fees.QuoteRequest{
+                FeeType:    req.FeeType,
+                MerchantID: req.MerchantID,
+                CardBrand:  req.CardBrand,
+                Amount:     amount,
+            },
+            PayerAccountID: req.PayerAccountID,
+            TransactionID:  req.TransactionID,
+            ReferenceID:    req.ReferenceID,
+            CreatedBy:      req.CreatedBy,
+        })
+        if err != nil {
+            writeLedgerError(w, r, err)
+            return
+        }
+
+        writeJSON(w, r, http.StatusCreated, feeResponse{
+            CorrelationID: security.CorrelationIDFromContext(r.Context()),
+            Fee:           fee,
+        })
+    }
+}
+
+// reportRequest reads the currency and period of a report from the query. The
+// balance sheet takes a single at instead of from and to. format selects json
+// (the default) or csv.
+func reportRequest(r *http.Request, period bool) (reporting.Request, bool) {
+    q := r.URL.Query()
+    switch q.Get("format") {
+    case "", "json", "csv":
+    default:
+        return reporting.Request{}, false
+    }
+
+    req := reporting.Request{CurrencyCode: q.Get("currency_code")}
+    var err error
+    if !period {
+        req.To, err = time.Parse(time.RFC3339, q.Get("at"))
+        return req, err == nil
+    }
+    if req.From, err = time.Parse(time.RFC3339, q.Get("from")); err != nil {
+        return req, false
+    }
+    if req.To, err = time.Parse(time.RFC3339, q.Get("to")); err != nil {
+        return req, false
+    }
+    return req, true
+}
+
+func handleTrialBalance(deps Dependencies) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if deps.Reports == nil {
+            writeUnavailable(w, r, "reports_unavailable")
+            return
+        }
+
+        req, ok := reportRequest(r, true)
+        if !ok {
+            security.WriteJSONError(w, r, http.StatusBadRequest, "validation_error")
+            return
+        }
+
+        report, err := deps.Reports.TrialBalance(r.Context(), req)
+        if err != nil {
+            writeLedgerError(w, r, err)
+            return
+        }
+
+        if r.URL.Query().Get("format") == "csv" {
+            writeCSV(w, r, "trial-balance.csv", report.WriteCSV)
+            return
+        }
+        writeJSON(w, r, http.StatusOK, trialBalanceResponse{
+            CorrelationID: security.CorrelationIDFromContext(r.Context()),
+            TrialBalance:  report,
         })
     }
 }
 
-func handleDebit(deps Dependencies) http.HandlerFunc {
-    return handlePostEntry(deps, "debit")
-}
-
-func handleCredit(deps Dependencies) http.HandlerFunc {
-    return handlePostEntry(deps, "credit")
-}
-
-func handlePostEntry(deps Dependencies, entryType string) http.HandlerFunc {
+func handleBalanceSheet(deps Dependencies) http.HandlerFunc {
     return func(w http.ResponseWriter, r *http.Request) {
-        if deps.LedgerWriter == nil {
-            security.WriteJSONError(w, r, http.StatusServiceUnavailable, "ledger_unavailable")
+        if deps.Reports == nil {
+            writeUnavailable(w, r, "reports_unavailable")
             return
         }
 
-        var req postEntryRequest
-        if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-            security.WriteJSONError(w, r, http.StatusBadRequest, "invalid_json")
+        req, ok := reportRequest(r, false)
+        if !ok {
+            security.WriteJSONError(w, r, http.StatusBadRequest, "validation_error")
             return
         }
-        if req.TransactionID == "" {
-            req.TransactionID = uuid.NewString()
-        }
 
-        switch entryType {
-        case "debit":
-            err := deps.LedgerWriter.Debit(r.Context(), ledger.DebitRequest{
-                TransactionRequest: ledger.TransactionRequest{
-                    TransactionID: req.TransactionID,
-                    Description:   req.Description,
-                    ReferenceType: req.ReferenceType,
-                    ReferenceID:   req.ReferenceID,
-                    CurrencyCode:  req.CurrencyCode,
-                    CreatedBy:     req.CreatedBy,
-                    Metadata:      req.Metadata,
-                },
-                AccountID:   req.AccountID,
-                Amount:      req.Amount,
-                Description: req.Description,
-            })
-            if err != nil {
-                security.WriteJSONError(w, r, http.StatusBadRequest, "invalid_request")
-                return
-            }
-        case "credit":
-            err := deps.LedgerWriter.Credit(r.Context(), ledger.CreditRequest{
-                TransactionRequest: ledger.TransactionRequest{
-                    TransactionID: req.TransactionID,
-                    Description:   req.Description,
-                    ReferenceType: req.ReferenceType,
-                    ReferenceID:   req.ReferenceID,
-                    CurrencyCode:  req.CurrencyCode,
-                    CreatedBy:     req.CreatedBy,
-                    Metadata:      req.Metadata,
-                },
-                AccountID:   req.AccountID,
-                Amount:      req.Amount,
-                Description: req.Description,
-            })
-            if err != nil {
-                security.WriteJSONError(w, r, http.StatusBadRequest, "invalid_request")
-                return
-            }
-        default:
-            security.WriteJSONError(w, r, http.StatusInternalServerError, "internal_error")
+        report, err := deps.Reports.BalanceSheet(r.Context(), req)
+        if err != nil {
+            writeLedgerError(w, r, err)
             return
         }
 
-        writeJSON(w, r, http.StatusOK, postEntryResponse{
+        if r.URL.Query().Get("format") == "csv" {
+            writeCSV(w, r, "balance-sheet.csv", report.WriteCSV)
+            return
+        }
+        writeJSON(w, r, http.StatusOK, balanceSheetResponse{
             CorrelationID: security.CorrelationIDFromContext(r.Context()),
-            Success:       true,
-            Type:          entryType,
-            TransactionID: req.TransactionID,
-            AccountID:     req.AccountID,
-            Amount:        req.Amount,
+            BalanceSheet:  report,
         })
     }
 }
 
-func handleBalance(deps Dependencies) http.HandlerFunc {
+func handleIncomeStatement(deps Dependencies) http.HandlerFunc {
     return func(w http.ResponseWriter, r *http.Request) {
-        if deps.LedgerReader == nil {
-            security.WriteJSONError(w, r, http.StatusServiceUnavailable, "ledger_unavailable")
+        if deps.Reports == nil {
+            writeUnavailable(w, r, "reports_unavailable")
             return
         }
 
-        accountID := r.URL.Query().Get("account_id")
-        if accountID == "" {
+        req, ok := reportRequest(r, true)
+        if !ok {
             security.WriteJSONError(w, r, http.StatusBadRequest, "validation_error")
             return
         }
 
-        bal, err := deps.LedgerReader.GetBalance(r.Context(), accountID)
+        report, err := deps.Reports.IncomeStatement(r.Context(), req)
         if err != nil {
-            security.WriteJSONError(w, r, http.StatusBadRequest, "invalid_request")
+            writeLedgerError(w, r, err)
             return
         }
 
-        writeJSON(w, r, http.StatusOK, balanceResponse{
-            CorrelationID: security.CorrelationIDFromContext(r.Context()),
-            AccountID:     accountID,
-            Balance:       bal,
+        if r.URL.Query().Get("format") == "csv" {
+            writeCSV(w, r, "income-statement.csv", report.WriteCSV)
+            return
+        }
+        writeJSON(w, r, http.StatusOK, incomeStatementResponse{
+            CorrelationID:   security.CorrelationIDFromContext(r.Context()),
+            IncomeStatement: report,
         })
     }
 }
 
+func writeLedgerError(w http.ResponseWriter, r *http.Request, err error) {
+    switch {
+    case errors.Is(err, ledger.ErrAccountNotFound):
+        security.WriteJSONError(w, r, http.StatusNotFound, "account_not_found")
+    case errors.Is(err, ledger.ErrPendingNotFound):
+        security.WriteJSONError(w, r, http.StatusNotFound, "pending_transaction_not_found")
+    case errors.Is(err, ledger.ErrTransactionNotFound):
+        security.WriteJSONError(w, r, http.StatusNotFound, "transaction_not_found")
+    case errors.Is(err, ledger.ErrFXRateNotFound):
+        security.WriteJSONError(w, r, http.StatusNotFound, "fx_rate_not_found")
+    case errors.Is(err, ledger.ErrPeriodNotFound):
+        security.WriteJSONError(w, r, http.StatusNotFound, "period_not_found")
+    case errors.Is(err, ledger.ErrCreditLimitNotFound):
+        security.WriteJSONError(w, r, http.StatusNotFound, "credit_limit_not_found")
+    case errors.Is(err, reconciliation.ErrStatementNotFound):
+        security.WriteJSONError(w, r, http.StatusNotFound, "statement_not_found")
+    case errors.Is(err, reconciliation.ErrMatchNotFound):
+        security.WriteJSONError(w, r, http.StatusNotFound, "match_not_found")
+    case errors.Is(err, webhooks.ErrSubscriptionNotFound):
+        security.WriteJSONError(w, r, http.StatusNotFound, "webhook_not_found")
+    case errors.Is(err, webhooks.ErrDeliveryNotFound):
+        security.WriteJSONError(w, r, http.StatusNotFound, "webhook_delivery_not_found")
+    case errors.Is(err, payouts.ErrSettingsNotFound):
+        security.WriteJSONError(w, r, http.StatusNotFound, "payout_settings_not_found")
+    case errors.Is(err, payouts.ErrPayoutNotFound):
+        security.WriteJSONError(w, r, http.StatusNotFound, "payout_not_found")
+    case errors.Is(err, fees.ErrScheduleNotFound):
+        security.WriteJSONError(w, r, http.StatusNotFound, "fee_schedule_not_found")
+    case errors.Is(err, ledger.ErrInvalidRequest), errors.Is(err, ledger.ErrInvalidEntry):
+        security.WriteJSONError(w, r, http.StatusBadRequest, "validation_error")
+    case errors.Is(err, ledger.ErrCurrencyMismatch):
+        security.WriteJSONError(w, r, http.StatusBadRequest, "currency_mismatch")
+    case errors.Is(err, ledger.ErrAccountExists):
+        security.WriteJSONError(w, r, http.StatusConflict, "account_exists")
+    case errors.Is(err, ledger.ErrPendingResolved):
+        security.WriteJSONError(w, r, http.StatusConflict, "pending_transaction_resolved")
+    case errors.Is(err, ledger.ErrPendingExpired):
+        security.WriteJSONError(w, r, http.StatusConflict, "pending_transaction_expired")
+    case errors.Is(err, ledger.ErrAlreadyReversed):
+        security.WriteJSONError(w, r, http.StatusConflict, "transaction_already_reversed")
+    case errors.Is(err, ledger.ErrAccountFrozen):
+        security.WriteJSONError(w, r, http.StatusConflict, "account_frozen")
+    case errors.Is(err, ledger.ErrAccountBlocked):
+        security.WriteJSONError(w, r, http.StatusConflict, "account_blocked")
+    case errors.Is(err, ledger.ErrAccountClosed):
+        security.WriteJSONError(w, r, http.StatusConflict, "account_closed")
+    case errors.Is(err, ledger.ErrInvalidStatusTransition):
+        security.WriteJSONError(w, r, http.StatusConflict, "invalid_status_transition")
+    case errors.Is(err, ledger.ErrAccountNotEmpty):
+        security.WriteJSONError(w, r, http.StatusConflict, "account_not_empty")
+    case errors.Is(err, ledger.ErrPeriodSoftClosed):
+        security.WriteJSONError(w, r, http.StatusConflict, "period_soft_closed")
+    case errors.Is(err, ledger.ErrPeriodHardClosed):
+        security.WriteJSONError(w, r, http.StatusConflict, "period_hard_closed")
+    case errors.Is(err, ledger.ErrInvalidPeriodTransition):
+        security.WriteJSONError(w, r, http.StatusConflict, "invalid_period_transition")
+    case errors.Is(err, reconciliation.ErrStatementExists):
+        security.WriteJSONError(w, r, http.StatusConflict, "statement_exists")
+    case errors.Is(err, reconciliation.ErrMatchDecided):
+        security.WriteJSONError(w, r, http.StatusConflict, "match_already_decided")
+    case errors.Is(err, webhooks.ErrDeliveryPending):
+        security.WriteJSONError(w, r, http.StatusConflict, "webhook_delivery_pending")
+    case errors.Is(err, payouts.ErrInvalidPayoutTransition):
+        security.WriteJSONError(w, r, http.StatusConflict, "invalid_payout_transition")
+    case errors.Is(err, ledger.ErrInsufficientFunds):
+        security.WriteJSONError(w, r, http.StatusUnprocessableEntity, "insufficient_funds")
+    case errors.Is(err, ledger.ErrTransactionLimitExceeded):
+        security.WriteJSONError(w, r, http.StatusUnprocessableEntity, "transaction_limit_exceeded")
+    case errors.Is(err, payouts.ErrBelowMinimum):
+        security.WriteJSONError(w, r, http.StatusUnprocessableEntity, "below_minimum_payout")
+    case errors.Is(err, ledger.ErrAccountInactive):
+        security.WriteJSONError(w, r, http.StatusUnprocessableEntity, "account_inactive")
+    case errors.Is(err, ledger.ErrFXNotConfigured):
+        security.WriteJSONError(w, r, http.StatusUnprocessableEntity, "fx_not_configured")
+    case errors.Is(err, ledger.ErrQuoteOffMarket):
+        security.WriteJSONError(w, r, http.StatusUnprocessableEntity, "quote_off_market")
+    case errors.Is(err, ledger.ErrRetainedEarningsNotConfigured):
+        security.WriteJSONError(w, r, http.StatusUnprocessableEntity, "retained_earnings_not_configured")
+    default:
+        security.WriteJSONError(w, r, http.StatusInternalServerError, "internal_error")
+    }
+}
+
 // Dispute-related request/response types
 type createDisputeRequest struct {
     JournalEntryID  string                 `json:"journal_entry_id"`
     MerchantID      string                 `json:"merchant_id"`
-    DisputedAmount  float64                `json:"disputed_amount"`
+    DisputedAmount  json.Number            `json:"disputed_amount"`
     CurrencyCode    string                 `json:"currency_code"`
     ReasonCode      string                 `json:"reason_code"`
     ReasonText      string                 `json:"reason_text"`
@@ -259,6 +2581,17 @@ type createDisputeResponse struct {
     Dispute       *disputes.Dispute `json:"dispute"`
 }
 
+// disputeAmountExceededResponse says how much of a transaction is left to
+// dispute when a dispute asks for more
+type disputeAmountExceededResponse struct {
+    Error           string      `json:"error"`
+    CorrelationID   string      `json:"correlation_id,omitempty"`
+    JournalEntryID  string      `json:"journal_entry_id"`
+    OriginalAmount  money.Money `json:"original_amount"`
+    DisputedAmount  money.Money `json:"disputed_amount"`
+    RemainingAmount money.Money `json:"remaining_amount"`
+}
+
 type authorizeDisputeRequest struct {
     DisputeID      string `json:"dispute_id"`
     AuthorizedBy   string `json:"authorized_by"`
@@ -280,6 +2613,28 @@ type reverseDisputeRequest struct {
     Reason      string `json:"reason"`
 }
 
+type disputeActionRequest struct {
+    ActedBy string `json:"acted_by"`
+    Reason  string `json:"reason"`
+}
+
+type resolveDisputeRequest struct {
+    Outcome    string `json:"outcome"`
+    ResolvedBy string `json:"resolved_by"`
+    Reason     string `json:"reason"`
+}
+
+type extendHoldRequest struct {
+    ExpiresAt time.Time `json:"expires_at"`
+    ActedBy   string    `json:"acted_by"`
+    Reason    string    `json:"reason"`
+}
+
+type holdResponse struct {
+    CorrelationID string         `json:"correlation_id"`
+    Hold          *disputes.Hold `json:"hold"`
+}
+
 type getDisputeResponse struct {
     CorrelationID string         `json:"correlation_id"`
     Dispute       *disputes.Dispute `json:"dispute"`
@@ -297,18 +2652,18 @@ type getDisputeHistoryResponse struct {
 }
 
 type calculateReserveRequest struct {
-    MerchantID        string  `json:"merchant_id"`
-    TransactionVolume float64 `json:"transaction_volume"`
-    CurrencyCode      string  `json:"currency_code"`
+    MerchantID        string      `json:"merchant_id"`
+    TransactionVolume json.Number `json:"transaction_volume"`
+    CurrencyCode      string      `json:"currency_code"`
 }
 
 type calculateReserveResponse struct {
-    CorrelationID     string  `json:"correlation_id"`
-    RequiredReserve   float64 `json:"required_reserve"`
-    CurrencyCode      string  `json:"currency_code"`
-    ReservePercentage float64 `json:"reserve_percentage"`
-    MinimumReserve    float64 `json:"minimum_reserve"`
-    CurrentReserve    float64 `json:"current_reserve"`
+    CorrelationID     string      `json:"correlation_id"`
+    RequiredReserve   money.Money `json:"required_reserve"`
+    CurrencyCode      string      `json:"currency_code"`
+    ReservePercentage money.Rate  `json:"reserve_percentage"`
+    MinimumReserve    money.Money `json:"minimum_reserve"`
+    CurrentReserve    money.Money `json:"current_reserve"`
 }
 
 type actionResponse struct {
@@ -321,7 +2676,7 @@ type actionResponse struct {
 func handleCreateDispute(deps Dependencies) http.HandlerFunc {
     return func(w http.ResponseWriter, r *http.Request) {
         if deps.DisputesService == nil {
-            security.WriteJSONError(w, r, http.StatusServiceUnavailable, "disputes_unavailable")
+            writeUnavailable(w, r, "disputes_unavailable")
             return
         }
 
@@ -331,10 +2686,16 @@ func handleCreateDispute(deps Dependencies) http.HandlerFunc {
             return
         }
 
+        disputedAmount, err := money.Parse(req.DisputedAmount.String(), req.CurrencyCode)
+        if err != nil {
+            security.WriteJSONError(w, r, http.StatusBadRequest, "invalid_amount")
+            return
+        }
+
         dispute, err := deps.DisputesService.CreateDispute(r.Context(), disputes.CreateDisputeRequest{
             JournalEntryID: req.JournalEntryID,
             MerchantID:     req.MerchantID,
-            DisputedAmount: req.DisputedAmount,
+            DisputedAmount: disputedAmount,
             CurrencyCode:   req.CurrencyCode,
             ReasonCode:     req.ReasonCode,
             ReasonText:     req.ReasonText,
@@ -343,6 +2704,18 @@ func handleCreateDispute(deps Dependencies) http.HandlerFunc {
             CreatedBy:      req.CreatedBy,
             Metadata:       disputes.MaskPII(req.Metadata),
         })
+        var exceeded *disputes.DisputeAmountExceededError
+        if errors.As(err, &exceeded) {
+            writeJSON(w, r, http.StatusUnprocessableEntity, disputeAmountExceededResponse{
+                Error:           "dispute_amount_exceeded",
+                CorrelationID:   security.CorrelationIDFromContext(r.Context()),
+                JournalEntryID:  exceeded.JournalEntryID,
+                OriginalAmount:  exceeded.OriginalAmount,
+                DisputedAmount:  exceeded.AlreadyDisputed,
+                RemainingAmount: exceeded.Remaining(),
+            })
+            return
+        }
         if err != nil {
             security.WriteJSONError(w, r, http.StatusBadRequest, "invalid_request")
             return
@@ -358,7 +2731,7 @@ func handleCreateDispute(deps Dependencies) http.HandlerFunc {
 func handleAuthorizeDispute(deps Dependencies) http.HandlerFunc {
     return func(w http.ResponseWriter, r *http.Request) {
         if deps.DisputesService == nil {
-            security.WriteJSONError(w, r, http.StatusServiceUnavailable, "disputes_unavailable")
+            writeUnavailable(w, r, "disputes_unavailable")
             return
         }
 
@@ -391,7 +2764,7 @@ func handleAuthorizeDispute(deps Dependencies) http.HandlerFunc {
 func handleSettleTransaction(deps Dependencies) http.HandlerFunc {
     return func(w http.ResponseWriter, r *http.Request) {
         if deps.DisputesService == nil {
-            security.WriteJSONError(w, r, http.StatusServiceUnavailable, "disputes_unavailable")
+            writeUnavailable(w, r, "disputes_unavailable")
             return
         }
 
@@ -418,7 +2791,7 @@ func handleSettleTransaction(deps Dependencies) http.HandlerFunc {
 func handleInitiateDispute(deps Dependencies) http.HandlerFunc {
     return func(w http.ResponseWriter, r *http.Request) {
         if deps.DisputesService == nil {
-            security.WriteJSONError(w, r, http.StatusServiceUnavailable, "disputes_unavailable")
+            writeUnavailable(w, r, "disputes_unavailable")
             return
         }
 
@@ -451,7 +2824,7 @@ func handleInitiateDispute(deps Dependencies) http.HandlerFunc {
 func handleReverseDispute(deps Dependencies) http.HandlerFunc {
     return func(w http.ResponseWriter, r *http.Request) {
         if deps.DisputesService == nil {
-            security.WriteJSONError(w, r, http.StatusServiceUnavailable, "disputes_unavailable")
+            writeUnavailable(w, r, "disputes_unavailable")
             return
         }
 
@@ -481,10 +2854,107 @@ func handleReverseDispute(deps Dependencies) http.HandlerFunc {
     }
 }
 
+func writeDisputeError(w http.ResponseWriter, r *http.Request, err error) {
+    var transitionErr *disputes.InvalidStateTransitionError
+    switch {
+    case errors.Is(err, disputes.ErrDisputeNotFound):
+        security.WriteJSONError(w, r, http.StatusNotFound, "dispute_not_found")
+    case errors.Is(err, disputes.ErrInvalidDisputeAction):
+        security.WriteJSONError(w, r, http.StatusBadRequest, "validation_error")
+    case errors.As(err, &transitionErr):
+        security.WriteJSONError(w, r, http.StatusConflict, "invalid_dispute_transition")
+    case errors.Is(err, disputes.ErrChargebackNotConfigured):
+        security.WriteJSONError(w, r, http.StatusUnprocessableEntity, "chargeback_not_configured")
+    case errors.Is(err, disputes.ErrHoldNotFound):
+        security.WriteJSONError(w, r, http.StatusNotFound, "hold_not_found")
+    case errors.Is(err, disputes.ErrHoldNotActive):
+        security.WriteJSONError(w, r, http.StatusConflict, "hold_not_active")
+    case errors.Is(err, disputes.ErrInvalidHoldAction):
+        security.WriteJSONError(w, r, http.StatusBadRequest, "validation_error")
+    default:
+        writeLedgerError(w, r, err)
+    }
+}
+
+// handleDisputeAction serves a step in the chargeback cycle that takes an
+// actor and a reason
+func handleDisputeAction(deps Dependencies, act func(ctx context.Context, disputeID, actedBy, reason string) (disputes.DisputeState, error)) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if deps.DisputesService == nil {
+            writeUnavailable(w, r, "disputes_unavailable")
+            return
+        }
+
+        var req disputeActionRequest
+        if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+            security.WriteJSONError(w, r, http.StatusBadRequest, "invalid_json")
+            return
+        }
+
+        status, err := act(r.Context(), chi.URLParam(r, "dispute_id"), req.ActedBy, req.Reason)
+        if err != nil {
+            writeDisputeError(w, r, err)
+            return
+        }
+
+        writeJSON(w, r, http.StatusOK, actionResponse{
+            CorrelationID: security.CorrelationIDFromContext(r.Context()),
+            Success:       true,
+            Status:        string(status),
+        })
+    }
+}
+
+func handleRepresentDispute(deps Dependencies) http.HandlerFunc {
+    return handleDisputeAction(deps, func(ctx context.Context, disputeID, actedBy, reason string) (disputes.DisputeState, error) {
+        return disputes.StateRepresented, deps.DisputesService.RepresentDispute(ctx, disputeID, actedBy, reason)
+    })
+}
+
+func handleEscalateDispute(deps Dependencies) http.HandlerFunc {
+    return handleDisputeAction(deps, func(ctx context.Context, disputeID, actedBy, reason string) (disputes.DisputeState, error) {
+        return deps.DisputesService.EscalateDispute(ctx, disputeID, actedBy, reason)
+    })
+}
+
+func handleAcceptDispute(deps Dependencies) http.HandlerFunc {
+    return handleDisputeAction(deps, func(ctx context.Context, disputeID, actedBy, reason string) (disputes.DisputeState, error) {
+        return disputes.StateAccepted, deps.DisputesService.AcceptDispute(ctx, disputeID, actedBy, reason)
+    })
+}
+
+func handleResolveDispute(deps Dependencies) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if deps.DisputesService == nil {
+            writeUnavailable(w, r, "disputes_unavailable")
+            return
+        }
+
+        var req resolveDisputeRequest
+        if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+            security.WriteJSONError(w, r, http.StatusBadRequest, "invalid_json")
+            return
+        }
+
+        outcome := disputes.DisputeState(req.Outcome)
+        err := deps.DisputesService.ResolveDispute(r.Context(), chi.URLParam(r, "dispute_id"), outcome, req.ResolvedBy, req.Reason)
+        if err != nil {
+            writeDisputeError(w, r, err)
+            return
+        }
+
+        writeJSON(w, r, http.StatusOK, actionResponse{
+            CorrelationID: security.CorrelationIDFromContext(r.Context()),
+            Success:       true,
+            Status:        string(outcome),
+        })
+    }
+}
+
 func handleGetDispute(deps Dependencies) http.HandlerFunc {
     return func(w http.ResponseWriter, r *http.Request) {
         if deps.DisputesService == nil {
-            security.WriteJSONError(w, r, http.StatusServiceUnavailable, "disputes_unavailable")
+            writeUnavailable(w, r, "disputes_unavailable")
             return
         }
 
@@ -516,10 +2986,62 @@ func handleGetDispute(deps Dependencies) http.HandlerFunc {
     }
 }
 
+func handleExtendHold(deps Dependencies) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if deps.DisputesService == nil {
+            writeUnavailable(w, r, "disputes_unavailable")
+            return
+        }
+
+        var req extendHoldRequest
+        if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+            security.WriteJSONError(w, r, http.StatusBadRequest, "invalid_json")
+            return
+        }
+
+        hold, err := deps.DisputesService.ExtendHold(r.Context(), chi.URLParam(r, "hold_id"), req.ExpiresAt, req.ActedBy, req.Reason)
+        if err != nil {
+            writeDisputeError(w, r, err)
+            return
+        }
+
+        writeJSON(w, r, http.StatusOK, holdResponse{
+            CorrelationID: security.CorrelationIDFromContext(r.Context()),
+            Hold:          hold,
+        })
+    }
+}
+
+func handleReleaseHold(deps Dependencies) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if deps.DisputesService == nil {
+            writeUnavailable(w, r, "disputes_unavailable")
+            return
+        }
+
+        var req disputeActionRequest
+        if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+            security.WriteJSONError(w, r, http.StatusBadRequest, "invalid_json")
+            return
+        }
+
+        hold, err := deps.DisputesService.ReleaseHold(r.Context(), chi.URLParam(r, "hold_id"), req.ActedBy, req.Reason)
+        if err != nil {
+            writeDisputeError(w, r, err)
+            return
+        }
+
+        writeJSON(w, r, http.StatusOK, holdResponse{
+            CorrelationID: security.CorrelationIDFromContext(r.Context()),
+            Hold:          hold,
+        })
+    }
+}
+
 func handleListDisputes(deps Dependencies) http.HandlerFunc {
     return func(w http.ResponseWriter, r *http.Request) {
         if deps.DisputesService == nil {
-            security.WriteJSONError(w, r, http.StatusServiceUnavailable, "disputes_unavailable")
+            writeUnavailable(w, r, "disputes_unavailable")
             return
         }
 
@@ -540,6 +3062,17 @@ func handleListDisputes(deps Dependencies) http.HandlerFunc {
             }
         }
 
+        if v := r.URL.Query().Get("overdue"); v != "" {
+            if b, err := strconv.ParseBool(v); err == nil {
+                filter.Overdue = b
+            }
+        }
+        if v := r.URL.Query().Get("due_within"); v != "" {
+            if d, err := time.ParseDuration(v); err == nil {
+                filter.DueWithin = d
+            }
+        }
+
         if v := r.URL.Query().Get("limit"); v != "" {
             if i, err := strconv.Atoi(v); err == nil {
                 filter.Limit = i
@@ -568,7 +3101,7 @@ func handleListDisputes(deps Dependencies) http.HandlerFunc {
 func handleGetDisputeHistory(deps Dependencies) http.HandlerFunc {
     return func(w http.ResponseWriter, r *http.Request) {
         if deps.DisputesService == nil {
-            security.WriteJSONError(w, r, http.StatusServiceUnavailable, "disputes_unavailable")
+            writeUnavailable(w, r, "disputes_unavailable")
             return
         }
 
@@ -595,7 +3128,7 @@ func handleGetDisputeHistory(deps Dependencies) http.HandlerFunc {
 func handleCalculateReserve(deps Dependencies) http.HandlerFunc {
     return func(w http.ResponseWriter, r *http.Request) {
         if deps.DisputesService == nil {
-            security.WriteJSONError(w, r, http.StatusServiceUnavailable, "disputes_unavailable")
+            writeUnavailable(w, r, "disputes_unavailable")
             return
         }
 
@@ -605,32 +3138,151 @@ func handleCalculateReserve(deps Dependencies) http.HandlerFunc {
             return
         }
 
-        transactionVolumeStr := r.URL.Query().Get("transaction_volume")
-        transactionVolume := 0.0
-        if v := transactionVolumeStr; v != "" {
-            if f, err := strconv.ParseFloat(v, 64); err == nil {
-                transactionVolume = f
-            }
-        }
-
         currencyCode := r.URL.Query().Get("currency_code")
         if currencyCode == "" {
             currencyCode = "USD"
         }
 
+        transactionVolume, err := money.Zero(currencyCode)
+        if err != nil {
+            security.WriteJSONError(w, r, http.StatusBadRequest, "validation_error")
+            return
+        }
+        if v := r.URL.Query().Get("transaction_volume"); v != "" {
+            transactionVolume, err = money.Parse(v, currencyCode)
+            if err != nil {
+                security.WriteJSONError(w, r, http.StatusBadRequest, "invalid_amount")
+                return
+            }
+        }
+
         requiredReserve, err := deps.DisputesService.CalculateMerchantReserve(r.Context(), merchantID, transactionVolume)
         if err != nil {
             security.WriteJSONError(w, r, http.StatusBadRequest, "invalid_request")
             return
         }
 
+        zero, _ := money.Zero(currencyCode)
         writeJSON(w, r, http.StatusOK, calculateReserveResponse{
             CorrelationID:     security.CorrelationIDFromContext(r.Context()),
             RequiredReserve:   requiredReserve,
             CurrencyCode:      currencyCode,
-            ReservePercentage: 0.05, // Default 5% - would come from config
-            MinimumReserve:    0.0,
-            CurrentReserve:    0.0,  // Would come from actual merchant data
+            ReservePercentage: money.BasisPoints(500), // Default 5% - would come from config
+            MinimumReserve:    zero,
+            CurrentReserve:    zero, // Would come from actual merchant data
+        })
+    }
+}
+
+type uploadEvidenceRequest struct {
+    EvidenceType string                 `json:"evidence_type"`
+    Description  string                 `json:"description"`
+    FileName     string                 `json:"file_name"`
+    MIMEType     string                 `json:"mime_type"`
+    Content      []byte                 `json:"content"`
+    UploadedBy   string                 `json:"uploaded_by"`
+    Metadata     map[string]interface{} `json:"metadata"`
+}
+
+type evidenceResponse struct {
+    CorrelationID string             `json:"correlation_id"`
+    Evidence      *disputes.Evidence `json:"evidence"`
+}
+
+type listEvidenceResponse struct {
+    CorrelationID string               `json:"correlation_id"`
+    Evidence      []*disputes.Evidence `json:"evidence"`
+}
+
+// writeEvidenceError maps evidence store errors to responses
+func writeEvidenceError(w http.ResponseWriter, r *http.Request, err error) {
+    switch {
+    case errors.Is(err, disputes.ErrDisputeNotFound):
+        security.WriteJSONError(w, r, http.StatusNotFound, "dispute_not_found")
+    case errors.Is(err, disputes.ErrEvidenceNotFound):
+        security.WriteJSONError(w, r, http.StatusNotFound, "evidence_not_found")
+    case errors.Is(err, disputes.ErrEvidenceTooLarge):
+        security.WriteJSONError(w, r, http.StatusRequestEntityTooLarge, "evidence_too_large")
+    case errors.Is(err, disputes.ErrUnsupportedEvidenceType):
+        security.WriteJSONError(w, r, http.StatusUnsupportedMediaType, "unsupported_media_type")
+    case errors.Is(err, disputes.ErrInvalidEvidence):
+        security.WriteJSONError(w, r, http.StatusBadRequest, "validation_error")
+    default:
+        security.WriteJSONError(w, r, http.StatusInternalServerError, "internal_error")
+    }
+}
+
+func handleUploadEvidence(deps Dependencies) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if deps.DisputeEvidence == nil {
+            writeUnavailable(w, r, "evidence_unavailable")
+            return
+        }
+
+        var req uploadEvidenceRequest
+        if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+            security.WriteJSONError(w, r, http.StatusBadRequest, "invalid_json")
+            return
+        }
+
+        evidence, err := deps.DisputeEvidence.UploadEvidence(r.Context(), disputes.UploadEvidenceRequest{
+            DisputeID:    chi.URLParam(r, "dispute_id"),
+            EvidenceType: req.EvidenceType,
+            Description:  req.Description,
+            FileName:     req.FileName,
+            MIMEType:     req.MIMEType,
+            Content:      req.Content,
+            Metadata:     req.Metadata,
+            UploadedBy:   req.UploadedBy,
+        })
+        if err != nil {
+            writeEvidenceError(w, r, err)
+            return
+        }
+
+        writeJSON(w, r, http.StatusCreated, evidenceResponse{
+            CorrelationID: security.CorrelationIDFromContext(r.Context()),
+            Evidence:      evidence,
+        })
+    }
+}
+
+func handleListEvidence(deps Dependencies) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if deps.DisputeEvidence == nil {
+            writeUnavailable(w, r, "evidence_unavailable")
+            return
+        }
+
+        evidence, err := deps.DisputeEvidence.ListEvidence(r.Context(), chi.URLParam(r, "dispute_id"))
+        if err != nil {
+            writeEvidenceError(w, r, err)
+            return
+        }
+
+        writeJSON(w, r, http.StatusOK, listEvidenceResponse{
+            CorrelationID: security.CorrelationIDFromContext(r.Context()),
+            Evidence:      evidence,
+        })
+    }
+}
+
+func handleDownloadEvidence(deps Dependencies) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if deps.DisputeEvidence == nil {
+            writeUnavailable(w, r, "evidence_unavailable")
+            return
+        }
+
+        evidence, content, err := deps.DisputeEvidence.DownloadEvidence(r.Context(), chi.URLParam(r, "dispute_id"), chi.URLParam(r, "evidence_id"))
+        if err != nil {
+            writeEvidenceError(w, r, err)
+            return
+        }
+
+        writeDownload(w, r, evidence.MIMEType, evidence.FileName, func(out io.Writer) error {
+            _, err := out.Write(content)
+            return err
         })
     }
 }
@@ -0,0 +1,277 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/example/pci-infra/internal/auth"
+	"github.com/example/pci-infra/internal/security"
+)
+
+// IdempotencyKeyHeader is the request header carrying the client-chosen key
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// IdempotentReplayedHeader is set on responses served from a stored result
+const IdempotentReplayedHeader = "Idempotent-Replayed"
+
+const maxIdempotencyKeyLength = 255
+
+// idempotencyHeartbeat is how often the claim on a key is extended while its
+// request is handled; stores must hold an unextended claim for longer
+var idempotencyHeartbeat = 15 * time.Second
+
+// IdempotencyRecord is the stored outcome of the first request made with a key
+type IdempotencyRecord struct {
+	Fingerprint string
+	Completed   bool
+	StatusCode  int
+	ContentType string
+	Body        []byte
+}
+
+// IdempotencyStore persists idempotency keys per client
+type IdempotencyStore interface {
+	// Begin claims key for clientID. It returns (nil, nil) when the caller now owns
+	// the key and must Complete or Release it, or the existing record otherwise.
+	Begin(ctx context.Context, clientID, key, method, path, fingerprint string) (*IdempotencyRecord, error)
+	// Extend renews the claim on a key while its request is still being
+	// handled, so a slow request is never taken over by a retry
+	Extend(ctx context.Context, clientID, key string) error
+	// Complete stores the response for a key claimed by Begin
+	Complete(ctx context.Context, clientID, key string, statusCode int, contentType string, body []byte) error
+	// Hold keeps a claimed key in use until it expires. It is used when the
+	// request succeeded but its response could not be stored, so the request
+	// is never run again.
+	Hold(ctx context.Context, clientID, key string) error
+	// Release forgets a claimed key so the request can be retried
+	Release(ctx context.Context, clientID, key string) error
+}
+
+// idempotencyClaimKey is the context key of the claim on a request's key
+type idempotencyClaimKey struct{}
+
+// idempotencyClaim is a request's claim on its Idempotency-Key
+type idempotencyClaim struct {
+	uncommitted atomic.Bool
+}
+
+// releaseIdempotencyKey marks a request as having failed before changing
+// anything, so its Idempotency-Key is released rather than kept with the
+// error response and the client can retry under the same key. Handlers only
+// call it when they know nothing was written.
+func releaseIdempotencyKey(r *http.Request) {
+	if claim, ok := r.Context().Value(idempotencyClaimKey{}).(*idempotencyClaim); ok {
+		claim.uncommitted.Store(true)
+	}
+}
+
+// captureWriter records the response while passing it through
+type captureWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *captureWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *captureWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+// requestFingerprint hashes the parts of a request that must match on replay
+func requestFingerprint(method, path string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method))
+	h.Write([]byte{0})
+	h.Write([]byte(path))
+	h.Write([]byte{0})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Idempotency makes write endpoints safe to retry. Requests carrying an
+// Idempotency-Key are recorded per JWT client_id: a replay with the same
+// payload returns the stored response, a different payload is rejected with
+// 422. Server errors are stored too, as the request may have committed before
+// failing; only a handler that calls releaseIdempotencyKey gives the key back
+// for a retry. A panic holds the key until it expires. Requests without the
+// header, or a nil store, pass through unchanged.
+func Idempotency(store IdempotencyStore, logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if store == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(IdempotencyKeyHeader)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > maxIdempotencyKeyLength {
+				security.WriteJSONError(w, r, http.StatusBadRequest, "invalid_idempotency_key")
+				return
+			}
+
+			ai, ok := auth.AuthInfoFromContext(r.Context())
+			if !ok || ai.ClientID == "" {
+				security.WriteJSONError(w, r, http.StatusUnauthorized, "unauthorized")
+				return
+			}
+
+			var body []byte
+			if r.Body != nil {
+				b, err := io.ReadAll(r.Body)
+				if err != nil {
+					var mbe *http.MaxBytesError
+					if errors.As(err, &mbe) {
+						security.WriteJSONError(w, r, http.StatusRequestEntityTooLarge, "payload_too_large")
+						return
+					}
+					security.WriteJSONError(w, r, http.StatusBadRequest, "invalid_request")
+					return
+				}
+				_ = r.Body.Close()
+				body = b
+				r.Body = io.NopCloser(bytes.NewReader(body))
+			}
+
+			fingerprint := requestFingerprint(r.Method, r.URL.Path, body)
+
+			ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+			rec, err := store.Begin(ctx, ai.ClientID, key, r.Method, r.URL.Path, fingerprint)
+			cancel()
+			if err != nil {
+				security.WriteJSONError(w, r, http.StatusServiceUnavailable, "idempotency_unavailable")
+				return
+			}
+
+			if rec != nil {
+				if rec.Fingerprint != fingerprint {
+					security.WriteJSONError(w, r, http.StatusUnprocessableEntity, "idempotency_key_mismatch")
+					return
+				}
+				if !rec.Completed {
+					security.WriteJSONError(w, r, http.StatusConflict, "idempotency_key_in_use")
+					return
+				}
+				replay(w, r, rec)
+				return
+			}
+
+			hold := func(ctx context.Context) {
+				if err := store.Hold(ctx, ai.ClientID, key); err != nil {
+					logger.Error("failed to hold idempotency key, a retry may run the request again once its claim lapses",
+						"client_id", ai.ClientID, "idempotency_key", key, "error", err)
+				}
+			}
+
+			claim := &idempotencyClaim{}
+			r = r.WithContext(context.WithValue(r.Context(), idempotencyClaimKey{}, claim))
+			stopExtending := extendClaim(store, logger, ai.ClientID, key)
+			cw := &captureWriter{ResponseWriter: w}
+			defer func() {
+				if p := recover(); p != nil {
+					stopExtending()
+					// The handler may have committed before panicking
+					ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+					defer cancel()
+					logger.Error("idempotent request panicked, holding key until it expires",
+						"client_id", ai.ClientID, "idempotency_key", key)
+					hold(ctx)
+					panic(p)
+				}
+			}()
+
+			next.ServeHTTP(cw, r)
+			stopExtending()
+
+			ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if claim.uncommitted.Load() {
+				if err := store.Release(ctx, ai.ClientID, key); err != nil {
+					logger.Warn("failed to release idempotency key", "client_id", ai.ClientID, "idempotency_key", key, "error", err)
+				}
+				return
+			}
+
+			// A handler that wrote nothing sent an empty 200
+			status := cw.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			// If storing the response fails, hold the key until it expires so a
+			// retry is blocked rather than risking a double post
+			err = store.Complete(ctx, ai.ClientID, key, status, cw.Header().Get("Content-Type"), cw.body.Bytes())
+			if err == nil {
+				return
+			}
+			logger.Error("failed to store idempotent response, holding key until it expires",
+				"client_id", ai.ClientID, "idempotency_key", key, "status", status, "error", err)
+			hold(ctx)
+		})
+	}
+}
+
+// extendClaim extends the claim on key every idempotencyHeartbeat until the
+// returned func is called
+func extendClaim(store IdempotencyStore, logger *slog.Logger, clientID, key string) (stop func()) {
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(idempotencyHeartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				if err := store.Extend(ctx, clientID, key); err != nil {
+					logger.Warn("failed to extend idempotency key", "client_id", clientID, "idempotency_key", key, "error", err)
+				}
+				cancel()
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			wg.Wait()
+		})
+	}
+}
+
+func replay(w http.ResponseWriter, r *http.Request, rec *IdempotencyRecord) {
+	if cid := security.CorrelationIDFromContext(r.Context()); cid != "" {
+		w.Header().Set(security.CorrelationIDHeader, cid)
+	}
+	if rec.ContentType != "" {
+		w.Header().Set("Content-Type", rec.ContentType)
+	}
+	w.Header().Set(IdempotentReplayedHeader, "true")
+	w.WriteHeader(rec.StatusCode)
+	_, _ = w.Write(rec.Body)
+}
@@ -0,0 +1,157 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PostgresIdempotencyStore keeps idempotency keys in the idempotency_keys table
+type PostgresIdempotencyStore struct {
+	Pool *pgxpool.Pool
+	// TTL is how long a completed response is replayed; defaults to 24h
+	TTL time.Duration
+	// LockTimeout is how long an unfinished claim blocks the key unless it is
+	// extended, after which it can be taken over, e.g. after a crash
+	// mid-request; defaults to 1m and must exceed idempotencyHeartbeat
+	LockTimeout time.Duration
+}
+
+func (s *PostgresIdempotencyStore) lockTimeout() time.Duration {
+	if s.LockTimeout == 0 {
+		return time.Minute
+	}
+	return s.LockTimeout
+}
+
+func (s *PostgresIdempotencyStore) Begin(ctx context.Context, clientID, key, method, path, fingerprint string) (*IdempotencyRecord, error) {
+	if s.Pool == nil {
+		return nil, errors.New("missing pool")
+	}
+
+	ttl := s.TTL
+	if ttl == 0 {
+		ttl = 24 * time.Hour
+	}
+
+	// Claim the key, taking over rows that expired or whose claim lapsed
+	// mid-request
+	var claimed bool
+	err := s.Pool.QueryRow(ctx, `
+		INSERT INTO idempotency_keys (client_id, idempotency_key, request_method, request_path, request_fingerprint, locked_until, expires_at)
+		VALUES ($1, $2, $3, $4, $5, CURRENT_TIMESTAMP + $7 * INTERVAL '1 second', CURRENT_TIMESTAMP + $6 * INTERVAL '1 second')
+		ON CONFLICT (client_id, idempotency_key) DO UPDATE SET
+			request_method = EXCLUDED.request_method,
+			request_path = EXCLUDED.request_path,
+			request_fingerprint = EXCLUDED.request_fingerprint,
+			status = 'in_progress',
+			response_status = NULL,
+			response_content_type = NULL,
+			response_body = NULL,
+			created_at = CURRENT_TIMESTAMP,
+			completed_at = NULL,
+			locked_until = EXCLUDED.locked_until,
+			expires_at = EXCLUDED.expires_at
+		WHERE idempotency_keys.expires_at < CURRENT_TIMESTAMP
+			OR (idempotency_keys.status = 'in_progress'
+				AND idempotency_keys.locked_until < CURRENT_TIMESTAMP)
+		RETURNING true
+	`, clientID, key, method, path, fingerprint, ttl.Seconds(), s.lockTimeout().Seconds()).Scan(&claimed)
+	if err == nil {
+		return nil, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("failed to claim idempotency key: %w", err)
+	}
+
+	var rec IdempotencyRecord
+	var status string
+	var statusCode *int32
+	var contentType *string
+	err = s.Pool.QueryRow(ctx, `
+		SELECT request_fingerprint, status, response_status, response_content_type, response_body
+		FROM idempotency_keys
+		WHERE client_id = $1 AND idempotency_key = $2
+	`, clientID, key).Scan(&rec.Fingerprint, &status, &statusCode, &contentType, &rec.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load idempotency key: %w", err)
+	}
+
+	rec.Completed = status == "completed"
+	if statusCode != nil {
+		rec.StatusCode = int(*statusCode)
+	}
+	if contentType != nil {
+		rec.ContentType = *contentType
+	}
+	return &rec, nil
+}
+
+func (s *PostgresIdempotencyStore) Extend(ctx context.Context, clientID, key string) error {
+	if s.Pool == nil {
+		return errors.New("missing pool")
+	}
+
+	// Never shorten a claim that Hold extended to the key's expiry
+	_, err := s.Pool.Exec(ctx, `
+		UPDATE idempotency_keys
+		SET locked_until = GREATEST(locked_until, CURRENT_TIMESTAMP + $3 * INTERVAL '1 second')
+		WHERE client_id = $1 AND idempotency_key = $2 AND status = 'in_progress'
+	`, clientID, key, s.lockTimeout().Seconds())
+	if err != nil {
+		return fmt.Errorf("failed to extend idempotency key: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresIdempotencyStore) Hold(ctx context.Context, clientID, key string) error {
+	if s.Pool == nil {
+		return errors.New("missing pool")
+	}
+
+	_, err := s.Pool.Exec(ctx, `
+		UPDATE idempotency_keys
+		SET locked_until = expires_at
+		WHERE client_id = $1 AND idempotency_key = $2 AND status = 'in_progress'
+	`, clientID, key)
+	if err != nil {
+		return fmt.Errorf("failed to hold idempotency key: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresIdempotencyStore) Complete(ctx context.Context, clientID, key string, statusCode int, contentType string, body []byte) error {
+	if s.Pool == nil {
+		return errors.New("missing pool")
+	}
+
+	_, err := s.Pool.Exec(ctx, `
+		UPDATE idempotency_keys
+		SET status = 'completed', response_status = $3, response_content_type = $4,
+			response_body = $5, completed_at = CURRENT_TIMESTAMP
+		WHERE client_id = $1 AND idempotency_key = $2 AND status = 'in_progress'
+	`, clientID, key, statusCode, contentType, body)
+	if err != nil {
+		return fmt.Errorf("failed to store idempotent response: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresIdempotencyStore) Release(ctx context.Context, clientID, key string) error {
+	if s.Pool == nil {
+		return errors.New("missing pool")
+	}
+
+	_, err := s.Pool.Exec(ctx, `
+		DELETE FROM idempotency_keys
+		WHERE client_id = $1 AND idempotency_key = $2 AND status = 'in_progress'
+	`, clientID, key)
+	if err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
@@ -1,7 +1,9 @@
 package api
 
 import (
+	"bytes"
 	"encoding/json"
+	"io"
 	"net/http"
 
 	"github.com/example/pci-infra/internal/security"
@@ -16,3 +18,35 @@ func writeJSON(w http.ResponseWriter, r *http.Request, status int, v any) {
 	w.WriteHeader(status)
 	_ = json.NewEncoder(w).Encode(v)
 }
+
+// writeUnavailable reports that a service the handler needs is not
+// configured. Nothing was changed, so an idempotent request may be retried
+// under the same key.
+func writeUnavailable(w http.ResponseWriter, r *http.Request, code string) {
+	releaseIdempotencyKey(r)
+	security.WriteJSONError(w, r, http.StatusServiceUnavailable, code)
+}
+
+// writeCSV renders a CSV download
+func writeCSV(w http.ResponseWriter, r *http.Request, filename string, render func(io.Writer) error) {
+	writeDownload(w, r, "text/csv; charset=utf-8", filename, render)
+}
+
+// writeDownload renders a file download. The body is buffered so a rendering
+// failure still produces an error response.
+func writeDownload(w http.ResponseWriter, r *http.Request, contentType, filename string, render func(io.Writer) error) {
+	var buf bytes.Buffer
+	if err := render(&buf); err != nil {
+		security.WriteJSONError(w, r, http.StatusInternalServerError, "internal_error")
+		return
+	}
+
+	cid := security.CorrelationIDFromContext(r.Context())
+	if cid != "" {
+		w.Header().Set(security.CorrelationIDHeader, cid)
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(buf.Bytes())
+}
@@ -5,6 +5,7 @@ import (
     "log/slog"
     "net"
     "net/http"
+    "time"
 
     "github.com/go-chi/chi/v5"
     "github.com/go-chi/chi/v5/middleware"
@@ -12,7 +13,13 @@ import (
     "github.com/example/pci-infra/internal/auth"
     "github.com/example/pci-infra/internal/disputes"
     "github.com/example/pci-infra/internal/ledger"
+    "github.com/example/pci-infra/internal/money"
+    "github.com/example/pci-infra/internal/fees"
+    "github.com/example/pci-infra/internal/payouts"
+    "github.com/example/pci-infra/internal/reconciliation"
+    "github.com/example/pci-infra/internal/reporting"
     "github.com/example/pci-infra/internal/security"
+    "github.com/example/pci-infra/internal/webhooks"
     "github.com/example/pci-infra/pkg/audit"
 )
 
@@ -27,12 +34,83 @@ type Dependencies struct {
 
     LedgerReader interface {
         ListAccounts(ctx context.Context, filter ledger.AccountFilter) ([]*ledger.Account, error)
-        GetBalance(ctx context.Context, accountID string) (float64, error)
+        GetBalance(ctx context.Context, accountID string) (money.Money, error)
+        GetBalanceAt(ctx context.Context, accountID string, at time.Time) (money.Money, error)
+        GetStatement(ctx context.Context, req ledger.StatementRequest) (*ledger.Statement, error)
+        GetAccountBalances(ctx context.Context, accountID string) (*ledger.AccountBalances, error)
+        GetPendingTransaction(ctx context.Context, id string) (*ledger.PendingTransaction, error)
+        GetTransaction(ctx context.Context, transactionID string) (*ledger.TransactionDetail, error)
+        GetFXRate(ctx context.Context, base, quote string, at time.Time) (*ledger.FXRate, error)
+        GetAccountStatusHistory(ctx context.Context, accountID string) ([]ledger.AccountStatusTransition, error)
+        GetAccountTree(ctx context.Context, accountID string) (*ledger.AccountNode, error)
+        ListPeriods(ctx context.Context) ([]ledger.AccountingPeriod, error)
+        GetPeriod(ctx context.Context, periodID string) (*ledger.AccountingPeriod, error)
+        GetCreditLimit(ctx context.Context, accountID string, at time.Time) (*ledger.CreditLimit, error)
+        ListCreditLimits(ctx context.Context, accountID string) ([]ledger.CreditLimit, error)
     }
     LedgerWriter interface {
         CreateAccount(ctx context.Context, req ledger.CreateAccountRequest) (*ledger.Account, error)
         Debit(ctx context.Context, req ledger.DebitRequest) error
         Credit(ctx context.Context, req ledger.CreditRequest) error
+        Transfer(ctx context.Context, req ledger.TransferRequest) error
+        PostTransaction(ctx context.Context, txn ledger.Transaction) (*ledger.Transaction, error)
+        AuthorizePending(ctx context.Context, req ledger.AuthorizeRequest) (*ledger.PendingTransaction, error)
+        CapturePending(ctx context.Context, req ledger.CaptureRequest) (*ledger.PendingTransaction, error)
+        VoidPending(ctx context.Context, req ledger.VoidRequest) (*ledger.PendingTransaction, error)
+        ReverseTransaction(ctx context.Context, req ledger.ReverseRequest) (*ledger.Reversal, error)
+        SetFXRate(ctx context.Context, req ledger.SetFXRateRequest) (*ledger.FXRate, error)
+        SetFXAccounts(ctx context.Context, accts ledger.FXAccounts) error
+        FXTransfer(ctx context.Context, req ledger.FXTransferRequest) (*ledger.FXConversion, error)
+        FreezeAccount(ctx context.Context, req ledger.AccountStatusRequest) (*ledger.AccountStatusTransition, error)
+        UnfreezeAccount(ctx context.Context, req ledger.AccountStatusRequest) (*ledger.AccountStatusTransition, error)
+        BlockAccount(ctx context.Context, req ledger.AccountStatusRequest) (*ledger.AccountStatusTransition, error)
+        CloseAccount(ctx context.Context, req ledger.AccountStatusRequest) (*ledger.AccountStatusTransition, error)
+        CreatePeriod(ctx context.Context, req ledger.CreatePeriodRequest) (*ledger.AccountingPeriod, error)
+        SoftClosePeriod(ctx context.Context, req ledger.ClosePeriodRequest) (*ledger.AccountingPeriod, error)
+        HardClosePeriod(ctx context.Context, req ledger.ClosePeriodRequest) (*ledger.AccountingPeriod, error)
+        SetRetainedEarningsAccount(ctx context.Context, acct ledger.RetainedEarningsAccount) error
+        SetCreditLimit(ctx context.Context, req ledger.SetCreditLimitRequest) (*ledger.CreditLimit, error)
+    }
+    Reports interface {
+        TrialBalance(ctx context.Context, req reporting.Request) (*reporting.TrialBalance, error)
+        BalanceSheet(ctx context.Context, req reporting.Request) (*reporting.BalanceSheet, error)
+        IncomeStatement(ctx context.Context, req reporting.Request) (*reporting.IncomeStatement, error)
+    }
+    Reconciliation interface {
+        ImportStatement(ctx context.Context, req reconciliation.ImportRequest) (*reconciliation.Statement, error)
+        Match(ctx context.Context, statementID string) (*reconciliation.Result, error)
+        Result(ctx context.Context, statementID string) (*reconciliation.Result, error)
+        ConfirmMatch(ctx context.Context, req reconciliation.DecisionRequest) (*reconciliation.Match, error)
+        RejectMatch(ctx context.Context, req reconciliation.DecisionRequest) (*reconciliation.Match, error)
+    }
+    Webhooks interface {
+        CreateSubscription(ctx context.Context, req webhooks.CreateRequest) (*webhooks.Subscription, error)
+        ListSubscriptions(ctx context.Context, owner string) ([]webhooks.Subscription, error)
+        GetSubscription(ctx context.Context, owner, id string) (*webhooks.Subscription, error)
+        UpdateSubscription(ctx context.Context, req webhooks.UpdateRequest) (*webhooks.Subscription, error)
+        DeleteSubscription(ctx context.Context, owner, id string) error
+        RotateSecret(ctx context.Context, owner, id string) (*webhooks.Subscription, error)
+        ListDeliveries(ctx context.Context, filter webhooks.DeliveryFilter) ([]webhooks.Delivery, error)
+        GetDelivery(ctx context.Context, owner, id string) (*webhooks.Delivery, error)
+        ReplayDelivery(ctx context.Context, owner, id string) (*webhooks.Delivery, error)
+    }
+    Payouts interface {
+        SetSettings(ctx context.Context, req payouts.Settings) (*payouts.Settings, error)
+        GetSettings(ctx context.Context, merchantID string) (*payouts.Settings, error)
+        Compute(ctx context.Context, merchantID string) (*payouts.Computation, error)
+        RequestPayout(ctx context.Context, req payouts.PayoutRequest) (*payouts.Payout, error)
+        GetPayout(ctx context.Context, id string) (*payouts.Payout, error)
+        ListPayouts(ctx context.Context, filter payouts.PayoutFilter) ([]payouts.Payout, error)
+        MarkSent(ctx context.Context, req payouts.TransitionRequest) (*payouts.Payout, error)
+        MarkPaid(ctx context.Context, req payouts.TransitionRequest) (*payouts.Payout, error)
+        MarkFailed(ctx context.Context, req payouts.TransitionRequest) (*payouts.Payout, error)
+    }
+    Fees interface {
+        CreateSchedule(ctx context.Context, req fees.ScheduleRequest) (*fees.Schedule, error)
+        GetSchedule(ctx context.Context, id string) (*fees.Schedule, error)
+        ListSchedules(ctx context.Context, filter fees.ScheduleFilter) ([]fees.Schedule, error)
+        Quote(ctx context.Context, req fees.QuoteRequest) (*fees.Fee, error)
+        Charge(ctx context.Context, req fees.ChargeRequest) (*fees.Fee, error)
     }
     DisputesService interface {
         CreateDispute(ctx context.Context, req disputes.CreateDisputeRequest) (*disputes.Dispute, error)
@@ -40,12 +118,24 @@ type Dependencies struct {
         SettleTransaction(ctx context.Context, journalEntryID, settledBy string) error
         InitiateDispute(ctx context.Context, disputeID, initiatedBy string) error
         ReverseDispute(ctx context.Context, disputeID, reversedBy, reason string) error
+        RepresentDispute(ctx context.Context, disputeID, representedBy, reason string) error
+        EscalateDispute(ctx context.Context, disputeID, escalatedBy, reason string) (disputes.DisputeState, error)
+        AcceptDispute(ctx context.Context, disputeID, acceptedBy, reason string) error
+        ResolveDispute(ctx context.Context, disputeID string, outcome disputes.DisputeState, resolvedBy, reason string) error
+        ExtendHold(ctx context.Context, holdID string, expiresAt time.Time, extendedBy, reason string) (*disputes.Hold, error)
+        ReleaseHold(ctx context.Context, holdID, releasedBy, reason string) (*disputes.Hold, error)
         GetDispute(ctx context.Context, disputeID string) (*disputes.Dispute, error)
         ListDisputes(ctx context.Context, filter disputes.DisputeFilter) ([]*disputes.Dispute, error)
-        CalculateMerchantReserve(ctx context.Context, merchantID string, transactionVolume float64) (float64, error)
+        CalculateMerchantReserve(ctx context.Context, merchantID string, transactionVolume money.Money) (money.Money, error)
+    }
+    DisputeEvidence interface {
+        UploadEvidence(ctx context.Context, req disputes.UploadEvidenceRequest) (*disputes.Evidence, error)
+        ListEvidence(ctx context.Context, disputeID string) ([]*disputes.Evidence, error)
+        DownloadEvidence(ctx context.Context, disputeID, evidenceID string) (*disputes.Evidence, []byte, error)
     }
 
     Auditor      Auditor
+    Idempotency  IdempotencyStore
     RateLimiter  *security.RedisTokenBucket
     IPAllowlist  []*net.IPNet
     MaxBodyBytes int64
@@ -68,11 +158,114 @@ func NewRouter(deps Dependencies) (http.Handler, error) {
     if err != nil {
         return nil, err
     }
+    transferV, err := security.NewJSONSchemaValidator(transferSchema)
+    if err != nil {
+        return nil, err
+    }
+    postTransactionV, err := security.NewJSONSchemaValidator(postTransactionSchema)
+    if err != nil {
+        return nil, err
+    }
+    authorizePendingV, err := security.NewJSONSchemaValidator(authorizePendingSchema)
+    if err != nil {
+        return nil, err
+    }
+    capturePendingV, err := security.NewJSONSchemaValidator(capturePendingSchema)
+    if err != nil {
+        return nil, err
+    }
+    voidPendingV, err := security.NewJSONSchemaValidator(voidPendingSchema)
+    if err != nil {
+        return nil, err
+    }
+    reverseTransactionV, err := security.NewJSONSchemaValidator(reverseTransactionSchema)
+    if err != nil {
+        return nil, err
+    }
+    setFXRateV, err := security.NewJSONSchemaValidator(setFXRateSchema)
+    if err != nil {
+        return nil, err
+    }
+    setFXAccountsV, err := security.NewJSONSchemaValidator(setFXAccountsSchema)
+    if err != nil {
+        return nil, err
+    }
+    fxTransferV, err := security.NewJSONSchemaValidator(fxTransferSchema)
+    if err != nil {
+        return nil, err
+    }
+    accountStatusV, err := security.NewJSONSchemaValidator(accountStatusSchema)
+    if err != nil {
+        return nil, err
+    }
+    setCreditLimitV, err := security.NewJSONSchemaValidator(setCreditLimitSchema)
+    if err != nil {
+        return nil, err
+    }
+    createPeriodV, err := security.NewJSONSchemaValidator(createPeriodSchema)
+    if err != nil {
+        return nil, err
+    }
+    closePeriodV, err := security.NewJSONSchemaValidator(closePeriodSchema)
+    if err != nil {
+        return nil, err
+    }
+    setRetainedEarningsV, err := security.NewJSONSchemaValidator(setRetainedEarningsSchema)
+    if err != nil {
+        return nil, err
+    }
+    importStatementV, err := security.NewJSONSchemaValidator(importStatementSchema)
+    if err != nil {
+        return nil, err
+    }
+    decideMatchV, err := security.NewJSONSchemaValidator(decideMatchSchema)
+    if err != nil {
+        return nil, err
+    }
+    createWebhookV, err := security.NewJSONSchemaValidator(createWebhookSchema)
+    if err != nil {
+        return nil, err
+    }
+    updateWebhookV, err := security.NewJSONSchemaValidator(updateWebhookSchema)
+    if err != nil {
+        return nil, err
+    }
+    payoutSettingsV, err := security.NewJSONSchemaValidator(payoutSettingsSchema)
+    if err != nil {
+        return nil, err
+    }
+    requestPayoutV, err := security.NewJSONSchemaValidator(requestPayoutSchema)
+    if err != nil {
+        return nil, err
+    }
+    payoutTransitionV, err := security.NewJSONSchemaValidator(payoutTransitionSchema)
+    if err != nil {
+        return nil, err
+    }
+    failPayoutV, err := security.NewJSONSchemaValidator(failPayoutSchema)
+    if err != nil {
+        return nil, err
+    }
+    feeScheduleV, err := security.NewJSONSchemaValidator(createFeeScheduleSchema)
+    if err != nil {
+        return nil, err
+    }
+    chargeFeeV, err := security.NewJSONSchemaValidator(chargeFeeSchema)
+    if err != nil {
+        return nil, err
+    }
+    uploadEvidenceV, err := security.NewJSONSchemaValidator(uploadEvidenceSchema)
+    if err != nil {
+        return nil, err
+    }
 
     onAuthError := func(w http.ResponseWriter, r *http.Request, status int, code string) {
         security.WriteJSONError(w, r, status, code)
     }
 
+    // Writes replay the stored response when retried with the same Idempotency-Key
+    idempotent := Idempotency(deps.Idempotency, deps.Logger)
+
     r := chi.NewRouter()
     r.Use(middleware.Recoverer)
     r.Use(security.CorrelationID)
@@ -103,44 +296,175 @@ func NewRouter(deps Dependencies) (http.Handler, error) {
             list.Get("/", handleListAccounts(deps))
             list.Get("", handleListAccounts(deps))
 
-            create := r.With(auth.RequireScopes("accounts:write", onAuthError), createAccountV.Middleware)
+            create := r.With(auth.RequireScopes("accounts:write", onAuthError), idempotent, createAccountV.Middleware)
             create.Post("/", handleCreateAccount(deps))
             create.Post("", handleCreateAccount(deps))
+
+            r.With(auth.RequireScopes("ledger:read", onAuthError)).Get("/{id}/statement", handleStatement(deps))
+            r.With(auth.RequireScopes("ledger:read", onAuthError)).Get("/{id}/balances", handleAccountBalances(deps))
+
+            r.With(auth.RequireScopes("accounts:read", onAuthError)).Get("/{id}/tree", handleAccountTree(deps))
+            r.With(auth.RequireScopes("accounts:read", onAuthError)).Get("/{id}/status-history", handleAccountStatusHistory(deps))
+            lifecycle := r.With(auth.RequireScopes("accounts:write", onAuthError), idempotent, accountStatusV.Middleware)
+            lifecycle.Post("/{id}/freeze", handleChangeAccountStatus(deps, ledger.AccountStatusFrozen))
+            lifecycle.Post("/{id}/unfreeze", handleChangeAccountStatus(deps, ledger.AccountStatusActive))
+            lifecycle.Post("/{id}/block", handleChangeAccountStatus(deps, ledger.AccountStatusBlocked))
+            lifecycle.Post("/{id}/close", handleChangeAccountStatus(deps, ledger.AccountStatusClosed))
+
+            r.With(auth.RequireScopes("accounts:read", onAuthError)).Get("/{id}/credit-limit", handleGetCreditLimit(deps))
+            r.With(auth.RequireScopes("accounts:read", onAuthError)).Get("/{id}/credit-limits", handleListCreditLimits(deps))
+            r.With(auth.RequireScopes("accounts:write", onAuthError), idempotent, setCreditLimitV.Middleware).Post("/{id}/credit-limits", handleSetCreditLimit(deps))
         })
 
         r.Route("/ledger", func(r chi.Router) {
-            r.With(auth.RequireScopes("ledger:write", onAuthError), debitV.Middleware).Post("/debit", handleDebit(deps))
-            r.With(auth.RequireScopes("ledger:write", onAuthError), creditV.Middleware).Post("/credit", handleCredit(deps))
+            r.Use(allowAdjustments)
+
+            r.With(auth.RequireScopes("ledger:write", onAuthError), idempotent, debitV.Middleware).Post("/debit", handleDebit(deps))
+            r.With(auth.RequireScopes("ledger:write", onAuthError), idempotent, creditV.Middleware).Post("/credit", handleCredit(deps))
+            r.With(auth.RequireScopes("ledger:write", onAuthError), idempotent, transferV.Middleware).Post("/transfer", handleTransfer(deps))
+            r.With(auth.RequireScopes("ledger:write", onAuthError), idempotent, allowOffMarketFXQuotes, fxTransferV.Middleware).Post("/fx-transfer", handleFXTransfer(deps))
+            r.With(auth.RequireScopes("ledger:write", onAuthError), idempotent, postTransactionV.Middleware).Post("/transactions", handlePostTransaction(deps))
+            r.With(auth.RequireScopes("ledger:read", onAuthError)).Get("/transactions/{id}", handleGetTransaction(deps))
+            r.With(auth.RequireScopes("ledger:write", onAuthError), idempotent, reverseTransactionV.Middleware).Post("/transactions/{id}/reverse", handleReverseTransaction(deps))
             r.With(auth.RequireScopes("ledger:read", onAuthError)).Get("/balance", handleBalance(deps))
+
+            r.With(auth.RequireScopes("ledger:write", onAuthError), idempotent, authorizePendingV.Middleware).Post("/pending", handleAuthorizePending(deps))
+            r.With(auth.RequireScopes("ledger:read", onAuthError)).Get("/pending/{pending_id}", handleGetPending(deps))
+            r.With(auth.RequireScopes("ledger:write", onAuthError), idempotent, capturePendingV.Middleware).Post("/pending/{pending_id}/capture", handleCapturePending(deps))
+            r.With(auth.RequireScopes("ledger:write", onAuthError), idempotent, voidPendingV.Middleware).Post("/pending/{pending_id}/void", handleVoidPending(deps))
+        })
+
+        r.Route("/fx", func(r chi.Router) {
+            r.With(auth.RequireScopes("ledger:read", onAuthError)).Get("/rates", handleGetFXRate(deps))
+            r.With(auth.RequireScopes("ledger:fx", onAuthError), idempotent, setFXRateV.Middleware).Post("/rates", handleSetFXRate(deps))
+            r.With(auth.RequireScopes("accounts:write", onAuthError), idempotent, setFXAccountsV.Middleware).Put("/accounts/{currency}", handleSetFXAccounts(deps))
+        })
+
+        r.Route("/periods", func(r chi.Router) {
+            list := r.With(auth.RequireScopes("ledger:read", onAuthError))
+            list.Get("/", handleListPeriods(deps))
+            list.Get("", handleListPeriods(deps))
+            list.Get("/{id}", handleGetPeriod(deps))
+
+            create := r.With(auth.RequireScopes("ledger:close", onAuthError), idempotent, createPeriodV.Middleware)
+            create.Post("/", handleCreatePeriod(deps))
+            create.Post("", handleCreatePeriod(deps))
+
+            closing := r.With(auth.RequireScopes("ledger:close", onAuthError), idempotent, closePeriodV.Middleware)
+            closing.Post("/{id}/soft-close", handleClosePeriod(deps, ledger.PeriodStatusSoftClosed))
+            closing.Post("/{id}/hard-close", handleClosePeriod(deps, ledger.PeriodStatusHardClosed))
+
+            r.With(auth.RequireScopes("accounts:write", onAuthError), idempotent, setRetainedEarningsV.Middleware).Put("/retained-earnings/{currency}", handleSetRetainedEarnings(deps))
+        })
+
+        r.Route("/reports", func(r chi.Router) {
+            reports := r.With(auth.RequireScopes("ledger:read", onAuthError))
+            reports.Get("/trial-balance", handleTrialBalance(deps))
+            reports.Get("/balance-sheet", handleBalanceSheet(deps))
+            reports.Get("/income-statement", handleIncomeStatement(deps))
+        })
+
+        r.Route("/reconciliation", func(r chi.Router) {
+            r.With(auth.RequireScopes("ledger:reconcile", onAuthError), idempotent, importStatementV.Middleware).Post("/statements", handleImportStatement(deps))
+            r.With(auth.RequireScopes("ledger:read", onAuthError)).Get("/statements/{id}", handleGetReconciliation(deps))
+            r.With(auth.RequireScopes("ledger:reconcile", onAuthError), idempotent).Post("/statements/{id}/match", handleMatchStatement(deps))
+
+            decide := r.With(auth.RequireScopes("ledger:reconcile", onAuthError), idempotent, decideMatchV.Middleware)
+            decide.Post("/matches/{id}/confirm", handleDecideMatch(deps, true))
+            decide.Post("/matches/{id}/reject", handleDecideMatch(deps, false))
+        })
+
+        r.Route("/webhooks", func(r chi.Router) {
+            list := r.With(auth.RequireScopes("webhooks:read", onAuthError))
+            list.Get("/", handleListWebhooks(deps))
+            list.Get("", handleListWebhooks(deps))
+            list.Get("/{id}", handleGetWebhook(deps))
+            list.Get("/{id}/deliveries", handleListWebhookDeliveries(deps))
+            list.Get("/deliveries/{delivery_id}", handleGetWebhookDelivery(deps))
+
+            create := r.With(auth.RequireScopes("webhooks:write", onAuthError), idempotent, createWebhookV.Middleware)
+            create.Post("/", handleCreateWebhook(deps))
+            create.Post("", handleCreateWebhook(deps))
+
+            write := r.With(auth.RequireScopes("webhooks:write", onAuthError), idempotent)
+            write.With(updateWebhookV.Middleware).Put("/{id}", handleUpdateWebhook(deps))
+            write.Delete("/{id}", handleDeleteWebhook(deps))
+            write.Post("/{id}/rotate-secret", handleRotateWebhookSecret(deps))
+            write.Post("/deliveries/{delivery_id}/replay", handleReplayWebhookDelivery(deps))
+        })
+
+        r.Route("/payouts", func(r chi.Router) {
+            read := r.With(auth.RequireScopes("payouts:read", onAuthError))
+            read.Get("/", handleListPayouts(deps))
+            read.Get("", handleListPayouts(deps))
+            read.Get("/{id}", handleGetPayout(deps))
+            read.Get("/settings/{merchant_id}", handleGetPayoutSettings(deps))
+            read.Get("/settings/{merchant_id}/payable", handleGetPayable(deps))
+
+            write := r.With(auth.RequireScopes("payouts:write", onAuthError), idempotent)
+            write.With(payoutSettingsV.Middleware).Put("/settings/{merchant_id}", handleSetPayoutSettings(deps))
+            write.With(requestPayoutV.Middleware).Post("/", handleRequestPayout(deps))
+            write.With(requestPayoutV.Middleware).Post("", handleRequestPayout(deps))
+            write.With(payoutTransitionV.Middleware).Post("/{id}/sent", handleTransitionPayout(deps, payouts.StatusSent))
+            write.With(payoutTransitionV.Middleware).Post("/{id}/paid", handleTransitionPayout(deps, payouts.StatusPaid))
+            write.With(failPayoutV.Middleware).Post("/{id}/fail", handleTransitionPayout(deps, payouts.StatusFailed))
+        })
+
+        r.Route("/fees", func(r chi.Router) {
+            read := r.With(auth.RequireScopes("fees:read", onAuthError))
+            read.Get("/schedules", handleListFeeSchedules(deps))
+            read.Get("/schedules/{id}", handleGetFeeSchedule(deps))
+            read.Get("/quote", handleQuoteFee(deps))
+
+            write := r.With(auth.RequireScopes("fees:write", onAuthError), idempotent)
+            write.With(feeScheduleV.Middleware).Post("/schedules", handleCreateFeeSchedule(deps))
+            write.With(chargeFeeV.Middleware).Post("/charges", handleChargeFee(deps))
         })
 
         r.Route("/disputes", func(r chi.Router) {
-            create := r.With(auth.RequireScopes("disputes:write", onAuthError), disputesSchema.Middleware)
+            create := r.With(auth.RequireScopes("disputes:write", onAuthError), idempotent, disputesSchema.Middleware)
             create.Post("/", handleCreateDispute(deps))
             create.Post("", handleCreateDispute(deps))
 
-            authorize := r.With(auth.RequireScopes("disputes:write", onAuthError))
+            authorize := r.With(auth.RequireScopes("disputes:write", onAuthError), idempotent)
             authorize.Post("/{dispute_id}/authorize", handleAuthorizeDispute(deps))
 
-            settle := r.With(auth.RequireScopes("disputes:write", onAuthError))
+            settle := r.With(auth.RequireScopes("disputes:write", onAuthError), idempotent)
             settle.Post("/settle", handleSettleTransaction(deps))
 
-            dispute := r.With(auth.RequireScopes("disputes:write", onAuthError))
+            dispute := r.With(auth.RequireScopes("disputes:write", onAuthError), idempotent)
             dispute.Post("/{dispute_id}/dispute", handleInitiateDispute(deps))
 
-            reverse := r.With(auth.RequireScopes("disputes:write", onAuthError))
+            reverse := r.With(auth.RequireScopes("disputes:write", onAuthError), idempotent)
             reverse.Post("/{dispute_id}/reverse", handleReverseDispute(deps))
 
+            chargeback := r.With(auth.RequireScopes("disputes:write", onAuthError), idempotent)
+            chargeback.Post("/{dispute_id}/represent", handleRepresentDispute(deps))
+            chargeback.Post("/{dispute_id}/escalate", handleEscalateDispute(deps))
+            chargeback.Post("/{dispute_id}/accept", handleAcceptDispute(deps))
+            chargeback.Post("/{dispute_id}/resolve", handleResolveDispute(deps))
+
             get := r.With(auth.RequireScopes("disputes:read", onAuthError))
             get.Get("/{dispute_id}", handleGetDispute(deps))
             get.Get("/{dispute_id}/history", handleGetDisputeHistory(deps))
 
+            evidence := r.With(auth.RequireScopes("disputes:read", onAuthError))
+            evidence.Get("/{dispute_id}/evidence", handleListEvidence(deps))
+            evidence.Get("/{dispute_id}/evidence/{evidence_id}", handleDownloadEvidence(deps))
+
+            upload := r.With(auth.RequireScopes("disputes:write", onAuthError), idempotent, uploadEvidenceV.Middleware)
+            upload.Post("/{dispute_id}/evidence", handleUploadEvidence(deps))
+
             list := r.With(auth.RequireScopes("disputes:read", onAuthError))
             list.Get("/", handleListDisputes(deps))
             list.Get("", handleListDisputes(deps))
 
             reserve := r.With(auth.RequireScopes("disputes:read", onAuthError))
             reserve.Get("/reserve/calculate", handleCalculateReserve(deps))
+
+            holds := r.With(auth.RequireScopes("disputes:admin", onAuthError), idempotent)
+            holds.Post("/holds/{hold_id}/extend", handleExtendHold(deps))
+            holds.Post("/holds/{hold_id}/release", handleReleaseHold(deps))
         })
     })
 
@@ -165,3 +489,29 @@ func rateLimitKeyByIP(r *http.Request) string {
     }
     return "ip:" + host
 }
+
+// allowAdjustments lets callers holding ledger:adjust post into soft-closed
+// accounting periods
+func allowAdjustments(next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if ai, ok := auth.AuthInfoFromContext(r.Context()); ok {
+            if _, ok := ai.Scopes["ledger:adjust"]; ok {
+                r = r.WithContext(ledger.WithSoftClosedPostings(r.Context()))
+            }
+        }
+        next.ServeHTTP(w, r)
+    })
+}
+
+// allowOffMarketFXQuotes lets callers holding ledger:fx apply quoted rates
+// outside the configured spread of the market rate
+func allowOffMarketFXQuotes(next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if ai, ok := auth.AuthInfoFromContext(r.Context()); ok {
+            if _, ok := ai.Scopes["ledger:fx"]; ok {
+                r = r.WithContext(ledger.WithOffMarketFXQuotes(r.Context()))
+            }
+        }
+        next.ServeHTTP(w, r)
+    })
+}
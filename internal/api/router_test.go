@@ -10,11 +10,17 @@ import (
     "crypto/x509/pkix"
     "encoding/json"
     "encoding/pem"
+    "errors"
+    "fmt"
+    "io"
+    "log/slog"
     "math/big"
     "net"
     "net/http"
     "net/http/httptest"
     "net/url"
+    "strings"
+    "sync"
     "testing"
     "time"
 
@@ -23,8 +29,15 @@ import (
     "github.com/stretchr/testify/require"
 
     "github.com/example/pci-infra/internal/auth"
+    "github.com/example/pci-infra/internal/disputes"
     "github.com/example/pci-infra/internal/ledger"
+    "github.com/example/pci-infra/internal/money"
+    "github.com/example/pci-infra/internal/fees"
+    "github.com/example/pci-infra/internal/payouts"
+    "github.com/example/pci-infra/internal/reconciliation"
+    "github.com/example/pci-infra/internal/reporting"
     "github.com/example/pci-infra/internal/security"
+    "github.com/example/pci-infra/internal/webhooks"
     "github.com/example/pci-infra/pkg/audit"
 )
 
@@ -40,44 +53,2198 @@ func (m *memoryClientStore) GetClient(ctx context.Context, clientID string) (*au
     return c, nil
 }
 
+type memoryIdempotencyStore struct {
+    mu      sync.Mutex
+    records map[string]*IdempotencyRecord
+
+    failComplete bool
+    extended     int
+    held         []string
+}
+
+func (m *memoryIdempotencyStore) Begin(ctx context.Context, clientID, key, method, path, fingerprint string) (*IdempotencyRecord, error) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    if rec, ok := m.records[clientID+"/"+key]; ok {
+        cp := *rec
+        return &cp, nil
+    }
+    m.records[clientID+"/"+key] = &IdempotencyRecord{Fingerprint: fingerprint}
+    return nil, nil
+}
+
+func (m *memoryIdempotencyStore) Extend(ctx context.Context, clientID, key string) error {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    m.extended++
+    return nil
+}
+
+func (m *memoryIdempotencyStore) Hold(ctx context.Context, clientID, key string) error {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    m.held = append(m.held, clientID+"/"+key)
+    return nil
+}
+
+func (m *memoryIdempotencyStore) Complete(ctx context.Context, clientID, key string, statusCode int, contentType string, body []byte) error {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    if m.failComplete {
+        return errors.New("store unavailable")
+    }
+    rec := m.records[clientID+"/"+key]
+    rec.Completed = true
+    rec.StatusCode = statusCode
+    rec.ContentType = contentType
+    rec.Body = append([]byte(nil), body...)
+    return nil
+}
+
+func (m *memoryIdempotencyStore) Release(ctx context.Context, clientID, key string) error {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    delete(m.records, clientID+"/"+key)
+    return nil
+}
+
 type fakeLedger struct {
-    createCalls int
-    debitCalls  int
-    creditCalls int
+    createCalls      int
+    debitCalls       int
+    creditCalls      int
+    transferCalls    int
+    transactionCalls int
+    failAfterDebit   bool
+
+    statementRequests []ledger.StatementRequest
+    pending           map[string]*ledger.PendingTransaction
+    reversals         []*ledger.Reversal
+    fxRates           []*ledger.FXRate
+    fxAccounts        map[string]ledger.FXAccounts
+    statusHistory     []ledger.AccountStatusTransition
+    periods           []ledger.AccountingPeriod
+    retainedEarnings  map[string]string
+    creditLimits      []ledger.CreditLimit
+}
+
+func (f *fakeLedger) ListAccounts(ctx context.Context, filter ledger.AccountFilter) ([]*ledger.Account, error) {
+    return []*ledger.Account{{ID: "acc-1", AccountNumber: "A1", AccountType: "asset", Name: "Test", CurrencyCode: "USD"}}, nil
+}
+
+func (f *fakeLedger) GetBalance(ctx context.Context, accountID string) (money.Money, error) {
+    return money.MustParse("123.45", "USD"), nil
+}
+
+func (f *fakeLedger) GetBalanceAt(ctx context.Context, accountID string, at time.Time) (money.Money, error) {
+    return money.MustParse("100.00", "USD"), nil
+}
+
+func (f *fakeLedger) GetStatement(ctx context.Context, req ledger.StatementRequest) (*ledger.Statement, error) {
+    if req.AccountID != "acc-1" {
+        return nil, ledger.ErrAccountNotFound
+    }
+    f.statementRequests = append(f.statementRequests, req)
+    stmt := &ledger.Statement{
+        AccountID:      req.AccountID,
+        AccountNumber:  "2000",
+        CurrencyCode:   "USD",
+        From:           req.From.Format(time.RFC3339),
+        To:             req.To.Format(time.RFC3339),
+        OpeningBalance: money.MustParse("100.00", "USD"),
+        ClosingBalance: money.MustParse("75.00", "USD"),
+        Entries: []ledger.StatementEntry{{
+            EntryID:        "entry-2",
+            EntryType:      "credit",
+            Amount:         money.MustParse("25.00", "USD"),
+            BalanceChange:  money.MustParse("-25.00", "USD"),
+            RunningBalance: money.MustParse("75.00", "USD"),
+            PostedAt:       "2024-01-15T08:00:00Z",
+        }},
+    }
+    if req.Cursor == "" {
+        stmt.NextCursor = "page-2"
+    }
+    return stmt, nil
+}
+
+func (f *fakeLedger) GetAccountBalances(ctx context.Context, accountID string) (*ledger.AccountBalances, error) {
+    if accountID != "acc-1" {
+        return nil, ledger.ErrAccountNotFound
+    }
+    return &ledger.AccountBalances{
+        AccountID:      accountID,
+        CurrencyCode:   "USD",
+        Posted:         money.MustParse("123.45", "USD"),
+        Pending:        money.MustParse("20.00", "USD"),
+        Held:           money.MustParse("3.45", "USD"),
+        OverdraftLimit: money.MustParse("0.00", "USD"),
+        Available:      money.MustParse("100.00", "USD"),
+    }, nil
+}
+
+func (f *fakeLedger) GetPendingTransaction(ctx context.Context, id string) (*ledger.PendingTransaction, error) {
+    p, ok := f.pending[id]
+    if !ok {
+        return nil, ledger.ErrPendingNotFound
+    }
+    return p, nil
+}
+
+func (f *fakeLedger) AuthorizePending(ctx context.Context, req ledger.AuthorizeRequest) (*ledger.PendingTransaction, error) {
+    if f.pending == nil {
+        f.pending = map[string]*ledger.PendingTransaction{}
+    }
+    p := &ledger.PendingTransaction{
+        ID:            fmt.Sprintf("pending-%d", len(f.pending)+1),
+        TransactionID: req.TransactionID,
+        FromAccountID: req.FromAccountID,
+        ToAccountID:   req.ToAccountID,
+        Amount:        req.Amount,
+        Status:        ledger.PendingStatusPending,
+        Description:   req.Description,
+        CreatedBy:     req.CreatedBy,
+        ExpiresAt:     time.Now().Add(req.TTL).UTC().Format(time.RFC3339),
+    }
+    f.pending[p.ID] = p
+    return p, nil
+}
+
+func (f *fakeLedger) CapturePending(ctx context.Context, req ledger.CaptureRequest) (*ledger.PendingTransaction, error) {
+    p, err := f.GetPendingTransaction(ctx, req.PendingID)
+    if err != nil {
+        return nil, err
+    }
+    if p.Status != ledger.PendingStatusPending {
+        return nil, ledger.ErrPendingResolved
+    }
+    amount := req.Amount
+    if amount.IsZero() {
+        amount = p.Amount
+    }
+    if p.Amount.LessThan(amount) {
+        return nil, ledger.ErrInvalidRequest
+    }
+    p.Status = ledger.PendingStatusPosted
+    p.CapturedAmount = &amount
+    p.ResolvedBy = req.CapturedBy
+    return p, nil
+}
+
+func (f *fakeLedger) VoidPending(ctx context.Context, req ledger.VoidRequest) (*ledger.PendingTransaction, error) {
+    p, err := f.GetPendingTransaction(ctx, req.PendingID)
+    if err != nil {
+        return nil, err
+    }
+    if p.Status != ledger.PendingStatusPending {
+        return nil, ledger.ErrPendingResolved
+    }
+    p.Status = ledger.PendingStatusVoided
+    p.ResolvedBy = req.VoidedBy
+    return p, nil
+}
+
+func (f *fakeLedger) GetTransaction(ctx context.Context, transactionID string) (*ledger.TransactionDetail, error) {
+    if transactionID != "txn-1" {
+        return nil, ledger.ErrTransactionNotFound
+    }
+    detail := &ledger.TransactionDetail{TransactionID: transactionID, Reversals: []ledger.Reversal{}}
+    for _, r := range f.reversals {
+        detail.Reversals = append(detail.Reversals, *r)
+        detail.FullyReversed = detail.FullyReversed || r.Full
+    }
+    return detail, nil
+}
+
+func (f *fakeLedger) ReverseTransaction(ctx context.Context, req ledger.ReverseRequest) (*ledger.Reversal, error) {
+    detail, err := f.GetTransaction(ctx, req.TransactionID)
+    if err != nil {
+        return nil, err
+    }
+    if detail.FullyReversed {
+        return nil, ledger.ErrAlreadyReversed
+    }
+    r := &ledger.Reversal{
+        ID:                    fmt.Sprintf("reversal-%d", len(f.reversals)+1),
+        TransactionID:         req.ReversalID,
+        OriginalTransactionID: req.TransactionID,
+        Full:                  req.Amount.IsZero(),
+        Reason:                req.Reason,
+        ReversedBy:            req.ReversedBy,
+        ApprovedBy:            req.ApprovedBy,
+    }
+    if !r.Full {
+        r.Amount = &req.Amount
+    }
+    f.reversals = append(f.reversals, r)
+    return r, nil
+}
+
+func (f *fakeLedger) GetFXRate(ctx context.Context, base, quote string, at time.Time) (*ledger.FXRate, error) {
+    for i := len(f.fxRates) - 1; i >= 0; i-- {
+        if r := f.fxRates[i]; r.BaseCurrency == base && r.QuoteCurrency == quote {
+            return r, nil
+        }
+    }
+    return nil, ledger.ErrFXRateNotFound
+}
+
+func (f *fakeLedger) SetFXRate(ctx context.Context, req ledger.SetFXRateRequest) (*ledger.FXRate, error) {
+    r := &ledger.FXRate{
+        ID:            fmt.Sprintf("rate-%d", len(f.fxRates)+1),
+        BaseCurrency:  req.BaseCurrency,
+        QuoteCurrency: req.QuoteCurrency,
+        Rate:          req.Rate,
+        Source:        req.Source,
+        CreatedBy:     req.CreatedBy,
+    }
+    f.fxRates = append(f.fxRates, r)
+    return r, nil
+}
+
+func (f *fakeLedger) SetFXAccounts(ctx context.Context, accts ledger.FXAccounts) error {
+    if f.fxAccounts == nil {
+        f.fxAccounts = map[string]ledger.FXAccounts{}
+    }
+    f.fxAccounts[accts.CurrencyCode] = accts
+    return nil
+}
+
+func (f *fakeLedger) FXTransfer(ctx context.Context, req ledger.FXTransferRequest) (*ledger.FXConversion, error) {
+    // the fake converts USD into EUR only
+    if _, ok := f.fxAccounts["USD"]; !ok {
+        return nil, ledger.ErrFXNotConfigured
+    }
+    rate, err := f.GetFXRate(ctx, req.Amount.Currency(), "EUR", time.Time{})
+    if err != nil {
+        return nil, err
+    }
+    applied := req.QuotedRate
+    if applied.IsZero() {
+        applied = rate.Rate
+    }
+    target, err := applied.Convert(req.Amount, "EUR")
+    if err != nil {
+        return nil, err
+    }
+    atMarket, err := rate.Rate.Convert(req.Amount, "EUR")
+    if err != nil {
+        return nil, err
+    }
+    gainLoss, err := atMarket.Sub(target)
+    if err != nil {
+        return nil, err
+    }
+    return &ledger.FXConversion{
+        TransactionID:    req.TransactionID,
+        FromAccountID:    req.FromAccountID,
+        ToAccountID:      req.ToAccountID,
+        SourceAmount:     req.Amount,
+        TargetAmount:     target,
+        FXRateID:         rate.ID,
+        MarketRate:       rate.Rate,
+        AppliedRate:      applied,
+        RealisedGainLoss: gainLoss,
+        CreatedBy:        req.CreatedBy,
+    }, nil
+}
+
+func (f *fakeLedger) CreateAccount(ctx context.Context, req ledger.CreateAccountRequest) (*ledger.Account, error) {
+    f.createCalls++
+    if req.ParentID != "" && req.ParentID != "acc-1" {
+        return nil, ledger.ErrAccountNotFound
+    }
+    return &ledger.Account{ID: "acc-1", ParentID: req.ParentID, AccountNumber: req.AccountNumber, AccountType: req.AccountType, Name: req.Name, CurrencyCode: req.CurrencyCode, CreatedBy: req.CreatedBy}, nil
+}
+
+func (f *fakeLedger) GetAccountTree(ctx context.Context, accountID string) (*ledger.AccountNode, error) {
+    if accountID != "acc-1" {
+        return nil, ledger.ErrAccountNotFound
+    }
+    child := &ledger.AccountNode{
+        Account:       &ledger.Account{ID: "acc-2", ParentID: "acc-1", AccountNumber: "A1-PENDING", CurrencyCode: "USD", CurrentBalance: money.MustParse("7.50", "USD")},
+        Depth:         1,
+        RollupBalance: money.MustParse("7.50", "USD"),
+        Children:      []*ledger.AccountNode{},
+    }
+    return &ledger.AccountNode{
+        Account:       &ledger.Account{ID: "acc-1", AccountNumber: "A1", CurrencyCode: "USD", CurrentBalance: money.MustParse("2.50", "USD")},
+        RollupBalance: money.MustParse("10.00", "USD"),
+        Children:      []*ledger.AccountNode{child},
+    }, nil
+}
+
+func (f *fakeLedger) Debit(ctx context.Context, req ledger.DebitRequest) error {
+    f.debitCalls++
+    if f.failAfterDebit {
+        return errors.New("connection reset after commit")
+    }
+    switch f.accountStatus(req.AccountID) {
+    case ledger.AccountStatusFrozen:
+        return ledger.ErrAccountFrozen
+    case ledger.AccountStatusClosed:
+        return ledger.ErrAccountClosed
+    }
+    if limit, err := f.GetCreditLimit(ctx, req.AccountID, time.Time{}); err == nil && limit.MaxTransactionAmount != nil && req.Amount.GreaterThan(*limit.MaxTransactionAmount) {
+        return ledger.ErrTransactionLimitExceeded
+    }
+    return nil
+}
+
+func (f *fakeLedger) Credit(ctx context.Context, req ledger.CreditRequest) error {
+    f.creditCalls++
+    if f.accountStatus(req.AccountID) == ledger.AccountStatusClosed {
+        return ledger.ErrAccountClosed
+    }
+    return nil
+}
+
+func (f *fakeLedger) accountStatus(accountID string) string {
+    status := ledger.AccountStatusActive
+    for _, t := range f.statusHistory {
+        if t.AccountID == accountID {
+            status = t.ToStatus
+        }
+    }
+    return status
+}
+
+func (f *fakeLedger) changeAccountStatus(req ledger.AccountStatusRequest, status string) (*ledger.AccountStatusTransition, error) {
+    from := f.accountStatus(req.AccountID)
+    switch {
+    case from == ledger.AccountStatusClosed:
+        return nil, ledger.ErrAccountClosed
+    case from == status:
+        return nil, ledger.ErrInvalidStatusTransition
+    }
+    t := ledger.AccountStatusTransition{
+        ID:         fmt.Sprintf("transition-%d", len(f.statusHistory)+1),
+        AccountID:  req.AccountID,
+        FromStatus: from,
+        ToStatus:   status,
+        Reason:     req.Reason,
+        ChangedBy:  req.ChangedBy,
+    }
+    f.statusHistory = append(f.statusHistory, t)
+    return &t, nil
+}
+
+func (f *fakeLedger) FreezeAccount(ctx context.Context, req ledger.AccountStatusRequest) (*ledger.AccountStatusTransition, error) {
+    return f.changeAccountStatus(req, ledger.AccountStatusFrozen)
+}
+
+func (f *fakeLedger) UnfreezeAccount(ctx context.Context, req ledger.AccountStatusRequest) (*ledger.AccountStatusTransition, error) {
+    return f.changeAccountStatus(req, ledger.AccountStatusActive)
+}
+
+func (f *fakeLedger) BlockAccount(ctx context.Context, req ledger.AccountStatusRequest) (*ledger.AccountStatusTransition, error) {
+    return f.changeAccountStatus(req, ledger.AccountStatusBlocked)
+}
+
+func (f *fakeLedger) CloseAccount(ctx context.Context, req ledger.AccountStatusRequest) (*ledger.AccountStatusTransition, error) {
+    return f.changeAccountStatus(req, ledger.AccountStatusClosed)
+}
+
+func (f *fakeLedger) GetAccountStatusHistory(ctx context.Context, accountID string) ([]ledger.AccountStatusTransition, error) {
+    history := []ledger.AccountStatusTransition{}
+    for _, t := range f.statusHistory {
+        if t.AccountID == accountID {
+            history = append(history, t)
+        }
+    }
+    return history, nil
+}
+
+func (f *fakeLedger) Transfer(ctx context.Context, req ledger.TransferRequest) error {
+    f.transferCalls++
+    return nil
+}
+
+func (f *fakeLedger) PostTransaction(ctx context.Context, txn ledger.Transaction) (*ledger.Transaction, error) {
+    f.transactionCalls++
+    if err := ledger.ValidateTransactionEntries(txn.Entries); err != nil {
+        return nil, err
+    }
+    return &txn, nil
+}
+
+func (f *fakeLedger) CreatePeriod(ctx context.Context, req ledger.CreatePeriodRequest) (*ledger.AccountingPeriod, error) {
+    for _, p := range f.periods {
+        if p.Name == req.Name {
+            return nil, ledger.ErrInvalidRequest
+        }
+    }
+    f.periods = append(f.periods, ledger.AccountingPeriod{
+        ID:        fmt.Sprintf("period-%d", len(f.periods)+1),
+        Name:      req.Name,
+        StartsOn:  req.StartsOn,
+        EndsOn:    req.EndsOn,
+        Status:    ledger.PeriodStatusOpen,
+        CreatedBy: req.CreatedBy,
+    })
+    return &f.periods[len(f.periods)-1], nil
+}
+
+func (f *fakeLedger) GetPeriod(ctx context.Context, periodID string) (*ledger.AccountingPeriod, error) {
+    for i := range f.periods {
+        if f.periods[i].ID == periodID {
+            return &f.periods[i], nil
+        }
+    }
+    return nil, ledger.ErrPeriodNotFound
+}
+
+func (f *fakeLedger) ListPeriods(ctx context.Context) ([]ledger.AccountingPeriod, error) {
+    return f.periods, nil
+}
+
+func (f *fakeLedger) closePeriod(ctx context.Context, req ledger.ClosePeriodRequest, status string) (*ledger.AccountingPeriod, error) {
+    p, err := f.GetPeriod(ctx, req.PeriodID)
+    if err != nil {
+        return nil, err
+    }
+    switch {
+    case p.Status == ledger.PeriodStatusHardClosed:
+        return nil, ledger.ErrPeriodHardClosed
+    case p.Status == status:
+        return nil, ledger.ErrInvalidPeriodTransition
+    }
+    if f.retainedEarnings["USD"] == "" {
+        return nil, ledger.ErrRetainedEarningsNotConfigured
+    }
+    p.Status = status
+    return p, nil
+}
+
+func (f *fakeLedger) SoftClosePeriod(ctx context.Context, req ledger.ClosePeriodRequest) (*ledger.AccountingPeriod, error) {
+    return f.closePeriod(ctx, req, ledger.PeriodStatusSoftClosed)
+}
+
+func (f *fakeLedger) HardClosePeriod(ctx context.Context, req ledger.ClosePeriodRequest) (*ledger.AccountingPeriod, error) {
+    return f.closePeriod(ctx, req, ledger.PeriodStatusHardClosed)
+}
+
+func (f *fakeLedger) SetRetainedEarningsAccount(ctx context.Context, acct ledger.RetainedEarningsAccount) error {
+    if f.retainedEarnings == nil {
+        f.retainedEarnings = map[string]string{}
+    }
+    f.retainedEarnings[acct.CurrencyCode] = acct.AccountID
+    return nil
+}
+
+func (f *fakeLedger) SetCreditLimit(ctx context.Context, req ledger.SetCreditLimitRequest) (*ledger.CreditLimit, error) {
+    if req.AccountID != "acc-1" {
+        return nil, ledger.ErrAccountNotFound
+    }
+    if req.EffectiveFrom.IsZero() {
+        req.EffectiveFrom = time.Now()
+    }
+    f.creditLimits = append(f.creditLimits, ledger.CreditLimit{
+        ID:                   fmt.Sprintf("limit-%d", len(f.creditLimits)+1),
+        AccountID:            req.AccountID,
+        OverdraftLimit:       req.OverdraftLimit,
+        MaxTransactionAmount: req.MaxTransactionAmount,
+        EffectiveFrom:        req.EffectiveFrom.UTC().Format(time.RFC3339),
+        Reason:               req.Reason,
+        SetBy:                req.SetBy,
+    })
+    return &f.creditLimits[len(f.creditLimits)-1], nil
+}
+
+func (f *fakeLedger) GetCreditLimit(ctx context.Context, accountID string, at time.Time) (*ledger.CreditLimit, error) {
+    if at.IsZero() {
+        at = time.Now()
+    }
+    var current *ledger.CreditLimit
+    for i, l := range f.creditLimits {
+        from, _ := time.Parse(time.RFC3339, l.EffectiveFrom)
+        if l.AccountID == accountID && !from.After(at) {
+            current = &f.creditLimits[i]
+        }
+    }
+    if current == nil {
+        return nil, ledger.ErrCreditLimitNotFound
+    }
+    return current, nil
+}
+
+func (f *fakeLedger) ListCreditLimits(ctx context.Context, accountID string) ([]ledger.CreditLimit, error) {
+    limits := []ledger.CreditLimit{}
+    for _, l := range f.creditLimits {
+        if l.AccountID == accountID {
+            limits = append(limits, l)
+        }
+    }
+    return limits, nil
+}
+
+type auditSpy struct{ calls int }
+
+func (a *auditSpy) Append(payload string) *audit.LogEntry {
+    a.calls++
+    return &audit.LogEntry{Hash: payload}
+}
+
+type fakeReports struct {
+    requests []reporting.Request
+}
+
+func (f *fakeReports) check(req reporting.Request) error {
+    f.requests = append(f.requests, req)
+    if req.CurrencyCode != "USD" {
+        return ledger.ErrInvalidRequest
+    }
+    return nil
+}
+
+func (f *fakeReports) TrialBalance(ctx context.Context, req reporting.Request) (*reporting.TrialBalance, error) {
+    if err := f.check(req); err != nil {
+        return nil, err
+    }
+    return &reporting.TrialBalance{
+        CurrencyCode: req.CurrencyCode,
+        Lines: []reporting.TrialBalanceLine{{
+            AccountNumber: "1000",
+            Name:          "Cash",
+            AccountType:   "asset",
+            PeriodDebits:  money.MustParse("5.00", "USD"),
+            PeriodCredits: money.MustParse("0.00", "USD"),
+            DebitBalance:  money.MustParse("5.00", "USD"),
+            CreditBalance: money.MustParse("0.00", "USD"),
+        }},
+        TotalPeriodDebits:  money.MustParse("5.00", "USD"),
+        TotalPeriodCredits: money.MustParse("5.00", "USD"),
+        TotalDebitBalance:  money.MustParse("5.00", "USD"),
+        TotalCreditBalance: money.MustParse("5.00", "USD"),
+        Check:              reporting.Check{Balanced: true, Difference: money.MustParse("0.00", "USD")},
+    }, nil
+}
+
+func (f *fakeReports) BalanceSheet(ctx context.Context, req reporting.Request) (*reporting.BalanceSheet, error) {
+    if err := f.check(req); err != nil {
+        return nil, err
+    }
+    zero := money.MustParse("0.00", "USD")
+    return &reporting.BalanceSheet{
+        CurrencyCode:              req.CurrencyCode,
+        Assets:                    reporting.Section{Lines: []reporting.Line{}, Total: zero},
+        Liabilities:               reporting.Section{Lines: []reporting.Line{}, Total: zero},
+        Equity:                    reporting.Section{Lines: []reporting.Line{}, Total: zero},
+        RetainedEarnings:          zero,
+        TotalLiabilitiesAndEquity: zero,
+        Check:                     reporting.Check{Balanced: true, Difference: zero},
+    }, nil
+}
+
+func (f *fakeReports) IncomeStatement(ctx context.Context, req reporting.Request) (*reporting.IncomeStatement, error) {
+    if err := f.check(req); err != nil {
+        return nil, err
+    }
+    zero := money.MustParse("0.00", "USD")
+    return &reporting.IncomeStatement{
+        CurrencyCode: req.CurrencyCode,
+        Revenue:      reporting.Section{Lines: []reporting.Line{}, Total: zero},
+        Expenses:     reporting.Section{Lines: []reporting.Line{}, Total: zero},
+        NetIncome:    zero,
+        Check:        reporting.Check{Balanced: true, Difference: zero},
+    }, nil
+}
+
+type fakeReconciliation struct {
+    matches map[string]*reconciliation.Match
+}
+
+func (f *fakeReconciliation) ImportStatement(ctx context.Context, req reconciliation.ImportRequest) (*reconciliation.Statement, error) {
+    if req.Format != reconciliation.FormatCSV {
+        return nil, reconciliation.ErrInvalidStatement
+    }
+    if req.Reference == "dup" {
+        return nil, reconciliation.ErrStatementExists
+    }
+    return &reconciliation.Statement{ID: "st-1", AccountID: req.AccountID, Format: req.Format, Reference: req.Reference, CurrencyCode: "USD"}, nil
+}
+
+func (f *fakeReconciliation) Match(ctx context.Context, statementID string) (*reconciliation.Result, error) {
+    if statementID != "st-1" {
+        return nil, reconciliation.ErrStatementNotFound
+    }
+    f.matches = map[string]*reconciliation.Match{
+        "m-1": {ID: "m-1", StatementID: statementID, JournalEntryID: "je-1", Status: reconciliation.MatchSuggested, Rule: reconciliation.RuleAmountDate, Score: 60},
+    }
+    return f.Result(ctx, statementID)
 }
 
-func (f *fakeLedger) ListAccounts(ctx context.Context, filter ledger.AccountFilter) ([]*ledger.Account, error) {
-    return []*ledger.Account{{ID: "acc-1", AccountNumber: "A1", AccountType: "asset", Name: "Test", CurrencyCode: "USD"}}, nil
-}
+func (f *fakeReconciliation) Result(ctx context.Context, statementID string) (*reconciliation.Result, error) {
+    if statementID != "st-1" {
+        return nil, reconciliation.ErrStatementNotFound
+    }
+    result := &reconciliation.Result{Statement: &reconciliation.Statement{ID: statementID}}
+    for _, m := range f.matches {
+        if m.Status == reconciliation.MatchSuggested {
+            result.Suggested = append(result.Suggested, *m)
+        } else if m.Status != reconciliation.MatchRejected {
+            result.Matched = append(result.Matched, *m)
+        }
+    }
+    return result, nil
+}
+
+func (f *fakeReconciliation) decide(req reconciliation.DecisionRequest, status string) (*reconciliation.Match, error) {
+    m, ok := f.matches[req.MatchID]
+    if !ok {
+        return nil, reconciliation.ErrMatchNotFound
+    }
+    if m.Status != reconciliation.MatchSuggested {
+        return nil, reconciliation.ErrMatchDecided
+    }
+    m.Status = status
+    m.DecidedBy = req.DecidedBy
+    return m, nil
+}
+
+func (f *fakeReconciliation) ConfirmMatch(ctx context.Context, req reconciliation.DecisionRequest) (*reconciliation.Match, error) {
+    return f.decide(req, reconciliation.MatchConfirmed)
+}
+
+func (f *fakeReconciliation) RejectMatch(ctx context.Context, req reconciliation.DecisionRequest) (*reconciliation.Match, error) {
+    return f.decide(req, reconciliation.MatchRejected)
+}
+
+type fakeWebhooks struct {
+    subs       map[string]*webhooks.Subscription
+    deliveries map[string]*webhooks.Delivery
+}
+
+func (f *fakeWebhooks) CreateSubscription(ctx context.Context, req webhooks.CreateRequest) (*webhooks.Subscription, error) {
+    if !strings.HasPrefix(req.URL, "https://") {
+        return nil, webhooks.ErrInvalidSubscription
+    }
+    if f.subs == nil {
+        f.subs = map[string]*webhooks.Subscription{}
+    }
+    id := fmt.Sprintf("wh-%d", len(f.subs)+1)
+    f.subs[id] = &webhooks.Subscription{ID: id, Owner: req.Owner, URL: req.URL, Description: req.Description, Filter: req.Filter, Active: true}
+    sub := *f.subs[id]
+    sub.Secret = "whsec_test"
+    return &sub, nil
+}
+
+func (f *fakeWebhooks) ListSubscriptions(ctx context.Context, owner string) ([]webhooks.Subscription, error) {
+    subs := []webhooks.Subscription{}
+    for _, s := range f.subs {
+        if s.Owner == owner {
+            subs = append(subs, *s)
+        }
+    }
+    return subs, nil
+}
+
+func (f *fakeWebhooks) GetSubscription(ctx context.Context, owner, id string) (*webhooks.Subscription, error) {
+    s, ok := f.subs[id]
+    if !ok || s.Owner != owner {
+        return nil, webhooks.ErrSubscriptionNotFound
+    }
+    return s, nil
+}
+
+func (f *fakeWebhooks) UpdateSubscription(ctx context.Context, req webhooks.UpdateRequest) (*webhooks.Subscription, error) {
+    s, err := f.GetSubscription(ctx, req.Owner, req.ID)
+    if err != nil {
+        return nil, err
+    }
+    s.URL, s.Description, s.Filter, s.Active = req.URL, req.Description, req.Filter, req.Active
+    return s, nil
+}
+
+func (f *fakeWebhooks) DeleteSubscription(ctx context.Context, owner, id string) error {
+    if _, err := f.GetSubscription(ctx, owner, id); err != nil {
+        return err
+    }
+    delete(f.subs, id)
+    return nil
+}
+
+func (f *fakeWebhooks) RotateSecret(ctx context.Context, owner, id string) (*webhooks.Subscription, error) {
+    s, err := f.GetSubscription(ctx, owner, id)
+    if err != nil {
+        return nil, err
+    }
+    sub := *s
+    sub.Secret = "whsec_rotated"
+    return &sub, nil
+}
+
+func (f *fakeWebhooks) ListDeliveries(ctx context.Context, filter webhooks.DeliveryFilter) ([]webhooks.Delivery, error) {
+    if _, err := f.GetSubscription(ctx, filter.Owner, filter.SubscriptionID); err != nil {
+        return nil, err
+    }
+    deliveries := []webhooks.Delivery{}
+    for _, d := range f.deliveries {
+        if d.SubscriptionID == filter.SubscriptionID && (filter.Status == "" || d.Status == filter.Status) {
+            deliveries = append(deliveries, *d)
+        }
+    }
+    return deliveries, nil
+}
+
+func (f *fakeWebhooks) GetDelivery(ctx context.Context, owner, id string) (*webhooks.Delivery, error) {
+    d, ok := f.deliveries[id]
+    if !ok {
+        return nil, webhooks.ErrDeliveryNotFound
+    }
+    if _, err := f.GetSubscription(ctx, owner, d.SubscriptionID); err != nil {
+        return nil, webhooks.ErrDeliveryNotFound
+    }
+    return d, nil
+}
+
+func (f *fakeWebhooks) ReplayDelivery(ctx context.Context, owner, id string) (*webhooks.Delivery, error) {
+    d, err := f.GetDelivery(ctx, owner, id)
+    if err != nil {
+        return nil, err
+    }
+    if d.Status == webhooks.DeliveryPending {
+        return nil, webhooks.ErrDeliveryPending
+    }
+    d.Status = webhooks.DeliveryPending
+    d.Attempts = 0
+    return d, nil
+}
+
+type fakePayouts struct {
+    settings map[string]*payouts.Settings
+    payouts  map[string]*payouts.Payout
+    // payable is what each merchant could be paid out
+    payable map[string]money.Money
+}
+
+func (f *fakePayouts) SetSettings(ctx context.Context, req payouts.Settings) (*payouts.Settings, error) {
+    if req.Schedule == payouts.ScheduleWeekly && req.Weekday == nil {
+        return nil, payouts.ErrInvalidPayout
+    }
+    if f.settings == nil {
+        f.settings = map[string]*payouts.Settings{}
+    }
+    req.CurrencyCode = req.MinimumAmount.Currency()
+    f.settings[req.MerchantID] = &req
+    return &req, nil
+}
+
+func (f *fakePayouts) GetSettings(ctx context.Context, merchantID string) (*payouts.Settings, error) {
+    s, ok := f.settings[merchantID]
+    if !ok {
+        return nil, payouts.ErrSettingsNotFound
+    }
+    return s, nil
+}
+
+func (f *fakePayouts) Compute(ctx context.Context, merchantID string) (*payouts.Computation, error) {
+    s, err := f.GetSettings(ctx, merchantID)
+    if err != nil {
+        return nil, err
+    }
+    payable := f.payable[merchantID]
+    return &payouts.Computation{MerchantID: merchantID, Posted: payable, Payable: payable, Minimum: s.MinimumAmount, Eligible: !payable.LessThan(s.MinimumAmount)}, nil
+}
+
+func (f *fakePayouts) RequestPayout(ctx context.Context, req payouts.PayoutRequest) (*payouts.Payout, error) {
+    c, err := f.Compute(ctx, req.MerchantID)
+    if err != nil {
+        return nil, err
+    }
+    if !c.Eligible {
+        return nil, payouts.ErrBelowMinimum
+    }
+    if f.payouts == nil {
+        f.payouts = map[string]*payouts.Payout{}
+    }
+    id := fmt.Sprintf("po-%d", len(f.payouts)+1)
+    f.payouts[id] = &payouts.Payout{ID: id, MerchantID: req.MerchantID, Amount: c.Payable, Trigger: payouts.ScheduleOnDemand, Status: payouts.StatusPending, TransactionID: "txn-" + id, CreatedBy: req.CreatedBy}
+    return f.payouts[id], nil
+}
+
+func (f *fakePayouts) GetPayout(ctx context.Context, id string) (*payouts.Payout, error) {
+    p, ok := f.payouts[id]
+    if !ok {
+        return nil, payouts.ErrPayoutNotFound
+    }
+    return p, nil
+}
+
+func (f *fakePayouts) ListPayouts(ctx context.Context, filter payouts.PayoutFilter) ([]payouts.Payout, error) {
+    list := []payouts.Payout{}
+    for _, p := range f.payouts {
+        if (filter.MerchantID == "" || p.MerchantID == filter.MerchantID) && (filter.Status == "" || p.Status == filter.Status) {
+            list = append(list, *p)
+        }
+    }
+    return list, nil
+}
+
+func (f *fakePayouts) transition(req payouts.TransitionRequest, from []string, to string) (*payouts.Payout, error) {
+    p, err := f.GetPayout(context.Background(), req.PayoutID)
+    if err != nil {
+        return nil, err
+    }
+    for _, status := range from {
+        if p.Status == status {
+            p.Status = to
+            p.UpdatedBy = req.ChangedBy
+            return p, nil
+        }
+    }
+    return nil, payouts.ErrInvalidPayoutTransition
+}
+
+func (f *fakePayouts) MarkSent(ctx context.Context, req payouts.TransitionRequest) (*payouts.Payout, error) {
+    p, err := f.transition(req, []string{payouts.StatusPending}, payouts.StatusSent)
+    if err == nil {
+        p.BankReference = req.BankReference
+    }
+    return p, err
+}
+
+func (f *fakePayouts) MarkPaid(ctx context.Context, req payouts.TransitionRequest) (*payouts.Payout, error) {
+    return f.transition(req, []string{payouts.StatusSent}, payouts.StatusPaid)
+}
+
+func (f *fakePayouts) MarkFailed(ctx context.Context, req payouts.TransitionRequest) (*payouts.Payout, error) {
+    p, err := f.transition(req, []string{payouts.StatusPending, payouts.StatusSent, payouts.StatusPaid}, payouts.StatusFailed)
+    if err == nil {
+        p.FailureReason = req.Reason
+        p.ReversalID = "rev-" + p.ID
+    }
+    return p, err
+}
+
+type fakeFees struct {
+    schedules []*fees.Schedule
+    charges   []fees.ChargeRequest
+}
+
+func (f *fakeFees) CreateSchedule(ctx context.Context, req fees.ScheduleRequest) (*fees.Schedule, error) {
+    if req.MinimumFee != nil && req.MaximumFee != nil && req.MinimumFee.GreaterThan(*req.MaximumFee) {
+        return nil, fees.ErrInvalidSchedule
+    }
+    s := &fees.Schedule{
+        ID:               fmt.Sprintf("fs-%d", len(f.schedules)+1),
+        FeeType:          req.FeeType,
+        MerchantID:       req.MerchantID,
+        CardBrand:        req.CardBrand,
+        CurrencyCode:     req.FixedAmount.Currency(),
+        FixedAmount:      req.FixedAmount,
+        Rate:             req.Rate,
+        Tiers:            req.Tiers,
+        MinimumFee:       req.MinimumFee,
+        MaximumFee:       req.MaximumFee,
+        RevenueAccountID: req.RevenueAccountID,
+        CreatedBy:        req.CreatedBy,
+    }
+    f.schedules = append(f.schedules, s)
+    return s, nil
+}
+
+func (f *fakeFees) GetSchedule(ctx context.Context, id string) (*fees.Schedule, error) {
+    for _, s := range f.schedules {
+        if s.ID == id {
+            return s, nil
+        }
+    }
+    return nil, fees.ErrScheduleNotFound
+}
+
+func (f *fakeFees) ListSchedules(ctx context.Context, filter fees.ScheduleFilter) ([]fees.Schedule, error) {
+    out := []fees.Schedule{}
+    for _, s := range f.schedules {
+        if filter.FeeType == "" || s.FeeType == filter.FeeType {
+            out = append(out, *s)
+        }
+    }
+    return out, nil
+}
+
+// Quote charges the most specific matching schedule, brand over default
+func (f *fakeFees) Quote(ctx context.Context, req fees.QuoteRequest) (*fees.Fee, error) {
+    var match *fees.Schedule
+    for _, s := range f.schedules {
+        if s.FeeType != req.FeeType || s.CurrencyCode != req.Amount.Currency() {
+            continue
+        }
+        if s.CardBrand == req.CardBrand || (s.CardBrand == "" && match == nil) {
+            match = s
+        }
+    }
+    if match == nil {
+        return nil, fees.ErrScheduleNotFound
+    }
+    amount, err := fees.Calculate(match, req.Amount)
+    if err != nil {
+        return nil, err
+    }
+    return &fees.Fee{ScheduleID: match.ID, FeeType: match.FeeType, Base: req.Amount, Amount: amount, RevenueAccountID: match.RevenueAccountID}, nil
+}
+
+func (f *fakeFees) Charge(ctx context.Context, req fees.ChargeRequest) (*fees.Fee, error) {
+    fee, err := f.Quote(ctx, req.QuoteRequest)
+    if err != nil {
+        return nil, err
+    }
+    f.charges = append(f.charges, req)
+    fee.TransactionID = fmt.Sprintf("txn-fee-%d", len(f.charges))
+    return fee, nil
+}
+
+type fakeEvidence struct {
+    evidence []*disputes.Evidence
+    content  map[string][]byte
+}
+
+func (f *fakeEvidence) UploadEvidence(ctx context.Context, req disputes.UploadEvidenceRequest) (*disputes.Evidence, error) {
+    if req.DisputeID != "DSP-1" {
+        return nil, disputes.ErrDisputeNotFound
+    }
+    if req.MIMEType != "application/pdf" {
+        return nil, disputes.ErrUnsupportedEvidenceType
+    }
+    if len(req.Content) > 16 {
+        return nil, disputes.ErrEvidenceTooLarge
+    }
+    e := &disputes.Evidence{
+        ID:           fmt.Sprintf("ev-%d", len(f.evidence)+1),
+        DisputeID:    req.DisputeID,
+        EvidenceType: req.EvidenceType,
+        Description:  req.Description,
+        FileName:     req.FileName,
+        MIMEType:     req.MIMEType,
+        SizeBytes:    int64(len(req.Content)),
+        Metadata:     disputes.MaskPII(req.Metadata),
+        UploadedBy:   req.UploadedBy,
+    }
+    f.evidence = append(f.evidence, e)
+    if f.content == nil {
+        f.content = map[string][]byte{}
+    }
+    f.content[e.ID] = req.Content
+    return e, nil
+}
+
+func (f *fakeEvidence) ListEvidence(ctx context.Context, disputeID string) ([]*disputes.Evidence, error) {
+    if disputeID != "DSP-1" {
+        return nil, disputes.ErrDisputeNotFound
+    }
+    return f.evidence, nil
+}
+
+func (f *fakeEvidence) DownloadEvidence(ctx context.Context, disputeID, evidenceID string) (*disputes.Evidence, []byte, error) {
+    for _, e := range f.evidence {
+        if e.DisputeID == disputeID && e.ID == evidenceID {
+            return e, f.content[e.ID], nil
+        }
+    }
+    return nil, nil, disputes.ErrEvidenceNotFound
+}
+
+// fakeDisputes moves disputes through the chargeback cycle by the state
+// machine's rules; only DSP-1 and its hold HLD-1 exist. New disputes may be
+// opened against JE-1, a 100.00 USD entry, up to its amount.
+type fakeDisputes struct {
+    status     disputes.DisputeState
+    filter     disputes.DisputeFilter
+    holdStatus string
+    disputed   money.Money
+}
+
+func (f *fakeDisputes) advance(disputeID string, to disputes.DisputeState, actor, reason string) error {
+    if disputeID != "DSP-1" {
+        return disputes.ErrDisputeNotFound
+    }
+    if actor == "" || reason == "" {
+        return disputes.ErrInvalidDisputeAction
+    }
+    if !disputes.NewStateMachine(nil).IsValidTransition(f.status, to) {
+        return &disputes.InvalidStateTransitionError{FromState: f.status, ToState: to, DisputeID: disputeID}
+    }
+    f.status = to
+    return nil
+}
+
+func (f *fakeDisputes) CreateDispute(ctx context.Context, req disputes.CreateDisputeRequest) (*disputes.Dispute, error) {
+    if req.JournalEntryID != "JE-1" {
+        return nil, fmt.Errorf("journal entry not found: %s", req.JournalEntryID)
+    }
+    original := money.MustParse("100.00", "USD")
+    if f.disputed.Currency() == "" {
+        f.disputed = money.MustNew(0, "USD")
+    }
+    total, err := f.disputed.Add(req.DisputedAmount)
+    if err != nil {
+        return nil, err
+    }
+    if total.GreaterThan(original) {
+        return nil, &disputes.DisputeAmountExceededError{
+            JournalEntryID:  req.JournalEntryID,
+            OriginalAmount:  original,
+            AlreadyDisputed: f.disputed,
+            RequestedAmount: req.DisputedAmount,
+        }
+    }
+    f.disputed = total
+    return &disputes.Dispute{
+        DisputeID:      fmt.Sprintf("DSP-%d", total.MinorUnits()),
+        JournalEntryID: req.JournalEntryID,
+        OriginalAmount: original,
+        DisputedAmount: req.DisputedAmount,
+        CurrencyCode:   req.CurrencyCode,
+        Status:         disputes.StatePending,
+    }, nil
+}
+
+func (f *fakeDisputes) AuthorizeDispute(ctx context.Context, disputeID, authorizedBy string) error {
+    return f.advance(disputeID, disputes.StateAuthorized, authorizedBy, "authorized")
+}
+
+func (f *fakeDisputes) SettleTransaction(ctx context.Context, journalEntryID, settledBy string) error {
+    return f.advance("DSP-1", disputes.StateSettled, settledBy, "settled")
+}
+
+func (f *fakeDisputes) InitiateDispute(ctx context.Context, disputeID, initiatedBy string) error {
+    return f.advance(disputeID, disputes.StateDisputed, initiatedBy, "initiated")
+}
+
+func (f *fakeDisputes) ReverseDispute(ctx context.Context, disputeID, reversedBy, reason string) error {
+    return f.advance(disputeID, disputes.StateReversed, reversedBy, reason)
+}
+
+func (f *fakeDisputes) RepresentDispute(ctx context.Context, disputeID, representedBy, reason string) error {
+    return f.advance(disputeID, disputes.StateRepresented, representedBy, reason)
+}
+
+func (f *fakeDisputes) EscalateDispute(ctx context.Context, disputeID, escalatedBy, reason string) (disputes.DisputeState, error) {
+    to := disputes.StatePreArbitration
+    if f.status == disputes.StatePreArbitration {
+        to = disputes.StateArbitration
+    }
+    return to, f.advance(disputeID, to, escalatedBy, reason)
+}
+
+func (f *fakeDisputes) AcceptDispute(ctx context.Context, disputeID, acceptedBy, reason string) error {
+    return f.advance(disputeID, disputes.StateAccepted, acceptedBy, reason)
+}
+
+func (f *fakeDisputes) ResolveDispute(ctx context.Context, disputeID string, outcome disputes.DisputeState, resolvedBy, reason string) error {
+    if outcome != disputes.StateWon && outcome != disputes.StateLost {
+        return disputes.ErrInvalidDisputeAction
+    }
+    return f.advance(disputeID, outcome, resolvedBy, reason)
+}
+
+func (f *fakeDisputes) ExtendHold(ctx context.Context, holdID string, expiresAt time.Time, extendedBy, reason string) (*disputes.Hold, error) {
+    if holdID != "HLD-1" {
+        return nil, disputes.ErrHoldNotFound
+    }
+    if extendedBy == "" || reason == "" {
+        return nil, disputes.ErrInvalidHoldAction
+    }
+    if f.holdStatus == "RELEASED" {
+        return nil, disputes.ErrHoldNotActive
+    }
+    return &disputes.Hold{HoldID: holdID, Status: "ACTIVE", ExpiresAt: expiresAt}, nil
+}
+
+func (f *fakeDisputes) ReleaseHold(ctx context.Context, holdID, releasedBy, reason string) (*disputes.Hold, error) {
+    if holdID != "HLD-1" {
+        return nil, disputes.ErrHoldNotFound
+    }
+    if releasedBy == "" || reason == "" {
+        return nil, disputes.ErrInvalidHoldAction
+    }
+    if f.holdStatus == "RELEASED" {
+        return nil, disputes.ErrHoldNotActive
+    }
+    f.holdStatus = "RELEASED"
+    return &disputes.Hold{HoldID: holdID, Status: f.holdStatus, ReleasedBy: releasedBy}, nil
+}
+
+func (f *fakeDisputes) GetDispute(ctx context.Context, disputeID string) (*disputes.Dispute, error) {
+    return nil, nil
+}
+
+func (f *fakeDisputes) ListDisputes(ctx context.Context, filter disputes.DisputeFilter) ([]*disputes.Dispute, error) {
+    f.filter = filter
+    return nil, nil
+}
+
+func (f *fakeDisputes) CalculateMerchantReserve(ctx context.Context, merchantID string, transactionVolume money.Money) (money.Money, error) {
+    return money.Zero(transactionVolume.Currency())
+}
+
+func TestMTLSRequired(t *testing.T) {
+    deps, tlsCfg, clientTLS, noClientTLS := newTestDeps(t)
+
+    h, err := NewRouter(deps)
+    require.NoError(t, err)
+
+    ts := httptest.NewUnstartedServer(h)
+    ts.TLS = tlsCfg
+    ts.StartTLS()
+    defer ts.Close()
+
+    clientNoCert := &http.Client{Transport: &http.Transport{TLSClientConfig: noClientTLS}}
+    _, err = clientNoCert.Get(ts.URL + "/healthz")
+    require.Error(t, err)
+
+    clientWithCert := &http.Client{Transport: &http.Transport{TLSClientConfig: clientTLS}}
+    resp, err := clientWithCert.Get(ts.URL + "/healthz")
+    require.NoError(t, err)
+    require.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestAuthFailuresAndValidation(t *testing.T) {
+    deps, tlsCfg, clientTLS, _ := newTestDeps(t)
+
+    h, err := NewRouter(deps)
+    require.NoError(t, err)
+
+    ts := httptest.NewUnstartedServer(h)
+    ts.TLS = tlsCfg
+    ts.StartTLS()
+    defer ts.Close()
+
+    client := &http.Client{Transport: &http.Transport{TLSClientConfig: clientTLS}}
+
+    resp, err := client.Get(ts.URL + "/v1/accounts")
+    require.NoError(t, err)
+    require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+    token := issueToken(t, deps, "read-client", "read-secret", "accounts:read")
+
+    reqBody := map[string]any{
+        "account_type":  "asset",
+        "name":          "Test",
+        "currency_code": "USD",
+        "created_by":    "tester",
+    }
+    b, _ := json.Marshal(reqBody)
+
+    req, _ := http.NewRequest(http.MethodPost, ts.URL+"/v1/accounts", bytes.NewReader(b))
+    req.Header.Set("Authorization", "Bearer "+token)
+    resp, err = client.Do(req)
+    require.NoError(t, err)
+    require.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+    // validation error before ledger call
+    token = issueToken(t, deps, "write-client", "write-secret", "accounts:write")
+    req, _ = http.NewRequest(http.MethodPost, ts.URL+"/v1/accounts", bytes.NewReader(b))
+    req.Header.Set("Authorization", "Bearer "+token)
+    resp, err = client.Do(req)
+    require.NoError(t, err)
+    require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+    fl := deps.LedgerWriter.(*fakeLedger)
+    require.Equal(t, 0, fl.createCalls)
+}
+
+func TestRateLimitTrips(t *testing.T) {
+    deps, tlsCfg, clientTLS, _ := newTestDeps(t)
+    deps.RateLimiter.Capacity = 1
+    deps.RateLimiter.RefillRate = 0.0000001
+
+    h, err := NewRouter(deps)
+    require.NoError(t, err)
+
+    ts := httptest.NewUnstartedServer(h)
+    ts.TLS = tlsCfg
+    ts.StartTLS()
+    defer ts.Close()
+
+    client := &http.Client{Transport: &http.Transport{TLSClientConfig: clientTLS}}
+
+    resp, err := client.Get(ts.URL + "/oauth/jwks.json")
+    require.NoError(t, err)
+    require.Equal(t, http.StatusOK, resp.StatusCode)
+
+    resp, err = client.Get(ts.URL + "/oauth/jwks.json")
+    require.NoError(t, err)
+    require.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+}
+
+func TestBodySizeLimit(t *testing.T) {
+    deps, tlsCfg, clientTLS, _ := newTestDeps(t)
+    deps.MaxBodyBytes = 32
+
+    h, err := NewRouter(deps)
+    require.NoError(t, err)
+
+    ts := httptest.NewUnstartedServer(h)
+    ts.TLS = tlsCfg
+    ts.StartTLS()
+    defer ts.Close()
+
+    client := &http.Client{Transport: &http.Transport{TLSClientConfig: clientTLS}}
+    token := issueToken(t, deps, "write-client", "write-secret", "accounts:write")
+
+    // body > 32 bytes
+    bigBody := []byte(`{"account_number":"ACC001","account_type":"asset","name":"Main","currency_code":"USD","created_by":"tester"}`)
+    req, _ := http.NewRequest(http.MethodPost, ts.URL+"/v1/accounts", bytes.NewReader(bigBody))
+    req.Header.Set("Authorization", "Bearer "+token)
+    req.Header.Set("Content-Type", "application/json")
+    resp, err := client.Do(req)
+    require.NoError(t, err)
+    require.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+}
+
+func TestSuccessfulFlow(t *testing.T) {
+    deps, tlsCfg, clientTLS, _ := newTestDeps(t)
+
+    h, err := NewRouter(deps)
+    require.NoError(t, err)
+
+    ts := httptest.NewUnstartedServer(h)
+    ts.TLS = tlsCfg
+    ts.StartTLS()
+    defer ts.Close()
+
+    client := &http.Client{Transport: &http.Transport{TLSClientConfig: clientTLS}}
+
+    token := issueToken(t, deps, "full-client", "full-secret", "accounts:write accounts:read ledger:read ledger:write")
+
+    createReq := map[string]any{
+        "account_number": "ACC001",
+        "account_type":   "asset",
+        "name":           "Main",
+        "currency_code":  "USD",
+        "created_by":     "tester",
+    }
+    b, _ := json.Marshal(createReq)
+    req, _ := http.NewRequest(http.MethodPost, ts.URL+"/v1/accounts", bytes.NewReader(b))
+    req.Header.Set("Authorization", "Bearer "+token)
+    resp, err := client.Do(req)
+    require.NoError(t, err)
+    require.Equal(t, http.StatusCreated, resp.StatusCode)
+    require.NotEmpty(t, resp.Header.Get(security.CorrelationIDHeader))
+
+    // debit
+    debitReq := map[string]any{"account_id": "acc-1", "amount": 10, "currency_code": "USD", "created_by": "tester"}
+    b, _ = json.Marshal(debitReq)
+    req, _ = http.NewRequest(http.MethodPost, ts.URL+"/v1/ledger/debit", bytes.NewReader(b))
+    req.Header.Set("Authorization", "Bearer "+token)
+    resp, err = client.Do(req)
+    require.NoError(t, err)
+    require.Equal(t, http.StatusOK, resp.StatusCode)
+
+    // decimal string amounts are accepted
+    debitReq = map[string]any{"account_id": "acc-1", "amount": "10.25", "currency_code": "USD", "created_by": "tester"}
+    b, _ = json.Marshal(debitReq)
+    req, _ = http.NewRequest(http.MethodPost, ts.URL+"/v1/ledger/debit", bytes.NewReader(b))
+    req.Header.Set("Authorization", "Bearer "+token)
+    resp, err = client.Do(req)
+    require.NoError(t, err)
+    require.Equal(t, http.StatusOK, resp.StatusCode)
+    var entryResp struct {
+        Amount money.Money `json:"amount"`
+    }
+    require.NoError(t, json.NewDecoder(resp.Body).Decode(&entryResp))
+    require.Equal(t, money.MustParse("10.25", "USD"), entryResp.Amount)
+
+    // sub-minor-unit precision is rejected rather than rounded
+    debitReq = map[string]any{"account_id": "acc-1", "amount": "10.255", "currency_code": "USD", "created_by": "tester"}
+    b, _ = json.Marshal(debitReq)
+    req, _ = http.NewRequest(http.MethodPost, ts.URL+"/v1/ledger/debit", bytes.NewReader(b))
+    req.Header.Set("Authorization", "Bearer "+token)
+    resp, err = client.Do(req)
+    require.NoError(t, err)
+    require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+    // zero amounts are rejected in either form
+    fl := deps.LedgerWriter.(*fakeLedger)
+    debits := fl.debitCalls
+    for _, amount := range []any{0, "0", "0.00", "000.000"} {
+        debitReq = map[string]any{"account_id": "acc-1", "amount": amount, "currency_code": "USD", "created_by": "tester"}
+        b, _ = json.Marshal(debitReq)
+        req, _ = http.NewRequest(http.MethodPost, ts.URL+"/v1/ledger/debit", bytes.NewReader(b))
+        req.Header.Set("Authorization", "Bearer "+token)
+        resp, err = client.Do(req)
+        require.NoError(t, err)
+        require.Equal(t, http.StatusBadRequest, resp.StatusCode, "amount %v", amount)
+    }
+    require.Equal(t, debits, fl.debitCalls)
+
+    // balance
+    req, _ = http.NewRequest(http.MethodGet, ts.URL+"/v1/ledger/balance?account_id=acc-1", nil)
+    req.Header.Set("Authorization", "Bearer "+token)
+    resp, err = client.Do(req)
+    require.NoError(t, err)
+    require.Equal(t, http.StatusOK, resp.StatusCode)
+    var balResp struct {
+        Balance money.Money `json:"balance"`
+    }
+    require.NoError(t, json.NewDecoder(resp.Body).Decode(&balResp))
+    require.Equal(t, money.MustParse("123.45", "USD"), balResp.Balance)
+}
+
+func TestPostTransaction(t *testing.T) {
+    deps, tlsCfg, clientTLS, _ := newTestDeps(t)
+
+    h, err := NewRouter(deps)
+    require.NoError(t, err)
+
+    ts := httptest.NewUnstartedServer(h)
+    ts.TLS = tlsCfg
+    ts.StartTLS()
+    defer ts.Close()
+
+    client := &http.Client{Transport: &http.Transport{TLSClientConfig: clientTLS}}
+    token := issueToken(t, deps, "full-client", "full-secret", "ledger:write")
+
+    // card capture split across merchant payable, fee revenue and network cost
+    txnReq := map[string]any{
+        "description": "card capture",
+        "created_by":  "tester",
+        "entries": []map[string]any{
+            {"account_id": "acc-clearing", "entry_type": "debit", "amount": "100.00", "currency_code": "USD"},
+            {"account_id": "acc-merchant", "entry_type": "credit", "amount": "97.10", "currency_code": "USD"},
+            {"account_id": "acc-fees", "entry_type": "credit", "amount": "2.50", "currency_code": "USD"},
+            {"account_id": "acc-network", "entry_type": "credit", "amount": "0.40", "currency_code": "USD"},
+        },
+    }
+    b, _ := json.Marshal(txnReq)
+    req, _ := http.NewRequest(http.MethodPost, ts.URL+"/v1/ledger/transactions", bytes.NewReader(b))
+    req.Header.Set("Authorization", "Bearer "+token)
+    resp, err := client.Do(req)
+    require.NoError(t, err)
+    require.Equal(t, http.StatusCreated, resp.StatusCode)
+    var txnResp struct {
+        Transaction ledger.Transaction `json:"transaction"`
+    }
+    require.NoError(t, json.NewDecoder(resp.Body).Decode(&txnResp))
+    require.NotEmpty(t, txnResp.Transaction.TransactionID)
+    require.Len(t, txnResp.Transaction.Entries, 4)
+
+    // unbalanced legs are rejected
+    txnReq["entries"] = []map[string]any{
+        {"account_id": "acc-clearing", "entry_type": "debit", "amount": "100.00", "currency_code": "USD"},
+        {"account_id": "acc-merchant", "entry_type": "credit", "amount": "97.10", "currency_code": "USD"},
+    }
+    b, _ = json.Marshal(txnReq)
+    req, _ = http.NewRequest(http.MethodPost, ts.URL+"/v1/ledger/transactions", bytes.NewReader(b))
+    req.Header.Set("Authorization", "Bearer "+token)
+    resp, err = client.Do(req)
+    require.NoError(t, err)
+    require.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+
+    // a single leg fails schema validation before reaching the ledger
+    txnReq["entries"] = []map[string]any{
+        {"account_id": "acc-clearing", "entry_type": "debit", "amount": "100.00", "currency_code": "USD"},
+    }
+    b, _ = json.Marshal(txnReq)
+    req, _ = http.NewRequest(http.MethodPost, ts.URL+"/v1/ledger/transactions", bytes.NewReader(b))
+    req.Header.Set("Authorization", "Bearer "+token)
+    resp, err = client.Do(req)
+    require.NoError(t, err)
+    require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+    fl := deps.LedgerWriter.(*fakeLedger)
+    require.Equal(t, 2, fl.transactionCalls)
+}
+
+func TestStatement(t *testing.T) {
+    deps, tlsCfg, clientTLS, _ := newTestDeps(t)
+
+    h, err := NewRouter(deps)
+    require.NoError(t, err)
+
+    ts := httptest.NewUnstartedServer(h)
+    ts.TLS = tlsCfg
+    ts.StartTLS()
+    defer ts.Close()
+
+    client := &http.Client{Transport: &http.Transport{TLSClientConfig: clientTLS}}
+    token := issueToken(t, deps, "full-client", "full-secret", "ledger:read")
+
+    get := func(path string) *http.Response {
+        req, _ := http.NewRequest(http.MethodGet, ts.URL+path, nil)
+        req.Header.Set("Authorization", "Bearer "+token)
+        resp, err := client.Do(req)
+        require.NoError(t, err)
+        return resp
+    }
+
+    resp := get("/v1/accounts/acc-1/statement?from=2024-01-01T00:00:00Z&to=2024-02-01T00:00:00Z&limit=1")
+    require.Equal(t, http.StatusOK, resp.StatusCode)
+    var stmt struct {
+        OpeningBalance money.Money             `json:"opening_balance"`
+        ClosingBalance money.Money             `json:"closing_balance"`
+        Entries        []ledger.StatementEntry `json:"entries"`
+        NextCursor     string                  `json:"next_cursor"`
+    }
+    require.NoError(t, json.NewDecoder(resp.Body).Decode(&stmt))
+    require.Equal(t, "100.00", stmt.OpeningBalance.Amount())
+    require.Equal(t, "75.00", stmt.ClosingBalance.Amount())
+    require.Len(t, stmt.Entries, 1)
+    require.Equal(t, "75.00", stmt.Entries[0].RunningBalance.Amount())
+    require.Equal(t, "page-2", stmt.NextCursor)
+
+    // the cursor from the previous page is passed through
+    resp = get("/v1/accounts/acc-1/statement?from=2024-01-01T00:00:00Z&to=2024-02-01T00:00:00Z&limit=1&cursor=" + stmt.NextCursor)
+    require.Equal(t, http.StatusOK, resp.StatusCode)
+
+    fl := deps.LedgerReader.(*fakeLedger)
+    require.Len(t, fl.statementRequests, 2)
+    require.Equal(t, 1, fl.statementRequests[0].Limit)
+    require.Equal(t, "page-2", fl.statementRequests[1].Cursor)
+
+    resp = get("/v1/accounts/acc-1/statement?from=yesterday&to=2024-02-01T00:00:00Z")
+    require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+    // exports page through the whole period
+    resp = get("/v1/accounts/acc-1/statement?from=2024-01-01T00:00:00Z&to=2024-02-01T00:00:00Z&format=camt053&limit=1")
+    require.Equal(t, http.StatusOK, resp.StatusCode)
+    require.Equal(t, "application/xml", resp.Header.Get("Content-Type"))
+    require.Equal(t, `attachment; filename="2000-2024-01-01.xml"`, resp.Header.Get("Content-Disposition"))
+    body, _ := io.ReadAll(resp.Body)
+    require.Equal(t, 2, strings.Count(string(body), "<Ntry>"))
+    require.Len(t, fl.statementRequests, 4)
+    require.Equal(t, 1000, fl.statementRequests[2].Limit)
+
+    resp = get("/v1/accounts/acc-1/statement?from=2024-01-01T00:00:00Z&to=2024-02-01T00:00:00Z&format=ofx")
+    require.Equal(t, http.StatusOK, resp.StatusCode)
+    require.Equal(t, "application/x-ofx", resp.Header.Get("Content-Type"))
+
+    resp = get("/v1/accounts/acc-1/statement?from=2024-01-01T00:00:00Z&to=2024-02-01T00:00:00Z&format=csv")
+    require.Equal(t, http.StatusOK, resp.StatusCode)
+    require.Equal(t, "text/csv; charset=utf-8", resp.Header.Get("Content-Type"))
+
+    resp = get("/v1/accounts/acc-1/statement?from=2024-01-01T00:00:00Z&to=2024-02-01T00:00:00Z&format=pdf")
+    require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+    resp = get("/v1/accounts/acc-missing/statement?from=2024-01-01T00:00:00Z&to=2024-02-01T00:00:00Z")
+    require.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+    // point-in-time balance, e.g. at month-end
+    resp = get("/v1/ledger/balance?account_id=acc-1&at=2024-01-31T23:59:59Z")
+    require.Equal(t, http.StatusOK, resp.StatusCode)
+    var bal struct {
+        Balance money.Money `json:"balance"`
+        At      string      `json:"at"`
+    }
+    require.NoError(t, json.NewDecoder(resp.Body).Decode(&bal))
+    require.Equal(t, "100.00", bal.Balance.Amount())
+    require.Equal(t, "2024-01-31T23:59:59Z", bal.At)
+
+    // statements need ledger:read
+    token = issueToken(t, deps, "read-client", "read-secret", "accounts:read")
+    resp = get("/v1/accounts/acc-1/statement?from=2024-01-01T00:00:00Z&to=2024-02-01T00:00:00Z")
+    require.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
+
+func TestPendingTransactions(t *testing.T) {
+    deps, tlsCfg, clientTLS, _ := newTestDeps(t)
+
+    h, err := NewRouter(deps)
+    require.NoError(t, err)
+
+    ts := httptest.NewUnstartedServer(h)
+    ts.TLS = tlsCfg
+    ts.StartTLS()
+    defer ts.Close()
+
+    client := &http.Client{Transport: &http.Transport{TLSClientConfig: clientTLS}}
+    token := issueToken(t, deps, "full-client", "full-secret", "ledger:read ledger:write")
+
+    do := func(method, path string, body any) *http.Response {
+        var rdr io.Reader
+        if body != nil {
+            b, _ := json.Marshal(body)
+            rdr = bytes.NewReader(b)
+        }
+        req, _ := http.NewRequest(method, ts.URL+path, rdr)
+        req.Header.Set("Authorization", "Bearer "+token)
+        resp, err := client.Do(req)
+        require.NoError(t, err)
+        return resp
+    }
+    decode := func(resp *http.Response) *ledger.PendingTransaction {
+        var out struct {
+            PendingTransaction *ledger.PendingTransaction `json:"pending_transaction"`
+        }
+        require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+        return out.PendingTransaction
+    }
+
+    authorize := map[string]any{
+        "from_account_id": "acc-customer",
+        "to_account_id":   "acc-merchant",
+        "amount":          "50.00",
+        "currency_code":   "USD",
+        "description":     "card authorization",
+        "created_by":      "tester",
+        "ttl_seconds":     3600,
+    }
+    resp := do(http.MethodPost, "/v1/ledger/pending", authorize)
+    require.Equal(t, http.StatusCreated, resp.StatusCode)
+    pending := decode(resp)
+    require.Equal(t, ledger.PendingStatusPending, pending.Status)
+    require.Equal(t, "50.00", pending.Amount.Amount())
+
+    resp = do(http.MethodGet, "/v1/ledger/pending/"+pending.ID, nil)
+    require.Equal(t, http.StatusOK, resp.StatusCode)
+
+    // an amount without a currency fails schema validation
+    resp = do(http.MethodPost, "/v1/ledger/pending/"+pending.ID+"/capture", map[string]any{"amount": "30.00", "captured_by": "tester"})
+    require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+    // partial capture
+    resp = do(http.MethodPost, "/v1/ledger/pending/"+pending.ID+"/capture", map[string]any{"amount": "30.00", "currency_code": "USD", "captured_by": "tester"})
+    require.Equal(t, http.StatusOK, resp.StatusCode)
+    captured := decode(resp)
+    require.Equal(t, ledger.PendingStatusPosted, captured.Status)
+    require.NotNil(t, captured.CapturedAmount)
+    require.Equal(t, "30.00", captured.CapturedAmount.Amount())
+
+    // a resolved authorization cannot be captured or voided again
+    resp = do(http.MethodPost, "/v1/ledger/pending/"+pending.ID+"/capture", map[string]any{"captured_by": "tester"})
+    require.Equal(t, http.StatusConflict, resp.StatusCode)
+    resp = do(http.MethodPost, "/v1/ledger/pending/"+pending.ID+"/void", map[string]any{"voided_by": "tester"})
+    require.Equal(t, http.StatusConflict, resp.StatusCode)
+
+    // void releases a second authorization
+    resp = do(http.MethodPost, "/v1/ledger/pending", authorize)
+    require.Equal(t, http.StatusCreated, resp.StatusCode)
+    second := decode(resp)
+    resp = do(http.MethodPost, "/v1/ledger/pending/"+second.ID+"/void", map[string]any{"voided_by": "tester"})
+    require.Equal(t, http.StatusOK, resp.StatusCode)
+    require.Equal(t, ledger.PendingStatusVoided, decode(resp).Status)
+
+    resp = do(http.MethodPost, "/v1/ledger/pending/missing/void", map[string]any{"voided_by": "tester"})
+    require.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+    resp = do(http.MethodGet, "/v1/accounts/acc-1/balances", nil)
+    require.Equal(t, http.StatusOK, resp.StatusCode)
+    var balances ledger.AccountBalances
+    require.NoError(t, json.NewDecoder(resp.Body).Decode(&balances))
+    require.Equal(t, "123.45", balances.Posted.Amount())
+    require.Equal(t, "20.00", balances.Pending.Amount())
+    require.Equal(t, "100.00", balances.Available.Amount())
+}
+
+func TestReverseTransaction(t *testing.T) {
+    deps, tlsCfg, clientTLS, _ := newTestDeps(t)
+
+    h, err := NewRouter(deps)
+    require.NoError(t, err)
+
+    ts := httptest.NewUnstartedServer(h)
+    ts.TLS = tlsCfg
+    ts.StartTLS()
+    defer ts.Close()
+
+    client := &http.Client{Transport: &http.Transport{TLSClientConfig: clientTLS}}
+    token := issueToken(t, deps, "full-client", "full-secret", "ledger:read ledger:write")
+
+    do := func(method, path string, body any) *http.Response {
+        var rdr io.Reader
+        if body != nil {
+            b, _ := json.Marshal(body)
+            rdr = bytes.NewReader(b)
+        }
+        req, _ := http.NewRequest(method, ts.URL+path, rdr)
+        req.Header.Set("Authorization", "Bearer "+token)
+        resp, err := client.Do(req)
+        require.NoError(t, err)
+        return resp
+    }
+
+    // approver is required
+    resp := do(http.MethodPost, "/v1/ledger/transactions/txn-1/reverse", map[string]any{"reason": "duplicate", "reversed_by": "ops"})
+    require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+    partial := map[string]any{"amount": "30.00", "currency_code": "USD", "reason": "overcharge", "reversed_by": "ops", "approved_by": "manager"}
+    resp = do(http.MethodPost, "/v1/ledger/transactions/txn-1/reverse", partial)
+    require.Equal(t, http.StatusCreated, resp.StatusCode)
+    var out reversalResponse
+    require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+    require.False(t, out.Reversal.Full)
+    require.Equal(t, "30.00", out.Reversal.Amount.Amount())
+    require.Equal(t, "manager", out.Reversal.ApprovedBy)
+
+    full := map[string]any{"reason": "duplicate", "reversed_by": "ops", "approved_by": "manager"}
+    resp = do(http.MethodPost, "/v1/ledger/transactions/txn-1/reverse", full)
+    require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+    // a second full reversal is rejected
+    resp = do(http.MethodPost, "/v1/ledger/transactions/txn-1/reverse", full)
+    require.Equal(t, http.StatusConflict, resp.StatusCode)
+
+    resp = do(http.MethodPost, "/v1/ledger/transactions/missing/reverse", full)
+    require.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+    resp = do(http.MethodGet, "/v1/ledger/transactions/txn-1", nil)
+    require.Equal(t, http.StatusOK, resp.StatusCode)
+    var detail ledger.TransactionDetail
+    require.NoError(t, json.NewDecoder(resp.Body).Decode(&detail))
+    require.Len(t, detail.Reversals, 2)
+    require.True(t, detail.FullyReversed)
+
+    resp = do(http.MethodGet, "/v1/ledger/transactions/missing", nil)
+    require.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestFXTransfer(t *testing.T) {
+    deps, tlsCfg, clientTLS, _ := newTestDeps(t)
+
+    h, err := NewRouter(deps)
+    require.NoError(t, err)
+
+    ts := httptest.NewUnstartedServer(h)
+    ts.TLS = tlsCfg
+    ts.StartTLS()
+    defer ts.Close()
+
+    client := &http.Client{Transport: &http.Transport{TLSClientConfig: clientTLS}}
+    token := issueToken(t, deps, "full-client", "full-secret", "accounts:write ledger:read ledger:write")
+
+    do := func(method, path string, body any) *http.Response {
+        var rdr io.Reader
+        if body != nil {
+            b, _ := json.Marshal(body)
+            rdr = bytes.NewReader(b)
+        }
+        req, _ := http.NewRequest(method, ts.URL+path, rdr)
+        req.Header.Set("Authorization", "Bearer "+token)
+        resp, err := client.Do(req)
+        require.NoError(t, err)
+        return resp
+    }
+
+    transfer := map[string]any{
+        "from_account_id": "acc-usd",
+        "to_account_id":   "acc-eur",
+        "amount":          "100.00",
+        "currency_code":   "USD",
+        "description":     "wallet conversion",
+        "created_by":      "user-1",
+    }
+
+    // no clearing accounts yet
+    resp := do(http.MethodPost, "/v1/ledger/fx-transfer", transfer)
+    require.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+
+    resp = do(http.MethodPut, "/v1/fx/accounts/USD", map[string]any{
+        "clearing_account_id": "acc-clearing",
+        "gain_account_id":     "acc-gain",
+        "loss_account_id":     "acc-loss",
+        "updated_by":          "ops",
+    })
+    require.Equal(t, http.StatusOK, resp.StatusCode)
+
+    // no rate published yet
+    resp = do(http.MethodPost, "/v1/ledger/fx-transfer", transfer)
+    require.Equal(t, http.StatusNotFound, resp.StatusCode)
+    resp = do(http.MethodGet, "/v1/fx/rates?base=USD&quote=EUR", nil)
+    require.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+    // publishing rates needs ledger:fx
+    resp = do(http.MethodPost, "/v1/fx/rates", map[string]any{"base_currency": "USD", "quote_currency": "EUR", "rate": "0.9", "source": "ecb", "created_by": "ops"})
+    require.Equal(t, http.StatusForbidden, resp.StatusCode)
+    token = issueToken(t, deps, "fx-client", "fx-secret", "accounts:write ledger:read ledger:write ledger:fx")
+
+    resp = do(http.MethodPost, "/v1/fx/rates", map[string]any{"base_currency": "USD", "quote_currency": "EUR", "rate": "0.9", "effective_at": "yesterday", "created_by": "ops"})
+    require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+    resp = do(http.MethodPost, "/v1/fx/rates", map[string]any{"base_currency": "USD", "quote_currency": "EUR", "rate": "0.9", "source": "ecb", "created_by": "ops"})
+    require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+    resp = do(http.MethodGet, "/v1/fx/rates?base=USD&quote=EUR&at=2026-01-01T00:00:00Z", nil)
+    require.Equal(t, http.StatusOK, resp.StatusCode)
+    var rate fxRateResponse
+    require.NoError(t, json.NewDecoder(resp.Body).Decode(&rate))
+    require.Equal(t, "0.9000000000", rate.FXRate.Rate.String())
+
+    transfer["quoted_rate"] = "0.88"
+    resp = do(http.MethodPost, "/v1/ledger/fx-transfer", transfer)
+    require.Equal(t, http.StatusCreated, resp.StatusCode)
+    var out fxConversionResponse
+    require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+    require.Equal(t, "88.00", out.Conversion.TargetAmount.Amount())
+    require.Equal(t, "EUR", out.Conversion.TargetAmount.Currency())
+    require.Equal(t, "0.9000000000", out.Conversion.MarketRate.String())
+    require.Equal(t, "2.00", out.Conversion.RealisedGainLoss.Amount())
+}
+
+func TestAccountLifecycle(t *testing.T) {
+    deps, tlsCfg, clientTLS, _ := newTestDeps(t)
+
+    h, err := NewRouter(deps)
+    require.NoError(t, err)
+
+    ts := httptest.NewUnstartedServer(h)
+    ts.TLS = tlsCfg
+    ts.StartTLS()
+    defer ts.Close()
+
+    client := &http.Client{Transport: &http.Transport{TLSClientConfig: clientTLS}}
+    token := issueToken(t, deps, "full-client", "full-secret", "accounts:read accounts:write ledger:write")
+
+    do := func(method, path string, body any) *http.Response {
+        var rdr io.Reader
+        if body != nil {
+            b, _ := json.Marshal(body)
+            rdr = bytes.NewReader(b)
+        }
+        req, _ := http.NewRequest(method, ts.URL+path, rdr)
+        req.Header.Set("Authorization", "Bearer "+token)
+        resp, err := client.Do(req)
+        require.NoError(t, err)
+        return resp
+    }
+    change := map[string]any{"reason": "suspected fraud", "changed_by": "risk"}
+    posting := map[string]any{"account_id": "acc-1", "amount": "5.00", "currency_code": "USD", "created_by": "u1"}
+
+    // reason is required
+    resp := do(http.MethodPost, "/v1/accounts/acc-1/freeze", map[string]any{"changed_by": "risk"})
+    require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+    resp = do(http.MethodPost, "/v1/accounts/acc-1/freeze", change)
+    require.Equal(t, http.StatusOK, resp.StatusCode)
+    var out accountStatusResponse
+    require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+    require.Equal(t, ledger.AccountStatusActive, out.Transition.FromStatus)
+    require.Equal(t, ledger.AccountStatusFrozen, out.Transition.ToStatus)
+
+    resp = do(http.MethodPost, "/v1/accounts/acc-1/freeze", change)
+    require.Equal(t, http.StatusConflict, resp.StatusCode)
+
+    // frozen accounts take credits but not debits
+    resp = do(http.MethodPost, "/v1/ledger/debit", posting)
+    require.Equal(t, http.StatusConflict, resp.StatusCode)
+    resp = do(http.MethodPost, "/v1/ledger/credit", posting)
+    require.Equal(t, http.StatusOK, resp.StatusCode)
+
+    resp = do(http.MethodPost, "/v1/accounts/acc-1/unfreeze", change)
+    require.Equal(t, http.StatusOK, resp.StatusCode)
+    resp = do(http.MethodPost, "/v1/ledger/debit", posting)
+    require.Equal(t, http.StatusOK, resp.StatusCode)
+
+    resp = do(http.MethodPost, "/v1/accounts/acc-1/close", map[string]any{"reason": "customer request", "changed_by": "ops"})
+    require.Equal(t, http.StatusOK, resp.StatusCode)
+
+    // closing is irreversible
+    resp = do(http.MethodPost, "/v1/accounts/acc-1/unfreeze", change)
+    require.Equal(t, http.StatusConflict, resp.StatusCode)
+    resp = do(http.MethodPost, "/v1/ledger/credit", posting)
+    require.Equal(t, http.StatusConflict, resp.StatusCode)
+
+    resp = do(http.MethodGet, "/v1/accounts/acc-1/status-history", nil)
+    require.Equal(t, http.StatusOK, resp.StatusCode)
+    var history accountStatusHistoryResponse
+    require.NoError(t, json.NewDecoder(resp.Body).Decode(&history))
+    require.Len(t, history.Transitions, 3)
+    require.Equal(t, ledger.AccountStatusClosed, history.Transitions[2].ToStatus)
+    require.Equal(t, "ops", history.Transitions[2].ChangedBy)
+}
+
+func TestCreditLimits(t *testing.T) {
+    deps, tlsCfg, clientTLS, _ := newTestDeps(t)
+
+    h, err := NewRouter(deps)
+    require.NoError(t, err)
+
+    ts := httptest.NewUnstartedServer(h)
+    ts.TLS = tlsCfg
+    ts.StartTLS()
+    defer ts.Close()
+
+    client := &http.Client{Transport: &http.Transport{TLSClientConfig: clientTLS}}
+    token := issueToken(t, deps, "full-client", "full-secret", "accounts:read accounts:write ledger:write")
+
+    do := func(method, path string, body any) *http.Response {
+        var rdr io.Reader
+        if body != nil {
+            b, _ := json.Marshal(body)
+            rdr = bytes.NewReader(b)
+        }
+        req, _ := http.NewRequest(method, ts.URL+path, rdr)
+        req.Header.Set("Authorization", "Bearer "+token)
+        resp, err := client.Do(req)
+        require.NoError(t, err)
+        return resp
+    }
+
+    resp := do(http.MethodGet, "/v1/accounts/acc-1/credit-limit", nil)
+    require.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+    // reason is required and limits cannot be negative
+    resp = do(http.MethodPost, "/v1/accounts/acc-1/credit-limits", map[string]any{"overdraft_limit": "500.00", "currency_code": "USD", "set_by": "risk"})
+    require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+    resp = do(http.MethodPost, "/v1/accounts/acc-1/credit-limits", map[string]any{"overdraft_limit": "-500.00", "currency_code": "USD", "reason": "review", "set_by": "risk"})
+    require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+    resp = do(http.MethodPost, "/v1/accounts/acc-1/credit-limits", map[string]any{
+        "overdraft_limit":        "500.00",
+        "max_transaction_amount": "100.00",
+        "currency_code":          "USD",
+        "effective_from":         "2024-01-01T00:00:00Z",
+        "reason":                 "annual credit review",
+        "set_by":                 "risk",
+    })
+    require.Equal(t, http.StatusCreated, resp.StatusCode)
+    var out creditLimitResponse
+    require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+    require.Equal(t, "500.00", out.CreditLimit.OverdraftLimit.Amount())
+    require.Equal(t, "100.00", out.CreditLimit.MaxTransactionAmount.Amount())
+
+    resp = do(http.MethodPost, "/v1/accounts/acc-1/credit-limits", map[string]any{
+        "overdraft_limit": "0",
+        "currency_code":   "USD",
+        "reason":          "limit withdrawn",
+        "set_by":          "risk",
+    })
+    require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+    // the limit in force depends on when
+    resp = do(http.MethodGet, "/v1/accounts/acc-1/credit-limit?at=2024-06-01T00:00:00Z", nil)
+    require.Equal(t, http.StatusOK, resp.StatusCode)
+    require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+    require.Equal(t, "annual credit review", out.CreditLimit.Reason)
+
+    resp = do(http.MethodGet, "/v1/accounts/acc-1/credit-limit", nil)
+    require.Equal(t, http.StatusOK, resp.StatusCode)
+    var current creditLimitResponse
+    require.NoError(t, json.NewDecoder(resp.Body).Decode(&current))
+    require.Equal(t, "limit withdrawn", current.CreditLimit.Reason)
+    require.Nil(t, current.CreditLimit.MaxTransactionAmount)
+
+    resp = do(http.MethodGet, "/v1/accounts/acc-1/credit-limits", nil)
+    require.Equal(t, http.StatusOK, resp.StatusCode)
+    var history creditLimitsResponse
+    require.NoError(t, json.NewDecoder(resp.Body).Decode(&history))
+    require.Len(t, history.Limits, 2)
+
+    resp = do(http.MethodPost, "/v1/accounts/acc-9/credit-limits", map[string]any{"overdraft_limit": "1.00", "currency_code": "USD", "reason": "review", "set_by": "risk"})
+    require.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestTransactionLimitExceeded(t *testing.T) {
+    deps, tlsCfg, clientTLS, _ := newTestDeps(t)
+    fake := deps.LedgerWriter.(*fakeLedger)
+    maxAmount := money.MustParse("10.00", "USD")
+    fake.creditLimits = []ledger.CreditLimit{{AccountID: "acc-1", OverdraftLimit: money.MustParse("0.00", "USD"), MaxTransactionAmount: &maxAmount, EffectiveFrom: "2024-01-01T00:00:00Z"}}
+
+    h, err := NewRouter(deps)
+    require.NoError(t, err)
+
+    ts := httptest.NewUnstartedServer(h)
+    ts.TLS = tlsCfg
+    ts.StartTLS()
+    defer ts.Close()
+
+    client := &http.Client{Transport: &http.Transport{TLSClientConfig: clientTLS}}
+    token := issueToken(t, deps, "full-client", "full-secret", "ledger:write")
+
+    body, _ := json.Marshal(map[string]any{"account_id": "acc-1", "amount": "10.01", "currency_code": "USD", "created_by": "u1"})
+    req, _ := http.NewRequest(http.MethodPost, ts.URL+"/v1/ledger/debit", bytes.NewReader(body))
+    req.Header.Set("Authorization", "Bearer "+token)
+    resp, err := client.Do(req)
+    require.NoError(t, err)
+    require.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+    var errResp security.ErrorResponse
+    require.NoError(t, json.NewDecoder(resp.Body).Decode(&errResp))
+    require.Equal(t, "transaction_limit_exceeded", errResp.Error)
+}
+
+func TestAccountTree(t *testing.T) {
+    deps, tlsCfg, clientTLS, _ := newTestDeps(t)
+
+    h, err := NewRouter(deps)
+    require.NoError(t, err)
+
+    ts := httptest.NewUnstartedServer(h)
+    ts.TLS = tlsCfg
+    ts.StartTLS()
+    defer ts.Close()
+
+    client := &http.Client{Transport: &http.Transport{TLSClientConfig: clientTLS}}
+    token := issueToken(t, deps, "full-client", "full-secret", "accounts:read accounts:write")
+
+    do := func(method, path string, body any) *http.Response {
+        var rdr io.Reader
+        if body != nil {
+            b, _ := json.Marshal(body)
+            rdr = bytes.NewReader(b)
+        }
+        req, _ := http.NewRequest(method, ts.URL+path, rdr)
+        req.Header.Set("Authorization", "Bearer "+token)
+        resp, err := client.Do(req)
+        require.NoError(t, err)
+        return resp
+    }
+    account := map[string]any{"parent_id": "acc-1", "account_number": "A1-PENDING", "account_type": "asset", "name": "Pending", "currency_code": "USD", "created_by": "u1"}
+
+    resp := do(http.MethodPost, "/v1/accounts", account)
+    require.Equal(t, http.StatusCreated, resp.StatusCode)
+    var created struct {
+        Account struct {
+            ParentID string `json:"parent_id"`
+        } `json:"account"`
+    }
+    require.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+    require.Equal(t, "acc-1", created.Account.ParentID)
+
+    account["parent_id"] = "missing"
+    resp = do(http.MethodPost, "/v1/accounts", account)
+    require.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+    resp = do(http.MethodGet, "/v1/accounts/acc-1/tree", nil)
+    require.Equal(t, http.StatusOK, resp.StatusCode)
+    var out struct {
+        Tree struct {
+            ID            string      `json:"id"`
+            RollupBalance money.Money `json:"rollup_balance"`
+            Children      []struct {
+                ID       string `json:"id"`
+                ParentID string `json:"parent_id"`
+                Depth    int    `json:"depth"`
+            } `json:"children"`
+        } `json:"tree"`
+    }
+    require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+    require.Equal(t, "acc-1", out.Tree.ID)
+    require.Equal(t, "10.00", out.Tree.RollupBalance.Amount())
+    require.Len(t, out.Tree.Children, 1)
+    require.Equal(t, "acc-1", out.Tree.Children[0].ParentID)
+    require.Equal(t, 1, out.Tree.Children[0].Depth)
+
+    resp = do(http.MethodGet, "/v1/accounts/missing/tree", nil)
+    require.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+    // the tree is read with accounts:read, not ledger:read
+    token = issueToken(t, deps, "full-client", "full-secret", "ledger:read")
+    resp = do(http.MethodGet, "/v1/accounts/acc-1/tree", nil)
+    require.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
+
+func TestReports(t *testing.T) {
+    deps, tlsCfg, clientTLS, _ := newTestDeps(t)
+    reports := deps.Reports.(*fakeReports)
+
+    h, err := NewRouter(deps)
+    require.NoError(t, err)
+
+    ts := httptest.NewUnstartedServer(h)
+    ts.TLS = tlsCfg
+    ts.StartTLS()
+    defer ts.Close()
+
+    client := &http.Client{Transport: &http.Transport{TLSClientConfig: clientTLS}}
+    token := issueToken(t, deps, "full-client", "full-secret", "ledger:read")
+
+    get := func(path string) *http.Response {
+        req, _ := http.NewRequest(http.MethodGet, ts.URL+path, nil)
+        req.Header.Set("Authorization", "Bearer "+token)
+        resp, err := client.Do(req)
+        require.NoError(t, err)
+        return resp
+    }
+    period := "currency_code=USD&from=2024-01-01T00:00:00Z&to=2024-02-01T00:00:00Z"
+
+    resp := get("/v1/reports/trial-balance?" + period)
+    require.Equal(t, http.StatusOK, resp.StatusCode)
+    var tb trialBalanceResponse
+    require.NoError(t, json.NewDecoder(resp.Body).Decode(&tb))
+    require.True(t, tb.Check.Balanced)
+    require.Len(t, tb.Lines, 1)
+    require.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), reports.requests[0].From)
+    require.Equal(t, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), reports.requests[0].To)
+
+    resp = get("/v1/reports/trial-balance?format=csv&" + period)
+    require.Equal(t, http.StatusOK, resp.StatusCode)
+    require.Equal(t, "text/csv; charset=utf-8", resp.Header.Get("Content-Type"))
+    body, _ := io.ReadAll(resp.Body)
+    require.Contains(t, string(body), "1000,Cash,asset,5.00,0.00,5.00,0.00\n")
+    require.Contains(t, string(body), "check,balanced,true,0.00\n")
+
+    resp = get("/v1/reports/balance-sheet?currency_code=USD&at=2024-02-01T00:00:00Z")
+    require.Equal(t, http.StatusOK, resp.StatusCode)
+    require.Equal(t, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), reports.requests[len(reports.requests)-1].To)
+
+    resp = get("/v1/reports/income-statement?format=csv&" + period)
+    require.Equal(t, http.StatusOK, resp.StatusCode)
+    body, _ = io.ReadAll(resp.Body)
+    require.Contains(t, string(body), ",,Net income,0.00\n")
+
+    // the period, format and currency are validated
+    resp = get("/v1/reports/income-statement?currency_code=USD&from=2024-01-01")
+    require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+    resp = get("/v1/reports/balance-sheet?currency_code=USD")
+    require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+    resp = get("/v1/reports/trial-balance?format=xlsx&" + period)
+    require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+    resp = get("/v1/reports/trial-balance?currency_code=EUR&from=2024-01-01T00:00:00Z&to=2024-02-01T00:00:00Z")
+    require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+    // reports need ledger:read
+    token = issueToken(t, deps, "read-client", "read-secret", "accounts:read")
+    resp = get("/v1/reports/trial-balance?" + period)
+    require.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
+
+func TestPeriods(t *testing.T) {
+    deps, tlsCfg, clientTLS, _ := newTestDeps(t)
+
+    h, err := NewRouter(deps)
+    require.NoError(t, err)
+
+    ts := httptest.NewUnstartedServer(h)
+    ts.TLS = tlsCfg
+    ts.StartTLS()
+    defer ts.Close()
+
+    client := &http.Client{Transport: &http.Transport{TLSClientConfig: clientTLS}}
+    token := issueToken(t, deps, "close-client", "close-secret", "accounts:write ledger:read ledger:close")
+
+    do := func(method, path string, body map[string]any) (*http.Response, periodResponse) {
+        var r io.Reader
+        if body != nil {
+            b, _ := json.Marshal(body)
+            r = bytes.NewReader(b)
+        }
+        req, _ := http.NewRequest(method, ts.URL+path, r)
+        req.Header.Set("Authorization", "Bearer "+token)
+        resp, err := client.Do(req)
+        require.NoError(t, err)
+        defer resp.Body.Close()
+        var out periodResponse
+        _ = json.NewDecoder(resp.Body).Decode(&out)
+        return resp, out
+    }
+
+    resp, created := do(http.MethodPost, "/v1/periods", map[string]any{"name": "2024-01", "starts_on": "2024-01-01", "ends_on": "2024-01-31", "created_by": "tester"})
+    require.Equal(t, http.StatusCreated, resp.StatusCode)
+    require.Equal(t, ledger.PeriodStatusOpen, created.Period.Status)
+    id := created.Period.ID
+
+    // bounds must be dates
+    resp, _ = do(http.MethodPost, "/v1/periods", map[string]any{"name": "2024-02", "starts_on": "2024-02-01T00:00:00Z", "ends_on": "2024-02-29", "created_by": "tester"})
+    require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+    // closing needs a retained earnings account
+    closeReq := map[string]any{"closed_by": "tester"}
+    resp, _ = do(http.MethodPost, "/v1/periods/"+id+"/soft-close", closeReq)
+    require.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+
+    resp, _ = do(http.MethodPut, "/v1/periods/retained-earnings/USD", map[string]any{"account_id": "acc-re", "updated_by": "tester"})
+    require.Equal(t, http.StatusOK, resp.StatusCode)
+
+    resp, closed := do(http.MethodPost, "/v1/periods/"+id+"/soft-close", closeReq)
+    require.Equal(t, http.StatusOK, resp.StatusCode)
+    require.Equal(t, ledger.PeriodStatusSoftClosed, closed.Period.Status)
+
+    resp, _ = do(http.MethodPost, "/v1/periods/"+id+"/soft-close", closeReq)
+    require.Equal(t, http.StatusConflict, resp.StatusCode)
+
+    resp, closed = do(http.MethodPost, "/v1/periods/"+id+"/hard-close", closeReq)
+    require.Equal(t, http.StatusOK, resp.StatusCode)
+    require.Equal(t, ledger.PeriodStatusHardClosed, closed.Period.Status)
+
+    // hard closed is final
+    resp, _ = do(http.MethodPost, "/v1/periods/"+id+"/hard-close", closeReq)
+    require.Equal(t, http.StatusConflict, resp.StatusCode)
+
+    resp, got := do(http.MethodGet, "/v1/periods/"+id, nil)
+    require.Equal(t, http.StatusOK, resp.StatusCode)
+    require.Equal(t, ledger.PeriodStatusHardClosed, got.Period.Status)
+
+    resp, _ = do(http.MethodGet, "/v1/periods/missing", nil)
+    require.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+    // closing periods needs ledger:close
+    token = issueToken(t, deps, "full-client", "full-secret", "ledger:read ledger:write")
+    resp, _ = do(http.MethodPost, "/v1/periods", map[string]any{"name": "2024-03", "starts_on": "2024-03-01", "ends_on": "2024-03-31", "created_by": "tester"})
+    require.Equal(t, http.StatusForbidden, resp.StatusCode)
+    resp, _ = do(http.MethodGet, "/v1/periods/"+id, nil)
+    require.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestReconciliation(t *testing.T) {
+    deps, tlsCfg, clientTLS, _ := newTestDeps(t)
+
+    h, err := NewRouter(deps)
+    require.NoError(t, err)
+
+    ts := httptest.NewUnstartedServer(h)
+    ts.TLS = tlsCfg
+    ts.StartTLS()
+    defer ts.Close()
+
+    client := &http.Client{Transport: &http.Transport{TLSClientConfig: clientTLS}}
+    token := issueToken(t, deps, "recon-client", "recon-secret", "ledger:read ledger:reconcile")
+
+    do := func(method, path string, body map[string]any, out any) *http.Response {
+        var r io.Reader
+        if body != nil {
+            b, _ := json.Marshal(body)
+            r = bytes.NewReader(b)
+        }
+        req, _ := http.NewRequest(method, ts.URL+path, r)
+        req.Header.Set("Authorization", "Bearer "+token)
+        resp, err := client.Do(req)
+        require.NoError(t, err)
+        defer resp.Body.Close()
+        if out != nil {
+            _ = json.NewDecoder(resp.Body).Decode(out)
+        }
+        return resp
+    }
+
+    statement := map[string]any{"account_id": "acc-bank", "format": "csv", "content": "date,amount\n2024-01-02,5.00\n", "imported_by": "tester"}
+    var imported importStatementResponse
+    resp := do(http.MethodPost, "/v1/reconciliation/statements", statement, &imported)
+    require.Equal(t, http.StatusCreated, resp.StatusCode)
+    require.Equal(t, "st-1", imported.Statement.ID)
+
+    statement["reference"] = "dup"
+    resp = do(http.MethodPost, "/v1/reconciliation/statements", statement, nil)
+    require.Equal(t, http.StatusConflict, resp.StatusCode)
+
+    statement["format"] = "ofx"
+    resp = do(http.MethodPost, "/v1/reconciliation/statements", statement, nil)
+    require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+    var result reconciliationResponse
+    resp = do(http.MethodPost, "/v1/reconciliation/statements/st-1/match", nil, &result)
+    require.Equal(t, http.StatusOK, resp.StatusCode)
+    require.Len(t, result.Suggested, 1)
 
-func (f *fakeLedger) GetBalance(ctx context.Context, accountID string) (float64, error) {
-    return 123.45, nil
-}
+    resp = do(http.MethodPost, "/v1/reconciliation/statements/missing/match", nil, nil)
+    require.Equal(t, http.StatusNotFound, resp.StatusCode)
 
-func (f *fakeLedger) CreateAccount(ctx context.Context, req ledger.CreateAccountRequest) (*ledger.Account, error) {
-    f.createCalls++
-    return &ledger.Account{ID: "acc-1", AccountNumber: req.AccountNumber, AccountType: req.AccountType, Name: req.Name, CurrencyCode: req.CurrencyCode, CreatedBy: req.CreatedBy}, nil
-}
+    decision := map[string]any{"decided_by": "tester"}
+    var decided matchResponse
+    resp = do(http.MethodPost, "/v1/reconciliation/matches/m-1/confirm", decision, &decided)
+    require.Equal(t, http.StatusOK, resp.StatusCode)
+    require.Equal(t, reconciliation.MatchConfirmed, decided.Match.Status)
 
-func (f *fakeLedger) Debit(ctx context.Context, req ledger.DebitRequest) error {
-    f.debitCalls++
-    return nil
-}
+    // a decision is final
+    resp = do(http.MethodPost, "/v1/reconciliation/matches/m-1/reject", decision, nil)
+    require.Equal(t, http.StatusConflict, resp.StatusCode)
 
-func (f *fakeLedger) Credit(ctx context.Context, req ledger.CreditRequest) error {
-    f.creditCalls++
-    return nil
-}
+    resp = do(http.MethodPost, "/v1/reconciliation/matches/missing/confirm", decision, nil)
+    require.Equal(t, http.StatusNotFound, resp.StatusCode)
 
-type auditSpy struct{ calls int }
+    result = reconciliationResponse{}
+    resp = do(http.MethodGet, "/v1/reconciliation/statements/st-1", nil, &result)
+    require.Equal(t, http.StatusOK, resp.StatusCode)
+    require.Len(t, result.Matched, 1)
+    require.Empty(t, result.Suggested)
 
-func (a *auditSpy) Append(payload string) *audit.LogEntry {
-    a.calls++
-    return &audit.LogEntry{Hash: payload}
+    // importing and matching needs ledger:reconcile
+    token = issueToken(t, deps, "full-client", "full-secret", "ledger:read ledger:write")
+    resp = do(http.MethodPost, "/v1/reconciliation/statements/st-1/match", nil, nil)
+    require.Equal(t, http.StatusForbidden, resp.StatusCode)
+    resp = do(http.MethodGet, "/v1/reconciliation/statements/st-1", nil, nil)
+    require.Equal(t, http.StatusOK, resp.StatusCode)
 }
 
-func TestMTLSRequired(t *testing.T) {
-    deps, tlsCfg, clientTLS, noClientTLS := newTestDeps(t)
+func TestWebhooks(t *testing.T) {
+    deps, tlsCfg, clientTLS, _ := newTestDeps(t)
 
     h, err := NewRouter(deps)
     require.NoError(t, err)
@@ -87,18 +2254,118 @@ func TestMTLSRequired(t *testing.T) {
     ts.StartTLS()
     defer ts.Close()
 
-    clientNoCert := &http.Client{Transport: &http.Transport{TLSClientConfig: noClientTLS}}
-    _, err = clientNoCert.Get(ts.URL + "/healthz")
-    require.Error(t, err)
+    client := &http.Client{Transport: &http.Transport{TLSClientConfig: clientTLS}}
+    token := issueToken(t, deps, "webhook-client", "webhook-secret", "webhooks:read webhooks:write")
+
+    do := func(method, path string, body map[string]any, out any) *http.Response {
+        var r io.Reader
+        if body != nil {
+            b, _ := json.Marshal(body)
+            r = bytes.NewReader(b)
+        }
+        req, _ := http.NewRequest(method, ts.URL+path, r)
+        req.Header.Set("Authorization", "Bearer "+token)
+        resp, err := client.Do(req)
+        require.NoError(t, err)
+        defer resp.Body.Close()
+        if out != nil {
+            _ = json.NewDecoder(resp.Body).Decode(out)
+        }
+        return resp
+    }
 
-    clientWithCert := &http.Client{Transport: &http.Transport{TLSClientConfig: clientTLS}}
-    resp, err := clientWithCert.Get(ts.URL + "/healthz")
-    require.NoError(t, err)
+    subscription := map[string]any{
+        "url":            "https://merchant.example/hooks",
+        "event_types":    []string{"dispute.transitioned"},
+        "dispute_states": []string{"AUTHORIZED", "DISPUTED", "REVERSED"},
+    }
+    var created webhookResponse
+    resp := do(http.MethodPost, "/v1/webhooks", subscription, &created)
+    require.Equal(t, http.StatusCreated, resp.StatusCode)
+    require.Equal(t, "webhook-client", created.Subscription.Owner)
+    require.NotEmpty(t, created.Subscription.Secret)
+    id := created.Subscription.ID
+
+    // endpoints must use https and filters must name known values
+    resp = do(http.MethodPost, "/v1/webhooks", map[string]any{"url": "http://merchant.example/hooks"}, nil)
+    require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+    resp = do(http.MethodPost, "/v1/webhooks", map[string]any{"url": "https://merchant.example/hooks", "dispute_states": []string{"LOST"}}, nil)
+    require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+    var listed listWebhooksResponse
+    resp = do(http.MethodGet, "/v1/webhooks", nil, &listed)
+    require.Equal(t, http.StatusOK, resp.StatusCode)
+    require.Len(t, listed.Subscriptions, 1)
+    require.Empty(t, listed.Subscriptions[0].Secret)
+
+    update := map[string]any{"url": "https://merchant.example/v2/hooks", "active": false}
+    var updated webhookResponse
+    resp = do(http.MethodPut, "/v1/webhooks/"+id, update, &updated)
+    require.Equal(t, http.StatusOK, resp.StatusCode)
+    require.False(t, updated.Subscription.Active)
+    require.Empty(t, updated.Subscription.EventTypes)
+
+    var rotated webhookResponse
+    resp = do(http.MethodPost, "/v1/webhooks/"+id+"/rotate-secret", nil, &rotated)
+    require.Equal(t, http.StatusOK, resp.StatusCode)
+    require.NotEqual(t, created.Subscription.Secret, rotated.Subscription.Secret)
+
+    fw := deps.Webhooks.(*fakeWebhooks)
+    fw.deliveries = map[string]*webhooks.Delivery{
+        "del-1": {ID: "del-1", SubscriptionID: id, EventID: "ev-1", Status: webhooks.DeliveryDeadLetter, Attempts: 8},
+        "del-2": {ID: "del-2", SubscriptionID: id, EventID: "ev-2", Status: webhooks.DeliveryPending},
+    }
+
+    var deliveries listWebhookDeliveriesResponse
+    resp = do(http.MethodGet, "/v1/webhooks/"+id+"/deliveries?status=dead_letter", nil, &deliveries)
+    require.Equal(t, http.StatusOK, resp.StatusCode)
+    require.Len(t, deliveries.Deliveries, 1)
+    require.Equal(t, "del-1", deliveries.Deliveries[0].ID)
+
+    var delivery webhookDeliveryResponse
+    resp = do(http.MethodGet, "/v1/webhooks/deliveries/del-1", nil, &delivery)
     require.Equal(t, http.StatusOK, resp.StatusCode)
+    require.Equal(t, webhooks.DeliveryDeadLetter, delivery.Delivery.Status)
+
+    resp = do(http.MethodPost, "/v1/webhooks/deliveries/del-1/replay", nil, &delivery)
+    require.Equal(t, http.StatusAccepted, resp.StatusCode)
+    require.Equal(t, webhooks.DeliveryPending, delivery.Delivery.Status)
+    require.Zero(t, delivery.Delivery.Attempts)
+
+    // a pending delivery is already queued
+    resp = do(http.MethodPost, "/v1/webhooks/deliveries/del-2/replay", nil, nil)
+    require.Equal(t, http.StatusConflict, resp.StatusCode)
+
+    resp = do(http.MethodGet, "/v1/webhooks/deliveries/missing", nil, nil)
+    require.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+    // subscriptions are private to the client that created them
+    token = issueToken(t, deps, "other-webhook-client", "other-webhook-secret", "webhooks:read webhooks:write")
+    resp = do(http.MethodGet, "/v1/webhooks/"+id, nil, nil)
+    require.Equal(t, http.StatusNotFound, resp.StatusCode)
+    resp = do(http.MethodGet, "/v1/webhooks/deliveries/del-1", nil, nil)
+    require.Equal(t, http.StatusNotFound, resp.StatusCode)
+    resp = do(http.MethodDelete, "/v1/webhooks/"+id, nil, nil)
+    require.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+    // managing subscriptions needs webhooks:write
+    token = issueToken(t, deps, "webhook-client", "webhook-secret", "webhooks:read")
+    resp = do(http.MethodDelete, "/v1/webhooks/"+id, nil, nil)
+    require.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+    token = issueToken(t, deps, "webhook-client", "webhook-secret", "webhooks:read webhooks:write")
+    resp = do(http.MethodDelete, "/v1/webhooks/"+id, nil, nil)
+    require.Equal(t, http.StatusNoContent, resp.StatusCode)
+    resp = do(http.MethodGet, "/v1/webhooks/"+id, nil, nil)
+    require.Equal(t, http.StatusNotFound, resp.StatusCode)
 }
 
-func TestAuthFailuresAndValidation(t *testing.T) {
+func TestPayouts(t *testing.T) {
     deps, tlsCfg, clientTLS, _ := newTestDeps(t)
+    deps.Payouts.(*fakePayouts).payable = map[string]money.Money{
+        "merchant-1": money.MustParse("250.00", "USD"),
+        "merchant-2": money.MustParse("5.00", "USD"),
+    }
 
     h, err := NewRouter(deps)
     require.NoError(t, err)
@@ -109,42 +2376,229 @@ func TestAuthFailuresAndValidation(t *testing.T) {
     defer ts.Close()
 
     client := &http.Client{Transport: &http.Transport{TLSClientConfig: clientTLS}}
+    token := issueToken(t, deps, "payout-client", "payout-secret", "payouts:read payouts:write")
+
+    do := func(method, path string, body map[string]any, out any) *http.Response {
+        var r io.Reader
+        if body != nil {
+            b, _ := json.Marshal(body)
+            r = bytes.NewReader(b)
+        }
+        req, _ := http.NewRequest(method, ts.URL+path, r)
+        req.Header.Set("Authorization", "Bearer "+token)
+        resp, err := client.Do(req)
+        require.NoError(t, err)
+        defer resp.Body.Close()
+        if out != nil {
+            _ = json.NewDecoder(resp.Body).Decode(out)
+        }
+        return resp
+    }
 
-    resp, err := client.Get(ts.URL + "/v1/accounts")
-    require.NoError(t, err)
-    require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+    resp := do(http.MethodGet, "/v1/payouts/settings/merchant-1", nil, nil)
+    require.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+    settings := map[string]any{
+        "payable_account_id": "acc-payable",
+        "funding_account_id": "acc-bank",
+        "schedule":           "weekly",
+        "weekday":            1,
+        "minimum_amount":     "25.00",
+        "currency_code":      "USD",
+        "active":             true,
+        "updated_by":         "ops",
+    }
+    var set payoutSettingsResponse
+    resp = do(http.MethodPut, "/v1/payouts/settings/merchant-1", settings, &set)
+    require.Equal(t, http.StatusOK, resp.StatusCode)
+    require.Equal(t, "merchant-1", set.Settings.MerchantID)
+    require.Equal(t, 1, *set.Settings.Weekday)
+    require.Equal(t, "25.00", set.Settings.MinimumAmount.Amount())
 
-    token := issueToken(t, deps, "read-client", "read-secret", "accounts:read")
+    // schedules and weekdays are checked
+    settings["schedule"] = "monthly"
+    resp = do(http.MethodPut, "/v1/payouts/settings/merchant-1", settings, nil)
+    require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+    settings["schedule"], settings["weekday"] = "weekly", 7
+    resp = do(http.MethodPut, "/v1/payouts/settings/merchant-1", settings, nil)
+    require.Equal(t, http.StatusBadRequest, resp.StatusCode)
 
-    reqBody := map[string]any{
-        "account_type":  "asset",
-        "name":          "Test",
-        "currency_code": "USD",
-        "created_by":    "tester",
-    }
-    b, _ := json.Marshal(reqBody)
+    var payable payableResponse
+    resp = do(http.MethodGet, "/v1/payouts/settings/merchant-1/payable", nil, &payable)
+    require.Equal(t, http.StatusOK, resp.StatusCode)
+    require.Equal(t, "250.00", payable.Payable.Payable.Amount())
+    require.True(t, payable.Payable.Eligible)
 
-    req, _ := http.NewRequest(http.MethodPost, ts.URL+"/v1/accounts", bytes.NewReader(b))
-    req.Header.Set("Authorization", "Bearer "+token)
-    resp, err = client.Do(req)
-    require.NoError(t, err)
+    var created payoutResponse
+    resp = do(http.MethodPost, "/v1/payouts", map[string]any{"merchant_id": "merchant-1", "created_by": "ops"}, &created)
+    require.Equal(t, http.StatusCreated, resp.StatusCode)
+    require.Equal(t, payouts.StatusPending, created.Payout.Status)
+    require.Equal(t, "250.00", created.Payout.Amount.Amount())
+    id := created.Payout.ID
+
+    settings["schedule"], settings["minimum_amount"] = "on_demand", "10.00"
+    delete(settings, "weekday")
+    resp = do(http.MethodPut, "/v1/payouts/settings/merchant-2", settings, nil)
+    require.Equal(t, http.StatusOK, resp.StatusCode)
+    resp = do(http.MethodPost, "/v1/payouts", map[string]any{"merchant_id": "merchant-2", "created_by": "ops"}, nil)
+    require.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+
+    // a payout is paid only once sent
+    transition := map[string]any{"bank_reference": "ACH-0001", "changed_by": "ops"}
+    resp = do(http.MethodPost, "/v1/payouts/"+id+"/paid", transition, nil)
+    require.Equal(t, http.StatusConflict, resp.StatusCode)
+
+    var moved payoutResponse
+    resp = do(http.MethodPost, "/v1/payouts/"+id+"/sent", transition, &moved)
+    require.Equal(t, http.StatusOK, resp.StatusCode)
+    require.Equal(t, payouts.StatusSent, moved.Payout.Status)
+    require.Equal(t, "ACH-0001", moved.Payout.BankReference)
+
+    resp = do(http.MethodPost, "/v1/payouts/"+id+"/paid", map[string]any{"changed_by": "ops"}, &moved)
+    require.Equal(t, http.StatusOK, resp.StatusCode)
+    require.Equal(t, payouts.StatusPaid, moved.Payout.Status)
+
+    // a returned payout needs a reason and is reversed
+    resp = do(http.MethodPost, "/v1/payouts/"+id+"/fail", map[string]any{"changed_by": "ops"}, nil)
+    require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+    resp = do(http.MethodPost, "/v1/payouts/"+id+"/fail", map[string]any{"reason": "R03 no account", "changed_by": "ops"}, &moved)
+    require.Equal(t, http.StatusOK, resp.StatusCode)
+    require.Equal(t, payouts.StatusFailed, moved.Payout.Status)
+    require.NotEmpty(t, moved.Payout.ReversalID)
+
+    var listed listPayoutsResponse
+    resp = do(http.MethodGet, "/v1/payouts?merchant_id=merchant-1&status=failed", nil, &listed)
+    require.Equal(t, http.StatusOK, resp.StatusCode)
+    require.Len(t, listed.Payouts, 1)
+
+    resp = do(http.MethodGet, "/v1/payouts/missing", nil, nil)
+    require.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+    // making and moving payouts needs payouts:write
+    token = issueToken(t, deps, "payout-client", "payout-secret", "payouts:read")
+    resp = do(http.MethodPost, "/v1/payouts", map[string]any{"merchant_id": "merchant-1", "created_by": "ops"}, nil)
     require.Equal(t, http.StatusForbidden, resp.StatusCode)
+    resp = do(http.MethodGet, "/v1/payouts/"+id, nil, nil)
+    require.Equal(t, http.StatusOK, resp.StatusCode)
+}
 
-    // validation error before ledger call
-    token = issueToken(t, deps, "write-client", "write-secret", "accounts:write")
-    req, _ = http.NewRequest(http.MethodPost, ts.URL+"/v1/accounts", bytes.NewReader(b))
-    req.Header.Set("Authorization", "Bearer "+token)
-    resp, err = client.Do(req)
+func TestFees(t *testing.T) {
+    deps, tlsCfg, clientTLS, _ := newTestDeps(t)
+
+    h, err := NewRouter(deps)
     require.NoError(t, err)
+
+    ts := httptest.NewUnstartedServer(h)
+    ts.TLS = tlsCfg
+    ts.StartTLS()
+    defer ts.Close()
+
+    client := &http.Client{Transport: &http.Transport{TLSClientConfig: clientTLS}}
+    token := issueToken(t, deps, "fee-client", "fee-secret", "fees:read fees:write")
+
+    do := func(method, path string, body map[string]any, out any) *http.Response {
+        var r io.Reader
+        if body != nil {
+            b, _ := json.Marshal(body)
+            r = bytes.NewReader(b)
+        }
+        req, _ := http.NewRequest(method, ts.URL+path, r)
+        req.Header.Set("Authorization", "Bearer "+token)
+        resp, err := client.Do(req)
+        require.NoError(t, err)
+        defer resp.Body.Close()
+        if out != nil {
+            _ = json.NewDecoder(resp.Body).Decode(out)
+        }
+        return resp
+    }
+
+    resp := do(http.MethodGet, "/v1/fees/quote?fee_type=chargeback&amount=100.00&currency_code=USD&card_brand=VISA", nil, nil)
+    require.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+    schedule := map[string]any{
+        "fee_type":           "chargeback",
+        "card_brand":         "VISA",
+        "currency_code":      "USD",
+        "rate":               "0.02",
+        "minimum_fee":        "5.00",
+        "maximum_fee":        "15.00",
+        "revenue_account_id": "acc-revenue",
+        "effective_from":     "2024-01-01T00:00:00Z",
+        "created_by":         "ops",
+    }
+    var created feeScheduleResponse
+    resp = do(http.MethodPost, "/v1/fees/schedules", schedule, &created)
+    require.Equal(t, http.StatusCreated, resp.StatusCode)
+    require.Equal(t, "0.00", created.Schedule.FixedAmount.Amount())
+    require.Equal(t, money.BasisPoints(200), created.Schedule.Rate)
+    require.Equal(t, "15.00", created.Schedule.MaximumFee.Amount())
+
+    resp = do(http.MethodPost, "/v1/fees/schedules", map[string]any{
+        "fee_type":           "chargeback",
+        "currency_code":      "USD",
+        "fixed_amount":       "10.00",
+        "revenue_account_id": "acc-revenue",
+        "created_by":         "ops",
+    }, nil)
+    require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+    // fee types, brands and bounds are checked
+    schedule["fee_type"] = "refund"
+    resp = do(http.MethodPost, "/v1/fees/schedules", schedule, nil)
     require.Equal(t, http.StatusBadRequest, resp.StatusCode)
-    fl := deps.LedgerWriter.(*fakeLedger)
-    require.Equal(t, 0, fl.createCalls)
+    schedule["fee_type"], schedule["card_brand"] = "chargeback", "UNIONPAY"
+    resp = do(http.MethodPost, "/v1/fees/schedules", schedule, nil)
+    require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+    schedule["card_brand"], schedule["minimum_fee"] = "VISA", "20.00"
+    resp = do(http.MethodPost, "/v1/fees/schedules", schedule, nil)
+    require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+    var quoted feeResponse
+    resp = do(http.MethodGet, "/v1/fees/quote?fee_type=chargeback&amount=500.00&currency_code=USD&card_brand=VISA", nil, &quoted)
+    require.Equal(t, http.StatusOK, resp.StatusCode)
+    require.Equal(t, "10.00", quoted.Fee.Amount.Amount())
+    require.Equal(t, created.Schedule.ID, quoted.Fee.ScheduleID)
+    resp = do(http.MethodGet, "/v1/fees/quote?fee_type=chargeback&amount=1000.00&currency_code=USD&card_brand=MASTERCARD", nil, &quoted)
+    require.Equal(t, http.StatusOK, resp.StatusCode)
+    require.Equal(t, "10.00", quoted.Fee.Amount.Amount())
+    resp = do(http.MethodGet, "/v1/fees/quote?fee_type=chargeback&amount=lots&currency_code=USD", nil, nil)
+    require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+    var charged feeResponse
+    resp = do(http.MethodPost, "/v1/fees/charges", map[string]any{
+        "fee_type":         "chargeback",
+        "card_brand":       "VISA",
+        "amount":           "1000.00",
+        "currency_code":    "USD",
+        "payer_account_id": "acc-merchant",
+        "reference_id":     "DSP-1",
+        "created_by":       "ops",
+    }, &charged)
+    require.Equal(t, http.StatusCreated, resp.StatusCode)
+    require.Equal(t, "15.00", charged.Fee.Amount.Amount())
+    require.NotEmpty(t, charged.Fee.TransactionID)
+    require.Equal(t, "acc-merchant", deps.Fees.(*fakeFees).charges[0].PayerAccountID)
+
+    var listed listFeeSchedulesResponse
+    resp = do(http.MethodGet, "/v1/fees/schedules?fee_type=chargeback", nil, &listed)
+    require.Equal(t, http.StatusOK, resp.StatusCode)
+    require.Len(t, listed.Schedules, 2)
+    resp = do(http.MethodGet, "/v1/fees/schedules/"+created.Schedule.ID, nil, nil)
+    require.Equal(t, http.StatusOK, resp.StatusCode)
+    resp = do(http.MethodGet, "/v1/fees/schedules/missing", nil, nil)
+    require.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+    // schedules and charges need fees:write
+    token = issueToken(t, deps, "fee-client", "fee-secret", "fees:read")
+    resp = do(http.MethodPost, "/v1/fees/schedules", schedule, nil)
+    require.Equal(t, http.StatusForbidden, resp.StatusCode)
+    resp = do(http.MethodGet, "/v1/fees/schedules", nil, nil)
+    require.Equal(t, http.StatusOK, resp.StatusCode)
 }
 
-func TestRateLimitTrips(t *testing.T) {
+func TestDisputeEvidence(t *testing.T) {
     deps, tlsCfg, clientTLS, _ := newTestDeps(t)
-    deps.RateLimiter.Capacity = 1
-    deps.RateLimiter.RefillRate = 0.0000001
 
     h, err := NewRouter(deps)
     require.NoError(t, err)
@@ -155,19 +2609,91 @@ func TestRateLimitTrips(t *testing.T) {
     defer ts.Close()
 
     client := &http.Client{Transport: &http.Transport{TLSClientConfig: clientTLS}}
+    token := issueToken(t, deps, "dispute-client", "dispute-secret", "disputes:read disputes:write")
+
+    do := func(method, path string, body map[string]any, out any) *http.Response {
+        var r io.Reader
+        if body != nil {
+            b, _ := json.Marshal(body)
+            r = bytes.NewReader(b)
+        }
+        req, _ := http.NewRequest(method, ts.URL+path, r)
+        req.Header.Set("Authorization", "Bearer "+token)
+        resp, err := client.Do(req)
+        require.NoError(t, err)
+        defer resp.Body.Close()
+        if out != nil {
+            _ = json.NewDecoder(resp.Body).Decode(out)
+        }
+        return resp
+    }
 
-    resp, err := client.Get(ts.URL + "/oauth/jwks.json")
-    require.NoError(t, err)
+    upload := map[string]any{
+        "evidence_type": "receipt",
+        "description":   "Signed delivery receipt",
+        "file_name":     "receipt.pdf",
+        "mime_type":     "application/pdf",
+        "content":       "JVBERi0xLjQK",
+        "uploaded_by":   "merchant-ops",
+        "metadata":      map[string]any{"email": "jane@example.com", "carrier": "UPS"},
+    }
+    var created evidenceResponse
+    resp := do(http.MethodPost, "/v1/disputes/DSP-1/evidence", upload, &created)
+    require.Equal(t, http.StatusCreated, resp.StatusCode)
+    require.Equal(t, "ev-1", created.Evidence.ID)
+    require.Equal(t, int64(9), created.Evidence.SizeBytes)
+    require.Equal(t, "j***@example.com", created.Evidence.Metadata["email"])
+    require.Equal(t, "UPS", created.Evidence.Metadata["carrier"])
+
+    // types, encodings, sizes and disputes are checked
+    upload["evidence_type"] = "invoice"
+    resp = do(http.MethodPost, "/v1/disputes/DSP-1/evidence", upload, nil)
+    require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+    upload["evidence_type"], upload["content"] = "receipt", "not base64!"
+    resp = do(http.MethodPost, "/v1/disputes/DSP-1/evidence", upload, nil)
+    require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+    upload["content"], upload["mime_type"] = "JVBERi0xLjQK", "application/zip"
+    resp = do(http.MethodPost, "/v1/disputes/DSP-1/evidence", upload, nil)
+    require.Equal(t, http.StatusUnsupportedMediaType, resp.StatusCode)
+    upload["mime_type"], upload["content"] = "application/pdf", "JVBERi0xLjQKJSVFT0YKJSVFT0YK"
+    resp = do(http.MethodPost, "/v1/disputes/DSP-1/evidence", upload, nil)
+    require.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+    upload["content"] = "JVBERi0xLjQK"
+    resp = do(http.MethodPost, "/v1/disputes/DSP-404/evidence", upload, nil)
+    require.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+    var listed listEvidenceResponse
+    resp = do(http.MethodGet, "/v1/disputes/DSP-1/evidence", nil, &listed)
     require.Equal(t, http.StatusOK, resp.StatusCode)
+    require.Len(t, listed.Evidence, 1)
+    resp = do(http.MethodGet, "/v1/disputes/DSP-404/evidence", nil, nil)
+    require.Equal(t, http.StatusNotFound, resp.StatusCode)
 
-    resp, err = client.Get(ts.URL + "/oauth/jwks.json")
+    req, _ := http.NewRequest(http.MethodGet, ts.URL+"/v1/disputes/DSP-1/evidence/ev-1", nil)
+    req.Header.Set("Authorization", "Bearer "+token)
+    resp, err = client.Do(req)
     require.NoError(t, err)
-    require.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+    content, err := io.ReadAll(resp.Body)
+    resp.Body.Close()
+    require.NoError(t, err)
+    require.Equal(t, http.StatusOK, resp.StatusCode)
+    require.Equal(t, "application/pdf", resp.Header.Get("Content-Type"))
+    require.Equal(t, `attachment; filename="receipt.pdf"`, resp.Header.Get("Content-Disposition"))
+    require.Equal(t, "%PDF-1.4\n", string(content))
+    resp = do(http.MethodGet, "/v1/disputes/DSP-1/evidence/ev-2", nil, nil)
+    require.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+    // uploads need disputes:write
+    token = issueToken(t, deps, "dispute-client", "dispute-secret", "disputes:read")
+    resp = do(http.MethodPost, "/v1/disputes/DSP-1/evidence", upload, nil)
+    require.Equal(t, http.StatusForbidden, resp.StatusCode)
+    resp = do(http.MethodGet, "/v1/disputes/DSP-1/evidence", nil, nil)
+    require.Equal(t, http.StatusOK, resp.StatusCode)
 }
 
-func TestBodySizeLimit(t *testing.T) {
+func TestDisputeChargebackCycle(t *testing.T) {
     deps, tlsCfg, clientTLS, _ := newTestDeps(t)
-    deps.MaxBodyBytes = 32
+    deps.DisputesService = &fakeDisputes{status: disputes.StateDisputed}
 
     h, err := NewRouter(deps)
     require.NoError(t, err)
@@ -178,20 +2704,109 @@ func TestBodySizeLimit(t *testing.T) {
     defer ts.Close()
 
     client := &http.Client{Transport: &http.Transport{TLSClientConfig: clientTLS}}
-    token := issueToken(t, deps, "write-client", "write-secret", "accounts:write")
+    token := issueToken(t, deps, "dispute-client", "dispute-secret", "disputes:read disputes:write")
+
+    do := func(path string, body map[string]any) (int, actionResponse) {
+        b, _ := json.Marshal(body)
+        req, _ := http.NewRequest(http.MethodPost, ts.URL+path, bytes.NewReader(b))
+        req.Header.Set("Authorization", "Bearer "+token)
+        resp, err := client.Do(req)
+        require.NoError(t, err)
+        defer resp.Body.Close()
+        var out actionResponse
+        _ = json.NewDecoder(resp.Body).Decode(&out)
+        return resp.StatusCode, out
+    }
+    action := map[string]any{"acted_by": "merchant-ops", "reason": "Proof of delivery uploaded"}
+
+    code, out := do("/v1/disputes/DSP-1/represent", action)
+    require.Equal(t, http.StatusOK, code)
+    require.Equal(t, "REPRESENTED", out.Status)
+
+    // a represented dispute cannot be accepted, and every step needs a reason
+    code, _ = do("/v1/disputes/DSP-1/accept", action)
+    require.Equal(t, http.StatusConflict, code)
+    code, _ = do("/v1/disputes/DSP-1/escalate", map[string]any{"acted_by": "merchant-ops"})
+    require.Equal(t, http.StatusBadRequest, code)
+
+    code, out = do("/v1/disputes/DSP-1/escalate", action)
+    require.Equal(t, http.StatusOK, code)
+    require.Equal(t, "PRE_ARBITRATION", out.Status)
+    code, out = do("/v1/disputes/DSP-1/escalate", action)
+    require.Equal(t, http.StatusOK, code)
+    require.Equal(t, "ARBITRATION", out.Status)
+
+    resolve := map[string]any{"outcome": "REVERSED", "resolved_by": "network", "reason": "Arbitration ruling"}
+    code, _ = do("/v1/disputes/DSP-1/resolve", resolve)
+    require.Equal(t, http.StatusBadRequest, code)
+    resolve["outcome"] = "LOST"
+    code, out = do("/v1/disputes/DSP-1/resolve", resolve)
+    require.Equal(t, http.StatusOK, code)
+    require.Equal(t, "LOST", out.Status)
+
+    // resolved disputes are final
+    code, _ = do("/v1/disputes/DSP-1/escalate", action)
+    require.Equal(t, http.StatusConflict, code)
+    code, _ = do("/v1/disputes/DSP-404/represent", action)
+    require.Equal(t, http.StatusNotFound, code)
+}
 
-    // body > 32 bytes
-    bigBody := []byte(`{"account_number":"ACC001","account_type":"asset","name":"Main","currency_code":"USD","created_by":"tester"}`)
-    req, _ := http.NewRequest(http.MethodPost, ts.URL+"/v1/accounts", bytes.NewReader(bigBody))
-    req.Header.Set("Authorization", "Bearer "+token)
-    req.Header.Set("Content-Type", "application/json")
-    resp, err := client.Do(req)
+func TestPartialDisputes(t *testing.T) {
+    deps, tlsCfg, clientTLS, _ := newTestDeps(t)
+    deps.DisputesService = &fakeDisputes{}
+
+    h, err := NewRouter(deps)
     require.NoError(t, err)
-    require.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+
+    ts := httptest.NewUnstartedServer(h)
+    ts.TLS = tlsCfg
+    ts.StartTLS()
+    defer ts.Close()
+
+    client := &http.Client{Transport: &http.Transport{TLSClientConfig: clientTLS}}
+    token := issueToken(t, deps, "dispute-client", "dispute-secret", "disputes:read disputes:write")
+
+    create := func(amount string) (int, map[string]any) {
+        b, _ := json.Marshal(map[string]any{
+            "journal_entry_id": "JE-1",
+            "merchant_id":      "M-1",
+            "disputed_amount":  amount,
+            "currency_code":    "USD",
+            "reason_code":      "13.1",
+            "reason_text":      "Item not received",
+            "created_by":       "ops",
+        })
+        req, _ := http.NewRequest(http.MethodPost, ts.URL+"/v1/disputes/", bytes.NewReader(b))
+        req.Header.Set("Authorization", "Bearer "+token)
+        resp, err := client.Do(req)
+        require.NoError(t, err)
+        defer resp.Body.Close()
+        var out map[string]any
+        _ = json.NewDecoder(resp.Body).Decode(&out)
+        return resp.StatusCode, out
+    }
+
+    // partial disputes against one transaction, up to its amount
+    code, _ := create("60.00")
+    require.Equal(t, http.StatusCreated, code)
+    code, _ = create("30.00")
+    require.Equal(t, http.StatusCreated, code)
+
+    code, out := create("10.01")
+    require.Equal(t, http.StatusUnprocessableEntity, code)
+    require.Equal(t, "dispute_amount_exceeded", out["error"])
+    require.Equal(t, "JE-1", out["journal_entry_id"])
+    require.Equal(t, map[string]any{"amount": "90.00", "currency": "USD"}, out["disputed_amount"])
+    require.Equal(t, map[string]any{"amount": "10.00", "currency": "USD"}, out["remaining_amount"])
+
+    code, _ = create("10.00")
+    require.Equal(t, http.StatusCreated, code)
 }
 
-func TestSuccessfulFlow(t *testing.T) {
+func TestListDisputesByDeadline(t *testing.T) {
     deps, tlsCfg, clientTLS, _ := newTestDeps(t)
+    fake := &fakeDisputes{status: disputes.StateDisputed}
+    deps.DisputesService = fake
 
     h, err := NewRouter(deps)
     require.NoError(t, err)
@@ -202,39 +2817,234 @@ func TestSuccessfulFlow(t *testing.T) {
     defer ts.Close()
 
     client := &http.Client{Transport: &http.Transport{TLSClientConfig: clientTLS}}
+    token := issueToken(t, deps, "dispute-client", "dispute-secret", "disputes:read")
+
+    get := func(query string) {
+        req, _ := http.NewRequest(http.MethodGet, ts.URL+"/v1/disputes/?"+query, nil)
+        req.Header.Set("Authorization", "Bearer "+token)
+        resp, err := client.Do(req)
+        require.NoError(t, err)
+        resp.Body.Close()
+        require.Equal(t, http.StatusOK, resp.StatusCode)
+    }
 
-    token := issueToken(t, deps, "full-client", "full-secret", "accounts:write accounts:read ledger:read ledger:write")
+    get("overdue=true")
+    require.True(t, fake.filter.Overdue)
+    require.Zero(t, fake.filter.DueWithin)
 
-    createReq := map[string]any{
-        "account_number": "ACC001",
-        "account_type":   "asset",
-        "name":           "Main",
-        "currency_code":  "USD",
-        "created_by":     "tester",
-    }
-    b, _ := json.Marshal(createReq)
-    req, _ := http.NewRequest(http.MethodPost, ts.URL+"/v1/accounts", bytes.NewReader(b))
-    req.Header.Set("Authorization", "Bearer "+token)
-    resp, err := client.Do(req)
+    get("due_within=72h&merchant_id=m-1")
+    require.False(t, fake.filter.Overdue)
+    require.Equal(t, 72*time.Hour, fake.filter.DueWithin)
+    require.Equal(t, "m-1", fake.filter.MerchantID)
+}
+
+func TestHoldAdmin(t *testing.T) {
+    deps, tlsCfg, clientTLS, _ := newTestDeps(t)
+    deps.DisputesService = &fakeDisputes{status: disputes.StateDisputed}
+
+    h, err := NewRouter(deps)
     require.NoError(t, err)
-    require.Equal(t, http.StatusCreated, resp.StatusCode)
-    require.NotEmpty(t, resp.Header.Get(security.CorrelationIDHeader))
 
-    // debit
-    debitReq := map[string]any{"account_id": "acc-1", "amount": 10, "currency_code": "USD", "created_by": "tester"}
-    b, _ = json.Marshal(debitReq)
-    req, _ = http.NewRequest(http.MethodPost, ts.URL+"/v1/ledger/debit", bytes.NewReader(b))
-    req.Header.Set("Authorization", "Bearer "+token)
-    resp, err = client.Do(req)
+    ts := httptest.NewUnstartedServer(h)
+    ts.TLS = tlsCfg
+    ts.StartTLS()
+    defer ts.Close()
+
+    client := &http.Client{Transport: &http.Transport{TLSClientConfig: clientTLS}}
+    token := issueToken(t, deps, "dispute-client", "dispute-secret", "disputes:read disputes:write")
+
+    do := func(path string, body map[string]any) (int, holdResponse) {
+        b, _ := json.Marshal(body)
+        req, _ := http.NewRequest(http.MethodPost, ts.URL+path, bytes.NewReader(b))
+        req.Header.Set("Authorization", "Bearer "+token)
+        resp, err := client.Do(req)
+        require.NoError(t, err)
+        defer resp.Body.Close()
+        var out holdResponse
+        _ = json.NewDecoder(resp.Body).Decode(&out)
+        return resp.StatusCode, out
+    }
+    expiresAt := time.Now().Add(60 * 24 * time.Hour).UTC().Truncate(time.Second)
+    extend := map[string]any{"expires_at": expiresAt, "acted_by": "risk-ops", "reason": "Arbitration pending"}
+    release := map[string]any{"acted_by": "risk-ops", "reason": "Merchant posted collateral"}
+
+    // hold administration needs disputes:admin
+    code, _ := do("/v1/disputes/holds/HLD-1/extend", extend)
+    require.Equal(t, http.StatusForbidden, code)
+    token = issueToken(t, deps, "dispute-admin", "dispute-admin-secret", "disputes:admin")
+
+    code, out := do("/v1/disputes/holds/HLD-1/extend", extend)
+    require.Equal(t, http.StatusOK, code)
+    require.True(t, expiresAt.Equal(out.Hold.ExpiresAt))
+    code, _ = do("/v1/disputes/holds/HLD-1/release", map[string]any{"acted_by": "risk-ops"})
+    require.Equal(t, http.StatusBadRequest, code)
+
+    code, out = do("/v1/disputes/holds/HLD-1/release", release)
+    require.Equal(t, http.StatusOK, code)
+    require.Equal(t, "RELEASED", out.Hold.Status)
+
+    // a released hold is final
+    code, _ = do("/v1/disputes/holds/HLD-1/release", release)
+    require.Equal(t, http.StatusConflict, code)
+    code, _ = do("/v1/disputes/holds/HLD-1/extend", extend)
+    require.Equal(t, http.StatusConflict, code)
+    code, _ = do("/v1/disputes/holds/HLD-404/release", release)
+    require.Equal(t, http.StatusNotFound, code)
+}
+
+func TestIdempotencyKey(t *testing.T) {
+    deps, tlsCfg, clientTLS, _ := newTestDeps(t)
+
+    h, err := NewRouter(deps)
     require.NoError(t, err)
+
+    ts := httptest.NewUnstartedServer(h)
+    ts.TLS = tlsCfg
+    ts.StartTLS()
+    defer ts.Close()
+
+    client := &http.Client{Transport: &http.Transport{TLSClientConfig: clientTLS}}
+    token := issueToken(t, deps, "full-client", "full-secret", "ledger:write")
+    otherToken := issueToken(t, deps, "other-client", "other-secret", "ledger:write")
+
+    post := func(token, key string, body map[string]any) (*http.Response, map[string]any) {
+        b, _ := json.Marshal(body)
+        req, _ := http.NewRequest(http.MethodPost, ts.URL+"/v1/ledger/debit", bytes.NewReader(b))
+        req.Header.Set("Authorization", "Bearer "+token)
+        if key != "" {
+            req.Header.Set(IdempotencyKeyHeader, key)
+        }
+        resp, err := client.Do(req)
+        require.NoError(t, err)
+        defer resp.Body.Close()
+        var out map[string]any
+        _ = json.NewDecoder(resp.Body).Decode(&out)
+        return resp, out
+    }
+
+    fl := deps.LedgerWriter.(*fakeLedger)
+    debitReq := map[string]any{"account_id": "acc-1", "amount": "10.00", "currency_code": "USD", "created_by": "tester"}
+
+    // first request posts and returns a generated transaction_id
+    resp, first := post(token, "key-1", debitReq)
     require.Equal(t, http.StatusOK, resp.StatusCode)
+    require.NotEmpty(t, first["transaction_id"])
+    require.Equal(t, 1, fl.debitCalls)
 
-    // balance
-    req, _ = http.NewRequest(http.MethodGet, ts.URL+"/v1/ledger/balance?account_id=acc-1", nil)
-    req.Header.Set("Authorization", "Bearer "+token)
-    resp, err = client.Do(req)
+    // a retry replays the stored response without posting again
+    resp, replayed := post(token, "key-1", debitReq)
+    require.Equal(t, http.StatusOK, resp.StatusCode)
+    require.Equal(t, "true", resp.Header.Get(IdempotentReplayedHeader))
+    require.Equal(t, first["transaction_id"], replayed["transaction_id"])
+    require.Equal(t, 1, fl.debitCalls)
+
+    // reusing the key with a different payload is rejected
+    mismatched := map[string]any{"account_id": "acc-1", "amount": "11.00", "currency_code": "USD", "created_by": "tester"}
+    resp, _ = post(token, "key-1", mismatched)
+    require.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+    require.Equal(t, 1, fl.debitCalls)
+
+    // keys are scoped per client
+    resp, other := post(otherToken, "key-1", debitReq)
+    require.Equal(t, http.StatusOK, resp.StatusCode)
+    require.NotEqual(t, first["transaction_id"], other["transaction_id"])
+    require.Equal(t, 2, fl.debitCalls)
+
+    // requests without a key are not deduplicated
+    resp, _ = post(token, "", debitReq)
+    require.Equal(t, http.StatusOK, resp.StatusCode)
+    resp, _ = post(token, "", debitReq)
+    require.Equal(t, http.StatusOK, resp.StatusCode)
+    require.Equal(t, 4, fl.debitCalls)
+
+    // a response that cannot be stored holds the key instead of letting a
+    // retry post again
+    store := deps.Idempotency.(*memoryIdempotencyStore)
+    store.failComplete = true
+    resp, _ = post(token, "key-2", debitReq)
+    require.Equal(t, http.StatusOK, resp.StatusCode)
+    store.failComplete = false
+    require.Equal(t, []string{"full-client/key-2"}, store.held)
+    resp, _ = post(token, "key-2", debitReq)
+    require.Equal(t, http.StatusConflict, resp.StatusCode)
+    require.Equal(t, 5, fl.debitCalls)
+}
+
+func TestIdempotencyKeyServerError(t *testing.T) {
+    deps, tlsCfg, clientTLS, _ := newTestDeps(t)
+    fl := deps.LedgerWriter.(*fakeLedger)
+    store := deps.Idempotency.(*memoryIdempotencyStore)
+
+    h, err := NewRouter(deps)
+    require.NoError(t, err)
+    ts := httptest.NewUnstartedServer(h)
+    ts.TLS = tlsCfg
+    ts.StartTLS()
+    defer ts.Close()
+
+    unavailableDeps := deps
+    unavailableDeps.LedgerWriter = nil
+    uh, err := NewRouter(unavailableDeps)
     require.NoError(t, err)
+    uts := httptest.NewUnstartedServer(uh)
+    uts.TLS = tlsCfg
+    uts.StartTLS()
+    defer uts.Close()
+
+    client := &http.Client{Transport: &http.Transport{TLSClientConfig: clientTLS}}
+    token := issueToken(t, deps, "full-client", "full-secret", "ledger:write")
+    body, _ := json.Marshal(map[string]any{"account_id": "acc-1", "amount": "10.00", "currency_code": "USD", "created_by": "tester"})
+    post := func(baseURL, key string) *http.Response {
+        req, _ := http.NewRequest(http.MethodPost, baseURL+"/v1/ledger/debit", bytes.NewReader(body))
+        req.Header.Set("Authorization", "Bearer "+token)
+        req.Header.Set(IdempotencyKeyHeader, key)
+        resp, err := client.Do(req)
+        require.NoError(t, err)
+        resp.Body.Close()
+        return resp
+    }
+
+    // a posting that fails after committing keeps its key, and a retry
+    // replays the error rather than posting again
+    fl.failAfterDebit = true
+    resp := post(ts.URL, "key-1")
+    require.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+    require.Equal(t, 1, fl.debitCalls)
+    fl.failAfterDebit = false
+    resp = post(ts.URL, "key-1")
+    require.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+    require.Equal(t, "true", resp.Header.Get(IdempotentReplayedHeader))
+    require.Equal(t, 1, fl.debitCalls)
+
+    // a request turned away before changing anything releases its key
+    resp = post(uts.URL, "key-2")
+    require.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+    resp = post(ts.URL, "key-2")
     require.Equal(t, http.StatusOK, resp.StatusCode)
+    require.Empty(t, resp.Header.Get(IdempotentReplayedHeader))
+    require.Equal(t, 2, fl.debitCalls)
+    require.Empty(t, store.held)
+}
+
+func TestExtendClaim(t *testing.T) {
+    heartbeat := idempotencyHeartbeat
+    idempotencyHeartbeat = time.Millisecond
+    defer func() { idempotencyHeartbeat = heartbeat }()
+
+    store := &memoryIdempotencyStore{records: map[string]*IdempotencyRecord{}}
+    extensions := func() int {
+        store.mu.Lock()
+        defer store.mu.Unlock()
+        return store.extended
+    }
+
+    stop := extendClaim(store, slog.Default(), "full-client", "key-1")
+    require.Eventually(t, func() bool { return extensions() >= 2 }, time.Second, time.Millisecond)
+    stop()
+    stopped := extensions()
+    time.Sleep(10 * time.Millisecond)
+    require.Equal(t, stopped, extensions())
+    stop()
 }
 
 func issueToken(t *testing.T, deps Dependencies, clientID, clientSecret, scope string) string {
@@ -278,6 +3088,16 @@ func newTestDeps(t *testing.T) (Dependencies, *tls.Config, *tls.Config, *tls.Con
     store.clients["read-client"] = &auth.Client{ID: "read-client", SecretHash: mustHash(t, "read-secret"), Scopes: []string{"accounts:read"}}
     store.clients["write-client"] = &auth.Client{ID: "write-client", SecretHash: mustHash(t, "write-secret"), Scopes: []string{"accounts:write"}}
     store.clients["full-client"] = &auth.Client{ID: "full-client", SecretHash: mustHash(t, "full-secret"), Scopes: []string{"accounts:read", "accounts:write", "ledger:read", "ledger:write"}}
+    store.clients["close-client"] = &auth.Client{ID: "close-client", SecretHash: mustHash(t, "close-secret"), Scopes: []string{"accounts:write", "ledger:read", "ledger:close"}}
+    store.clients["fx-client"] = &auth.Client{ID: "fx-client", SecretHash: mustHash(t, "fx-secret"), Scopes: []string{"accounts:write", "ledger:read", "ledger:write", "ledger:fx"}}
+    store.clients["recon-client"] = &auth.Client{ID: "recon-client", SecretHash: mustHash(t, "recon-secret"), Scopes: []string{"ledger:read", "ledger:reconcile"}}
+    store.clients["webhook-client"] = &auth.Client{ID: "webhook-client", SecretHash: mustHash(t, "webhook-secret"), Scopes: []string{"webhooks:read", "webhooks:write"}}
+    store.clients["other-webhook-client"] = &auth.Client{ID: "other-webhook-client", SecretHash: mustHash(t, "other-webhook-secret"), Scopes: []string{"webhooks:read", "webhooks:write"}}
+    store.clients["payout-client"] = &auth.Client{ID: "payout-client", SecretHash: mustHash(t, "payout-secret"), Scopes: []string{"payouts:read", "payouts:write"}}
+    store.clients["fee-client"] = &auth.Client{ID: "fee-client", SecretHash: mustHash(t, "fee-secret"), Scopes: []string{"fees:read", "fees:write"}}
+    store.clients["dispute-client"] = &auth.Client{ID: "dispute-client", SecretHash: mustHash(t, "dispute-secret"), Scopes: []string{"disputes:read", "disputes:write"}}
+    store.clients["dispute-admin"] = &auth.Client{ID: "dispute-admin", SecretHash: mustHash(t, "dispute-admin-secret"), Scopes: []string{"disputes:admin"}}
+    store.clients["other-client"] = &auth.Client{ID: "other-client", SecretHash: mustHash(t, "other-secret"), Scopes: []string{"ledger:write"}}
 
     oauthServer := &auth.OAuthServer{Store: store, Keys: keySet, Issuer: "test", AccessTokenTTL: 5 * time.Minute}
     validator := &auth.JWTValidator{KeySet: keySet, Issuer: "test"}
@@ -286,14 +3106,21 @@ func newTestDeps(t *testing.T) (Dependencies, *tls.Config, *tls.Config, *tls.Con
     as := &auditSpy{}
 
     deps := Dependencies{
-        OAuth:        oauthServer,
-        JWTValidator: validator,
-        LedgerReader: fl,
-        LedgerWriter: fl,
-        Auditor:      as,
-        RateLimiter:  &security.RedisTokenBucket{Redis: rdb, Prefix: "test", Capacity: 100, RefillRate: 100},
-        IPAllowlist:  nil,
-        MaxBodyBytes: 1 << 20,
+        OAuth:          oauthServer,
+        JWTValidator:   validator,
+        LedgerReader:   fl,
+        LedgerWriter:   fl,
+        Reports:        &fakeReports{},
+        Reconciliation: &fakeReconciliation{},
+        Webhooks:       &fakeWebhooks{},
+        Payouts:        &fakePayouts{},
+        Fees:           &fakeFees{},
+        DisputeEvidence: &fakeEvidence{},
+        Auditor:        as,
+        Idempotency:    &memoryIdempotencyStore{records: map[string]*IdempotencyRecord{}},
+        RateLimiter:    &security.RedisTokenBucket{Redis: rdb, Prefix: "test", Capacity: 100, RefillRate: 100},
+        IPAllowlist:    nil,
+        MaxBodyBytes:   1 << 20,
     }
 
     return deps, certs.serverTLS, certs.clientTLS, certs.noClientTLS
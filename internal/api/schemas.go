@@ -5,6 +5,7 @@ const createAccountSchema = `{
   "additionalProperties": false,
   "required": ["account_number", "account_type", "name", "currency_code", "created_by"],
   "properties": {
+    "parent_id": {"type": "string", "minLength": 1},
     "account_number": {"type": "string", "minLength": 1, "maxLength": 50},
     "account_type": {"type": "string", "enum": ["asset", "liability", "equity", "revenue", "expense"]},
     "name": {"type": "string", "minLength": 1, "maxLength": 255},
@@ -21,7 +22,7 @@ const debitSchema = `{
   "properties": {
     "transaction_id": {"type": "string"},
     "account_id": {"type": "string", "minLength": 1},
-    "amount": {"type": "number", "exclusiveMinimum": 0},
+    "amount": {"type": ["string", "number"], "pattern": "^([0-9]*[1-9][0-9]*(\\.[0-9]+)?|[0-9]+\\.[0-9]*[1-9][0-9]*)$", "exclusiveMinimum": 0},
     "description": {"type": "string"},
     "reference_type": {"type": "string"},
     "reference_id": {"type": "string"},
@@ -33,6 +34,357 @@ const debitSchema = `{
 
 const creditSchema = debitSchema
 
+const transferSchema = `{
+  "type": "object",
+  "additionalProperties": false,
+  "required": ["from_account_id", "to_account_id", "amount", "currency_code", "created_by"],
+  "properties": {
+    "transaction_id": {"type": "string"},
+    "from_account_id": {"type": "string", "minLength": 1},
+    "to_account_id": {"type": "string", "minLength": 1},
+    "amount": {"type": ["string", "number"], "pattern": "^([0-9]*[1-9][0-9]*(\\.[0-9]+)?|[0-9]+\\.[0-9]*[1-9][0-9]*)$", "exclusiveMinimum": 0},
+    "description": {"type": "string"},
+    "reference_type": {"type": "string"},
+    "reference_id": {"type": "string"},
+    "currency_code": {"type": "string", "pattern": "^[A-Z]{3}$"},
+    "created_by": {"type": "string", "minLength": 1},
+    "metadata": {"type": "object"}
+  }
+}`
+
+const authorizePendingSchema = `{
+  "type": "object",
+  "additionalProperties": false,
+  "required": ["from_account_id", "to_account_id", "amount", "currency_code", "description", "created_by"],
+  "properties": {
+    "transaction_id": {"type": "string"},
+    "from_account_id": {"type": "string", "minLength": 1},
+    "to_account_id": {"type": "string", "minLength": 1},
+    "amount": {"type": ["string", "number"], "pattern": "^([0-9]*[1-9][0-9]*(\\.[0-9]+)?|[0-9]+\\.[0-9]*[1-9][0-9]*)$", "exclusiveMinimum": 0},
+    "description": {"type": "string", "minLength": 1},
+    "reference_type": {"type": "string"},
+    "reference_id": {"type": "string"},
+    "currency_code": {"type": "string", "pattern": "^[A-Z]{3}$"},
+    "created_by": {"type": "string", "minLength": 1},
+    "ttl_seconds": {"type": "integer", "minimum": 1},
+    "metadata": {"type": "object"}
+  }
+}`
+
+const capturePendingSchema = `{
+  "type": "object",
+  "additionalProperties": false,
+  "required": ["captured_by"],
+  "dependentRequired": {"amount": ["currency_code"]},
+  "properties": {
+    "amount": {"type": ["string", "number"], "pattern": "^([0-9]*[1-9][0-9]*(\\.[0-9]+)?|[0-9]+\\.[0-9]*[1-9][0-9]*)$", "exclusiveMinimum": 0},
+    "currency_code": {"type": "string", "pattern": "^[A-Z]{3}$"},
+    "captured_by": {"type": "string", "minLength": 1}
+  }
+}`
+
+const voidPendingSchema = `{
+  "type": "object",
+  "additionalProperties": false,
+  "required": ["voided_by"],
+  "properties": {
+    "voided_by": {"type": "string", "minLength": 1}
+  }
+}`
+
+const reverseTransactionSchema = `{
+  "type": "object",
+  "additionalProperties": false,
+  "required": ["reason", "reversed_by", "approved_by"],
+  "dependentRequired": {"amount": ["currency_code"]},
+  "properties": {
+    "reversal_id": {"type": "string"},
+    "amount": {"type": ["string", "number"], "pattern": "^([0-9]*[1-9][0-9]*(\\.[0-9]+)?|[0-9]+\\.[0-9]*[1-9][0-9]*)$", "exclusiveMinimum": 0},
+    "currency_code": {"type": "string", "pattern": "^[A-Z]{3}$"},
+    "reason": {"type": "string", "minLength": 1},
+    "reversed_by": {"type": "string", "minLength": 1},
+    "approved_by": {"type": "string", "minLength": 1}
+  }
+}`
+
+const accountStatusSchema = `{
+  "type": "object",
+  "additionalProperties": false,
+  "required": ["reason", "changed_by"],
+  "properties": {
+    "reason": {"type": "string", "minLength": 1},
+    "changed_by": {"type": "string", "minLength": 1}
+  }
+}`
+
+const setCreditLimitSchema = `{
+  "type": "object",
+  "additionalProperties": false,
+  "required": ["overdraft_limit", "currency_code", "reason", "set_by"],
+  "properties": {
+    "overdraft_limit": {"type": ["string", "number"], "pattern": "^[0-9]+(\\.[0-9]+)?$"},
+    "max_transaction_amount": {"type": ["string", "number"], "pattern": "^([0-9]*[1-9][0-9]*(\\.[0-9]+)?|[0-9]+\\.[0-9]*[1-9][0-9]*)$", "exclusiveMinimum": 0},
+    "currency_code": {"type": "string", "pattern": "^[A-Z]{3}$"},
+    "effective_from": {"type": "string", "format": "date-time"},
+    "reason": {"type": "string", "minLength": 1},
+    "set_by": {"type": "string", "minLength": 1}
+  }
+}`
+
+const setFXRateSchema = `{
+  "type": "object",
+  "additionalProperties": false,
+  "required": ["base_currency", "quote_currency", "rate", "created_by"],
+  "properties": {
+    "base_currency": {"type": "string", "pattern": "^[A-Z]{3}$"},
+    "quote_currency": {"type": "string", "pattern": "^[A-Z]{3}$"},
+    "rate": {"type": ["string", "number"], "pattern": "^([0-9]*[1-9][0-9]*(\\.[0-9]+)?|[0-9]+\\.[0-9]*[1-9][0-9]*)$", "exclusiveMinimum": 0},
+    "effective_at": {"type": "string", "format": "date-time"},
+    "source": {"type": "string"},
+    "created_by": {"type": "string", "minLength": 1}
+  }
+}`
+
+const setFXAccountsSchema = `{
+  "type": "object",
+  "additionalProperties": false,
+  "required": ["clearing_account_id", "gain_account_id", "loss_account_id", "updated_by"],
+  "properties": {
+    "clearing_account_id": {"type": "string", "minLength": 1},
+    "gain_account_id": {"type": "string", "minLength": 1},
+    "loss_account_id": {"type": "string", "minLength": 1},
+    "updated_by": {"type": "string", "minLength": 1}
+  }
+}`
+
+const fxTransferSchema = `{
+  "type": "object",
+  "additionalProperties": false,
+  "required": ["from_account_id", "to_account_id", "amount", "currency_code", "description", "created_by"],
+  "properties": {
+    "transaction_id": {"type": "string"},
+    "from_account_id": {"type": "string", "minLength": 1},
+    "to_account_id": {"type": "string", "minLength": 1},
+    "amount": {"type": ["string", "number"], "pattern": "^([0-9]*[1-9][0-9]*(\\.[0-9]+)?|[0-9]+\\.[0-9]*[1-9][0-9]*)$", "exclusiveMinimum": 0},
+    "currency_code": {"type": "string", "pattern": "^[A-Z]{3}$"},
+    "quoted_rate": {"type": ["string", "number"], "pattern": "^([0-9]*[1-9][0-9]*(\\.[0-9]+)?|[0-9]+\\.[0-9]*[1-9][0-9]*)$", "exclusiveMinimum": 0},
+    "description": {"type": "string", "minLength": 1},
+    "reference_type": {"type": "string"},
+    "reference_id": {"type": "string"},
+    "created_by": {"type": "string", "minLength": 1},
+    "metadata": {"type": "object"}
+  }
+}`
+
+const postTransactionSchema = `{
+  "type": "object",
+  "additionalProperties": false,
+  "required": ["entries", "created_by"],
+  "properties": {
+    "transaction_id": {"type": "string"},
+    "description": {"type": "string"},
+    "reference_type": {"type": "string"},
+    "reference_id": {"type": "string"},
+    "effective_date": {"type": "string", "pattern": "^[0-9]{4}-[0-9]{2}-[0-9]{2}$"},
+    "created_by": {"type": "string", "minLength": 1},
+    "metadata": {"type": "object"},
+    "entries": {
+      "type": "array",
+      "minItems": 2,
+      "items": {
+        "type": "object",
+        "additionalProperties": false,
+        "required": ["account_id", "entry_type", "amount", "currency_code"],
+        "properties": {
+          "account_id": {"type": "string", "minLength": 1},
+          "entry_type": {"type": "string", "enum": ["debit", "credit"]},
+          "amount": {"type": ["string", "number"], "pattern": "^([0-9]*[1-9][0-9]*(\\.[0-9]+)?|[0-9]+\\.[0-9]*[1-9][0-9]*)$", "exclusiveMinimum": 0},
+          "currency_code": {"type": "string", "pattern": "^[A-Z]{3}$"},
+          "description": {"type": "string"},
+          "metadata": {"type": "object"}
+        }
+      }
+    }
+  }
+}`
+
+const createPeriodSchema = `{
+  "type": "object",
+  "additionalProperties": false,
+  "required": ["name", "starts_on", "ends_on", "created_by"],
+  "properties": {
+    "name": {"type": "string", "minLength": 1},
+    "starts_on": {"type": "string", "pattern": "^[0-9]{4}-[0-9]{2}-[0-9]{2}$"},
+    "ends_on": {"type": "string", "pattern": "^[0-9]{4}-[0-9]{2}-[0-9]{2}$"},
+    "created_by": {"type": "string", "minLength": 1}
+  }
+}`
+
+const closePeriodSchema = `{
+  "type": "object",
+  "additionalProperties": false,
+  "required": ["closed_by"],
+  "properties": {
+    "transaction_id": {"type": "string"},
+    "closed_by": {"type": "string", "minLength": 1}
+  }
+}`
+
+const setRetainedEarningsSchema = `{
+  "type": "object",
+  "additionalProperties": false,
+  "required": ["account_id", "updated_by"],
+  "properties": {
+    "account_id": {"type": "string", "minLength": 1},
+    "updated_by": {"type": "string", "minLength": 1}
+  }
+}`
+
+const importStatementSchema = `{
+  "type": "object",
+  "additionalProperties": false,
+  "required": ["account_id", "format", "content", "imported_by"],
+  "properties": {
+    "account_id": {"type": "string", "minLength": 1},
+    "format": {"type": "string", "enum": ["csv", "camt053", "mt940"]},
+    "content": {"type": "string", "minLength": 1},
+    "reference": {"type": "string"},
+    "imported_by": {"type": "string", "minLength": 1}
+  }
+}`
+
+const decideMatchSchema = `{
+  "type": "object",
+  "additionalProperties": false,
+  "required": ["decided_by"],
+  "properties": {
+    "decided_by": {"type": "string", "minLength": 1}
+  }
+}`
+
+const createWebhookSchema = `{
+  "type": "object",
+  "additionalProperties": false,
+  "required": ["url"],
+  "properties": {
+    "url": {"type": "string", "pattern": "^https://"},
+    "description": {"type": "string", "maxLength": 256},
+    "event_types": {"type": "array", "items": {"type": "string", "enum": ["transaction.posted", "account.created", "account.status_changed", "dispute.transitioned"]}},
+    "account_ids": {"type": "array", "items": {"type": "string", "minLength": 1}},
+    "dispute_states": {"type": "array", "items": {"type": "string", "enum": ["PENDING", "AUTHORIZED", "SETTLED", "DISPUTED", "REVERSED"]}}
+  }
+}`
+
+const updateWebhookSchema = `{
+  "type": "object",
+  "additionalProperties": false,
+  "required": ["url", "active"],
+  "properties": {
+    "url": {"type": "string", "pattern": "^https://"},
+    "description": {"type": "string", "maxLength": 256},
+    "event_types": {"type": "array", "items": {"type": "string", "enum": ["transaction.posted", "account.created", "account.status_changed", "dispute.transitioned"]}},
+    "account_ids": {"type": "array", "items": {"type": "string", "minLength": 1}},
+    "dispute_states": {"type": "array", "items": {"type": "string", "enum": ["PENDING", "AUTHORIZED", "SETTLED", "DISPUTED", "REVERSED"]}},
+    "active": {"type": "boolean"}
+  }
+}`
+
+const payoutSettingsSchema = `{
+  "type": "object",
+  "additionalProperties": false,
+  "required": ["payable_account_id", "funding_account_id", "schedule", "minimum_amount", "currency_code", "active", "updated_by"],
+  "properties": {
+    "payable_account_id": {"type": "string", "minLength": 1},
+    "funding_account_id": {"type": "string", "minLength": 1},
+    "schedule": {"type": "string", "enum": ["daily", "weekly", "on_demand"]},
+    "weekday": {"type": "integer", "minimum": 0, "maximum": 6},
+    "minimum_amount": {"type": ["string", "number"], "pattern": "^[0-9]+(\\.[0-9]+)?$"},
+    "currency_code": {"type": "string", "pattern": "^[A-Z]{3}$"},
+    "active": {"type": "boolean"},
+    "updated_by": {"type": "string", "minLength": 1}
+  }
+}`
+
+const requestPayoutSchema = `{
+  "type": "object",
+  "additionalProperties": false,
+  "required": ["merchant_id", "created_by"],
+  "properties": {
+    "merchant_id": {"type": "string", "minLength": 1},
+    "created_by": {"type": "string", "minLength": 1}
+  }
+}`
+
+const payoutTransitionSchema = `{
+  "type": "object",
+  "additionalProperties": false,
+  "required": ["changed_by"],
+  "properties": {
+    "bank_reference": {"type": "string", "maxLength": 256},
+    "changed_by": {"type": "string", "minLength": 1}
+  }
+}`
+
+const failPayoutSchema = `{
+  "type": "object",
+  "additionalProperties": false,
+  "required": ["reason", "changed_by"],
+  "properties": {
+    "reason": {"type": "string", "minLength": 1},
+    "changed_by": {"type": "string", "minLength": 1}
+  }
+}`
+
+const createFeeScheduleSchema = `{
+  "type": "object",
+  "additionalProperties": false,
+  "required": ["fee_type", "currency_code", "revenue_account_id", "created_by"],
+  "properties": {
+    "fee_type": {"type": "string", "enum": ["processing", "chargeback", "payout"]},
+    "merchant_id": {"type": "string", "minLength": 1},
+    "card_brand": {"type": "string", "enum": ["VISA", "MASTERCARD", "AMERICAN_EXPRESS", "DISCOVER", "JCB", "DINERS_CLUB"]},
+    "currency_code": {"type": "string", "pattern": "^[A-Z]{3}$"},
+    "fixed_amount": {"type": ["string", "number"], "pattern": "^[0-9]+(\\.[0-9]+)?$"},
+    "rate": {"type": ["string", "number"], "pattern": "^[0-9]+(\\.[0-9]+)?$"},
+    "tiers": {
+      "type": "array",
+      "maxItems": 20,
+      "items": {
+        "type": "object",
+        "additionalProperties": false,
+        "properties": {
+          "up_to": {"type": ["string", "number"], "pattern": "^([0-9]*[1-9][0-9]*(\\.[0-9]+)?|[0-9]+\\.[0-9]*[1-9][0-9]*)$", "exclusiveMinimum": 0},
+          "fixed_amount": {"type": ["string", "number"], "pattern": "^[0-9]+(\\.[0-9]+)?$"},
+          "rate": {"type": ["string", "number"], "pattern": "^[0-9]+(\\.[0-9]+)?$"}
+        }
+      }
+    },
+    "minimum_fee": {"type": ["string", "number"], "pattern": "^[0-9]+(\\.[0-9]+)?$"},
+    "maximum_fee": {"type": ["string", "number"], "pattern": "^[0-9]+(\\.[0-9]+)?$"},
+    "revenue_account_id": {"type": "string", "minLength": 1},
+    "effective_from": {"type": "string", "format": "date-time"},
+    "effective_to": {"type": "string", "format": "date-time"},
+    "created_by": {"type": "string", "minLength": 1}
+  }
+}`
+
+const chargeFeeSchema = `{
+  "type": "object",
+  "additionalProperties": false,
+  "required": ["fee_type", "amount", "currency_code", "payer_account_id", "reference_id", "created_by"],
+  "properties": {
+    "fee_type": {"type": "string", "enum": ["processing", "chargeback", "payout"]},
+    "merchant_id": {"type": "string", "minLength": 1},
+    "card_brand": {"type": "string", "enum": ["VISA", "MASTERCARD", "AMERICAN_EXPRESS", "DISCOVER", "JCB", "DINERS_CLUB"]},
+    "amount": {"type": ["string", "number"], "pattern": "^[0-9]+(\\.[0-9]+)?$"},
+    "currency_code": {"type": "string", "pattern": "^[A-Z]{3}$"},
+    "payer_account_id": {"type": "string", "minLength": 1},
+    "transaction_id": {"type": "string", "minLength": 1},
+    "reference_id": {"type": "string", "minLength": 1},
+    "created_by": {"type": "string", "minLength": 1}
+  }
+}`
+
 const disputesSchema = `{
   "type": "object",
   "additionalProperties": false,
@@ -40,7 +392,7 @@ const disputesSchema = `{
   "properties": {
     "journal_entry_id": {"type": "string", "minLength": 1},
     "merchant_id": {"type": "string", "minLength": 1},
-    "disputed_amount": {"type": "number", "exclusiveMinimum": 0},
+    "disputed_amount": {"type": ["string", "number"], "pattern": "^([0-9]*[1-9][0-9]*(\\.[0-9]+)?|[0-9]+\\.[0-9]*[1-9][0-9]*)$", "exclusiveMinimum": 0},
     "currency_code": {"type": "string", "pattern": "^[A-Z]{3}$"},
     "reason_code": {"type": "string", "minLength": 1},
     "reason_text": {"type": "string", "minLength": 1},
@@ -50,3 +402,18 @@ const disputesSchema = `{
     "metadata": {"type": "object"}
   }
 }`
+
+const uploadEvidenceSchema = `{
+  "type": "object",
+  "additionalProperties": false,
+  "required": ["evidence_type", "description", "file_name", "mime_type", "content", "uploaded_by"],
+  "properties": {
+    "evidence_type": {"type": "string", "enum": ["receipt", "delivery_proof", "correspondence", "other"]},
+    "description": {"type": "string", "minLength": 1},
+    "file_name": {"type": "string", "minLength": 1, "maxLength": 255},
+    "mime_type": {"type": "string", "minLength": 1},
+    "content": {"type": "string", "minLength": 1, "pattern": "^[A-Za-z0-9+/]*={0,2}$"},
+    "uploaded_by": {"type": "string", "minLength": 1},
+    "metadata": {"type": "object"}
+  }
+}`
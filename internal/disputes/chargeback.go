@@ -0,0 +1,252 @@
+package disputes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/example/pci-infra/internal/fees"
+	"github.com/example/pci-infra/internal/ledger"
+	"github.com/example/pci-infra/internal/money"
+)
+
+// ReferenceTypeChargeback is the reference type of the transaction debiting a
+// merchant for a chargeback it lost or accepted
+const ReferenceTypeChargeback = "chargeback"
+
+// ErrInvalidDisputeAction is returned when a step in the chargeback cycle is
+// missing its actor or reason, or names an outcome that is not one
+var ErrInvalidDisputeAction = errors.New("invalid dispute action")
+
+// ErrChargebackNotConfigured is returned when resolving a chargeback against
+// the merchant in a currency with no chargeback settlement account
+var ErrChargebackNotConfigured = errors.New("chargeback settlement account not configured")
+
+// RepresentDispute records the merchant's response to a first chargeback:
+// the chargeback is contested with the evidence uploaded to the dispute
+func (ds *DisputesService) RepresentDispute(ctx context.Context, disputeID, representedBy, reason string) error {
+	_, err := ds.advance(ctx, disputeID, StateRepresented, representedBy, reason)
+	return err
+}
+
+// EscalateDispute moves a dispute to the next cycle: a represented dispute
+// the issuer still contests goes to pre-arbitration, and a pre-arbitration
+// the merchant does not accept goes to network arbitration. It returns the
+// state the dispute moved to.
+func (ds *DisputesService) EscalateDispute(ctx context.Context, disputeID, escalatedBy, reason string) (DisputeState, error) {
+	return ds.advance(ctx, disputeID, "", escalatedBy, reason)
+}
+
+// AcceptDispute accepts liability for a first chargeback or pre-arbitration.
+// The merchant is debited the disputed amount and charged the chargeback fee.
+func (ds *DisputesService) AcceptDispute(ctx context.Context, disputeID, acceptedBy, reason string) error {
+	_, err := ds.advance(ctx, disputeID, StateAccepted, acceptedBy, reason)
+	return err
+}
+
+// ResolveDispute records the outcome of a contested chargeback, WON or LOST.
+// Either way the hold is released and the chargeback fee charged; a lost
+// dispute also debits the merchant the disputed amount.
+func (ds *DisputesService) ResolveDispute(ctx context.Context, disputeID string, outcome DisputeState, resolvedBy, reason string) error {
+	if outcome != StateWon && outcome != StateLost {
+		return fmt.Errorf("%w: outcome must be %s or %s, got %q", ErrInvalidDisputeAction, StateWon, StateLost, outcome)
+	}
+	_, err := ds.advance(ctx, disputeID, outcome, resolvedBy, reason)
+	return err
+}
+
+// advance moves a dispute in the chargeback cycle to the state to, or to its
+// next escalation when to is empty, posting the ledger effects of an outcome.
+// The status, the postings and the transition are written in one database
+// transaction, so a failure leaves none of them behind.
+func (ds *DisputesService) advance(ctx context.Context, disputeID string, to DisputeState, actor, reason string) (DisputeState, error) {
+	if actor == "" {
+		return "", fmt.Errorf("%w: actor is required", ErrInvalidDisputeAction)
+	}
+	if strings.TrimSpace(reason) == "" {
+		return "", fmt.Errorf("%w: reason is required", ErrInvalidDisputeAction)
+	}
+
+	tx, err := ds.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Lock the dispute so a concurrent action, such as the deadline
+	// scheduler on another replica, sees the state this one leaves it in
+	if _, err := tx.Exec(ctx, `SELECT 1 FROM disputes WHERE dispute_id = $1 FOR UPDATE`, disputeID); err != nil {
+		return "", fmt.Errorf("failed to lock dispute: %w", err)
+	}
+
+	dispute, err := ds.getDisputeByDisputeID(ctx, tx, disputeID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get dispute: %w", err)
+	}
+	if dispute == nil {
+		return "", fmt.Errorf("%w: %s", ErrDisputeNotFound, disputeID)
+	}
+
+	if to == "" {
+		to = escalation(dispute.Status)
+	}
+	if !ds.stateMachine.IsValidTransition(dispute.Status, to) {
+		return "", &InvalidStateTransitionError{FromState: dispute.Status, ToState: to, DisputeID: disputeID}
+	}
+
+	if err := ds.setStatus(ctx, tx, dispute, to, actor); err != nil {
+		return "", err
+	}
+
+	if IsFinalState(to) {
+		if err := ds.postChargeback(ctx, tx, dispute, to, actor); err != nil {
+			return "", err
+		}
+	}
+
+	result := ds.transitionsIn(tx).Transition(ctx, TransitionRequest{
+		DisputeID: disputeID,
+		ToState:   to,
+		Reason:    reason,
+		CreatedBy: actor,
+		Metadata:  map[string]interface{}{},
+	})
+	if !result.Success {
+		return "", fmt.Errorf("failed to transition dispute: %w", result.Error)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return "", fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return to, nil
+}
+
+// escalation is the state a dispute escalates to from state, or "" when it
+// cannot be escalated
+func escalation(state DisputeState) DisputeState {
+	switch state {
+	case StateRepresented:
+		return StatePreArbitration
+	case StatePreArbitration:
+		return StateArbitration
+	}
+	return ""
+}
+
+// postChargeback posts what resolving a chargeback costs the merchant. The
+// chargeback fee is charged whatever the outcome, as the networks levy it on
+// every chargeback received; it is quoted from the schedules in force when
+// the dispute was opened and prorated to the disputed share of the
+// transaction, as when it was held. When the merchant is liable the disputed
+// amount is also debited and credited to the currency's chargeback
+// settlement account.
+// Both are posted within tx. Their transaction IDs derive from the dispute,
+// and one already posted is not posted again, so each posts once.
+func (ds *DisputesService) postChargeback(ctx context.Context, tx pgx.Tx, dispute *Dispute, outcome DisputeState, actor string) error {
+	// Lock the disputed entry so partial disputes against it are charged
+	// their fees one at a time
+	var accountID string
+	err := tx.QueryRow(ctx, `
+		SELECT account_id FROM journal_entries WHERE id = $1 FOR UPDATE
+	`, dispute.JournalEntryID).Scan(&accountID)
+	if err != nil {
+		return fmt.Errorf("failed to get account ID: %w", err)
+	}
+
+	if MerchantLiable(outcome) {
+		var settlementAccountID string
+		err := tx.QueryRow(ctx, `
+			SELECT settlement_account_id FROM chargeback_accounts WHERE currency_code = $1
+		`, dispute.CurrencyCode).Scan(&settlementAccountID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("%w: %s", ErrChargebackNotConfigured, dispute.CurrencyCode)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get chargeback settlement account: %w", err)
+		}
+
+		err = ds.postOnce(ctx, tx, ledger.Transaction{
+			TransactionID: chargebackTransactionID(dispute.ID, "chargeback"),
+			Entries:       chargebackLegs(accountID, settlementAccountID, dispute.DisputedAmount),
+			Description:   fmt.Sprintf("Chargeback %s %s: %s", dispute.DisputeID, strings.ToLower(string(outcome)), dispute.DisputedAmount),
+			ReferenceType: ReferenceTypeChargeback,
+			ReferenceID:   dispute.DisputeID,
+			CreatedBy:     actor,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to post chargeback for dispute %s: %w", dispute.DisputeID, err)
+		}
+	}
+
+	reasonCode, err := ValidateReasonCode(dispute.ReasonCode)
+	if err != nil {
+		return fmt.Errorf("invalid reason code: %w", err)
+	}
+	covered, charged, err := chargedChargebackFees(ctx, tx, dispute.JournalEntryID, dispute.ID, dispute.CurrencyCode)
+	if err != nil {
+		return fmt.Errorf("failed to charge chargeback fee for dispute %s: %w", dispute.DisputeID, err)
+	}
+	if covered, err = covered.Add(dispute.DisputedAmount); err != nil {
+		return fmt.Errorf("failed to charge chargeback fee for dispute %s: %w", dispute.DisputeID, err)
+	}
+	fee, err := ds.quoteChargebackFee(ctx, dispute.MerchantID, reasonCode, dispute.OriginalAmount, covered, charged, dispute.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to charge chargeback fee for dispute %s: %w", dispute.DisputeID, err)
+	}
+	if fee == nil || fee.Amount.IsZero() {
+		return nil
+	}
+	fee.Base = dispute.DisputedAmount
+	err = ds.postOnce(ctx, tx, ledger.Transaction{
+		TransactionID: chargebackTransactionID(dispute.ID, "fee"),
+		Entries:       fee.Legs(accountID),
+		Description:   fmt.Sprintf("%s fee on %s", fee.FeeType, fee.Base),
+		ReferenceType: fees.ReferenceTypeFee,
+		ReferenceID:   dispute.DisputeID,
+		CreatedBy:     actor,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to charge chargeback fee for dispute %s: %w", dispute.DisputeID, err)
+	}
+
+	return nil
+}
+
+// postOnce posts txn within tx unless a transaction with its ID has been
+// posted already, such as by a resolution that committed its postings but
+// not its status
+func (ds *DisputesService) postOnce(ctx context.Context, tx pgx.Tx, txn ledger.Transaction) error {
+	var posted bool
+	err := tx.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM journal_entries WHERE transaction_id = $1)
+	`, txn.TransactionID).Scan(&posted)
+	if err != nil {
+		return fmt.Errorf("failed to check transaction %s: %w", txn.TransactionID, err)
+	}
+	if posted {
+		return nil
+	}
+	_, err = ds.ledger.PostTransactionTx(ctx, tx, txn)
+	return err
+}
+
+// chargebackLegs debit the merchant's account the disputed amount and credit
+// the chargeback settlement account
+func chargebackLegs(accountID, settlementAccountID string, amount money.Money) []ledger.JournalEntry {
+	return []ledger.JournalEntry{
+		{AccountID: accountID, EntryType: "debit", Amount: amount},
+		{AccountID: settlementAccountID, EntryType: "credit", Amount: amount},
+	}
+}
+
+// chargebackTransactionID is the ID of a transaction posted when a dispute is
+// resolved, derived from the dispute so a retried resolution is recognised
+// as posted
+func chargebackTransactionID(disputeUUID, kind string) string {
+	return uuid.NewSHA1(uuid.MustParse(disputeUUID), []byte(kind)).String()
+}
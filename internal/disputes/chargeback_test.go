@@ -0,0 +1,34 @@
+package disputes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/pci-infra/internal/money"
+)
+
+func TestChargebackLegsBalance(t *testing.T) {
+	legs := chargebackLegs("merchant-1", "settlement-1", money.MustParse("42.50", "USD"))
+	require.Len(t, legs, 2)
+	assert.Equal(t, "merchant-1", legs[0].AccountID)
+	assert.Equal(t, "debit", legs[0].EntryType)
+	assert.Equal(t, "settlement-1", legs[1].AccountID)
+	assert.Equal(t, "credit", legs[1].EntryType)
+	assert.True(t, legs[0].Amount.Equal(legs[1].Amount))
+}
+
+func TestChargebackTransactionIDIsStable(t *testing.T) {
+	id := "8a6e0804-2bd0-4672-b79d-d97027f9071a"
+	assert.Equal(t, chargebackTransactionID(id, "chargeback"), chargebackTransactionID(id, "chargeback"))
+	assert.NotEqual(t, chargebackTransactionID(id, "chargeback"), chargebackTransactionID(id, "fee"))
+	assert.NotEqual(t, chargebackTransactionID(id, "fee"), chargebackTransactionID("1b4e28ba-2fa1-11d2-883f-0016d3cca427", "fee"))
+}
+
+func TestEscalation(t *testing.T) {
+	assert.Equal(t, StatePreArbitration, escalation(StateRepresented))
+	assert.Equal(t, StateArbitration, escalation(StatePreArbitration))
+	assert.Equal(t, DisputeState(""), escalation(StateDisputed))
+	assert.Equal(t, DisputeState(""), escalation(StateArbitration))
+}
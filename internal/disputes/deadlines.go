@@ -0,0 +1,175 @@
+package disputes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// SystemActor is recorded as the creator of transitions made automatically,
+// such as those the deadline scheduler makes when a response window closes
+const SystemActor = "system"
+
+// DefaultDeadlineInterval is how often the deadline scheduler looks for
+// disputes whose response window has closed
+const DefaultDeadlineInterval = 15 * time.Minute
+
+// stageWindows are the days a dispute may stay in each stage of the
+// chargeback cycle before the card network's response window closes, by
+// brand. Stages without a window have no deadline.
+var stageWindows = map[CardBrand]map[DisputeState]int{
+	BrandVisa: {
+		StateDisputed:       30,
+		StateRepresented:    30,
+		StatePreArbitration: 30,
+		StateArbitration:    90,
+	},
+	BrandMastercard: {
+		StateDisputed:       45,
+		StateRepresented:    45,
+		StatePreArbitration: 30,
+		StateArbitration:    90,
+	},
+}
+
+// reasonCodeWindows override a brand's stage windows for reason codes the
+// network gives a shorter response window
+var reasonCodeWindows = map[string]map[DisputeState]int{
+	// Authorization and late presentment disputes are decided on the
+	// transaction record alone, so there is less time to respond
+	"10.2": {StateDisputed: 20},
+	"4808": {StateDisputed: 30},
+	"4842": {StateDisputed: 30},
+}
+
+// StageWindow is how long a dispute with reasonCode may stay in stage before
+// its deadline passes. It reports false for stages with no deadline and for
+// unknown reason codes.
+func StageWindow(reasonCode string, stage DisputeState) (time.Duration, bool) {
+	code, err := ValidateReasonCode(reasonCode)
+	if err != nil {
+		return 0, false
+	}
+	days, ok := reasonCodeWindows[code.Code][stage]
+	if !ok {
+		days, ok = stageWindows[code.Brand][stage]
+	}
+	if !ok {
+		return 0, false
+	}
+	return time.Duration(days) * 24 * time.Hour, true
+}
+
+// StageDueAt is when a dispute with reasonCode that entered stage at entered
+// must have moved on, or nil when the stage has no deadline
+func StageDueAt(reasonCode string, stage DisputeState, entered time.Time) *time.Time {
+	window, ok := StageWindow(reasonCode, stage)
+	if !ok {
+		return nil
+	}
+	due := entered.Add(window)
+	return &due
+}
+
+// DeadlineAction is the state a dispute in stage moves to when its deadline
+// passes, or "" when nothing happens automatically. A first chargeback the
+// merchant has not represented in time is accepted, and a pre-arbitration
+// left unanswered is escalated to arbitration. A represented dispute waits
+// on the issuer and one in arbitration on the network's ruling, so their
+// deadlines are tracked but not acted on.
+func DeadlineAction(stage DisputeState) DisputeState {
+	switch stage {
+	case StateDisputed:
+		return StateAccepted
+	case StatePreArbitration:
+		return StateArbitration
+	}
+	return ""
+}
+
+// RunDeadlines acts on every dispute whose stage deadline has passed and has
+// a DeadlineAction, recording each transition as made by SystemActor. A
+// dispute moved on by someone else in the meantime is skipped. It returns
+// how many disputes it moved.
+func (ds *DisputesService) RunDeadlines(ctx context.Context) (int, error) {
+	rows, err := ds.pool.Query(ctx, `
+		SELECT dispute_id, status
+		FROM disputes
+		WHERE stage_due_at <= CURRENT_TIMESTAMP
+		AND status IN ($1, $2)
+		ORDER BY stage_due_at
+	`, StateDisputed, StatePreArbitration)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list overdue disputes: %w", err)
+	}
+	type overdue struct {
+		disputeID string
+		status    DisputeState
+	}
+	var due []overdue
+	for rows.Next() {
+		var d overdue
+		if err := rows.Scan(&d.disputeID, &d.status); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan overdue dispute: %w", err)
+		}
+		due = append(due, d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to list overdue disputes: %w", err)
+	}
+
+	moved := 0
+	for _, d := range due {
+		to := DeadlineAction(d.status)
+		reason := fmt.Sprintf("%s response deadline passed", d.status)
+		_, err := ds.advance(ctx, d.disputeID, to, SystemActor, reason)
+		var invalid *InvalidStateTransitionError
+		switch {
+		case err == nil:
+			moved++
+		case errors.As(err, &invalid):
+		case ctx.Err() != nil:
+			return moved, ctx.Err()
+		default:
+			log.Printf("failed to act on deadline of dispute %s: %v", d.disputeID, err)
+		}
+	}
+	return moved, nil
+}
+
+// DeadlineScheduler accepts or escalates disputes as their stage deadlines
+// pass. Several schedulers may run at once: each dispute is locked while it
+// is moved, and one already moved is skipped.
+type DeadlineScheduler struct {
+	Service  *DisputesService
+	Interval time.Duration
+}
+
+// Run acts on passed deadlines now and every Interval until ctx is done
+func (s *DeadlineScheduler) Run(ctx context.Context) error {
+	interval := s.Interval
+	if interval <= 0 {
+		interval = DefaultDeadlineInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		n, err := s.Service.RunDeadlines(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("failed to run dispute deadlines: %v", err)
+		}
+		if n > 0 {
+			log.Printf("moved %d disputes past their deadline", n)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
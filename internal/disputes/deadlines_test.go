@@ -0,0 +1,68 @@
+package disputes
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStageWindow(t *testing.T) {
+	day := 24 * time.Hour
+	tests := []struct {
+		reasonCode string
+		stage      DisputeState
+		window     time.Duration
+		ok         bool
+	}{
+		{"13.1", StateDisputed, 30 * day, true},
+		{"13.1", StatePreArbitration, 30 * day, true},
+		{"13.1", StateArbitration, 90 * day, true},
+		{"4853", StateDisputed, 45 * day, true},
+		{"4853", StateRepresented, 45 * day, true},
+		{"4853", StatePreArbitration, 30 * day, true},
+		// reason codes with a shorter window override their brand's
+		{"10.2", StateDisputed, 20 * day, true},
+		{"10.2", StateRepresented, 30 * day, true},
+		{"4808", StateDisputed, 30 * day, true},
+		// no deadline before the chargeback or once resolved
+		{"13.1", StateSettled, 0, false},
+		{"13.1", StateAccepted, 0, false},
+		{"4853", StateWon, 0, false},
+		{"99.9", StateDisputed, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.reasonCode+" "+string(tt.stage), func(t *testing.T) {
+			window, ok := StageWindow(tt.reasonCode, tt.stage)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.window, window)
+		})
+	}
+}
+
+func TestStageDueAt(t *testing.T) {
+	entered := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	due := StageDueAt("4853", StateDisputed, entered)
+	require.NotNil(t, due)
+	assert.Equal(t, time.Date(2024, 4, 15, 12, 0, 0, 0, time.UTC), *due)
+
+	assert.Nil(t, StageDueAt("4853", StateLost, entered))
+}
+
+func TestDeadlineAction(t *testing.T) {
+	assert.Equal(t, StateAccepted, DeadlineAction(StateDisputed))
+	assert.Equal(t, StateArbitration, DeadlineAction(StatePreArbitration))
+	assert.Equal(t, DisputeState(""), DeadlineAction(StateRepresented))
+	assert.Equal(t, DisputeState(""), DeadlineAction(StateArbitration))
+	assert.Equal(t, DisputeState(""), DeadlineAction(StateWon))
+
+	// every automatic action is a transition the state machine allows
+	sm := NewStateMachine(nil)
+	for _, stage := range []DisputeState{StateDisputed, StatePreArbitration} {
+		_, ok := StageWindow("13.1", stage)
+		assert.True(t, ok, "stage %s acted on without a deadline", stage)
+		assert.True(t, sm.IsValidTransition(stage, DeadlineAction(stage)))
+	}
+}
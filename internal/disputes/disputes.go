@@ -1,5 +1,7 @@
 package disputes
 
+import "github.com/example/pci-infra/internal/money"
+
 // This file exports the main types and functions for the disputes module
 
 // Core types
@@ -44,7 +46,7 @@ const (
 )
 
 // Constructor functions
-func NewDisputesService(pool interface{}, ledger interface{}, reservePercentage float64) *DisputesService {
+func NewDisputesService(pool interface{}, ledger interface{}, fees interface{}, reservePercentage money.Rate) *DisputesService {
 	// This is a type assertion placeholder - in real usage, this would be properly typed
 	// The actual implementation is in service.go
 	return nil
@@ -0,0 +1,328 @@
+package disputes
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/example/pci-infra/internal/crypto"
+)
+
+// Evidence types
+const (
+	EvidenceTypeReceipt        = "receipt"
+	EvidenceTypeDeliveryProof  = "delivery_proof"
+	EvidenceTypeCorrespondence = "correspondence"
+	EvidenceTypeOther          = "other"
+)
+
+// MaxEvidenceSize is the largest evidence document accepted, in bytes. It
+// leaves room for the base64 encoding within the API's default 1 MiB body
+// limit.
+const MaxEvidenceSize = 512 << 10
+
+// evidenceMIMETypes are the document types accepted as evidence
+var evidenceMIMETypes = map[string]bool{
+	"application/pdf": true,
+	"image/png":       true,
+	"image/jpeg":      true,
+	"text/plain":      true,
+}
+
+var (
+	// ErrInvalidEvidence is returned when an evidence upload is malformed
+	ErrInvalidEvidence = errors.New("invalid evidence")
+	// ErrEvidenceTooLarge is returned when a document exceeds MaxEvidenceSize
+	ErrEvidenceTooLarge = errors.New("evidence too large")
+	// ErrUnsupportedEvidenceType is returned when a document's MIME type is not
+	// accepted, or its content is not of the type declared
+	ErrUnsupportedEvidenceType = errors.New("unsupported evidence MIME type")
+	// ErrEvidenceNotFound is returned when evidence does not exist for a dispute
+	ErrEvidenceNotFound = errors.New("evidence not found")
+	// ErrDisputeNotFound is returned when evidence is uploaded to or listed for
+	// a dispute that does not exist
+	ErrDisputeNotFound = errors.New("dispute not found")
+)
+
+// Evidence is a document submitted for a dispute. Its content is stored
+// encrypted and only returned by DownloadEvidence.
+type Evidence struct {
+	ID           string                 `json:"id"`
+	DisputeID    string                 `json:"dispute_id"`
+	EvidenceType string                 `json:"evidence_type"`
+	Description  string                 `json:"description"`
+	FileName     string                 `json:"file_name"`
+	MIMEType     string                 `json:"mime_type"`
+	SizeBytes    int64                  `json:"size_bytes"`
+	SHA256       string                 `json:"sha256"`
+	Metadata     map[string]interface{} `json:"metadata"`
+	UploadedBy   string                 `json:"uploaded_by"`
+	UploadedAt   time.Time              `json:"uploaded_at"`
+}
+
+// UploadEvidenceRequest represents the request to submit evidence for a
+// dispute. Metadata is masked with MaskPII before it is stored.
+type UploadEvidenceRequest struct {
+	DisputeID    string                 `json:"dispute_id"`
+	EvidenceType string                 `json:"evidence_type"`
+	Description  string                 `json:"description"`
+	FileName     string                 `json:"file_name"`
+	MIMEType     string                 `json:"mime_type"`
+	Content      []byte                 `json:"content"`
+	Metadata     map[string]interface{} `json:"metadata"`
+	UploadedBy   string                 `json:"uploaded_by"`
+}
+
+// EvidenceStore stores dispute evidence encrypted under a KMS master key
+type EvidenceStore struct {
+	pool      *pgxpool.Pool
+	encryptor *crypto.AEADEncryptor
+	keyID     string
+}
+
+// NewEvidenceStore creates an evidence store encrypting documents under the
+// master key keyID
+func NewEvidenceStore(pool *pgxpool.Pool, encryptor *crypto.AEADEncryptor, keyID string) *EvidenceStore {
+	return &EvidenceStore{
+		pool:      pool,
+		encryptor: encryptor,
+		keyID:     keyID,
+	}
+}
+
+// validEvidenceType reports whether t is a known evidence type
+func validEvidenceType(t string) bool {
+	switch t {
+	case EvidenceTypeReceipt, EvidenceTypeDeliveryProof, EvidenceTypeCorrespondence, EvidenceTypeOther:
+		return true
+	}
+	return false
+}
+
+// validateEvidence checks an upload and returns its normalised MIME type. The
+// declared type must be accepted and match the type sniffed from the content.
+func validateEvidence(req UploadEvidenceRequest) (string, error) {
+	if req.DisputeID == "" {
+		return "", fmt.Errorf("%w: dispute_id is required", ErrInvalidEvidence)
+	}
+	if !validEvidenceType(req.EvidenceType) {
+		return "", fmt.Errorf("%w: unknown evidence type %q", ErrInvalidEvidence, req.EvidenceType)
+	}
+	if strings.TrimSpace(req.Description) == "" {
+		return "", fmt.Errorf("%w: description is required", ErrInvalidEvidence)
+	}
+	if req.UploadedBy == "" {
+		return "", fmt.Errorf("%w: uploaded_by is required", ErrInvalidEvidence)
+	}
+	if err := validateFileName(req.FileName); err != nil {
+		return "", err
+	}
+	if len(req.Content) == 0 {
+		return "", fmt.Errorf("%w: content is empty", ErrInvalidEvidence)
+	}
+	if len(req.Content) > MaxEvidenceSize {
+		return "", fmt.Errorf("%w: %d bytes exceeds %d", ErrEvidenceTooLarge, len(req.Content), MaxEvidenceSize)
+	}
+
+	declared, _, err := mime.ParseMediaType(req.MIMEType)
+	if err != nil || !evidenceMIMETypes[declared] {
+		return "", fmt.Errorf("%w: %q", ErrUnsupportedEvidenceType, req.MIMEType)
+	}
+	sniffed, _, _ := mime.ParseMediaType(http.DetectContentType(req.Content))
+	if sniffed != declared {
+		return "", fmt.Errorf("%w: content is %s, declared %s", ErrUnsupportedEvidenceType, sniffed, declared)
+	}
+	return declared, nil
+}
+
+// validateFileName checks that name is a bare file name safe to return in a
+// Content-Disposition header
+func validateFileName(name string) error {
+	if name == "" || len(name) > 255 {
+		return fmt.Errorf("%w: file_name must be 1 to 255 bytes", ErrInvalidEvidence)
+	}
+	if name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("%w: file_name must not be a path", ErrInvalidEvidence)
+	}
+	for _, r := range name {
+		if r == '"' || unicode.IsControl(r) {
+			return fmt.Errorf("%w: file_name must not contain quotes or control characters", ErrInvalidEvidence)
+		}
+	}
+	return nil
+}
+
+// evidenceAAD binds a document's ciphertext to its dispute and evidence row
+func evidenceAAD(disputeUUID, evidenceID string) []byte {
+	return []byte("dispute_evidence:" + disputeUUID + ":" + evidenceID)
+}
+
+// UploadEvidence encrypts and stores a document for a dispute
+func (es *EvidenceStore) UploadEvidence(ctx context.Context, req UploadEvidenceRequest) (*Evidence, error) {
+	mimeType, err := validateEvidence(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var disputeUUID string
+	err = es.pool.QueryRow(ctx, `SELECT id FROM disputes WHERE dispute_id = $1`, req.DisputeID).Scan(&disputeUUID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrDisputeNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query dispute: %w", err)
+	}
+
+	digest := sha256.Sum256(req.Content)
+	evidence := &Evidence{
+		ID:           uuid.New().String(),
+		DisputeID:    req.DisputeID,
+		EvidenceType: req.EvidenceType,
+		Description:  req.Description,
+		FileName:     req.FileName,
+		MIMEType:     mimeType,
+		SizeBytes:    int64(len(req.Content)),
+		SHA256:       hex.EncodeToString(digest[:]),
+		Metadata:     MaskPII(req.Metadata),
+		UploadedBy:   req.UploadedBy,
+	}
+	if evidence.Metadata == nil {
+		evidence.Metadata = map[string]interface{}{}
+	}
+	metadataJSON, err := json.Marshal(evidence.Metadata)
+	if err != nil {
+		return nil, fmt.Errorf("%w: metadata: %v", ErrInvalidEvidence, err)
+	}
+
+	enc, err := es.encryptor.Encrypt(ctx, req.Content, es.keyID, evidenceAAD(disputeUUID, evidence.ID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt evidence: %w", err)
+	}
+
+	err = es.pool.QueryRow(ctx, `
+		INSERT INTO dispute_evidence (
+			id, dispute_id, evidence_type, description, file_name, mime_type,
+			size_bytes, sha256, ciphertext, encrypted_key, nonce, key_id,
+			metadata, uploaded_by
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		RETURNING uploaded_at
+	`, evidence.ID, disputeUUID, evidence.EvidenceType, evidence.Description, evidence.FileName,
+		evidence.MIMEType, evidence.SizeBytes, evidence.SHA256, enc.Ciphertext, enc.EncryptedDataKey,
+		enc.Nonce, enc.KeyID, metadataJSON, evidence.UploadedBy,
+	).Scan(&evidence.UploadedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store evidence: %w", err)
+	}
+
+	return evidence, nil
+}
+
+// ListEvidence lists the evidence submitted for a dispute, oldest first
+func (es *EvidenceStore) ListEvidence(ctx context.Context, disputeID string) ([]*Evidence, error) {
+	var exists bool
+	if err := es.pool.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM disputes WHERE dispute_id = $1)`, disputeID).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to query dispute: %w", err)
+	}
+	if !exists {
+		return nil, ErrDisputeNotFound
+	}
+
+	rows, err := es.pool.Query(ctx, `
+		SELECT e.id, d.dispute_id, e.evidence_type, e.description, e.file_name,
+		       e.mime_type, e.size_bytes, e.sha256, e.metadata, e.uploaded_by,
+		       e.uploaded_at
+		FROM dispute_evidence e
+		JOIN disputes d ON d.id = e.dispute_id
+		WHERE d.dispute_id = $1
+		ORDER BY e.uploaded_at, e.id
+	`, disputeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query evidence: %w", err)
+	}
+	defer rows.Close()
+
+	evidence := []*Evidence{}
+	for rows.Next() {
+		e, err := scanEvidence(rows)
+		if err != nil {
+			return nil, err
+		}
+		evidence = append(evidence, e)
+	}
+	return evidence, rows.Err()
+}
+
+// DownloadEvidence returns a piece of evidence with its decrypted content
+func (es *EvidenceStore) DownloadEvidence(ctx context.Context, disputeID, evidenceID string) (*Evidence, []byte, error) {
+	if _, err := uuid.Parse(evidenceID); err != nil {
+		return nil, nil, ErrEvidenceNotFound
+	}
+
+	var disputeUUID string
+	var metadataJSON []byte
+	e := &Evidence{}
+	enc := &crypto.EncryptedData{}
+	err := es.pool.QueryRow(ctx, `
+		SELECT e.id, d.dispute_id, e.evidence_type, e.description, e.file_name,
+		       e.mime_type, e.size_bytes, e.sha256, e.metadata, e.uploaded_by,
+		       e.uploaded_at, d.id, e.ciphertext, e.encrypted_key, e.nonce, e.key_id
+		FROM dispute_evidence e
+		JOIN disputes d ON d.id = e.dispute_id
+		WHERE d.dispute_id = $1 AND e.id = $2
+	`, disputeID, evidenceID).Scan(
+		&e.ID, &e.DisputeID, &e.EvidenceType, &e.Description, &e.FileName,
+		&e.MIMEType, &e.SizeBytes, &e.SHA256, &metadataJSON, &e.UploadedBy,
+		&e.UploadedAt, &disputeUUID, &enc.Ciphertext, &enc.EncryptedDataKey, &enc.Nonce, &enc.KeyID,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil, ErrEvidenceNotFound
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query evidence: %w", err)
+	}
+	if err := json.Unmarshal(metadataJSON, &e.Metadata); err != nil {
+		return nil, nil, fmt.Errorf("failed to read evidence metadata: %w", err)
+	}
+
+	enc.AdditionalData = evidenceAAD(disputeUUID, e.ID)
+	content, err := es.encryptor.Decrypt(ctx, enc)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decrypt evidence %s: %w", e.ID, err)
+	}
+	digest := sha256.Sum256(content)
+	if hex.EncodeToString(digest[:]) != e.SHA256 {
+		return nil, nil, fmt.Errorf("evidence %s does not match its digest", e.ID)
+	}
+
+	return e, content, nil
+}
+
+// scanEvidence scans the listed columns of an evidence row
+func scanEvidence(row pgx.Row) (*Evidence, error) {
+	e := &Evidence{}
+	var metadataJSON []byte
+	err := row.Scan(
+		&e.ID, &e.DisputeID, &e.EvidenceType, &e.Description, &e.FileName,
+		&e.MIMEType, &e.SizeBytes, &e.SHA256, &metadataJSON, &e.UploadedBy,
+		&e.UploadedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan evidence: %w", err)
+	}
+	if err := json.Unmarshal(metadataJSON, &e.Metadata); err != nil {
+		return nil, fmt.Errorf("failed to read evidence metadata: %w", err)
+	}
+	return e, nil
+}
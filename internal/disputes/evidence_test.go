@@ -0,0 +1,105 @@
+package disputes
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/pci-infra/internal/crypto"
+)
+
+var (
+	pdfContent = []byte("%PDF-1.4\n1 0 obj\n<< /Type /Catalog >>\nendobj\n%%EOF\n")
+	pngContent = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x00\x01\x00\x00\x00\x01")
+)
+
+func TestValidateEvidence(t *testing.T) {
+	valid := UploadEvidenceRequest{
+		DisputeID:    "DSP-1",
+		EvidenceType: EvidenceTypeReceipt,
+		Description:  "Signed delivery receipt",
+		FileName:     "receipt.pdf",
+		MIMEType:     "application/pdf",
+		Content:      pdfContent,
+		UploadedBy:   "merchant-ops",
+	}
+	mimeType, err := validateEvidence(valid)
+	require.NoError(t, err)
+	assert.Equal(t, "application/pdf", mimeType)
+
+	tests := []struct {
+		name   string
+		modify func(*UploadEvidenceRequest)
+		target error
+	}{
+		{"image", func(r *UploadEvidenceRequest) { r.MIMEType, r.Content = "image/png", pngContent }, nil},
+		{"text with parameters", func(r *UploadEvidenceRequest) {
+			r.MIMEType, r.Content = "text/plain; charset=utf-8", []byte("Customer confirmed receipt by email.")
+		}, nil},
+		{"missing dispute", func(r *UploadEvidenceRequest) { r.DisputeID = "" }, ErrInvalidEvidence},
+		{"unknown type", func(r *UploadEvidenceRequest) { r.EvidenceType = "invoice" }, ErrInvalidEvidence},
+		{"blank description", func(r *UploadEvidenceRequest) { r.Description = "  " }, ErrInvalidEvidence},
+		{"missing uploader", func(r *UploadEvidenceRequest) { r.UploadedBy = "" }, ErrInvalidEvidence},
+		{"path as file name", func(r *UploadEvidenceRequest) { r.FileName = "../receipt.pdf" }, ErrInvalidEvidence},
+		{"header injection in file name", func(r *UploadEvidenceRequest) { r.FileName = "a.pdf\r\nX-Evil: 1" }, ErrInvalidEvidence},
+		{"empty content", func(r *UploadEvidenceRequest) { r.Content = nil }, ErrInvalidEvidence},
+		{"too large", func(r *UploadEvidenceRequest) {
+			r.Content = append(append([]byte{}, pdfContent...), bytes.Repeat([]byte{' '}, MaxEvidenceSize)...)
+		}, ErrEvidenceTooLarge},
+		{"unaccepted type", func(r *UploadEvidenceRequest) { r.MIMEType = "application/zip" }, ErrUnsupportedEvidenceType},
+		{"malformed type", func(r *UploadEvidenceRequest) { r.MIMEType = "pdf" }, ErrUnsupportedEvidenceType},
+		{"content not as declared", func(r *UploadEvidenceRequest) { r.MIMEType = "image/png" }, ErrUnsupportedEvidenceType},
+		{"html declared as text", func(r *UploadEvidenceRequest) {
+			r.MIMEType, r.Content = "text/plain", []byte("<html><script>alert(1)</script></html>")
+		}, ErrUnsupportedEvidenceType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := valid
+			tt.modify(&r)
+			_, err := validateEvidence(r)
+			if tt.target == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.True(t, errors.Is(err, tt.target), "got %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateFileName(t *testing.T) {
+	assert.NoError(t, validateFileName("delivery proof (signed).jpg"))
+	assert.Error(t, validateFileName(""))
+	assert.Error(t, validateFileName(".."))
+	assert.Error(t, validateFileName(`C:\receipt.pdf`))
+	assert.Error(t, validateFileName(`receipt".pdf`))
+	assert.Error(t, validateFileName(strings.Repeat("a", 256)))
+}
+
+func TestEvidenceAADBindsCiphertext(t *testing.T) {
+	kms, err := crypto.NewFileBasedKMS(crypto.FileBasedKMSConfig{KeyStorePath: t.TempDir()})
+	require.NoError(t, err)
+	encryptor := crypto.NewAEADEncryptor(kms)
+	ctx := context.Background()
+
+	disputeUUID := "8a6e0804-2bd0-4672-b79d-d97027f9071a"
+	evidenceID := "1b4e28ba-2fa1-11d2-883f-0016d3cca427"
+	enc, err := encryptor.Encrypt(ctx, pdfContent, "test-key-1", evidenceAAD(disputeUUID, evidenceID))
+	require.NoError(t, err)
+	assert.False(t, bytes.Contains(enc.Ciphertext, pdfContent))
+
+	enc.AdditionalData = evidenceAAD(disputeUUID, evidenceID)
+	content, err := encryptor.Decrypt(ctx, enc)
+	require.NoError(t, err)
+	assert.Equal(t, pdfContent, content)
+
+	// A ciphertext moved onto another dispute's evidence does not decrypt
+	enc.AdditionalData = evidenceAAD("5f0c3b1e-9d2a-4b7c-8e6f-1a2b3c4d5e6f", evidenceID)
+	_, err = encryptor.Decrypt(ctx, enc)
+	assert.Error(t, err)
+}
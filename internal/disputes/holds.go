@@ -0,0 +1,405 @@
+package disputes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/example/pci-infra/internal/ledger"
+	"github.com/example/pci-infra/internal/money"
+)
+
+// DefaultHoldSweepInterval is how often the hold sweeper looks for expired
+// holds
+const DefaultHoldSweepInterval = 15 * time.Minute
+
+// HoldExpiryPeriod is how long a hold lasts unless it is extended
+const HoldExpiryPeriod = 30 * 24 * time.Hour
+
+// ReferenceTypeHold marks the postings that move held funds to the hold
+// account and back
+const ReferenceTypeHold = "hold"
+
+var (
+	// ErrHoldNotFound is returned when a referenced hold does not exist
+	ErrHoldNotFound = errors.New("hold not found")
+
+	// ErrHoldNotActive is returned when extending or releasing a hold that
+	// has already been released or converted
+	ErrHoldNotActive = errors.New("hold not active")
+
+	// ErrInvalidHoldAction is returned when extending or releasing a hold
+	// without an actor or reason, or to an expiry that is not later
+	ErrInvalidHoldAction = errors.New("invalid hold action")
+)
+
+// holdColumns are the holds columns scanHold reads, in order
+const holdColumns = `
+	id, hold_id, dispute_id, account_id, held_amount, currency_code, status,
+	expires_at, created_at, created_by, released_at, COALESCE(released_by, '')
+`
+
+// scanHold reads a holds row selected with holdColumns, followed by any
+// extra columns
+func scanHold(row pgx.Row, extra ...interface{}) (*Hold, error) {
+	hold := &Hold{}
+	var held pgtype.Numeric
+	err := row.Scan(append([]interface{}{
+		&hold.ID, &hold.HoldID, &hold.DisputeID, &hold.AccountID, &held, &hold.CurrencyCode, &hold.Status,
+		&hold.ExpiresAt, &hold.CreatedAt, &hold.CreatedBy, &hold.ReleasedAt, &hold.ReleasedBy,
+	}, extra...)...)
+	if err != nil {
+		return nil, err
+	}
+	if hold.HeldAmount, err = money.FromNumeric(held, hold.CurrencyCode); err != nil {
+		return nil, fmt.Errorf("failed to read held amount: %w", err)
+	}
+	return hold, nil
+}
+
+// placeHold holds amount of accountID for a dispute until expiresAt. When the
+// currency has a hold account the amount is posted there through the ledger,
+// within tx; otherwise the hold only reduces the available balance.
+func (ds *DisputesService) placeHold(ctx context.Context, tx pgx.Tx, dispute *Dispute, accountID string, amount money.Money, expiresAt time.Time) error {
+	hold := &Hold{
+		ID:           uuid.New().String(),
+		HoldID:       fmt.Sprintf("HLD-%s", time.Now().Format("20060102-")) + uuid.New().String()[:8],
+		DisputeID:    dispute.ID,
+		AccountID:    accountID,
+		HeldAmount:   amount,
+		CurrencyCode: dispute.CurrencyCode,
+	}
+
+	var holdAccountID string
+	err := tx.QueryRow(ctx, `
+		SELECT hold_account_id::text FROM hold_accounts WHERE currency_code = $1
+	`, hold.CurrencyCode).Scan(&holdAccountID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("failed to get hold account: %w", err)
+	}
+	if holdAccountID != "" {
+		err := ds.postHold(ctx, tx, hold, accountID, holdAccountID, "Funds held for dispute: "+dispute.ID, dispute.CreatedBy)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO holds (
+			id, hold_id, dispute_id, account_id, held_amount, currency_code,
+			status, expires_at, created_by, prev_hold_hash, hold_account_id
+		) VALUES ($1, $2, $3, $4, $5, $6, 'ACTIVE', $7, $8, '', NULLIF($9, '')::uuid)
+	`, hold.ID, hold.HoldID, hold.DisputeID, accountID, amount, hold.CurrencyCode, expiresAt, dispute.CreatedBy, holdAccountID)
+	if err != nil {
+		return fmt.Errorf("failed to create hold: %w", err)
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO hold_events (hold_id, action, expires_at, reason, created_by)
+		VALUES ($1, 'placed', $2, 'Dispute authorized', $3)
+	`, hold.ID, expiresAt, dispute.CreatedBy)
+	if err != nil {
+		return fmt.Errorf("failed to record hold placement: %w", err)
+	}
+	return nil
+}
+
+// releaseHoldIn releases an active hold within tx, posting a posted hold's
+// amount back to the merchant through the ledger. It returns false, doing
+// nothing, when the hold is no longer ACTIVE.
+func (ds *DisputesService) releaseHoldIn(ctx context.Context, tx pgx.Tx, id, releasedBy, reason string) (bool, error) {
+	var holdAccountID string
+	hold, err := scanHold(tx.QueryRow(ctx, `
+		SELECT `+holdColumns+`, COALESCE(hold_account_id::text, '')
+		FROM holds WHERE id = $1 FOR UPDATE
+	`, id), &holdAccountID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to lock hold: %w", err)
+	}
+	if hold.Status != "ACTIVE" {
+		return false, nil
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE holds
+		SET status = 'RELEASED', released_at = CURRENT_TIMESTAMP, released_by = $2
+		WHERE id = $1
+	`, hold.ID, releasedBy)
+	if err != nil {
+		return false, fmt.Errorf("failed to release hold: %w", err)
+	}
+	if holdAccountID != "" {
+		if err := ds.postHold(ctx, tx, hold, holdAccountID, hold.AccountID, "Hold released: "+reason, releasedBy); err != nil {
+			return false, err
+		}
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO hold_events (hold_id, action, expires_at, reason, created_by)
+		VALUES ($1, 'released', $2, $3, $4)
+	`, hold.ID, hold.ExpiresAt, reason, releasedBy)
+	if err != nil {
+		return false, fmt.Errorf("failed to record hold release: %w", err)
+	}
+	return true, nil
+}
+
+// releaseDisputeHolds releases the active holds of a dispute that has been
+// resolved with outcome
+func (ds *DisputesService) releaseDisputeHolds(ctx context.Context, tx pgx.Tx, dispute *Dispute, outcome DisputeState, actor string) error {
+	rows, err := tx.Query(ctx, `
+		SELECT id FROM holds WHERE dispute_id = $1 AND status = 'ACTIVE' ORDER BY created_at
+	`, dispute.ID)
+	if err != nil {
+		return fmt.Errorf("failed to list holds: %w", err)
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return fmt.Errorf("failed to list holds: %w", err)
+	}
+
+	reason := fmt.Sprintf("dispute %s: %s", strings.ToLower(string(outcome)), dispute.DisputeID)
+	for _, id := range ids {
+		if _, err := ds.releaseHoldIn(ctx, tx, id, actor, reason); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// postHold moves a hold's amount from one account to another through the
+// ledger, within tx, so it meets the account status, period and credit limit
+// checks of any other posting and is published as transaction.posted
+func (ds *DisputesService) postHold(ctx context.Context, tx pgx.Tx, hold *Hold, fromAccountID, toAccountID, description, actor string) error {
+	_, err := ds.ledger.PostTransactionTx(ctx, tx, ledger.Transaction{
+		Entries:       holdLegs(fromAccountID, toAccountID, hold.HeldAmount),
+		Description:   description,
+		ReferenceType: ReferenceTypeHold,
+		ReferenceID:   hold.HoldID,
+		CreatedBy:     actor,
+		Metadata:      map[string]interface{}{"dispute_id": hold.DisputeID, "hold_id": hold.ID},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to post hold %s: %w", hold.HoldID, err)
+	}
+	return nil
+}
+
+// holdLegs debit the account funds are held from, or released from, and
+// credit the account they move to
+func holdLegs(fromAccountID, toAccountID string, amount money.Money) []ledger.JournalEntry {
+	return []ledger.JournalEntry{
+		{AccountID: fromAccountID, EntryType: "debit", Amount: amount},
+		{AccountID: toAccountID, EntryType: "credit", Amount: amount},
+	}
+}
+
+// lockHold locks an active hold for a change by an administrator
+func lockHold(ctx context.Context, tx pgx.Tx, holdID string) (*Hold, error) {
+	hold, err := scanHold(tx.QueryRow(ctx, `SELECT `+holdColumns+` FROM holds WHERE hold_id = $1 FOR UPDATE`, holdID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrHoldNotFound, holdID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get hold: %w", err)
+	}
+	if hold.Status != "ACTIVE" {
+		return nil, fmt.Errorf("%w: %s is %s", ErrHoldNotActive, holdID, hold.Status)
+	}
+	return hold, nil
+}
+
+// checkExtension checks that a hold can be extended to expiresAt at now
+func checkExtension(hold *Hold, expiresAt, now time.Time) error {
+	if !expiresAt.After(hold.ExpiresAt) {
+		return fmt.Errorf("%w: new expiry %s is not after %s", ErrInvalidHoldAction,
+			expiresAt.Format(time.RFC3339), hold.ExpiresAt.Format(time.RFC3339))
+	}
+	if !expiresAt.After(now) {
+		return fmt.Errorf("%w: new expiry %s has passed", ErrInvalidHoldAction, expiresAt.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// checkHoldAction checks a change to a hold names who made it and why
+func checkHoldAction(actor, reason string) error {
+	if actor == "" {
+		return fmt.Errorf("%w: actor is required", ErrInvalidHoldAction)
+	}
+	if strings.TrimSpace(reason) == "" {
+		return fmt.Errorf("%w: reason is required", ErrInvalidHoldAction)
+	}
+	return nil
+}
+
+// ExtendHold moves an active hold's expiry later, for a dispute that will
+// outlast it
+func (ds *DisputesService) ExtendHold(ctx context.Context, holdID string, expiresAt time.Time, extendedBy, reason string) (*Hold, error) {
+	if err := checkHoldAction(extendedBy, reason); err != nil {
+		return nil, err
+	}
+
+	tx, err := ds.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	hold, err := lockHold(ctx, tx, holdID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkExtension(hold, expiresAt, time.Now()); err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE holds SET expires_at = $2 WHERE id = $1`, hold.ID, expiresAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extend hold: %w", err)
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO hold_events (hold_id, action, previous_expires_at, expires_at, reason, created_by)
+		VALUES ($1, 'extended', $2, $3, $4, $5)
+	`, hold.ID, hold.ExpiresAt, expiresAt, reason, extendedBy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record hold extension: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	hold.ExpiresAt = expiresAt
+	return hold, nil
+}
+
+// ReleaseHold releases an active hold before it expires, moving a posted
+// hold's amount back to the merchant
+func (ds *DisputesService) ReleaseHold(ctx context.Context, holdID, releasedBy, reason string) (*Hold, error) {
+	if err := checkHoldAction(releasedBy, reason); err != nil {
+		return nil, err
+	}
+
+	tx, err := ds.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	hold, err := lockHold(ctx, tx, holdID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := ds.releaseHoldIn(ctx, tx, hold.ID, releasedBy, reason); err != nil {
+		return nil, err
+	}
+
+	hold, err = scanHold(tx.QueryRow(ctx, `SELECT `+holdColumns+` FROM holds WHERE id = $1`, hold.ID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get hold: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return hold, nil
+}
+
+// ReleaseExpiredHolds releases every active hold past its expiry, each in its
+// own transaction. Holds locked by another sweeper are skipped, so several
+// may run at once, and a hold that fails to release is logged and left for
+// the next run. It returns how many holds it released.
+func (ds *DisputesService) ReleaseExpiredHolds(ctx context.Context) (int, error) {
+	released := 0
+	failed := []string{}
+	for {
+		id, err := ds.releaseNextExpiredHold(ctx, failed)
+		switch {
+		case err == nil && id == "":
+			return released, nil
+		case err == nil:
+			released++
+		case ctx.Err() != nil:
+			return released, ctx.Err()
+		case id == "":
+			return released, err
+		default:
+			log.Printf("failed to release expired hold %s: %v", id, err)
+			failed = append(failed, id)
+		}
+	}
+}
+
+// releaseNextExpiredHold releases the longest expired hold that no other
+// sweeper has locked and is not in skip. It returns the hold's ID, or "" when
+// there is none.
+func (ds *DisputesService) releaseNextExpiredHold(ctx context.Context, skip []string) (string, error) {
+	tx, err := ds.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var id string
+	err = tx.QueryRow(ctx, `
+		SELECT id FROM holds
+		WHERE status = 'ACTIVE' AND expires_at <= CURRENT_TIMESTAMP
+		AND NOT (id::text = ANY($1))
+		ORDER BY expires_at
+		LIMIT 1
+		FOR UPDATE SKIP LOCKED
+	`, skip).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to find expired hold: %w", err)
+	}
+
+	if _, err := ds.releaseHoldIn(ctx, tx, id, SystemActor, "hold expired"); err != nil {
+		return id, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return id, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return id, nil
+}
+
+// HoldSweeper releases dispute holds as they expire
+type HoldSweeper struct {
+	Service  *DisputesService
+	Interval time.Duration
+}
+
+// Run releases expired holds now and every Interval until ctx is done
+func (s *HoldSweeper) Run(ctx context.Context) error {
+	interval := s.Interval
+	if interval <= 0 {
+		interval = DefaultHoldSweepInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		n, err := s.Service.ReleaseExpiredHolds(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("failed to release expired holds: %v", err)
+		}
+		if n > 0 {
+			log.Printf("released %d expired holds", n)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package disputes
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/example/pci-infra/internal/money"
+)
+
+func TestCheckExtension(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	hold := &Hold{HoldID: "HLD-1", Status: "ACTIVE", ExpiresAt: now.Add(24 * time.Hour)}
+
+	assert.NoError(t, checkExtension(hold, hold.ExpiresAt.Add(30*24*time.Hour), now))
+
+	err := checkExtension(hold, hold.ExpiresAt, now)
+	assert.True(t, errors.Is(err, ErrInvalidHoldAction), "got %v", err)
+	err = checkExtension(hold, now.Add(time.Hour), now)
+	assert.True(t, errors.Is(err, ErrInvalidHoldAction), "got %v", err)
+
+	// an expired hold the sweeper has not reached yet can be extended, but
+	// not to another time in the past
+	expired := &Hold{HoldID: "HLD-2", Status: "ACTIVE", ExpiresAt: now.Add(-48 * time.Hour)}
+	assert.NoError(t, checkExtension(expired, now.Add(time.Hour), now))
+	err = checkExtension(expired, now.Add(-time.Hour), now)
+	assert.True(t, errors.Is(err, ErrInvalidHoldAction), "got %v", err)
+}
+
+func TestCheckHoldAction(t *testing.T) {
+	assert.NoError(t, checkHoldAction("risk-ops", "Arbitration pending"))
+	assert.True(t, errors.Is(checkHoldAction("", "Arbitration pending"), ErrInvalidHoldAction))
+	assert.True(t, errors.Is(checkHoldAction("risk-ops", "  "), ErrInvalidHoldAction))
+}
+
+func TestHoldLegs(t *testing.T) {
+	amount := money.MustParse("25.00", "USD")
+	legs := holdLegs("merchant", "holds", amount)
+
+	assert.Len(t, legs, 2)
+	assert.Equal(t, "merchant", legs[0].AccountID)
+	assert.Equal(t, "debit", legs[0].EntryType)
+	assert.Equal(t, "holds", legs[1].AccountID)
+	assert.Equal(t, "credit", legs[1].EntryType)
+	assert.True(t, legs[0].Amount.Equal(amount) && legs[1].Amount.Equal(amount))
+}
@@ -14,6 +14,10 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
+
+	"github.com/example/pci-infra/internal/fees"
+	"github.com/example/pci-infra/internal/ledger"
+	"github.com/example/pci-infra/internal/money"
 )
 
 // IntegrationTestSuite tests the complete disputes workflow
@@ -73,8 +77,13 @@ func (m *MockLedgerService) PostJournalEntry(ctx context.Context, entry *Journal
 	return nil
 }
 
-func (m *MockLedgerService) GetBalance(ctx context.Context, accountID string) (float64, error) {
-	return 1000.0, nil // Mock balance
+func (m *MockLedgerService) PostTransactionTx(ctx context.Context, tx pgx.Tx, txn ledger.Transaction) (*ledger.Transaction, error) {
+	// Mock implementation - accept the transaction as posted
+	return &txn, nil
+}
+
+func (m *MockLedgerService) GetBalance(ctx context.Context, accountID string) (money.Money, error) {
+	return money.MustParse("1000.00", "USD"), nil // Mock balance
 }
 
 // SetupSuite creates test database and services
@@ -92,8 +101,9 @@ func (suite *IntegrationTestSuite) SetupSuite() {
 	suite.service = &DisputesService{
 		pool:             nil, // Would be set in real test with actual DB pool
 		ledger:           suite.ledger,
+		fees:             stubFeeQuoter{},
 		stateMachine:     suite.sm,
-		reservePercentage: 0.05, // 5% reserve
+		reservePercentage: money.MustParseRate("0.05"), // 5% reserve
 	}
 }
 
@@ -109,7 +119,7 @@ func (suite *IntegrationTestSuite) TestCompleteDisputeWorkflow() {
 	createReq := CreateDisputeRequest{
 		JournalEntryID:  journalEntryID,
 		MerchantID:      merchantID,
-		DisputedAmount:  100.0,
+		DisputedAmount:  money.MustParse("100.00", "USD"),
 		CurrencyCode:    "USD",
 		ReasonCode:      "14.1", // Visa Cardholder Dispute - Fraud
 		ReasonText:      "Cardholder does not recognize transaction",
@@ -128,7 +138,7 @@ func (suite *IntegrationTestSuite) TestCompleteDisputeWorkflow() {
 	
 	// Verify dispute was created with correct properties
 	suite.Equal("PENDING", string(dispute.Status))
-	suite.Equal(100.0, dispute.DisputedAmount)
+	suite.Equal(money.MustParse("100.00", "USD"), dispute.DisputedAmount)
 	suite.Equal("14.1", dispute.ReasonCode)
 	suite.True(dispute.IsFraud) // Based on reason code
 	suite.NotEmpty(dispute.DisputeID)
@@ -198,7 +208,7 @@ func (suite *IntegrationTestSuite) TestConcurrentDisputes() {
 			req := CreateDisputeRequest{
 				JournalEntryID:  journalEntryID,
 				MerchantID:      merchantID,
-				DisputedAmount:  50.0 + float64(index)*10.0,
+				DisputedAmount:  money.MustNew(int64(5000+index*1000), "USD"),
 				CurrencyCode:    "USD",
 				ReasonCode:      "14.1",
 				ReasonText:      "Concurrent dispute",
@@ -233,19 +243,20 @@ func (suite *IntegrationTestSuite) TestFraudReserveCalculation() {
 	
 	// Test reserve calculation for different volumes
 	testCases := []struct {
-		volume     float64
-		reservePct float64
-		expected   float64
+		volume     money.Money
+		reservePct money.Rate
+		expected   money.Money
 	}{
-		{1000.0, 0.05, 50.0},
-		{10000.0, 0.05, 500.0},
-		{100000.0, 0.05, 5000.0},
+		{money.MustParse("1000.00", "USD"), money.MustParseRate("0.05"), money.MustParse("50.00", "USD")},
+		{money.MustParse("10000.00", "USD"), money.MustParseRate("0.05"), money.MustParse("500.00", "USD")},
+		{money.MustParse("100000.00", "USD"), money.MustParseRate("0.05"), money.MustParse("5000.00", "USD")},
+		{money.MustParse("0.30", "USD"), money.MustParseRate("0.05"), money.MustParse("0.02", "USD")}, // 0.015 rounds half away from zero
 	}
 	
 	for _, tc := range testCases {
 		actual, err := suite.service.CalculateMerchantReserve(ctx, merchantID, tc.volume)
 		suite.NoError(err)
-		suite.Equal(tc.expected, actual, "Reserve calculation incorrect for volume %f", tc.volume)
+		suite.Equal(tc.expected, actual, "Reserve calculation incorrect for volume %s", tc.volume)
 	}
 }
 
@@ -257,7 +268,7 @@ func (suite *IntegrationTestSuite) TestPIIMaskingInAuditLog() {
 	createReq := CreateDisputeRequest{
 		JournalEntryID:  uuid.NewString(),
 		MerchantID:      uuid.NewString(),
-		DisputedAmount:  100.0,
+		DisputedAmount:  money.MustParse("100.00", "USD"),
 		CurrencyCode:    "USD",
 		ReasonCode:      "14.1",
 		ReasonText:      "Cardholder dispute",
@@ -297,7 +308,7 @@ func (suite *IntegrationTestSuite) TestInvalidStateTransitions() {
 	createReq := CreateDisputeRequest{
 		JournalEntryID:  uuid.NewString(),
 		MerchantID:      uuid.NewString(),
-		DisputedAmount:  100.0,
+		DisputedAmount:  money.MustParse("100.00", "USD"),
 		CurrencyCode:    "USD",
 		ReasonCode:      "14.1",
 		ReasonText:      "Test dispute",
@@ -419,7 +430,7 @@ func (suite *IntegrationTestSuite) TestDisputeFiltering() {
 	req1 := CreateDisputeRequest{
 		JournalEntryID:  uuid.NewString(),
 		MerchantID:      merchant1ID,
-		DisputedAmount:  100.0,
+		DisputedAmount:  money.MustParse("100.00", "USD"),
 		CurrencyCode:    "USD",
 		ReasonCode:      "14.1", // Fraud code
 		ReasonText:      "Fraud dispute",
@@ -433,7 +444,7 @@ func (suite *IntegrationTestSuite) TestDisputeFiltering() {
 	req2 := CreateDisputeRequest{
 		JournalEntryID:  uuid.NewString(),
 		MerchantID:      merchant2ID,
-		DisputedAmount:  50.0,
+		DisputedAmount:  money.MustParse("50.00", "USD"),
 		CurrencyCode:    "USD",
 		ReasonCode:      "10.1", // Non-fraud code
 		ReasonText:      "Authorization dispute",
@@ -480,7 +491,7 @@ func (suite *IntegrationTestSuite) TestACIDCompliance() {
 	validReq := CreateDisputeRequest{
 		JournalEntryID:  journalEntryID,
 		MerchantID:      merchantID,
-		DisputedAmount:  100.0,
+		DisputedAmount:  money.MustParse("100.00", "USD"),
 		CurrencyCode:    "USD",
 		ReasonCode:      "14.1",
 		ReasonText:      "Valid dispute",
@@ -530,7 +541,7 @@ func (suite *IntegrationTestSuite) TestDisputeAuditTrail() {
 	req := CreateDisputeRequest{
 		JournalEntryID:  uuid.NewString(),
 		MerchantID:      uuid.NewString(),
-		DisputedAmount:  100.0,
+		DisputedAmount:  money.MustParse("100.00", "USD"),
 		CurrencyCode:    "USD",
 		ReasonCode:      "14.1",
 		ReasonText:      "Audit trail test",
@@ -591,27 +602,96 @@ func (suite *IntegrationTestSuite) TestDisputeAuditTrail() {
 	suite.True(valid)
 }
 
-// TestChargebackFeeCalculation tests chargeback fee calculations
+// TestChargebackFeeCalculation tests that chargeback fees are quoted from the
+// fee schedules on the whole transaction and prorated to the disputed share
 func (suite *IntegrationTestSuite) TestChargebackFeeCalculation() {
 	ctx := context.Background()
 	
 	testCases := []struct {
-		amount    float64
-		brand     CardBrand
-		expected  float64
+		original  money.Money
+		amount    money.Money
+		reason    string
+		expected  money.Money
 	}{
-		{100.0, BrandVisa, 5.0},      // 2% of 100 = 2, min 5
-		{1000.0, BrandVisa, 15.0},    // 2% of 1000 = 20, max 15
-		{100.0, BrandMastercard, 8.0}, // 2.5% of 100 = 2.5, min 8
-		{1000.0, BrandMastercard, 25.0}, // 2.5% of 1000 = 25, max 25
+		{money.MustParse("100.00", "USD"), money.MustParse("100.00", "USD"), "10.1", money.MustParse("5.00", "USD")},      // Visa: 2% of 100 = 2, min 5
+		{money.MustParse("1000.00", "USD"), money.MustParse("1000.00", "USD"), "10.1", money.MustParse("15.00", "USD")},   // Visa: 2% of 1000 = 20, max 15
+		{money.MustParse("500.00", "USD"), money.MustParse("500.00", "USD"), "10.1", money.MustParse("10.00", "USD")},     // Visa: 2% of 500 = 10
+		{money.MustParse("100.00", "USD"), money.MustParse("100.00", "USD"), "4837", money.MustParse("8.00", "USD")},      // Mastercard: 2.5% of 100 = 2.5, min 8
+		{money.MustParse("1000.00", "USD"), money.MustParse("1000.00", "USD"), "4837", money.MustParse("25.00", "USD")},   // Mastercard: 2.5% of 1000 = 25, max 25
+		{money.MustParse("1000.00", "USD"), money.MustParse("250.00", "USD"), "10.1", money.MustParse("3.75", "USD")},     // Visa: a quarter of the 15 on the whole
+		{money.MustParse("300.00", "USD"), money.MustParse("100.00", "USD"), "4837", money.MustParse("2.67", "USD")},      // Mastercard: a third of the 8 on the whole
+		{money.MustParse("1000.00", "USD"), money.MustParse("1000.00", "USD"), "10.2", money.MustParse("0.00", "USD")},    // reason code without a chargeback fee
+		{money.MustParse("100000", "JPY"), money.MustParse("100000", "JPY"), "10.1", money.MustParse("0", "JPY")},         // no schedule in force for the currency
 	}
 	
 	for _, tc := range testCases {
-		fee := calculateChargebackFee(tc.amount, tc.brand)
-		suite.Equal(tc.expected, fee, "Chargeback fee calculation incorrect for amount %f and brand %s", tc.amount, tc.brand)
+		reasonCode, err := ValidateReasonCode(tc.reason)
+		suite.Require().NoError(err)
+		fee, err := suite.service.quoteChargebackFee(ctx, uuid.NewString(), reasonCode, tc.original, tc.amount, money.MustNew(0, tc.amount.Currency()), time.Now())
+		suite.NoError(err)
+		charged := money.MustNew(0, tc.amount.Currency())
+		if fee != nil {
+			charged = fee.Amount
+		}
+		suite.Equal(tc.expected, charged, "Chargeback fee calculation incorrect for %s of %s and reason code %s", tc.amount, tc.original, tc.reason)
 	}
 }
 
+// TestPartialChargebackFeesSumToWhole tests that partial disputes of a whole
+// transaction are charged no more between them than one dispute of it would be
+func (suite *IntegrationTestSuite) TestPartialChargebackFeesSumToWhole() {
+	ctx := context.Background()
+	reasonCode, err := ValidateReasonCode("4837")
+	suite.Require().NoError(err)
+	
+	original := money.MustParse("300.00", "USD")
+	third := money.MustParse("100.00", "USD")
+	covered := money.MustNew(0, "USD")
+	charged := money.MustNew(0, "USD")
+	var shares []money.Money
+	for i := 0; i < 3; i++ {
+		covered, err = covered.Add(third)
+		suite.Require().NoError(err)
+		fee, err := suite.service.quoteChargebackFee(ctx, uuid.NewString(), reasonCode, original, covered, charged, time.Now())
+		suite.Require().NoError(err)
+		shares = append(shares, fee.Amount)
+		charged, err = charged.Add(fee.Amount)
+		suite.Require().NoError(err)
+	}
+	
+	// a third of the 8.00 on the whole, with the rounding taken up by the second
+	suite.Equal([]money.Money{money.MustParse("2.67", "USD"), money.MustParse("2.66", "USD"), money.MustParse("2.67", "USD")}, shares)
+	suite.Equal(money.MustParse("8.00", "USD"), charged)
+}
+
+// stubFeeQuoter quotes chargeback fees from the USD schedules migration 034
+// seeds
+type stubFeeQuoter struct{}
+
+func (stubFeeQuoter) Quote(ctx context.Context, req fees.QuoteRequest) (*fees.Fee, error) {
+	if req.Amount.Currency() != "USD" {
+		return nil, fees.ErrScheduleNotFound
+	}
+	usd := func(amount string) *money.Money {
+		m := money.MustParse(amount, "USD")
+		return &m
+	}
+	schedule := &fees.Schedule{FeeType: fees.FeeTypeChargeback, CurrencyCode: "USD", FixedAmount: *usd("10")}
+	switch CardBrand(req.CardBrand) {
+	case BrandVisa:
+		schedule.FixedAmount, schedule.Rate = *usd("0"), money.BasisPoints(200)
+		schedule.MinimumFee, schedule.MaximumFee = usd("5"), usd("15")
+	case BrandMastercard:
+		schedule.FixedAmount, schedule.Rate = *usd("0"), money.BasisPoints(250)
+		schedule.MinimumFee, schedule.MaximumFee = usd("8"), usd("25")
+	}
+	amount, err := fees.Calculate(schedule, req.Amount)
+	if err != nil {
+		return nil, err
+	}
+	return &fees.Fee{FeeType: fees.FeeTypeChargeback, Base: req.Amount, Amount: amount}, nil
+}
+
 // RunIntegrationTests runs the integration test suite
 func RunIntegrationTests(t *testing.T) {
 	// Create a new test suite
@@ -636,7 +716,7 @@ func BenchmarkCreateDispute(b *testing.B) {
 	service := &DisputesService{
 		ledger:           NewMockLedgerService(),
 		stateMachine:     NewStateMachine(&MockTransitionStore{}),
-		reservePercentage: 0.05,
+		reservePercentage: money.MustParseRate("0.05"),
 	}
 	
 	b.ResetTimer()
@@ -645,7 +725,7 @@ func BenchmarkCreateDispute(b *testing.B) {
 		req := CreateDisputeRequest{
 			JournalEntryID:  uuid.NewString(),
 			MerchantID:      uuid.NewString(),
-			DisputedAmount:  100.0,
+			DisputedAmount:  money.MustParse("100.00", "USD"),
 			CurrencyCode:    "USD",
 			ReasonCode:      "14.1",
 			ReasonText:      "Benchmark dispute",
@@ -688,16 +768,17 @@ func BenchmarkStateTransition(b *testing.B) {
 
 // Test utility functions
 func TestCalculateMinMax(t *testing.T) {
+	usd := func(amount string) money.Money { return money.MustParse(amount, "USD") }
 	tests := []struct {
-		a, b      float64
-		minExpected float64
-		maxExpected float64
+		a, b      money.Money
+		minExpected money.Money
+		maxExpected money.Money
 	}{
-		{1.0, 2.0, 1.0, 2.0},
-		{2.0, 1.0, 1.0, 2.0},
-		{-1.0, 1.0, -1.0, 1.0},
-		{-2.0, -1.0, -2.0, -1.0},
-		{5.0, 5.0, 5.0, 5.0},
+		{usd("1.00"), usd("2.00"), usd("1.00"), usd("2.00")},
+		{usd("2.00"), usd("1.00"), usd("1.00"), usd("2.00")},
+		{usd("-1.00"), usd("1.00"), usd("-1.00"), usd("1.00")},
+		{usd("-2.00"), usd("-1.00"), usd("-2.00"), usd("-1.00")},
+		{usd("5.00"), usd("5.00"), usd("5.00"), usd("5.00")},
 	}
 	
 	for _, test := range tests {
@@ -720,8 +801,8 @@ func TestDisputeValidationEdgeCases(t *testing.T) {
 			request: DisputeValidationRequest{
 				JournalEntryID: "",
 				MerchantID:     uuid.NewString(),
-				OriginalAmount: 100.0,
-				DisputedAmount: 50.0,
+				OriginalAmount: money.MustParse("100.00", "USD"),
+				DisputedAmount: money.MustParse("50.00", "USD"),
 				CurrencyCode:   "USD",
 				ReasonCode:     "14.1",
 				CreatedBy:      "test-user",
@@ -733,8 +814,8 @@ func TestDisputeValidationEdgeCases(t *testing.T) {
 			request: DisputeValidationRequest{
 				JournalEntryID: uuid.NewString(),
 				MerchantID:     uuid.NewString(),
-				OriginalAmount: 100.0,
-				DisputedAmount: 0.0,
+				OriginalAmount: money.MustParse("100.00", "USD"),
+				DisputedAmount: money.MustParse("0.00", "USD"),
 				CurrencyCode:   "USD",
 				ReasonCode:     "14.1",
 				CreatedBy:      "test-user",
@@ -746,8 +827,8 @@ func TestDisputeValidationEdgeCases(t *testing.T) {
 			request: DisputeValidationRequest{
 				JournalEntryID: uuid.NewString(),
 				MerchantID:     uuid.NewString(),
-				OriginalAmount: -100.0,
-				DisputedAmount: 50.0,
+				OriginalAmount: money.MustParse("-100.00", "USD"),
+				DisputedAmount: money.MustParse("50.00", "USD"),
 				CurrencyCode:   "USD",
 				ReasonCode:     "14.1",
 				CreatedBy:      "test-user",
@@ -0,0 +1,160 @@
+package disputes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/example/pci-infra/internal/fees"
+	"github.com/example/pci-infra/internal/money"
+)
+
+// DisputeAmountExceededError is returned when a dispute would take the amount
+// disputed against a journal entry past the entry's amount. Several partial
+// disputes may be opened against one entry; reversed disputes no longer count
+// towards it.
+type DisputeAmountExceededError struct {
+	JournalEntryID  string
+	OriginalAmount  money.Money
+	AlreadyDisputed money.Money
+	RequestedAmount money.Money
+}
+
+func (e *DisputeAmountExceededError) Error() string {
+	return fmt.Sprintf("disputed amount %s exceeds the %s left undisputed on journal entry %s (%s of %s already disputed)",
+		e.RequestedAmount, e.Remaining(), e.JournalEntryID, e.AlreadyDisputed, e.OriginalAmount)
+}
+
+// Remaining is how much of the entry may still be disputed
+func (e *DisputeAmountExceededError) Remaining() money.Money {
+	remaining, err := e.OriginalAmount.Sub(e.AlreadyDisputed)
+	if err != nil || remaining.IsNegative() {
+		zero, _ := money.Zero(e.OriginalAmount.Currency())
+		return zero
+	}
+	return remaining
+}
+
+// checkDisputeCap checks that requested can be disputed against an entry of
+// original of which alreadyDisputed is under dispute
+func checkDisputeCap(journalEntryID string, original, alreadyDisputed, requested money.Money) error {
+	total, err := alreadyDisputed.Add(requested)
+	if err != nil {
+		return fmt.Errorf("dispute validation failed: %w", err)
+	}
+	if total.GreaterThan(original) {
+		return &DisputeAmountExceededError{
+			JournalEntryID:  journalEntryID,
+			OriginalAmount:  original,
+			AlreadyDisputed: alreadyDisputed,
+			RequestedAmount: requested,
+		}
+	}
+	return nil
+}
+
+// disputedAmount totals the disputes against a journal entry that have not
+// been reversed. The caller holds the entry's lock so concurrent disputes are
+// totalled one at a time.
+func disputedAmount(ctx context.Context, tx pgx.Tx, journalEntryID, currency string) (money.Money, error) {
+	var total pgtype.Numeric
+	err := tx.QueryRow(ctx, `
+		SELECT COALESCE(SUM(disputed_amount), 0)
+		FROM disputes
+		WHERE journal_entry_id = $1 AND status <> 'REVERSED'
+	`, journalEntryID).Scan(&total)
+	if err != nil {
+		return money.Money{}, fmt.Errorf("failed to total disputed amount: %w", err)
+	}
+	return money.FromNumeric(total, currency)
+}
+
+// heldChargebackFees totals the chargeback fees held for the open disputes
+// against a journal entry and the amount those disputes cover. The caller
+// holds the entry's lock.
+func heldChargebackFees(ctx context.Context, tx pgx.Tx, journalEntryID, currency string) (covered, held money.Money, err error) {
+	return scanFeeCover(tx.QueryRow(ctx, `
+		SELECT COALESCE(SUM(disputed_amount), 0), COALESCE(SUM(chargeback_fee), 0)
+		FROM disputes
+		WHERE journal_entry_id = $1 AND status <> 'REVERSED' AND chargeback_fee > 0
+	`, journalEntryID), currency)
+}
+
+// chargedChargebackFees totals the chargeback fees charged on the other
+// disputes against a journal entry and the amount those disputes cover. The
+// caller holds the entry's lock so resolutions are charged one at a time.
+func chargedChargebackFees(ctx context.Context, tx pgx.Tx, journalEntryID, disputeUUID, currency string) (covered, charged money.Money, err error) {
+	return scanFeeCover(tx.QueryRow(ctx, `
+		SELECT COALESCE(SUM(d.disputed_amount), 0), COALESCE(SUM(je.amount), 0)
+		FROM disputes d
+		JOIN journal_entries je
+		  ON je.reference_type = $3 AND je.reference_id = d.dispute_id AND je.entry_type = 'debit'
+		WHERE d.journal_entry_id = $1 AND d.id <> $2
+	`, journalEntryID, disputeUUID, fees.ReferenceTypeFee), currency)
+}
+
+func scanFeeCover(row pgx.Row, currency string) (covered, fee money.Money, err error) {
+	var coveredN, feeN pgtype.Numeric
+	if err := row.Scan(&coveredN, &feeN); err != nil {
+		return money.Money{}, money.Money{}, fmt.Errorf("failed to total chargeback fees: %w", err)
+	}
+	if covered, err = money.FromNumeric(coveredN, currency); err != nil {
+		return money.Money{}, money.Money{}, err
+	}
+	if fee, err = money.FromNumeric(feeN, currency); err != nil {
+		return money.Money{}, money.Money{}, err
+	}
+	return covered, fee, nil
+}
+
+// quoteChargebackFee quotes the chargeback fee on a dispute against a
+// transaction of original, where covered is the amount disputed by this and
+// the earlier disputes that carry a fee, and charged is what those earlier
+// disputes were charged or have held. The schedule is applied to the whole transaction,
+// prorated to covered, and what was already charged is taken off, so partial
+// disputes on one transaction are charged exactly what one dispute of the
+// same total would be, with no rounding drift between them. It returns nil
+// when the reason code carries no fee or no schedule is in force.
+func (ds *DisputesService) quoteChargebackFee(ctx context.Context, merchantID string, reasonCode *ReasonCode, original, covered, charged money.Money, at time.Time) (*fees.Fee, error) {
+	if !reasonCode.ChargebackFee || ds.fees == nil {
+		return nil, nil
+	}
+	fee, err := ds.fees.Quote(ctx, fees.QuoteRequest{
+		FeeType:    fees.FeeTypeChargeback,
+		MerchantID: merchantID,
+		CardBrand:  string(reasonCode.Brand),
+		Amount:     original,
+		At:         at,
+	})
+	if errors.Is(err, fees.ErrScheduleNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate chargeback fee: %w", err)
+	}
+	if fee.Amount, err = chargebackFeeShare(fee.Amount, original, covered, charged); err != nil {
+		return nil, err
+	}
+	return fee, nil
+}
+
+// chargebackFeeShare prorates the fee on the whole transaction to covered and
+// takes off what was already charged, never going below zero
+func chargebackFeeShare(fee, original, covered, charged money.Money) (money.Money, error) {
+	owed, err := fee.Prorate(covered, original)
+	if err != nil {
+		return money.Money{}, fmt.Errorf("failed to prorate chargeback fee: %w", err)
+	}
+	share, err := owed.Sub(charged)
+	if err != nil {
+		return money.Money{}, fmt.Errorf("failed to prorate chargeback fee: %w", err)
+	}
+	if share.IsNegative() {
+		return money.Zero(fee.Currency())
+	}
+	return share, nil
+}
@@ -0,0 +1,66 @@
+package disputes
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/pci-infra/internal/money"
+)
+
+func TestCheckDisputeCap(t *testing.T) {
+	usd := func(amount string) money.Money { return money.MustParse(amount, "USD") }
+	original := usd("100.00")
+
+	assert.NoError(t, checkDisputeCap("JE-1", original, usd("0"), usd("40.00")))
+	assert.NoError(t, checkDisputeCap("JE-1", original, usd("40.00"), usd("35.00")))
+	// the last partial dispute may take exactly what is left
+	assert.NoError(t, checkDisputeCap("JE-1", original, usd("75.00"), usd("25.00")))
+
+	err := checkDisputeCap("JE-1", original, usd("75.00"), usd("25.01"))
+	var exceeded *DisputeAmountExceededError
+	require.True(t, errors.As(err, &exceeded), "got %v", err)
+	assert.Equal(t, usd("25.00"), exceeded.Remaining())
+	assert.Equal(t, usd("75.00"), exceeded.AlreadyDisputed)
+	assert.Contains(t, err.Error(), "JE-1")
+
+	// a single dispute of more than the transaction
+	err = checkDisputeCap("JE-1", original, usd("0"), usd("100.01"))
+	require.True(t, errors.As(err, &exceeded), "got %v", err)
+	assert.Equal(t, original, exceeded.Remaining())
+
+	// nothing is left once the whole transaction is disputed
+	err = checkDisputeCap("JE-1", original, original, usd("0.01"))
+	require.True(t, errors.As(err, &exceeded), "got %v", err)
+	assert.True(t, exceeded.Remaining().IsZero())
+
+	err = checkDisputeCap("JE-1", original, usd("0"), money.MustParse("10", "JPY"))
+	assert.True(t, errors.Is(err, money.ErrCurrencyMismatch), "got %v", err)
+}
+
+func TestChargebackFeeShare(t *testing.T) {
+	usd := func(amount string) money.Money { return money.MustParse(amount, "USD") }
+	fee, original := usd("8.00"), usd("300.00")
+
+	share, err := chargebackFeeShare(fee, original, usd("100.00"), usd("0"))
+	require.NoError(t, err)
+	assert.Equal(t, usd("2.67"), share)
+
+	share, err = chargebackFeeShare(fee, original, usd("200.00"), usd("2.67"))
+	require.NoError(t, err)
+	assert.Equal(t, usd("2.66"), share)
+
+	share, err = chargebackFeeShare(fee, original, usd("300.00"), usd("5.33"))
+	require.NoError(t, err)
+	assert.Equal(t, usd("2.67"), share)
+
+	// earlier disputes charged under a dearer schedule leave nothing to charge
+	share, err = chargebackFeeShare(fee, original, usd("200.00"), usd("6.00"))
+	require.NoError(t, err)
+	assert.True(t, share.IsZero())
+
+	_, err = chargebackFeeShare(fee, original, usd("100.00"), money.MustParse("1", "JPY"))
+	assert.Error(t, err)
+}
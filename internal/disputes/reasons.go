@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+
+	"github.com/example/pci-infra/internal/money"
 )
 
 // CardBrand represents the card network brand
@@ -265,22 +267,26 @@ func ValidateDisputeRequest(request DisputeValidationRequest) error {
 		return fmt.Errorf("merchant ID is required")
 	}
 
-	if request.OriginalAmount <= 0 {
+	if !request.OriginalAmount.IsPositive() {
 		return fmt.Errorf("original amount must be positive")
 	}
 
-	if request.DisputedAmount <= 0 {
+	if !request.DisputedAmount.IsPositive() {
 		return fmt.Errorf("disputed amount must be positive")
 	}
 
-	if request.DisputedAmount > request.OriginalAmount {
-		return fmt.Errorf("disputed amount cannot exceed original amount")
-	}
-
 	if len(request.CurrencyCode) != 3 {
 		return fmt.Errorf("currency code must be 3 characters")
 	}
 
+	if request.DisputedAmount.Currency() != request.CurrencyCode || request.OriginalAmount.Currency() != request.CurrencyCode {
+		return fmt.Errorf("disputed and original amounts must be in %s", request.CurrencyCode)
+	}
+
+	if request.DisputedAmount.GreaterThan(request.OriginalAmount) {
+		return fmt.Errorf("disputed amount cannot exceed original amount")
+	}
+
 	if request.ReasonCode == "" {
 		return fmt.Errorf("reason code is required")
 	}
@@ -316,8 +322,8 @@ type DisputeValidationRequest struct {
 	DisputeID                string                 `json:"dispute_id"`
 	JournalEntryID           string                 `json:"journal_entry_id"`
 	MerchantID               string                 `json:"merchant_id"`
-	OriginalAmount           float64                `json:"original_amount"`
-	DisputedAmount           float64                `json:"disputed_amount"`
+	OriginalAmount           money.Money            `json:"original_amount"`
+	DisputedAmount           money.Money            `json:"disputed_amount"`
 	CurrencyCode             string                 `json:"currency_code"`
 	ReasonCode               string                 `json:"reason_code"`
 	ReasonText               string                 `json:"reason_text"`
@@ -10,20 +10,38 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"golang.org/x/crypto/bcrypt"
+
+	"github.com/example/pci-infra/internal/fees"
+	"github.com/example/pci-infra/internal/ledger"
+	"github.com/example/pci-infra/internal/money"
+	"github.com/example/pci-infra/internal/outbox"
 )
 
+// FeeQuoter works out chargeback fees from the fee schedules
+type FeeQuoter interface {
+	Quote(ctx context.Context, req fees.QuoteRequest) (*fees.Fee, error)
+}
+
+// Ledger is the part of the ledger chargebacks post through. Postings are
+// made within the transaction that changes the dispute's status.
+type Ledger interface {
+	PostTransactionTx(ctx context.Context, tx pgx.Tx, txn ledger.Transaction) (*ledger.Transaction, error)
+}
+
 // DisputesService provides dispute management functionality
 type DisputesService struct {
 	pool            *pgxpool.Pool
-	ledger          *LedgerService
+	ledger          Ledger
+	fees            FeeQuoter
 	stateMachine    *StateMachine
-	reservePercentage float64
+	reservePercentage money.Rate
 }
 
 // NewDisputesService creates a new disputes service
-func NewDisputesService(pool *pgxpool.Pool, ledger *LedgerService, reservePercentage float64) *DisputesService {
+func NewDisputesService(pool *pgxpool.Pool, ledger Ledger, feeQuoter FeeQuoter, reservePercentage money.Rate) *DisputesService {
 	// Create transition store implementation
 	transitionStore := &PostgresTransitionStore{Pool: pool}
 	stateMachine := NewStateMachine(transitionStore)
@@ -31,6 +49,7 @@ func NewDisputesService(pool *pgxpool.Pool, ledger *LedgerService, reservePercen
 	return &DisputesService{
 		pool:             pool,
 		ledger:           ledger,
+		fees:             feeQuoter,
 		stateMachine:     stateMachine,
 		reservePercentage: reservePercentage,
 	}
@@ -40,7 +59,7 @@ func NewDisputesService(pool *pgxpool.Pool, ledger *LedgerService, reservePercen
 type CreateDisputeRequest struct {
 	JournalEntryID  string                 `json:"journal_entry_id"`
 	MerchantID      string                 `json:"merchant_id"`
-	DisputedAmount  float64                `json:"disputed_amount"`
+	DisputedAmount  money.Money            `json:"disputed_amount"`
 	CurrencyCode    string                 `json:"currency_code"`
 	ReasonCode      string                 `json:"reason_code"`
 	ReasonText      string                 `json:"reason_text"`
@@ -56,18 +75,19 @@ type Dispute struct {
 	DisputeID        string                 `json:"dispute_id"`
 	JournalEntryID   string                 `json:"journal_entry_id"`
 	MerchantID       string                 `json:"merchant_id"`
-	OriginalAmount   float64                `json:"original_amount"`
-	DisputedAmount   float64                `json:"disputed_amount"`
+	OriginalAmount   money.Money            `json:"original_amount"`
+	DisputedAmount   money.Money            `json:"disputed_amount"`
 	CurrencyCode     string                 `json:"currency_code"`
 	ReasonCode       string                 `json:"reason_code"`
 	ReasonText       string                 `json:"reason_text"`
 	Status           DisputeState           `json:"status"`
 	IsFraud          bool                   `json:"is_fraud"`
-	ChargebackFee    float64                `json:"chargeback_fee"`
+	ChargebackFee    money.Money            `json:"chargeback_fee"`
 	CreatedAt        time.Time              `json:"created_at"`
 	CreatedBy        string                 `json:"created_by"`
 	ResolvedAt       *time.Time             `json:"resolved_at,omitempty"`
 	ResolvedBy       string                 `json:"resolved_by,omitempty"`
+	StageDueAt       *time.Time             `json:"stage_due_at,omitempty"`
 	Metadata         map[string]interface{} `json:"metadata"`
 }
 
@@ -77,7 +97,7 @@ type Hold struct {
 	HoldID        string                 `json:"hold_id"`
 	DisputeID     string                 `json:"dispute_id"`
 	AccountID     string                 `json:"account_id"`
-	HeldAmount    float64                `json:"held_amount"`
+	HeldAmount    money.Money            `json:"held_amount"`
 	CurrencyCode  string                 `json:"currency_code"`
 	Status        string                 `json:"status"`
 	ExpiresAt     time.Time              `json:"expires_at"`
@@ -90,14 +110,14 @@ type Hold struct {
 
 // FraudReserve represents a merchant fraud reserve record
 type FraudReserve struct {
-	ID                   string  `json:"id"`
-	MerchantID           string  `json:"merchant_id"`
-	ReserveAccountID     string  `json:"reserve_account_id"`
-	ReservePercentage    float64 `json:"reserve_percentage"`
-	MinimumReserveAmount float64 `json:"minimum_reserve_amount"`
-	CurrentReserveAmount float64 `json:"current_reserve_amount"`
-	CurrencyCode         string  `json:"currency_code"`
-	IsActive             bool    `json:"is_active"`
+	ID                   string      `json:"id"`
+	MerchantID           string      `json:"merchant_id"`
+	ReserveAccountID     string      `json:"reserve_account_id"`
+	ReservePercentage    money.Rate  `json:"reserve_percentage"`
+	MinimumReserveAmount money.Money `json:"minimum_reserve_amount"`
+	CurrentReserveAmount money.Money `json:"current_reserve_amount"`
+	CurrencyCode         string      `json:"currency_code"`
+	IsActive             bool        `json:"is_active"`
 	CreatedAt            time.Time `json:"created_at"`
 	CreatedBy            string   `json:"created_by"`
 	UpdatedAt            time.Time `json:"updated_at"`
@@ -113,6 +133,10 @@ func (ds *DisputesService) CreateDispute(ctx context.Context, req CreateDisputeR
 	}
 	defer tx.Rollback(ctx)
 
+	if req.CurrencyCode == "" {
+		req.CurrencyCode = req.DisputedAmount.Currency()
+	}
+
 	// Validate dispute request
 	validationReq := DisputeValidationRequest{
 		DisputeID:      uuid.New().String(),
@@ -133,14 +157,16 @@ func (ds *DisputesService) CreateDispute(ctx context.Context, req CreateDisputeR
 		return nil, fmt.Errorf("dispute validation failed: %w", err)
 	}
 
-	// Get journal entry to validate and get original amount
-	var originalAmount float64
+	// Get journal entry to validate and get original amount, locking it so
+	// partial disputes against it are checked against the cap one at a time
+	var entryAmount pgtype.Numeric
 	var accountID, accountType string
 	err = tx.QueryRow(ctx, `
 		SELECT amount, account_id, account_type, currency_code, reference_type, reference_id
 		FROM journal_entries
 		WHERE id = $1
-	`, req.JournalEntryID).Scan(&originalAmount, &accountID, &accountType, &validationReq.CurrencyCode, &validationReq.ReferenceType, &validationReq.ReferenceID)
+		FOR UPDATE
+	`, req.JournalEntryID).Scan(&entryAmount, &accountID, &accountType, &validationReq.CurrencyCode, &validationReq.ReferenceType, &validationReq.ReferenceID)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -149,8 +175,22 @@ func (ds *DisputesService) CreateDispute(ctx context.Context, req CreateDisputeR
 		return nil, fmt.Errorf("failed to get journal entry: %w", err)
 	}
 
+	originalAmount, err := money.FromNumeric(entryAmount, validationReq.CurrencyCode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read journal entry amount: %w", err)
+	}
+
 	validationReq.OriginalAmount = originalAmount
 
+	// Disputes against the entry may not add up to more than it
+	alreadyDisputed, err := disputedAmount(ctx, tx, req.JournalEntryID, validationReq.CurrencyCode)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkDisputeCap(req.JournalEntryID, originalAmount, alreadyDisputed, req.DisputedAmount); err != nil {
+		return nil, err
+	}
+
 	if err := ValidateDisputeRequest(validationReq); err != nil {
 		return nil, fmt.Errorf("dispute validation failed: %w", err)
 	}
@@ -161,10 +201,26 @@ func (ds *DisputesService) CreateDispute(ctx context.Context, req CreateDisputeR
 		return nil, fmt.Errorf("invalid reason code: %w", err)
 	}
 
-	// Calculate chargeback fee based on reason code
-	chargebackFee := 0.0
-	if reasonCode.ChargebackFee {
-		chargebackFee = calculateChargebackFee(req.DisputedAmount, reasonCode.Brand)
+	// Calculate chargeback fee based on reason code, prorated to the
+	// disputed share of the transaction net of the fees held for earlier
+	// partial disputes
+	chargebackFee, err := money.Zero(req.DisputedAmount.Currency())
+	if err != nil {
+		return nil, fmt.Errorf("invalid disputed amount: %w", err)
+	}
+	covered, held, err := heldChargebackFees(ctx, tx, req.JournalEntryID, validationReq.CurrencyCode)
+	if err != nil {
+		return nil, err
+	}
+	if covered, err = covered.Add(req.DisputedAmount); err != nil {
+		return nil, fmt.Errorf("dispute validation failed: %w", err)
+	}
+	fee, err := ds.quoteChargebackFee(ctx, req.MerchantID, reasonCode, originalAmount, covered, held, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if fee != nil {
+		chargebackFee = fee.Amount
 	}
 
 	// Create dispute record
@@ -213,13 +269,13 @@ func (ds *DisputesService) CreateDispute(ctx context.Context, req CreateDisputeR
 		Metadata:  dispute.Metadata,
 	}
 
-	result := ds.stateMachine.Transition(ctx, transitionReq)
+	result := ds.transitionsIn(tx).Transition(ctx, transitionReq)
 	if !result.Success {
 		return nil, fmt.Errorf("failed to create state transition: %w", result.Error)
 	}
 
 	// Apply holds and reserves if dispute is immediately authorized
-	if req.DisputedAmount > 0 {
+	if req.DisputedAmount.IsPositive() {
 		err = ds.applyFundsHold(ctx, tx, dispute)
 		if err != nil {
 			return nil, fmt.Errorf("failed to apply funds hold: %w", err)
@@ -270,11 +326,15 @@ func (ds *DisputesService) AuthorizeDispute(ctx context.Context, disputeID, auth
 		Metadata:  map[string]interface{}{},
 	}
 
-	result := ds.stateMachine.Transition(ctx, transitionReq)
+	result := ds.transitionsIn(tx).Transition(ctx, transitionReq)
 	if !result.Success {
 		return fmt.Errorf("failed to transition dispute: %w", result.Error)
 	}
 
+	if err := ds.setStatus(ctx, tx, dispute, StateAuthorized, authorizedBy); err != nil {
+		return err
+	}
+
 	// Apply funds hold
 	err = ds.applyFundsHold(ctx, tx, dispute)
 	if err != nil {
@@ -323,10 +383,14 @@ func (ds *DisputesService) SettleTransaction(ctx context.Context, journalEntryID
 				Metadata:  map[string]interface{}{},
 			}
 
-			result := ds.stateMachine.Transition(ctx, transitionReq)
+			result := ds.transitionsIn(tx).Transition(ctx, transitionReq)
 			if !result.Success {
 				return fmt.Errorf("failed to transition dispute %s: %w", dispute.DisputeID, result.Error)
 			}
+
+			if err := ds.setStatus(ctx, tx, dispute, StateSettled, settledBy); err != nil {
+				return err
+			}
 		}
 	}
 
@@ -342,7 +406,13 @@ func (ds *DisputesService) InitiateDispute(ctx context.Context, disputeID, initi
 	// For now, this is a simple state transition
 	// In a real implementation, this would involve network communication with card networks
 	
-	dispute, err := ds.getDispute(ctx, disputeID)
+	tx, err := ds.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	dispute, err := ds.getDisputeByDisputeID(ctx, tx, disputeID)
 	if err != nil {
 		return fmt.Errorf("failed to get dispute: %w", err)
 	}
@@ -363,11 +433,19 @@ func (ds *DisputesService) InitiateDispute(ctx context.Context, disputeID, initi
 		Metadata:  map[string]interface{}{},
 	}
 
-	result := ds.stateMachine.Transition(ctx, transitionReq)
+	result := ds.transitionsIn(tx).Transition(ctx, transitionReq)
 	if !result.Success {
 		return fmt.Errorf("failed to transition dispute: %w", result.Error)
 	}
 
+	if err := ds.setStatus(ctx, tx, dispute, StateDisputed, initiatedBy); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
 	return nil
 }
 
@@ -398,23 +476,16 @@ func (ds *DisputesService) ReverseDispute(ctx context.Context, disputeID, revers
 		Metadata:  map[string]interface{}{},
 	}
 
-	result := ds.stateMachine.Transition(ctx, transitionReq)
+	result := ds.transitionsIn(tx).Transition(ctx, transitionReq)
 	if !result.Success {
 		return fmt.Errorf("failed to transition dispute: %w", result.Error)
 	}
 
 	// Update dispute resolution details
-	_, err = tx.Exec(ctx, `
-		UPDATE disputes 
-		SET resolved_at = CURRENT_TIMESTAMP, resolved_by = $2
-		WHERE dispute_id = $1
-	`, disputeID, reversedBy)
-
-	if err != nil {
-		return fmt.Errorf("failed to update dispute resolution: %w", err)
+	if err := ds.setStatus(ctx, tx, dispute, StateReversed, reversedBy); err != nil {
+		return err
 	}
 
-	// Release holds and adjust reserves will be handled by database triggers
 	if err := tx.Commit(ctx); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
@@ -433,15 +504,18 @@ func (ds *DisputesService) ListDisputes(ctx context.Context, filter DisputeFilte
 }
 
 // CalculateMerchantReserve calculates the required reserve for a merchant
-func (ds *DisputesService) CalculateMerchantReserve(ctx context.Context, merchantID string, transactionVolume float64) (float64, error) {
+func (ds *DisputesService) CalculateMerchantReserve(ctx context.Context, merchantID string, transactionVolume money.Money) (money.Money, error) {
 	// Get merchant's current reserve configuration
 	_, err := ds.getFraudReserve(ctx, merchantID)
 	if err != nil {
-		return 0, fmt.Errorf("failed to get fraud reserve: %w", err)
+		return money.Money{}, fmt.Errorf("failed to get fraud reserve: %w", err)
 	}
 
 	// Calculate reserve based on volume and percentage
-	requiredReserve := transactionVolume * ds.reservePercentage
+	requiredReserve, err := transactionVolume.MulRate(ds.reservePercentage)
+	if err != nil {
+		return money.Money{}, fmt.Errorf("failed to calculate reserve: %w", err)
+	}
 	
 	return requiredReserve, nil
 }
@@ -458,33 +532,64 @@ func (ds *DisputesService) applyFundsHold(ctx context.Context, tx pgx.Tx, disput
 		return fmt.Errorf("failed to get account ID: %w", err)
 	}
 
-	// Create hold ID
-	holdID := fmt.Sprintf("HLD-%s", time.Now().Format("20060102-")) + uuid.New().String()[:8]
-	
 	// Calculate hold amount (disputed amount plus any fees)
-	holdAmount := dispute.DisputedAmount + dispute.ChargebackFee
+	holdAmount, err := dispute.DisputedAmount.Add(dispute.ChargebackFee)
+	if err != nil {
+		return fmt.Errorf("failed to calculate hold amount: %w", err)
+	}
 	
-	// Create hold with 30-day expiry
-	expiresAt := time.Now().Add(30 * 24 * time.Hour)
+	// Create hold with 30-day expiry; the hold sweeper releases it then
+	if err := ds.placeHold(ctx, tx, dispute, accountID, holdAmount, time.Now().Add(HoldExpiryPeriod)); err != nil {
+		return fmt.Errorf("failed to create hold: %w", err)
+	}
 
-	_, err = tx.Exec(ctx, `
-		INSERT INTO holds (
-			hold_id, dispute_id, account_id, held_amount, currency_code,
-			status, expires_at, created_by, prev_hold_hash
-		) VALUES ($1, $2, $3, $4, $5, 'ACTIVE', $6, $7, '')
-	`, holdID, dispute.ID, accountID, holdAmount, dispute.CurrencyCode, expiresAt, dispute.CreatedBy)
+	return nil
+}
+
+// setStatus keeps the disputes row in step with the state machine. Moving to
+// a final state records who resolved the dispute and releases its holds. The
+// new stage's deadline runs from now; stages without one, and final states,
+// clear it.
+func (ds *DisputesService) setStatus(ctx context.Context, tx pgx.Tx, dispute *Dispute, status DisputeState, actor string) error {
+	var windowDays *int
+	if window, ok := StageWindow(dispute.ReasonCode, status); ok {
+		days := int(window / (24 * time.Hour))
+		windowDays = &days
+	}
+
+	_, err := tx.Exec(ctx, `
+		UPDATE disputes
+		SET status = $2,
+		    resolved_at = CASE WHEN $3 THEN CURRENT_TIMESTAMP ELSE resolved_at END,
+		    resolved_by = CASE WHEN $3 THEN $4 ELSE resolved_by END,
+		    stage_entered_at = CURRENT_TIMESTAMP,
+		    stage_due_at = CURRENT_TIMESTAMP + make_interval(days => $5::int)
+		WHERE dispute_id = $1
+	`, dispute.DisputeID, status, IsFinalState(status), actor, windowDays)
 
 	if err != nil {
-		return fmt.Errorf("failed to create hold: %w", err)
+		return fmt.Errorf("failed to update dispute status: %w", err)
 	}
 
-	// The database trigger will handle the journal entries automatically
-
+	if IsFinalState(status) {
+		return ds.releaseDisputeHolds(ctx, tx, dispute, status, actor)
+	}
 	return nil
 }
 
+// transitionsIn returns the service's state machine recording transitions
+// within tx, so they commit or roll back with the dispute's status. State
+// machines on other stores, as in tests, record them as they do already.
+func (ds *DisputesService) transitionsIn(tx pgx.Tx) *StateMachine {
+	store, ok := ds.stateMachine.transitionStore.(*PostgresTransitionStore)
+	if !ok {
+		return ds.stateMachine
+	}
+	return NewStateMachine(store.inTx(tx))
+}
+
 // updateFraudReserve updates the merchant's fraud reserve
-func (ds *DisputesService) updateFraudReserve(ctx context.Context, tx pgx.Tx, merchantID string, amount float64) error {
+func (ds *DisputesService) updateFraudReserve(ctx context.Context, tx pgx.Tx, merchantID string, amount money.Money) error {
 	// Get or create fraud reserve
 	reserve, err := ds.getFraudReserve(ctx, tx, merchantID)
 	if err != nil {
@@ -495,14 +600,18 @@ func (ds *DisputesService) updateFraudReserve(ctx context.Context, tx pgx.Tx, me
 		// Create new reserve
 		reserveID := uuid.New().String()
 		reserveAccountID := uuid.New().String()
+		zero, err := money.Zero(amount.Currency())
+		if err != nil {
+			return fmt.Errorf("invalid reserve currency: %w", err)
+		}
 		
 		_, err = tx.Exec(ctx, `
 			INSERT INTO fraud_reserves (
 				id, merchant_id, reserve_account_id, reserve_percentage,
 				minimum_reserve_amount, current_reserve_amount, currency_code,
 				created_by, updated_by
-			) VALUES ($1, $2, $3, $4, $5, $6, 'USD', $7, $7)
-		`, reserveID, merchantID, reserveAccountID, ds.reservePercentage, 0.0, 0.0, "system")
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $8)
+		`, reserveID, merchantID, reserveAccountID, ds.reservePercentage, zero, zero, amount.Currency(), "system")
 
 		if err != nil {
 			return fmt.Errorf("failed to create fraud reserve: %w", err)
@@ -513,9 +622,9 @@ func (ds *DisputesService) updateFraudReserve(ctx context.Context, tx pgx.Tx, me
 			MerchantID:           merchantID,
 			ReserveAccountID:     reserveAccountID,
 			ReservePercentage:    ds.reservePercentage,
-			MinimumReserveAmount: 0.0,
-			CurrentReserveAmount: 0.0,
-			CurrencyCode:         "USD",
+			MinimumReserveAmount: zero,
+			CurrentReserveAmount: zero,
+			CurrencyCode:         amount.Currency(),
 			IsActive:             true,
 			CreatedBy:           "system",
 			UpdatedBy:           "system",
@@ -523,7 +632,14 @@ func (ds *DisputesService) updateFraudReserve(ctx context.Context, tx pgx.Tx, me
 	}
 
 	// Update reserve amount
-	newReserveAmount := reserve.CurrentReserveAmount + (amount * reserve.ReservePercentage)
+	reserveIncrement, err := amount.MulRate(reserve.ReservePercentage)
+	if err != nil {
+		return fmt.Errorf("failed to calculate reserve increment: %w", err)
+	}
+	newReserveAmount, err := reserve.CurrentReserveAmount.Add(reserveIncrement)
+	if err != nil {
+		return fmt.Errorf("failed to calculate reserve amount: %w", err)
+	}
 	
 	_, err = tx.Exec(ctx, `
 		UPDATE fraud_reserves 
@@ -548,20 +664,21 @@ func (ds *DisputesService) getDisputeByDisputeID(ctx context.Context, tx interfa
 	QueryRow(ctx context.Context, query string, args ...interface{}) pgx.Row
 }, disputeID string) (*Dispute, error) {
 	dispute := &Dispute{}
+	var amounts disputeAmounts
 	
 	err := tx.QueryRow(ctx, `
 		SELECT id, dispute_id, journal_entry_id, merchant_id, original_amount,
 		       disputed_amount, currency_code, reason_code, reason_text, status,
 		       is_fraud, chargeback_fee, created_at, created_by, resolved_at,
-		       resolved_by, metadata
+		       resolved_by, stage_due_at, metadata
 		FROM disputes
 		WHERE dispute_id = $1
 	`, disputeID).Scan(
 		&dispute.ID, &dispute.DisputeID, &dispute.JournalEntryID, &dispute.MerchantID,
-		&dispute.OriginalAmount, &dispute.DisputedAmount, &dispute.CurrencyCode,
+		&amounts.original, &amounts.disputed, &dispute.CurrencyCode,
 		&dispute.ReasonCode, &dispute.ReasonText, &dispute.Status, &dispute.IsFraud,
-		&dispute.ChargebackFee, &dispute.CreatedAt, &dispute.CreatedBy,
-		&dispute.ResolvedAt, &dispute.ResolvedBy,
+		&amounts.chargebackFee, &dispute.CreatedAt, &dispute.CreatedBy,
+		&dispute.ResolvedAt, &dispute.ResolvedBy, &dispute.StageDueAt,
 	)
 
 	if err != nil {
@@ -571,6 +688,10 @@ func (ds *DisputesService) getDisputeByDisputeID(ctx context.Context, tx interfa
 		return nil, fmt.Errorf("failed to query dispute: %w", err)
 	}
 
+	if err := amounts.apply(dispute); err != nil {
+		return nil, err
+	}
+
 	// Parse metadata
 	var metadataJSON json.RawMessage
 	if err := tx.QueryRow(ctx, `SELECT metadata FROM disputes WHERE dispute_id = $1`, disputeID).Scan(&metadataJSON); err == nil {
@@ -589,7 +710,7 @@ func (ds *DisputesService) getDisputesByJournalEntry(ctx context.Context, tx int
 		SELECT id, dispute_id, journal_entry_id, merchant_id, original_amount,
 		       disputed_amount, currency_code, reason_code, reason_text, status,
 		       is_fraud, chargeback_fee, created_at, created_by, resolved_at,
-		       resolved_by
+		       resolved_by, stage_due_at
 		FROM disputes
 		WHERE journal_entry_id = $1
 	`, journalEntryID)
@@ -602,16 +723,20 @@ func (ds *DisputesService) getDisputesByJournalEntry(ctx context.Context, tx int
 	var disputes []*Dispute
 	for rows.Next() {
 		dispute := &Dispute{}
+		var amounts disputeAmounts
 		err := rows.Scan(
 			&dispute.ID, &dispute.DisputeID, &dispute.JournalEntryID, &dispute.MerchantID,
-			&dispute.OriginalAmount, &dispute.DisputedAmount, &dispute.CurrencyCode,
+			&amounts.original, &amounts.disputed, &dispute.CurrencyCode,
 			&dispute.ReasonCode, &dispute.ReasonText, &dispute.Status, &dispute.IsFraud,
-			&dispute.ChargebackFee, &dispute.CreatedAt, &dispute.CreatedBy,
-			&dispute.ResolvedAt, &dispute.ResolvedBy,
+			&amounts.chargebackFee, &dispute.CreatedAt, &dispute.CreatedBy,
+			&dispute.ResolvedAt, &dispute.ResolvedBy, &dispute.StageDueAt,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan dispute: %w", err)
 		}
+		if err := amounts.apply(dispute); err != nil {
+			return nil, err
+		}
 		disputes = append(disputes, dispute)
 	}
 
@@ -628,6 +753,7 @@ func (ds *DisputesService) getFraudReserve(ctx context.Context, tx interface {
 	QueryRow(ctx context.Context, query string, args ...interface{}) pgx.Row
 }, merchantID string) (*FraudReserve, error) {
 	reserve := &FraudReserve{}
+	var percentage, minimum, current pgtype.Numeric
 	
 	err := tx.QueryRow(ctx, `
 		SELECT id, merchant_id, reserve_account_id, reserve_percentage,
@@ -636,8 +762,8 @@ func (ds *DisputesService) getFraudReserve(ctx context.Context, tx interface {
 		FROM fraud_reserves
 		WHERE merchant_id = $1
 	`, merchantID).Scan(
-		&reserve.ID, &reserve.MerchantID, &reserve.ReserveAccountID, &reserve.ReservePercentage,
-		&reserve.MinimumReserveAmount, &reserve.CurrentReserveAmount, &reserve.CurrencyCode,
+		&reserve.ID, &reserve.MerchantID, &reserve.ReserveAccountID, &percentage,
+		&minimum, &current, &reserve.CurrencyCode,
 		&reserve.IsActive, &reserve.CreatedAt, &reserve.CreatedBy, &reserve.UpdatedAt, &reserve.UpdatedBy,
 	)
 
@@ -648,6 +774,16 @@ func (ds *DisputesService) getFraudReserve(ctx context.Context, tx interface {
 		return nil, fmt.Errorf("failed to query fraud reserve: %w", err)
 	}
 
+	if reserve.ReservePercentage, err = money.RateFromNumeric(percentage); err != nil {
+		return nil, fmt.Errorf("failed to read reserve percentage: %w", err)
+	}
+	if reserve.MinimumReserveAmount, err = money.FromNumeric(minimum, reserve.CurrencyCode); err != nil {
+		return nil, fmt.Errorf("failed to read minimum reserve amount: %w", err)
+	}
+	if reserve.CurrentReserveAmount, err = money.FromNumeric(current, reserve.CurrencyCode); err != nil {
+		return nil, fmt.Errorf("failed to read current reserve amount: %w", err)
+	}
+
 	return reserve, nil
 }
 
@@ -657,7 +793,7 @@ func (ds *DisputesService) listDisputes(ctx context.Context, filter DisputeFilte
 		SELECT id, dispute_id, journal_entry_id, merchant_id, original_amount,
 		       disputed_amount, currency_code, reason_code, reason_text, status,
 		       is_fraud, chargeback_fee, created_at, created_by, resolved_at,
-		       resolved_by
+		       resolved_by, stage_due_at
 		FROM disputes
 		WHERE 1=1
 	`
@@ -688,7 +824,21 @@ func (ds *DisputesService) listDisputes(ctx context.Context, filter DisputeFilte
 		argCount++
 	}
 
-	query += " ORDER BY created_at DESC"
+	if filter.Overdue {
+		query += " AND stage_due_at <= CURRENT_TIMESTAMP"
+	}
+
+	if filter.DueWithin > 0 {
+		query += fmt.Sprintf(" AND stage_due_at > CURRENT_TIMESTAMP AND stage_due_at <= CURRENT_TIMESTAMP + make_interval(secs => $%d)", argCount)
+		args = append(args, filter.DueWithin.Seconds())
+		argCount++
+	}
+
+	if filter.Overdue || filter.DueWithin > 0 {
+		query += " ORDER BY stage_due_at"
+	} else {
+		query += " ORDER BY created_at DESC"
+	}
 
 	if filter.Limit > 0 {
 		query += fmt.Sprintf(" LIMIT $%d", argCount)
@@ -710,16 +860,20 @@ func (ds *DisputesService) listDisputes(ctx context.Context, filter DisputeFilte
 	var disputes []*Dispute
 	for rows.Next() {
 		dispute := &Dispute{}
+		var amounts disputeAmounts
 		err := rows.Scan(
 			&dispute.ID, &dispute.DisputeID, &dispute.JournalEntryID, &dispute.MerchantID,
-			&dispute.OriginalAmount, &dispute.DisputedAmount, &dispute.CurrencyCode,
+			&amounts.original, &amounts.disputed, &dispute.CurrencyCode,
 			&dispute.ReasonCode, &dispute.ReasonText, &dispute.Status, &dispute.IsFraud,
-			&dispute.ChargebackFee, &dispute.CreatedAt, &dispute.CreatedBy,
-			&dispute.ResolvedAt, &dispute.ResolvedBy,
+			&amounts.chargebackFee, &dispute.CreatedAt, &dispute.CreatedBy,
+			&dispute.ResolvedAt, &dispute.ResolvedBy, &dispute.StageDueAt,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan dispute: %w", err)
 		}
+		if err := amounts.apply(dispute); err != nil {
+			return nil, err
+		}
 		disputes = append(disputes, dispute)
 	}
 
@@ -732,46 +886,109 @@ type DisputeFilter struct {
 	Status        string
 	IsFraud       *bool
 	CreatedAfter  time.Time
+	// Overdue selects disputes whose stage deadline has passed
+	Overdue       bool
+	// DueWithin selects disputes whose stage deadline falls within it
+	DueWithin     time.Duration
 	Limit         int
 	Offset        int
 }
 
-// calculateChargebackFee calculates chargeback fee based on amount and brand
-func calculateChargebackFee(amount float64, brand CardBrand) float64 {
-	// Typical chargeback fees range from $5 to $25 depending on card network
-	switch brand {
-	case BrandVisa:
-		return min(15.0, max(5.0, amount*0.02)) // 2% capped at $15
-	case BrandMastercard:
-		return min(25.0, max(8.0, amount*0.025)) // 2.5% capped at $25
-	default:
-		return 10.0 // Default fee
+// min and max helper functions; both operands must share a currency
+func min(a, b money.Money) money.Money {
+	if a.LessThan(b) {
+		return a
 	}
+	return b
 }
 
-// min and max helper functions
-func min(a, b float64) float64 {
-	if a < b {
+func max(a, b money.Money) money.Money {
+	if a.GreaterThan(b) {
 		return a
 	}
 	return b
 }
 
-func max(a, b float64) float64 {
-	if a > b {
-		return a
+// disputeAmounts holds the NUMERIC columns of a disputes row until the
+// currency code has been scanned
+type disputeAmounts struct {
+	original      pgtype.Numeric
+	disputed      pgtype.Numeric
+	chargebackFee pgtype.Numeric
+}
+
+// apply converts the scanned amounts into Money on the dispute
+func (a disputeAmounts) apply(dispute *Dispute) error {
+	var err error
+	if dispute.OriginalAmount, err = money.FromNumeric(a.original, dispute.CurrencyCode); err != nil {
+		return fmt.Errorf("failed to read original amount: %w", err)
 	}
-	return b
+	if dispute.DisputedAmount, err = money.FromNumeric(a.disputed, dispute.CurrencyCode); err != nil {
+		return fmt.Errorf("failed to read disputed amount: %w", err)
+	}
+	if dispute.ChargebackFee, err = money.FromNumeric(a.chargebackFee, dispute.CurrencyCode); err != nil {
+		return fmt.Errorf("failed to read chargeback fee: %w", err)
+	}
+	return nil
 }
 
 // PostgresTransitionStore implements TransitionStore interface for PostgreSQL
 type PostgresTransitionStore struct {
 	Pool *pgxpool.Pool
+
+	// tx, when set, is the caller's transaction transitions are read and
+	// written in; see inTx
+	tx pgx.Tx
+}
+
+// inTx returns a store that reads and records transitions within tx, so a
+// transition and its outbox event commit only with the caller's other
+// writes, such as the dispute's status
+func (pts *PostgresTransitionStore) inTx(tx pgx.Tx) *PostgresTransitionStore {
+	return &PostgresTransitionStore{Pool: pts.Pool, tx: tx}
 }
 
-// CreateTransition creates a new state transition
+// querier is the part of a pool or transaction transitions are read through
+type querier interface {
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+// db is the caller's transaction when the store is bound to one, the pool
+// otherwise
+func (pts *PostgresTransitionStore) db() querier {
+	if pts.tx != nil {
+		return pts.tx
+	}
+	return pts.Pool
+}
+
+// CreateTransition creates a new state transition and its
+// dispute.transitioned outbox event in one transaction
 func (pts *PostgresTransitionStore) CreateTransition(ctx context.Context, transition *StateTransition) error {
-	_, err := pts.Pool.Exec(ctx, `
+	if pts.tx != nil {
+		return insertTransition(ctx, pts.tx, transition)
+	}
+
+	tx, err := pts.Pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := insertTransition(ctx, tx, transition); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// insertTransition inserts a transition and its outbox event within tx
+func insertTransition(ctx context.Context, tx pgx.Tx, transition *StateTransition) error {
+	_, err := tx.Exec(ctx, `
 		INSERT INTO dispute_transitions (
 			id, dispute_id, from_state, to_state, reason, transition_hash,
 			prev_hash, created_at, created_by, metadata
@@ -784,14 +1001,14 @@ func (pts *PostgresTransitionStore) CreateTransition(ctx context.Context, transi
 		return fmt.Errorf("failed to insert transition: %w", err)
 	}
 
-	return nil
+	return outbox.Append(ctx, tx, outbox.EventDisputeTransitioned, "dispute", transition.DisputeID, transition)
 }
 
 // GetLatestTransition gets the latest transition for a dispute
 func (pts *PostgresTransitionStore) GetLatestTransition(ctx context.Context, disputeID string) (*StateTransition, error) {
 	transition := &StateTransition{}
 	
-	err := pts.Pool.QueryRow(ctx, `
+	err := pts.db().QueryRow(ctx, `
 		SELECT id, dispute_id, from_state, to_state, reason, transition_hash,
 		       prev_hash, created_at, created_by
 		FROM dispute_transitions
@@ -816,7 +1033,7 @@ func (pts *PostgresTransitionStore) GetLatestTransition(ctx context.Context, dis
 
 // GetTransitionHistory gets the complete transition history for a dispute
 func (pts *PostgresTransitionStore) GetTransitionHistory(ctx context.Context, disputeID string) ([]*StateTransition, error) {
-	rows, err := pts.Pool.Query(ctx, `
+	rows, err := pts.db().Query(ctx, `
 		SELECT id, dispute_id, from_state, to_state, reason, transition_hash,
 		       prev_hash, created_at, created_by
 		FROM dispute_transitions
@@ -849,7 +1066,7 @@ func (pts *PostgresTransitionStore) GetTransitionHistory(ctx context.Context, di
 // GetTransitionHash gets the hash of the latest transition for a dispute
 func (pts *PostgresTransitionStore) GetTransitionHash(ctx context.Context, disputeID string) (string, error) {
 	var hash string
-	err := pts.Pool.QueryRow(ctx, `
+	err := pts.db().QueryRow(ctx, `
 		SELECT transition_hash FROM dispute_transitions
 		WHERE dispute_id = $1
 		ORDER BY created_at DESC, id DESC
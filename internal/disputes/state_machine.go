@@ -20,6 +20,16 @@ const (
 	StateSettled   DisputeState = "SETTLED"
 	StateDisputed  DisputeState = "DISPUTED"
 	StateReversed  DisputeState = "REVERSED"
+
+	// Chargeback cycle after the first chargeback (DISPUTED)
+	StateRepresented    DisputeState = "REPRESENTED"
+	StatePreArbitration DisputeState = "PRE_ARBITRATION"
+	StateArbitration    DisputeState = "ARBITRATION"
+
+	// Outcomes of the chargeback cycle
+	StateWon      DisputeState = "WON"
+	StateLost     DisputeState = "LOST"
+	StateAccepted DisputeState = "ACCEPTED"
 )
 
 // InvalidStateTransitionError represents an invalid state transition
@@ -78,17 +88,42 @@ func NewStateMachine(store TransitionStore) *StateMachine {
 	}
 }
 
-// AllowedTransitions defines valid state transitions
+// AllowedTransitions defines valid state transitions. DISPUTED is the first
+// chargeback: the merchant accepts it or represents with evidence. The issuer
+// either takes the representment (WON) or opens a second cycle
+// (PRE_ARBITRATION), which the merchant accepts or escalates to network
+// arbitration, where the network rules WON or LOST.
 func AllowedTransitions() map[DisputeState][]DisputeState {
 	return map[DisputeState][]DisputeState{
-		StatePending:    {StateAuthorized, StateReversed},
-		StateAuthorized: {StateSettled, StateReversed},
-		StateSettled:    {StateDisputed, StateReversed},
-		StateDisputed:   {StateReversed},
-		StateReversed:   {}, // Terminal state
+		StatePending:        {StateAuthorized, StateReversed},
+		StateAuthorized:     {StateSettled, StateReversed},
+		StateSettled:        {StateDisputed, StateReversed},
+		StateDisputed:       {StateRepresented, StateAccepted, StateReversed},
+		StateRepresented:    {StatePreArbitration, StateWon},
+		StatePreArbitration: {StateArbitration, StateAccepted, StateWon},
+		StateArbitration:    {StateWon, StateLost},
+		StateReversed:       {}, // Terminal state
+		StateWon:            {}, // Terminal state
+		StateLost:           {}, // Terminal state
+		StateAccepted:       {}, // Terminal state
 	}
 }
 
+// IsFinalState reports whether a dispute in state has been resolved
+func IsFinalState(state DisputeState) bool {
+	switch state {
+	case StateReversed, StateWon, StateLost, StateAccepted:
+		return true
+	}
+	return false
+}
+
+// MerchantLiable reports whether a dispute resolved in state is charged back
+// to the merchant
+func MerchantLiable(state DisputeState) bool {
+	return state == StateLost || state == StateAccepted
+}
+
 // IsValidTransition checks if a state transition is allowed
 func (sm *StateMachine) IsValidTransition(fromState, toState DisputeState) bool {
 	allowed := AllowedTransitions()
@@ -119,6 +154,30 @@ func (sm *StateMachine) ValidateOperation(state DisputeState, operation string)
 		if state != StateDisputed && state != StateSettled && state != StateAuthorized && state != StatePending {
 			return &InvalidOperationError{State: state, Operation: operation}
 		}
+	case "represent":
+		if state != StateDisputed {
+			return &InvalidOperationError{State: state, Operation: operation}
+		}
+	case "pre_arbitrate":
+		if state != StateRepresented {
+			return &InvalidOperationError{State: state, Operation: operation}
+		}
+	case "arbitrate":
+		if state != StatePreArbitration {
+			return &InvalidOperationError{State: state, Operation: operation}
+		}
+	case "accept":
+		if state != StateDisputed && state != StatePreArbitration {
+			return &InvalidOperationError{State: state, Operation: operation}
+		}
+	case "win":
+		if state != StateRepresented && state != StatePreArbitration && state != StateArbitration {
+			return &InvalidOperationError{State: state, Operation: operation}
+		}
+	case "lose":
+		if state != StateArbitration {
+			return &InvalidOperationError{State: state, Operation: operation}
+		}
 	case "create":
 		if state != "" { // Initial state
 			return &InvalidOperationError{State: state, Operation: operation}
@@ -211,6 +270,18 @@ func (sm *StateMachine) Transition(ctx context.Context, req TransitionRequest) *
 			operation = "dispute"
 		case StateReversed:
 			operation = "reverse"
+		case StateRepresented:
+			operation = "represent"
+		case StatePreArbitration:
+			operation = "pre_arbitrate"
+		case StateArbitration:
+			operation = "arbitrate"
+		case StateAccepted:
+			operation = "accept"
+		case StateWon:
+			operation = "win"
+		case StateLost:
+			operation = "lose"
 		}
 
 		if err := sm.ValidateOperation(currentState, operation); err != nil {
@@ -363,6 +434,18 @@ func StateDescription(state DisputeState) string {
 		return "Transaction is currently under dispute with chargeback initiated"
 	case StateReversed:
 		return "Dispute has been resolved with funds reversed"
+	case StateRepresented:
+		return "Merchant has represented the chargeback with evidence"
+	case StatePreArbitration:
+		return "Issuer has rejected the representment and opened a second cycle"
+	case StateArbitration:
+		return "Dispute has been escalated to the card network for arbitration"
+	case StateWon:
+		return "Dispute has been resolved in the merchant's favour"
+	case StateLost:
+		return "Network arbitration has found against the merchant"
+	case StateAccepted:
+		return "Merchant has accepted liability for the chargeback"
 	default:
 		return "Unknown state"
 	}
@@ -379,6 +462,18 @@ func OperationDescription(operation string) string {
 		return "Initiate a formal dispute/chargeback"
 	case "reverse":
 		return "Reverse the dispute and release any holds"
+	case "represent":
+		return "Represent the chargeback with compelling evidence"
+	case "pre_arbitrate":
+		return "Record the issuer's pre-arbitration (second cycle)"
+	case "arbitrate":
+		return "Escalate the dispute to network arbitration"
+	case "accept":
+		return "Accept liability for the chargeback"
+	case "win":
+		return "Resolve the dispute in the merchant's favour"
+	case "lose":
+		return "Resolve the dispute against the merchant"
 	case "create":
 		return "Create a new dispute"
 	default:
@@ -8,6 +8,8 @@ import (
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/example/pci-infra/internal/money"
 )
 
 // MockTransitionStore implements TransitionStore for testing
@@ -72,12 +74,53 @@ func TestStateMachine_ValidTransitions(t *testing.T) {
 	assert.Contains(t, allowed[StateSettled], StateReversed)
 	assert.Equal(t, 2, len(allowed[StateSettled]))
 	
-	// DISPUTED can only go to REVERSED
+	// DISPUTED (first chargeback) can be represented, accepted or REVERSED
+	assert.Contains(t, allowed[StateDisputed], StateRepresented)
+	assert.Contains(t, allowed[StateDisputed], StateAccepted)
 	assert.Contains(t, allowed[StateDisputed], StateReversed)
-	assert.Equal(t, 1, len(allowed[StateDisputed]))
+	assert.Equal(t, 3, len(allowed[StateDisputed]))
+
+	// The issuer takes a representment or opens a second cycle
+	assert.ElementsMatch(t, []DisputeState{StatePreArbitration, StateWon}, allowed[StateRepresented])
+	assert.ElementsMatch(t, []DisputeState{StateArbitration, StateAccepted, StateWon}, allowed[StatePreArbitration])
+	assert.ElementsMatch(t, []DisputeState{StateWon, StateLost}, allowed[StateArbitration])
 	
-	// REVERSED is terminal
-	assert.Equal(t, 0, len(allowed[StateReversed]))
+	// REVERSED and the outcomes are terminal
+	for _, state := range []DisputeState{StateReversed, StateWon, StateLost, StateAccepted} {
+		assert.Equal(t, 0, len(allowed[state]))
+		assert.True(t, IsFinalState(state))
+	}
+	assert.False(t, IsFinalState(StateArbitration))
+	assert.True(t, sm.IsValidTransition(StateArbitration, StateLost))
+	assert.False(t, sm.IsValidTransition(StateRepresented, StateLost))
+}
+
+func TestStateMachine_ChargebackCycle(t *testing.T) {
+	ctx := context.Background()
+	steps := []DisputeState{StatePending, StateAuthorized, StateSettled, StateDisputed,
+		StateRepresented, StatePreArbitration, StateArbitration, StateLost}
+
+	sm := NewStateMachine(&MockTransitionStore{})
+	for _, to := range steps {
+		result := sm.Transition(ctx, TransitionRequest{DisputeID: "DSP-1", ToState: to, Reason: "step", CreatedBy: "ops"})
+		require.True(t, result.Success, "transition to %s: %v", to, result.Error)
+	}
+	result := sm.Transition(ctx, TransitionRequest{DisputeID: "DSP-1", ToState: StateWon, Reason: "late", CreatedBy: "ops"})
+	assert.False(t, result.Success)
+
+	// A merchant may accept the second cycle, but not a representment
+	sm = NewStateMachine(&MockTransitionStore{})
+	for _, to := range steps[:5] {
+		require.True(t, sm.Transition(ctx, TransitionRequest{DisputeID: "DSP-2", ToState: to, Reason: "step", CreatedBy: "ops"}).Success)
+	}
+	assert.False(t, sm.Transition(ctx, TransitionRequest{DisputeID: "DSP-2", ToState: StateAccepted, Reason: "accept", CreatedBy: "ops"}).Success)
+	assert.True(t, sm.Transition(ctx, TransitionRequest{DisputeID: "DSP-2", ToState: StatePreArbitration, Reason: "second cycle", CreatedBy: "ops"}).Success)
+	assert.True(t, sm.Transition(ctx, TransitionRequest{DisputeID: "DSP-2", ToState: StateAccepted, Reason: "accept", CreatedBy: "ops"}).Success)
+
+	assert.True(t, MerchantLiable(StateLost))
+	assert.True(t, MerchantLiable(StateAccepted))
+	assert.False(t, MerchantLiable(StateWon))
+	assert.False(t, MerchantLiable(StateReversed))
 }
 
 func TestStateMachine_TransitionValidation(t *testing.T) {
@@ -228,6 +271,21 @@ func TestStateMachine_OperationValidation(t *testing.T) {
 		{StateReversed, "settle", false},
 		{StateReversed, "dispute", false},
 		{StateReversed, "reverse", false},
+
+		{StateDisputed, "represent", true},
+		{StateDisputed, "accept", true},
+		{StateDisputed, "win", false},
+		{StateRepresented, "pre_arbitrate", true},
+		{StateRepresented, "win", true},
+		{StateRepresented, "accept", false},
+		{StatePreArbitration, "arbitrate", true},
+		{StatePreArbitration, "accept", true},
+		{StatePreArbitration, "lose", false},
+		{StateArbitration, "win", true},
+		{StateArbitration, "lose", true},
+		{StateArbitration, "accept", false},
+		{StateLost, "represent", false},
+		{StateWon, "arbitrate", false},
 	}
 
 	for _, test := range tests {
@@ -375,8 +433,8 @@ func TestDisputeValidation(t *testing.T) {
 				DisputeID:      uuid.NewString(),
 				JournalEntryID: uuid.NewString(),
 				MerchantID:     uuid.NewString(),
-				OriginalAmount: 100.0,
-				DisputedAmount: 50.0,
+				OriginalAmount: money.MustParse("100.00", "USD"),
+				DisputedAmount: money.MustParse("50.00", "USD"),
 				CurrencyCode:   "USD",
 				ReasonCode:     "14.1",
 				CreatedBy:      "test-user",
@@ -389,8 +447,8 @@ func TestDisputeValidation(t *testing.T) {
 				DisputeID:      uuid.NewString(),
 				JournalEntryID: uuid.NewString(),
 				MerchantID:     uuid.NewString(),
-				OriginalAmount: 100.0,
-				DisputedAmount: 50.0,
+				OriginalAmount: money.MustParse("100.00", "USD"),
+				DisputedAmount: money.MustParse("50.00", "USD"),
 				CurrencyCode:   "USD",
 				ReasonCode:     "999.9",
 				CreatedBy:      "test-user",
@@ -403,8 +461,8 @@ func TestDisputeValidation(t *testing.T) {
 				DisputeID:      uuid.NewString(),
 				JournalEntryID: uuid.NewString(),
 				MerchantID:     uuid.NewString(),
-				OriginalAmount: 50.0,
-				DisputedAmount: 100.0,
+				OriginalAmount: money.MustParse("50.00", "USD"),
+				DisputedAmount: money.MustParse("100.00", "USD"),
 				CurrencyCode:   "USD",
 				ReasonCode:     "14.1",
 				CreatedBy:      "test-user",
@@ -417,8 +475,8 @@ func TestDisputeValidation(t *testing.T) {
 				DisputeID:      uuid.NewString(),
 				JournalEntryID: uuid.NewString(),
 				MerchantID:     uuid.NewString(),
-				OriginalAmount: 100.0,
-				DisputedAmount: 50.0,
+				OriginalAmount: money.MustParse("100.00", "USD"),
+				DisputedAmount: money.MustParse("50.00", "USD"),
 				CurrencyCode:   "INVALID",
 				ReasonCode:     "14.1",
 				CreatedBy:      "test-user",
@@ -449,6 +507,9 @@ func TestStateDescriptions(t *testing.T) {
 		{StateSettled, "Transaction has been settled and is eligible for dispute"},
 		{StateDisputed, "Transaction is currently under dispute with chargeback initiated"},
 		{StateReversed, "Dispute has been resolved with funds reversed"},
+		{StateRepresented, "Merchant has represented the chargeback with evidence"},
+		{StateArbitration, "Dispute has been escalated to the card network for arbitration"},
+		{StateAccepted, "Merchant has accepted liability for the chargeback"},
 		{"UNKNOWN", "Unknown state"},
 	}
 
@@ -468,6 +529,7 @@ func TestOperationDescriptions(t *testing.T) {
 		{"settle", "Settle the underlying transaction"},
 		{"dispute", "Initiate a formal dispute/chargeback"},
 		{"reverse", "Reverse the dispute and release any holds"},
+		{"arbitrate", "Escalate the dispute to network arbitration"},
 		{"create", "Create a new dispute"},
 		{"unknown", "Unknown operation"},
 	}
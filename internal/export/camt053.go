@@ -0,0 +1,168 @@
+package export
+
+import (
+	"encoding/xml"
+	"io"
+	"time"
+
+	"github.com/example/pci-infra/internal/ledger"
+	"github.com/example/pci-infra/internal/money"
+)
+
+const camtNamespace = "urn:iso:std:iso:20022:tech:xsd:camt.053.001.02"
+
+type camtDocument struct {
+	XMLName     xml.Name        `xml:"Document"`
+	Namespace   string          `xml:"xmlns,attr"`
+	GroupHeader camtGroupHeader `xml:"BkToCstmrStmt>GrpHdr"`
+	Statement   camtStatement   `xml:"BkToCstmrStmt>Stmt"`
+}
+
+type camtGroupHeader struct {
+	MessageID string `xml:"MsgId"`
+	CreatedAt string `xml:"CreDtTm"`
+}
+
+type camtStatement struct {
+	ID        string        `xml:"Id"`
+	CreatedAt string        `xml:"CreDtTm"`
+	From      string        `xml:"FrToDt>FrDtTm"`
+	To        string        `xml:"FrToDt>ToDtTm"`
+	AccountID string        `xml:"Acct>Id>Othr>Id"`
+	Currency  string        `xml:"Acct>Ccy"`
+	Balances  []camtBalance `xml:"Bal"`
+	Entries   []camtEntry   `xml:"Ntry"`
+}
+
+type camtAmount struct {
+	Currency string `xml:"Ccy,attr"`
+	Value    string `xml:",chardata"`
+}
+
+type camtBalance struct {
+	Type      string     `xml:"Tp>CdOrPrtry>Cd"`
+	Amount    camtAmount `xml:"Amt"`
+	Indicator string     `xml:"CdtDbtInd"`
+	Date      string     `xml:"Dt>Dt"`
+}
+
+type camtEntry struct {
+	Reference         string           `xml:"NtryRef"`
+	Amount            camtAmount       `xml:"Amt"`
+	Indicator         string           `xml:"CdtDbtInd"`
+	Status            string           `xml:"Sts"`
+	BookingDate       string           `xml:"BookgDt>DtTm"`
+	ServicerReference string           `xml:"AcctSvcrRef"`
+	BankTxCode        string           `xml:"BkTxCd>Prtry>Cd"`
+	EndToEndID        string           `xml:"NtryDtls>TxDtls>Refs>EndToEndId"`
+	Proprietary       *camtProprietary `xml:"NtryDtls>TxDtls>Refs>Prtry"`
+	Unstructured      string           `xml:"NtryDtls>TxDtls>RmtInf>Ustrd,omitempty"`
+}
+
+type camtProprietary struct {
+	Type      string `xml:"Tp"`
+	Reference string `xml:"Ref"`
+}
+
+// writeCAMT053 writes a camt.053.001.02 bank to customer statement. Each entry
+// is an Ntry: its ID is the NtryRef and its transaction ID the AcctSvcrRef.
+// reference_id is the end to end ID and, with reference_type, the proprietary
+// reference; reference_type is also the proprietary bank transaction code.
+// The description is the remittance information.
+func writeCAMT053(w io.Writer, st *ledger.Statement, opts Options) error {
+	from, err := parseTime("from", st.From)
+	if err != nil {
+		return err
+	}
+	to, err := parseTime("to", st.To)
+	if err != nil {
+		return err
+	}
+	created := opts.GeneratedAt.UTC().Format(time.RFC3339)
+
+	doc := camtDocument{
+		Namespace:   camtNamespace,
+		GroupHeader: camtGroupHeader{MessageID: opts.MessageID, CreatedAt: created},
+		Statement: camtStatement{
+			ID:        opts.MessageID,
+			CreatedAt: created,
+			From:      from.Format(time.RFC3339),
+			To:        to.Format(time.RFC3339),
+			AccountID: accountIdentifier(st),
+			Currency:  st.CurrencyCode,
+			Balances: []camtBalance{
+				camtBalanceOf("OPBD", st.OpeningBalance, from),
+				// To is exclusive, so the closing balance is as of the day before
+				camtBalanceOf("CLBD", st.ClosingBalance, to.Add(-time.Nanosecond)),
+			},
+		},
+	}
+
+	for _, e := range st.Entries {
+		posted, err := parseTime("posted_at", e.PostedAt)
+		if err != nil {
+			return err
+		}
+		entry := camtEntry{
+			Reference:         e.EntryID,
+			Amount:            camtAmountOf(e.BalanceChange),
+			Indicator:         camtIndicator(e.BalanceChange),
+			Status:            "BOOK",
+			BookingDate:       posted.Format(time.RFC3339),
+			ServicerReference: e.TransactionID,
+			BankTxCode:        e.ReferenceType,
+			EndToEndID:        e.ReferenceID,
+			Unstructured:      e.Description,
+		}
+		if entry.BankTxCode == "" {
+			entry.BankTxCode = "OTHR"
+		}
+		if entry.EndToEndID == "" {
+			entry.EndToEndID = "NOTPROVIDED"
+		}
+		if e.ReferenceType != "" && e.ReferenceID != "" {
+			entry.Proprietary = &camtProprietary{Type: e.ReferenceType, Reference: e.ReferenceID}
+		}
+		doc.Statement.Entries = append(doc.Statement.Entries, entry)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+func camtBalanceOf(code string, m money.Money, at time.Time) camtBalance {
+	return camtBalance{
+		Type:      code,
+		Amount:    camtAmountOf(m),
+		Indicator: camtIndicator(m),
+		Date:      at.Format(ledger.DateLayout),
+	}
+}
+
+func camtAmountOf(m money.Money) camtAmount {
+	return camtAmount{Currency: m.Currency(), Value: m.Abs().Amount()}
+}
+
+func camtIndicator(m money.Money) string {
+	if m.IsNegative() {
+		return "DBIT"
+	}
+	return "CRDT"
+}
+
+// accountIdentifier names the account to the outside world by its number,
+// falling back to its ID
+func accountIdentifier(st *ledger.Statement) string {
+	if st.AccountNumber != "" {
+		return st.AccountNumber
+	}
+	return st.AccountID
+}
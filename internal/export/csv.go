@@ -0,0 +1,34 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+
+	"github.com/example/pci-infra/internal/ledger"
+)
+
+// writeCSV writes one row per entry between an opening and a closing balance
+// row. amount is signed by its effect on the balance.
+func writeCSV(w io.Writer, st *ledger.Statement) error {
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"posted_at", "entry_id", "transaction_id", "direction", "amount", "balance", "currency_code", "description", "reference_type", "reference_id"})
+	_ = cw.Write([]string{st.From, "", "", "", "", st.OpeningBalance.Amount(), st.CurrencyCode, "Opening balance", "", ""})
+	for _, e := range st.Entries {
+		_ = cw.Write([]string{
+			e.PostedAt, e.EntryID, e.TransactionID, direction(e.BalanceChange.IsNegative()),
+			e.BalanceChange.Amount(), e.RunningBalance.Amount(), st.CurrencyCode,
+			e.Description, e.ReferenceType, e.ReferenceID,
+		})
+	}
+	_ = cw.Write([]string{st.To, "", "", "", "", st.ClosingBalance.Amount(), st.CurrencyCode, "Closing balance", "", ""})
+	cw.Flush()
+	return cw.Error()
+}
+
+// direction is whether an entry is a credit or debit to the account holder
+func direction(negative bool) string {
+	if negative {
+		return "debit"
+	}
+	return "credit"
+}
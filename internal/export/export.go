@@ -0,0 +1,105 @@
+// Package export renders ledger account statements in standard formats:
+// ISO 20022 camt.053, OFX and CSV. Amounts are signed by their effect on the
+// account's balance, so money into the account is a credit.
+package export
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/example/pci-infra/internal/ledger"
+)
+
+const (
+	FormatCSV     = "csv"
+	FormatCAMT053 = "camt053"
+	FormatOFX     = "ofx"
+)
+
+// pageSize is the largest statement page the ledger serves
+const pageSize = 1000
+
+// ErrUnknownFormat is returned for a format this package cannot write
+var ErrUnknownFormat = fmt.Errorf("%w: unknown statement format", ledger.ErrInvalidRequest)
+
+// Options identify an export. MessageID names the camt.053 message and
+// statement and the OFX transaction; GeneratedAt is when the file was made.
+// BankID fills the OFX BANKACCTFROM, which has no use for a ledger but must be
+// present, and defaults to LEDGER.
+type Options struct {
+	MessageID   string
+	GeneratedAt time.Time
+	BankID      string
+}
+
+// Write renders a statement in format. The statement should hold every entry
+// of the period; see Collect.
+func Write(w io.Writer, format string, st *ledger.Statement, opts Options) error {
+	switch format {
+	case FormatCSV:
+		return writeCSV(w, st)
+	case FormatCAMT053:
+		return writeCAMT053(w, st, opts)
+	case FormatOFX:
+		return writeOFX(w, st, opts)
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+	}
+}
+
+// ContentType is the media type of a format
+func ContentType(format string) string {
+	switch format {
+	case FormatCAMT053:
+		return "application/xml"
+	case FormatOFX:
+		return "application/x-ofx"
+	default:
+		return "text/csv; charset=utf-8"
+	}
+}
+
+// Filename is the download name of a statement, e.g. 1000-2024-01-01.ofx
+func Filename(format string, st *ledger.Statement) string {
+	name := accountIdentifier(st)
+	if from, err := time.Parse(time.RFC3339Nano, st.From); err == nil {
+		name += "-" + from.UTC().Format(ledger.DateLayout)
+	}
+	ext := map[string]string{FormatCSV: ".csv", FormatCAMT053: ".xml", FormatOFX: ".ofx"}[format]
+	return name + ext
+}
+
+// Collect reads every page of a statement into one
+func Collect(ctx context.Context, get func(context.Context, ledger.StatementRequest) (*ledger.Statement, error), req ledger.StatementRequest) (*ledger.Statement, error) {
+	req.Cursor = ""
+	req.Limit = pageSize
+
+	var st *ledger.Statement
+	for {
+		page, err := get(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		if st == nil {
+			st = page
+		} else {
+			st.Entries = append(st.Entries, page.Entries...)
+		}
+		if page.NextCursor == "" {
+			st.NextCursor = ""
+			return st, nil
+		}
+		req.Cursor = page.NextCursor
+	}
+}
+
+// parseTime reads a statement timestamp
+func parseTime(field, s string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("statement %s %q: %w", field, s, err)
+	}
+	return t.UTC(), nil
+}
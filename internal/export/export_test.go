@@ -0,0 +1,146 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/pci-infra/internal/ledger"
+	"github.com/example/pci-infra/internal/money"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+func usd(s string) money.Money {
+	return money.MustParse(s, "USD")
+}
+
+func testStatement() *ledger.Statement {
+	return &ledger.Statement{
+		AccountID:      "7c1f4c5e-2b7a-4f0e-9d7e-5a8c3b2e1f00",
+		AccountNumber:  "2100-0042",
+		CurrencyCode:   "USD",
+		From:           "2024-01-01T00:00:00Z",
+		To:             "2024-02-01T00:00:00Z",
+		OpeningBalance: usd("100.00"),
+		ClosingBalance: usd("1225.50"),
+		Entries: []ledger.StatementEntry{
+			{
+				EntryID:        "e0000000-0000-0000-0000-000000000001",
+				TransactionID:  "t0000000-0000-0000-0000-000000000001",
+				EntryType:      "credit",
+				Amount:         usd("1250.00"),
+				BalanceChange:  usd("1250.00"),
+				RunningBalance: usd("1350.00"),
+				Description:    "Card settlement batch 2024-01-02",
+				ReferenceType:  "settlement",
+				ReferenceID:    "STL-20240102",
+				PostedAt:       "2024-01-02T10:30:00Z",
+			},
+			{
+				EntryID:        "e0000000-0000-0000-0000-000000000002",
+				TransactionID:  "t0000000-0000-0000-0000-000000000002",
+				EntryType:      "debit",
+				Amount:         usd("124.50"),
+				BalanceChange:  usd("-124.50"),
+				RunningBalance: usd("1225.50"),
+				Description:    `Chargeback fee, "ref" <CB-77> & co`,
+				PostedAt:       "2024-01-15T08:00:00.123456Z",
+			},
+		},
+	}
+}
+
+func TestWriteGolden(t *testing.T) {
+	opts := Options{MessageID: "STMT-2100-0042-202401", GeneratedAt: time.Date(2024, 2, 1, 6, 0, 0, 0, time.UTC)}
+
+	for _, tc := range []struct {
+		format string
+		golden string
+	}{
+		{FormatCSV, "statement.csv"},
+		{FormatCAMT053, "statement.camt053.xml"},
+		{FormatOFX, "statement.ofx"},
+	} {
+		t.Run(tc.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, Write(&buf, tc.format, testStatement(), opts))
+
+			path := filepath.Join("testdata", tc.golden)
+			if *update {
+				require.NoError(t, os.WriteFile(path, buf.Bytes(), 0o644))
+			}
+			want, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assert.Equal(t, string(want), buf.String())
+		})
+	}
+}
+
+func TestWriteEmptyStatement(t *testing.T) {
+	st := testStatement()
+	st.Entries = nil
+	st.OpeningBalance = usd("-5.00")
+	st.ClosingBalance = usd("-5.00")
+
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, FormatCAMT053, st, Options{MessageID: "m"}))
+	assert.NotContains(t, buf.String(), "<Ntry>")
+	assert.Equal(t, 2, strings.Count(buf.String(), "<CdtDbtInd>DBIT</CdtDbtInd>"))
+
+	buf.Reset()
+	require.NoError(t, Write(&buf, FormatOFX, st, Options{MessageID: "m"}))
+	assert.Contains(t, buf.String(), "<BANKID>LEDGER</BANKID>")
+	assert.Contains(t, buf.String(), "<BALAMT>-5.00</BALAMT>")
+}
+
+func TestWriteUnknownFormat(t *testing.T) {
+	err := Write(&bytes.Buffer{}, "mt940", testStatement(), Options{})
+	assert.True(t, errors.Is(err, ErrUnknownFormat))
+	assert.True(t, errors.Is(err, ledger.ErrInvalidRequest))
+}
+
+func TestFilename(t *testing.T) {
+	assert.Equal(t, "2100-0042-2024-01-01.ofx", Filename(FormatOFX, testStatement()))
+	assert.Equal(t, "2100-0042-2024-01-01.xml", Filename(FormatCAMT053, testStatement()))
+}
+
+func TestCollect(t *testing.T) {
+	full := testStatement()
+	var requests []ledger.StatementRequest
+	get := func(ctx context.Context, req ledger.StatementRequest) (*ledger.Statement, error) {
+		requests = append(requests, req)
+		page := *full
+		if req.Cursor == "" {
+			page.Entries = full.Entries[:1]
+			page.NextCursor = "page-2"
+		} else {
+			page.Entries = full.Entries[1:]
+		}
+		return &page, nil
+	}
+
+	st, err := Collect(context.Background(), get, ledger.StatementRequest{AccountID: full.AccountID, Cursor: "ignored", Limit: 5})
+	require.NoError(t, err)
+	assert.Equal(t, full.Entries, st.Entries)
+	assert.Empty(t, st.NextCursor)
+
+	require.Len(t, requests, 2)
+	assert.Equal(t, "", requests[0].Cursor)
+	assert.Equal(t, pageSize, requests[0].Limit)
+	assert.Equal(t, "page-2", requests[1].Cursor)
+
+	_, err = Collect(context.Background(), func(context.Context, ledger.StatementRequest) (*ledger.Statement, error) {
+		return nil, ledger.ErrAccountNotFound
+	}, ledger.StatementRequest{})
+	assert.True(t, errors.Is(err, ledger.ErrAccountNotFound))
+}
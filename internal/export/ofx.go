@@ -0,0 +1,126 @@
+package export
+
+import (
+	"encoding/xml"
+	"io"
+	"time"
+
+	"github.com/example/pci-infra/internal/ledger"
+)
+
+const ofxHeader = `<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>` + "\n"
+
+type ofxDocument struct {
+	XMLName   xml.Name     `xml:"OFX"`
+	SignOn    ofxSignOn    `xml:"SIGNONMSGSRSV1>SONRS"`
+	Statement ofxStatement `xml:"BANKMSGSRSV1>STMTTRNRS"`
+}
+
+type ofxStatus struct {
+	Code     int    `xml:"CODE"`
+	Severity string `xml:"SEVERITY"`
+}
+
+type ofxSignOn struct {
+	Status     ofxStatus `xml:"STATUS"`
+	ServerTime string    `xml:"DTSERVER"`
+	Language   string    `xml:"LANGUAGE"`
+}
+
+type ofxStatement struct {
+	TransactionUID string           `xml:"TRNUID"`
+	Status         ofxStatus        `xml:"STATUS"`
+	Currency       string           `xml:"STMTRS>CURDEF"`
+	BankID         string           `xml:"STMTRS>BANKACCTFROM>BANKID"`
+	AccountID      string           `xml:"STMTRS>BANKACCTFROM>ACCTID"`
+	AccountType    string           `xml:"STMTRS>BANKACCTFROM>ACCTTYPE"`
+	Start          string           `xml:"STMTRS>BANKTRANLIST>DTSTART"`
+	End            string           `xml:"STMTRS>BANKTRANLIST>DTEND"`
+	Transactions   []ofxTransaction `xml:"STMTRS>BANKTRANLIST>STMTTRN"`
+	Balance        string           `xml:"STMTRS>LEDGERBAL>BALAMT"`
+	BalanceAsOf    string           `xml:"STMTRS>LEDGERBAL>DTASOF"`
+}
+
+type ofxTransaction struct {
+	Type       string `xml:"TRNTYPE"`
+	Posted     string `xml:"DTPOSTED"`
+	Amount     string `xml:"TRNAMT"`
+	FITID      string `xml:"FITID"`
+	ServerTxID string `xml:"SRVRTID"`
+	Reference  string `xml:"REFNUM,omitempty"`
+	Name       string `xml:"NAME,omitempty"`
+	Memo       string `xml:"MEMO,omitempty"`
+}
+
+// writeOFX writes an OFX 2.2 bank statement response. Each entry is a
+// STMTTRN: its ID is the FITID, its transaction ID the SRVRTID and
+// reference_id the REFNUM. OFX has no field for the kind of reference, so
+// reference_type is the NAME; the description is the MEMO.
+func writeOFX(w io.Writer, st *ledger.Statement, opts Options) error {
+	from, err := parseTime("from", st.From)
+	if err != nil {
+		return err
+	}
+	to, err := parseTime("to", st.To)
+	if err != nil {
+		return err
+	}
+	bankID := opts.BankID
+	if bankID == "" {
+		bankID = "LEDGER"
+	}
+
+	ok := ofxStatus{Code: 0, Severity: "INFO"}
+	doc := ofxDocument{
+		SignOn: ofxSignOn{Status: ok, ServerTime: ofxTime(opts.GeneratedAt), Language: "ENG"},
+		Statement: ofxStatement{
+			TransactionUID: opts.MessageID,
+			Status:         ok,
+			Currency:       st.CurrencyCode,
+			BankID:         bankID,
+			AccountID:      accountIdentifier(st),
+			AccountType:    "CHECKING",
+			Start:          ofxTime(from),
+			End:            ofxTime(to),
+			Balance:        st.ClosingBalance.Amount(),
+			BalanceAsOf:    ofxTime(to),
+		},
+	}
+
+	for _, e := range st.Entries {
+		posted, err := parseTime("posted_at", e.PostedAt)
+		if err != nil {
+			return err
+		}
+		trnType := "CREDIT"
+		if e.BalanceChange.IsNegative() {
+			trnType = "DEBIT"
+		}
+		doc.Statement.Transactions = append(doc.Statement.Transactions, ofxTransaction{
+			Type:       trnType,
+			Posted:     ofxTime(posted),
+			Amount:     e.BalanceChange.Amount(),
+			FITID:      e.EntryID,
+			ServerTxID: e.TransactionID,
+			Reference:  e.ReferenceID,
+			Name:       e.ReferenceType,
+			Memo:       e.Description,
+		})
+	}
+
+	if _, err := io.WriteString(w, xml.Header+ofxHeader); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+// ofxTime formats a time as OFX does, in UTC
+func ofxTime(t time.Time) string {
+	return t.UTC().Format("20060102150405.000") + "[0:GMT]"
+}
@@ -0,0 +1,274 @@
+// Package fees charges processing, chargeback and payout fees by schedule. A
+// schedule applies to one fee type and currency, and optionally to one
+// merchant and/or card brand. Its fee on an amount is a fixed amount plus a
+// rate, optionally tiered by amount, clamped between a floor and a cap.
+// Schedules are effective-dated and never changed; the most specific schedule
+// in force is charged. Fees post as their own legs, crediting the schedule's
+// revenue account.
+package fees
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/example/pci-infra/internal/ledger"
+	"github.com/example/pci-infra/internal/money"
+)
+
+// Fee types
+const (
+	FeeTypeProcessing = "processing"
+	FeeTypeChargeback = "chargeback"
+	FeeTypePayout     = "payout"
+)
+
+// ReferenceTypeFee marks the legs of a fee charged on its own; their
+// ReferenceID is the charge's reference
+const ReferenceTypeFee = "fee"
+
+// ErrInvalidSchedule is returned when a fee schedule, quote or charge request
+// is malformed
+var ErrInvalidSchedule = fmt.Errorf("%w: fee schedule", ledger.ErrInvalidRequest)
+
+// ErrScheduleNotFound is returned when a referenced schedule does not exist,
+// or no schedule is in force for a fee
+var ErrScheduleNotFound = errors.New("fee schedule not found")
+
+// Tier replaces a schedule's fixed amount and rate for amounts up to UpTo. A
+// nil UpTo covers every larger amount.
+type Tier struct {
+	UpTo        *money.Money `json:"up_to"`
+	FixedAmount money.Money  `json:"fixed_amount"`
+	Rate        money.Rate   `json:"rate"`
+}
+
+// Schedule is how a fee is charged from EffectiveFrom until EffectiveTo, or
+// until a later schedule for the same fee, merchant, brand and currency
+// supersedes it. An empty MerchantID or CardBrand matches every merchant or
+// brand. A nil MinimumFee or MaximumFee leaves the fee unclamped.
+type Schedule struct {
+	ID               string       `json:"id"`
+	FeeType          string       `json:"fee_type"`
+	MerchantID       string       `json:"merchant_id,omitempty"`
+	CardBrand        string       `json:"card_brand,omitempty"`
+	CurrencyCode     string       `json:"currency_code"`
+	FixedAmount      money.Money  `json:"fixed_amount"`
+	Rate             money.Rate   `json:"rate"`
+	Tiers            []Tier       `json:"tiers"`
+	MinimumFee       *money.Money `json:"minimum_fee,omitempty"`
+	MaximumFee       *money.Money `json:"maximum_fee,omitempty"`
+	RevenueAccountID string       `json:"revenue_account_id"`
+	EffectiveFrom    string       `json:"effective_from"`
+	EffectiveTo      string       `json:"effective_to,omitempty"`
+	CreatedBy        string       `json:"created_by"`
+	CreatedAt        string       `json:"created_at"`
+}
+
+// ScheduleRequest represents the request to add a fee schedule. Its currency
+// is FixedAmount's. A zero EffectiveFrom makes it effective immediately; a nil
+// EffectiveTo leaves it in force until superseded.
+type ScheduleRequest struct {
+	FeeType          string       `json:"fee_type"`
+	MerchantID       string       `json:"merchant_id"`
+	CardBrand        string       `json:"card_brand"`
+	FixedAmount      money.Money  `json:"fixed_amount"`
+	Rate             money.Rate   `json:"rate"`
+	Tiers            []Tier       `json:"tiers"`
+	MinimumFee       *money.Money `json:"minimum_fee,omitempty"`
+	MaximumFee       *money.Money `json:"maximum_fee,omitempty"`
+	RevenueAccountID string       `json:"revenue_account_id"`
+	EffectiveFrom    time.Time    `json:"effective_from"`
+	EffectiveTo      *time.Time   `json:"effective_to,omitempty"`
+	CreatedBy        string       `json:"created_by"`
+}
+
+// ScheduleFilter narrows a schedule query. Empty fields match every schedule;
+// a zero At lists schedules whatever their effective dates.
+type ScheduleFilter struct {
+	FeeType      string
+	MerchantID   string
+	CardBrand    string
+	CurrencyCode string
+	At           time.Time
+	Limit        int
+	Offset       int
+}
+
+// QuoteRequest asks for the fee of FeeType on Amount for a merchant and card
+// brand, at At or now when zero
+type QuoteRequest struct {
+	FeeType    string      `json:"fee_type"`
+	MerchantID string      `json:"merchant_id"`
+	CardBrand  string      `json:"card_brand"`
+	Amount     money.Money `json:"amount"`
+	At         time.Time   `json:"at"`
+}
+
+// Fee is a fee worked out from a schedule: Amount is charged on Base and
+// credited to RevenueAccountID
+type Fee struct {
+	ScheduleID       string      `json:"schedule_id"`
+	FeeType          string      `json:"fee_type"`
+	Base             money.Money `json:"base"`
+	Amount           money.Money `json:"amount"`
+	RevenueAccountID string      `json:"revenue_account_id"`
+	// TransactionID is the posting of a charged fee, empty for quotes and
+	// for zero fees, which are not posted
+	TransactionID string `json:"transaction_id,omitempty"`
+}
+
+// ChargeRequest represents the request to charge a fee on its own, debiting
+// PayerAccountID. TransactionID makes a retried charge post once; it is
+// generated when empty. ReferenceID is what the fee was charged for.
+type ChargeRequest struct {
+	QuoteRequest
+	PayerAccountID string `json:"payer_account_id"`
+	TransactionID  string `json:"transaction_id"`
+	ReferenceID    string `json:"reference_id"`
+	CreatedBy      string `json:"created_by"`
+}
+
+// validFeeType reports whether t is a known fee type
+func validFeeType(t string) bool {
+	switch t {
+	case FeeTypeProcessing, FeeTypeChargeback, FeeTypePayout:
+		return true
+	}
+	return false
+}
+
+// validateSchedule checks a schedule request before its revenue account is
+// looked up
+func validateSchedule(req ScheduleRequest) error {
+	if !validFeeType(req.FeeType) {
+		return fmt.Errorf("%w: unknown fee type %q", ErrInvalidSchedule, req.FeeType)
+	}
+	if req.RevenueAccountID == "" {
+		return fmt.Errorf("%w: revenue account is required", ErrInvalidSchedule)
+	}
+	if req.CreatedBy == "" {
+		return fmt.Errorf("%w: created_by is required", ErrInvalidSchedule)
+	}
+	currency := req.FixedAmount.Currency()
+	if _, err := money.Exponent(currency); err != nil {
+		return fmt.Errorf("%w: fixed amount currency: %v", ErrInvalidSchedule, err)
+	}
+	if req.FixedAmount.IsNegative() {
+		return fmt.Errorf("%w: fixed amount must not be negative", ErrInvalidSchedule)
+	}
+	if req.Rate.BasisPoints() < 0 || req.Rate.BasisPoints() >= 10000 {
+		return fmt.Errorf("%w: rate must be at least 0 and below 1", ErrInvalidSchedule)
+	}
+	for _, b := range []struct {
+		name  string
+		bound *money.Money
+	}{{"minimum fee", req.MinimumFee}, {"maximum fee", req.MaximumFee}} {
+		if b.bound == nil {
+			continue
+		}
+		if b.bound.Currency() != currency {
+			return fmt.Errorf("%w: %s is in %s, fixed amount in %s", ledger.ErrCurrencyMismatch, b.name, b.bound.Currency(), currency)
+		}
+		if b.bound.IsNegative() {
+			return fmt.Errorf("%w: %s must not be negative", ErrInvalidSchedule, b.name)
+		}
+	}
+	if req.MinimumFee != nil && req.MaximumFee != nil && req.MinimumFee.GreaterThan(*req.MaximumFee) {
+		return fmt.Errorf("%w: minimum fee exceeds maximum fee", ErrInvalidSchedule)
+	}
+	if err := validateTiers(req.Tiers, currency); err != nil {
+		return err
+	}
+	if req.EffectiveTo != nil && !req.EffectiveFrom.IsZero() && !req.EffectiveTo.After(req.EffectiveFrom) {
+		return fmt.Errorf("%w: effective_to must be after effective_from", ErrInvalidSchedule)
+	}
+	return nil
+}
+
+// validateTiers checks that tiers are in currency, ascend by bound and leave
+// only the last unbounded
+func validateTiers(tiers []Tier, currency string) error {
+	for i, tier := range tiers {
+		if tier.FixedAmount.Currency() != currency {
+			return fmt.Errorf("%w: tier %d fixed amount is in %s, schedule in %s", ledger.ErrCurrencyMismatch, i+1, tier.FixedAmount.Currency(), currency)
+		}
+		if tier.FixedAmount.IsNegative() {
+			return fmt.Errorf("%w: tier %d fixed amount must not be negative", ErrInvalidSchedule, i+1)
+		}
+		if tier.Rate.BasisPoints() < 0 || tier.Rate.BasisPoints() >= 10000 {
+			return fmt.Errorf("%w: tier %d rate must be at least 0 and below 1", ErrInvalidSchedule, i+1)
+		}
+		if tier.UpTo == nil {
+			if i != len(tiers)-1 {
+				return fmt.Errorf("%w: only the last tier may be unbounded", ErrInvalidSchedule)
+			}
+			continue
+		}
+		if tier.UpTo.Currency() != currency {
+			return fmt.Errorf("%w: tier %d bound is in %s, schedule in %s", ledger.ErrCurrencyMismatch, i+1, tier.UpTo.Currency(), currency)
+		}
+		if !tier.UpTo.IsPositive() {
+			return fmt.Errorf("%w: tier %d bound must be positive", ErrInvalidSchedule, i+1)
+		}
+		if i > 0 && !tier.UpTo.GreaterThan(*tiers[i-1].UpTo) {
+			return fmt.Errorf("%w: tier bounds must ascend", ErrInvalidSchedule)
+		}
+	}
+	return nil
+}
+
+// checkRevenueAccount checks that fees can be credited to account in currency
+func checkRevenueAccount(account *ledger.Account, currency string) error {
+	if account.AccountType != "revenue" {
+		return fmt.Errorf("%w: revenue account %s is %s, not revenue", ErrInvalidSchedule, account.ID, account.AccountType)
+	}
+	if account.CurrencyCode != currency {
+		return fmt.Errorf("%w: revenue account is in %s, schedule in %s", ledger.ErrCurrencyMismatch, account.CurrencyCode, currency)
+	}
+	return nil
+}
+
+// Calculate works out s's fee on base: the fixed amount plus the rate times
+// base, both taken from the first tier covering base if s is tiered, clamped
+// between the minimum and maximum fee
+func Calculate(s *Schedule, base money.Money) (money.Money, error) {
+	if base.Currency() != s.CurrencyCode {
+		return money.Money{}, fmt.Errorf("%w: amount is in %s, fee schedule in %s", ledger.ErrCurrencyMismatch, base.Currency(), s.CurrencyCode)
+	}
+	if base.IsNegative() {
+		return money.Money{}, fmt.Errorf("%w: fees are charged on amounts that are not negative", ErrInvalidSchedule)
+	}
+	fixed, rate := s.FixedAmount, s.Rate
+	for _, tier := range s.Tiers {
+		if tier.UpTo == nil || !base.GreaterThan(*tier.UpTo) {
+			fixed, rate = tier.FixedAmount, tier.Rate
+			break
+		}
+	}
+
+	variable, err := base.MulRate(rate)
+	if err != nil {
+		return money.Money{}, err
+	}
+	fee, err := fixed.Add(variable)
+	if err != nil {
+		return money.Money{}, fmt.Errorf("%w: %v", ledger.ErrCurrencyMismatch, err)
+	}
+	if s.MinimumFee != nil && fee.LessThan(*s.MinimumFee) {
+		fee = *s.MinimumFee
+	}
+	if s.MaximumFee != nil && fee.GreaterThan(*s.MaximumFee) {
+		fee = *s.MaximumFee
+	}
+	return fee, nil
+}
+
+// Legs are the legs posting f on its own: payerAccountID is debited and the
+// revenue account credited
+func (f *Fee) Legs(payerAccountID string) []ledger.JournalEntry {
+	return []ledger.JournalEntry{
+		{AccountID: payerAccountID, EntryType: "debit", Amount: f.Amount},
+		{AccountID: f.RevenueAccountID, EntryType: "credit", Amount: f.Amount},
+	}
+}
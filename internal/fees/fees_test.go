@@ -0,0 +1,196 @@
+package fees
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/pci-infra/internal/ledger"
+	"github.com/example/pci-infra/internal/money"
+)
+
+func ptr(m money.Money) *money.Money { return &m }
+
+// chargebackSchedule is the chargeback schedule migration 034 seeds for brand
+func chargebackSchedule(brand, currency string) *Schedule {
+	s := &Schedule{FeeType: FeeTypeChargeback, CardBrand: brand, CurrencyCode: currency}
+	switch brand {
+	case "VISA":
+		s.FixedAmount = money.MustParse("0", currency)
+		s.Rate = money.BasisPoints(200)
+		s.MinimumFee, s.MaximumFee = ptr(money.MustParse("5", currency)), ptr(money.MustParse("15", currency))
+	case "MASTERCARD":
+		s.FixedAmount = money.MustParse("0", currency)
+		s.Rate = money.BasisPoints(250)
+		s.MinimumFee, s.MaximumFee = ptr(money.MustParse("8", currency)), ptr(money.MustParse("25", currency))
+	default:
+		s.FixedAmount = money.MustParse("10", currency)
+	}
+	return s
+}
+
+func TestCalculateChargebackFees(t *testing.T) {
+	tests := []struct {
+		name     string
+		brand    string
+		amount   money.Money
+		expected money.Money
+	}{
+		{"Visa small amount", "VISA", money.MustParse("100.00", "USD"), money.MustParse("5.00", "USD")},
+		{"Visa large amount", "VISA", money.MustParse("1000.00", "USD"), money.MustParse("15.00", "USD")},
+		{"Visa medium amount", "VISA", money.MustParse("500.00", "USD"), money.MustParse("10.00", "USD")},
+		{"Mastercard small amount", "MASTERCARD", money.MustParse("100.00", "USD"), money.MustParse("8.00", "USD")},
+		{"Mastercard large amount", "MASTERCARD", money.MustParse("1000.00", "USD"), money.MustParse("25.00", "USD")},
+		{"Amex flat fee", "AMERICAN_EXPRESS", money.MustParse("1000.00", "USD"), money.MustParse("10.00", "USD")},
+		{"Visa zero-decimal currency", "VISA", money.MustParse("100000", "JPY"), money.MustParse("15", "JPY")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fee, err := Calculate(chargebackSchedule(tt.brand, tt.amount.Currency()), tt.amount)
+			require.NoError(t, err)
+			assert.True(t, tt.expected.Equal(fee), "expected %s, got %s", tt.expected, fee)
+		})
+	}
+}
+
+func TestCalculateTiers(t *testing.T) {
+	usd := func(amount string) money.Money { return money.MustParse(amount, "USD") }
+	s := &Schedule{
+		CurrencyCode: "USD",
+		FixedAmount:  usd("0.30"),
+		Rate:         money.BasisPoints(290),
+		Tiers: []Tier{
+			{UpTo: ptr(usd("10.00")), FixedAmount: usd("0.05"), Rate: money.BasisPoints(500)},
+			{UpTo: ptr(usd("1000.00")), FixedAmount: usd("0.30"), Rate: money.BasisPoints(290)},
+			{FixedAmount: usd("0.30"), Rate: money.BasisPoints(200)},
+		},
+		MaximumFee: ptr(usd("50.00")),
+	}
+	tests := []struct {
+		base, fee string
+	}{
+		{"0.00", "0.05"},
+		{"10.00", "0.55"},
+		{"10.01", "0.59"},
+		{"1000.00", "29.30"},
+		{"2000.00", "40.30"},
+		{"5000.00", "50.00"},
+	}
+	for _, tt := range tests {
+		fee, err := Calculate(s, usd(tt.base))
+		require.NoError(t, err)
+		assert.Equal(t, tt.fee, fee.Amount(), "fee on %s", tt.base)
+	}
+
+	// Without an unbounded tier, amounts above the last bound fall back to the
+	// schedule's own fixed amount and rate
+	s.Tiers = s.Tiers[:2]
+	fee, err := Calculate(s, usd("2000.00"))
+	require.NoError(t, err)
+	assert.Equal(t, "50.00", fee.Amount())
+	s.MaximumFee = nil
+	fee, err = Calculate(s, usd("2000.00"))
+	require.NoError(t, err)
+	assert.Equal(t, "58.30", fee.Amount())
+}
+
+func TestCalculateRejectsMismatchedAmounts(t *testing.T) {
+	s := chargebackSchedule("VISA", "USD")
+	_, err := Calculate(s, money.MustParse("100.00", "EUR"))
+	assert.True(t, errors.Is(err, ledger.ErrCurrencyMismatch))
+	_, err = Calculate(s, money.MustParse("-100.00", "USD"))
+	assert.True(t, errors.Is(err, ErrInvalidSchedule))
+}
+
+func TestValidateSchedule(t *testing.T) {
+	usd := func(amount string) money.Money { return money.MustParse(amount, "USD") }
+	valid := ScheduleRequest{
+		FeeType:          FeeTypeProcessing,
+		FixedAmount:      usd("0.30"),
+		Rate:             money.BasisPoints(290),
+		MinimumFee:       ptr(usd("0.50")),
+		MaximumFee:       ptr(usd("50.00")),
+		RevenueAccountID: "acc-1",
+		CreatedBy:        "ops",
+	}
+	assert.NoError(t, validateSchedule(valid))
+
+	now := time.Now()
+	earlier := now.Add(-time.Hour)
+	tests := []struct {
+		name   string
+		modify func(*ScheduleRequest)
+		target error
+	}{
+		{"tiered", func(r *ScheduleRequest) {
+			r.Tiers = []Tier{{UpTo: ptr(usd("10.00")), FixedAmount: usd("0.05")}, {FixedAmount: usd("0.30")}}
+		}, nil},
+		{"unknown fee type", func(r *ScheduleRequest) { r.FeeType = "refund" }, ErrInvalidSchedule},
+		{"missing revenue account", func(r *ScheduleRequest) { r.RevenueAccountID = "" }, ErrInvalidSchedule},
+		{"missing actor", func(r *ScheduleRequest) { r.CreatedBy = "" }, ErrInvalidSchedule},
+		{"negative fixed amount", func(r *ScheduleRequest) { r.FixedAmount = usd("-0.30") }, ErrInvalidSchedule},
+		{"rate of one", func(r *ScheduleRequest) { r.Rate = money.BasisPoints(10000) }, ErrInvalidSchedule},
+		{"floor above cap", func(r *ScheduleRequest) { r.MinimumFee = ptr(usd("60.00")) }, ErrInvalidSchedule},
+		{"cap in another currency", func(r *ScheduleRequest) { r.MaximumFee = ptr(money.MustParse("50.00", "EUR")) }, ledger.ErrCurrencyMismatch},
+		{"unbounded tier first", func(r *ScheduleRequest) {
+			r.Tiers = []Tier{{FixedAmount: usd("0.30")}, {UpTo: ptr(usd("10.00")), FixedAmount: usd("0.05")}}
+		}, ErrInvalidSchedule},
+		{"descending tiers", func(r *ScheduleRequest) {
+			r.Tiers = []Tier{{UpTo: ptr(usd("10.00")), FixedAmount: usd("0.05")}, {UpTo: ptr(usd("5.00")), FixedAmount: usd("0.05")}}
+		}, ErrInvalidSchedule},
+		{"tier in another currency", func(r *ScheduleRequest) {
+			r.Tiers = []Tier{{FixedAmount: money.MustParse("0.30", "EUR")}}
+		}, ledger.ErrCurrencyMismatch},
+		{"ends before it starts", func(r *ScheduleRequest) { r.EffectiveFrom, r.EffectiveTo = now, &earlier }, ErrInvalidSchedule},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := valid
+			tt.modify(&r)
+			err := validateSchedule(r)
+			if tt.target == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.True(t, errors.Is(err, tt.target), "got %v", err)
+			}
+		})
+	}
+}
+
+func TestCheckRevenueAccount(t *testing.T) {
+	revenue := &ledger.Account{ID: "acc-1", AccountType: "revenue", CurrencyCode: "USD"}
+	assert.NoError(t, checkRevenueAccount(revenue, "USD"))
+	assert.True(t, errors.Is(checkRevenueAccount(revenue, "EUR"), ledger.ErrCurrencyMismatch))
+	asset := &ledger.Account{ID: "acc-2", AccountType: "asset", CurrencyCode: "USD"}
+	assert.True(t, errors.Is(checkRevenueAccount(asset, "USD"), ErrInvalidSchedule))
+}
+
+func TestFeeLegsBalance(t *testing.T) {
+	fee := &Fee{Amount: money.MustParse("2.90", "USD"), RevenueAccountID: "rev-1"}
+	legs := fee.Legs("acc-1")
+	require.Len(t, legs, 2)
+	assert.Equal(t, "acc-1", legs[0].AccountID)
+	assert.Equal(t, "debit", legs[0].EntryType)
+	assert.Equal(t, "rev-1", legs[1].AccountID)
+	assert.Equal(t, "credit", legs[1].EntryType)
+	assert.True(t, legs[0].Amount.Equal(legs[1].Amount))
+}
+
+func TestTiersRoundTripJSON(t *testing.T) {
+	tiers := []Tier{
+		{UpTo: ptr(money.MustParse("10.00", "USD")), FixedAmount: money.MustParse("0.05", "USD"), Rate: money.BasisPoints(500)},
+		{FixedAmount: money.MustParse("0.30", "USD"), Rate: money.BasisPoints(290)},
+	}
+	data, err := json.Marshal(tiers)
+	require.NoError(t, err)
+	var decoded []Tier
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	require.Len(t, decoded, 2)
+	assert.True(t, tiers[0].UpTo.Equal(*decoded[0].UpTo))
+	assert.Nil(t, decoded[1].UpTo)
+	assert.Equal(t, tiers[1].Rate, decoded[1].Rate)
+}
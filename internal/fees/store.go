@@ -0,0 +1,307 @@
+package fees
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/example/pci-infra/internal/ledger"
+	"github.com/example/pci-infra/internal/money"
+)
+
+// Default and largest number of schedules ListSchedules returns
+const (
+	defaultScheduleLimit = 50
+	maxScheduleLimit     = 500
+)
+
+const scheduleColumns = `id, fee_type, COALESCE(merchant_id::text, ''), COALESCE(card_brand, ''), currency_code,
+	fixed_amount, rate, tiers, minimum_fee, maximum_fee, revenue_account_id, effective_from::text,
+	COALESCE(effective_to::text, ''), created_by, created_at::text`
+
+// Ledger is the part of the ledger fees post through
+type Ledger interface {
+	GetAccountByID(ctx context.Context, accountID string) (*ledger.Account, error)
+	PostTransaction(ctx context.Context, txn ledger.Transaction) (*ledger.Transaction, error)
+}
+
+// Service manages fee schedules and charges fees. Schedules are stored in
+// Pool; fees charged on their own are posted through Ledger.
+type Service struct {
+	Pool   *pgxpool.Pool
+	Ledger Ledger
+}
+
+// NewService creates a fee service over pool, posting through ls
+func NewService(pool *pgxpool.Pool, ls Ledger) *Service {
+	return &Service{Pool: pool, Ledger: ls}
+}
+
+// CreateSchedule adds a fee schedule. Its revenue account must be a revenue
+// account in the schedule's currency. A schedule effective at the same time
+// as an existing one for the same fee, merchant, brand and currency is
+// rejected.
+func (s *Service) CreateSchedule(ctx context.Context, req ScheduleRequest) (*Schedule, error) {
+	if err := validateSchedule(req); err != nil {
+		return nil, err
+	}
+	if req.MerchantID != "" && !isUUID(req.MerchantID) {
+		return nil, fmt.Errorf("%w: merchant ID %q is not a UUID", ErrInvalidSchedule, req.MerchantID)
+	}
+	if req.EffectiveFrom.IsZero() {
+		req.EffectiveFrom = time.Now()
+		if req.EffectiveTo != nil && !req.EffectiveTo.After(req.EffectiveFrom) {
+			return nil, fmt.Errorf("%w: effective_to must be in the future", ErrInvalidSchedule)
+		}
+	}
+	currency := req.FixedAmount.Currency()
+	account, err := s.Ledger.GetAccountByID(ctx, req.RevenueAccountID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkRevenueAccount(account, currency); err != nil {
+		return nil, err
+	}
+
+	tiers := req.Tiers
+	if tiers == nil {
+		tiers = []Tier{}
+	}
+	tiersJSON, err := json.Marshal(tiers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode fee tiers: %w", err)
+	}
+	var effectiveTo *time.Time
+	if req.EffectiveTo != nil {
+		to := req.EffectiveTo.UTC()
+		effectiveTo = &to
+	}
+
+	schedule, err := scanSchedule(s.Pool.QueryRow(ctx, `
+		INSERT INTO fee_schedules (
+			fee_type, merchant_id, card_brand, currency_code, fixed_amount, rate, tiers,
+			minimum_fee, maximum_fee, revenue_account_id, effective_from, effective_to, created_by
+		) VALUES ($1, NULLIF($2, '')::uuid, NULLIF($3, ''), $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		RETURNING `+scheduleColumns,
+		req.FeeType, req.MerchantID, req.CardBrand, currency, req.FixedAmount, req.Rate, tiersJSON,
+		req.MinimumFee, req.MaximumFee, req.RevenueAccountID, req.EffectiveFrom.UTC(), effectiveTo, req.CreatedBy))
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return nil, fmt.Errorf("%w: a %s schedule is already effective at %s", ErrInvalidSchedule, req.FeeType, req.EffectiveFrom.UTC().Format(time.RFC3339))
+		}
+		return nil, fmt.Errorf("failed to create fee schedule: %w", err)
+	}
+	return schedule, nil
+}
+
+// GetSchedule gets a fee schedule
+func (s *Service) GetSchedule(ctx context.Context, id string) (*Schedule, error) {
+	if !isUUID(id) {
+		return nil, fmt.Errorf("%w: %s", ErrScheduleNotFound, id)
+	}
+	schedule, err := scanSchedule(s.Pool.QueryRow(ctx, `
+		SELECT `+scheduleColumns+`
+		FROM fee_schedules
+		WHERE id = $1
+	`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrScheduleNotFound, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fee schedule: %w", err)
+	}
+	return schedule, nil
+}
+
+// ListSchedules lists fee schedules, latest effective first
+func (s *Service) ListSchedules(ctx context.Context, filter ScheduleFilter) ([]Schedule, error) {
+	if filter.FeeType != "" && !validFeeType(filter.FeeType) {
+		return nil, fmt.Errorf("%w: unknown fee type %q", ErrInvalidSchedule, filter.FeeType)
+	}
+	if filter.MerchantID != "" && !isUUID(filter.MerchantID) {
+		return []Schedule{}, nil
+	}
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultScheduleLimit
+	}
+	if limit > maxScheduleLimit {
+		limit = maxScheduleLimit
+	}
+	offset := filter.Offset
+	if offset < 0 {
+		offset = 0
+	}
+	var at *time.Time
+	if !filter.At.IsZero() {
+		t := filter.At.UTC()
+		at = &t
+	}
+
+	rows, err := s.Pool.Query(ctx, `
+		SELECT `+scheduleColumns+`
+		FROM fee_schedules
+		WHERE ($1 = '' OR fee_type = $1)
+		AND ($2 = '' OR merchant_id::text = $2)
+		AND ($3 = '' OR card_brand = $3)
+		AND ($4 = '' OR currency_code = $4)
+		AND ($5::timestamp IS NULL OR (effective_from <= $5 AND (effective_to IS NULL OR effective_to > $5)))
+		ORDER BY effective_from DESC, id
+		LIMIT $6 OFFSET $7
+	`, filter.FeeType, filter.MerchantID, filter.CardBrand, filter.CurrencyCode, at, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list fee schedules: %w", err)
+	}
+	defer rows.Close()
+
+	schedules := []Schedule{}
+	for rows.Next() {
+		schedule, err := scanSchedule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan fee schedule: %w", err)
+		}
+		schedules = append(schedules, *schedule)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list fee schedules: %w", err)
+	}
+	return schedules, nil
+}
+
+// Quote works out a fee from the most specific schedule in force: one for the
+// merchant and card brand, then the merchant, then the brand, then the
+// default, each in the amount's currency. It fails with ErrScheduleNotFound
+// when no schedule applies.
+func (s *Service) Quote(ctx context.Context, req QuoteRequest) (*Fee, error) {
+	if !validFeeType(req.FeeType) {
+		return nil, fmt.Errorf("%w: unknown fee type %q", ErrInvalidSchedule, req.FeeType)
+	}
+	at := req.At
+	if at.IsZero() {
+		at = time.Now()
+	}
+	merchantID := req.MerchantID
+	if merchantID != "" && !isUUID(merchantID) {
+		// No schedule names a malformed merchant, but the defaults still apply
+		merchantID = ""
+	}
+
+	schedule, err := scanSchedule(s.Pool.QueryRow(ctx, `
+		SELECT `+scheduleColumns+`
+		FROM fee_schedules
+		WHERE fee_type = $1 AND currency_code = $2
+		AND (merchant_id IS NULL OR merchant_id::text = $3)
+		AND (card_brand IS NULL OR card_brand = $4)
+		AND effective_from <= $5 AND (effective_to IS NULL OR effective_to > $5)
+		ORDER BY merchant_id IS NULL, card_brand IS NULL, effective_from DESC
+		LIMIT 1
+	`, req.FeeType, req.Amount.Currency(), merchantID, req.CardBrand, at.UTC()))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("%w: no %s fee in %s for merchant %q, brand %q", ErrScheduleNotFound, req.FeeType, req.Amount.Currency(), req.MerchantID, req.CardBrand)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find fee schedule: %w", err)
+	}
+
+	amount, err := Calculate(schedule, req.Amount)
+	if err != nil {
+		return nil, err
+	}
+	return &Fee{
+		ScheduleID:       schedule.ID,
+		FeeType:          schedule.FeeType,
+		Base:             req.Amount,
+		Amount:           amount,
+		RevenueAccountID: schedule.RevenueAccountID,
+	}, nil
+}
+
+// Charge quotes a fee and posts it on its own, debiting the payer and
+// crediting the schedule's revenue account. A zero fee is not posted.
+func (s *Service) Charge(ctx context.Context, req ChargeRequest) (*Fee, error) {
+	if req.PayerAccountID == "" {
+		return nil, fmt.Errorf("%w: payer account is required", ErrInvalidSchedule)
+	}
+	if req.ReferenceID == "" {
+		return nil, fmt.Errorf("%w: reference ID is required", ErrInvalidSchedule)
+	}
+	if req.CreatedBy == "" {
+		return nil, fmt.Errorf("%w: created_by is required", ErrInvalidSchedule)
+	}
+	if req.TransactionID != "" && !isUUID(req.TransactionID) {
+		return nil, fmt.Errorf("%w: transaction ID %q is not a UUID", ErrInvalidSchedule, req.TransactionID)
+	}
+
+	fee, err := s.Quote(ctx, req.QuoteRequest)
+	if err != nil {
+		return nil, err
+	}
+	if fee.Amount.IsZero() {
+		return fee, nil
+	}
+	fee.TransactionID = req.TransactionID
+	if fee.TransactionID == "" {
+		fee.TransactionID = uuid.New().String()
+	}
+	_, err = s.Ledger.PostTransaction(ctx, ledger.Transaction{
+		TransactionID: fee.TransactionID,
+		Entries:       fee.Legs(req.PayerAccountID),
+		Description:   fmt.Sprintf("%s fee on %s", fee.FeeType, fee.Base),
+		ReferenceType: ReferenceTypeFee,
+		ReferenceID:   req.ReferenceID,
+		CreatedBy:     req.CreatedBy,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to post %s fee: %w", fee.FeeType, err)
+	}
+	return fee, nil
+}
+
+func scanSchedule(row pgx.Row) (*Schedule, error) {
+	var sc Schedule
+	var fixed, rate, minimum, maximum pgtype.Numeric
+	var tiers []byte
+	err := row.Scan(&sc.ID, &sc.FeeType, &sc.MerchantID, &sc.CardBrand, &sc.CurrencyCode,
+		&fixed, &rate, &tiers, &minimum, &maximum, &sc.RevenueAccountID, &sc.EffectiveFrom,
+		&sc.EffectiveTo, &sc.CreatedBy, &sc.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if sc.FixedAmount, err = money.FromNumeric(fixed, sc.CurrencyCode); err != nil {
+		return nil, fmt.Errorf("failed to read fee schedule %s: %w", sc.ID, err)
+	}
+	if sc.Rate, err = money.RateFromNumeric(rate); err != nil {
+		return nil, fmt.Errorf("failed to read fee schedule %s rate: %w", sc.ID, err)
+	}
+	for _, f := range []struct {
+		dest **money.Money
+		n    pgtype.Numeric
+	}{{&sc.MinimumFee, minimum}, {&sc.MaximumFee, maximum}} {
+		if !f.n.Valid {
+			continue
+		}
+		m, err := money.FromNumeric(f.n, sc.CurrencyCode)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read fee schedule %s: %w", sc.ID, err)
+		}
+		*f.dest = &m
+	}
+	if err := json.Unmarshal(tiers, &sc.Tiers); err != nil {
+		return nil, fmt.Errorf("failed to read fee schedule %s tiers: %w", sc.ID, err)
+	}
+	return &sc, nil
+}
+
+func isUUID(s string) bool {
+	_, err := uuid.Parse(s)
+	return err == nil
+}
@@ -0,0 +1,214 @@
+package ledger
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Posting batch statuses
+const (
+	// BatchStatusPosting batches have lines still to post
+	BatchStatusPosting = "posting"
+	// BatchStatusCompleted batches have every line posted or failed
+	BatchStatusCompleted = "completed"
+)
+
+// Batch line statuses
+const (
+	BatchLinePending = "pending"
+	BatchLinePosted  = "posted"
+	BatchLineFailed  = "failed"
+)
+
+const (
+	// DefaultBatchChunkSize is how many lines are posted per database
+	// transaction unless LedgerService.BatchChunkSize is set
+	DefaultBatchChunkSize = 500
+	// MaxBatchLines bounds the transactions in one batch
+	MaxBatchLines = 1000000
+
+	defaultBatchLineLimit = 100
+	maxBatchLineLimit     = 1000
+)
+
+// BatchRequest represents a settlement batch to post. Reference identifies the
+// batch to the submitter and may only be used once. Each transaction is a line;
+// lines are numbered from 1 in order.
+type BatchRequest struct {
+	Reference    string        `json:"reference"`
+	CreatedBy    string        `json:"created_by"`
+	Transactions []Transaction `json:"transactions"`
+}
+
+// PostingBatch is a stored batch and its progress
+type PostingBatch struct {
+	ID          string `json:"id"`
+	Reference   string `json:"reference"`
+	Status      string `json:"status"`
+	LineCount   int    `json:"line_count"`
+	PostedCount int    `json:"posted_count"`
+	FailedCount int    `json:"failed_count"`
+	CreatedBy   string `json:"created_by"`
+	CreatedAt   string `json:"created_at"`
+	CompletedAt string `json:"completed_at,omitempty"`
+}
+
+// BatchLine is the result of one line of a batch
+type BatchLine struct {
+	LineNumber    int    `json:"line_number"`
+	TransactionID string `json:"transaction_id"`
+	Status        string `json:"status"`
+	Error         string `json:"error,omitempty"`
+	PostedAt      string `json:"posted_at,omitempty"`
+}
+
+// BatchLineFilter selects the lines of a batch. Status is empty for all.
+type BatchLineFilter struct {
+	BatchID string
+	Status  string
+	Limit   int
+	Offset  int
+}
+
+// BatchError is returned when lines of a batch fail validation. Nothing of the
+// batch is stored; Lines holds every invalid line with its reason.
+type BatchError struct {
+	Lines []BatchLine
+}
+
+func (e *BatchError) Error() string {
+	if len(e.Lines) == 0 {
+		return ErrInvalidBatch.Error()
+	}
+	first := e.Lines[0]
+	return fmt.Sprintf("%v: %d invalid lines; line %d: %s", ErrInvalidBatch, len(e.Lines), first.LineNumber, first.Error)
+}
+
+func (e *BatchError) Unwrap() error {
+	return ErrInvalidBatch
+}
+
+// ReadBatchFile reads a settlement file of JSON transactions, one per line, in
+// the form PostTransaction takes
+func ReadBatchFile(r io.Reader) ([]Transaction, error) {
+	dec := json.NewDecoder(r)
+	var txns []Transaction
+	for {
+		var txn Transaction
+		err := dec.Decode(&txn)
+		if errors.Is(err, io.EOF) {
+			return txns, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidBatch, len(txns)+1, err)
+		}
+		txns = append(txns, txn)
+		if len(txns) > MaxBatchLines {
+			return nil, fmt.Errorf("%w: more than %d lines", ErrInvalidBatch, MaxBatchLines)
+		}
+	}
+}
+
+// prepareBatchLine prepares a line of a batch as PostTransaction prepares a
+// transaction. Batch lines also need UUID transaction IDs unique within the
+// batch; seen maps those of earlier lines to their line numbers.
+func prepareBatchLine(txn *Transaction, now time.Time, seen map[string]int) error {
+	if _, err := prepareTransaction(txn, now); err != nil {
+		return err
+	}
+	if _, err := uuid.Parse(txn.TransactionID); err != nil {
+		return fmt.Errorf("%w: transaction_id %q is not a UUID", ErrInvalidEntry, txn.TransactionID)
+	}
+	if first, ok := seen[txn.TransactionID]; ok {
+		return fmt.Errorf("%w: transaction_id %s repeats line %d", ErrInvalidEntry, txn.TransactionID, first)
+	}
+	return nil
+}
+
+// batchAccount is what a batch line is checked against when it is submitted
+type batchAccount struct {
+	status       string
+	currencyCode string
+}
+
+// batchAccountIDs lists, once each, the accounts the lines of a batch post to
+func batchAccountIDs(txns []Transaction) []string {
+	seen := make(map[string]bool)
+	var ids []string
+	for _, txn := range txns {
+		for _, entry := range txn.Entries {
+			if !seen[entry.AccountID] {
+				seen[entry.AccountID] = true
+				ids = append(ids, entry.AccountID)
+			}
+		}
+	}
+	return ids
+}
+
+// checkBatchLineAccounts checks that every leg of a line posts to an existing
+// account, in its currency, that accepts the leg
+func checkBatchLineAccounts(txn Transaction, accounts map[string]batchAccount) error {
+	for _, entry := range txn.Entries {
+		acct, ok := accounts[entry.AccountID]
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrAccountNotFound, entry.AccountID)
+		}
+		if err := checkPostable(entry.AccountID, acct.status, entry.EntryType); err != nil {
+			return err
+		}
+		if entry.Amount.Currency() != acct.currencyCode {
+			return fmt.Errorf("%w: entry currency %s does not match account %s currency %s", ErrCurrencyMismatch, entry.Amount.Currency(), entry.AccountID, acct.currencyCode)
+		}
+	}
+	return nil
+}
+
+// batchLineFailure reports whether a posting failed because of the lines
+// posted rather than the database, so they are marked failed instead of
+// retried when the batch resumes
+func batchLineFailure(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		// Integrity violations and exceptions raised by the ledger triggers
+		return strings.HasPrefix(pgErr.Code, "23") || pgErr.Code == "P0001"
+	}
+	return errors.Is(err, ErrInvalidRequest) ||
+		errors.Is(err, ErrInvalidEntry) ||
+		errors.Is(err, ErrUnbalancedTransaction) ||
+		errors.Is(err, ErrAccountNotFound) ||
+		errors.Is(err, ErrAccountInactive) ||
+		errors.Is(err, ErrCurrencyMismatch) ||
+		errors.Is(err, ErrInsufficientFunds) ||
+		errors.Is(err, ErrTransactionLimitExceeded) ||
+		errors.Is(err, ErrPeriodClosed)
+}
+
+// checkBatchID rejects a batch ID that is missing or cannot name a batch
+func checkBatchID(batchID string) error {
+	if batchID == "" {
+		return fmt.Errorf("%w: batch ID is required", ErrInvalidRequest)
+	}
+	if _, err := uuid.Parse(batchID); err != nil {
+		return fmt.Errorf("%w: %s", ErrBatchNotFound, batchID)
+	}
+	return nil
+}
+
+// batchLineLimit clamps a page size of batch lines
+func batchLineLimit(limit int) int {
+	if limit <= 0 {
+		return defaultBatchLineLimit
+	}
+	if limit > maxBatchLineLimit {
+		return maxBatchLineLimit
+	}
+	return limit
+}
@@ -0,0 +1,118 @@
+package ledger
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidRequest is returned when a request fails input validation
+var ErrInvalidRequest = errors.New("invalid request")
+
+// ErrInvalidEntry is returned when a journal entry leg fails validation
+var ErrInvalidEntry = errors.New("invalid journal entry")
+
+// ErrUnbalancedTransaction is returned when debits and credits of a
+// transaction differ in any currency
+var ErrUnbalancedTransaction = errors.New("unbalanced transaction")
+
+// ErrAccountNotFound is returned when a referenced account does not exist
+var ErrAccountNotFound = errors.New("account not found")
+
+// ErrAccountExists is returned when an account number is already taken
+var ErrAccountExists = errors.New("account already exists")
+
+// ErrAccountInactive is returned when posting to an inactive account
+var ErrAccountInactive = errors.New("account is not active")
+
+// ErrAccountFrozen is returned when debiting a frozen account; credits are
+// still accepted
+var ErrAccountFrozen = fmt.Errorf("%w: frozen", ErrAccountInactive)
+
+// ErrAccountBlocked is returned when posting to a blocked account
+var ErrAccountBlocked = fmt.Errorf("%w: blocked", ErrAccountInactive)
+
+// ErrAccountClosed is returned when posting to or changing the status of a
+// closed account
+var ErrAccountClosed = fmt.Errorf("%w: closed", ErrAccountInactive)
+
+// ErrInvalidStatusTransition is returned when an account is already in the
+// requested status
+var ErrInvalidStatusTransition = errors.New("invalid account status transition")
+
+// ErrAccountNotEmpty is returned when closing an account that still has a
+// balance, pending transactions or active holds
+var ErrAccountNotEmpty = errors.New("account is not empty")
+
+// ErrCurrencyMismatch is returned when an amount's currency differs from the account's
+var ErrCurrencyMismatch = errors.New("currency mismatch")
+
+// ErrInsufficientFunds is returned when a posting would overdraw an account
+var ErrInsufficientFunds = errors.New("insufficient funds")
+
+// ErrPendingNotFound is returned when a pending transaction does not exist
+var ErrPendingNotFound = errors.New("pending transaction not found")
+
+// ErrPendingResolved is returned when capturing or voiding a pending
+// transaction that was already posted, voided or expired
+var ErrPendingResolved = errors.New("pending transaction already resolved")
+
+// ErrPendingExpired is returned when capturing or voiding a pending
+// transaction past its expiry
+var ErrPendingExpired = errors.New("pending transaction expired")
+
+// ErrTransactionNotFound is returned when no journal entries carry a transaction ID
+var ErrTransactionNotFound = errors.New("transaction not found")
+
+// ErrAlreadyReversed is returned when reversing a transaction that has
+// already been reversed in full
+var ErrAlreadyReversed = errors.New("transaction already reversed")
+
+// ErrFXRateNotFound is returned when no exchange rate is in effect for a currency pair
+var ErrFXRateNotFound = errors.New("fx rate not found")
+
+// ErrFXNotConfigured is returned when a currency has no FX clearing accounts
+var ErrFXNotConfigured = errors.New("fx accounts not configured")
+
+// ErrQuoteOffMarket is returned when a quoted FX rate is further from the
+// market rate than the configured spread allows
+var ErrQuoteOffMarket = errors.New("quoted rate outside the allowed spread")
+
+// ErrPeriodNotFound is returned when an accounting period does not exist
+var ErrPeriodNotFound = errors.New("accounting period not found")
+
+// ErrPeriodClosed is returned when an entry takes effect in a closed
+// accounting period
+var ErrPeriodClosed = errors.New("accounting period is closed")
+
+// ErrPeriodSoftClosed is returned when an entry takes effect in a soft-closed
+// period without permission to adjust it
+var ErrPeriodSoftClosed = fmt.Errorf("%w: soft closed", ErrPeriodClosed)
+
+// ErrPeriodHardClosed is returned when an entry takes effect in, or a close is
+// attempted on, a hard-closed period
+var ErrPeriodHardClosed = fmt.Errorf("%w: hard closed", ErrPeriodClosed)
+
+// ErrInvalidPeriodTransition is returned when a period is already in or past
+// the requested status
+var ErrInvalidPeriodTransition = errors.New("invalid accounting period transition")
+
+// ErrRetainedEarningsNotConfigured is returned when closing a period with
+// income in a currency that has no retained earnings account
+var ErrRetainedEarningsNotConfigured = errors.New("retained earnings account not configured")
+
+// ErrInvalidBatch is returned when a posting batch, or any of its lines, fails
+// validation
+var ErrInvalidBatch = fmt.Errorf("%w: batch", ErrInvalidRequest)
+
+// ErrBatchExists is returned when a batch reference has already been used
+var ErrBatchExists = errors.New("posting batch already exists")
+
+// ErrBatchNotFound is returned when a posting batch does not exist
+var ErrBatchNotFound = errors.New("posting batch not found")
+
+// ErrTransactionLimitExceeded is returned when a transaction would reduce an
+// account by more than its credit limit allows in one transaction
+var ErrTransactionLimitExceeded = errors.New("transaction limit exceeded")
+
+// ErrCreditLimitNotFound is returned when no credit limit is in effect for an account
+var ErrCreditLimitNotFound = errors.New("credit limit not found")
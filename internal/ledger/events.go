@@ -0,0 +1,60 @@
+package ledger
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/example/pci-infra/internal/money"
+	"github.com/example/pci-infra/internal/outbox"
+)
+
+// Aggregate types of ledger events
+const (
+	aggregateTransaction = "transaction"
+	aggregateAccount     = "account"
+)
+
+// TransactionPostedEvent is the payload of a transaction.posted event: every
+// leg written under the transaction ID in one database transaction
+type TransactionPostedEvent struct {
+	TransactionID string          `json:"transaction_id"`
+	Entries       []*JournalEntry `json:"entries"`
+}
+
+// appendTransactionPosted records a transaction.posted event within tx for
+// each transaction ID among entries, in order of first appearance
+func appendTransactionPosted(ctx context.Context, tx pgx.Tx, entries []*JournalEntry) error {
+	var order []string
+	byTransaction := make(map[string][]*JournalEntry)
+	for _, entry := range entries {
+		if _, ok := byTransaction[entry.TransactionID]; !ok {
+			order = append(order, entry.TransactionID)
+		}
+		byTransaction[entry.TransactionID] = append(byTransaction[entry.TransactionID], entry)
+	}
+
+	for _, transactionID := range order {
+		event := TransactionPostedEvent{TransactionID: transactionID, Entries: byTransaction[transactionID]}
+		if err := outbox.Append(ctx, tx, outbox.EventTransactionPosted, aggregateTransaction, transactionID, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// appendAccountCreated records an account.created event within tx. A new
+// account's balance is zero.
+func appendAccountCreated(ctx context.Context, tx pgx.Tx, account *Account) error {
+	balance, err := money.Zero(account.CurrencyCode)
+	if err != nil {
+		return err
+	}
+	account.CurrentBalance = balance
+	return outbox.Append(ctx, tx, outbox.EventAccountCreated, aggregateAccount, account.ID, account)
+}
+
+// appendAccountStatusChanged records an account.status_changed event within tx
+func appendAccountStatusChanged(ctx context.Context, tx pgx.Tx, transition *AccountStatusTransition) error {
+	return outbox.Append(ctx, tx, outbox.EventAccountStatusChanged, aggregateAccount, transition.AccountID, transition)
+}
@@ -0,0 +1,202 @@
+package ledger
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/example/pci-infra/internal/money"
+)
+
+// ReferenceTypeFXTransfer marks the legs of a cross-currency transfer
+const ReferenceTypeFXTransfer = "fx_transfer"
+
+// DefaultMaxFXSpread is how far a quoted rate may be from the market rate, as
+// a fraction of the market rate, when the service sets no spread
+var DefaultMaxFXSpread = money.BasisPoints(200)
+
+type offMarketKey struct{}
+
+// WithOffMarketFXQuotes allows FX transfers made with the returned context to
+// apply a quoted rate outside the configured spread. Grant it only to callers
+// holding the elevated FX permission.
+func WithOffMarketFXQuotes(ctx context.Context) context.Context {
+	return context.WithValue(ctx, offMarketKey{}, true)
+}
+
+func offMarketQuotesAllowed(ctx context.Context) bool {
+	allowed, _ := ctx.Value(offMarketKey{}).(bool)
+	return allowed
+}
+
+// FXRate is an exchange rate in effect from EffectiveAt until a later rate for
+// the same pair supersedes it
+type FXRate struct {
+	ID            string       `json:"id"`
+	BaseCurrency  string       `json:"base_currency"`
+	QuoteCurrency string       `json:"quote_currency"`
+	Rate          money.FXRate `json:"rate"`
+	EffectiveAt   string       `json:"effective_at"`
+	Source        string       `json:"source"`
+	CreatedAt     string       `json:"created_at"`
+	CreatedBy     string       `json:"created_by"`
+}
+
+// SetFXRateRequest represents the request to publish an exchange rate. A zero
+// EffectiveAt makes the rate effective immediately.
+type SetFXRateRequest struct {
+	BaseCurrency  string       `json:"base_currency"`
+	QuoteCurrency string       `json:"quote_currency"`
+	Rate          money.FXRate `json:"rate"`
+	EffectiveAt   time.Time    `json:"effective_at"`
+	Source        string       `json:"source"`
+	CreatedBy     string       `json:"created_by"`
+}
+
+// FXAccounts are the accounts FX transfers post through in one currency
+type FXAccounts struct {
+	CurrencyCode      string `json:"currency_code"`
+	ClearingAccountID string `json:"clearing_account_id"`
+	GainAccountID     string `json:"gain_account_id"`
+	LossAccountID     string `json:"loss_account_id"`
+	UpdatedBy         string `json:"updated_by"`
+}
+
+// FXTransferRequest represents a transfer between accounts in different
+// currencies. Amount is in the from account's currency. QuotedRate is the rate
+// promised to the customer; when zero the market rate is applied and no gain or
+// loss is realised. A quoted rate must be within the service's spread of the
+// market rate unless the caller may apply off-market quotes.
+type FXTransferRequest struct {
+	TransactionID string                 `json:"transaction_id"`
+	FromAccountID string                 `json:"from_account_id"`
+	ToAccountID   string                 `json:"to_account_id"`
+	Amount        money.Money            `json:"amount"`
+	QuotedRate    money.FXRate           `json:"quoted_rate"`
+	Description   string                 `json:"description"`
+	ReferenceType string                 `json:"reference_type"`
+	ReferenceID   string                 `json:"reference_id"`
+	CreatedBy     string                 `json:"created_by"`
+	Metadata      map[string]interface{} `json:"metadata"`
+}
+
+// FXConversion records a posted FX transfer
+type FXConversion struct {
+	ID            string       `json:"id"`
+	TransactionID string       `json:"transaction_id"`
+	FromAccountID string       `json:"from_account_id"`
+	ToAccountID   string       `json:"to_account_id"`
+	SourceAmount  money.Money  `json:"source_amount"`
+	TargetAmount  money.Money  `json:"target_amount"`
+	FXRateID      string       `json:"fx_rate_id"`
+	MarketRate    money.FXRate `json:"market_rate"`
+	AppliedRate   money.FXRate `json:"applied_rate"`
+	// RealisedGainLoss is positive for a gain and negative for a loss, in the
+	// target currency
+	RealisedGainLoss money.Money    `json:"realised_gain_loss"`
+	CreatedAt        string         `json:"created_at"`
+	CreatedBy        string         `json:"created_by"`
+	Entries          []JournalEntry `json:"entries"`
+}
+
+// checkQuotedRate rejects a quoted rate further than maxSpread from the market
+// rate; a zero quote applies the market rate and always passes
+func checkQuotedRate(quoted money.FXRate, rate *FXRate, maxSpread money.Rate) error {
+	if quoted.IsZero() || quoted.WithinSpread(rate.Rate, maxSpread) {
+		return nil
+	}
+	return fmt.Errorf("%w: %s is more than %s from the %s/%s market rate %s",
+		ErrQuoteOffMarket, quoted, maxSpread, rate.BaseCurrency, rate.QuoteCurrency, rate.Rate)
+}
+
+// planFXTransfer converts req.Amount into the target currency and builds the
+// legs of the transfer. The source currency moves from the from account into
+// its clearing account; the target currency moves out of its clearing account
+// at the market rate into the to account at the applied rate, with any
+// difference realised as gain or loss. Each currency balances on its own.
+func planFXTransfer(req FXTransferRequest, source, target FXAccounts, rate *FXRate) (*FXConversion, []*JournalEntry, error) {
+	if rate.BaseCurrency != source.CurrencyCode || rate.QuoteCurrency != target.CurrencyCode {
+		return nil, nil, fmt.Errorf("%w: rate %s/%s for a %s to %s transfer", ErrCurrencyMismatch, rate.BaseCurrency, rate.QuoteCurrency, source.CurrencyCode, target.CurrencyCode)
+	}
+	if req.Amount.Currency() != source.CurrencyCode {
+		return nil, nil, fmt.Errorf("%w: amount %s vs account %s", ErrCurrencyMismatch, req.Amount.Currency(), source.CurrencyCode)
+	}
+
+	applied := req.QuotedRate
+	if applied.IsZero() {
+		applied = rate.Rate
+	}
+
+	atMarket, err := rate.Rate.Convert(req.Amount, target.CurrencyCode)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to convert at market rate: %w", err)
+	}
+	credited, err := applied.Convert(req.Amount, target.CurrencyCode)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to convert at applied rate: %w", err)
+	}
+	if !atMarket.IsPositive() || !credited.IsPositive() {
+		return nil, nil, fmt.Errorf("%w: %s converts to zero %s", ErrInvalidRequest, req.Amount, target.CurrencyCode)
+	}
+	gainLoss, err := atMarket.Sub(credited)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	conversion := &FXConversion{
+		TransactionID:    req.TransactionID,
+		FromAccountID:    req.FromAccountID,
+		ToAccountID:      req.ToAccountID,
+		SourceAmount:     req.Amount,
+		TargetAmount:     credited,
+		FXRateID:         rate.ID,
+		MarketRate:       rate.Rate,
+		AppliedRate:      applied,
+		RealisedGainLoss: gainLoss,
+		CreatedBy:        req.CreatedBy,
+	}
+
+	metadata := map[string]interface{}{
+		"fx_rate_id":   rate.ID,
+		"market_rate":  rate.Rate.String(),
+		"applied_rate": applied.String(),
+	}
+	for k, v := range req.Metadata {
+		metadata[k] = v
+	}
+
+	referenceType := req.ReferenceType
+	if referenceType == "" {
+		referenceType = ReferenceTypeFXTransfer
+	}
+
+	var entries []*JournalEntry
+	leg := func(entryType, accountID string, amount money.Money, description string) {
+		entries = append(entries, &JournalEntry{
+			EntryNumber:   fmt.Sprintf("JE-%s-%d", req.TransactionID, len(entries)+1),
+			TransactionID: req.TransactionID,
+			EntryType:     entryType,
+			AccountID:     accountID,
+			Amount:        amount,
+			Description:   description,
+			ReferenceType: referenceType,
+			ReferenceID:   req.ReferenceID,
+			CurrencyCode:  amount.Currency(),
+			CreatedBy:     req.CreatedBy,
+			Metadata:      metadata,
+		})
+	}
+
+	leg("debit", req.FromAccountID, req.Amount, fmt.Sprintf("FX transfer to %s: %s", req.ToAccountID, req.Description))
+	leg("credit", source.ClearingAccountID, req.Amount, fmt.Sprintf("FX clearing %s", source.CurrencyCode))
+	leg("debit", target.ClearingAccountID, atMarket, fmt.Sprintf("FX clearing %s", target.CurrencyCode))
+	leg("credit", req.ToAccountID, credited, fmt.Sprintf("FX transfer from %s: %s", req.FromAccountID, req.Description))
+	switch {
+	case gainLoss.IsPositive():
+		leg("credit", target.GainAccountID, gainLoss, "Realised FX gain")
+	case gainLoss.IsNegative():
+		leg("debit", target.LossAccountID, gainLoss.Abs(), "Realised FX loss")
+	}
+
+	return conversion, entries, nil
+}
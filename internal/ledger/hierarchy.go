@@ -0,0 +1,85 @@
+package ledger
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/example/pci-infra/internal/money"
+)
+
+// AccountNumberSeparator joins a parent's account number to the segment that
+// identifies a child beneath it
+const AccountNumberSeparator = "-"
+
+// AccountNode is an account in a chart-of-accounts tree. RollupBalance is the
+// account's own balance plus the roll-up of every child.
+type AccountNode struct {
+	*Account
+	Depth         int            `json:"depth"`
+	RollupBalance money.Money    `json:"rollup_balance"`
+	Children      []*AccountNode `json:"children"`
+}
+
+// checkChildAccount reports whether req may be created beneath parent. Children
+// share the parent's type and currency so their balances roll up, and their
+// account number is the parent's plus one segment.
+func checkChildAccount(parent *Account, req CreateAccountRequest) error {
+	if parent.Status == AccountStatusClosed {
+		return fmt.Errorf("%w: parent %s", ErrAccountClosed, parent.ID)
+	}
+	if req.AccountType != parent.AccountType {
+		return fmt.Errorf("%w: %s account cannot sit under %s account %s", ErrInvalidRequest, req.AccountType, parent.AccountType, parent.AccountNumber)
+	}
+	if req.CurrencyCode != parent.CurrencyCode {
+		return fmt.Errorf("%w: %s account cannot sit under %s account %s", ErrCurrencyMismatch, req.CurrencyCode, parent.CurrencyCode, parent.AccountNumber)
+	}
+
+	prefix := parent.AccountNumber + AccountNumberSeparator
+	segment := strings.TrimPrefix(req.AccountNumber, prefix)
+	if !strings.HasPrefix(req.AccountNumber, prefix) || segment == "" || strings.Contains(segment, AccountNumberSeparator) {
+		return fmt.Errorf("%w: account number %s must be %s followed by one segment", ErrInvalidRequest, req.AccountNumber, prefix)
+	}
+	return nil
+}
+
+// buildAccountTree assembles the subtree rooted at rootID from a flat list of
+// its accounts, starting with the root and ordered so every parent precedes its
+// children, and rolls balances up from the leaves
+func buildAccountTree(rootID string, accounts []*Account) (*AccountNode, error) {
+	if len(accounts) == 0 || accounts[0].ID != rootID {
+		return nil, fmt.Errorf("%w: %s", ErrAccountNotFound, rootID)
+	}
+
+	root := &AccountNode{Account: accounts[0], Children: []*AccountNode{}}
+	nodes := map[string]*AccountNode{rootID: root}
+	for _, a := range accounts[1:] {
+		parent, ok := nodes[a.ParentID]
+		if !ok {
+			return nil, fmt.Errorf("account %s listed before its parent %s", a.ID, a.ParentID)
+		}
+		node := &AccountNode{Account: a, Depth: parent.Depth + 1, Children: []*AccountNode{}}
+		nodes[a.ID] = node
+		parent.Children = append(parent.Children, node)
+	}
+
+	if err := rollUp(root); err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+// rollUp sets RollupBalance on node and every node beneath it
+func rollUp(node *AccountNode) error {
+	total := node.CurrentBalance
+	for _, child := range node.Children {
+		if err := rollUp(child); err != nil {
+			return err
+		}
+		var err error
+		if total, err = total.Add(child.RollupBalance); err != nil {
+			return fmt.Errorf("failed to roll up %s into %s: %w", child.AccountNumber, node.AccountNumber, err)
+		}
+	}
+	node.RollupBalance = total
+	return nil
+}
@@ -3,25 +3,35 @@ package ledger
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
-	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/example/pci-infra/internal/money"
 )
 
-// SimpleMockPool provides a simplified mock for testing
+// SimpleMockPool provides a simplified mock for testing. Transactions begun on
+// it run their statements through the same funcs.
 type SimpleMockPool struct {
 	execFunc func(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error)
 	queryFunc func(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
 	queryRowFunc func(ctx context.Context, sql string, args ...interface{}) pgx.Row
 }
 
-func (m *SimpleMockPool) Acquire(ctx context.Context) (*pgx.Conn, error) {
-	return &pgx.Conn{}, nil
+func (m *SimpleMockPool) Begin(ctx context.Context) (pgx.Tx, error) {
+	return &mockTx{pool: m}, nil
+}
+
+func (m *SimpleMockPool) BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error) {
+	return &mockTx{pool: m}, nil
 }
 
 func (m *SimpleMockPool) Close() {}
@@ -47,6 +57,29 @@ func (m *SimpleMockPool) QueryRow(ctx context.Context, sql string, args ...inter
 	return &mockRow{}
 }
 
+// mockTx is a transaction on a SimpleMockPool. Methods the ledger does not
+// use are left to the embedded nil pgx.Tx.
+type mockTx struct {
+	pgx.Tx
+	pool *SimpleMockPool
+}
+
+func (tx *mockTx) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	return tx.pool.Exec(ctx, sql, args...)
+}
+
+func (tx *mockTx) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	return tx.pool.Query(ctx, sql, args...)
+}
+
+func (tx *mockTx) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	return tx.pool.QueryRow(ctx, sql, args...)
+}
+
+func (tx *mockTx) Commit(ctx context.Context) error { return nil }
+
+func (tx *mockTx) Rollback(ctx context.Context) error { return nil }
+
 type mockRow struct {
 	scanned bool
 }
@@ -64,8 +97,11 @@ func (r *mockRow) Scan(dest ...interface{}) error {
 	return pgx.ErrNoRows
 }
 
+// mockRows returns each of rows in turn
 type mockRows struct {
 	closed bool
+	rows   [][]interface{}
+	next   int
 }
 
 func (r *mockRows) Close() {
@@ -77,55 +113,91 @@ func (r *mockRows) Err() error {
 }
 
 func (r *mockRows) Next() bool {
-	return false
+	if r.closed || r.next >= len(r.rows) {
+		return false
+	}
+	r.next++
+	return true
 }
 
 func (r *mockRows) Scan(dest ...interface{}) error {
-	return nil
+	return (&mockRowWithValues{values: r.rows[r.next-1]}).Scan(dest...)
 }
 
-// TestCreateAccount tests the CreateAccount functionality
-func TestCreateAccount(t *testing.T) {
-	ctx := context.Background()
-	
-	mockPool := &SimpleMockPool{
+func (r *mockRows) CommandTag() pgconn.CommandTag {
+	return pgconn.NewCommandTag("SELECT " + fmt.Sprint(len(r.rows)))
+}
+
+func (r *mockRows) FieldDescriptions() []pgconn.FieldDescription { return nil }
+
+func (r *mockRows) Values() ([]interface{}, error) {
+	return r.rows[r.next-1], nil
+}
+
+func (r *mockRows) RawValues() [][]byte { return nil }
+
+func (r *mockRows) Conn() *pgx.Conn { return nil }
+
+// accountMockPool answers the statements CreateAccount runs for ACC001
+func accountMockPool() *SimpleMockPool {
+	return &SimpleMockPool{
 		queryRowFunc: func(ctx context.Context, sql string, args ...interface{}) pgx.Row {
 			switch {
 			case contains(sql, "EXISTS"):
 				return &mockRowWithValues{values: []interface{}{false}}
+			case contains(sql, "INSERT INTO accounts"):
+				return &mockRowWithValues{values: []interface{}{"acc-123", true, "active", "2024-01-01T00:00:00Z"}}
 			case contains(sql, "SELECT") && contains(sql, "accounts"):
 				return &mockRowWithValues{values: []interface{}{
-					"acc-123", "ACC001", "asset", "Test Account", "USD", true, 
-					"2024-01-01T00:00:00Z", "test_user", nil, 0.0,
+					"acc-123", "", "ACC001", "asset", "Test Account", "USD", true, "active",
+					"2024-01-01T00:00:00Z", "test_user", nil, "0",
 				}}
 			}
 			return &mockRow{}
 		},
 	}
+}
+
+// TestCreateAccount tests the CreateAccount functionality
+func TestCreateAccount(t *testing.T) {
+	ctx := context.Background()
 	
-	postgresLedger := &PostgresLedger{Pool: mockPool}
+	postgresLedger := &PostgresLedger{Pool: accountMockPool()}
 	
 	account, err := postgresLedger.CreateAccount(ctx, "ACC001", "asset", "Test Account", "USD", "test_user", map[string]interface{}{})
 	
 	require.NoError(t, err)
 	assert.NotNil(t, account)
+	assert.Equal(t, "acc-123", account.ID)
 	assert.Equal(t, "ACC001", account.AccountNumber)
 	assert.Equal(t, "asset", account.AccountType)
 	assert.Equal(t, "Test Account", account.Name)
 	assert.Equal(t, "USD", account.CurrencyCode)
+	assert.Equal(t, AccountStatusActive, account.Status)
+	assert.Equal(t, money.MustParse("0.00", "USD"), account.CurrentBalance)
 }
 
 // TestPostJournalEntry tests posting journal entries
 func TestPostJournalEntry(t *testing.T) {
 	ctx := context.Background()
 	
+	var events []string
 	mockPool := &SimpleMockPool{
 		queryRowFunc: func(ctx context.Context, sql string, args ...interface{}) pgx.Row {
-			if contains(sql, "SELECT account_type, is_active") {
-				return &mockRowWithValues{values: []interface{}{"asset", true}}
+			switch {
+			case contains(sql, "SELECT account_type, status, currency_code"):
+				return &mockRowWithValues{values: []interface{}{"asset", AccountStatusActive, "USD"}}
+			case contains(sql, "INSERT INTO journal_entries"):
+				return &mockRowWithValues{values: []interface{}{"je-1", "2024-01-01T00:00:00Z"}}
 			}
 			return &mockRow{}
 		},
+		execFunc: func(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+			if contains(sql, "INSERT INTO outbox_events") {
+				events = append(events, args[0].(string))
+			}
+			return pgconn.NewCommandTag("INSERT 0 1"), nil
+		},
 	}
 	
 	postgresLedger := &PostgresLedger{Pool: mockPool}
@@ -135,7 +207,7 @@ func TestPostJournalEntry(t *testing.T) {
 		TransactionID: "txn-123",
 		EntryType:     "debit",
 		AccountID:     "acc-123",
-		Amount:        100.0,
+		Amount:        money.MustParse("100.00", "USD"),
 		Description:   "Test debit",
 		CurrencyCode:  "USD",
 		CreatedBy:     "test_user",
@@ -145,6 +217,10 @@ func TestPostJournalEntry(t *testing.T) {
 	err := postgresLedger.PostJournalEntry(ctx, entry)
 	
 	require.NoError(t, err)
+	assert.Equal(t, "je-1", entry.ID)
+	assert.Equal(t, "asset", entry.AccountType)
+	assert.NotEmpty(t, entry.EffectiveDate)
+	assert.Equal(t, []string{"transaction.posted"}, events)
 }
 
 // TestGetBalance tests getting account balance
@@ -153,8 +229,8 @@ func TestGetBalance(t *testing.T) {
 	
 	mockPool := &SimpleMockPool{
 		queryRowFunc: func(ctx context.Context, sql string, args ...interface{}) pgx.Row {
-			if contains(sql, "SELECT COALESCE(balance") {
-				return &mockRowWithValues{values: []interface{}{150.75}}
+			if contains(sql, "SELECT COALESCE(ab.balance") {
+				return &mockRowWithValues{values: []interface{}{"150.75", "USD"}}
 			}
 			return &mockRow{}
 		},
@@ -165,7 +241,7 @@ func TestGetBalance(t *testing.T) {
 	balance, err := postgresLedger.GetBalance(ctx, "acc-123")
 	
 	require.NoError(t, err)
-	assert.Equal(t, 150.75, balance)
+	assert.Equal(t, money.MustParse("150.75", "USD"), balance)
 }
 
 // TestValidateAccountType tests account type validation
@@ -212,18 +288,22 @@ func TestValidateTransactionAmount(t *testing.T) {
 	validator := &Validator{}
 	
 	// Valid amounts
-	validResult := validator.ValidateTransactionAmount(100.50)
+	validResult := validator.ValidateTransactionAmount(money.MustParse("100.50", "USD"))
 	assert.True(t, validResult.IsValid)
 	assert.Contains(t, validResult.Message, "is valid")
 	
 	// Invalid amounts
-	zeroAmountResult := validator.ValidateTransactionAmount(0)
+	zeroAmountResult := validator.ValidateTransactionAmount(money.MustNew(0, "USD"))
 	assert.False(t, zeroAmountResult.IsValid)
 	assert.Contains(t, zeroAmountResult.Message, "must be greater than zero")
 	
-	negativeAmountResult := validator.ValidateTransactionAmount(-50.0)
+	negativeAmountResult := validator.ValidateTransactionAmount(money.MustParse("-50.00", "USD"))
 	assert.False(t, negativeAmountResult.IsValid)
 	assert.Contains(t, negativeAmountResult.Message, "must be greater than zero")
+	
+	missingCurrencyResult := validator.ValidateTransactionAmount(money.Money{})
+	assert.False(t, missingCurrencyResult.IsValid)
+	assert.Contains(t, missingCurrencyResult.Message, "must have a currency")
 }
 
 // TestLedgerService_CreateAccount tests the ledger service CreateAccount method
@@ -272,7 +352,7 @@ func TestLedgerService_CreditDebit(t *testing.T) {
 	// Test invalid amount in credit
 	creditReq := CreditRequest{
 		AccountID:   "acc-123",
-		Amount:      0, // Invalid amount
+		Amount:      money.MustNew(0, "USD"), // Invalid amount
 		Description: "Test credit",
 		TransactionRequest: TransactionRequest{
 			TransactionID: "txn-123",
@@ -288,7 +368,7 @@ func TestLedgerService_CreditDebit(t *testing.T) {
 	// Test invalid amount in debit
 	debitReq := DebitRequest{
 		AccountID:   "acc-123",
-		Amount:      -50.0, // Invalid amount
+		Amount:      money.MustParse("-50.00", "USD"), // Invalid amount
 		Description: "Test debit",
 		TransactionRequest: TransactionRequest{
 			TransactionID: "txn-123",
@@ -313,7 +393,7 @@ func TestLedgerService_Transfer(t *testing.T) {
 	sameAccountReq := TransferRequest{
 		FromAccountID: "acc-123",
 		ToAccountID:   "acc-123", // Same as from
-		Amount:        100.0,
+		Amount:        money.MustParse("100.00", "USD"),
 		Description:   "Test transfer",
 		CurrencyCode:  "USD",
 		CreatedBy:     "test_user",
@@ -327,7 +407,7 @@ func TestLedgerService_Transfer(t *testing.T) {
 	negativeAmountReq := TransferRequest{
 		FromAccountID: "acc-123",
 		ToAccountID:   "acc-456",
-		Amount:        -100.0, // Invalid amount
+		Amount:        money.MustParse("-100.00", "USD"), // Invalid amount
 		Description:   "Test transfer",
 		CurrencyCode:  "USD",
 		CreatedBy:     "test_user",
@@ -338,6 +418,1131 @@ func TestLedgerService_Transfer(t *testing.T) {
 	assert.Contains(t, err.Error(), "amount must be positive")
 }
 
+// TestValidateTransactionEntries tests balance and shape checks on multi-leg transactions
+func TestValidateTransactionEntries(t *testing.T) {
+	leg := func(accountID, entryType, amount, currency string) JournalEntry {
+		return JournalEntry{AccountID: accountID, EntryType: entryType, Amount: money.MustParse(amount, currency)}
+	}
+
+	tests := []struct {
+		name    string
+		entries []JournalEntry
+		wantErr error
+	}{
+		{
+			name: "card capture split",
+			entries: []JournalEntry{
+				leg("acc-clearing", "debit", "100.00", "USD"),
+				leg("acc-merchant", "credit", "97.10", "USD"),
+				leg("acc-fees", "credit", "2.50", "USD"),
+				leg("acc-network", "credit", "0.40", "USD"),
+			},
+		},
+		{
+			name: "balanced in each currency",
+			entries: []JournalEntry{
+				leg("acc-1", "debit", "10.00", "USD"),
+				leg("acc-2", "credit", "10.00", "USD"),
+				leg("acc-3", "debit", "1000", "JPY"),
+				leg("acc-4", "credit", "1000", "JPY"),
+			},
+		},
+		{
+			name: "unbalanced",
+			entries: []JournalEntry{
+				leg("acc-1", "debit", "100.00", "USD"),
+				leg("acc-2", "credit", "99.99", "USD"),
+			},
+			wantErr: ErrUnbalancedTransaction,
+		},
+		{
+			name: "balanced in total but not per currency",
+			entries: []JournalEntry{
+				leg("acc-1", "debit", "10.00", "USD"),
+				leg("acc-2", "credit", "10.00", "EUR"),
+			},
+			wantErr: ErrUnbalancedTransaction,
+		},
+		{
+			name: "single leg",
+			entries: []JournalEntry{
+				leg("acc-1", "debit", "10.00", "USD"),
+			},
+			wantErr: ErrInvalidEntry,
+		},
+		{
+			name: "zero amount",
+			entries: []JournalEntry{
+				leg("acc-1", "debit", "0", "USD"),
+				leg("acc-2", "credit", "0", "USD"),
+			},
+			wantErr: ErrInvalidEntry,
+		},
+		{
+			name: "invalid entry type",
+			entries: []JournalEntry{
+				leg("acc-1", "debit", "10.00", "USD"),
+				leg("acc-2", "refund", "10.00", "USD"),
+			},
+			wantErr: ErrInvalidEntry,
+		},
+		{
+			name: "missing account",
+			entries: []JournalEntry{
+				leg("acc-1", "debit", "10.00", "USD"),
+				leg("", "credit", "10.00", "USD"),
+			},
+			wantErr: ErrInvalidEntry,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateTransactionEntries(tt.entries)
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+// TestLedgerService_PostTransaction tests that unbalanced transactions are rejected before posting
+func TestLedgerService_PostTransaction(t *testing.T) {
+	ctx := context.Background()
+	
+	// Validation must fail before the pool is ever used
+	ledgerService := NewLedgerService(&PostgresLedger{})
+	
+	_, err := ledgerService.PostTransaction(ctx, Transaction{
+		Description: "Card capture",
+		CreatedBy:   "test_user",
+		Entries: []JournalEntry{
+			{AccountID: "acc-1", EntryType: "debit", Amount: money.MustParse("100.00", "USD")},
+			{AccountID: "acc-2", EntryType: "credit", Amount: money.MustParse("90.00", "USD")},
+		},
+	})
+	require.Error(t, err)
+	assert.ErrorIs(t, err, ErrUnbalancedTransaction)
+	
+	_, err = ledgerService.PostTransaction(ctx, Transaction{
+		Entries: []JournalEntry{
+			{AccountID: "acc-1", EntryType: "debit", Amount: money.MustParse("100.00", "USD")},
+			{AccountID: "acc-2", EntryType: "credit", Amount: money.MustParse("100.00", "USD")},
+		},
+	})
+	require.Error(t, err)
+	assert.ErrorIs(t, err, ErrInvalidEntry)
+}
+
+// TestLedgerService_GetStatement tests statement request validation
+func TestLedgerService_GetStatement(t *testing.T) {
+	ctx := context.Background()
+	
+	// Validation must fail before the pool is ever used
+	ledgerService := NewLedgerService(&PostgresLedger{})
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	
+	testCases := []struct {
+		name string
+		req  StatementRequest
+		msg  string
+	}{
+		{"missing account", StatementRequest{From: from, To: to}, "account ID is required"},
+		{"missing range", StatementRequest{AccountID: "acc-1", From: from}, "from and to are required"},
+		{"reversed range", StatementRequest{AccountID: "acc-1", From: to, To: from}, "from must be before to"},
+		{"limit too large", StatementRequest{AccountID: "acc-1", From: from, To: to, Limit: maxStatementLimit + 1}, "limit must be between"},
+		{"bad cursor", StatementRequest{AccountID: "acc-1", From: from, To: to, Cursor: "not-a-cursor"}, "invalid cursor"},
+	}
+	
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ledgerService.GetStatement(ctx, tc.req)
+			require.Error(t, err)
+			assert.ErrorIs(t, err, ErrInvalidRequest)
+			assert.Contains(t, err.Error(), tc.msg)
+		})
+	}
+	
+	_, err := ledgerService.GetBalanceAt(ctx, "acc-1", time.Time{})
+	require.Error(t, err)
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+}
+
+// TestStatementCursor tests that cursors round-trip and reject tampering
+func TestStatementCursor(t *testing.T) {
+	c := statementCursor{
+		PostedAt: time.Date(2024, 1, 31, 23, 59, 59, 123456000, time.UTC),
+		EntryID:  "8c1f0b4e-2d7a-4f7e-9a51-3b2f4c6d8e90",
+	}
+	
+	decoded, err := decodeStatementCursor(encodeStatementCursor(c))
+	require.NoError(t, err)
+	assert.True(t, c.PostedAt.Equal(decoded.PostedAt))
+	assert.Equal(t, c.EntryID, decoded.EntryID)
+	
+	for _, bad := range []string{"%%%", "bm8tc2VwYXJhdG9y", "eWVzdGVyZGF5fDEyMw"} {
+		_, err := decodeStatementCursor(bad)
+		assert.ErrorIs(t, err, ErrInvalidRequest, bad)
+	}
+}
+
+// TestLedgerService_PendingValidation tests authorize, capture and void request validation
+func TestLedgerService_PendingValidation(t *testing.T) {
+	ctx := context.Background()
+	
+	// Validation must fail before the pool is ever used
+	ledgerService := NewLedgerService(&PostgresLedger{})
+	valid := AuthorizeRequest{
+		FromAccountID: "acc-customer",
+		ToAccountID:   "acc-merchant",
+		Amount:        money.MustParse("50.00", "USD"),
+		Description:   "Card authorization",
+		CreatedBy:     "test_user",
+	}
+	
+	testCases := []struct {
+		name   string
+		mutate func(*AuthorizeRequest)
+		msg    string
+	}{
+		{"same account", func(r *AuthorizeRequest) { r.ToAccountID = r.FromAccountID }, "must be different"},
+		{"zero amount", func(r *AuthorizeRequest) { r.Amount = money.MustParse("0.00", "USD") }, "amount must be positive"},
+		{"missing description", func(r *AuthorizeRequest) { r.Description = "" }, "description is required"},
+		{"missing created_by", func(r *AuthorizeRequest) { r.CreatedBy = "" }, "created_by is required"},
+		{"negative ttl", func(r *AuthorizeRequest) { r.TTL = -time.Minute }, "ttl must be positive"},
+	}
+	
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := valid
+			tc.mutate(&req)
+			_, err := ledgerService.AuthorizePending(ctx, req)
+			require.Error(t, err)
+			assert.ErrorIs(t, err, ErrInvalidRequest)
+			assert.Contains(t, err.Error(), tc.msg)
+		})
+	}
+	
+	_, err := ledgerService.CapturePending(ctx, CaptureRequest{PendingID: "p-1"})
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+	
+	_, err = ledgerService.CapturePending(ctx, CaptureRequest{PendingID: "p-1", CapturedBy: "test_user", Amount: money.MustParse("-1.00", "USD")})
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+	
+	_, err = ledgerService.VoidPending(ctx, VoidRequest{PendingID: "p-1"})
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+}
+
+// TestCaptureEntries tests the legs posted when capturing a pending transaction
+func TestCaptureEntries(t *testing.T) {
+	p := &PendingTransaction{
+		ID:            "pending-1",
+		TransactionID: "txn-1",
+		FromAccountID: "acc-customer",
+		ToAccountID:   "acc-merchant",
+		Amount:        money.MustParse("50.00", "USD"),
+		Description:   "Card authorization",
+		Metadata:      map[string]interface{}{"order": "o-1"},
+	}
+	
+	entries := captureEntries(p, money.MustParse("30.00", "USD"), "capturer")
+	require.Len(t, entries, 2)
+	
+	legs := make([]JournalEntry, len(entries))
+	for i, e := range entries {
+		legs[i] = *e
+		assert.Equal(t, "txn-1", e.TransactionID)
+		assert.Equal(t, "30.00", e.Amount.Amount())
+		assert.Equal(t, "capturer", e.CreatedBy)
+		assert.Equal(t, "pending-1", e.Metadata["pending_transaction_id"])
+		assert.Equal(t, "o-1", e.Metadata["order"])
+	}
+	assert.Equal(t, "debit", entries[0].EntryType)
+	assert.Equal(t, "acc-customer", entries[0].AccountID)
+	assert.Equal(t, "credit", entries[1].EntryType)
+	assert.Equal(t, "acc-merchant", entries[1].AccountID)
+	assert.NoError(t, ValidateTransactionEntries(legs))
+	
+	// the pending transaction's own metadata is left untouched
+	assert.NotContains(t, p.Metadata, "pending_transaction_id")
+}
+
+// TestLedgerService_ReverseValidation tests reversal request validation
+func TestLedgerService_ReverseValidation(t *testing.T) {
+	ctx := context.Background()
+	
+	// Validation must fail before the pool is ever used
+	ledgerService := NewLedgerService(&PostgresLedger{})
+	valid := ReverseRequest{
+		TransactionID: "txn-1",
+		Reason:        "Posted to the wrong merchant",
+		ReversedBy:    "ops_user",
+		ApprovedBy:    "ops_manager",
+	}
+	
+	testCases := []struct {
+		name   string
+		mutate func(*ReverseRequest)
+		msg    string
+	}{
+		{"missing transaction", func(r *ReverseRequest) { r.TransactionID = "" }, "transaction ID is required"},
+		{"missing reason", func(r *ReverseRequest) { r.Reason = "" }, "reason is required"},
+		{"missing approver", func(r *ReverseRequest) { r.ApprovedBy = "" }, "approved_by are required"},
+		{"self approved", func(r *ReverseRequest) { r.ApprovedBy = r.ReversedBy }, "someone other than its requester"},
+		{"negative amount", func(r *ReverseRequest) { r.Amount = money.MustParse("-1.00", "USD") }, "amount must be positive"},
+	}
+	
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := valid
+			tc.mutate(&req)
+			_, err := ledgerService.ReverseTransaction(ctx, req)
+			require.Error(t, err)
+			assert.ErrorIs(t, err, ErrInvalidRequest)
+			assert.Contains(t, err.Error(), tc.msg)
+		})
+	}
+	
+	_, err := ledgerService.GetTransaction(ctx, "")
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+}
+
+// TestPlanReversal tests the mirror-image legs built for full and partial reversals
+func TestPlanReversal(t *testing.T) {
+	usd := func(s string) money.Money { return money.MustParse(s, "USD") }
+	transfer := []JournalEntry{
+		{ID: "e-1", TransactionID: "txn-1", EntryType: "debit", AccountID: "acc-cash", Amount: usd("100.00"), ReferenceType: "order"},
+		{ID: "e-2", TransactionID: "txn-1", EntryType: "credit", AccountID: "acc-merchant", Amount: usd("100.00"), ReferenceType: "order"},
+	}
+	req := ReverseRequest{
+		TransactionID: "txn-1",
+		ReversalID:    "rev-1",
+		Reason:        "Duplicate charge",
+		ReversedBy:    "ops_user",
+		ApprovedBy:    "ops_manager",
+	}
+	
+	t.Run("full", func(t *testing.T) {
+		plan, err := planReversal(transfer, "", nil, req)
+		require.NoError(t, err)
+		assert.True(t, plan.full)
+		require.Len(t, plan.entries, 2)
+		
+		legs := make([]JournalEntry, len(plan.entries))
+		for i, e := range plan.entries {
+			legs[i] = *e
+			assert.Equal(t, "rev-1", e.TransactionID)
+			assert.Equal(t, ReferenceTypeReversal, e.ReferenceType)
+			assert.Equal(t, "txn-1", e.ReferenceID)
+			assert.Equal(t, "100.00", e.Amount.Amount())
+			assert.Equal(t, "ops_user", e.CreatedBy)
+			assert.Equal(t, "ops_manager", e.Metadata["approved_by"])
+			assert.Equal(t, transfer[i].ID, e.Metadata["original_entry_id"])
+			assert.Equal(t, transfer[i].AccountID, e.AccountID)
+		}
+		assert.Equal(t, "credit", plan.entries[0].EntryType)
+		assert.Equal(t, "debit", plan.entries[1].EntryType)
+		assert.NoError(t, ValidateTransactionEntries(legs))
+	})
+	
+	t.Run("partial then remainder", func(t *testing.T) {
+		partial := req
+		partial.Amount = usd("30.00")
+		plan, err := planReversal(transfer, "", nil, partial)
+		require.NoError(t, err)
+		assert.False(t, plan.full)
+		assert.Equal(t, "30.00", plan.entries[0].Amount.Amount())
+		
+		prior := []Reversal{{TransactionID: "rev-1", Amount: plan.amount}}
+		_, err = planReversal(transfer, "", prior, ReverseRequest{TransactionID: "txn-1", Amount: usd("80.00")})
+		assert.ErrorIs(t, err, ErrInvalidRequest)
+		
+		plan, err = planReversal(transfer, "", prior, ReverseRequest{TransactionID: "txn-1", ReversalID: "rev-2"})
+		require.NoError(t, err)
+		assert.True(t, plan.full)
+		assert.Equal(t, "70.00", plan.amount.Amount())
+	})
+	
+	t.Run("double reversal", func(t *testing.T) {
+		amount := usd("100.00")
+		_, err := planReversal(transfer, "", []Reversal{{TransactionID: "rev-1", Full: true, Amount: &amount}}, req)
+		assert.ErrorIs(t, err, ErrAlreadyReversed)
+	})
+	
+	t.Run("reversal of a reversal", func(t *testing.T) {
+		reversal := []JournalEntry{
+			{EntryType: "credit", AccountID: "acc-cash", Amount: usd("100.00"), ReferenceType: ReferenceTypeReversal, ReferenceID: "txn-1"},
+			{EntryType: "debit", AccountID: "acc-merchant", Amount: usd("100.00"), ReferenceType: ReferenceTypeReversal, ReferenceID: "txn-1"},
+		}
+		_, err := planReversal(reversal, "txn-1", nil, req)
+		assert.ErrorIs(t, err, ErrInvalidRequest)
+	})
+	
+	t.Run("client-set reversal reference type", func(t *testing.T) {
+		spoofed := []JournalEntry{
+			{EntryType: "debit", AccountID: "acc-cash", Amount: usd("100.00"), ReferenceType: ReferenceTypeReversal, ReferenceID: "txn-0"},
+			{EntryType: "credit", AccountID: "acc-merchant", Amount: usd("100.00"), ReferenceType: ReferenceTypeReversal, ReferenceID: "txn-0"},
+		}
+		plan, err := planReversal(spoofed, "", nil, req)
+		require.NoError(t, err)
+		assert.True(t, plan.full)
+	})
+	
+	t.Run("partial multi-leg", func(t *testing.T) {
+		split := []JournalEntry{
+			{EntryType: "debit", AccountID: "acc-cash", Amount: usd("100.00")},
+			{EntryType: "credit", AccountID: "acc-merchant", Amount: usd("97.00")},
+			{EntryType: "credit", AccountID: "acc-fees", Amount: usd("3.00")},
+		}
+		plan, err := planReversal(split, "", nil, req)
+		require.NoError(t, err)
+		assert.True(t, plan.full)
+		assert.Nil(t, plan.amount)
+		assert.Equal(t, "3.00", plan.entries[2].Amount.Amount())
+		
+		partial := req
+		partial.Amount = usd("10.00")
+		_, err = planReversal(split, "", nil, partial)
+		assert.ErrorIs(t, err, ErrInvalidRequest)
+	})
+	
+	t.Run("not found", func(t *testing.T) {
+		_, err := planReversal(nil, "", nil, req)
+		assert.ErrorIs(t, err, ErrTransactionNotFound)
+	})
+}
+
+// TestLedgerService_FXValidation tests FX request validation
+func TestLedgerService_FXValidation(t *testing.T) {
+	ctx := context.Background()
+	
+	// Validation must fail before the pool is ever used
+	ledgerService := NewLedgerService(&PostgresLedger{})
+	
+	_, err := ledgerService.SetFXRate(ctx, SetFXRateRequest{BaseCurrency: "USD", QuoteCurrency: "USD", Rate: money.MustParseFXRate("1"), CreatedBy: "treasury"})
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+	_, err = ledgerService.SetFXRate(ctx, SetFXRateRequest{BaseCurrency: "USD", QuoteCurrency: "XXX1", Rate: money.MustParseFXRate("1"), CreatedBy: "treasury"})
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+	_, err = ledgerService.SetFXRate(ctx, SetFXRateRequest{BaseCurrency: "USD", QuoteCurrency: "EUR", CreatedBy: "treasury"})
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+	_, err = ledgerService.GetFXRate(ctx, "USD", "", time.Time{})
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+	
+	err = ledgerService.SetFXAccounts(ctx, FXAccounts{CurrencyCode: "EUR", ClearingAccountID: "a", GainAccountID: "a", LossAccountID: "b", UpdatedBy: "treasury"})
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+	
+	valid := FXTransferRequest{
+		FromAccountID: "acc-usd",
+		ToAccountID:   "acc-eur",
+		Amount:        money.MustParse("100.00", "USD"),
+		Description:   "Wallet conversion",
+		CreatedBy:     "test_user",
+	}
+	testCases := []struct {
+		name   string
+		mutate func(*FXTransferRequest)
+	}{
+		{"same account", func(r *FXTransferRequest) { r.ToAccountID = r.FromAccountID }},
+		{"zero amount", func(r *FXTransferRequest) { r.Amount = money.MustParse("0.00", "USD") }},
+		{"missing description", func(r *FXTransferRequest) { r.Description = "" }},
+		{"missing created_by", func(r *FXTransferRequest) { r.CreatedBy = "" }},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := valid
+			tc.mutate(&req)
+			_, err := ledgerService.FXTransfer(ctx, req)
+			assert.ErrorIs(t, err, ErrInvalidRequest)
+		})
+	}
+}
+
+// TestPlanFXTransfer tests the legs posted for a cross-currency transfer
+func TestPlanFXTransfer(t *testing.T) {
+	usd := FXAccounts{CurrencyCode: "USD", ClearingAccountID: "usd-clearing", GainAccountID: "usd-gain", LossAccountID: "usd-loss"}
+	eur := FXAccounts{CurrencyCode: "EUR", ClearingAccountID: "eur-clearing", GainAccountID: "eur-gain", LossAccountID: "eur-loss"}
+	rate := &FXRate{ID: "rate-1", BaseCurrency: "USD", QuoteCurrency: "EUR", Rate: money.MustParseFXRate("0.92")}
+	req := FXTransferRequest{
+		TransactionID: "txn-1",
+		FromAccountID: "acc-usd",
+		ToAccountID:   "acc-eur",
+		Amount:        money.MustParse("100.00", "USD"),
+		Description:   "Wallet conversion",
+		CreatedBy:     "test_user",
+	}
+	
+	balanced := func(t *testing.T, entries []*JournalEntry) {
+		legs := make([]JournalEntry, len(entries))
+		for i, e := range entries {
+			legs[i] = *e
+			assert.Equal(t, "txn-1", e.TransactionID)
+			assert.Equal(t, ReferenceTypeFXTransfer, e.ReferenceType)
+			assert.Equal(t, "rate-1", e.Metadata["fx_rate_id"])
+		}
+		assert.NoError(t, ValidateTransactionEntries(legs))
+	}
+	
+	t.Run("market rate", func(t *testing.T) {
+		conversion, entries, err := planFXTransfer(req, usd, eur, rate)
+		require.NoError(t, err)
+		assert.Equal(t, money.MustParse("92.00", "EUR"), conversion.TargetAmount)
+		assert.True(t, conversion.RealisedGainLoss.IsZero())
+		require.Len(t, entries, 4)
+		assert.Equal(t, "acc-usd", entries[0].AccountID)
+		assert.Equal(t, "usd-clearing", entries[1].AccountID)
+		assert.Equal(t, "eur-clearing", entries[2].AccountID)
+		assert.Equal(t, "acc-eur", entries[3].AccountID)
+		balanced(t, entries)
+	})
+	
+	t.Run("quote below market realises a gain", func(t *testing.T) {
+		quoted := req
+		quoted.QuotedRate = money.MustParseFXRate("0.90")
+		conversion, entries, err := planFXTransfer(quoted, usd, eur, rate)
+		require.NoError(t, err)
+		assert.Equal(t, money.MustParse("90.00", "EUR"), conversion.TargetAmount)
+		assert.Equal(t, money.MustParse("2.00", "EUR"), conversion.RealisedGainLoss)
+		require.Len(t, entries, 5)
+		assert.Equal(t, "eur-gain", entries[4].AccountID)
+		assert.Equal(t, "credit", entries[4].EntryType)
+		balanced(t, entries)
+	})
+	
+	t.Run("quote above market realises a loss", func(t *testing.T) {
+		quoted := req
+		quoted.QuotedRate = money.MustParseFXRate("0.93")
+		conversion, entries, err := planFXTransfer(quoted, usd, eur, rate)
+		require.NoError(t, err)
+		assert.Equal(t, money.MustParse("-1.00", "EUR"), conversion.RealisedGainLoss)
+		require.Len(t, entries, 5)
+		assert.Equal(t, "eur-loss", entries[4].AccountID)
+		assert.Equal(t, "debit", entries[4].EntryType)
+		assert.Equal(t, "1.00", entries[4].Amount.Amount())
+		balanced(t, entries)
+	})
+	
+	t.Run("amount in the wrong currency", func(t *testing.T) {
+		wrong := req
+		wrong.Amount = money.MustParse("100.00", "EUR")
+		_, _, err := planFXTransfer(wrong, usd, eur, rate)
+		assert.ErrorIs(t, err, ErrCurrencyMismatch)
+	})
+	
+	t.Run("converts to zero", func(t *testing.T) {
+		tiny := req
+		tiny.Amount = money.MustParse("0.01", "USD")
+		_, _, err := planFXTransfer(tiny, usd, eur, &FXRate{ID: "rate-2", BaseCurrency: "USD", QuoteCurrency: "EUR", Rate: money.MustParseFXRate("0.0001")})
+		assert.ErrorIs(t, err, ErrInvalidRequest)
+	})
+}
+
+// TestCheckQuotedRate tests the spread allowed between quoted and market rates
+func TestCheckQuotedRate(t *testing.T) {
+	rate := &FXRate{ID: "rate-1", BaseCurrency: "USD", QuoteCurrency: "EUR", Rate: money.MustParseFXRate("0.92")}
+	spread := money.BasisPoints(200)
+	
+	assert.NoError(t, checkQuotedRate(money.FXRate{}, rate, spread))
+	assert.NoError(t, checkQuotedRate(money.MustParseFXRate("0.9016"), rate, spread))
+	assert.ErrorIs(t, checkQuotedRate(money.MustParseFXRate("0.90"), rate, spread), ErrQuoteOffMarket)
+	assert.NoError(t, checkQuotedRate(money.MustParseFXRate("0.9384"), rate, spread))
+	
+	err := checkQuotedRate(money.MustParseFXRate("9.2"), rate, spread)
+	assert.ErrorIs(t, err, ErrQuoteOffMarket)
+	assert.Contains(t, err.Error(), "USD/EUR")
+	
+	ctx := context.Background()
+	assert.False(t, offMarketQuotesAllowed(ctx))
+	assert.True(t, offMarketQuotesAllowed(WithOffMarketFXQuotes(ctx)))
+}
+
+// TestLedgerService_AccountStatusValidation tests lifecycle request validation
+func TestLedgerService_AccountStatusValidation(t *testing.T) {
+	ctx := context.Background()
+	
+	// Validation must fail before the pool is ever used
+	ledgerService := NewLedgerService(&PostgresLedger{})
+	valid := AccountStatusRequest{
+		AccountID: "acc-1",
+		Reason:    "Suspected account takeover",
+		ChangedBy: "risk_analyst",
+	}
+	
+	testCases := []struct {
+		name   string
+		mutate func(*AccountStatusRequest)
+		msg    string
+	}{
+		{"missing account", func(r *AccountStatusRequest) { r.AccountID = "" }, "account ID is required"},
+		{"missing reason", func(r *AccountStatusRequest) { r.Reason = "" }, "reason is required"},
+		{"missing actor", func(r *AccountStatusRequest) { r.ChangedBy = "" }, "changed_by is required"},
+	}
+	
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := valid
+			tc.mutate(&req)
+			for _, change := range []func(context.Context, AccountStatusRequest) (*AccountStatusTransition, error){
+				ledgerService.FreezeAccount,
+				ledgerService.UnfreezeAccount,
+				ledgerService.BlockAccount,
+				ledgerService.CloseAccount,
+			} {
+				_, err := change(ctx, req)
+				require.Error(t, err)
+				assert.ErrorIs(t, err, ErrInvalidRequest)
+				assert.Contains(t, err.Error(), tc.msg)
+			}
+		})
+	}
+	
+	_, err := ledgerService.GetAccountStatusHistory(ctx, "")
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+}
+
+// TestCheckStatusTransition tests which lifecycle transitions are allowed
+func TestCheckStatusTransition(t *testing.T) {
+	testCases := []struct {
+		from, to string
+		err      error
+	}{
+		{AccountStatusActive, AccountStatusFrozen, nil},
+		{AccountStatusActive, AccountStatusBlocked, nil},
+		{AccountStatusActive, AccountStatusClosed, nil},
+		{AccountStatusFrozen, AccountStatusActive, nil},
+		{AccountStatusFrozen, AccountStatusBlocked, nil},
+		{AccountStatusBlocked, AccountStatusActive, nil},
+		{AccountStatusBlocked, AccountStatusClosed, nil},
+		{AccountStatusFrozen, AccountStatusFrozen, ErrInvalidStatusTransition},
+		{AccountStatusActive, AccountStatusActive, ErrInvalidStatusTransition},
+		{AccountStatusClosed, AccountStatusActive, ErrAccountClosed},
+		{AccountStatusClosed, AccountStatusClosed, ErrAccountClosed},
+		{AccountStatusActive, "dormant", ErrInvalidRequest},
+	}
+	
+	for _, tc := range testCases {
+		t.Run(tc.from+"->"+tc.to, func(t *testing.T) {
+			err := checkStatusTransition("acc-1", tc.from, tc.to)
+			if tc.err == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, tc.err)
+		})
+	}
+}
+
+// TestCheckPostable tests which legs each account status accepts
+func TestCheckPostable(t *testing.T) {
+	testCases := []struct {
+		status    string
+		entryType string
+		err       error
+	}{
+		{AccountStatusActive, "debit", nil},
+		{AccountStatusActive, "credit", nil},
+		{AccountStatusFrozen, "credit", nil},
+		{AccountStatusFrozen, "debit", ErrAccountFrozen},
+		{AccountStatusBlocked, "credit", ErrAccountBlocked},
+		{AccountStatusBlocked, "debit", ErrAccountBlocked},
+		{AccountStatusClosed, "credit", ErrAccountClosed},
+		{AccountStatusClosed, "debit", ErrAccountClosed},
+	}
+	
+	for _, tc := range testCases {
+		t.Run(tc.status+" "+tc.entryType, func(t *testing.T) {
+			err := checkPostable("acc-1", tc.status, tc.entryType)
+			if tc.err == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, tc.err)
+			// Lifecycle errors still read as inactive to existing callers
+			assert.ErrorIs(t, err, ErrAccountInactive)
+		})
+	}
+}
+
+// TestCheckChildAccount tests the parent and numbering rules for child accounts
+func TestCheckChildAccount(t *testing.T) {
+	parent := &Account{ID: "p", AccountNumber: "2100-123", AccountType: "liability", CurrencyCode: "USD", Status: AccountStatusActive}
+	child := func(number, accountType, currency string) CreateAccountRequest {
+		return CreateAccountRequest{ParentID: "p", AccountNumber: number, AccountType: accountType, CurrencyCode: currency}
+	}
+	
+	assert.NoError(t, checkChildAccount(parent, child("2100-123-PENDING", "liability", "USD")))
+	
+	testCases := []struct {
+		name string
+		req  CreateAccountRequest
+		err  error
+	}{
+		{"other type", child("2100-123-PENDING", "asset", "USD"), ErrInvalidRequest},
+		{"other currency", child("2100-123-PENDING", "liability", "EUR"), ErrCurrencyMismatch},
+		{"missing prefix", child("2200-123-PENDING", "liability", "USD"), ErrInvalidRequest},
+		{"no separator", child("2100-123PENDING", "liability", "USD"), ErrInvalidRequest},
+		{"empty segment", child("2100-123-", "liability", "USD"), ErrInvalidRequest},
+		{"two levels", child("2100-123-PENDING-1", "liability", "USD"), ErrInvalidRequest},
+	}
+	
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.ErrorIs(t, checkChildAccount(parent, tc.req), tc.err)
+		})
+	}
+	
+	closed := *parent
+	closed.Status = AccountStatusClosed
+	assert.ErrorIs(t, checkChildAccount(&closed, child("2100-123-PENDING", "liability", "USD")), ErrAccountClosed)
+}
+
+// TestBuildAccountTree tests assembling a subtree and rolling up balances
+func TestBuildAccountTree(t *testing.T) {
+	account := func(id, parentID, number, balance string) *Account {
+		return &Account{ID: id, ParentID: parentID, AccountNumber: number, CurrencyCode: "USD", CurrentBalance: money.MustParse(balance, "USD")}
+	}
+	accounts := []*Account{
+		account("payables", "", "2100", "0.00"),
+		account("m123", "payables", "2100-123", "10.00"),
+		account("m123-pending", "m123", "2100-123-PENDING", "25.50"),
+		account("m456", "payables", "2100-456", "4.50"),
+	}
+	
+	root, err := buildAccountTree("payables", accounts)
+	require.NoError(t, err)
+	
+	assert.Equal(t, "40.00", root.RollupBalance.Amount())
+	assert.Equal(t, "0.00", root.CurrentBalance.Amount())
+	require.Len(t, root.Children, 2)
+	
+	m123 := root.Children[0]
+	assert.Equal(t, "2100-123", m123.AccountNumber)
+	assert.Equal(t, 1, m123.Depth)
+	assert.Equal(t, "35.50", m123.RollupBalance.Amount())
+	require.Len(t, m123.Children, 1)
+	assert.Equal(t, 2, m123.Children[0].Depth)
+	assert.Equal(t, "25.50", m123.Children[0].RollupBalance.Amount())
+	assert.Empty(t, m123.Children[0].Children)
+	
+	assert.Equal(t, "4.50", root.Children[1].RollupBalance.Amount())
+	
+	// A subtree can be rooted anywhere
+	sub, err := buildAccountTree("m123", accounts[1:3])
+	require.NoError(t, err)
+	assert.Equal(t, "35.50", sub.RollupBalance.Amount())
+	assert.Equal(t, 0, sub.Depth)
+	
+	_, err = buildAccountTree("missing", accounts)
+	assert.ErrorIs(t, err, ErrAccountNotFound)
+	
+	_, err = buildAccountTree("payables", []*Account{accounts[0], accounts[2]})
+	assert.Error(t, err)
+	
+	mixed := []*Account{accounts[0], {ID: "eur", ParentID: "payables", AccountNumber: "2100-EUR", CurrencyCode: "EUR", CurrentBalance: money.MustParse("1.00", "EUR")}}
+	_, err = buildAccountTree("payables", mixed)
+	assert.Error(t, err)
+}
+
+// TestCheckPeriodTransition tests that periods only move forward
+func TestCheckPeriodTransition(t *testing.T) {
+	testCases := []struct {
+		from string
+		to   string
+		err  error
+	}{
+		{PeriodStatusOpen, PeriodStatusSoftClosed, nil},
+		{PeriodStatusOpen, PeriodStatusHardClosed, nil},
+		{PeriodStatusSoftClosed, PeriodStatusHardClosed, nil},
+		{PeriodStatusSoftClosed, PeriodStatusSoftClosed, ErrInvalidPeriodTransition},
+		{PeriodStatusHardClosed, PeriodStatusHardClosed, ErrPeriodHardClosed},
+		{PeriodStatusOpen, PeriodStatusOpen, ErrInvalidRequest},
+	}
+	
+	for _, tc := range testCases {
+		t.Run(tc.from+" to "+tc.to, func(t *testing.T) {
+			err := checkPeriodTransition("2024-01", tc.from, tc.to)
+			if tc.err == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, tc.err)
+		})
+	}
+}
+
+// TestCheckPeriodPostable tests which periods accept entries
+func TestCheckPeriodPostable(t *testing.T) {
+	assert.NoError(t, checkPeriodPostable("2024-01", PeriodStatusOpen, "2024-01-15", false))
+	assert.ErrorIs(t, checkPeriodPostable("2024-01", PeriodStatusSoftClosed, "2024-01-15", false), ErrPeriodSoftClosed)
+	assert.NoError(t, checkPeriodPostable("2024-01", PeriodStatusSoftClosed, "2024-01-15", true))
+	
+	// Not even an adjustment gets into a hard-closed period
+	err := checkPeriodPostable("2024-01", PeriodStatusHardClosed, "2024-01-15", true)
+	assert.ErrorIs(t, err, ErrPeriodHardClosed)
+	assert.ErrorIs(t, err, ErrPeriodClosed)
+	
+	ctx := context.Background()
+	assert.False(t, softClosedAllowed(ctx))
+	assert.True(t, softClosedAllowed(WithSoftClosedPostings(ctx)))
+}
+
+// TestValidateEffectiveDate tests back-dating rules for entries
+func TestValidateEffectiveDate(t *testing.T) {
+	now := time.Date(2024, 3, 10, 15, 0, 0, 0, time.UTC)
+	
+	assert.NoError(t, validateEffectiveDate("2024-03-10", now))
+	assert.NoError(t, validateEffectiveDate("2023-12-31", now))
+	assert.ErrorIs(t, validateEffectiveDate("2024-03-11", now), ErrInvalidEntry)
+	assert.ErrorIs(t, validateEffectiveDate("10/03/2024", now), ErrInvalidEntry)
+	
+	assert.NoError(t, validatePeriodRange("2024-01-01", "2024-01-31"))
+	assert.NoError(t, validatePeriodRange("2024-01-01", "2024-01-01"))
+	assert.ErrorIs(t, validatePeriodRange("2024-02-01", "2024-01-31"), ErrInvalidRequest)
+	assert.ErrorIs(t, validatePeriodRange("2024-01", "2024-01-31"), ErrInvalidRequest)
+}
+
+// TestPlanPeriodClose tests rolling revenue and expense into retained earnings
+func TestPlanPeriodClose(t *testing.T) {
+	period := &AccountingPeriod{ID: "p-1", Name: "2024-01", EndsOn: "2024-01-31"}
+	balance := func(id, accountType, amount, currency string) PeriodClosingBalance {
+		return PeriodClosingBalance{AccountID: id, AccountType: accountType, Balance: money.MustParse(amount, currency)}
+	}
+	balances := []PeriodClosingBalance{
+		balance("cash", "asset", "900.00", "USD"),
+		balance("fees", "revenue", "400.00", "USD"),
+		balance("refunds", "revenue", "-50.00", "USD"),
+		balance("costs", "expense", "150.00", "USD"),
+		balance("idle", "expense", "0.00", "USD"),
+		balance("eur-costs", "expense", "30.00", "EUR"),
+	}
+	retained := map[string]string{"USD": "re-usd", "EUR": "re-eur"}
+	
+	entries, err := planPeriodClose(period, balances, retained, "txn-1", "controller")
+	require.NoError(t, err)
+	require.Len(t, entries, 6)
+	
+	legs := map[string]*JournalEntry{}
+	for _, e := range entries {
+		legs[e.AccountID] = e
+		assert.Equal(t, "txn-1", e.TransactionID)
+		assert.Equal(t, "2024-01-31", e.EffectiveDate)
+		assert.Equal(t, ReferenceTypePeriodClose, e.ReferenceType)
+		assert.Equal(t, "p-1", e.ReferenceID)
+	}
+	assert.NotContains(t, legs, "cash")
+	assert.NotContains(t, legs, "idle")
+	
+	assert.Equal(t, "debit", legs["fees"].EntryType)
+	assert.Equal(t, "400.00", legs["fees"].Amount.Amount())
+	assert.Equal(t, "credit", legs["refunds"].EntryType)
+	assert.Equal(t, "50.00", legs["refunds"].Amount.Amount())
+	assert.Equal(t, "credit", legs["costs"].EntryType)
+	assert.Equal(t, "150.00", legs["costs"].Amount.Amount())
+	
+	// 400 - 50 - 150 of profit in USD, a 30 loss in EUR
+	assert.Equal(t, "credit", legs["re-usd"].EntryType)
+	assert.Equal(t, "200.00", legs["re-usd"].Amount.Amount())
+	assert.Equal(t, "debit", legs["re-eur"].EntryType)
+	assert.Equal(t, "30.00", legs["re-eur"].Amount.Amount())
+	
+	var plain []JournalEntry
+	for _, e := range entries {
+		plain = append(plain, *e)
+	}
+	assert.NoError(t, ValidateTransactionEntries(plain))
+	
+	_, err = planPeriodClose(period, balances, map[string]string{"USD": "re-usd"}, "txn-1", "controller")
+	assert.ErrorIs(t, err, ErrRetainedEarningsNotConfigured)
+	
+	entries, err = planPeriodClose(period, balances[:1], retained, "txn-1", "controller")
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+// TestLedgerService_SubmitBatchValidation tests that a batch with any invalid
+// line is rejected whole, with every invalid line reported
+func TestLedgerService_SubmitBatchValidation(t *testing.T) {
+	ctx := context.Background()
+	
+	// Validation must fail before the pool is ever used
+	ledgerService := NewLedgerService(&PostgresLedger{})
+	
+	leg := func(account, entryType, amount string) JournalEntry {
+		return JournalEntry{AccountID: account, EntryType: entryType, Amount: money.MustParse(amount, "USD")}
+	}
+	dupID := "6f1c2d3e-4a5b-4c6d-8e7f-901a2b3c4d5e"
+	
+	_, err := ledgerService.SubmitBatch(ctx, BatchRequest{CreatedBy: "settlement", Transactions: []Transaction{{}}})
+	assert.ErrorIs(t, err, ErrInvalidBatch)
+	_, err = ledgerService.SubmitBatch(ctx, BatchRequest{Reference: "2024-03-01", CreatedBy: "settlement"})
+	assert.ErrorIs(t, err, ErrInvalidBatch)
+	
+	_, err = ledgerService.SubmitBatch(ctx, BatchRequest{
+		Reference: "2024-03-01",
+		CreatedBy: "settlement",
+		Transactions: []Transaction{
+			{Entries: []JournalEntry{leg("acc-1", "debit", "10.00"), leg("acc-2", "credit", "10.00")}},
+			{Entries: []JournalEntry{leg("acc-1", "debit", "10.00"), leg("acc-2", "credit", "9.00")}},
+			{TransactionID: dupID, Entries: []JournalEntry{leg("acc-1", "debit", "1.00"), leg("acc-2", "credit", "1.00")}},
+			{TransactionID: dupID, Entries: []JournalEntry{leg("acc-1", "debit", "1.00"), leg("acc-2", "credit", "1.00")}},
+			{TransactionID: "txn-5", Entries: []JournalEntry{leg("acc-1", "debit", "1.00"), leg("acc-2", "credit", "1.00")}},
+		},
+	})
+	var batchErr *BatchError
+	require.ErrorAs(t, err, &batchErr)
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+	require.Len(t, batchErr.Lines, 3)
+	assert.Equal(t, 2, batchErr.Lines[0].LineNumber)
+	assert.Contains(t, batchErr.Lines[0].Error, "unbalanced")
+	assert.Equal(t, 4, batchErr.Lines[1].LineNumber)
+	assert.Contains(t, batchErr.Lines[1].Error, "repeats line 3")
+	assert.Equal(t, 5, batchErr.Lines[2].LineNumber)
+	assert.Contains(t, batchErr.Lines[2].Error, "not a UUID")
+	
+	_, err = ledgerService.GetBatch(ctx, "batch-1")
+	assert.ErrorIs(t, err, ErrBatchNotFound)
+	_, err = ledgerService.ListBatchLines(ctx, BatchLineFilter{BatchID: dupID, Status: "lost"})
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+}
+
+func TestPrepareBatchLine(t *testing.T) {
+	txn := Transaction{
+		Description: "Card settlement",
+		Entries: []JournalEntry{
+			{AccountID: "acc-1", EntryType: "debit", Amount: money.MustParse("5.00", "EUR")},
+			{AccountID: "acc-2", EntryType: "credit", Amount: money.MustParse("5.00", "EUR")},
+		},
+	}
+	
+	// The creator is required, as for PostTransaction
+	assert.ErrorIs(t, prepareBatchLine(&txn, time.Now(), map[string]int{}), ErrInvalidEntry)
+	
+	txn.CreatedBy = "settlement"
+	require.NoError(t, prepareBatchLine(&txn, time.Now(), map[string]int{}))
+	assert.NotEmpty(t, txn.TransactionID)
+	assert.Equal(t, "JE-"+txn.TransactionID+"-2", txn.Entries[1].EntryNumber)
+	assert.Equal(t, "Card settlement", txn.Entries[0].Description)
+	assert.Equal(t, "EUR", txn.Entries[0].CurrencyCode)
+	
+	err := prepareBatchLine(&txn, time.Now(), map[string]int{txn.TransactionID: 7})
+	assert.ErrorIs(t, err, ErrInvalidEntry)
+	assert.Contains(t, err.Error(), "repeats line 7")
+}
+
+func TestCheckBatchLineAccounts(t *testing.T) {
+	accounts := map[string]batchAccount{
+		"acc-usd":    {status: AccountStatusActive, currencyCode: "USD"},
+		"acc-frozen": {status: AccountStatusFrozen, currencyCode: "USD"},
+	}
+	txn := func(debit, credit string) Transaction {
+		return Transaction{Entries: []JournalEntry{
+			{AccountID: debit, EntryType: "debit", Amount: money.MustParse("1.00", "USD")},
+			{AccountID: credit, EntryType: "credit", Amount: money.MustParse("1.00", "USD")},
+		}}
+	}
+	
+	assert.NoError(t, checkBatchLineAccounts(txn("acc-usd", "acc-frozen"), accounts))
+	assert.ErrorIs(t, checkBatchLineAccounts(txn("acc-frozen", "acc-usd"), accounts), ErrAccountFrozen)
+	assert.ErrorIs(t, checkBatchLineAccounts(txn("acc-usd", "acc-missing"), accounts), ErrAccountNotFound)
+	
+	accounts["acc-eur"] = batchAccount{status: AccountStatusActive, currencyCode: "EUR"}
+	assert.ErrorIs(t, checkBatchLineAccounts(txn("acc-usd", "acc-eur"), accounts), ErrCurrencyMismatch)
+	
+	assert.Equal(t, []string{"acc-usd", "acc-frozen", "acc-eur"}, batchAccountIDs([]Transaction{
+		txn("acc-usd", "acc-frozen"), txn("acc-frozen", "acc-eur"),
+	}))
+}
+
+func TestReadBatchFile(t *testing.T) {
+	file := `{"transaction_id":"t-1","created_by":"settlement","entries":[{"account_id":"acc-1","entry_type":"debit","amount":{"amount":"12.50","currency":"USD"}},{"account_id":"acc-2","entry_type":"credit","amount":{"amount":"12.50","currency":"USD"}}]}
+
+{"transaction_id":"t-2","entries":[]}
+`
+	txns, err := ReadBatchFile(strings.NewReader(file))
+	require.NoError(t, err)
+	require.Len(t, txns, 2)
+	assert.Equal(t, "t-1", txns[0].TransactionID)
+	assert.True(t, txns[0].Entries[0].Amount.Equal(money.MustParse("12.50", "USD")))
+	
+	_, err = ReadBatchFile(strings.NewReader(file + `{"transaction_id":`))
+	assert.ErrorIs(t, err, ErrInvalidBatch)
+	assert.Contains(t, err.Error(), "line 3")
+}
+
+func TestPostingOrder(t *testing.T) {
+	entries := []*JournalEntry{
+		{TransactionID: "t-1", EntryNumber: "1a", AccountType: "asset", EntryType: "credit"},
+		{TransactionID: "t-1", EntryNumber: "1b", AccountType: "asset", EntryType: "debit"},
+		{TransactionID: "t-2", EntryNumber: "2a", AccountType: "liability", EntryType: "debit"},
+		{TransactionID: "t-2", EntryNumber: "2b", AccountType: "asset", EntryType: "debit"},
+	}
+	
+	// Each transaction's increases come first, but transactions keep their order
+	var numbers []string
+	for _, entry := range postingOrder(entries) {
+		numbers = append(numbers, entry.EntryNumber)
+	}
+	assert.Equal(t, []string{"1b", "1a", "2b", "2a"}, numbers)
+}
+
+func TestBatchLineFailure(t *testing.T) {
+	assert.True(t, batchLineFailure(fmt.Errorf("posting: %w", ErrAccountFrozen)))
+	assert.True(t, batchLineFailure(fmt.Errorf("posting: %w", ErrPeriodHardClosed)))
+	assert.True(t, batchLineFailure(&pgconn.PgError{Code: "P0001", Message: "Asset account balance cannot be negative"}))
+	assert.True(t, batchLineFailure(&pgconn.PgError{Code: "23505"}))
+	assert.False(t, batchLineFailure(&pgconn.PgError{Code: "40001"}))
+	assert.False(t, batchLineFailure(context.DeadlineExceeded))
+	
+	assert.Equal(t, defaultBatchLineLimit, batchLineLimit(0))
+	assert.Equal(t, maxBatchLineLimit, batchLineLimit(maxBatchLineLimit+1))
+}
+
+func TestValidateCreditLimit(t *testing.T) {
+	maxAmount := money.MustParse("500.00", "USD")
+	valid := SetCreditLimitRequest{
+		AccountID:            "acc-1",
+		OverdraftLimit:       money.MustParse("1000.00", "USD"),
+		MaxTransactionAmount: &maxAmount,
+		Reason:               "credit review",
+		SetBy:                "risk-ops",
+	}
+	assert.NoError(t, validateCreditLimit(valid))
+	
+	zeroMax := money.MustParse("0", "USD")
+	eurMax := money.MustParse("500.00", "EUR")
+	tests := []struct {
+		name   string
+		mutate func(*SetCreditLimitRequest)
+		want   error
+	}{
+		{"missing account", func(r *SetCreditLimitRequest) { r.AccountID = "" }, ErrInvalidRequest},
+		{"missing reason", func(r *SetCreditLimitRequest) { r.Reason = "" }, ErrInvalidRequest},
+		{"missing set_by", func(r *SetCreditLimitRequest) { r.SetBy = "" }, ErrInvalidRequest},
+		{"negative overdraft", func(r *SetCreditLimitRequest) { r.OverdraftLimit = money.MustParse("-1.00", "USD") }, ErrInvalidRequest},
+		{"zero maximum", func(r *SetCreditLimitRequest) { r.MaxTransactionAmount = &zeroMax }, ErrInvalidRequest},
+		{"maximum currency", func(r *SetCreditLimitRequest) { r.MaxTransactionAmount = &eurMax }, ErrCurrencyMismatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := valid
+			tt.mutate(&req)
+			assert.ErrorIs(t, validateCreditLimit(req), tt.want)
+		})
+	}
+}
+
+func TestBalanceChanges(t *testing.T) {
+	entries := []*JournalEntry{
+		{TransactionID: "t-1", AccountID: "cash", AccountType: "asset", EntryType: "credit", Amount: money.MustParse("30.00", "USD")},
+		{TransactionID: "t-1", AccountID: "cash", AccountType: "asset", EntryType: "debit", Amount: money.MustParse("10.00", "USD")},
+		{TransactionID: "t-1", AccountID: "payable", AccountType: "liability", EntryType: "debit", Amount: money.MustParse("20.00", "USD")},
+		{TransactionID: "t-2", AccountID: "cash", AccountType: "asset", EntryType: "debit", Amount: money.MustParse("5.00", "USD")},
+	}
+	changes, err := balanceChanges(entries)
+	require.NoError(t, err)
+	require.Len(t, changes, 3)
+	assert.Equal(t, "cash", changes[0].accountID)
+	assert.True(t, changes[0].amount.Equal(money.MustParse("-20.00", "USD")))
+	assert.True(t, changes[1].amount.Equal(money.MustParse("-20.00", "USD")))
+	assert.Equal(t, "t-2", changes[2].transactionID)
+	assert.True(t, changes[2].amount.Equal(money.MustParse("5.00", "USD")))
+}
+
+func TestCheckCreditLimits(t *testing.T) {
+	maxAmount := money.MustParse("100.00", "USD")
+	accounts := func() map[string]*limitedAccount {
+		return map[string]*limitedAccount{
+			"cash":     {balance: money.MustParse("50.00", "USD"), overdraftLimit: money.MustParse("0", "USD")},
+			"credit":   {balance: money.MustParse("50.00", "USD"), overdraftLimit: money.MustParse("200.00", "USD"), maxTransaction: &maxAmount},
+			"clearing": {balance: money.MustParse("0", "USD"), overdraftLimit: money.MustParse("0", "USD"), noFloor: true},
+		}
+	}
+	change := func(txn, account, amount string) balanceChange {
+		return balanceChange{transactionID: txn, accountID: account, amount: money.MustParse(amount, "USD")}
+	}
+	
+	assert.NoError(t, checkCreditLimits([]balanceChange{change("t-1", "cash", "-50.00")}, accounts()))
+	assert.ErrorIs(t, checkCreditLimits([]balanceChange{change("t-1", "cash", "-50.01")}, accounts()), ErrInsufficientFunds)
+	
+	// Overdraft lets the balance go negative, down to the limit
+	assert.NoError(t, checkCreditLimits([]balanceChange{change("t-1", "credit", "-100.00"), change("t-2", "credit", "-100.00")}, accounts()))
+	assert.ErrorIs(t, checkCreditLimits([]balanceChange{change("t-1", "credit", "-100.00"), change("t-2", "credit", "-100.00"), change("t-3", "credit", "-50.01")}, accounts()), ErrInsufficientFunds)
+	assert.ErrorIs(t, checkCreditLimits([]balanceChange{change("t-1", "credit", "-100.01")}, accounts()), ErrTransactionLimitExceeded)
+	
+	// Increases are never limited and count towards later transactions
+	assert.NoError(t, checkCreditLimits([]balanceChange{change("t-1", "cash", "1000.00"), change("t-2", "cash", "-1050.00")}, accounts()))
+	
+	assert.NoError(t, checkCreditLimits([]balanceChange{change("t-1", "clearing", "-1000000.00")}, accounts()))
+	assert.NoError(t, checkCreditLimits([]balanceChange{change("t-1", "unlocked", "-1.00")}, accounts()))
+	
+	// Pending transactions and holds reserve part of the balance
+	reserved := accounts()
+	reserved["cash"].reserved = money.MustParse("30.00", "USD")
+	assert.NoError(t, checkCreditLimits([]balanceChange{change("t-1", "cash", "-20.00")}, reserved))
+	assert.ErrorIs(t, checkCreditLimits([]balanceChange{change("t-1", "cash", "-20.01")}, reserved), ErrInsufficientFunds)
+}
+
+// authorizedMockPool answers as if an authorization holds the whole 100.00
+// balance of asset account acc-123
+func authorizedMockPool() *SimpleMockPool {
+	return &SimpleMockPool{
+		queryRowFunc: func(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+			switch {
+			case contains(sql, "get_pending_amount"):
+				return &mockRowWithValues{values: []interface{}{"100.00", "100.00", "0", "0", "USD"}}
+			case contains(sql, "SELECT account_type, status, currency_code"):
+				return &mockRowWithValues{values: []interface{}{"asset", AccountStatusActive, "USD"}}
+			case contains(sql, "FROM accounts"):
+				return &mockRowWithValues{values: []interface{}{
+					args[0], "", args[0], "asset", "Test Account", "USD", true, "active",
+					"2024-01-01T00:00:00Z", "test_user", nil, "100.00",
+				}}
+			}
+			return &mockRow{}
+		},
+		queryFunc: func(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+			switch {
+			case contains(sql, "FOR UPDATE"):
+				return &mockRows{rows: [][]interface{}{
+					{"acc-123", "asset", AccountStatusActive, "USD"},
+					{"acc-456", "asset", AccountStatusActive, "USD"},
+				}}, nil
+			case contains(sql, "get_overdraft_limit"):
+				return &mockRows{rows: [][]interface{}{{"acc-123", "USD", "100.00", "100.00", "0", nil}}}, nil
+			}
+			return &mockRows{}, nil
+		},
+	}
+}
+
+// TestTransferAfterAuthorize tests that funds reserved by an authorization
+// cannot be moved by a transfer or a posting
+func TestTransferAfterAuthorize(t *testing.T) {
+	ctx := context.Background()
+	
+	postgresLedger := &PostgresLedger{Pool: authorizedMockPool()}
+	ledgerService := NewLedgerService(postgresLedger)
+	
+	err := ledgerService.Transfer(ctx, TransferRequest{
+		FromAccountID: "acc-123",
+		ToAccountID:   "acc-456",
+		Amount:        money.MustParse("100.00", "USD"),
+		Description:   "Test transfer",
+		CurrencyCode:  "USD",
+		CreatedBy:     "test_user",
+	})
+	assert.ErrorIs(t, err, ErrInsufficientFunds)
+	
+	// The posting checks available funds under the account lock too
+	err = postgresLedger.PostTransaction(ctx, []*JournalEntry{
+		{TransactionID: "txn-1", EntryNumber: "JE-1-credit", EntryType: "credit", AccountID: "acc-123", Amount: money.MustParse("100.00", "USD"), CurrencyCode: "USD", CreatedBy: "test_user"},
+		{TransactionID: "txn-1", EntryNumber: "JE-1-debit", EntryType: "debit", AccountID: "acc-456", Amount: money.MustParse("100.00", "USD"), CurrencyCode: "USD", CreatedBy: "test_user"},
+	})
+	assert.ErrorIs(t, err, ErrInsufficientFunds)
+}
+
 // TestReconcileRequest_Validation tests reconcile request validation
 func TestReconcileRequest_Validation(t *testing.T) {
 	ctx := context.Background()
@@ -396,7 +1601,7 @@ type mockRowWithValues struct {
 func (r *mockRowWithValues) Scan(dest ...interface{}) error {
 	for i, d := range dest {
 		if i < len(r.values) {
-			switch v := dest[i].(type) {
+			switch v := d.(type) {
 			case *string:
 				if str, ok := r.values[i].(string); ok {
 					*v = str
@@ -409,10 +1614,21 @@ func (r *mockRowWithValues) Scan(dest ...interface{}) error {
 				if f, ok := r.values[i].(float64); ok {
 					*v = sql.NullFloat64{Float64: f, Valid: true}
 				}
+			case *pgtype.Numeric:
+				if str, ok := r.values[i].(string); ok {
+					if err := v.Scan(str); err != nil {
+						return err
+					}
+				}
 			case *sql.NullBool:
 				if b, ok := r.values[i].(bool); ok {
 					*v = sql.NullBool{Bool: b, Valid: true}
 				}
+			default:
+				// Plain destinations such as *bool take the value as is
+				if r.values[i] != nil {
+					reflect.ValueOf(d).Elem().Set(reflect.ValueOf(r.values[i]))
+				}
 			}
 		}
 	}
@@ -423,8 +1639,7 @@ func (r *mockRowWithValues) Scan(dest ...interface{}) error {
 func BenchmarkCreateAccount(b *testing.B) {
 	ctx := context.Background()
 	
-	mockPool := &SimpleMockPool{}
-	postgresLedger := &PostgresLedger{Pool: mockPool}
+	postgresLedger := &PostgresLedger{Pool: accountMockPool()}
 	
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
@@ -0,0 +1,70 @@
+package ledger
+
+import "fmt"
+
+// Account statuses. Only active accounts report IsActive.
+const (
+	// AccountStatusActive accepts debits and credits
+	AccountStatusActive = "active"
+	// AccountStatusFrozen accepts credits but rejects debits
+	AccountStatusFrozen = "frozen"
+	// AccountStatusBlocked rejects all postings
+	AccountStatusBlocked = "blocked"
+	// AccountStatusClosed rejects all postings and cannot be left
+	AccountStatusClosed = "closed"
+)
+
+// AccountStatusRequest represents a lifecycle change to an account
+type AccountStatusRequest struct {
+	AccountID string `json:"account_id"`
+	Reason    string `json:"reason"`
+	ChangedBy string `json:"changed_by"`
+}
+
+// AccountStatusTransition records one change to an account's status
+type AccountStatusTransition struct {
+	ID         string `json:"id"`
+	AccountID  string `json:"account_id"`
+	FromStatus string `json:"from_status"`
+	ToStatus   string `json:"to_status"`
+	Reason     string `json:"reason"`
+	ChangedBy  string `json:"changed_by"`
+	CreatedAt  string `json:"created_at"`
+}
+
+// checkStatusTransition reports whether an account may move from one status to
+// another. Any open status may move to any other; closed is final.
+func checkStatusTransition(accountID, from, to string) error {
+	switch to {
+	case AccountStatusActive, AccountStatusFrozen, AccountStatusBlocked, AccountStatusClosed:
+	default:
+		return fmt.Errorf("%w: unknown account status %q", ErrInvalidRequest, to)
+	}
+	if from == AccountStatusClosed {
+		return fmt.Errorf("%w: %s", ErrAccountClosed, accountID)
+	}
+	if from == to {
+		return fmt.Errorf("%w: %s is already %s", ErrInvalidStatusTransition, accountID, to)
+	}
+	return nil
+}
+
+// checkPostable reports whether a leg of entryType may be posted to an account
+// in the given status
+func checkPostable(accountID, status, entryType string) error {
+	switch status {
+	case AccountStatusActive:
+		return nil
+	case AccountStatusFrozen:
+		if entryType == "credit" {
+			return nil
+		}
+		return fmt.Errorf("%w: %s", ErrAccountFrozen, accountID)
+	case AccountStatusBlocked:
+		return fmt.Errorf("%w: %s", ErrAccountBlocked, accountID)
+	case AccountStatusClosed:
+		return fmt.Errorf("%w: %s", ErrAccountClosed, accountID)
+	default:
+		return fmt.Errorf("%w: %s has status %q", ErrAccountInactive, accountID, status)
+	}
+}
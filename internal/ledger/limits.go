@@ -0,0 +1,161 @@
+package ledger
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/example/pci-infra/internal/money"
+)
+
+// CreditLimit lets an account's balance fall below zero, down to minus
+// OverdraftLimit, and caps how much one transaction may reduce it. A limit is
+// in effect from EffectiveFrom until a later limit for the account supersedes
+// it. Limits are never changed, so an account's limits are its audit trail.
+type CreditLimit struct {
+	ID             string      `json:"id"`
+	AccountID      string      `json:"account_id"`
+	OverdraftLimit money.Money `json:"overdraft_limit"`
+	// MaxTransactionAmount is nil when transactions are not capped
+	MaxTransactionAmount *money.Money `json:"max_transaction_amount,omitempty"`
+	EffectiveFrom        string       `json:"effective_from"`
+	Reason               string       `json:"reason"`
+	SetBy                string       `json:"set_by"`
+	CreatedAt            string       `json:"created_at"`
+}
+
+// SetCreditLimitRequest represents the request to set an account's limits. A
+// zero EffectiveFrom makes the limit effective immediately. Setting a zero
+// overdraft limit and no maximum restores the default of neither.
+type SetCreditLimitRequest struct {
+	AccountID            string       `json:"account_id"`
+	OverdraftLimit       money.Money  `json:"overdraft_limit"`
+	MaxTransactionAmount *money.Money `json:"max_transaction_amount,omitempty"`
+	EffectiveFrom        time.Time    `json:"effective_from"`
+	Reason               string       `json:"reason"`
+	SetBy                string       `json:"set_by"`
+}
+
+func validateCreditLimit(req SetCreditLimitRequest) error {
+	if req.AccountID == "" {
+		return fmt.Errorf("%w: account ID is required", ErrInvalidRequest)
+	}
+	if req.Reason == "" {
+		return fmt.Errorf("%w: reason is required", ErrInvalidRequest)
+	}
+	if req.SetBy == "" {
+		return fmt.Errorf("%w: set_by is required", ErrInvalidRequest)
+	}
+	if _, err := money.Exponent(req.OverdraftLimit.Currency()); err != nil {
+		return fmt.Errorf("%w: overdraft limit currency: %v", ErrInvalidRequest, err)
+	}
+	if req.OverdraftLimit.IsNegative() {
+		return fmt.Errorf("%w: overdraft limit must not be negative", ErrInvalidRequest)
+	}
+	if maxAmount := req.MaxTransactionAmount; maxAmount != nil {
+		if !maxAmount.IsPositive() {
+			return fmt.Errorf("%w: max transaction amount must be positive", ErrInvalidRequest)
+		}
+		if maxAmount.Currency() != req.OverdraftLimit.Currency() {
+			return fmt.Errorf("%w: max transaction amount %s vs overdraft limit %s", ErrCurrencyMismatch, maxAmount.Currency(), req.OverdraftLimit.Currency())
+		}
+	}
+	return nil
+}
+
+// limitedAccount is an account's balance and the limit in force, which a
+// posting is checked against
+type limitedAccount struct {
+	balance money.Money
+	// reserved is held back from the balance by pending transactions and
+	// unposted holds
+	reserved       money.Money
+	overdraftLimit money.Money
+	maxTransaction *money.Money
+	// noFloor marks FX clearing accounts, which may go negative without limit
+	noFloor bool
+}
+
+// balanceChange is the net change one transaction makes to an account's balance
+type balanceChange struct {
+	transactionID string
+	accountID     string
+	amount        money.Money
+}
+
+// balanceChanges nets the legs of each transaction by account, in order of
+// first appearance. Legs need their AccountType.
+func balanceChanges(entries []*JournalEntry) ([]balanceChange, error) {
+	type key struct{ transactionID, accountID string }
+	index := make(map[key]int)
+	var changes []balanceChange
+	for _, entry := range entries {
+		amount := entry.Amount
+		if !increasesBalance(entry.AccountType, entry.EntryType) {
+			amount = amount.Neg()
+		}
+		k := key{entry.TransactionID, entry.AccountID}
+		i, ok := index[k]
+		if !ok {
+			index[k] = len(changes)
+			changes = append(changes, balanceChange{transactionID: entry.TransactionID, accountID: entry.AccountID, amount: amount})
+			continue
+		}
+		sum, err := changes[i].amount.Add(amount)
+		if err != nil {
+			return nil, fmt.Errorf("%w: account %s: %v", ErrInvalidEntry, entry.AccountID, err)
+		}
+		changes[i].amount = sum
+	}
+	return changes, nil
+}
+
+// checkCreditLimits applies changes in order to the balances of accounts and
+// rejects the first that reduces an account by more than its transaction
+// limit or takes it below its overdraft limit. Accounts missing from the map
+// are not checked.
+func checkCreditLimits(changes []balanceChange, accounts map[string]*limitedAccount) error {
+	for _, c := range changes {
+		acct, ok := accounts[c.accountID]
+		if !ok {
+			continue
+		}
+		if err := checkCreditLimit(c.accountID, c.transactionID, *acct, c.amount); err != nil {
+			return err
+		}
+		balance, err := acct.balance.Add(c.amount)
+		if err != nil {
+			return fmt.Errorf("%w: account %s: %v", ErrCurrencyMismatch, c.accountID, err)
+		}
+		acct.balance = balance
+	}
+	return nil
+}
+
+// checkCreditLimit checks one transaction's net change to an account against
+// the account's limits
+func checkCreditLimit(accountID, transactionID string, acct limitedAccount, change money.Money) error {
+	if !change.IsNegative() {
+		return nil
+	}
+	if acct.maxTransaction != nil && change.Abs().GreaterThan(*acct.maxTransaction) {
+		return fmt.Errorf("%w: transaction %s reduces account %s by %s, over its limit of %s", ErrTransactionLimitExceeded, transactionID, accountID, change.Abs(), acct.maxTransaction)
+	}
+	if acct.noFloor {
+		return nil
+	}
+	available := acct.balance
+	if !acct.reserved.IsZero() {
+		var err error
+		if available, err = acct.balance.Sub(acct.reserved); err != nil {
+			return fmt.Errorf("%w: account %s: %v", ErrCurrencyMismatch, accountID, err)
+		}
+	}
+	after, err := available.Add(change)
+	if err != nil {
+		return fmt.Errorf("%w: account %s: %v", ErrCurrencyMismatch, accountID, err)
+	}
+	if after.LessThan(acct.overdraftLimit.Neg()) {
+		return fmt.Errorf("%w: account %s has %s available with an overdraft limit of %s, needs %s", ErrInsufficientFunds, accountID, available, acct.overdraftLimit, change.Abs())
+	}
+	return nil
+}
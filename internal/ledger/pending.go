@@ -0,0 +1,112 @@
+package ledger
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/example/pci-infra/internal/money"
+)
+
+// Pending transaction statuses
+const (
+	PendingStatusPending = "pending"
+	PendingStatusPosted  = "posted"
+	PendingStatusVoided  = "voided"
+	PendingStatusExpired = "expired"
+)
+
+// DefaultPendingTTL is how long an authorization reserves funds when neither
+// the request nor the service sets a TTL
+const DefaultPendingTTL = 7 * 24 * time.Hour
+
+// PendingTransaction is an authorized transfer that reserves funds on
+// FromAccountID until it is captured, voided or expires
+type PendingTransaction struct {
+	ID             string                 `json:"id"`
+	TransactionID  string                 `json:"transaction_id"`
+	FromAccountID  string                 `json:"from_account_id"`
+	ToAccountID    string                 `json:"to_account_id"`
+	Amount         money.Money            `json:"amount"`
+	CapturedAmount *money.Money           `json:"captured_amount,omitempty"`
+	Status         string                 `json:"status"`
+	Description    string                 `json:"description"`
+	ReferenceType  string                 `json:"reference_type"`
+	ReferenceID    string                 `json:"reference_id"`
+	Metadata       map[string]interface{} `json:"metadata"`
+	ExpiresAt      string                 `json:"expires_at"`
+	CreatedAt      string                 `json:"created_at"`
+	CreatedBy      string                 `json:"created_by"`
+	ResolvedAt     string                 `json:"resolved_at,omitempty"`
+	ResolvedBy     string                 `json:"resolved_by,omitempty"`
+}
+
+// AuthorizeRequest represents the request to reserve funds for a later capture
+type AuthorizeRequest struct {
+	TransactionID string                 `json:"transaction_id"`
+	FromAccountID string                 `json:"from_account_id"`
+	ToAccountID   string                 `json:"to_account_id"`
+	Amount        money.Money            `json:"amount"`
+	Description   string                 `json:"description"`
+	ReferenceType string                 `json:"reference_type"`
+	ReferenceID   string                 `json:"reference_id"`
+	CreatedBy     string                 `json:"created_by"`
+	Metadata      map[string]interface{} `json:"metadata"`
+	// TTL overrides the service's pending TTL for this authorization
+	TTL time.Duration `json:"ttl"`
+}
+
+// CaptureRequest represents the request to post a pending transaction. A zero
+// Amount captures the full authorized amount; a smaller amount captures part of
+// it and releases the rest.
+type CaptureRequest struct {
+	PendingID  string      `json:"pending_id"`
+	Amount     money.Money `json:"amount"`
+	CapturedBy string      `json:"captured_by"`
+}
+
+// VoidRequest represents the request to release a pending transaction unposted
+type VoidRequest struct {
+	PendingID string `json:"pending_id"`
+	VoidedBy  string `json:"voided_by"`
+}
+
+// AccountBalances breaks an account balance down into what is posted, what is
+// reserved by pending transactions and dispute holds, and what is left to
+// spend, overdraft included
+type AccountBalances struct {
+	AccountID    string      `json:"account_id"`
+	CurrencyCode string      `json:"currency_code"`
+	Posted       money.Money `json:"posted"`
+	Pending      money.Money `json:"pending"`
+	Held         money.Money `json:"held"`
+	// OverdraftLimit is how far below zero the account may go
+	OverdraftLimit money.Money `json:"overdraft_limit"`
+	Available      money.Money `json:"available"`
+}
+
+// captureEntries builds the transfer legs posted when a pending transaction is
+// captured for amount
+func captureEntries(p *PendingTransaction, amount money.Money, capturedBy string) []*JournalEntry {
+	metadata := map[string]interface{}{"pending_transaction_id": p.ID}
+	for k, v := range p.Metadata {
+		metadata[k] = v
+	}
+
+	entry := func(entryType, accountID string) *JournalEntry {
+		return &JournalEntry{
+			EntryNumber:   fmt.Sprintf("JE-%s-%s", p.TransactionID, entryType),
+			TransactionID: p.TransactionID,
+			EntryType:     entryType,
+			AccountID:     accountID,
+			Amount:        amount,
+			Description:   p.Description,
+			ReferenceType: p.ReferenceType,
+			ReferenceID:   p.ReferenceID,
+			CurrencyCode:  amount.Currency(),
+			CreatedBy:     capturedBy,
+			Metadata:      metadata,
+		}
+	}
+
+	return []*JournalEntry{entry("debit", p.FromAccountID), entry("credit", p.ToAccountID)}
+}
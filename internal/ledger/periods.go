@@ -0,0 +1,246 @@
+package ledger
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/example/pci-infra/internal/money"
+)
+
+// Accounting period statuses, in the only order a period moves through them
+const (
+	// PeriodStatusOpen accepts postings
+	PeriodStatusOpen = "open"
+	// PeriodStatusSoftClosed accepts adjustments from callers allowed to post
+	// into soft-closed periods
+	PeriodStatusSoftClosed = "soft_closed"
+	// PeriodStatusHardClosed rejects all postings and cannot be left
+	PeriodStatusHardClosed = "hard_closed"
+)
+
+// ReferenceTypePeriodClose marks the entries that roll revenue and expense
+// into retained earnings when a period closes
+const ReferenceTypePeriodClose = "period_close"
+
+// DateLayout is the format of entry effective dates and period bounds
+const DateLayout = "2006-01-02"
+
+// AccountingPeriod is a date range, inclusive at both ends, that is closed once
+// it has been reported
+type AccountingPeriod struct {
+	ID              string                 `json:"id"`
+	Name            string                 `json:"name"`
+	StartsOn        string                 `json:"starts_on"`
+	EndsOn          string                 `json:"ends_on"`
+	Status          string                 `json:"status"`
+	CreatedAt       string                 `json:"created_at"`
+	CreatedBy       string                 `json:"created_by"`
+	SoftClosedAt    string                 `json:"soft_closed_at,omitempty"`
+	SoftClosedBy    string                 `json:"soft_closed_by,omitempty"`
+	HardClosedAt    string                 `json:"hard_closed_at,omitempty"`
+	HardClosedBy    string                 `json:"hard_closed_by,omitempty"`
+	ClosingBalances []PeriodClosingBalance `json:"closing_balances,omitempty"`
+}
+
+// PeriodClosingBalance is an account's balance at the end of a closed period,
+// in its normal direction
+type PeriodClosingBalance struct {
+	AccountID     string      `json:"account_id"`
+	AccountNumber string      `json:"account_number"`
+	AccountType   string      `json:"account_type"`
+	Balance       money.Money `json:"balance"`
+}
+
+// CreatePeriodRequest represents the request to open an accounting period.
+// StartsOn and EndsOn are dates in DateLayout.
+type CreatePeriodRequest struct {
+	Name      string `json:"name"`
+	StartsOn  string `json:"starts_on"`
+	EndsOn    string `json:"ends_on"`
+	CreatedBy string `json:"created_by"`
+}
+
+// ClosePeriodRequest represents a soft or hard close of an accounting period.
+// TransactionID identifies the entries that roll revenue and expense into
+// retained earnings.
+type ClosePeriodRequest struct {
+	PeriodID      string `json:"period_id"`
+	TransactionID string `json:"transaction_id"`
+	ClosedBy      string `json:"closed_by"`
+}
+
+// RetainedEarningsAccount is the equity account revenue and expense in one
+// currency roll into at period close
+type RetainedEarningsAccount struct {
+	CurrencyCode string `json:"currency_code"`
+	AccountID    string `json:"account_id"`
+	UpdatedBy    string `json:"updated_by"`
+}
+
+type softClosedKey struct{}
+
+// WithSoftClosedPostings allows postings made with the returned context to take
+// effect in soft-closed periods. Grant it only to callers holding the elevated
+// adjustment permission.
+func WithSoftClosedPostings(ctx context.Context) context.Context {
+	return context.WithValue(ctx, softClosedKey{}, true)
+}
+
+func softClosedAllowed(ctx context.Context) bool {
+	allowed, _ := ctx.Value(softClosedKey{}).(bool)
+	return allowed
+}
+
+// periodRank orders period statuses so a period only ever moves forward
+var periodRank = map[string]int{
+	PeriodStatusOpen:       0,
+	PeriodStatusSoftClosed: 1,
+	PeriodStatusHardClosed: 2,
+}
+
+// checkPeriodTransition reports whether a period may move from one status to
+// another. Periods move forward only and hard closed is final.
+func checkPeriodTransition(name, from, to string) error {
+	if to != PeriodStatusSoftClosed && to != PeriodStatusHardClosed {
+		return fmt.Errorf("%w: unknown close status %q", ErrInvalidRequest, to)
+	}
+	if from == PeriodStatusHardClosed {
+		return fmt.Errorf("%w: %s", ErrPeriodHardClosed, name)
+	}
+	if periodRank[from] >= periodRank[to] {
+		return fmt.Errorf("%w: %s is already %s", ErrInvalidPeriodTransition, name, from)
+	}
+	return nil
+}
+
+// checkPeriodPostable reports whether an entry may take effect in a period in
+// the given status
+func checkPeriodPostable(name, status, effectiveDate string, allowSoftClosed bool) error {
+	switch status {
+	case PeriodStatusOpen:
+		return nil
+	case PeriodStatusSoftClosed:
+		if allowSoftClosed {
+			return nil
+		}
+		return fmt.Errorf("%w: %s, entry effective %s", ErrPeriodSoftClosed, name, effectiveDate)
+	case PeriodStatusHardClosed:
+		return fmt.Errorf("%w: %s, entry effective %s", ErrPeriodHardClosed, name, effectiveDate)
+	default:
+		return fmt.Errorf("%w: %s has status %q", ErrPeriodClosed, name, status)
+	}
+}
+
+// validateEffectiveDate checks that date is a DateLayout date no later than
+// today (UTC) at now. Entries may be back-dated but not post-dated.
+func validateEffectiveDate(date string, now time.Time) error {
+	d, err := time.Parse(DateLayout, date)
+	if err != nil {
+		return fmt.Errorf("%w: effective_date %q must be YYYY-MM-DD", ErrInvalidEntry, date)
+	}
+	if d.After(now.UTC()) {
+		return fmt.Errorf("%w: effective_date %s is in the future", ErrInvalidEntry, date)
+	}
+	return nil
+}
+
+// validatePeriodRange checks that a period's bounds are DateLayout dates in order
+func validatePeriodRange(startsOn, endsOn string) error {
+	start, err := time.Parse(DateLayout, startsOn)
+	if err != nil {
+		return fmt.Errorf("%w: starts_on %q must be YYYY-MM-DD", ErrInvalidRequest, startsOn)
+	}
+	end, err := time.Parse(DateLayout, endsOn)
+	if err != nil {
+		return fmt.Errorf("%w: ends_on %q must be YYYY-MM-DD", ErrInvalidRequest, endsOn)
+	}
+	if end.Before(start) {
+		return fmt.Errorf("%w: ends_on must not be before starts_on", ErrInvalidRequest)
+	}
+	return nil
+}
+
+// planPeriodClose builds the legs that bring every revenue and expense account
+// to zero at the end of period and book the net into each currency's retained
+// earnings account. balances are in each account's normal direction; retained
+// maps currency to retained earnings account. No legs are returned when there
+// is nothing to roll.
+func planPeriodClose(period *AccountingPeriod, balances []PeriodClosingBalance, retained map[string]string, transactionID, closedBy string) ([]*JournalEntry, error) {
+	description := fmt.Sprintf("Close accounting period %s", period.Name)
+	var entries []*JournalEntry
+	leg := func(accountID, entryType string, amount money.Money) {
+		entries = append(entries, &JournalEntry{
+			EntryNumber:   fmt.Sprintf("JE-%s-%d", transactionID, len(entries)+1),
+			TransactionID: transactionID,
+			EntryType:     entryType,
+			AccountID:     accountID,
+			Amount:        amount,
+			Description:   description,
+			ReferenceType: ReferenceTypePeriodClose,
+			ReferenceID:   period.ID,
+			CurrencyCode:  amount.Currency(),
+			EffectiveDate: period.EndsOn,
+			CreatedBy:     closedBy,
+		})
+	}
+
+	// Net income per currency, in the retained earnings account's credit direction
+	net := map[string]money.Money{}
+	for _, b := range balances {
+		if b.Balance.IsZero() {
+			continue
+		}
+		var income money.Money
+		switch b.AccountType {
+		case "revenue":
+			income = b.Balance
+		case "expense":
+			income = b.Balance.Neg()
+		default:
+			continue
+		}
+
+		// A debit undoes revenue's credit balance and a credit undoes
+		// expense's debit balance; negative balances go the other way
+		entryType := "debit"
+		if income.IsNegative() {
+			entryType = "credit"
+		}
+		leg(b.AccountID, entryType, income.Abs())
+
+		currency := b.Balance.Currency()
+		sum, ok := net[currency]
+		if !ok {
+			sum = income
+		} else {
+			var err error
+			if sum, err = sum.Add(income); err != nil {
+				return nil, fmt.Errorf("failed to total %s income: %w", currency, err)
+			}
+		}
+		net[currency] = sum
+	}
+
+	currencies := make([]string, 0, len(net))
+	for currency := range net {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+	for _, currency := range currencies {
+		accountID, ok := retained[currency]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrRetainedEarningsNotConfigured, currency)
+		}
+		income := net[currency]
+		switch {
+		case income.IsPositive():
+			leg(accountID, "credit", income)
+		case income.IsNegative():
+			leg(accountID, "debit", income.Abs())
+		}
+	}
+
+	return entries, nil
+}
@@ -3,32 +3,51 @@ package ledger
 import (
     "context"
     "database/sql"
+    "encoding/json"
     "errors"
     "fmt"
+    "sort"
     "time"
 
+    "github.com/google/uuid"
     "github.com/jackc/pgx/v5"
     "github.com/jackc/pgx/v5/pgconn"
+    "github.com/jackc/pgx/v5/pgtype"
     "github.com/jackc/pgx/v5/pgxpool"
+
+    "github.com/example/pci-infra/internal/money"
 )
 
 // PostgreSQL connection and transaction management
 type PostgresLedger struct {
-    Pool *pgxpool.Pool
+    Pool DB
+}
+
+// DB is the part of a *pgxpool.Pool the ledger uses, so tests can stand in
+// for the database
+type DB interface {
+    Begin(ctx context.Context) (pgx.Tx, error)
+    BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error)
+    Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error)
+    Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+    QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+    Close()
 }
 
 // Account represents a ledger account
 type Account struct {
     ID             string  `json:"id"`
+    ParentID       string  `json:"parent_id,omitempty"`
     AccountNumber  string  `json:"account_number"`
     AccountType    string  `json:"account_type"`
     Name           string  `json:"name"`
     CurrencyCode   string  `json:"currency_code"`
     IsActive       bool    `json:"is_active"`
+    Status         string  `json:"status"`
     CreatedAt      string  `json:"created_at"`
     CreatedBy      string  `json:"created_by"`
     Metadata       map[string]interface{} `json:"metadata"`
-    CurrentBalance float64 `json:"current_balance"`
+    CurrentBalance money.Money `json:"current_balance"`
 }
 
 // JournalEntry represents a single debit/credit entry
@@ -39,11 +58,14 @@ type JournalEntry struct {
     EntryType     string  `json:"entry_type"`
     AccountID     string  `json:"account_id"`
     AccountType   string  `json:"account_type"`
-    Amount        float64 `json:"amount"`
+    Amount        money.Money `json:"amount"`
     Description   string  `json:"description"`
     ReferenceType string  `json:"reference_type"`
     ReferenceID   string  `json:"reference_id"`
     CurrencyCode  string  `json:"currency_code"`
+    // EffectiveDate is the day, in DateLayout, the entry takes effect in the
+    // books; empty means today (UTC)
+    EffectiveDate string  `json:"effective_date"`
     CreatedAt     string  `json:"created_at"`
     CreatedBy     string  `json:"created_by"`
     Metadata      map[string]interface{} `json:"metadata"`
@@ -55,14 +77,14 @@ type BalanceSnapshot struct {
     AccountID       string  `json:"account_id"`
     TransactionID   string  `json:"transaction_id"`
     SnapshotTime    string  `json:"snapshot_time"`
-    BalanceBefore   float64 `json:"balance_before"`
-    BalanceAfter    float64 `json:"balance_after"`
-    BalanceChange   float64 `json:"balance_change"`
+    BalanceBefore   money.Money `json:"balance_before"`
+    BalanceAfter    money.Money `json:"balance_after"`
+    BalanceChange   money.Money `json:"balance_change"`
     AccountType     string  `json:"account_type"`
     CurrencyCode    string  `json:"currency_code"`
     EntryID         string  `json:"entry_id"`
     EntryType       string  `json:"entry_type"`
-    Amount          float64 `json:"amount"`
+    Amount          money.Money `json:"amount"`
     Description     string  `json:"description"`
     ReferenceType   string  `json:"reference_type"`
     ReferenceID     string  `json:"reference_id"`
@@ -74,12 +96,19 @@ func NewPostgresLedger(pool *pgxpool.Pool) *PostgresLedger {
     return &PostgresLedger{Pool: pool}
 }
 
-// CreateAccount creates a new account with transaction safety
+// CreateAccount creates a new top-level account with transaction safety
 func (pl *PostgresLedger) CreateAccount(ctx context.Context, accountNumber, accountType, name, currencyCode, createdBy string, metadata map[string]interface{}) (*Account, error) {
+    return pl.CreateAccountUnder(ctx, "", accountNumber, accountType, name, currencyCode, createdBy, "", metadata)
+}
+
+// CreateAccountUnder creates a new account beneath parentID in the chart of
+// accounts. An empty parentID creates a top-level account. owner is the API
+// client the account belongs to, empty for platform accounts.
+func (pl *PostgresLedger) CreateAccountUnder(ctx context.Context, parentID, accountNumber, accountType, name, currencyCode, createdBy, owner string, metadata map[string]interface{}) (*Account, error) {
     const maxRetries = 3
     
     for attempt := 0; attempt < maxRetries; attempt++ {
-        err := pl.createAccountWithRetry(ctx, accountNumber, accountType, name, currencyCode, createdBy, metadata)
+        err := pl.createAccountWithRetry(ctx, parentID, accountNumber, accountType, name, currencyCode, createdBy, owner, metadata)
         if err != nil {
             var pgErr *pgconn.PgError
             if errors.As(err, &pgErr) && pgErr.Code == "40001" {
@@ -99,20 +128,14 @@ func (pl *PostgresLedger) CreateAccount(ctx context.Context, accountNumber, acco
 }
 
 // createAccountWithRetry handles the actual account creation with SERIALIZABLE isolation
-func (pl *PostgresLedger) createAccountWithRetry(ctx context.Context, accountNumber, accountType, name, currencyCode, createdBy string, metadata map[string]interface{}) error {
+func (pl *PostgresLedger) createAccountWithRetry(ctx context.Context, parentID, accountNumber, accountType, name, currencyCode, createdBy, owner string, metadata map[string]interface{}) error {
     // Set deadline for the operation
     queryCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
     defer cancel()
     
     // Use SERIALIZABLE isolation level for safety
-    conn, err := pl.Pool.Acquire(queryCtx)
-    if err != nil {
-        return fmt.Errorf("failed to acquire connection: %w", err)
-    }
-    defer conn.Release()
-    
     // Begin transaction with SERIALIZABLE isolation
-    tx, err := conn.BeginTx(queryCtx, pgx.TxOptions{
+    tx, err := pl.Pool.BeginTx(queryCtx, pgx.TxOptions{
         IsoLevel:   pgx.Serializable,
         AccessMode: pgx.ReadWrite,
     })
@@ -130,14 +153,47 @@ func (pl *PostgresLedger) createAccountWithRetry(ctx context.Context, accountNum
         return fmt.Errorf("failed to check account existence: %w", err)
     }
     if exists {
-        return fmt.Errorf("account number %s already exists", accountNumber)
+        return fmt.Errorf("%w: account number %s", ErrAccountExists, accountNumber)
+    }
+    
+    // Children must fit the parent's type, currency and numbering
+    if parentID != "" {
+        var parent Account
+        err = tx.QueryRow(queryCtx, `
+            SELECT id, account_number, account_type, currency_code, status
+            FROM accounts WHERE id = $1 FOR SHARE
+        `, parentID).Scan(&parent.ID, &parent.AccountNumber, &parent.AccountType, &parent.CurrencyCode, &parent.Status)
+        if err != nil {
+            if errors.Is(err, pgx.ErrNoRows) {
+                return fmt.Errorf("%w: parent %s", ErrAccountNotFound, parentID)
+            }
+            return fmt.Errorf("failed to get parent account: %w", err)
+        }
+        if err := checkChildAccount(&parent, CreateAccountRequest{
+            AccountNumber: accountNumber,
+            AccountType:   accountType,
+            CurrencyCode:  currencyCode,
+        }); err != nil {
+            return err
+        }
     }
     
     // Insert new account
-    _, err = tx.Exec(queryCtx, `
-        INSERT INTO accounts (account_number, account_type, name, currency_code, created_by, metadata)
-        VALUES ($1, $2, $3, $4, $5, $6)
-    `, accountNumber, accountType, name, currencyCode, createdBy, metadata)
+    account := &Account{
+        ParentID:      parentID,
+        AccountNumber: accountNumber,
+        AccountType:   accountType,
+        Name:          name,
+        CurrencyCode:  currencyCode,
+        CreatedBy:     createdBy,
+        Metadata:      metadata,
+    }
+    err = tx.QueryRow(queryCtx, `
+        INSERT INTO accounts (parent_id, account_number, account_type, name, currency_code, created_by, metadata, owner)
+        VALUES (NULLIF($1, '')::uuid, $2, $3, $4, $5, $6, $7, NULLIF($8, ''))
+        RETURNING id, is_active, status, created_at
+    `, parentID, accountNumber, accountType, name, currencyCode, createdBy, metadata, owner).Scan(
+        &account.ID, &account.IsActive, &account.Status, &account.CreatedAt)
     if err != nil {
         return fmt.Errorf("failed to insert account: %w", err)
     }
@@ -151,6 +207,10 @@ func (pl *PostgresLedger) createAccountWithRetry(ctx context.Context, accountNum
         return fmt.Errorf("failed to initialize account balance: %w", err)
     }
     
+    if err := appendAccountCreated(queryCtx, tx, account); err != nil {
+        return err
+    }
+    
     // Commit transaction
     err = tx.Commit(queryCtx)
     if err != nil {
@@ -167,15 +227,18 @@ func (pl *PostgresLedger) GetAccount(ctx context.Context, accountNumber string)
     
     var account Account
     var metadataJSON sql.NullString
+    var balance pgtype.Numeric
     
     err := pl.Pool.QueryRow(queryCtx, `
         SELECT 
             a.id,
+            COALESCE(a.parent_id::text, ''),
             a.account_number,
             a.account_type,
             a.name,
             a.currency_code,
             a.is_active,
+            a.status,
             a.created_at,
             a.created_by,
             a.metadata,
@@ -185,24 +248,31 @@ func (pl *PostgresLedger) GetAccount(ctx context.Context, accountNumber string)
         WHERE a.account_number = $1
     `, accountNumber).Scan(
         &account.ID,
+        &account.ParentID,
         &account.AccountNumber,
         &account.AccountType,
         &account.Name,
         &account.CurrencyCode,
         &account.IsActive,
+        &account.Status,
         &account.CreatedAt,
         &account.CreatedBy,
         &metadataJSON,
-        &account.CurrentBalance,
+        &balance,
     )
     
     if err != nil {
         if errors.Is(err, pgx.ErrNoRows) {
-            return nil, fmt.Errorf("account %s not found", accountNumber)
+            return nil, fmt.Errorf("%w: %s", ErrAccountNotFound, accountNumber)
         }
         return nil, fmt.Errorf("failed to get account: %w", err)
     }
     
+    account.CurrentBalance, err = money.FromNumeric(balance, account.CurrencyCode)
+    if err != nil {
+        return nil, fmt.Errorf("failed to read balance for account %s: %w", accountNumber, err)
+    }
+    
     // Parse metadata JSON
     if metadataJSON.Valid {
         account.Metadata = make(map[string]interface{})
@@ -242,14 +312,8 @@ func (pl *PostgresLedger) postJournalEntryWithRetry(ctx context.Context, entry *
     queryCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
     defer cancel()
     
-    conn, err := pl.Pool.Acquire(queryCtx)
-    if err != nil {
-        return fmt.Errorf("failed to acquire connection: %w", err)
-    }
-    defer conn.Release()
-    
     // Begin transaction with SERIALIZABLE isolation
-    tx, err := conn.BeginTx(queryCtx, pgx.TxOptions{
+    tx, err := pl.Pool.BeginTx(queryCtx, pgx.TxOptions{
         IsoLevel:   pgx.Serializable,
         AccessMode: pgx.ReadWrite,
     })
@@ -259,37 +323,58 @@ func (pl *PostgresLedger) postJournalEntryWithRetry(ctx context.Context, entry *
     defer tx.Rollback(queryCtx)
     
     // Lock the account for update
-    var accountType string
-    var accountActive bool
+    var accountType, accountStatus, accountCurrency string
     err = tx.QueryRow(queryCtx, `
-        SELECT account_type, is_active 
+        SELECT account_type, status, currency_code
         FROM accounts 
         WHERE id = $1 
         FOR UPDATE
-    `, entry.AccountID).Scan(&accountType, &accountActive)
+    `, entry.AccountID).Scan(&accountType, &accountStatus, &accountCurrency)
     
     if err != nil {
+        if errors.Is(err, pgx.ErrNoRows) {
+            return fmt.Errorf("%w: %s", ErrAccountNotFound, entry.AccountID)
+        }
         return fmt.Errorf("failed to lock account: %w", err)
     }
     
-    if !accountActive {
-        return fmt.Errorf("account %s is not active", entry.AccountID)
+    if err := checkPostable(entry.AccountID, accountStatus, entry.EntryType); err != nil {
+        return err
+    }
+    
+    if entry.Amount.Currency() != accountCurrency {
+        return fmt.Errorf("%w: entry currency %s does not match account %s currency %s", ErrCurrencyMismatch, entry.Amount.Currency(), entry.AccountID, accountCurrency)
+    }
+    
+    if err := checkEntryPeriods(queryCtx, tx, []*JournalEntry{entry}); err != nil {
+        return err
+    }
+    
+    entry.AccountType = accountType
+    if err := checkEntryLimits(queryCtx, tx, []*JournalEntry{entry}); err != nil {
+        return err
     }
     
     // Insert journal entry
-    _, err = tx.Exec(queryCtx, `
+    err = tx.QueryRow(queryCtx, `
         INSERT INTO journal_entries (
             entry_number, transaction_id, entry_type, account_id, account_type,
-            amount, description, reference_type, reference_id, currency_code, created_by, metadata
-        ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+            amount, description, reference_type, reference_id, currency_code, created_by, metadata,
+            effective_date
+        ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+        RETURNING id, created_at
     `, entry.EntryNumber, entry.TransactionID, entry.EntryType, entry.AccountID, accountType,
         entry.Amount, entry.Description, entry.ReferenceType, entry.ReferenceID, 
-        entry.CurrencyCode, entry.CreatedBy, entry.Metadata)
+        entry.Amount.Currency(), entry.CreatedBy, entry.Metadata, entry.EffectiveDate).Scan(&entry.ID, &entry.CreatedAt)
     
     if err != nil {
         return fmt.Errorf("failed to insert journal entry: %w", err)
     }
     
+    if err := appendTransactionPosted(queryCtx, tx, []*JournalEntry{entry}); err != nil {
+        return err
+    }
+    
     // Commit transaction
     err = tx.Commit(queryCtx)
     if err != nil {
@@ -299,94 +384,2775 @@ func (pl *PostgresLedger) postJournalEntryWithRetry(ctx context.Context, entry *
     return nil
 }
 
-// GetBalance retrieves the current balance for an account
-func (pl *PostgresLedger) GetBalance(ctx context.Context, accountID string) (float64, error) {
-    queryCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+// PostTransaction inserts all legs of a transaction atomically in one database transaction
+func (pl *PostgresLedger) PostTransaction(ctx context.Context, entries []*JournalEntry) error {
+    const maxRetries = 3
+
+    for attempt := 0; attempt < maxRetries; attempt++ {
+        err := pl.postTransactionWithRetry(ctx, entries)
+        if err != nil {
+            var pgErr *pgconn.PgError
+            if errors.As(err, &pgErr) && pgErr.Code == "40001" {
+                // Serialization failure, retry
+                if attempt == maxRetries-1 {
+                    return fmt.Errorf("failed to post transaction after %d retries due to serialization failure: %w", maxRetries, err)
+                }
+                time.Sleep(time.Duration(attempt+1) * 10 * time.Millisecond)
+                continue
+            }
+            return fmt.Errorf("failed to post transaction: %w", err)
+        }
+        break
+    }
+
+    return nil
+}
+
+// PostTransactionTx inserts all legs of a transaction within tx, a database
+// transaction the caller owns, so they commit or roll back with the caller's
+// own writes. The double-entry triggers run when the caller commits.
+func (pl *PostgresLedger) PostTransactionTx(ctx context.Context, tx pgx.Tx, entries []*JournalEntry) error {
+    if err := insertTransactionEntries(ctx, tx, entries); err != nil {
+        return fmt.Errorf("failed to post transaction: %w", err)
+    }
+    return nil
+}
+
+// postTransactionWithRetry handles the actual multi-leg posting with SERIALIZABLE isolation
+func (pl *PostgresLedger) postTransactionWithRetry(ctx context.Context, entries []*JournalEntry) error {
+    queryCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
     defer cancel()
-    
-    var balance sql.NullFloat64
-    err := pl.Pool.QueryRow(queryCtx, `
-        SELECT COALESCE(balance, 0)
-        FROM account_balances
-        WHERE account_id = $1
-    `, accountID).Scan(&balance)
-    
+
+    tx, err := pl.Pool.BeginTx(queryCtx, pgx.TxOptions{
+        IsoLevel:   pgx.Serializable,
+        AccessMode: pgx.ReadWrite,
+    })
+    if err != nil {
+        return fmt.Errorf("failed to begin transaction: %w", err)
+    }
+    defer tx.Rollback(queryCtx)
+
+    if err := insertTransactionEntries(queryCtx, tx, entries); err != nil {
+        return err
+    }
+
+    // Deferred double-entry triggers run here, against the complete set of legs
+    err = tx.Commit(queryCtx)
     if err != nil {
+        return fmt.Errorf("failed to commit transaction: %w", err)
+    }
+
+    return nil
+}
+
+// insertTransactionEntries locks and validates the accounts of one or more
+// transactions and inserts their legs, and a transaction.posted event for
+// each, within tx
+func insertTransactionEntries(ctx context.Context, tx pgx.Tx, entries []*JournalEntry) error {
+    accountIDs := make([]string, 0, len(entries))
+    for _, entry := range entries {
+        accountIDs = append(accountIDs, entry.AccountID)
+    }
+
+    // Lock every account touched by the transaction in a stable order to avoid deadlocks
+    rows, err := tx.Query(ctx, `
+        SELECT id, account_type, status, currency_code
+        FROM accounts
+        WHERE id = ANY($1::uuid[])
+        ORDER BY id
+        FOR UPDATE
+    `, accountIDs)
+    if err != nil {
+        return fmt.Errorf("failed to lock accounts: %w", err)
+    }
+
+    type lockedAccount struct {
+        accountType  string
+        status       string
+        currencyCode string
+    }
+    locked := make(map[string]lockedAccount, len(entries))
+    for rows.Next() {
+        var id string
+        var acct lockedAccount
+        if err := rows.Scan(&id, &acct.accountType, &acct.status, &acct.currencyCode); err != nil {
+            rows.Close()
+            return fmt.Errorf("failed to scan locked account: %w", err)
+        }
+        locked[id] = acct
+    }
+    rows.Close()
+    if err := rows.Err(); err != nil {
+        return fmt.Errorf("failed to lock accounts: %w", err)
+    }
+
+    for _, entry := range entries {
+        acct, ok := locked[entry.AccountID]
+        if !ok {
+            return fmt.Errorf("%w: %s", ErrAccountNotFound, entry.AccountID)
+        }
+        if err := checkPostable(entry.AccountID, acct.status, entry.EntryType); err != nil {
+            return err
+        }
+        if entry.Amount.Currency() != acct.currencyCode {
+            return fmt.Errorf("%w: entry currency %s does not match account %s currency %s", ErrCurrencyMismatch, entry.Amount.Currency(), entry.AccountID, acct.currencyCode)
+        }
+        entry.AccountType = acct.accountType
+    }
+
+    if err := checkEntryPeriods(ctx, tx, entries); err != nil {
+        return err
+    }
+
+    if err := checkEntryLimits(ctx, tx, entries); err != nil {
+        return err
+    }
+
+    if err := insertJournalEntries(ctx, tx, postingOrder(entries)); err != nil {
+        return err
+    }
+
+    return appendTransactionPosted(ctx, tx, entries)
+}
+
+// postingOrder orders the legs of each transaction, transactions in order of
+// first appearance, so balance increases come before decreases and the per-row
+// non-negative balance checks never see a transient dip within a transaction
+func postingOrder(entries []*JournalEntry) []*JournalEntry {
+    var order []string
+    byTransaction := make(map[string][]*JournalEntry)
+    for _, entry := range entries {
+        if _, ok := byTransaction[entry.TransactionID]; !ok {
+            order = append(order, entry.TransactionID)
+        }
+        byTransaction[entry.TransactionID] = append(byTransaction[entry.TransactionID], entry)
+    }
+
+    ordered := make([]*JournalEntry, 0, len(entries))
+    for _, transactionID := range order {
+        legs := byTransaction[transactionID]
+        for _, entry := range legs {
+            if increasesBalance(entry.AccountType, entry.EntryType) {
+                ordered = append(ordered, entry)
+            }
+        }
+        for _, entry := range legs {
+            if !increasesBalance(entry.AccountType, entry.EntryType) {
+                ordered = append(ordered, entry)
+            }
+        }
+    }
+    return ordered
+}
+
+// insertJournalEntries inserts entries in order in one round trip, filling in
+// their IDs and creation times
+func insertJournalEntries(ctx context.Context, tx pgx.Tx, entries []*JournalEntry) error {
+    batch := &pgx.Batch{}
+    for _, entry := range entries {
+        batch.Queue(`
+            INSERT INTO journal_entries (
+                entry_number, transaction_id, entry_type, account_id, account_type,
+                amount, description, reference_type, reference_id, currency_code, created_by, metadata,
+                effective_date
+            ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+            RETURNING id, created_at
+        `, entry.EntryNumber, entry.TransactionID, entry.EntryType, entry.AccountID, entry.AccountType,
+            entry.Amount, entry.Description, entry.ReferenceType, entry.ReferenceID,
+            entry.Amount.Currency(), entry.CreatedBy, entry.Metadata, entry.EffectiveDate)
+    }
+
+    results := tx.SendBatch(ctx, batch)
+    for _, entry := range entries {
+        if err := results.QueryRow().Scan(&entry.ID, &entry.CreatedAt); err != nil {
+            results.Close()
+            return fmt.Errorf("failed to insert journal entry %s: %w", entry.EntryNumber, err)
+        }
+    }
+    if err := results.Close(); err != nil {
+        return fmt.Errorf("failed to insert journal entries: %w", err)
+    }
+    return nil
+}
+
+// increasesBalance reports whether an entry raises the balance of an account of the given type
+func increasesBalance(accountType, entryType string) bool {
+    switch accountType {
+    case "asset", "expense":
+        return entryType == "debit"
+    default:
+        return entryType == "credit"
+    }
+}
+
+// checkEntryPeriods defaults each entry's effective date to today (UTC) and
+// rejects entries that take effect in a closed accounting period. The periods
+// are locked FOR SHARE so a close cannot interleave with the posting.
+func checkEntryPeriods(ctx context.Context, tx pgx.Tx, entries []*JournalEntry) error {
+    today := time.Now().UTC().Format(DateLayout)
+    var dates []string
+    seen := make(map[string]bool, len(entries))
+    for _, entry := range entries {
+        if entry.EffectiveDate == "" {
+            entry.EffectiveDate = today
+        }
+        if _, err := time.Parse(DateLayout, entry.EffectiveDate); err != nil {
+            return fmt.Errorf("%w: effective date %q must be YYYY-MM-DD", ErrInvalidEntry, entry.EffectiveDate)
+        }
+        if !seen[entry.EffectiveDate] {
+            seen[entry.EffectiveDate] = true
+            dates = append(dates, entry.EffectiveDate)
+        }
+    }
+    sort.Strings(dates)
+
+    allowSoftClosed := softClosedAllowed(ctx)
+    adjusting := false
+    for _, date := range dates {
+        var name, status string
+        err := tx.QueryRow(ctx, `
+            SELECT name, status
+            FROM accounting_periods
+            WHERE $1::date BETWEEN starts_on AND ends_on
+            FOR SHARE
+        `, date).Scan(&name, &status)
         if errors.Is(err, pgx.ErrNoRows) {
-            return 0, fmt.Errorf("account %s not found", accountID)
+            continue
+        }
+        if err != nil {
+            return fmt.Errorf("failed to look up accounting period: %w", err)
+        }
+        if err := checkPeriodPostable(name, status, date, allowSoftClosed); err != nil {
+            return err
         }
-        return 0, fmt.Errorf("failed to get balance: %w", err)
+        adjusting = adjusting || status == PeriodStatusSoftClosed
     }
-    
-    return balance.Float64, nil
+
+    if adjusting {
+        // Lets the database backstop accept the adjustment for the rest of this transaction
+        if _, err := tx.Exec(ctx, `SELECT set_config('ledger.allow_soft_closed', 'on', true)`); err != nil {
+            return fmt.Errorf("failed to allow soft-closed adjustment: %w", err)
+        }
+    }
+
+    return nil
 }
 
-// ReconcileTransactions performs reconciliation and drift detection
-func (pl *PostgresLedger) ReconcileTransactions(ctx context.Context, accountID string, startTime, endTime time.Time) ([]BalanceSnapshot, error) {
-    queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
-    defer cancel()
-    
-    rows, err := pl.Pool.Query(queryCtx, `
-        SELECT 
-            bs.id, bs.account_id, bs.transaction_id, bs.snapshot_time,
-            bs.balance_before, bs.balance_after, bs.balance_change,
-            bs.account_type, bs.currency_code, bs.entry_id, bs.entry_type,
-            bs.amount, bs.description, bs.reference_type, bs.reference_id, bs.created_at
-        FROM balance_snapshots bs
-        WHERE bs.account_id = $1
-        AND bs.snapshot_time >= $2
-        AND bs.snapshot_time <= $3
-        ORDER BY bs.snapshot_time ASC
-    `, accountID, startTime, endTime)
-    
+// checkEntryLimits rejects entries that would take an account past its
+// overdraft or transaction limit. The accounts must already be locked.
+func checkEntryLimits(ctx context.Context, tx pgx.Tx, entries []*JournalEntry) error {
+    changes, err := balanceChanges(entries)
     if err != nil {
-        return nil, fmt.Errorf("failed to query balance snapshots: %w", err)
+        return err
+    }
+
+    var reduced []string
+    seen := make(map[string]bool, len(changes))
+    for _, c := range changes {
+        if c.amount.IsNegative() && !seen[c.accountID] {
+            seen[c.accountID] = true
+            reduced = append(reduced, c.accountID)
+        }
+    }
+    if len(reduced) == 0 {
+        return nil
+    }
+
+    accounts, err := creditLimitsFor(ctx, tx, reduced)
+    if err != nil {
+        return err
+    }
+    return checkCreditLimits(changes, accounts)
+}
+
+// creditLimitsFor reads the balance, the amount reserved by pending
+// transactions and unposted holds, and the limits in force of each account
+func creditLimitsFor(ctx context.Context, q querier, accountIDs []string) (map[string]*limitedAccount, error) {
+    rows, err := q.Query(ctx, `
+        SELECT a.id, a.currency_code, COALESCE(ab.balance, 0),
+            get_pending_amount(a.id) + get_held_amount(a.id),
+            get_overdraft_limit(a.id, CURRENT_TIMESTAMP AT TIME ZONE 'UTC'),
+            get_transaction_limit(a.id, CURRENT_TIMESTAMP AT TIME ZONE 'UTC')
+        FROM accounts a
+        LEFT JOIN account_balances ab ON ab.account_id = a.id
+        WHERE a.id = ANY($1::uuid[])
+    `, accountIDs)
+    if err != nil {
+        return nil, fmt.Errorf("failed to read credit limits: %w", err)
     }
     defer rows.Close()
-    
-    var snapshots []BalanceSnapshot
+
+    accounts := make(map[string]*limitedAccount, len(accountIDs))
     for rows.Next() {
-        var snapshot BalanceSnapshot
-        err := rows.Scan(
-            &snapshot.ID, &snapshot.AccountID, &snapshot.TransactionID, &snapshot.SnapshotTime,
-            &snapshot.BalanceBefore, &snapshot.BalanceAfter, &snapshot.BalanceChange,
-            &snapshot.AccountType, &snapshot.CurrencyCode, &snapshot.EntryID, &snapshot.EntryType,
-            &snapshot.Amount, &snapshot.Description, &snapshot.ReferenceType, &snapshot.ReferenceID, &snapshot.CreatedAt,
-        )
-        if err != nil {
-            return nil, fmt.Errorf("failed to scan balance snapshot: %w", err)
+        var id, currencyCode string
+        var balance, reserved, overdraft, maxTransaction pgtype.Numeric
+        if err := rows.Scan(&id, &currencyCode, &balance, &reserved, &overdraft, &maxTransaction); err != nil {
+            return nil, fmt.Errorf("failed to scan credit limits: %w", err)
         }
-        snapshots = append(snapshots, snapshot)
+
+        acct := &limitedAccount{noFloor: !overdraft.Valid}
+        if acct.balance, err = money.FromNumeric(balance, currencyCode); err != nil {
+            return nil, fmt.Errorf("failed to read balance of account %s: %w", id, err)
+        }
+        if acct.reserved, err = money.FromNumeric(reserved, currencyCode); err != nil {
+            return nil, fmt.Errorf("failed to read reserved amount of account %s: %w", id, err)
+        }
+        acct.overdraftLimit, _ = money.Zero(currencyCode)
+        if overdraft.Valid {
+            if acct.overdraftLimit, err = money.FromNumeric(overdraft, currencyCode); err != nil {
+                return nil, fmt.Errorf("failed to read overdraft limit of account %s: %w", id, err)
+            }
+        }
+        if maxTransaction.Valid {
+            maxAmount, err := money.FromNumeric(maxTransaction, currencyCode)
+            if err != nil {
+                return nil, fmt.Errorf("failed to read transaction limit of account %s: %w", id, err)
+            }
+            acct.maxTransaction = &maxAmount
+        }
+        accounts[id] = acct
     }
-    
-    return snapshots, nil
+    if err := rows.Err(); err != nil {
+        return nil, fmt.Errorf("failed to read credit limits: %w", err)
+    }
+    return accounts, nil
 }
 
-// ValidateBalanceConsistency checks for reconciliation drift
-func (pl *PostgresLedger) ValidateBalanceConsistency(ctx context.Context) ([]map[string]interface{}, error) {
-    queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
-    defer cancel()
-    
-    rows, err := pl.Pool.Query(queryCtx, `
-        SELECT 
-            account_id, account_number, expected_balance, actual_balance, is_consistent
-        FROM validate_balance_consistency()
-    `)
+// pendingColumns are the pending_transactions columns read by scanPendingTransaction
+const pendingColumns = `
+    id, transaction_id, from_account_id, to_account_id, amount, captured_amount,
+    currency_code, status, description, COALESCE(reference_type, ''), COALESCE(reference_id, ''),
+    metadata, expires_at, created_at, created_by, resolved_at, resolved_by`
+
+// extraColumnsRow is a row whose columns beyond those its scanner reads are
+// scanned into extra
+type extraColumnsRow struct {
+    pgx.Row
+    extra []interface{}
+}
+
+func (r extraColumnsRow) Scan(dest ...interface{}) error {
+    return r.Row.Scan(append(dest, r.extra...)...)
+}
+
+// withColumns lets a scanner such as scanPendingTransaction read a row that
+// selects extra trailing columns, scanning those into extra
+func withColumns(row pgx.Row, extra ...interface{}) pgx.Row {
+    return extraColumnsRow{Row: row, extra: extra}
+}
+
+func scanPendingTransaction(row pgx.Row) (*PendingTransaction, error) {
+    var p PendingTransaction
+    var amount, captured pgtype.Numeric
+    var currencyCode string
+    var metadataJSON []byte
+    var resolvedAt, resolvedBy *string
     
+    err := row.Scan(
+        &p.ID, &p.TransactionID, &p.FromAccountID, &p.ToAccountID, &amount, &captured,
+        &currencyCode, &p.Status, &p.Description, &p.ReferenceType, &p.ReferenceID,
+        &metadataJSON, &p.ExpiresAt, &p.CreatedAt, &p.CreatedBy, &resolvedAt, &resolvedBy,
+    )
     if err != nil {
-        return nil, fmt.Errorf("failed to validate balance consistency: %w", err)
+        return nil, err
     }
-    defer rows.Close()
     
-    var results []map[string]interface{}
-    for rows.Next() {
-        var accountID, accountNumber string
-        var expected, actual float64
-        var isConsistent bool
-        
-        err := rows.Scan(&accountID, &accountNumber, &expected, &actual, &isConsistent)
+    if p.Amount, err = money.FromNumeric(amount, currencyCode); err != nil {
+        return nil, fmt.Errorf("failed to read pending transaction %s: %w", p.ID, err)
+    }
+    if captured.Valid {
+        c, err := money.FromNumeric(captured, currencyCode)
         if err != nil {
-            return nil, fmt.Errorf("failed to scan consistency result: %w", err)
+            return nil, fmt.Errorf("failed to read pending transaction %s: %w", p.ID, err)
+        }
+        p.CapturedAmount = &c
+    }
+    if len(metadataJSON) > 0 {
+        if err := json.Unmarshal(metadataJSON, &p.Metadata); err != nil {
+            return nil, fmt.Errorf("failed to read pending transaction %s metadata: %w", p.ID, err)
+        }
+    }
+    if resolvedAt != nil {
+        p.ResolvedAt = *resolvedAt
+    }
+    if resolvedBy != nil {
+        p.ResolvedBy = *resolvedBy
+    }
+    
+    return &p, nil
+}
+
+// AuthorizePendingTransaction reserves p.Amount on p.FromAccountID for ttl.
+// p.ID, ExpiresAt, CreatedAt and Status are set from the inserted row.
+func (pl *PostgresLedger) AuthorizePendingTransaction(ctx context.Context, p *PendingTransaction, ttl time.Duration) error {
+    const maxRetries = 3
+
+    for attempt := 0; attempt < maxRetries; attempt++ {
+        err := pl.authorizePendingWithRetry(ctx, p, ttl)
+        if err != nil {
+            var pgErr *pgconn.PgError
+            if errors.As(err, &pgErr) && pgErr.Code == "40001" {
+                // Serialization failure, retry
+                if attempt == maxRetries-1 {
+                    return fmt.Errorf("failed to authorize after %d retries due to serialization failure: %w", maxRetries, err)
+                }
+                time.Sleep(time.Duration(attempt+1) * 10 * time.Millisecond)
+                continue
+            }
+            return fmt.Errorf("failed to authorize: %w", err)
+        }
+        break
+    }
+
+    return nil
+}
+
+func (pl *PostgresLedger) authorizePendingWithRetry(ctx context.Context, p *PendingTransaction, ttl time.Duration) error {
+    queryCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+    defer cancel()
+
+    tx, err := pl.Pool.BeginTx(queryCtx, pgx.TxOptions{
+        IsoLevel:   pgx.Serializable,
+        AccessMode: pgx.ReadWrite,
+    })
+    if err != nil {
+        return fmt.Errorf("failed to begin transaction: %w", err)
+    }
+    defer tx.Rollback(queryCtx)
+
+    // Lock both accounts so concurrent authorizations against the same funds serialize
+    rows, err := tx.Query(queryCtx, `
+        SELECT id, status, currency_code
+        FROM accounts
+        WHERE id = ANY($1::uuid[])
+        ORDER BY id
+        FOR UPDATE
+    `, []string{p.FromAccountID, p.ToAccountID})
+    if err != nil {
+        return fmt.Errorf("failed to lock accounts: %w", err)
+    }
+    found := 0
+    for rows.Next() {
+        var id, accountStatus, currencyCode string
+        if err := rows.Scan(&id, &accountStatus, &currencyCode); err != nil {
+            rows.Close()
+            return fmt.Errorf("failed to scan locked account: %w", err)
+        }
+        found++
+        // The from account will be debited on capture and the to account credited
+        entryType := "credit"
+        if id == p.FromAccountID {
+            entryType = "debit"
+        }
+        if err := checkPostable(id, accountStatus, entryType); err != nil {
+            rows.Close()
+            return err
+        }
+        if currencyCode != p.Amount.Currency() {
+            rows.Close()
+            return fmt.Errorf("%w: amount %s vs account %s %s", ErrCurrencyMismatch, p.Amount.Currency(), id, currencyCode)
+        }
+    }
+    rows.Close()
+    if err := rows.Err(); err != nil {
+        return fmt.Errorf("failed to lock accounts: %w", err)
+    }
+    if found != 2 {
+        return fmt.Errorf("%w: %s or %s", ErrAccountNotFound, p.FromAccountID, p.ToAccountID)
+    }
+
+    var available bool
+    err = tx.QueryRow(queryCtx, `SELECT check_available_balance($1, $2)`, p.FromAccountID, p.Amount).Scan(&available)
+    if err != nil {
+        return fmt.Errorf("failed to check available balance: %w", err)
+    }
+    if !available {
+        return fmt.Errorf("%w: account %s cannot reserve %s", ErrInsufficientFunds, p.FromAccountID, p.Amount)
+    }
+
+    err = tx.QueryRow(queryCtx, `
+        INSERT INTO pending_transactions (
+            transaction_id, from_account_id, to_account_id, amount, currency_code,
+            description, reference_type, reference_id, metadata, expires_at, created_by
+        ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, CURRENT_TIMESTAMP + $10 * INTERVAL '1 second', $11)
+        RETURNING id, status, expires_at, created_at
+    `, p.TransactionID, p.FromAccountID, p.ToAccountID, p.Amount, p.Amount.Currency(),
+        p.Description, p.ReferenceType, p.ReferenceID, p.Metadata, ttl.Seconds(), p.CreatedBy,
+    ).Scan(&p.ID, &p.Status, &p.ExpiresAt, &p.CreatedAt)
+    if err != nil {
+        return fmt.Errorf("failed to insert pending transaction: %w", err)
+    }
+
+    err = tx.Commit(queryCtx)
+    if err != nil {
+        return fmt.Errorf("failed to commit transaction: %w", err)
+    }
+
+    return nil
+}
+
+// GetPendingTransaction retrieves a pending transaction by ID
+func (pl *PostgresLedger) GetPendingTransaction(ctx context.Context, id string) (*PendingTransaction, error) {
+    queryCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+    defer cancel()
+
+    p, err := scanPendingTransaction(pl.Pool.QueryRow(queryCtx, `
+        SELECT `+pendingColumns+`
+        FROM pending_transactions
+        WHERE id = $1
+    `, id))
+    if err != nil {
+        if errors.Is(err, pgx.ErrNoRows) {
+            return nil, fmt.Errorf("%w: %s", ErrPendingNotFound, id)
+        }
+        return nil, fmt.Errorf("failed to get pending transaction: %w", err)
+    }
+
+    return p, nil
+}
+
+// CapturePendingTransaction posts a pending transaction, fully or partially,
+// and releases its reservation in the same database transaction
+func (pl *PostgresLedger) CapturePendingTransaction(ctx context.Context, req CaptureRequest) (*PendingTransaction, error) {
+    const maxRetries = 3
+
+    var p *PendingTransaction
+    for attempt := 0; attempt < maxRetries; attempt++ {
+        var err error
+        p, err = pl.captureWithRetry(ctx, req)
+        if err != nil {
+            var pgErr *pgconn.PgError
+            if errors.As(err, &pgErr) && pgErr.Code == "40001" {
+                // Serialization failure, retry
+                if attempt == maxRetries-1 {
+                    return nil, fmt.Errorf("failed to capture after %d retries due to serialization failure: %w", maxRetries, err)
+                }
+                time.Sleep(time.Duration(attempt+1) * 10 * time.Millisecond)
+                continue
+            }
+            return nil, fmt.Errorf("failed to capture: %w", err)
+        }
+        break
+    }
+
+    return p, nil
+}
+
+func (pl *PostgresLedger) captureWithRetry(ctx context.Context, req CaptureRequest) (*PendingTransaction, error) {
+    queryCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+    defer cancel()
+
+    tx, err := pl.Pool.BeginTx(queryCtx, pgx.TxOptions{
+        IsoLevel:   pgx.Serializable,
+        AccessMode: pgx.ReadWrite,
+    })
+    if err != nil {
+        return nil, fmt.Errorf("failed to begin transaction: %w", err)
+    }
+    defer tx.Rollback(queryCtx)
+
+    // Expiry is read with the row lock, so the sweeper cannot expire the
+    // pending transaction between the check and the capture
+    var expired bool
+    p, err := scanPendingTransaction(withColumns(tx.QueryRow(queryCtx, `
+        SELECT `+pendingColumns+`, expires_at <= CURRENT_TIMESTAMP
+        FROM pending_transactions
+        WHERE id = $1
+        FOR UPDATE
+    `, req.PendingID), &expired))
+    if err != nil {
+        if errors.Is(err, pgx.ErrNoRows) {
+            return nil, fmt.Errorf("%w: %s", ErrPendingNotFound, req.PendingID)
+        }
+        return nil, fmt.Errorf("failed to lock pending transaction: %w", err)
+    }
+    if p.Status != PendingStatusPending {
+        return nil, fmt.Errorf("%w: %s is %s", ErrPendingResolved, p.ID, p.Status)
+    }
+    if expired {
+        return nil, fmt.Errorf("%w: %s", ErrPendingExpired, p.ID)
+    }
+
+    amount := req.Amount
+    if amount.IsZero() {
+        amount = p.Amount
+    }
+    if amount.Currency() != p.Amount.Currency() {
+        return nil, fmt.Errorf("%w: capture %s vs authorized %s", ErrCurrencyMismatch, amount.Currency(), p.Amount.Currency())
+    }
+    if p.Amount.LessThan(amount) {
+        return nil, fmt.Errorf("%w: capture amount %s exceeds authorized %s", ErrInvalidRequest, amount, p.Amount)
+    }
+
+    // Marking it posted first stops the pending transaction reserving the
+    // funds its own capture is checked against
+    var resolvedAt string
+    err = tx.QueryRow(queryCtx, `
+        UPDATE pending_transactions
+        SET status = 'posted', captured_amount = $2, resolved_at = CURRENT_TIMESTAMP, resolved_by = $3
+        WHERE id = $1
+        RETURNING resolved_at
+    `, p.ID, amount, req.CapturedBy).Scan(&resolvedAt)
+    if err != nil {
+        return nil, fmt.Errorf("failed to mark pending transaction posted: %w", err)
+    }
+
+    if err := insertTransactionEntries(queryCtx, tx, captureEntries(p, amount, req.CapturedBy)); err != nil {
+        return nil, err
+    }
+
+    err = tx.Commit(queryCtx)
+    if err != nil {
+        return nil, fmt.Errorf("failed to commit transaction: %w", err)
+    }
+
+    p.Status = PendingStatusPosted
+    p.CapturedAmount = &amount
+    p.ResolvedAt = resolvedAt
+    p.ResolvedBy = req.CapturedBy
+    return p, nil
+}
+
+// VoidPendingTransaction releases a pending transaction without posting it
+func (pl *PostgresLedger) VoidPendingTransaction(ctx context.Context, req VoidRequest) (*PendingTransaction, error) {
+    queryCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+    defer cancel()
+
+    p, err := scanPendingTransaction(pl.Pool.QueryRow(queryCtx, `
+        UPDATE pending_transactions
+        SET status = 'voided', resolved_at = CURRENT_TIMESTAMP, resolved_by = $2
+        WHERE id = $1 AND status = 'pending' AND expires_at > CURRENT_TIMESTAMP
+        RETURNING `+pendingColumns, req.PendingID, req.VoidedBy))
+    if err == nil {
+        return p, nil
+    }
+    if !errors.Is(err, pgx.ErrNoRows) {
+        return nil, fmt.Errorf("failed to void pending transaction: %w", err)
+    }
+
+    // Nothing was updated; report why
+    existing, err := pl.GetPendingTransaction(ctx, req.PendingID)
+    if err != nil {
+        return nil, err
+    }
+    if existing.Status != PendingStatusPending {
+        return nil, fmt.Errorf("%w: %s is %s", ErrPendingResolved, existing.ID, existing.Status)
+    }
+    return nil, fmt.Errorf("%w: %s", ErrPendingExpired, existing.ID)
+}
+
+// ExpirePendingTransactions marks pending transactions past their expiry as
+// expired and returns how many were changed. Expired rows stop reserving funds
+// as soon as expires_at passes; this only records the outcome.
+func (pl *PostgresLedger) ExpirePendingTransactions(ctx context.Context) (int64, error) {
+    queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+    defer cancel()
+
+    tag, err := pl.Pool.Exec(queryCtx, `
+        UPDATE pending_transactions
+        SET status = 'expired', resolved_at = CURRENT_TIMESTAMP, resolved_by = 'system'
+        WHERE status = 'pending' AND expires_at <= CURRENT_TIMESTAMP
+    `)
+    if err != nil {
+        return 0, fmt.Errorf("failed to expire pending transactions: %w", err)
+    }
+
+    return tag.RowsAffected(), nil
+}
+
+// GetAccountBalances retrieves the posted, pending, held and available balances of an account
+func (pl *PostgresLedger) GetAccountBalances(ctx context.Context, accountID string) (*AccountBalances, error) {
+    queryCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+    defer cancel()
+
+    var posted, pending, held, overdraft pgtype.Numeric
+    balances := &AccountBalances{AccountID: accountID}
+    err := pl.Pool.QueryRow(queryCtx, `
+        SELECT COALESCE(ab.balance, 0), get_pending_amount(a.id), get_held_amount(a.id),
+            COALESCE(get_overdraft_limit(a.id, CURRENT_TIMESTAMP AT TIME ZONE 'UTC'), 0), a.currency_code
+        FROM accounts a
+        LEFT JOIN account_balances ab ON ab.account_id = a.id
+        WHERE a.id = $1
+    `, accountID).Scan(&posted, &pending, &held, &overdraft, &balances.CurrencyCode)
+    if err != nil {
+        if errors.Is(err, pgx.ErrNoRows) {
+            return nil, fmt.Errorf("%w: %s", ErrAccountNotFound, accountID)
+        }
+        return nil, fmt.Errorf("failed to get account balances: %w", err)
+    }
+
+    for _, f := range []struct {
+        dst *money.Money
+        src pgtype.Numeric
+    }{
+        {&balances.Posted, posted},
+        {&balances.Pending, pending},
+        {&balances.Held, held},
+        {&balances.OverdraftLimit, overdraft},
+    } {
+        if *f.dst, err = money.FromNumeric(f.src, balances.CurrencyCode); err != nil {
+            return nil, fmt.Errorf("failed to read balances for account %s: %w", accountID, err)
+        }
+    }
+
+    available, err := balances.Posted.Sub(balances.Pending)
+    if err == nil {
+        available, err = available.Sub(balances.Held)
+    }
+    if err == nil {
+        available, err = available.Add(balances.OverdraftLimit)
+    }
+    if err != nil {
+        return nil, fmt.Errorf("failed to compute available balance for account %s: %w", accountID, err)
+    }
+    balances.Available = available
+
+    return balances, nil
+}
+
+// querier is satisfied by both the pool and an open transaction
+type querier interface {
+    Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+    QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+// journalEntryColumns are the journal_entries columns read by queryJournalEntries
+const journalEntryColumns = `
+    id, entry_number, transaction_id, entry_type, account_id, account_type, amount,
+    description, COALESCE(reference_type, ''), COALESCE(reference_id, ''), currency_code,
+    effective_date::text, created_at, created_by, metadata`
+
+// queryJournalEntries runs a journal_entries query selecting journalEntryColumns
+func queryJournalEntries(ctx context.Context, q querier, query string, args ...interface{}) ([]JournalEntry, error) {
+    rows, err := q.Query(ctx, query, args...)
+    if err != nil {
+        return nil, fmt.Errorf("failed to query journal entries: %w", err)
+    }
+    defer rows.Close()
+
+    var entries []JournalEntry
+    for rows.Next() {
+        var e JournalEntry
+        var amount pgtype.Numeric
+        var metadataJSON []byte
+        err := rows.Scan(
+            &e.ID, &e.EntryNumber, &e.TransactionID, &e.EntryType, &e.AccountID, &e.AccountType, &amount,
+            &e.Description, &e.ReferenceType, &e.ReferenceID, &e.CurrencyCode,
+            &e.EffectiveDate, &e.CreatedAt, &e.CreatedBy, &metadataJSON,
+        )
+        if err != nil {
+            return nil, fmt.Errorf("failed to scan journal entry: %w", err)
+        }
+
+        if e.Amount, err = money.FromNumeric(amount, e.CurrencyCode); err != nil {
+            return nil, fmt.Errorf("failed to read journal entry %s: %w", e.ID, err)
+        }
+        if len(metadataJSON) > 0 {
+            if err := json.Unmarshal(metadataJSON, &e.Metadata); err != nil {
+                return nil, fmt.Errorf("failed to read journal entry %s metadata: %w", e.ID, err)
+            }
+        }
+        entries = append(entries, e)
+    }
+    if err := rows.Err(); err != nil {
+        return nil, fmt.Errorf("failed to read journal entries: %w", err)
+    }
+
+    return entries, nil
+}
+
+// queryReversalOf returns the transaction that transactionID reverses, or ""
+// when transactionID is not a reversal. Reversal status comes from
+// transaction_reversals; the reference type on journal entries is client
+// settable and so cannot be trusted for this.
+func queryReversalOf(ctx context.Context, q querier, transactionID string) (string, error) {
+    var originalID string
+    err := q.QueryRow(ctx, `
+        SELECT original_transaction_id
+        FROM transaction_reversals
+        WHERE transaction_id = $1
+    `, transactionID).Scan(&originalID)
+    if errors.Is(err, pgx.ErrNoRows) {
+        return "", nil
+    }
+    if err != nil {
+        return "", fmt.Errorf("failed to query reversal: %w", err)
+    }
+    return originalID, nil
+}
+
+// queryReversals lists the reversals posted against a transaction, oldest first
+func queryReversals(ctx context.Context, q querier, originalID string) ([]Reversal, error) {
+    rows, err := q.Query(ctx, `
+        SELECT id, transaction_id, original_transaction_id, is_full, amount, currency_code,
+            reason, reversed_by, approved_by, created_at
+        FROM transaction_reversals
+        WHERE original_transaction_id = $1
+        ORDER BY created_at ASC, id ASC
+    `, originalID)
+    if err != nil {
+        return nil, fmt.Errorf("failed to query reversals: %w", err)
+    }
+    defer rows.Close()
+
+    var reversals []Reversal
+    for rows.Next() {
+        var r Reversal
+        var amount pgtype.Numeric
+        var currencyCode *string
+        err := rows.Scan(
+            &r.ID, &r.TransactionID, &r.OriginalTransactionID, &r.Full, &amount, &currencyCode,
+            &r.Reason, &r.ReversedBy, &r.ApprovedBy, &r.CreatedAt,
+        )
+        if err != nil {
+            return nil, fmt.Errorf("failed to scan reversal: %w", err)
+        }
+
+        if amount.Valid && currencyCode != nil {
+            a, err := money.FromNumeric(amount, *currencyCode)
+            if err != nil {
+                return nil, fmt.Errorf("failed to read reversal %s: %w", r.ID, err)
+            }
+            r.Amount = &a
+        }
+        reversals = append(reversals, r)
+    }
+    if err := rows.Err(); err != nil {
+        return nil, fmt.Errorf("failed to read reversals: %w", err)
+    }
+
+    return reversals, nil
+}
+
+// ReverseTransaction posts the mirror image of a transaction, or of part of it,
+// and records the reversal in the same database transaction
+func (pl *PostgresLedger) ReverseTransaction(ctx context.Context, req ReverseRequest) (*Reversal, error) {
+    const maxRetries = 3
+
+    var reversal *Reversal
+    for attempt := 0; attempt < maxRetries; attempt++ {
+        var err error
+        reversal, err = pl.reverseWithRetry(ctx, req)
+        if err != nil {
+            var pgErr *pgconn.PgError
+            if errors.As(err, &pgErr) && pgErr.Code == "40001" {
+                // Serialization failure, retry
+                if attempt == maxRetries-1 {
+                    return nil, fmt.Errorf("failed to reverse after %d retries due to serialization failure: %w", maxRetries, err)
+                }
+                time.Sleep(time.Duration(attempt+1) * 10 * time.Millisecond)
+                continue
+            }
+            return nil, fmt.Errorf("failed to reverse: %w", err)
+        }
+        break
+    }
+
+    return reversal, nil
+}
+
+func (pl *PostgresLedger) reverseWithRetry(ctx context.Context, req ReverseRequest) (*Reversal, error) {
+    queryCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+    defer cancel()
+
+    tx, err := pl.Pool.BeginTx(queryCtx, pgx.TxOptions{
+        IsoLevel:   pgx.Serializable,
+        AccessMode: pgx.ReadWrite,
+    })
+    if err != nil {
+        return nil, fmt.Errorf("failed to begin transaction: %w", err)
+    }
+    defer tx.Rollback(queryCtx)
+
+    original, err := queryJournalEntries(queryCtx, tx, `
+        SELECT `+journalEntryColumns+`
+        FROM journal_entries
+        WHERE transaction_id = $1
+        ORDER BY entry_number ASC
+    `, req.TransactionID)
+    if err != nil {
+        return nil, err
+    }
+
+    reversalOf, err := queryReversalOf(queryCtx, tx, req.TransactionID)
+    if err != nil {
+        return nil, err
+    }
+
+    // Concurrent reversals of the same transaction both read this set; SERIALIZABLE
+    // isolation aborts one of them so partial reversals cannot overshoot
+    prior, err := queryReversals(queryCtx, tx, req.TransactionID)
+    if err != nil {
+        return nil, err
+    }
+
+    plan, err := planReversal(original, reversalOf, prior, req)
+    if err != nil {
+        return nil, err
+    }
+
+    if err := insertTransactionEntries(queryCtx, tx, plan.entries); err != nil {
+        return nil, err
+    }
+
+    var currencyCode *string
+    if plan.amount != nil {
+        c := plan.amount.Currency()
+        currencyCode = &c
+    }
+
+    reversal := &Reversal{
+        TransactionID:         req.ReversalID,
+        OriginalTransactionID: req.TransactionID,
+        Full:                  plan.full,
+        Amount:                plan.amount,
+        Reason:                req.Reason,
+        ReversedBy:            req.ReversedBy,
+        ApprovedBy:            req.ApprovedBy,
+    }
+    err = tx.QueryRow(queryCtx, `
+        INSERT INTO transaction_reversals (
+            transaction_id, original_transaction_id, is_full, amount, currency_code,
+            reason, reversed_by, approved_by
+        ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+        RETURNING id, created_at
+    `, reversal.TransactionID, reversal.OriginalTransactionID, reversal.Full, plan.amount, currencyCode,
+        reversal.Reason, reversal.ReversedBy, reversal.ApprovedBy,
+    ).Scan(&reversal.ID, &reversal.CreatedAt)
+    if err != nil {
+        return nil, fmt.Errorf("failed to record reversal: %w", err)
+    }
+
+    err = tx.Commit(queryCtx)
+    if err != nil {
+        return nil, fmt.Errorf("failed to commit transaction: %w", err)
+    }
+
+    for _, entry := range plan.entries {
+        reversal.Entries = append(reversal.Entries, *entry)
+    }
+    return reversal, nil
+}
+
+// GetTransaction retrieves the legs of a posted transaction and any reversals
+// posted against it
+func (pl *PostgresLedger) GetTransaction(ctx context.Context, transactionID string) (*TransactionDetail, error) {
+    queryCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+    defer cancel()
+
+    entries, err := queryJournalEntries(queryCtx, pl.Pool, `
+        SELECT `+journalEntryColumns+`
+        FROM journal_entries
+        WHERE transaction_id = $1
+        ORDER BY entry_number ASC
+    `, transactionID)
+    if err != nil {
+        return nil, err
+    }
+    if len(entries) == 0 {
+        return nil, fmt.Errorf("%w: %s", ErrTransactionNotFound, transactionID)
+    }
+
+    detail := &TransactionDetail{
+        TransactionID: transactionID,
+        Entries:       entries,
+        Reversals:     []Reversal{},
+    }
+    detail.ReversalOf, err = queryReversalOf(queryCtx, pl.Pool, transactionID)
+    if err != nil {
+        return nil, err
+    }
+
+    reversals, err := queryReversals(queryCtx, pl.Pool, transactionID)
+    if err != nil {
+        return nil, err
+    }
+    if len(reversals) == 0 {
+        return detail, nil
+    }
+
+    reversalEntries, err := queryJournalEntries(queryCtx, pl.Pool, `
+        SELECT `+journalEntryColumns+`
+        FROM journal_entries
+        WHERE reference_type = $1 AND reference_id = $2
+        ORDER BY entry_number ASC
+    `, ReferenceTypeReversal, transactionID)
+    if err != nil {
+        return nil, err
+    }
+    byTransaction := make(map[string][]JournalEntry, len(reversals))
+    for _, e := range reversalEntries {
+        byTransaction[e.TransactionID] = append(byTransaction[e.TransactionID], e)
+    }
+
+    for _, r := range reversals {
+        r.Entries = byTransaction[r.TransactionID]
+        detail.FullyReversed = detail.FullyReversed || r.Full
+        detail.Reversals = append(detail.Reversals, r)
+    }
+
+    return detail, nil
+}
+
+// SetFXRate publishes an exchange rate for a currency pair
+func (pl *PostgresLedger) SetFXRate(ctx context.Context, req SetFXRateRequest) (*FXRate, error) {
+    queryCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+    defer cancel()
+
+    rate := &FXRate{
+        BaseCurrency:  req.BaseCurrency,
+        QuoteCurrency: req.QuoteCurrency,
+        Rate:          req.Rate,
+        Source:        req.Source,
+        CreatedBy:     req.CreatedBy,
+    }
+    err := pl.Pool.QueryRow(queryCtx, `
+        INSERT INTO fx_rates (base_currency, quote_currency, rate, effective_at, source, created_by)
+        VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6)
+        RETURNING id, effective_at, created_at
+    `, req.BaseCurrency, req.QuoteCurrency, req.Rate, req.EffectiveAt.UTC(), req.Source, req.CreatedBy,
+    ).Scan(&rate.ID, &rate.EffectiveAt, &rate.CreatedAt)
+    if err != nil {
+        var pgErr *pgconn.PgError
+        if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+            return nil, fmt.Errorf("%w: a %s/%s rate is already effective at %s", ErrInvalidRequest, req.BaseCurrency, req.QuoteCurrency, req.EffectiveAt.UTC().Format(time.RFC3339))
+        }
+        return nil, fmt.Errorf("failed to insert fx rate: %w", err)
+    }
+
+    return rate, nil
+}
+
+// fxRateAt looks up the rate for a currency pair in effect at a point in time
+func fxRateAt(ctx context.Context, q querier, base, quote string, at time.Time) (*FXRate, error) {
+    rate := &FXRate{BaseCurrency: base, QuoteCurrency: quote}
+    var value pgtype.Numeric
+    var source *string
+    err := q.QueryRow(ctx, `
+        SELECT id, rate, effective_at, source, created_at, created_by
+        FROM fx_rates
+        WHERE base_currency = $1 AND quote_currency = $2 AND effective_at <= $3
+        ORDER BY effective_at DESC
+        LIMIT 1
+    `, base, quote, at.UTC()).Scan(&rate.ID, &value, &rate.EffectiveAt, &source, &rate.CreatedAt, &rate.CreatedBy)
+    if err != nil {
+        if errors.Is(err, pgx.ErrNoRows) {
+            return nil, fmt.Errorf("%w: %s/%s at %s", ErrFXRateNotFound, base, quote, at.UTC().Format(time.RFC3339))
+        }
+        return nil, fmt.Errorf("failed to get fx rate: %w", err)
+    }
+
+    if rate.Rate, err = money.FXRateFromNumeric(value); err != nil {
+        return nil, fmt.Errorf("failed to read fx rate %s: %w", rate.ID, err)
+    }
+    if source != nil {
+        rate.Source = *source
+    }
+    return rate, nil
+}
+
+// GetFXRate retrieves the rate for a currency pair in effect at a point in time
+func (pl *PostgresLedger) GetFXRate(ctx context.Context, base, quote string, at time.Time) (*FXRate, error) {
+    queryCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+    defer cancel()
+
+    return fxRateAt(queryCtx, pl.Pool, base, quote, at)
+}
+
+// SetFXAccounts configures the clearing, gain and loss accounts FX transfers
+// use in one currency
+func (pl *PostgresLedger) SetFXAccounts(ctx context.Context, accts FXAccounts) error {
+    queryCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+    defer cancel()
+
+    tx, err := pl.Pool.BeginTx(queryCtx, pgx.TxOptions{
+        IsoLevel:   pgx.Serializable,
+        AccessMode: pgx.ReadWrite,
+    })
+    if err != nil {
+        return fmt.Errorf("failed to begin transaction: %w", err)
+    }
+    defer tx.Rollback(queryCtx)
+
+    // Gains are credited and losses debited, so each must be an account type
+    // whose balance that increases
+    required := map[string]string{
+        accts.GainAccountID: "revenue",
+        accts.LossAccountID: "expense",
+    }
+    for _, id := range []string{accts.ClearingAccountID, accts.GainAccountID, accts.LossAccountID} {
+        var accountType, currencyCode string
+        err := tx.QueryRow(queryCtx, `SELECT account_type, currency_code FROM accounts WHERE id = $1`, id).Scan(&accountType, &currencyCode)
+        if err != nil {
+            if errors.Is(err, pgx.ErrNoRows) {
+                return fmt.Errorf("%w: %s", ErrAccountNotFound, id)
+            }
+            return fmt.Errorf("failed to get account: %w", err)
+        }
+        if currencyCode != accts.CurrencyCode {
+            return fmt.Errorf("%w: account %s is %s, not %s", ErrCurrencyMismatch, id, currencyCode, accts.CurrencyCode)
+        }
+        if want, ok := required[id]; ok && accountType != want {
+            return fmt.Errorf("%w: account %s must be a %s account", ErrInvalidRequest, id, want)
+        }
+    }
+
+    _, err = tx.Exec(queryCtx, `
+        INSERT INTO fx_accounts (currency_code, clearing_account_id, gain_account_id, loss_account_id, updated_by)
+        VALUES ($1, $2, $3, $4, $5)
+        ON CONFLICT (currency_code)
+        DO UPDATE SET
+            clearing_account_id = EXCLUDED.clearing_account_id,
+            gain_account_id = EXCLUDED.gain_account_id,
+            loss_account_id = EXCLUDED.loss_account_id,
+            updated_at = CURRENT_TIMESTAMP,
+            updated_by = EXCLUDED.updated_by
+    `, accts.CurrencyCode, accts.ClearingAccountID, accts.GainAccountID, accts.LossAccountID, accts.UpdatedBy)
+    if err != nil {
+        return fmt.Errorf("failed to save fx accounts: %w", err)
+    }
+
+    err = tx.Commit(queryCtx)
+    if err != nil {
+        return fmt.Errorf("failed to commit transaction: %w", err)
+    }
+
+    return nil
+}
+
+// fxAccountsFor loads the FX accounts configured for a currency
+func fxAccountsFor(ctx context.Context, tx pgx.Tx, currencyCode string) (FXAccounts, error) {
+    accts := FXAccounts{CurrencyCode: currencyCode}
+    err := tx.QueryRow(ctx, `
+        SELECT clearing_account_id, gain_account_id, loss_account_id, updated_by
+        FROM fx_accounts
+        WHERE currency_code = $1
+    `, currencyCode).Scan(&accts.ClearingAccountID, &accts.GainAccountID, &accts.LossAccountID, &accts.UpdatedBy)
+    if err != nil {
+        if errors.Is(err, pgx.ErrNoRows) {
+            return accts, fmt.Errorf("%w: %s", ErrFXNotConfigured, currencyCode)
+        }
+        return accts, fmt.Errorf("failed to get fx accounts: %w", err)
+    }
+    return accts, nil
+}
+
+// FXTransfer converts an amount between accounts in different currencies at
+// the rate in effect now and posts it through the FX clearing accounts. A
+// quoted rate more than maxSpread from the market rate is rejected unless ctx
+// allows off-market quotes.
+func (pl *PostgresLedger) FXTransfer(ctx context.Context, req FXTransferRequest, maxSpread money.Rate) (*FXConversion, error) {
+    const maxRetries = 3
+
+    var conversion *FXConversion
+    for attempt := 0; attempt < maxRetries; attempt++ {
+        var err error
+        conversion, err = pl.fxTransferWithRetry(ctx, req, maxSpread)
+        if err != nil {
+            var pgErr *pgconn.PgError
+            if errors.As(err, &pgErr) && pgErr.Code == "40001" {
+                // Serialization failure, retry
+                if attempt == maxRetries-1 {
+                    return nil, fmt.Errorf("failed to post fx transfer after %d retries due to serialization failure: %w", maxRetries, err)
+                }
+                time.Sleep(time.Duration(attempt+1) * 10 * time.Millisecond)
+                continue
+            }
+            return nil, fmt.Errorf("failed to post fx transfer: %w", err)
+        }
+        break
+    }
+
+    return conversion, nil
+}
+
+func (pl *PostgresLedger) fxTransferWithRetry(ctx context.Context, req FXTransferRequest, maxSpread money.Rate) (*FXConversion, error) {
+    queryCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+    defer cancel()
+
+    tx, err := pl.Pool.BeginTx(queryCtx, pgx.TxOptions{
+        IsoLevel:   pgx.Serializable,
+        AccessMode: pgx.ReadWrite,
+    })
+    if err != nil {
+        return nil, fmt.Errorf("failed to begin transaction: %w", err)
+    }
+    defer tx.Rollback(queryCtx)
+
+    accountCurrency := func(id string) (string, error) {
+        var currencyCode string
+        err := tx.QueryRow(queryCtx, `SELECT currency_code FROM accounts WHERE id = $1`, id).Scan(&currencyCode)
+        if err != nil {
+            if errors.Is(err, pgx.ErrNoRows) {
+                return "", fmt.Errorf("%w: %s", ErrAccountNotFound, id)
+            }
+            return "", fmt.Errorf("failed to get account: %w", err)
+        }
+        return currencyCode, nil
+    }
+    sourceCurrency, err := accountCurrency(req.FromAccountID)
+    if err != nil {
+        return nil, err
+    }
+    targetCurrency, err := accountCurrency(req.ToAccountID)
+    if err != nil {
+        return nil, err
+    }
+    if sourceCurrency == targetCurrency {
+        return nil, fmt.Errorf("%w: both accounts are %s; use a transfer", ErrInvalidRequest, sourceCurrency)
+    }
+
+    source, err := fxAccountsFor(queryCtx, tx, sourceCurrency)
+    if err != nil {
+        return nil, err
+    }
+    target, err := fxAccountsFor(queryCtx, tx, targetCurrency)
+    if err != nil {
+        return nil, err
+    }
+
+    rate, err := fxRateAt(queryCtx, tx, sourceCurrency, targetCurrency, time.Now())
+    if err != nil {
+        return nil, err
+    }
+
+    if !offMarketQuotesAllowed(ctx) {
+        if err := checkQuotedRate(req.QuotedRate, rate, maxSpread); err != nil {
+            return nil, err
+        }
+    }
+
+    conversion, entries, err := planFXTransfer(req, source, target, rate)
+    if err != nil {
+        return nil, err
+    }
+
+    var available bool
+    err = tx.QueryRow(queryCtx, `SELECT check_available_balance($1, $2)`, req.FromAccountID, req.Amount).Scan(&available)
+    if err != nil {
+        return nil, fmt.Errorf("failed to check available balance: %w", err)
+    }
+    if !available {
+        return nil, fmt.Errorf("%w: account %s cannot transfer %s", ErrInsufficientFunds, req.FromAccountID, req.Amount)
+    }
+
+    if err := insertTransactionEntries(queryCtx, tx, entries); err != nil {
+        return nil, err
+    }
+
+    err = tx.QueryRow(queryCtx, `
+        INSERT INTO fx_conversions (
+            transaction_id, from_account_id, to_account_id, source_amount, source_currency,
+            target_amount, target_currency, fx_rate_id, market_rate, applied_rate,
+            realised_gain_loss, created_by
+        ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+        RETURNING id, created_at
+    `, conversion.TransactionID, conversion.FromAccountID, conversion.ToAccountID,
+        conversion.SourceAmount, sourceCurrency, conversion.TargetAmount, targetCurrency,
+        conversion.FXRateID, conversion.MarketRate, conversion.AppliedRate,
+        conversion.RealisedGainLoss, conversion.CreatedBy,
+    ).Scan(&conversion.ID, &conversion.CreatedAt)
+    if err != nil {
+        return nil, fmt.Errorf("failed to record fx conversion: %w", err)
+    }
+
+    err = tx.Commit(queryCtx)
+    if err != nil {
+        return nil, fmt.Errorf("failed to commit transaction: %w", err)
+    }
+
+    for _, entry := range entries {
+        conversion.Entries = append(conversion.Entries, *entry)
+    }
+    return conversion, nil
+}
+
+// ChangeAccountStatus moves an account to a new lifecycle status and records
+// the transition. Closing requires a zero balance and no pending transactions
+// or active holds.
+func (pl *PostgresLedger) ChangeAccountStatus(ctx context.Context, req AccountStatusRequest, status string) (*AccountStatusTransition, error) {
+    const maxRetries = 3
+
+    var transition *AccountStatusTransition
+    for attempt := 0; attempt < maxRetries; attempt++ {
+        var err error
+        transition, err = pl.changeAccountStatusWithRetry(ctx, req, status)
+        if err != nil {
+            var pgErr *pgconn.PgError
+            if errors.As(err, &pgErr) && pgErr.Code == "40001" {
+                // Serialization failure, retry
+                if attempt == maxRetries-1 {
+                    return nil, fmt.Errorf("failed to change account status after %d retries due to serialization failure: %w", maxRetries, err)
+                }
+                time.Sleep(time.Duration(attempt+1) * 10 * time.Millisecond)
+                continue
+            }
+            return nil, fmt.Errorf("failed to change account status: %w", err)
+        }
+        break
+    }
+
+    return transition, nil
+}
+
+func (pl *PostgresLedger) changeAccountStatusWithRetry(ctx context.Context, req AccountStatusRequest, status string) (*AccountStatusTransition, error) {
+    queryCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+    defer cancel()
+
+    tx, err := pl.Pool.BeginTx(queryCtx, pgx.TxOptions{
+        IsoLevel:   pgx.Serializable,
+        AccessMode: pgx.ReadWrite,
+    })
+    if err != nil {
+        return nil, fmt.Errorf("failed to begin transaction: %w", err)
+    }
+    defer tx.Rollback(queryCtx)
+
+    // Lock the account so no posting interleaves with the change
+    var current string
+    err = tx.QueryRow(queryCtx, `SELECT status FROM accounts WHERE id = $1 FOR UPDATE`, req.AccountID).Scan(&current)
+    if err != nil {
+        if errors.Is(err, pgx.ErrNoRows) {
+            return nil, fmt.Errorf("%w: %s", ErrAccountNotFound, req.AccountID)
+        }
+        return nil, fmt.Errorf("failed to lock account: %w", err)
+    }
+    if err := checkStatusTransition(req.AccountID, current, status); err != nil {
+        return nil, err
+    }
+
+    if status == AccountStatusClosed {
+        var hasBalance, hasHolds, hasPending bool
+        err = tx.QueryRow(queryCtx, `
+            SELECT
+                COALESCE((SELECT balance FROM account_balances WHERE account_id = $1), 0) <> 0,
+                EXISTS (SELECT 1 FROM holds WHERE account_id = $1 AND status = 'ACTIVE'),
+                EXISTS (
+                    SELECT 1 FROM pending_transactions
+                    WHERE (from_account_id = $1 OR to_account_id = $1)
+                    AND status = 'pending'
+                    AND expires_at > CURRENT_TIMESTAMP
+                )
+        `, req.AccountID).Scan(&hasBalance, &hasHolds, &hasPending)
+        if err != nil {
+            return nil, fmt.Errorf("failed to check account is empty: %w", err)
+        }
+        if hasBalance {
+            return nil, fmt.Errorf("%w: %s has a non-zero balance", ErrAccountNotEmpty, req.AccountID)
+        }
+        if hasHolds {
+            return nil, fmt.Errorf("%w: %s has active holds", ErrAccountNotEmpty, req.AccountID)
+        }
+        if hasPending {
+            return nil, fmt.Errorf("%w: %s has pending transactions", ErrAccountNotEmpty, req.AccountID)
+        }
+    }
+
+    _, err = tx.Exec(queryCtx, `
+        UPDATE accounts
+        SET status = $2,
+            is_active = ($2 = 'active'),
+            closed_at = CASE WHEN $2 = 'closed' THEN CURRENT_TIMESTAMP END
+        WHERE id = $1
+    `, req.AccountID, status)
+    if err != nil {
+        return nil, fmt.Errorf("failed to update account status: %w", err)
+    }
+
+    transition := &AccountStatusTransition{
+        AccountID:  req.AccountID,
+        FromStatus: current,
+        ToStatus:   status,
+        Reason:     req.Reason,
+        ChangedBy:  req.ChangedBy,
+    }
+    err = tx.QueryRow(queryCtx, `
+        INSERT INTO account_status_transitions (account_id, from_status, to_status, reason, changed_by)
+        VALUES ($1, $2, $3, $4, $5)
+        RETURNING id, created_at
+    `, req.AccountID, current, status, req.Reason, req.ChangedBy).Scan(&transition.ID, &transition.CreatedAt)
+    if err != nil {
+        return nil, fmt.Errorf("failed to record status transition: %w", err)
+    }
+
+    if err := appendAccountStatusChanged(queryCtx, tx, transition); err != nil {
+        return nil, err
+    }
+
+    err = tx.Commit(queryCtx)
+    if err != nil {
+        return nil, fmt.Errorf("failed to commit transaction: %w", err)
+    }
+
+    return transition, nil
+}
+
+// GetAccountStatusHistory lists the status transitions of an account, oldest first
+func (pl *PostgresLedger) GetAccountStatusHistory(ctx context.Context, accountID string) ([]AccountStatusTransition, error) {
+    queryCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+    defer cancel()
+
+    var exists bool
+    err := pl.Pool.QueryRow(queryCtx, `SELECT EXISTS(SELECT 1 FROM accounts WHERE id = $1)`, accountID).Scan(&exists)
+    if err != nil {
+        return nil, fmt.Errorf("failed to check account existence: %w", err)
+    }
+    if !exists {
+        return nil, fmt.Errorf("%w: %s", ErrAccountNotFound, accountID)
+    }
+
+    rows, err := pl.Pool.Query(queryCtx, `
+        SELECT id, account_id, from_status, to_status, reason, changed_by, created_at
+        FROM account_status_transitions
+        WHERE account_id = $1
+        ORDER BY created_at, id
+    `, accountID)
+    if err != nil {
+        return nil, fmt.Errorf("failed to query status transitions: %w", err)
+    }
+    defer rows.Close()
+
+    history := []AccountStatusTransition{}
+    for rows.Next() {
+        var t AccountStatusTransition
+        if err := rows.Scan(&t.ID, &t.AccountID, &t.FromStatus, &t.ToStatus, &t.Reason, &t.ChangedBy, &t.CreatedAt); err != nil {
+            return nil, fmt.Errorf("failed to scan status transition: %w", err)
+        }
+        history = append(history, t)
+    }
+    if err := rows.Err(); err != nil {
+        return nil, fmt.Errorf("failed to query status transitions: %w", err)
+    }
+
+    return history, nil
+}
+
+// creditLimitColumns are the account_credit_limits columns read by scanCreditLimit
+const creditLimitColumns = `
+    l.id, l.account_id, a.currency_code, l.overdraft_limit, l.max_transaction_amount,
+    l.effective_from::text, l.reason, l.set_by, l.created_at::text`
+
+func scanCreditLimit(row pgx.Row) (*CreditLimit, error) {
+    var l CreditLimit
+    var currencyCode string
+    var overdraft, maxTransaction pgtype.Numeric
+    if err := row.Scan(&l.ID, &l.AccountID, &currencyCode, &overdraft, &maxTransaction, &l.EffectiveFrom, &l.Reason, &l.SetBy, &l.CreatedAt); err != nil {
+        return nil, err
+    }
+
+    var err error
+    if l.OverdraftLimit, err = money.FromNumeric(overdraft, currencyCode); err != nil {
+        return nil, fmt.Errorf("failed to read overdraft limit %s: %w", l.ID, err)
+    }
+    if maxTransaction.Valid {
+        maxAmount, err := money.FromNumeric(maxTransaction, currencyCode)
+        if err != nil {
+            return nil, fmt.Errorf("failed to read transaction limit %s: %w", l.ID, err)
+        }
+        l.MaxTransactionAmount = &maxAmount
+    }
+    return &l, nil
+}
+
+// SetCreditLimit records a limit for an account, effective from req.EffectiveFrom
+func (pl *PostgresLedger) SetCreditLimit(ctx context.Context, req SetCreditLimitRequest) (*CreditLimit, error) {
+    queryCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+    defer cancel()
+
+    var currencyCode string
+    err := pl.Pool.QueryRow(queryCtx, `SELECT currency_code FROM accounts WHERE id = $1`, req.AccountID).Scan(&currencyCode)
+    if err != nil {
+        if errors.Is(err, pgx.ErrNoRows) {
+            return nil, fmt.Errorf("%w: %s", ErrAccountNotFound, req.AccountID)
+        }
+        return nil, fmt.Errorf("failed to get account: %w", err)
+    }
+    if req.OverdraftLimit.Currency() != currencyCode {
+        return nil, fmt.Errorf("%w: limit %s vs account %s %s", ErrCurrencyMismatch, req.OverdraftLimit.Currency(), req.AccountID, currencyCode)
+    }
+
+    var id string
+    err = pl.Pool.QueryRow(queryCtx, `
+        INSERT INTO account_credit_limits (account_id, overdraft_limit, max_transaction_amount, effective_from, reason, set_by)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        RETURNING id
+    `, req.AccountID, req.OverdraftLimit, req.MaxTransactionAmount, req.EffectiveFrom.UTC(), req.Reason, req.SetBy).Scan(&id)
+    if err != nil {
+        var pgErr *pgconn.PgError
+        if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+            return nil, fmt.Errorf("%w: account %s already has a limit effective at %s", ErrInvalidRequest, req.AccountID, req.EffectiveFrom.UTC().Format(time.RFC3339))
+        }
+        return nil, fmt.Errorf("failed to insert credit limit: %w", err)
+    }
+
+    return scanCreditLimit(pl.Pool.QueryRow(queryCtx, `
+        SELECT `+creditLimitColumns+`
+        FROM account_credit_limits l
+        JOIN accounts a ON a.id = l.account_id
+        WHERE l.id = $1
+    `, id))
+}
+
+// GetCreditLimit retrieves the limit in force for an account at a point in time
+func (pl *PostgresLedger) GetCreditLimit(ctx context.Context, accountID string, at time.Time) (*CreditLimit, error) {
+    queryCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+    defer cancel()
+
+    limit, err := scanCreditLimit(pl.Pool.QueryRow(queryCtx, `
+        SELECT `+creditLimitColumns+`
+        FROM account_credit_limits l
+        JOIN accounts a ON a.id = l.account_id
+        WHERE l.account_id = $1 AND l.effective_from <= $2
+        ORDER BY l.effective_from DESC
+        LIMIT 1
+    `, accountID, at.UTC()))
+    if err != nil {
+        if errors.Is(err, pgx.ErrNoRows) {
+            return nil, fmt.Errorf("%w: account %s at %s", ErrCreditLimitNotFound, accountID, at.UTC().Format(time.RFC3339))
+        }
+        return nil, fmt.Errorf("failed to get credit limit: %w", err)
+    }
+    return limit, nil
+}
+
+// ListCreditLimits lists every limit set for an account, including those not
+// yet in effect, latest effective first
+func (pl *PostgresLedger) ListCreditLimits(ctx context.Context, accountID string) ([]CreditLimit, error) {
+    queryCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+    defer cancel()
+
+    var exists bool
+    err := pl.Pool.QueryRow(queryCtx, `SELECT EXISTS(SELECT 1 FROM accounts WHERE id = $1)`, accountID).Scan(&exists)
+    if err != nil {
+        return nil, fmt.Errorf("failed to check account existence: %w", err)
+    }
+    if !exists {
+        return nil, fmt.Errorf("%w: %s", ErrAccountNotFound, accountID)
+    }
+
+    rows, err := pl.Pool.Query(queryCtx, `
+        SELECT `+creditLimitColumns+`
+        FROM account_credit_limits l
+        JOIN accounts a ON a.id = l.account_id
+        WHERE l.account_id = $1
+        ORDER BY l.effective_from DESC
+    `, accountID)
+    if err != nil {
+        return nil, fmt.Errorf("failed to query credit limits: %w", err)
+    }
+    defer rows.Close()
+
+    limits := []CreditLimit{}
+    for rows.Next() {
+        limit, err := scanCreditLimit(rows)
+        if err != nil {
+            return nil, fmt.Errorf("failed to scan credit limit: %w", err)
+        }
+        limits = append(limits, *limit)
+    }
+    if err := rows.Err(); err != nil {
+        return nil, fmt.Errorf("failed to query credit limits: %w", err)
+    }
+
+    return limits, nil
+}
+
+// GetAccountSubtree lists an account and every account beneath it, parents
+// before children and siblings by account number
+func (pl *PostgresLedger) GetAccountSubtree(ctx context.Context, accountID string) ([]*Account, error) {
+    queryCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+    defer cancel()
+
+    rows, err := pl.Pool.Query(queryCtx, `
+        WITH RECURSIVE subtree AS (
+            SELECT id, ARRAY[account_number] AS path
+            FROM accounts
+            WHERE id = $1
+            UNION ALL
+            SELECT c.id, s.path || c.account_number
+            FROM accounts c
+            JOIN subtree s ON c.parent_id = s.id
+        )
+        SELECT
+            a.id, COALESCE(a.parent_id::text, ''), a.account_number, a.account_type, a.name,
+            a.currency_code, a.is_active, a.status, a.created_at, a.created_by, a.metadata,
+            COALESCE(ab.balance, 0) as current_balance
+        FROM subtree s
+        JOIN accounts a ON a.id = s.id
+        LEFT JOIN account_balances ab ON a.id = ab.account_id
+        ORDER BY s.path
+    `, accountID)
+    if err != nil {
+        return nil, fmt.Errorf("failed to query account subtree: %w", err)
+    }
+    defer rows.Close()
+
+    var accounts []*Account
+    for rows.Next() {
+        var account Account
+        var metadataJSON sql.NullString
+        var balance pgtype.Numeric
+
+        err := rows.Scan(
+            &account.ID, &account.ParentID, &account.AccountNumber, &account.AccountType, &account.Name,
+            &account.CurrencyCode, &account.IsActive, &account.Status, &account.CreatedAt, &account.CreatedBy,
+            &metadataJSON, &balance,
+        )
+        if err != nil {
+            return nil, fmt.Errorf("failed to scan account: %w", err)
+        }
+
+        account.CurrentBalance, err = money.FromNumeric(balance, account.CurrencyCode)
+        if err != nil {
+            return nil, fmt.Errorf("failed to read balance for account %s: %w", account.AccountNumber, err)
+        }
+
+        if metadataJSON.Valid {
+            account.Metadata = make(map[string]interface{})
+            account.Metadata["raw"] = metadataJSON.String
+        }
+
+        accounts = append(accounts, &account)
+    }
+    if err := rows.Err(); err != nil {
+        return nil, fmt.Errorf("failed to query account subtree: %w", err)
+    }
+    if len(accounts) == 0 {
+        return nil, fmt.Errorf("%w: %s", ErrAccountNotFound, accountID)
+    }
+
+    return accounts, nil
+}
+
+// periodColumns are the accounting_periods columns read by scanAccountingPeriod
+const periodColumns = `
+    id, name, starts_on::text, ends_on::text, status, created_at, created_by,
+    soft_closed_at, soft_closed_by, hard_closed_at, hard_closed_by`
+
+func scanAccountingPeriod(row pgx.Row) (*AccountingPeriod, error) {
+    var p AccountingPeriod
+    var softClosedAt, softClosedBy, hardClosedAt, hardClosedBy *string
+
+    err := row.Scan(
+        &p.ID, &p.Name, &p.StartsOn, &p.EndsOn, &p.Status, &p.CreatedAt, &p.CreatedBy,
+        &softClosedAt, &softClosedBy, &hardClosedAt, &hardClosedBy,
+    )
+    if err != nil {
+        return nil, err
+    }
+
+    for _, f := range []struct {
+        dst *string
+        src *string
+    }{
+        {&p.SoftClosedAt, softClosedAt},
+        {&p.SoftClosedBy, softClosedBy},
+        {&p.HardClosedAt, hardClosedAt},
+        {&p.HardClosedBy, hardClosedBy},
+    } {
+        if f.src != nil {
+            *f.dst = *f.src
+        }
+    }
+
+    return &p, nil
+}
+
+// queryPeriodBalances runs a query selecting account ID, number, type,
+// currency and balance
+func queryPeriodBalances(ctx context.Context, q querier, query string, args ...interface{}) ([]PeriodClosingBalance, error) {
+    rows, err := q.Query(ctx, query, args...)
+    if err != nil {
+        return nil, fmt.Errorf("failed to query period balances: %w", err)
+    }
+    defer rows.Close()
+
+    balances := []PeriodClosingBalance{}
+    for rows.Next() {
+        var b PeriodClosingBalance
+        var currencyCode string
+        var balance pgtype.Numeric
+        if err := rows.Scan(&b.AccountID, &b.AccountNumber, &b.AccountType, &currencyCode, &balance); err != nil {
+            return nil, fmt.Errorf("failed to scan period balance: %w", err)
+        }
+        if b.Balance, err = money.FromNumeric(balance, currencyCode); err != nil {
+            return nil, fmt.Errorf("failed to read balance of account %s: %w", b.AccountNumber, err)
+        }
+        balances = append(balances, b)
+    }
+    if err := rows.Err(); err != nil {
+        return nil, fmt.Errorf("failed to query period balances: %w", err)
+    }
+
+    return balances, nil
+}
+
+// CreatePeriod opens an accounting period. Periods may not overlap.
+func (pl *PostgresLedger) CreatePeriod(ctx context.Context, req CreatePeriodRequest) (*AccountingPeriod, error) {
+    queryCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+    defer cancel()
+
+    period, err := scanAccountingPeriod(pl.Pool.QueryRow(queryCtx, `
+        INSERT INTO accounting_periods (name, starts_on, ends_on, created_by)
+        VALUES ($1, $2::date, $3::date, $4)
+        RETURNING `+periodColumns,
+        req.Name, req.StartsOn, req.EndsOn, req.CreatedBy))
+    if err != nil {
+        var pgErr *pgconn.PgError
+        if errors.As(err, &pgErr) {
+            switch pgErr.Code {
+            case "23505":
+                return nil, fmt.Errorf("%w: period %s already exists", ErrInvalidRequest, req.Name)
+            case "23P01":
+                return nil, fmt.Errorf("%w: %s to %s overlaps another period", ErrInvalidRequest, req.StartsOn, req.EndsOn)
+            }
+        }
+        return nil, fmt.Errorf("failed to create accounting period: %w", err)
+    }
+
+    return period, nil
+}
+
+// GetPeriod retrieves an accounting period and, once it is closed, the balance
+// of every account at its end
+func (pl *PostgresLedger) GetPeriod(ctx context.Context, periodID string) (*AccountingPeriod, error) {
+    queryCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+    defer cancel()
+
+    period, err := scanAccountingPeriod(pl.Pool.QueryRow(queryCtx, `SELECT `+periodColumns+` FROM accounting_periods WHERE id = $1`, periodID))
+    if err != nil {
+        if errors.Is(err, pgx.ErrNoRows) {
+            return nil, fmt.Errorf("%w: %s", ErrPeriodNotFound, periodID)
+        }
+        return nil, fmt.Errorf("failed to get accounting period: %w", err)
+    }
+
+    if period.ClosingBalances, err = queryClosingBalances(queryCtx, pl.Pool, period.ID); err != nil {
+        return nil, err
+    }
+
+    return period, nil
+}
+
+// queryClosingBalances lists the balances snapshotted when a period closed
+func queryClosingBalances(ctx context.Context, q querier, periodID string) ([]PeriodClosingBalance, error) {
+    return queryPeriodBalances(ctx, q, `
+        SELECT pcb.account_id, a.account_number, pcb.account_type, pcb.currency_code, pcb.balance
+        FROM period_closing_balances pcb
+        JOIN accounts a ON a.id = pcb.account_id
+        WHERE pcb.period_id = $1
+        ORDER BY pcb.currency_code, a.account_number
+    `, periodID)
+}
+
+// ListPeriods lists accounting periods, earliest first
+func (pl *PostgresLedger) ListPeriods(ctx context.Context) ([]AccountingPeriod, error) {
+    queryCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+    defer cancel()
+
+    rows, err := pl.Pool.Query(queryCtx, `SELECT `+periodColumns+` FROM accounting_periods ORDER BY starts_on`)
+    if err != nil {
+        return nil, fmt.Errorf("failed to query accounting periods: %w", err)
+    }
+    defer rows.Close()
+
+    periods := []AccountingPeriod{}
+    for rows.Next() {
+        period, err := scanAccountingPeriod(rows)
+        if err != nil {
+            return nil, fmt.Errorf("failed to scan accounting period: %w", err)
+        }
+        periods = append(periods, *period)
+    }
+    if err := rows.Err(); err != nil {
+        return nil, fmt.Errorf("failed to query accounting periods: %w", err)
+    }
+
+    return periods, nil
+}
+
+// SetRetainedEarningsAccount configures the equity account revenue and expense
+// in one currency roll into at period close
+func (pl *PostgresLedger) SetRetainedEarningsAccount(ctx context.Context, acct RetainedEarningsAccount) error {
+    queryCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+    defer cancel()
+
+    tx, err := pl.Pool.BeginTx(queryCtx, pgx.TxOptions{
+        IsoLevel:   pgx.Serializable,
+        AccessMode: pgx.ReadWrite,
+    })
+    if err != nil {
+        return fmt.Errorf("failed to begin transaction: %w", err)
+    }
+    defer tx.Rollback(queryCtx)
+
+    var accountType, currencyCode string
+    err = tx.QueryRow(queryCtx, `SELECT account_type, currency_code FROM accounts WHERE id = $1`, acct.AccountID).Scan(&accountType, &currencyCode)
+    if err != nil {
+        if errors.Is(err, pgx.ErrNoRows) {
+            return fmt.Errorf("%w: %s", ErrAccountNotFound, acct.AccountID)
+        }
+        return fmt.Errorf("failed to get account: %w", err)
+    }
+    if currencyCode != acct.CurrencyCode {
+        return fmt.Errorf("%w: account %s is %s, not %s", ErrCurrencyMismatch, acct.AccountID, currencyCode, acct.CurrencyCode)
+    }
+    if accountType != "equity" {
+        return fmt.Errorf("%w: account %s must be an equity account", ErrInvalidRequest, acct.AccountID)
+    }
+
+    _, err = tx.Exec(queryCtx, `
+        INSERT INTO retained_earnings_accounts (currency_code, account_id, updated_by)
+        VALUES ($1, $2, $3)
+        ON CONFLICT (currency_code)
+        DO UPDATE SET
+            account_id = EXCLUDED.account_id,
+            updated_at = CURRENT_TIMESTAMP,
+            updated_by = EXCLUDED.updated_by
+    `, acct.CurrencyCode, acct.AccountID, acct.UpdatedBy)
+    if err != nil {
+        return fmt.Errorf("failed to save retained earnings account: %w", err)
+    }
+
+    err = tx.Commit(queryCtx)
+    if err != nil {
+        return fmt.Errorf("failed to commit transaction: %w", err)
+    }
+
+    return nil
+}
+
+// ClosePeriod soft or hard closes an accounting period. Every earlier period
+// must already be at least as closed. Revenue and expense are rolled into
+// retained earnings by entries effective on the period's last day, then every
+// account's balance at the end of the period is snapshotted. Hard closing a
+// soft-closed period rolls and snapshots again to take in any adjustments.
+func (pl *PostgresLedger) ClosePeriod(ctx context.Context, req ClosePeriodRequest, status string) (*AccountingPeriod, error) {
+    const maxRetries = 3
+
+    var period *AccountingPeriod
+    for attempt := 0; attempt < maxRetries; attempt++ {
+        var err error
+        period, err = pl.closePeriodWithRetry(ctx, req, status)
+        if err != nil {
+            var pgErr *pgconn.PgError
+            if errors.As(err, &pgErr) && pgErr.Code == "40001" {
+                // Serialization failure, retry
+                if attempt == maxRetries-1 {
+                    return nil, fmt.Errorf("failed to close accounting period after %d retries due to serialization failure: %w", maxRetries, err)
+                }
+                time.Sleep(time.Duration(attempt+1) * 10 * time.Millisecond)
+                continue
+            }
+            return nil, fmt.Errorf("failed to close accounting period: %w", err)
+        }
+        break
+    }
+
+    return period, nil
+}
+
+func (pl *PostgresLedger) closePeriodWithRetry(ctx context.Context, req ClosePeriodRequest, status string) (*AccountingPeriod, error) {
+    queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+    defer cancel()
+
+    tx, err := pl.Pool.BeginTx(queryCtx, pgx.TxOptions{
+        IsoLevel:   pgx.Serializable,
+        AccessMode: pgx.ReadWrite,
+    })
+    if err != nil {
+        return nil, fmt.Errorf("failed to begin transaction: %w", err)
+    }
+    defer tx.Rollback(queryCtx)
+
+    // Lock the period so no posting into it interleaves with the close
+    period, err := scanAccountingPeriod(tx.QueryRow(queryCtx, `SELECT `+periodColumns+` FROM accounting_periods WHERE id = $1 FOR UPDATE`, req.PeriodID))
+    if err != nil {
+        if errors.Is(err, pgx.ErrNoRows) {
+            return nil, fmt.Errorf("%w: %s", ErrPeriodNotFound, req.PeriodID)
+        }
+        return nil, fmt.Errorf("failed to lock accounting period: %w", err)
+    }
+    if err := checkPeriodTransition(period.Name, period.Status, status); err != nil {
+        return nil, err
+    }
+
+    // Periods close in order, so the balances rolled here are this period's alone
+    behind := []string{PeriodStatusOpen}
+    if status == PeriodStatusHardClosed {
+        behind = append(behind, PeriodStatusSoftClosed)
+    }
+    var earlierName, earlierStatus string
+    err = tx.QueryRow(queryCtx, `
+        SELECT name, status
+        FROM accounting_periods
+        WHERE ends_on < $1::date AND status = ANY($2)
+        ORDER BY starts_on
+        LIMIT 1
+    `, period.StartsOn, behind).Scan(&earlierName, &earlierStatus)
+    if err == nil {
+        return nil, fmt.Errorf("%w: earlier period %s is still %s", ErrInvalidPeriodTransition, earlierName, earlierStatus)
+    }
+    if !errors.Is(err, pgx.ErrNoRows) {
+        return nil, fmt.Errorf("failed to check earlier periods: %w", err)
+    }
+
+    // Balance of every account at the end of the period, by effective date
+    const balancesAtEnd = `
+        SELECT bs.account_id, a.account_number, bs.account_type, bs.currency_code, SUM(bs.balance_change)
+        FROM balance_snapshots bs
+        JOIN journal_entries je ON je.id = bs.entry_id
+        JOIN accounts a ON a.id = bs.account_id
+        WHERE je.effective_date <= $1::date
+        GROUP BY bs.account_id, a.account_number, bs.account_type, bs.currency_code
+        ORDER BY bs.currency_code, a.account_number`
+    balances, err := queryPeriodBalances(queryCtx, tx, balancesAtEnd, period.EndsOn)
+    if err != nil {
+        return nil, err
+    }
+
+    retained := map[string]string{}
+    rows, err := tx.Query(queryCtx, `SELECT currency_code, account_id FROM retained_earnings_accounts`)
+    if err != nil {
+        return nil, fmt.Errorf("failed to query retained earnings accounts: %w", err)
+    }
+    for rows.Next() {
+        var currencyCode, accountID string
+        if err := rows.Scan(&currencyCode, &accountID); err != nil {
+            rows.Close()
+            return nil, fmt.Errorf("failed to scan retained earnings account: %w", err)
+        }
+        retained[currencyCode] = accountID
+    }
+    rows.Close()
+    if err := rows.Err(); err != nil {
+        return nil, fmt.Errorf("failed to query retained earnings accounts: %w", err)
+    }
+
+    entries, err := planPeriodClose(period, balances, retained, req.TransactionID, req.ClosedBy)
+    if err != nil {
+        return nil, err
+    }
+    if len(entries) > 0 {
+        // The closing entries are themselves an adjustment when the period is
+        // already soft closed
+        if err := insertTransactionEntries(WithSoftClosedPostings(queryCtx), tx, entries); err != nil {
+            return nil, err
+        }
+    }
+
+    _, err = tx.Exec(queryCtx, `
+        INSERT INTO period_closing_balances (period_id, account_id, account_type, currency_code, balance)
+        SELECT $1, bs.account_id, bs.account_type, bs.currency_code, SUM(bs.balance_change)
+        FROM balance_snapshots bs
+        JOIN journal_entries je ON je.id = bs.entry_id
+        WHERE je.effective_date <= $2::date
+        GROUP BY bs.account_id, bs.account_type, bs.currency_code
+        ON CONFLICT (period_id, account_id)
+        DO UPDATE SET
+            balance = EXCLUDED.balance,
+            snapshot_at = CURRENT_TIMESTAMP
+    `, period.ID, period.EndsOn)
+    if err != nil {
+        return nil, fmt.Errorf("failed to snapshot closing balances: %w", err)
+    }
+
+    period, err = scanAccountingPeriod(tx.QueryRow(queryCtx, `
+        UPDATE accounting_periods
+        SET status = $2,
+            soft_closed_at = CASE WHEN $2 = 'soft_closed' THEN CURRENT_TIMESTAMP ELSE soft_closed_at END,
+            soft_closed_by = CASE WHEN $2 = 'soft_closed' THEN $3 ELSE soft_closed_by END,
+            hard_closed_at = CASE WHEN $2 = 'hard_closed' THEN CURRENT_TIMESTAMP END,
+            hard_closed_by = CASE WHEN $2 = 'hard_closed' THEN $3 END
+        WHERE id = $1
+        RETURNING `+periodColumns,
+        period.ID, status, req.ClosedBy))
+    if err != nil {
+        return nil, fmt.Errorf("failed to update accounting period: %w", err)
+    }
+
+    if period.ClosingBalances, err = queryClosingBalances(queryCtx, tx, period.ID); err != nil {
+        return nil, err
+    }
+
+    err = tx.Commit(queryCtx)
+    if err != nil {
+        return nil, fmt.Errorf("failed to commit transaction: %w", err)
+    }
+
+    return period, nil
+}
+
+// GetBalance retrieves the current balance for an account
+func (pl *PostgresLedger) GetBalance(ctx context.Context, accountID string) (money.Money, error) {
+    queryCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+    defer cancel()
+    
+    var balance pgtype.Numeric
+    var currencyCode string
+    err := pl.Pool.QueryRow(queryCtx, `
+        SELECT COALESCE(ab.balance, 0), a.currency_code
+        FROM account_balances ab
+        JOIN accounts a ON a.id = ab.account_id
+        WHERE ab.account_id = $1
+    `, accountID).Scan(&balance, &currencyCode)
+    
+    if err != nil {
+        if errors.Is(err, pgx.ErrNoRows) {
+            return money.Money{}, fmt.Errorf("%w: %s", ErrAccountNotFound, accountID)
+        }
+        return money.Money{}, fmt.Errorf("failed to get balance: %w", err)
+    }
+    
+    return money.FromNumeric(balance, currencyCode)
+}
+
+// ReconcileTransactions performs reconciliation and drift detection
+func (pl *PostgresLedger) ReconcileTransactions(ctx context.Context, accountID string, startTime, endTime time.Time) ([]BalanceSnapshot, error) {
+    queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+    defer cancel()
+    
+    rows, err := pl.Pool.Query(queryCtx, `
+        SELECT 
+            bs.id, bs.account_id, bs.transaction_id, bs.snapshot_time,
+            bs.balance_before, bs.balance_after, bs.balance_change,
+            bs.account_type, bs.currency_code, bs.entry_id, bs.entry_type,
+            bs.amount, bs.description, bs.reference_type, bs.reference_id, bs.created_at
+        FROM balance_snapshots bs
+        WHERE bs.account_id = $1
+        AND bs.snapshot_time >= $2
+        AND bs.snapshot_time <= $3
+        ORDER BY bs.snapshot_time ASC
+    `, accountID, startTime, endTime)
+    
+    if err != nil {
+        return nil, fmt.Errorf("failed to query balance snapshots: %w", err)
+    }
+    defer rows.Close()
+    
+    var snapshots []BalanceSnapshot
+    for rows.Next() {
+        var snapshot BalanceSnapshot
+        var before, after, change, amount pgtype.Numeric
+        err := rows.Scan(
+            &snapshot.ID, &snapshot.AccountID, &snapshot.TransactionID, &snapshot.SnapshotTime,
+            &before, &after, &change,
+            &snapshot.AccountType, &snapshot.CurrencyCode, &snapshot.EntryID, &snapshot.EntryType,
+            &amount, &snapshot.Description, &snapshot.ReferenceType, &snapshot.ReferenceID, &snapshot.CreatedAt,
+        )
+        if err != nil {
+            return nil, fmt.Errorf("failed to scan balance snapshot: %w", err)
+        }
+        
+        for _, f := range []struct {
+            dst *money.Money
+            src pgtype.Numeric
+        }{
+            {&snapshot.BalanceBefore, before},
+            {&snapshot.BalanceAfter, after},
+            {&snapshot.BalanceChange, change},
+            {&snapshot.Amount, amount},
+        } {
+            if *f.dst, err = money.FromNumeric(f.src, snapshot.CurrencyCode); err != nil {
+                return nil, fmt.Errorf("failed to read balance snapshot %s: %w", snapshot.ID, err)
+            }
+        }
+        snapshots = append(snapshots, snapshot)
+    }
+    
+    return snapshots, nil
+}
+
+// GetBalanceAt retrieves the balance of an account as of a point in time,
+// including entries posted at exactly that time
+func (pl *PostgresLedger) GetBalanceAt(ctx context.Context, accountID string, at time.Time) (money.Money, error) {
+    queryCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+    defer cancel()
+    
+    var balance pgtype.Numeric
+    var currencyCode string
+    err := pl.Pool.QueryRow(queryCtx, `
+        SELECT get_account_balance_at_time(a.id, $2), a.currency_code
+        FROM accounts a
+        WHERE a.id = $1
+    `, accountID, at.UTC()).Scan(&balance, &currencyCode)
+    
+    if err != nil {
+        if errors.Is(err, pgx.ErrNoRows) {
+            return money.Money{}, fmt.Errorf("%w: %s", ErrAccountNotFound, accountID)
+        }
+        return money.Money{}, fmt.Errorf("failed to get balance at %s: %w", at.UTC().Format(time.RFC3339), err)
+    }
+    
+    return money.FromNumeric(balance, currencyCode)
+}
+
+// GetStatement returns one page of entries posted to an account in [From, To)
+// with the running balance after each entry. Entries are ordered by posting
+// time, then entry ID, so pages are stable while new entries are posted.
+func (pl *PostgresLedger) GetStatement(ctx context.Context, req StatementRequest) (*Statement, error) {
+    queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+    defer cancel()
+    
+    from, to := req.From.UTC(), req.To.UTC()
+    
+    // Snapshots at or after To are excluded, so the closing balance is the
+    // opening balance plus every entry in the period
+    var opening, closing pgtype.Numeric
+    var currencyCode, accountNumber string
+    err := pl.Pool.QueryRow(queryCtx, `
+        SELECT
+            COALESCE((SELECT SUM(balance_change) FROM balance_snapshots
+                WHERE account_id = a.id AND snapshot_time < $2), 0),
+            COALESCE((SELECT SUM(balance_change) FROM balance_snapshots
+                WHERE account_id = a.id AND snapshot_time < $3), 0),
+            a.currency_code, a.account_number
+        FROM accounts a
+        WHERE a.id = $1
+    `, req.AccountID, from, to).Scan(&opening, &closing, &currencyCode, &accountNumber)
+    
+    if err != nil {
+        if errors.Is(err, pgx.ErrNoRows) {
+            return nil, fmt.Errorf("%w: %s", ErrAccountNotFound, req.AccountID)
+        }
+        return nil, fmt.Errorf("failed to get statement balances: %w", err)
+    }
+    
+    statement := &Statement{
+        AccountID:     req.AccountID,
+        AccountNumber: accountNumber,
+        CurrencyCode:  currencyCode,
+        From:          from.Format(time.RFC3339Nano),
+        To:            to.Format(time.RFC3339Nano),
+        Entries:       []StatementEntry{},
+    }
+    if statement.OpeningBalance, err = money.FromNumeric(opening, currencyCode); err != nil {
+        return nil, fmt.Errorf("failed to read opening balance: %w", err)
+    }
+    if statement.ClosingBalance, err = money.FromNumeric(closing, currencyCode); err != nil {
+        return nil, fmt.Errorf("failed to read closing balance: %w", err)
+    }
+    
+    // The running balance of a later page starts after the cursor entry
+    running := statement.OpeningBalance
+    var afterTime time.Time
+    var afterEntry *string
+    if req.Cursor != "" {
+        cursor, err := decodeStatementCursor(req.Cursor)
+        if err != nil {
+            return nil, err
+        }
+        afterTime, afterEntry = cursor.PostedAt.UTC(), &cursor.EntryID
+        
+        var before pgtype.Numeric
+        err = pl.Pool.QueryRow(queryCtx, `
+            SELECT COALESCE(SUM(balance_change), 0)
+            FROM balance_snapshots
+            WHERE account_id = $1
+            AND (snapshot_time, entry_id) <= ($2, $3::uuid)
+        `, req.AccountID, afterTime, afterEntry).Scan(&before)
+        if err != nil {
+            return nil, fmt.Errorf("failed to get statement cursor balance: %w", err)
+        }
+        if running, err = money.FromNumeric(before, currencyCode); err != nil {
+            return nil, fmt.Errorf("failed to read cursor balance: %w", err)
+        }
+    }
+    
+    rows, err := pl.Pool.Query(queryCtx, `
+        SELECT
+            entry_id, transaction_id, snapshot_time, entry_type, amount, balance_change,
+            description, COALESCE(reference_type, ''), COALESCE(reference_id, '')
+        FROM balance_snapshots
+        WHERE account_id = $1
+        AND snapshot_time >= $2
+        AND snapshot_time < $3
+        AND ($5::uuid IS NULL OR (snapshot_time, entry_id) > ($4, $5::uuid))
+        ORDER BY snapshot_time ASC, entry_id ASC
+        LIMIT $6
+    `, req.AccountID, from, to, afterTime, afterEntry, req.Limit+1)
+    
+    if err != nil {
+        return nil, fmt.Errorf("failed to query statement entries: %w", err)
+    }
+    defer rows.Close()
+    
+    var last statementCursor
+    for rows.Next() {
+        if len(statement.Entries) == req.Limit {
+            statement.NextCursor = encodeStatementCursor(last)
+            break
+        }
+        
+        var entry StatementEntry
+        var postedAt time.Time
+        var amount, change pgtype.Numeric
+        err := rows.Scan(
+            &entry.EntryID, &entry.TransactionID, &postedAt, &entry.EntryType, &amount, &change,
+            &entry.Description, &entry.ReferenceType, &entry.ReferenceID,
+        )
+        if err != nil {
+            return nil, fmt.Errorf("failed to scan statement entry: %w", err)
+        }
+        
+        if entry.Amount, err = money.FromNumeric(amount, currencyCode); err != nil {
+            return nil, fmt.Errorf("failed to read statement entry %s: %w", entry.EntryID, err)
+        }
+        if entry.BalanceChange, err = money.FromNumeric(change, currencyCode); err != nil {
+            return nil, fmt.Errorf("failed to read statement entry %s: %w", entry.EntryID, err)
+        }
+        if running, err = running.Add(entry.BalanceChange); err != nil {
+            return nil, fmt.Errorf("failed to compute running balance: %w", err)
+        }
+        entry.RunningBalance = running
+        entry.PostedAt = postedAt.UTC().Format(time.RFC3339Nano)
+        
+        statement.Entries = append(statement.Entries, entry)
+        last = statementCursor{PostedAt: postedAt, EntryID: entry.EntryID}
+    }
+    if err := rows.Err(); err != nil {
+        return nil, fmt.Errorf("failed to read statement entries: %w", err)
+    }
+    
+    return statement, nil
+}
+
+// batchColumns are the posting_batches columns read by scanPostingBatch
+const batchColumns = `
+    id, reference, status, line_count, posted_count, failed_count,
+    created_by, created_at::text, COALESCE(completed_at::text, '')`
+
+func scanPostingBatch(row pgx.Row) (*PostingBatch, error) {
+    var b PostingBatch
+    err := row.Scan(
+        &b.ID, &b.Reference, &b.Status, &b.LineCount, &b.PostedCount, &b.FailedCount,
+        &b.CreatedBy, &b.CreatedAt, &b.CompletedAt,
+    )
+    if err != nil {
+        return nil, err
+    }
+    return &b, nil
+}
+
+// batchChunkTimeout bounds the posting of one chunk of a batch
+const batchChunkTimeout = time.Minute
+
+// CreateBatch checks the accounts of every line of a prepared batch and stores
+// the batch and its lines. The lines are copied in, so storing even a large
+// batch takes a handful of round trips. allowSoftClosed records whether the
+// submitter may post into soft-closed periods, for RunBatch to honor later.
+func (pl *PostgresLedger) CreateBatch(ctx context.Context, req BatchRequest, allowSoftClosed bool) (*PostingBatch, error) {
+    queryCtx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+    defer cancel()
+
+    // IDs that are not UUIDs cannot name an account
+    var accountIDs []string
+    for _, id := range batchAccountIDs(req.Transactions) {
+        if _, err := uuid.Parse(id); err == nil {
+            accountIDs = append(accountIDs, id)
+        }
+    }
+
+    rows, err := pl.Pool.Query(queryCtx, `
+        SELECT id, status, currency_code
+        FROM accounts
+        WHERE id = ANY($1::uuid[])
+    `, accountIDs)
+    if err != nil {
+        return nil, fmt.Errorf("failed to look up batch accounts: %w", err)
+    }
+    accounts := make(map[string]batchAccount, len(accountIDs))
+    for rows.Next() {
+        var id string
+        var acct batchAccount
+        if err := rows.Scan(&id, &acct.status, &acct.currencyCode); err != nil {
+            rows.Close()
+            return nil, fmt.Errorf("failed to scan batch account: %w", err)
+        }
+        accounts[id] = acct
+    }
+    rows.Close()
+    if err := rows.Err(); err != nil {
+        return nil, fmt.Errorf("failed to look up batch accounts: %w", err)
+    }
+
+    var invalid []BatchLine
+    for i, txn := range req.Transactions {
+        if err := checkBatchLineAccounts(txn, accounts); err != nil {
+            invalid = append(invalid, BatchLine{LineNumber: i + 1, TransactionID: txn.TransactionID, Status: BatchLineFailed, Error: err.Error()})
+        }
+    }
+    if len(invalid) > 0 {
+        return nil, &BatchError{Lines: invalid}
+    }
+
+    tx, err := pl.Pool.BeginTx(queryCtx, pgx.TxOptions{})
+    if err != nil {
+        return nil, fmt.Errorf("failed to begin transaction: %w", err)
+    }
+    defer tx.Rollback(queryCtx)
+
+    batch, err := scanPostingBatch(tx.QueryRow(queryCtx, `
+        INSERT INTO posting_batches (reference, line_count, allow_soft_closed, created_by)
+        VALUES ($1, $2, $3, $4)
+        RETURNING `+batchColumns,
+        req.Reference, len(req.Transactions), allowSoftClosed, req.CreatedBy))
+    if err != nil {
+        var pgErr *pgconn.PgError
+        if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+            return nil, fmt.Errorf("%w: %s", ErrBatchExists, req.Reference)
+        }
+        return nil, fmt.Errorf("failed to create batch: %w", err)
+    }
+
+    batchID, err := uuid.Parse(batch.ID)
+    if err != nil {
+        return nil, fmt.Errorf("failed to read batch ID: %w", err)
+    }
+    _, err = tx.CopyFrom(queryCtx,
+        pgx.Identifier{"posting_batch_lines"},
+        []string{"batch_id", "line_number", "transaction_id", "payload"},
+        pgx.CopyFromSlice(len(req.Transactions), func(i int) ([]interface{}, error) {
+            txn := req.Transactions[i]
+            transactionID, err := uuid.Parse(txn.TransactionID)
+            if err != nil {
+                return nil, fmt.Errorf("%w: line %d: transaction_id is not a UUID", ErrInvalidBatch, i+1)
+            }
+            payload, err := json.Marshal(txn)
+            if err != nil {
+                return nil, fmt.Errorf("failed to encode line %d: %w", i+1, err)
+            }
+            return []interface{}{batchID, i + 1, transactionID, payload}, nil
+        }),
+    )
+    if err != nil {
+        return nil, fmt.Errorf("failed to store batch lines: %w", err)
+    }
+
+    if err := tx.Commit(queryCtx); err != nil {
+        return nil, fmt.Errorf("failed to commit transaction: %w", err)
+    }
+
+    return batch, nil
+}
+
+// RunBatch posts the pending lines of a batch, chunkSize at a time, and marks
+// the batch completed once none are left. Each chunk is posted in one database
+// transaction that also marks its lines posted, so a batch interrupted at any
+// point resumes where it stopped. A chunk that fails because of its lines is
+// posted again a line at a time, and the lines that still fail are marked
+// failed. Lines are claimed with SKIP LOCKED, so concurrent runners of a batch
+// share its lines.
+func (pl *PostgresLedger) RunBatch(ctx context.Context, batchID string, chunkSize int) (*PostingBatch, error) {
+    var status string
+    var allowSoftClosed bool
+    err := pl.Pool.QueryRow(ctx, `
+        SELECT status, allow_soft_closed FROM posting_batches WHERE id = $1
+    `, batchID).Scan(&status, &allowSoftClosed)
+    if err != nil {
+        if errors.Is(err, pgx.ErrNoRows) {
+            return nil, fmt.Errorf("%w: %s", ErrBatchNotFound, batchID)
+        }
+        return nil, fmt.Errorf("failed to get batch: %w", err)
+    }
+
+    if status == BatchStatusPosting {
+        if allowSoftClosed {
+            ctx = WithSoftClosedPostings(ctx)
+        }
+        for {
+            n, err := pl.postBatchChunk(ctx, batchID, chunkSize)
+            if err != nil {
+                return nil, err
+            }
+            if n == 0 {
+                break
+            }
+        }
+
+        // Lines claimed by another runner may still be in flight; that runner
+        // completes the batch
+        _, err = pl.Pool.Exec(ctx, `
+            UPDATE posting_batches
+            SET status = 'completed', completed_at = CURRENT_TIMESTAMP
+            WHERE id = $1 AND status = 'posting' AND posted_count + failed_count = line_count
+        `, batchID)
+        if err != nil {
+            return nil, fmt.Errorf("failed to complete batch: %w", err)
+        }
+    }
+
+    return pl.GetBatch(ctx, batchID)
+}
+
+// postBatchChunk posts up to limit pending lines of a batch and returns how
+// many it claimed
+func (pl *PostgresLedger) postBatchChunk(ctx context.Context, batchID string, limit int) (int, error) {
+    const maxRetries = 3
+
+    for attempt := 0; attempt < maxRetries; attempt++ {
+        lines, err := pl.postBatchChunkWithRetry(ctx, batchID, limit)
+        if err == nil {
+            return len(lines), nil
+        }
+
+        var pgErr *pgconn.PgError
+        if errors.As(err, &pgErr) && pgErr.Code == "40001" {
+            // Serialization failure, retry
+            if attempt == maxRetries-1 {
+                return 0, fmt.Errorf("failed to post batch chunk after %d retries due to serialization failure: %w", maxRetries, err)
+            }
+            time.Sleep(time.Duration(attempt+1) * 10 * time.Millisecond)
+            continue
+        }
+        if !batchLineFailure(err) {
+            return 0, fmt.Errorf("failed to post batch chunk: %w", err)
+        }
+
+        // Find the lines at fault by posting the chunk a line at a time
+        for _, line := range lines {
+            if err := pl.postBatchLine(ctx, batchID, line); err != nil {
+                return 0, err
+            }
+        }
+        return len(lines), nil
+    }
+
+    return 0, nil
+}
+
+// postBatchChunkWithRetry claims up to limit pending lines of a batch and posts
+// them in one SERIALIZABLE transaction. It returns the claimed line numbers
+// even when posting fails.
+func (pl *PostgresLedger) postBatchChunkWithRetry(ctx context.Context, batchID string, limit int) ([]int, error) {
+    queryCtx, cancel := context.WithTimeout(ctx, batchChunkTimeout)
+    defer cancel()
+
+    tx, err := pl.Pool.BeginTx(queryCtx, pgx.TxOptions{
+        IsoLevel:   pgx.Serializable,
+        AccessMode: pgx.ReadWrite,
+    })
+    if err != nil {
+        return nil, fmt.Errorf("failed to begin transaction: %w", err)
+    }
+    defer tx.Rollback(queryCtx)
+
+    rows, err := tx.Query(queryCtx, `
+        SELECT line_number, payload
+        FROM posting_batch_lines
+        WHERE batch_id = $1 AND status = 'pending'
+        ORDER BY line_number
+        LIMIT $2
+        FOR UPDATE SKIP LOCKED
+    `, batchID, limit)
+    if err != nil {
+        return nil, fmt.Errorf("failed to claim batch lines: %w", err)
+    }
+    var lines []int
+    var entries []*JournalEntry
+    var decodeErr error
+    for rows.Next() {
+        var line int
+        var payload []byte
+        if err := rows.Scan(&line, &payload); err != nil {
+            rows.Close()
+            return nil, fmt.Errorf("failed to scan batch line: %w", err)
+        }
+        lines = append(lines, line)
+        legs, err := batchLineEntries(payload)
+        if err != nil && decodeErr == nil {
+            decodeErr = fmt.Errorf("line %d: %w", line, err)
+        }
+        entries = append(entries, legs...)
+    }
+    rows.Close()
+    if err := rows.Err(); err != nil {
+        return nil, fmt.Errorf("failed to claim batch lines: %w", err)
+    }
+    if len(lines) == 0 {
+        return nil, nil
+    }
+    if decodeErr != nil {
+        return lines, decodeErr
+    }
+
+    if err := insertTransactionEntries(queryCtx, tx, entries); err != nil {
+        return lines, err
+    }
+
+    _, err = tx.Exec(queryCtx, `
+        UPDATE posting_batch_lines
+        SET status = 'posted', posted_at = CURRENT_TIMESTAMP
+        WHERE batch_id = $1 AND line_number = ANY($2::int[])
+    `, batchID, lines)
+    if err != nil {
+        return lines, fmt.Errorf("failed to mark batch lines posted: %w", err)
+    }
+    _, err = tx.Exec(queryCtx, `
+        UPDATE posting_batches SET posted_count = posted_count + $2 WHERE id = $1
+    `, batchID, len(lines))
+    if err != nil {
+        return lines, fmt.Errorf("failed to update batch: %w", err)
+    }
+
+    // Deferred double-entry triggers run here, against every leg of the chunk
+    if err := tx.Commit(queryCtx); err != nil {
+        return lines, fmt.Errorf("failed to commit transaction: %w", err)
+    }
+
+    return lines, nil
+}
+
+// postBatchLine posts one pending line of a batch, or marks it failed if the
+// line itself is at fault
+func (pl *PostgresLedger) postBatchLine(ctx context.Context, batchID string, line int) error {
+    const maxRetries = 3
+
+    for attempt := 0; attempt < maxRetries; attempt++ {
+        err := pl.postBatchLineWithRetry(ctx, batchID, line)
+        if err != nil {
+            var pgErr *pgconn.PgError
+            if errors.As(err, &pgErr) && pgErr.Code == "40001" {
+                // Serialization failure, retry
+                if attempt == maxRetries-1 {
+                    return fmt.Errorf("failed to post batch line %d after %d retries due to serialization failure: %w", line, maxRetries, err)
+                }
+                time.Sleep(time.Duration(attempt+1) * 10 * time.Millisecond)
+                continue
+            }
+            return fmt.Errorf("failed to post batch line %d: %w", line, err)
+        }
+        break
+    }
+
+    return nil
+}
+
+// postBatchLineWithRetry posts a line inside a savepoint so that, if the line
+// is at fault, it can still be marked failed in the same transaction
+func (pl *PostgresLedger) postBatchLineWithRetry(ctx context.Context, batchID string, line int) error {
+    queryCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+    defer cancel()
+
+    tx, err := pl.Pool.BeginTx(queryCtx, pgx.TxOptions{
+        IsoLevel:   pgx.Serializable,
+        AccessMode: pgx.ReadWrite,
+    })
+    if err != nil {
+        return fmt.Errorf("failed to begin transaction: %w", err)
+    }
+    defer tx.Rollback(queryCtx)
+
+    var payload []byte
+    err = tx.QueryRow(queryCtx, `
+        SELECT payload
+        FROM posting_batch_lines
+        WHERE batch_id = $1 AND line_number = $2 AND status = 'pending'
+        FOR UPDATE SKIP LOCKED
+    `, batchID, line).Scan(&payload)
+    if errors.Is(err, pgx.ErrNoRows) {
+        // Already settled, or claimed by another runner
+        return nil
+    }
+    if err != nil {
+        return fmt.Errorf("failed to claim batch line: %w", err)
+    }
+
+    postErr := postBatchLineEntries(queryCtx, tx, payload)
+    if postErr != nil && !batchLineFailure(postErr) {
+        return postErr
+    }
+
+    if postErr == nil {
+        _, err = tx.Exec(queryCtx, `
+            UPDATE posting_batch_lines
+            SET status = 'posted', posted_at = CURRENT_TIMESTAMP
+            WHERE batch_id = $1 AND line_number = $2
+        `, batchID, line)
+        if err == nil {
+            _, err = tx.Exec(queryCtx, `UPDATE posting_batches SET posted_count = posted_count + 1 WHERE id = $1`, batchID)
+        }
+    } else {
+        _, err = tx.Exec(queryCtx, `
+            UPDATE posting_batch_lines
+            SET status = 'failed', error = $3
+            WHERE batch_id = $1 AND line_number = $2
+        `, batchID, line, postErr.Error())
+        if err == nil {
+            _, err = tx.Exec(queryCtx, `UPDATE posting_batches SET failed_count = failed_count + 1 WHERE id = $1`, batchID)
+        }
+    }
+    if err != nil {
+        return fmt.Errorf("failed to record batch line: %w", err)
+    }
+
+    if err := tx.Commit(queryCtx); err != nil {
+        return fmt.Errorf("failed to commit transaction: %w", err)
+    }
+
+    return nil
+}
+
+// postBatchLineEntries posts the legs of a batch line within a savepoint of tx,
+// running the deferred double-entry checks before the savepoint is released.
+// On error the savepoint is rolled back and tx can still be used.
+func postBatchLineEntries(ctx context.Context, tx pgx.Tx, payload []byte) error {
+    entries, err := batchLineEntries(payload)
+    if err != nil {
+        return err
+    }
+
+    sp, err := tx.Begin(ctx)
+    if err != nil {
+        return fmt.Errorf("failed to create savepoint: %w", err)
+    }
+    defer sp.Rollback(ctx)
+
+    if err := insertTransactionEntries(ctx, sp, entries); err != nil {
+        return err
+    }
+    if _, err := sp.Exec(ctx, `SET CONSTRAINTS ALL IMMEDIATE`); err != nil {
+        return err
+    }
+    return sp.Commit(ctx)
+}
+
+// batchLineEntries decodes the legs of a stored batch line
+func batchLineEntries(payload []byte) ([]*JournalEntry, error) {
+    var txn Transaction
+    if err := json.Unmarshal(payload, &txn); err != nil {
+        return nil, fmt.Errorf("%w: undecodable line: %v", ErrInvalidBatch, err)
+    }
+    entries := make([]*JournalEntry, len(txn.Entries))
+    for i := range txn.Entries {
+        entries[i] = &txn.Entries[i]
+    }
+    return entries, nil
+}
+
+// UnfinishedBatches lists the batches with lines still to post, oldest first
+func (pl *PostgresLedger) UnfinishedBatches(ctx context.Context) ([]string, error) {
+    queryCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+    defer cancel()
+
+    rows, err := pl.Pool.Query(queryCtx, `
+        SELECT id FROM posting_batches WHERE status = 'posting' ORDER BY created_at
+    `)
+    if err != nil {
+        return nil, fmt.Errorf("failed to list unfinished batches: %w", err)
+    }
+    defer rows.Close()
+
+    var ids []string
+    for rows.Next() {
+        var id string
+        if err := rows.Scan(&id); err != nil {
+            return nil, fmt.Errorf("failed to scan batch: %w", err)
+        }
+        ids = append(ids, id)
+    }
+    if err := rows.Err(); err != nil {
+        return nil, fmt.Errorf("failed to list unfinished batches: %w", err)
+    }
+
+    return ids, nil
+}
+
+// GetBatch retrieves a posting batch
+func (pl *PostgresLedger) GetBatch(ctx context.Context, batchID string) (*PostingBatch, error) {
+    queryCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+    defer cancel()
+
+    batch, err := scanPostingBatch(pl.Pool.QueryRow(queryCtx, `SELECT `+batchColumns+` FROM posting_batches WHERE id = $1`, batchID))
+    if err != nil {
+        if errors.Is(err, pgx.ErrNoRows) {
+            return nil, fmt.Errorf("%w: %s", ErrBatchNotFound, batchID)
+        }
+        return nil, fmt.Errorf("failed to get batch: %w", err)
+    }
+
+    return batch, nil
+}
+
+// ListBatchLines lists a page of the lines of a batch in line order
+func (pl *PostgresLedger) ListBatchLines(ctx context.Context, filter BatchLineFilter) ([]BatchLine, error) {
+    queryCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+    defer cancel()
+
+    var exists bool
+    if err := pl.Pool.QueryRow(queryCtx, `SELECT EXISTS (SELECT 1 FROM posting_batches WHERE id = $1)`, filter.BatchID).Scan(&exists); err != nil {
+        return nil, fmt.Errorf("failed to get batch: %w", err)
+    }
+    if !exists {
+        return nil, fmt.Errorf("%w: %s", ErrBatchNotFound, filter.BatchID)
+    }
+
+    rows, err := pl.Pool.Query(queryCtx, `
+        SELECT line_number, transaction_id::text, status, COALESCE(error, ''), COALESCE(posted_at::text, '')
+        FROM posting_batch_lines
+        WHERE batch_id = $1 AND ($2 = '' OR status = $2)
+        ORDER BY line_number
+        LIMIT $3 OFFSET $4
+    `, filter.BatchID, filter.Status, filter.Limit, filter.Offset)
+    if err != nil {
+        return nil, fmt.Errorf("failed to list batch lines: %w", err)
+    }
+    defer rows.Close()
+
+    lines := []BatchLine{}
+    for rows.Next() {
+        var l BatchLine
+        if err := rows.Scan(&l.LineNumber, &l.TransactionID, &l.Status, &l.Error, &l.PostedAt); err != nil {
+            return nil, fmt.Errorf("failed to scan batch line: %w", err)
+        }
+        lines = append(lines, l)
+    }
+    if err := rows.Err(); err != nil {
+        return nil, fmt.Errorf("failed to list batch lines: %w", err)
+    }
+
+    return lines, nil
+}
+
+// ValidateBalanceConsistency checks for reconciliation drift
+func (pl *PostgresLedger) ValidateBalanceConsistency(ctx context.Context) ([]map[string]interface{}, error) {
+    queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+    defer cancel()
+    
+    rows, err := pl.Pool.Query(queryCtx, `
+        SELECT 
+            v.account_id, v.account_number, v.expected_balance, v.actual_balance, v.is_consistent, a.currency_code
+        FROM validate_balance_consistency() v
+        JOIN accounts a ON a.id = v.account_id
+    `)
+    
+    if err != nil {
+        return nil, fmt.Errorf("failed to validate balance consistency: %w", err)
+    }
+    defer rows.Close()
+    
+    var results []map[string]interface{}
+    for rows.Next() {
+        var accountID, accountNumber, currencyCode string
+        var expectedNum, actualNum pgtype.Numeric
+        var isConsistent bool
+        
+        err := rows.Scan(&accountID, &accountNumber, &expectedNum, &actualNum, &isConsistent, &currencyCode)
+        if err != nil {
+            return nil, fmt.Errorf("failed to scan consistency result: %w", err)
+        }
+        
+        expected, err := money.FromNumeric(expectedNum, currencyCode)
+        if err != nil {
+            return nil, fmt.Errorf("failed to read expected balance for %s: %w", accountNumber, err)
+        }
+        actual, err := money.FromNumeric(actualNum, currencyCode)
+        if err != nil {
+            return nil, fmt.Errorf("failed to read actual balance for %s: %w", accountNumber, err)
+        }
+        drift, err := actual.Sub(expected)
+        if err != nil {
+            return nil, fmt.Errorf("failed to compute drift for %s: %w", accountNumber, err)
         }
         
         result := map[string]interface{}{
@@ -395,7 +3161,7 @@ func (pl *PostgresLedger) ValidateBalanceConsistency(ctx context.Context) ([]map
             "expected_balance":  expected,
             "actual_balance":    actual,
             "is_consistent":     isConsistent,
-            "drift_amount":      actual - expected,
+            "drift_amount":      drift,
         }
         results = append(results, result)
     }
@@ -0,0 +1,137 @@
+package ledger
+
+import (
+	"fmt"
+
+	"github.com/example/pci-infra/internal/money"
+)
+
+// ReferenceTypeReversal marks the legs of a reversing transaction; their
+// ReferenceID is the ID of the transaction they reverse
+const ReferenceTypeReversal = "reversal"
+
+// ReverseRequest represents the request to undo a posted transaction by posting
+// its mirror image. A zero Amount reverses whatever is left of the original; a
+// smaller amount reverses part of it and is only supported for two-leg
+// transactions.
+type ReverseRequest struct {
+	TransactionID string      `json:"transaction_id"`
+	ReversalID    string      `json:"reversal_id"`
+	Amount        money.Money `json:"amount"`
+	Reason        string      `json:"reason"`
+	ReversedBy    string      `json:"reversed_by"`
+	ApprovedBy    string      `json:"approved_by"`
+}
+
+// Reversal is a posted transaction that reverses all or part of another
+type Reversal struct {
+	ID                    string `json:"id"`
+	TransactionID         string `json:"transaction_id"`
+	OriginalTransactionID string `json:"original_transaction_id"`
+	Full                  bool   `json:"full"`
+	// Amount is unset when a multi-leg transaction is reversed in full
+	Amount     *money.Money   `json:"amount,omitempty"`
+	Reason     string         `json:"reason"`
+	ReversedBy string         `json:"reversed_by"`
+	ApprovedBy string         `json:"approved_by"`
+	CreatedAt  string         `json:"created_at"`
+	Entries    []JournalEntry `json:"entries"`
+}
+
+// TransactionDetail is a posted transaction together with its reversals
+type TransactionDetail struct {
+	TransactionID string         `json:"transaction_id"`
+	Entries       []JournalEntry `json:"entries"`
+	// ReversalOf is set when this transaction is itself a reversal
+	ReversalOf    string     `json:"reversal_of,omitempty"`
+	Reversals     []Reversal `json:"reversals"`
+	FullyReversed bool       `json:"fully_reversed"`
+}
+
+// reversalPlan is the set of legs to post for a reversal
+type reversalPlan struct {
+	entries []*JournalEntry
+	amount  *money.Money
+	full    bool
+}
+
+// planReversal builds the mirror-image legs reversing original, given the
+// reversals already posted against it. reversalOf is the transaction original
+// itself reverses, as recorded in transaction_reversals, or "".
+func planReversal(original []JournalEntry, reversalOf string, prior []Reversal, req ReverseRequest) (*reversalPlan, error) {
+	if len(original) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrTransactionNotFound, req.TransactionID)
+	}
+	if reversalOf != "" {
+		return nil, fmt.Errorf("%w: %s is a reversal and cannot itself be reversed", ErrInvalidRequest, req.TransactionID)
+	}
+	for _, r := range prior {
+		if r.Full {
+			return nil, fmt.Errorf("%w: %s by %s", ErrAlreadyReversed, req.TransactionID, r.TransactionID)
+		}
+	}
+
+	plan := &reversalPlan{full: true}
+	twoLeg := len(original) == 2 &&
+		original[0].EntryType != original[1].EntryType &&
+		original[0].Amount.Currency() == original[1].Amount.Currency()
+
+	if twoLeg {
+		remaining := original[0].Amount
+		for _, r := range prior {
+			var err error
+			if remaining, err = remaining.Sub(*r.Amount); err != nil {
+				return nil, fmt.Errorf("failed to total prior reversals: %w", err)
+			}
+		}
+		if !remaining.IsPositive() {
+			return nil, fmt.Errorf("%w: %s", ErrAlreadyReversed, req.TransactionID)
+		}
+
+		amount := req.Amount
+		if amount.IsZero() {
+			amount = remaining
+		}
+		if amount.Currency() != remaining.Currency() {
+			return nil, fmt.Errorf("%w: reversal %s vs transaction %s", ErrCurrencyMismatch, amount.Currency(), remaining.Currency())
+		}
+		if remaining.LessThan(amount) {
+			return nil, fmt.Errorf("%w: reversal amount %s exceeds unreversed %s", ErrInvalidRequest, amount, remaining)
+		}
+		plan.amount = &amount
+		plan.full = amount.Equal(remaining)
+	} else if !req.Amount.IsZero() {
+		return nil, fmt.Errorf("%w: partial reversal requires a two-leg transaction", ErrInvalidRequest)
+	}
+
+	for i, e := range original {
+		entryType := "credit"
+		if e.EntryType == "credit" {
+			entryType = "debit"
+		}
+		amount := e.Amount
+		if plan.amount != nil {
+			amount = *plan.amount
+		}
+
+		plan.entries = append(plan.entries, &JournalEntry{
+			EntryNumber:   fmt.Sprintf("JE-%s-%d", req.ReversalID, i+1),
+			TransactionID: req.ReversalID,
+			EntryType:     entryType,
+			AccountID:     e.AccountID,
+			Amount:        amount,
+			Description:   fmt.Sprintf("Reversal: %s", req.Reason),
+			ReferenceType: ReferenceTypeReversal,
+			ReferenceID:   req.TransactionID,
+			CurrencyCode:  amount.Currency(),
+			CreatedBy:     req.ReversedBy,
+			Metadata: map[string]interface{}{
+				"original_entry_id": e.ID,
+				"reason":            req.Reason,
+				"approved_by":       req.ApprovedBy,
+			},
+		})
+	}
+
+	return plan, nil
+}
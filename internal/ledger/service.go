@@ -5,9 +5,14 @@ import (
     "database/sql"
     "errors"
     "fmt"
+    "sort"
     "time"
 
     "github.com/google/uuid"
+    "github.com/jackc/pgx/v5"
+    "github.com/jackc/pgx/v5/pgtype"
+
+    "github.com/example/pci-infra/internal/money"
 )
 
 // Transaction represents a complete accounting transaction with multiple entries
@@ -19,6 +24,8 @@ type Transaction struct {
     ReferenceType string       `json:"reference_type"`
     ReferenceID   string       `json:"reference_id"`
     CurrencyCode  string       `json:"currency_code"`
+    // EffectiveDate back-dates every leg to a day in DateLayout; empty means today
+    EffectiveDate string       `json:"effective_date"`
     CreatedBy     string       `json:"created_by"`
     CreatedAt     string       `json:"created_at"`
     Metadata      map[string]interface{} `json:"metadata"`
@@ -27,6 +34,18 @@ type Transaction struct {
 // LedgerService provides the high-level API for double-entry bookkeeping
 type LedgerService struct {
     postgres *PostgresLedger
+
+    // PendingTTL is how long authorizations reserve funds unless a request
+    // sets its own TTL; zero means DefaultPendingTTL
+    PendingTTL time.Duration
+
+    // BatchChunkSize is how many batch lines are posted per database
+    // transaction; zero means DefaultBatchChunkSize
+    BatchChunkSize int
+
+    // MaxFXSpread is how far a quoted FX rate may be from the market rate;
+    // zero means DefaultMaxFXSpread
+    MaxFXSpread money.Rate
 }
 
 // NewLedgerService creates a new ledger service
@@ -40,16 +59,16 @@ func NewLedgerService(postgres *PostgresLedger) *LedgerService {
 func (ls *LedgerService) CreateAccount(ctx context.Context, req CreateAccountRequest) (*Account, error) {
     // Validate account type
     if !isValidAccountType(req.AccountType) {
-        return nil, fmt.Errorf("invalid account type: %s", req.AccountType)
+        return nil, fmt.Errorf("%w: invalid account type: %s", ErrInvalidRequest, req.AccountType)
     }
     
     // Validate currency code
     if len(req.CurrencyCode) != 3 {
-        return nil, fmt.Errorf("currency code must be 3 characters")
+        return nil, fmt.Errorf("%w: currency code must be 3 characters", ErrInvalidRequest)
     }
     
     // Create the account
-    account, err := ls.postgres.CreateAccount(ctx, req.AccountNumber, req.AccountType, req.Name, req.CurrencyCode, req.CreatedBy, req.Metadata)
+    account, err := ls.postgres.CreateAccountUnder(ctx, req.ParentID, req.AccountNumber, req.AccountType, req.Name, req.CurrencyCode, req.CreatedBy, req.Owner, req.Metadata)
     if err != nil {
         return nil, fmt.Errorf("failed to create account: %w", err)
     }
@@ -57,13 +76,18 @@ func (ls *LedgerService) CreateAccount(ctx context.Context, req CreateAccountReq
     return account, nil
 }
 
-// CreateAccountRequest represents the request to create an account
+// CreateAccountRequest represents the request to create an account. ParentID
+// places the account beneath another in the chart of accounts.
 type CreateAccountRequest struct {
+    ParentID      string                 `json:"parent_id"`
     AccountNumber string                 `json:"account_number"`
     AccountType   string                 `json:"account_type"`
     Name          string                 `json:"name"`
     CurrencyCode  string                 `json:"currency_code"`
     CreatedBy     string                 `json:"created_by"`
+    // Owner is the API client the account belongs to. Its webhook
+    // subscriptions receive the account's events.
+    Owner         string                 `json:"-"`
     Metadata      map[string]interface{} `json:"metadata"`
 }
 
@@ -75,9 +99,9 @@ func (ls *LedgerService) Credit(ctx context.Context, req CreditRequest) error {
 // CreditRequest represents the request to post a credit.
 type CreditRequest struct {
     TransactionRequest
-    AccountID   string  `json:"account_id"`
-    Amount      float64 `json:"amount"`
-    Description string  `json:"description"`
+    AccountID   string      `json:"account_id"`
+    Amount      money.Money `json:"amount"`
+    Description string      `json:"description"`
 }
 
 // Debit posts a debit entry to an account.
@@ -88,9 +112,9 @@ func (ls *LedgerService) Debit(ctx context.Context, req DebitRequest) error {
 // DebitRequest represents the request to post a debit.
 type DebitRequest struct {
     TransactionRequest
-    AccountID   string  `json:"account_id"`
-    Amount      float64 `json:"amount"`
-    Description string  `json:"description"`
+    AccountID   string      `json:"account_id"`
+    Amount      money.Money `json:"amount"`
+    Description string      `json:"description"`
 }
 
 // TransactionRequest contains common fields for transactions.
@@ -104,17 +128,21 @@ type TransactionRequest struct {
     Metadata      map[string]interface{} `json:"metadata"`
 }
 
-func (ls *LedgerService) postTransaction(ctx context.Context, req TransactionRequest, accountID string, amount float64, description string, entryType string) error {
+func (ls *LedgerService) postTransaction(ctx context.Context, req TransactionRequest, accountID string, amount money.Money, description string, entryType string) error {
     if req.TransactionID == "" {
         req.TransactionID = uuid.New().String()
     }
 
     if accountID == "" {
-        return fmt.Errorf("account ID is required")
+        return fmt.Errorf("%w: account ID is required", ErrInvalidRequest)
+    }
+
+    if !amount.IsPositive() {
+        return fmt.Errorf("%w: amount must be positive", ErrInvalidRequest)
     }
 
-    if amount <= 0 {
-        return fmt.Errorf("amount must be positive")
+    if req.CurrencyCode != "" && req.CurrencyCode != amount.Currency() {
+        return fmt.Errorf("%w: request %s vs amount %s", ErrCurrencyMismatch, req.CurrencyCode, amount.Currency())
     }
 
     if description == "" {
@@ -137,7 +165,7 @@ func (ls *LedgerService) postTransaction(ctx context.Context, req TransactionReq
         Description:   description,
         ReferenceType: req.ReferenceType,
         ReferenceID:   req.ReferenceID,
-        CurrencyCode:  req.CurrencyCode,
+        CurrencyCode:  amount.Currency(),
         CreatedBy:     req.CreatedBy,
         Metadata:      req.Metadata,
     }
@@ -146,26 +174,178 @@ func (ls *LedgerService) postTransaction(ctx context.Context, req TransactionReq
 }
 
 // GetBalance retrieves the current balance for an account
-func (ls *LedgerService) GetBalance(ctx context.Context, accountID string) (float64, error) {
+func (ls *LedgerService) GetBalance(ctx context.Context, accountID string) (money.Money, error) {
     if accountID == "" {
-        return 0, fmt.Errorf("account ID is required")
+        return money.Money{}, fmt.Errorf("%w: account ID is required", ErrInvalidRequest)
     }
     
     return ls.postgres.GetBalance(ctx, accountID)
 }
 
+// AuthorizePending reserves funds on the from account for a later capture
+func (ls *LedgerService) AuthorizePending(ctx context.Context, req AuthorizeRequest) (*PendingTransaction, error) {
+    if req.FromAccountID == "" || req.ToAccountID == "" {
+        return nil, fmt.Errorf("%w: from_account_id and to_account_id are required", ErrInvalidRequest)
+    }
+    
+    if req.FromAccountID == req.ToAccountID {
+        return nil, fmt.Errorf("%w: from_account_id and to_account_id must be different", ErrInvalidRequest)
+    }
+    
+    if !req.Amount.IsPositive() {
+        return nil, fmt.Errorf("%w: amount must be positive", ErrInvalidRequest)
+    }
+    
+    if req.Description == "" {
+        return nil, fmt.Errorf("%w: description is required", ErrInvalidRequest)
+    }
+    
+    if req.CreatedBy == "" {
+        return nil, fmt.Errorf("%w: created_by is required", ErrInvalidRequest)
+    }
+    
+    if req.TTL < 0 {
+        return nil, fmt.Errorf("%w: ttl must be positive", ErrInvalidRequest)
+    }
+    
+    ttl := req.TTL
+    if ttl == 0 {
+        ttl = ls.PendingTTL
+    }
+    if ttl == 0 {
+        ttl = DefaultPendingTTL
+    }
+    
+    p := &PendingTransaction{
+        TransactionID: req.TransactionID,
+        FromAccountID: req.FromAccountID,
+        ToAccountID:   req.ToAccountID,
+        Amount:        req.Amount,
+        Description:   req.Description,
+        ReferenceType: req.ReferenceType,
+        ReferenceID:   req.ReferenceID,
+        Metadata:      req.Metadata,
+        CreatedBy:     req.CreatedBy,
+    }
+    if p.TransactionID == "" {
+        p.TransactionID = uuid.New().String()
+    }
+    
+    if err := ls.postgres.AuthorizePendingTransaction(ctx, p, ttl); err != nil {
+        return nil, err
+    }
+    
+    return p, nil
+}
+
+// CapturePending posts a pending transaction for all or part of its amount
+func (ls *LedgerService) CapturePending(ctx context.Context, req CaptureRequest) (*PendingTransaction, error) {
+    if req.PendingID == "" {
+        return nil, fmt.Errorf("%w: pending transaction ID is required", ErrInvalidRequest)
+    }
+    
+    if req.CapturedBy == "" {
+        return nil, fmt.Errorf("%w: captured_by is required", ErrInvalidRequest)
+    }
+    
+    if !req.Amount.IsZero() && !req.Amount.IsPositive() {
+        return nil, fmt.Errorf("%w: amount must be positive", ErrInvalidRequest)
+    }
+    
+    return ls.postgres.CapturePendingTransaction(ctx, req)
+}
+
+// VoidPending releases a pending transaction without posting it
+func (ls *LedgerService) VoidPending(ctx context.Context, req VoidRequest) (*PendingTransaction, error) {
+    if req.PendingID == "" {
+        return nil, fmt.Errorf("%w: pending transaction ID is required", ErrInvalidRequest)
+    }
+    
+    if req.VoidedBy == "" {
+        return nil, fmt.Errorf("%w: voided_by is required", ErrInvalidRequest)
+    }
+    
+    return ls.postgres.VoidPendingTransaction(ctx, req)
+}
+
+// GetPendingTransaction retrieves a pending transaction by ID
+func (ls *LedgerService) GetPendingTransaction(ctx context.Context, id string) (*PendingTransaction, error) {
+    if id == "" {
+        return nil, fmt.Errorf("%w: pending transaction ID is required", ErrInvalidRequest)
+    }
+    
+    return ls.postgres.GetPendingTransaction(ctx, id)
+}
+
+// ExpirePendingTransactions records the expiry of pending transactions past their TTL
+func (ls *LedgerService) ExpirePendingTransactions(ctx context.Context) (int64, error) {
+    return ls.postgres.ExpirePendingTransactions(ctx)
+}
+
+// GetAccountBalances retrieves the posted, pending, held and available balances of an account
+func (ls *LedgerService) GetAccountBalances(ctx context.Context, accountID string) (*AccountBalances, error) {
+    if accountID == "" {
+        return nil, fmt.Errorf("%w: account ID is required", ErrInvalidRequest)
+    }
+    
+    return ls.postgres.GetAccountBalances(ctx, accountID)
+}
+
+// GetBalanceAt retrieves the balance of an account as of a point in time
+func (ls *LedgerService) GetBalanceAt(ctx context.Context, accountID string, at time.Time) (money.Money, error) {
+    if accountID == "" {
+        return money.Money{}, fmt.Errorf("%w: account ID is required", ErrInvalidRequest)
+    }
+    
+    if at.IsZero() {
+        return money.Money{}, fmt.Errorf("%w: time is required", ErrInvalidRequest)
+    }
+    
+    return ls.postgres.GetBalanceAt(ctx, accountID, at)
+}
+
+// GetStatement returns an account statement for a date range, one page at a time
+func (ls *LedgerService) GetStatement(ctx context.Context, req StatementRequest) (*Statement, error) {
+    if req.AccountID == "" {
+        return nil, fmt.Errorf("%w: account ID is required", ErrInvalidRequest)
+    }
+    
+    if req.From.IsZero() || req.To.IsZero() {
+        return nil, fmt.Errorf("%w: from and to are required", ErrInvalidRequest)
+    }
+    
+    if !req.From.Before(req.To) {
+        return nil, fmt.Errorf("%w: from must be before to", ErrInvalidRequest)
+    }
+    
+    if req.Limit == 0 {
+        req.Limit = defaultStatementLimit
+    }
+    if req.Limit < 0 || req.Limit > maxStatementLimit {
+        return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidRequest, maxStatementLimit)
+    }
+    
+    if req.Cursor != "" {
+        if _, err := decodeStatementCursor(req.Cursor); err != nil {
+            return nil, err
+        }
+    }
+    
+    return ls.postgres.GetStatement(ctx, req)
+}
+
 // Reconcile performs account reconciliation for a time period
 func (ls *LedgerService) Reconcile(ctx context.Context, req ReconcileRequest) ([]BalanceSnapshot, error) {
     if req.AccountID == "" {
-        return nil, fmt.Errorf("account ID is required")
+        return nil, fmt.Errorf("%w: account ID is required", ErrInvalidRequest)
     }
     
     if req.StartTime.IsZero() || req.EndTime.IsZero() {
-        return nil, fmt.Errorf("start time and end time are required")
+        return nil, fmt.Errorf("%w: start time and end time are required", ErrInvalidRequest)
     }
     
     if req.StartTime.After(req.EndTime) {
-        return nil, fmt.Errorf("start time must be before end time")
+        return nil, fmt.Errorf("%w: start time must be before end time", ErrInvalidRequest)
     }
     
     return ls.postgres.ReconcileTransactions(ctx, req.AccountID, req.StartTime, req.EndTime)
@@ -206,9 +386,10 @@ func (ls *LedgerService) Transfer(ctx context.Context, req TransferRequest) erro
 
 // TransferRequest represents the request to transfer funds between accounts
 type TransferRequest struct {
+    TransactionID string                 `json:"transaction_id"`
     FromAccountID string                 `json:"from_account_id"`
     ToAccountID   string                 `json:"to_account_id"`
-    Amount        float64                `json:"amount"`
+    Amount        money.Money            `json:"amount"`
     Description   string                 `json:"description"`
     CurrencyCode  string                 `json:"currency_code"`
     CreatedBy     string                 `json:"created_by"`
@@ -221,23 +402,26 @@ type TransferRequest struct {
 func (ls *LedgerService) transferWithRetry(ctx context.Context, req TransferRequest) error {
     // Validate request
     if req.FromAccountID == "" || req.ToAccountID == "" {
-        return fmt.Errorf("from_account_id and to_account_id are required")
+        return fmt.Errorf("%w: from_account_id and to_account_id are required", ErrInvalidRequest)
     }
     
     if req.FromAccountID == req.ToAccountID {
-        return fmt.Errorf("from_account_id and to_account_id must be different")
+        return fmt.Errorf("%w: from_account_id and to_account_id must be different", ErrInvalidRequest)
     }
     
-    if req.Amount <= 0 {
-        return fmt.Errorf("amount must be positive")
+    if !req.Amount.IsPositive() {
+        return fmt.Errorf("%w: amount must be positive", ErrInvalidRequest)
     }
     
     // Generate transaction ID if not provided
-    transactionID := uuid.New().String()
+    transactionID := req.TransactionID
+    if transactionID == "" {
+        transactionID = uuid.New().String()
+    }
     
     // Generate entry numbers
-    debitEntryNumber := fmt.Sprintf("JE-%s-debit", transactionID[:8])
-    creditEntryNumber := fmt.Sprintf("JE-%s-credit", transactionID[:8])
+    debitEntryNumber := fmt.Sprintf("JE-%s-debit", transactionID)
+    creditEntryNumber := fmt.Sprintf("JE-%s-credit", transactionID)
     
     // Get account information for validation
     fromAccount, err := ls.postgres.GetAccount(ctx, req.FromAccountID)
@@ -252,17 +436,23 @@ func (ls *LedgerService) transferWithRetry(ctx context.Context, req TransferRequ
     
     // Check currency compatibility
     if fromAccount.CurrencyCode != toAccount.CurrencyCode {
-        return fmt.Errorf("currency mismatch: %s vs %s", fromAccount.CurrencyCode, toAccount.CurrencyCode)
+        return fmt.Errorf("%w: %s vs %s", ErrCurrencyMismatch, fromAccount.CurrencyCode, toAccount.CurrencyCode)
     }
     
-    // Check sufficient balance for debit account
-    fromBalance, err := ls.postgres.GetBalance(ctx, req.FromAccountID)
-    if err != nil {
-        return fmt.Errorf("failed to get from account balance: %w", err)
+    if req.Amount.Currency() != fromAccount.CurrencyCode {
+        return fmt.Errorf("%w: transfer amount %s vs account %s", ErrCurrencyMismatch, req.Amount.Currency(), fromAccount.CurrencyCode)
     }
     
-    if fromAccount.AccountType == "asset" && fromBalance < req.Amount {
-        return fmt.Errorf("insufficient balance in account %s: have %.8f, need %.8f", req.FromAccountID, fromBalance, req.Amount)
+    // Check sufficient balance for debit account. The posting checks again
+    // under the account lock; this fails early with the available balance.
+    if fromAccount.AccountType == "asset" {
+        balances, err := ls.postgres.GetAccountBalances(ctx, req.FromAccountID)
+        if err != nil {
+            return fmt.Errorf("failed to get from account balance: %w", err)
+        }
+        if balances.Available.LessThan(req.Amount) {
+            return fmt.Errorf("%w: account %s has %s available, needs %s", ErrInsufficientFunds, req.FromAccountID, balances.Available, req.Amount)
+        }
     }
     
     // Create debit entry (money out of from account)
@@ -276,7 +466,7 @@ func (ls *LedgerService) transferWithRetry(ctx context.Context, req TransferRequ
         Description:   fmt.Sprintf("Transfer to %s: %s", req.ToAccountID, req.Description),
         ReferenceType: req.ReferenceType,
         ReferenceID:   req.ReferenceID,
-        CurrencyCode:  req.CurrencyCode,
+        CurrencyCode:  req.Amount.Currency(),
         CreatedBy:     req.CreatedBy,
         Metadata:      req.Metadata,
     }
@@ -292,29 +482,607 @@ func (ls *LedgerService) transferWithRetry(ctx context.Context, req TransferRequ
         Description:   fmt.Sprintf("Transfer from %s: %s", req.FromAccountID, req.Description),
         ReferenceType: req.ReferenceType,
         ReferenceID:   req.ReferenceID,
-        CurrencyCode:  req.CurrencyCode,
+        CurrencyCode:  req.Amount.Currency(),
         CreatedBy:     req.CreatedBy,
         Metadata:      req.Metadata,
     }
     
-    // Post both entries
-    err = ls.postgres.PostJournalEntry(ctx, debitEntry)
+    // Post both legs atomically so the deferred double-entry check sees a balanced pair
+    err = ls.postgres.PostTransaction(ctx, []*JournalEntry{debitEntry, creditEntry})
     if err != nil {
-        return fmt.Errorf("failed to post debit entry: %w", err)
+        return fmt.Errorf("failed to post transfer entries: %w", err)
     }
     
-    err = ls.postgres.PostJournalEntry(ctx, creditEntry)
+    return nil
+}
+
+// PostTransaction posts a balanced set of journal entry legs under one transaction ID.
+// Legs inherit description, reference, creator and metadata from the transaction when unset.
+func (ls *LedgerService) PostTransaction(ctx context.Context, txn Transaction) (*Transaction, error) {
+    entries, err := prepareTransaction(&txn, time.Now())
+    if err != nil {
+        return nil, err
+    }
+
+    if err := ls.postgres.PostTransaction(ctx, entries); err != nil {
+        return nil, err
+    }
+
+    return &txn, nil
+}
+
+// PostTransactionTx posts a transaction like PostTransaction, but within tx,
+// so its legs commit only if the caller's transaction does
+func (ls *LedgerService) PostTransactionTx(ctx context.Context, tx pgx.Tx, txn Transaction) (*Transaction, error) {
+    entries, err := prepareTransaction(&txn, time.Now())
     if err != nil {
-        return fmt.Errorf("failed to post credit entry: %w", err)
+        return nil, err
+    }
+
+    if err := ls.postgres.PostTransactionTx(ctx, tx, entries); err != nil {
+        return nil, err
+    }
+
+    return &txn, nil
+}
+
+// prepareTransaction assigns a transaction its ID and entry numbers, fills in
+// its legs from the transaction and validates it. The returned legs point
+// into txn.Entries.
+func prepareTransaction(txn *Transaction, now time.Time) ([]*JournalEntry, error) {
+    if txn.TransactionID == "" {
+        txn.TransactionID = uuid.New().String()
+    }
+
+    if txn.CreatedBy == "" {
+        return nil, fmt.Errorf("%w: created_by is required", ErrInvalidEntry)
+    }
+
+    if txn.EffectiveDate != "" {
+        if err := validateEffectiveDate(txn.EffectiveDate, now); err != nil {
+            return nil, err
+        }
+    }
+
+    entries := make([]*JournalEntry, len(txn.Entries))
+    for i := range txn.Entries {
+        entry := &txn.Entries[i]
+        entry.TransactionID = txn.TransactionID
+        entry.EntryNumber = fmt.Sprintf("JE-%s-%d", txn.TransactionID, i+1)
+        if entry.Description == "" {
+            entry.Description = txn.Description
+        }
+        if entry.ReferenceType == "" {
+            entry.ReferenceType = txn.ReferenceType
+        }
+        if entry.ReferenceID == "" {
+            entry.ReferenceID = txn.ReferenceID
+        }
+        if entry.CreatedBy == "" {
+            entry.CreatedBy = txn.CreatedBy
+        }
+        if entry.Metadata == nil {
+            entry.Metadata = txn.Metadata
+        }
+        entry.EffectiveDate = txn.EffectiveDate
+        entries[i] = entry
+    }
+
+    if err := ValidateTransactionEntries(txn.Entries); err != nil {
+        return nil, err
+    }
+
+    for _, entry := range entries {
+        entry.CurrencyCode = entry.Amount.Currency()
+    }
+
+    return entries, nil
+}
+
+// SubmitBatch validates every line of a settlement batch and stores the batch
+// for RunBatch to post. If any line is invalid nothing is stored and a
+// *BatchError lists the invalid lines. Lines without a creator take the
+// batch's, and lines without a transaction ID are given one, so a resumed
+// batch posts each line under the same ID.
+func (ls *LedgerService) SubmitBatch(ctx context.Context, req BatchRequest) (*PostingBatch, error) {
+    if req.Reference == "" {
+        return nil, fmt.Errorf("%w: reference is required", ErrInvalidBatch)
+    }
+
+    if req.CreatedBy == "" {
+        return nil, fmt.Errorf("%w: created_by is required", ErrInvalidBatch)
+    }
+
+    if len(req.Transactions) == 0 {
+        return nil, fmt.Errorf("%w: no transactions", ErrInvalidBatch)
+    }
+
+    if len(req.Transactions) > MaxBatchLines {
+        return nil, fmt.Errorf("%w: more than %d lines", ErrInvalidBatch, MaxBatchLines)
+    }
+
+    now := time.Now()
+    seen := make(map[string]int, len(req.Transactions))
+    var invalid []BatchLine
+    for i := range req.Transactions {
+        txn := &req.Transactions[i]
+        if txn.CreatedBy == "" {
+            txn.CreatedBy = req.CreatedBy
+        }
+        if err := prepareBatchLine(txn, now, seen); err != nil {
+            invalid = append(invalid, BatchLine{LineNumber: i + 1, TransactionID: txn.TransactionID, Status: BatchLineFailed, Error: err.Error()})
+            continue
+        }
+        seen[txn.TransactionID] = i + 1
+    }
+    if len(invalid) > 0 {
+        return nil, &BatchError{Lines: invalid}
+    }
+
+    return ls.postgres.CreateBatch(ctx, req, softClosedAllowed(ctx))
+}
+
+// RunBatch posts the pending lines of a batch, BatchChunkSize lines per
+// database transaction, and returns the batch once every line is posted or
+// failed. A line that cannot be posted is marked failed and the rest still
+// post. If RunBatch stops early it can be called again to resume.
+func (ls *LedgerService) RunBatch(ctx context.Context, batchID string) (*PostingBatch, error) {
+    if err := checkBatchID(batchID); err != nil {
+        return nil, err
+    }
+
+    chunkSize := ls.BatchChunkSize
+    if chunkSize <= 0 {
+        chunkSize = DefaultBatchChunkSize
+    }
+    return ls.postgres.RunBatch(ctx, batchID, chunkSize)
+}
+
+// ResumeBatches runs every batch left unfinished, oldest first, and returns
+// how many it completed
+func (ls *LedgerService) ResumeBatches(ctx context.Context) (int, error) {
+    ids, err := ls.postgres.UnfinishedBatches(ctx)
+    if err != nil {
+        return 0, err
+    }
+
+    for i, id := range ids {
+        if _, err := ls.RunBatch(ctx, id); err != nil {
+            return i, fmt.Errorf("failed to resume batch %s: %w", id, err)
+        }
+    }
+    return len(ids), nil
+}
+
+// GetBatch retrieves a posting batch and its progress
+func (ls *LedgerService) GetBatch(ctx context.Context, batchID string) (*PostingBatch, error) {
+    if err := checkBatchID(batchID); err != nil {
+        return nil, err
+    }
+
+    return ls.postgres.GetBatch(ctx, batchID)
+}
+
+// ListBatchLines returns the per-line results of a batch in line order
+func (ls *LedgerService) ListBatchLines(ctx context.Context, filter BatchLineFilter) ([]BatchLine, error) {
+    if err := checkBatchID(filter.BatchID); err != nil {
+        return nil, err
+    }
+
+    switch filter.Status {
+    case "", BatchLinePending, BatchLinePosted, BatchLineFailed:
+    default:
+        return nil, fmt.Errorf("%w: unknown line status %q", ErrInvalidRequest, filter.Status)
+    }
+
+    if filter.Offset < 0 {
+        filter.Offset = 0
+    }
+    filter.Limit = batchLineLimit(filter.Limit)
+
+    return ls.postgres.ListBatchLines(ctx, filter)
+}
+
+// ReverseTransaction undoes a posted transaction, in full or in part, by
+// posting its mirror image. Reversals need an approver other than the
+// requester, and a transaction can only be reversed once in full.
+func (ls *LedgerService) ReverseTransaction(ctx context.Context, req ReverseRequest) (*Reversal, error) {
+    if req.TransactionID == "" {
+        return nil, fmt.Errorf("%w: transaction ID is required", ErrInvalidRequest)
+    }
+    
+    if req.Reason == "" {
+        return nil, fmt.Errorf("%w: reason is required", ErrInvalidRequest)
     }
     
+    if req.ReversedBy == "" || req.ApprovedBy == "" {
+        return nil, fmt.Errorf("%w: reversed_by and approved_by are required", ErrInvalidRequest)
+    }
+    
+    if req.ReversedBy == req.ApprovedBy {
+        return nil, fmt.Errorf("%w: a reversal must be approved by someone other than its requester", ErrInvalidRequest)
+    }
+    
+    if !req.Amount.IsZero() && !req.Amount.IsPositive() {
+        return nil, fmt.Errorf("%w: amount must be positive", ErrInvalidRequest)
+    }
+    
+    if req.ReversalID == "" {
+        req.ReversalID = uuid.New().String()
+    }
+    
+    return ls.postgres.ReverseTransaction(ctx, req)
+}
+
+// GetTransaction retrieves a posted transaction together with its reversals
+func (ls *LedgerService) GetTransaction(ctx context.Context, transactionID string) (*TransactionDetail, error) {
+    if transactionID == "" {
+        return nil, fmt.Errorf("%w: transaction ID is required", ErrInvalidRequest)
+    }
+    
+    return ls.postgres.GetTransaction(ctx, transactionID)
+}
+
+// SetFXRate publishes an exchange rate, effective immediately unless the
+// request sets EffectiveAt
+func (ls *LedgerService) SetFXRate(ctx context.Context, req SetFXRateRequest) (*FXRate, error) {
+    if err := validateCurrencyPair(req.BaseCurrency, req.QuoteCurrency); err != nil {
+        return nil, err
+    }
+    
+    if req.Rate.IsZero() {
+        return nil, fmt.Errorf("%w: rate must be positive", ErrInvalidRequest)
+    }
+    
+    if req.CreatedBy == "" {
+        return nil, fmt.Errorf("%w: created_by is required", ErrInvalidRequest)
+    }
+    
+    if req.EffectiveAt.IsZero() {
+        req.EffectiveAt = time.Now()
+    }
+    
+    return ls.postgres.SetFXRate(ctx, req)
+}
+
+// GetFXRate retrieves the exchange rate for a currency pair in effect at a
+// point in time; a zero time means now
+func (ls *LedgerService) GetFXRate(ctx context.Context, base, quote string, at time.Time) (*FXRate, error) {
+    if err := validateCurrencyPair(base, quote); err != nil {
+        return nil, err
+    }
+    
+    if at.IsZero() {
+        at = time.Now()
+    }
+    
+    return ls.postgres.GetFXRate(ctx, base, quote, at)
+}
+
+// SetFXAccounts configures the accounts FX transfers post through in a currency
+func (ls *LedgerService) SetFXAccounts(ctx context.Context, accts FXAccounts) error {
+    if _, err := money.Exponent(accts.CurrencyCode); err != nil {
+        return fmt.Errorf("%w: %v", ErrInvalidRequest, err)
+    }
+    
+    if accts.ClearingAccountID == "" || accts.GainAccountID == "" || accts.LossAccountID == "" {
+        return fmt.Errorf("%w: clearing_account_id, gain_account_id and loss_account_id are required", ErrInvalidRequest)
+    }
+    
+    if accts.ClearingAccountID == accts.GainAccountID || accts.ClearingAccountID == accts.LossAccountID {
+        return fmt.Errorf("%w: the clearing account cannot also book gains or losses", ErrInvalidRequest)
+    }
+    
+    if accts.UpdatedBy == "" {
+        return fmt.Errorf("%w: updated_by is required", ErrInvalidRequest)
+    }
+    
+    return ls.postgres.SetFXAccounts(ctx, accts)
+}
+
+// FXTransfer moves an amount between accounts in different currencies at the
+// current exchange rate
+func (ls *LedgerService) FXTransfer(ctx context.Context, req FXTransferRequest) (*FXConversion, error) {
+    if req.FromAccountID == "" || req.ToAccountID == "" {
+        return nil, fmt.Errorf("%w: from_account_id and to_account_id are required", ErrInvalidRequest)
+    }
+    
+    if req.FromAccountID == req.ToAccountID {
+        return nil, fmt.Errorf("%w: from_account_id and to_account_id must be different", ErrInvalidRequest)
+    }
+    
+    if !req.Amount.IsPositive() {
+        return nil, fmt.Errorf("%w: amount must be positive", ErrInvalidRequest)
+    }
+    
+    if req.Description == "" {
+        return nil, fmt.Errorf("%w: description is required", ErrInvalidRequest)
+    }
+    
+    if req.CreatedBy == "" {
+        return nil, fmt.Errorf("%w: created_by is required", ErrInvalidRequest)
+    }
+    
+    if req.TransactionID == "" {
+        req.TransactionID = uuid.New().String()
+    }
+    
+    maxSpread := ls.MaxFXSpread
+    if maxSpread.IsZero() {
+        maxSpread = DefaultMaxFXSpread
+    }
+    return ls.postgres.FXTransfer(ctx, req, maxSpread)
+}
+
+// validateCurrencyPair checks that base and quote are distinct ISO 4217 codes
+func validateCurrencyPair(base, quote string) error {
+    for _, currency := range []string{base, quote} {
+        if _, err := money.Exponent(currency); err != nil {
+            return fmt.Errorf("%w: %v", ErrInvalidRequest, err)
+        }
+    }
+    if base == quote {
+        return fmt.Errorf("%w: base and quote currencies must differ", ErrInvalidRequest)
+    }
     return nil
 }
 
+// FreezeAccount stops debits to an account while still accepting credits
+func (ls *LedgerService) FreezeAccount(ctx context.Context, req AccountStatusRequest) (*AccountStatusTransition, error) {
+    return ls.changeAccountStatus(ctx, req, AccountStatusFrozen)
+}
+
+// UnfreezeAccount returns a frozen or blocked account to active
+func (ls *LedgerService) UnfreezeAccount(ctx context.Context, req AccountStatusRequest) (*AccountStatusTransition, error) {
+    return ls.changeAccountStatus(ctx, req, AccountStatusActive)
+}
+
+// BlockAccount stops all postings to an account
+func (ls *LedgerService) BlockAccount(ctx context.Context, req AccountStatusRequest) (*AccountStatusTransition, error) {
+    return ls.changeAccountStatus(ctx, req, AccountStatusBlocked)
+}
+
+// CloseAccount permanently closes an account. The account must have a zero
+// balance and no pending transactions or active holds.
+func (ls *LedgerService) CloseAccount(ctx context.Context, req AccountStatusRequest) (*AccountStatusTransition, error) {
+    return ls.changeAccountStatus(ctx, req, AccountStatusClosed)
+}
+
+func (ls *LedgerService) changeAccountStatus(ctx context.Context, req AccountStatusRequest, status string) (*AccountStatusTransition, error) {
+    if req.AccountID == "" {
+        return nil, fmt.Errorf("%w: account ID is required", ErrInvalidRequest)
+    }
+    
+    if req.Reason == "" {
+        return nil, fmt.Errorf("%w: reason is required", ErrInvalidRequest)
+    }
+    
+    if req.ChangedBy == "" {
+        return nil, fmt.Errorf("%w: changed_by is required", ErrInvalidRequest)
+    }
+    
+    return ls.postgres.ChangeAccountStatus(ctx, req, status)
+}
+
+// GetAccountStatusHistory lists the status transitions of an account, oldest first
+func (ls *LedgerService) GetAccountStatusHistory(ctx context.Context, accountID string) ([]AccountStatusTransition, error) {
+    if accountID == "" {
+        return nil, fmt.Errorf("%w: account ID is required", ErrInvalidRequest)
+    }
+    
+    return ls.postgres.GetAccountStatusHistory(ctx, accountID)
+}
+
+// SetCreditLimit sets an account's overdraft limit and transaction limit,
+// effective immediately unless the request sets EffectiveFrom. The limit
+// applies to every posting to the account from then on.
+func (ls *LedgerService) SetCreditLimit(ctx context.Context, req SetCreditLimitRequest) (*CreditLimit, error) {
+    if err := validateCreditLimit(req); err != nil {
+        return nil, err
+    }
+    
+    if req.EffectiveFrom.IsZero() {
+        req.EffectiveFrom = time.Now()
+    }
+    
+    return ls.postgres.SetCreditLimit(ctx, req)
+}
+
+// GetCreditLimit retrieves the limit in force for an account at a point in
+// time; a zero time means now
+func (ls *LedgerService) GetCreditLimit(ctx context.Context, accountID string, at time.Time) (*CreditLimit, error) {
+    if accountID == "" {
+        return nil, fmt.Errorf("%w: account ID is required", ErrInvalidRequest)
+    }
+    
+    if at.IsZero() {
+        at = time.Now()
+    }
+    
+    return ls.postgres.GetCreditLimit(ctx, accountID, at)
+}
+
+// ListCreditLimits lists every limit set for an account, latest effective first
+func (ls *LedgerService) ListCreditLimits(ctx context.Context, accountID string) ([]CreditLimit, error) {
+    if accountID == "" {
+        return nil, fmt.Errorf("%w: account ID is required", ErrInvalidRequest)
+    }
+    
+    return ls.postgres.ListCreditLimits(ctx, accountID)
+}
+
+// GetAccountTree returns an account and every account beneath it, with each
+// node's balance rolled up from its subtree
+func (ls *LedgerService) GetAccountTree(ctx context.Context, accountID string) (*AccountNode, error) {
+    if accountID == "" {
+        return nil, fmt.Errorf("%w: account ID is required", ErrInvalidRequest)
+    }
+    
+    accounts, err := ls.postgres.GetAccountSubtree(ctx, accountID)
+    if err != nil {
+        return nil, err
+    }
+    
+    return buildAccountTree(accountID, accounts)
+}
+
+// CreatePeriod opens an accounting period covering StartsOn to EndsOn inclusive
+func (ls *LedgerService) CreatePeriod(ctx context.Context, req CreatePeriodRequest) (*AccountingPeriod, error) {
+    if req.Name == "" {
+        return nil, fmt.Errorf("%w: name is required", ErrInvalidRequest)
+    }
+    
+    if err := validatePeriodRange(req.StartsOn, req.EndsOn); err != nil {
+        return nil, err
+    }
+    
+    if req.CreatedBy == "" {
+        return nil, fmt.Errorf("%w: created_by is required", ErrInvalidRequest)
+    }
+    
+    return ls.postgres.CreatePeriod(ctx, req)
+}
+
+// GetPeriod retrieves an accounting period with its closing balances
+func (ls *LedgerService) GetPeriod(ctx context.Context, periodID string) (*AccountingPeriod, error) {
+    if periodID == "" {
+        return nil, fmt.Errorf("%w: period ID is required", ErrInvalidRequest)
+    }
+    
+    return ls.postgres.GetPeriod(ctx, periodID)
+}
+
+// ListPeriods lists accounting periods, earliest first
+func (ls *LedgerService) ListPeriods(ctx context.Context) ([]AccountingPeriod, error) {
+    return ls.postgres.ListPeriods(ctx)
+}
+
+// SetRetainedEarningsAccount configures the equity account revenue and expense
+// in a currency roll into at period close
+func (ls *LedgerService) SetRetainedEarningsAccount(ctx context.Context, acct RetainedEarningsAccount) error {
+    if _, err := money.Exponent(acct.CurrencyCode); err != nil {
+        return fmt.Errorf("%w: %v", ErrInvalidRequest, err)
+    }
+    
+    if acct.AccountID == "" {
+        return fmt.Errorf("%w: account_id is required", ErrInvalidRequest)
+    }
+    
+    if acct.UpdatedBy == "" {
+        return fmt.Errorf("%w: updated_by is required", ErrInvalidRequest)
+    }
+    
+    return ls.postgres.SetRetainedEarningsAccount(ctx, acct)
+}
+
+// SoftClosePeriod closes a period to everything but adjustments from callers
+// allowed to post into soft-closed periods
+func (ls *LedgerService) SoftClosePeriod(ctx context.Context, req ClosePeriodRequest) (*AccountingPeriod, error) {
+    return ls.closePeriod(ctx, req, PeriodStatusSoftClosed)
+}
+
+// HardClosePeriod permanently closes a period to all postings
+func (ls *LedgerService) HardClosePeriod(ctx context.Context, req ClosePeriodRequest) (*AccountingPeriod, error) {
+    return ls.closePeriod(ctx, req, PeriodStatusHardClosed)
+}
+
+func (ls *LedgerService) closePeriod(ctx context.Context, req ClosePeriodRequest, status string) (*AccountingPeriod, error) {
+    if req.PeriodID == "" {
+        return nil, fmt.Errorf("%w: period ID is required", ErrInvalidRequest)
+    }
+    
+    if req.ClosedBy == "" {
+        return nil, fmt.Errorf("%w: closed_by is required", ErrInvalidRequest)
+    }
+    
+    if req.TransactionID == "" {
+        req.TransactionID = uuid.New().String()
+    }
+    
+    return ls.postgres.ClosePeriod(ctx, req, status)
+}
+
+// ValidateTransactionEntries checks that a set of legs is well formed and that
+// debits equal credits in every currency
+func ValidateTransactionEntries(entries []JournalEntry) error {
+    if len(entries) < 2 {
+        return fmt.Errorf("%w: transaction requires at least 2 entries, got %d", ErrInvalidEntry, len(entries))
+    }
+
+    debits := make(map[string]money.Money)
+    credits := make(map[string]money.Money)
+
+    for i, entry := range entries {
+        if entry.AccountID == "" {
+            return fmt.Errorf("%w: entry %d: account ID is required", ErrInvalidEntry, i)
+        }
+        if entry.Amount.Currency() == "" {
+            return fmt.Errorf("%w: entry %d: amount must have a currency", ErrInvalidEntry, i)
+        }
+        if !entry.Amount.IsPositive() {
+            return fmt.Errorf("%w: entry %d: amount must be positive", ErrInvalidEntry, i)
+        }
+        if entry.CurrencyCode != "" && entry.CurrencyCode != entry.Amount.Currency() {
+            return fmt.Errorf("%w: entry %d: currency mismatch: entry %s vs amount %s", ErrInvalidEntry, i, entry.CurrencyCode, entry.Amount.Currency())
+        }
+
+        var totals map[string]money.Money
+        switch entry.EntryType {
+        case "debit":
+            totals = debits
+        case "credit":
+            totals = credits
+        default:
+            return fmt.Errorf("%w: entry %d: invalid entry type: %s", ErrInvalidEntry, i, entry.EntryType)
+        }
+
+        cur := entry.Amount.Currency()
+        total, ok := totals[cur]
+        if !ok {
+            totals[cur] = entry.Amount
+            continue
+        }
+        sum, err := total.Add(entry.Amount)
+        if err != nil {
+            return fmt.Errorf("%w: entry %d: %v", ErrInvalidEntry, i, err)
+        }
+        totals[cur] = sum
+    }
+
+    currencies := make([]string, 0, len(debits)+len(credits))
+    for cur := range debits {
+        currencies = append(currencies, cur)
+    }
+    for cur := range credits {
+        if _, ok := debits[cur]; !ok {
+            currencies = append(currencies, cur)
+        }
+    }
+    sort.Strings(currencies)
+
+    for _, cur := range currencies {
+        debit, hasDebit := debits[cur]
+        credit, hasCredit := credits[cur]
+        if !hasDebit || !hasCredit || !debit.Equal(credit) {
+            return fmt.Errorf("%w: %s debits %s != credits %s", ErrUnbalancedTransaction, cur, totalAmount(debit, hasDebit), totalAmount(credit, hasCredit))
+        }
+    }
+
+    return nil
+}
+
+// totalAmount formats a per-currency total, treating a missing side as zero
+func totalAmount(total money.Money, ok bool) string {
+    if !ok {
+        return "0"
+    }
+    return total.Amount()
+}
+
 // GetAccount retrieves an account by account number
 func (ls *LedgerService) GetAccount(ctx context.Context, accountNumber string) (*Account, error) {
     if accountNumber == "" {
-        return nil, fmt.Errorf("account number is required")
+        return nil, fmt.Errorf("%w: account number is required", ErrInvalidRequest)
     }
     
     return ls.postgres.GetAccount(ctx, accountNumber)
@@ -340,7 +1108,7 @@ func isValidAccountType(accountType string) bool {
 // GetAccountByID retrieves an account by ID
 func (ls *LedgerService) GetAccountByID(ctx context.Context, accountID string) (*Account, error) {
     if accountID == "" {
-        return nil, fmt.Errorf("account ID is required")
+        return nil, fmt.Errorf("%w: account ID is required", ErrInvalidRequest)
     }
     
     queryCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
@@ -348,28 +1116,34 @@ func (ls *LedgerService) GetAccountByID(ctx context.Context, accountID string) (
     
     var account Account
     var metadataJSON sql.NullString
+    var balance pgtype.Numeric
     
     err := ls.postgres.Pool.QueryRow(queryCtx, `
         SELECT 
-            a.id, a.account_number, a.account_type, a.name, a.currency_code,
-            a.is_active, a.created_at, a.created_by, a.metadata,
+            a.id, COALESCE(a.parent_id::text, ''), a.account_number, a.account_type, a.name, a.currency_code,
+            a.is_active, a.status, a.created_at, a.created_by, a.metadata,
             COALESCE(ab.balance, 0) as current_balance
         FROM accounts a
         LEFT JOIN account_balances ab ON a.id = ab.account_id
         WHERE a.id = $1
     `, accountID).Scan(
-        &account.ID, &account.AccountNumber, &account.AccountType, &account.Name,
-        &account.CurrencyCode, &account.IsActive, &account.CreatedAt, &account.CreatedBy,
-        &metadataJSON, &account.CurrentBalance,
+        &account.ID, &account.ParentID, &account.AccountNumber, &account.AccountType, &account.Name,
+        &account.CurrencyCode, &account.IsActive, &account.Status, &account.CreatedAt, &account.CreatedBy,
+        &metadataJSON, &balance,
     )
     
     if err != nil {
-        if errors.Is(err, sql.ErrNoRows) {
-            return nil, fmt.Errorf("account %s not found", accountID)
+        if errors.Is(err, pgx.ErrNoRows) {
+            return nil, fmt.Errorf("%w: %s", ErrAccountNotFound, accountID)
         }
         return nil, fmt.Errorf("failed to get account: %w", err)
     }
     
+    account.CurrentBalance, err = money.FromNumeric(balance, account.CurrencyCode)
+    if err != nil {
+        return nil, fmt.Errorf("failed to read balance for account %s: %w", accountID, err)
+    }
+    
     // Parse metadata JSON
     if metadataJSON.Valid {
         account.Metadata = make(map[string]interface{})
@@ -387,8 +1161,8 @@ func (ls *LedgerService) ListAccounts(ctx context.Context, filter AccountFilter)
     // Build query based on filters
     query := `
         SELECT 
-            a.id, a.account_number, a.account_type, a.name, a.currency_code,
-            a.is_active, a.created_at, a.created_by, a.metadata,
+            a.id, COALESCE(a.parent_id::text, ''), a.account_number, a.account_type, a.name, a.currency_code,
+            a.is_active, a.status, a.created_at, a.created_by, a.metadata,
             COALESCE(ab.balance, 0) as current_balance
         FROM accounts a
         LEFT JOIN account_balances ab ON a.id = ab.account_id
@@ -409,12 +1183,28 @@ func (ls *LedgerService) ListAccounts(ctx context.Context, filter AccountFilter)
         argCount++
     }
     
+    if filter.Status != "" {
+        query += fmt.Sprintf(" AND a.status = $%d", argCount)
+        args = append(args, filter.Status)
+        argCount++
+    }
+    
     if filter.CurrencyCode != "" {
         query += fmt.Sprintf(" AND a.currency_code = $%d", argCount)
         args = append(args, filter.CurrencyCode)
         argCount++
     }
     
+    if filter.ParentID != "" {
+        query += fmt.Sprintf(" AND a.parent_id = $%d", argCount)
+        args = append(args, filter.ParentID)
+        argCount++
+    }
+    
+    if filter.TopLevel {
+        query += " AND a.parent_id IS NULL"
+    }
+    
     query += " ORDER BY a.account_number"
     
     if filter.Limit > 0 {
@@ -438,16 +1228,22 @@ func (ls *LedgerService) ListAccounts(ctx context.Context, filter AccountFilter)
     for rows.Next() {
         var account Account
         var metadataJSON sql.NullString
+        var balance pgtype.Numeric
         
         err := rows.Scan(
-            &account.ID, &account.AccountNumber, &account.AccountType, &account.Name,
-            &account.CurrencyCode, &account.IsActive, &account.CreatedAt, &account.CreatedBy,
-            &metadataJSON, &account.CurrentBalance,
+            &account.ID, &account.ParentID, &account.AccountNumber, &account.AccountType, &account.Name,
+            &account.CurrencyCode, &account.IsActive, &account.Status, &account.CreatedAt, &account.CreatedBy,
+            &metadataJSON, &balance,
         )
         if err != nil {
             return nil, fmt.Errorf("failed to scan account: %w", err)
         }
         
+        account.CurrentBalance, err = money.FromNumeric(balance, account.CurrencyCode)
+        if err != nil {
+            return nil, fmt.Errorf("failed to read balance for account %s: %w", account.AccountNumber, err)
+        }
+        
         // Parse metadata JSON
         if metadataJSON.Valid {
             account.Metadata = make(map[string]interface{})
@@ -460,11 +1256,16 @@ func (ls *LedgerService) ListAccounts(ctx context.Context, filter AccountFilter)
     return accounts, nil
 }
 
-// AccountFilter represents filtering options for listing accounts
+// AccountFilter represents filtering options for listing accounts. ParentID
+// lists the direct children of an account; TopLevel lists accounts without a
+// parent.
 type AccountFilter struct {
     AccountType  string
     IsActive     *bool
+    Status       string
     CurrencyCode string
+    ParentID     string
+    TopLevel     bool
     Limit        int
     Offset       int
 }
\ No newline at end of file
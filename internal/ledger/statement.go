@@ -0,0 +1,85 @@
+package ledger
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/example/pci-infra/internal/money"
+)
+
+const (
+	defaultStatementLimit = 100
+	maxStatementLimit     = 1000
+)
+
+// StatementRequest selects the entries of one account posted in [From, To)
+type StatementRequest struct {
+	AccountID string    `json:"account_id"`
+	From      time.Time `json:"from"`
+	To        time.Time `json:"to"`
+	Cursor    string    `json:"cursor"`
+	Limit     int       `json:"limit"`
+}
+
+// StatementEntry is a posted journal entry with the account balance after it
+type StatementEntry struct {
+	EntryID        string      `json:"entry_id"`
+	TransactionID  string      `json:"transaction_id"`
+	EntryType      string      `json:"entry_type"`
+	Amount         money.Money `json:"amount"`
+	BalanceChange  money.Money `json:"balance_change"`
+	RunningBalance money.Money `json:"running_balance"`
+	Description    string      `json:"description"`
+	ReferenceType  string      `json:"reference_type"`
+	ReferenceID    string      `json:"reference_id"`
+	PostedAt       string      `json:"posted_at"`
+}
+
+// Statement is one page of an account statement. Opening and closing balances
+// cover the whole period; NextCursor is empty on the last page.
+type Statement struct {
+	AccountID      string           `json:"account_id"`
+	AccountNumber  string           `json:"account_number"`
+	CurrencyCode   string           `json:"currency_code"`
+	From           string           `json:"from"`
+	To             string           `json:"to"`
+	OpeningBalance money.Money      `json:"opening_balance"`
+	ClosingBalance money.Money      `json:"closing_balance"`
+	Entries        []StatementEntry `json:"entries"`
+	NextCursor     string           `json:"next_cursor,omitempty"`
+}
+
+// statementCursor is the position of the last entry returned on a page.
+// Entries are ordered by (snapshot_time, entry_id).
+type statementCursor struct {
+	PostedAt time.Time
+	EntryID  string
+}
+
+func encodeStatementCursor(c statementCursor) string {
+	raw := c.PostedAt.UTC().Format(time.RFC3339Nano) + "|" + c.EntryID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeStatementCursor(s string) (statementCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return statementCursor{}, fmt.Errorf("%w: invalid cursor", ErrInvalidRequest)
+	}
+	postedAt, entryID, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return statementCursor{}, fmt.Errorf("%w: invalid cursor", ErrInvalidRequest)
+	}
+	t, err := time.Parse(time.RFC3339Nano, postedAt)
+	if err != nil {
+		return statementCursor{}, fmt.Errorf("%w: invalid cursor", ErrInvalidRequest)
+	}
+	if _, err := uuid.Parse(entryID); err != nil {
+		return statementCursor{}, fmt.Errorf("%w: invalid cursor", ErrInvalidRequest)
+	}
+	return statementCursor{PostedAt: t, EntryID: entryID}, nil
+}
@@ -7,6 +7,10 @@ import (
 	"regexp"
 	"strings"
 	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/example/pci-infra/internal/money"
 )
 
 // Validator provides invariants checking for the ledger
@@ -148,9 +152,21 @@ func (v *Validator) ValidateAccountNumber(ctx context.Context, accountNumber str
 	}
 }
 
+// maxTransactionMajorUnits is the largest whole-unit amount NUMERIC(20, 8) can hold
+const maxTransactionMajorUnits = 999999999999
+
 // ValidateTransactionAmount checks if transaction amount is valid
-func (v *Validator) ValidateTransactionAmount(amount float64) *ValidationResult {
-	if amount <= 0 {
+func (v *Validator) ValidateTransactionAmount(amount money.Money) *ValidationResult {
+	if amount.Currency() == "" {
+		return &ValidationResult{
+			IsValid:        false,
+			ValidationType: "transaction_amount",
+			Message:        "transaction amount must have a currency",
+			Timestamp:      time.Now(),
+		}
+	}
+
+	if !amount.IsPositive() {
 		return &ValidationResult{
 			IsValid:        false,
 			ValidationType: "transaction_amount",
@@ -159,7 +175,7 @@ func (v *Validator) ValidateTransactionAmount(amount float64) *ValidationResult
 		}
 	}
 	
-	if amount > 999999999999.99999999 {
+	if amount.Major() > maxTransactionMajorUnits {
 		return &ValidationResult{
 			IsValid:        false,
 			ValidationType: "transaction_amount",
@@ -171,7 +187,7 @@ func (v *Validator) ValidateTransactionAmount(amount float64) *ValidationResult
 	return &ValidationResult{
 		IsValid:        true,
 		ValidationType: "transaction_amount",
-		Message:        fmt.Sprintf("transaction amount %.8f is valid", amount),
+		Message:        fmt.Sprintf("transaction amount %s is valid", amount),
 		Timestamp:      time.Now(),
 	}
 }
@@ -181,15 +197,18 @@ func (v *Validator) ValidateDoubleEntryConstraint(ctx context.Context, transacti
 	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 	
-	var totalDebits, totalCredits sql.NullFloat64
-	
-	err := v.postgres.Pool.QueryRow(ctx, `
+	// Sum per currency: a transaction only balances if every currency it
+	// touches balances on its own.
+	rows, err := v.postgres.Pool.Query(ctx, `
 		SELECT 
+			currency_code,
 			COALESCE(SUM(CASE WHEN entry_type = 'debit' THEN amount ELSE 0 END), 0) as total_debits,
 			COALESCE(SUM(CASE WHEN entry_type = 'credit' THEN amount ELSE 0 END), 0) as total_credits
 		FROM journal_entries
 		WHERE transaction_id = $1
-	`, transactionID).Scan(&totalDebits, &totalCredits)
+		GROUP BY currency_code
+		ORDER BY currency_code
+	`, transactionID)
 	
 	if err != nil {
 		return &ValidationResult{
@@ -200,39 +219,75 @@ func (v *Validator) ValidateDoubleEntryConstraint(ctx context.Context, transacti
 			Timestamp:       time.Now(),
 		}
 	}
+	defer rows.Close()
+	
+	totals := make(map[string]interface{})
+	var violations []string
+	for rows.Next() {
+		var currencyCode string
+		var totalDebits, totalCredits pgtype.Numeric
+		if err := rows.Scan(&currencyCode, &totalDebits, &totalCredits); err != nil {
+			return &ValidationResult{
+				IsValid:         false,
+				ValidationType:  "double_entry_constraint",
+				Message:         fmt.Sprintf("failed to scan double-entry totals: %v", err),
+				TransactionID:   transactionID,
+				Timestamp:       time.Now(),
+			}
+		}
+		
+		debits, err := money.FromNumeric(totalDebits, currencyCode)
+		if err == nil {
+			var credits money.Money
+			credits, err = money.FromNumeric(totalCredits, currencyCode)
+			if err == nil {
+				totals[currencyCode] = map[string]interface{}{
+					"total_debits":  debits.Amount(),
+					"total_credits": credits.Amount(),
+				}
+				if !debits.Equal(credits) {
+					violations = append(violations, fmt.Sprintf("debits (%s) != credits (%s)", debits, credits))
+				}
+			}
+		}
+		if err != nil {
+			return &ValidationResult{
+				IsValid:         false,
+				ValidationType:  "double_entry_constraint",
+				Message:         fmt.Sprintf("failed to read double-entry totals: %v", err),
+				TransactionID:   transactionID,
+				Timestamp:       time.Now(),
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return &ValidationResult{
+			IsValid:         false,
+			ValidationType:  "double_entry_constraint",
+			Message:         fmt.Sprintf("failed to validate double-entry constraint: %v", err),
+			TransactionID:   transactionID,
+			Timestamp:       time.Now(),
+		}
+	}
 	
-	debits := totalDebits.Float64
-	credits := totalCredits.Float64
-	
-	// Allow for small floating point differences
-	const epsilon = 0.00000001
-	isValid := abs(debits-credits) < epsilon
-	
-	if !isValid {
+	if len(violations) > 0 {
 		return &ValidationResult{
 			IsValid:         false,
 			ValidationType:  "double_entry_constraint",
-			Message:         fmt.Sprintf("double-entry violation: debits (%.8f) != credits (%.8f)", debits, credits),
+			Message:         fmt.Sprintf("double-entry violation: %s", strings.Join(violations, "; ")),
 			TransactionID:   transactionID,
 			Timestamp:       time.Now(),
-			Details: map[string]interface{}{
-				"total_debits":  debits,
-				"total_credits": credits,
-				"difference":    debits - credits,
-			},
+			Details:         totals,
 		}
 	}
 	
 	return &ValidationResult{
 		IsValid:         true,
 		ValidationType:  "double_entry_constraint",
-		Message:         fmt.Sprintf("double-entry constraint satisfied: debits = credits = %.8f", debits),
+		Message:         "double-entry constraint satisfied: debits = credits",
 		TransactionID:   transactionID,
 		Timestamp:       time.Now(),
-		Details: map[string]interface{}{
-			"total_debits":  debits,
-			"total_credits": credits,
-		},
+		Details:         totals,
 	}
 }
 
@@ -241,17 +296,18 @@ func (v *Validator) ValidateAccountBalanceConsistency(ctx context.Context, accou
 	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 	
-	var actualBalance, expectedBalance sql.NullFloat64
+	var actualBalance, expectedBalance pgtype.Numeric
 	var accountNumber sql.NullString
 	var accountType sql.NullString
+	var currencyCode string
 	
 	// Get current balance from account_balances table
 	err := v.postgres.Pool.QueryRow(ctx, `
-		SELECT ab.balance, a.account_number, a.account_type
+		SELECT ab.balance, a.account_number, a.account_type, a.currency_code
 		FROM account_balances ab
 		JOIN accounts a ON ab.account_id = a.id
 		WHERE ab.account_id = $1
-	`, accountID).Scan(&actualBalance, &accountNumber, &accountType)
+	`, accountID).Scan(&actualBalance, &accountNumber, &accountType, &currencyCode)
 	
 	if err != nil {
 		return &ValidationResult{
@@ -289,24 +345,39 @@ func (v *Validator) ValidateAccountBalanceConsistency(ctx context.Context, accou
 		}
 	}
 	
-	actual := actualBalance.Float64
-	expected := expectedBalance.Float64
-	
-	// Allow for small floating point differences
-	const epsilon = 0.00000001
-	isValid := abs(actual-expected) < epsilon
+	actual, err := money.FromNumeric(actualBalance, currencyCode)
+	if err != nil {
+		return &ValidationResult{
+			IsValid:       false,
+			ValidationType: "balance_consistency",
+			Message:       fmt.Sprintf("failed to read account balance: %v", err),
+			AccountID:     accountID,
+			Timestamp:     time.Now(),
+		}
+	}
+	expected, err := money.FromNumeric(expectedBalance, currencyCode)
+	if err != nil {
+		return &ValidationResult{
+			IsValid:       false,
+			ValidationType: "balance_consistency",
+			Message:       fmt.Sprintf("failed to read expected balance: %v", err),
+			AccountID:     accountID,
+			Timestamp:     time.Now(),
+		}
+	}
 	
-	if !isValid {
+	if !actual.Equal(expected) {
+		difference, _ := actual.Sub(expected)
 		return &ValidationResult{
 			IsValid:         false,
 			ValidationType:  "balance_consistency",
-			Message:         fmt.Sprintf("balance inconsistency: actual (%.8f) != expected (%.8f)", actual, expected),
+			Message:         fmt.Sprintf("balance inconsistency: actual (%s) != expected (%s)", actual, expected),
 			AccountID:       accountID,
 			Timestamp:       time.Now(),
 			Details: map[string]interface{}{
-				"actual_balance":   actual,
-				"expected_balance": expected,
-				"difference":       actual - expected,
+				"actual_balance":   actual.Amount(),
+				"expected_balance": expected.Amount(),
+				"difference":       difference.Amount(),
 				"account_number":   accountNumber.String,
 				"account_type":     accountType.String,
 			},
@@ -316,12 +387,12 @@ func (v *Validator) ValidateAccountBalanceConsistency(ctx context.Context, accou
 	return &ValidationResult{
 		IsValid:         true,
 		ValidationType:  "balance_consistency",
-		Message:         fmt.Sprintf("balance is consistent: %.8f", actual),
+		Message:         fmt.Sprintf("balance is consistent: %s", actual),
 		AccountID:       accountID,
 		Timestamp:       time.Now(),
 		Details: map[string]interface{}{
-			"actual_balance":   actual,
-			"expected_balance": expected,
+			"actual_balance":   actual.Amount(),
+			"expected_balance": expected.Amount(),
 			"account_number":   accountNumber.String,
 			"account_type":     accountType.String,
 		},
@@ -329,19 +400,23 @@ func (v *Validator) ValidateAccountBalanceConsistency(ctx context.Context, accou
 }
 
 // ValidateOverdraftPrevention checks if a transaction would cause an overdraft
-func (v *Validator) ValidateOverdraftPrevention(ctx context.Context, accountID string, amount float64, entryType string) *ValidationResult {
+func (v *Validator) ValidateOverdraftPrevention(ctx context.Context, accountID string, amount money.Money, entryType string) *ValidationResult {
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 	
-	var currentBalance sql.NullFloat64
+	var currentBalance, overdraftNum, maxNum pgtype.Numeric
 	var accountType sql.NullString
+	var currencyCode string
 	
+	// A NULL overdraft limit means the account has no floor (FX clearing)
 	err := v.postgres.Pool.QueryRow(ctx, `
-		SELECT ab.balance, a.account_type
+		SELECT ab.balance, a.account_type, a.currency_code,
+		       get_overdraft_limit(ab.account_id, CURRENT_TIMESTAMP AT TIME ZONE 'UTC'),
+		       get_transaction_limit(ab.account_id, CURRENT_TIMESTAMP AT TIME ZONE 'UTC')
 		FROM account_balances ab
 		JOIN accounts a ON ab.account_id = a.id
 		WHERE ab.account_id = $1
-	`, accountID).Scan(¤tBalance, &accountType)
+	`, accountID).Scan(&currentBalance, &accountType, &currencyCode, &overdraftNum, &maxNum)
 	
 	if err != nil {
 		return &ValidationResult{
@@ -353,25 +428,61 @@ func (v *Validator) ValidateOverdraftPrevention(ctx context.Context, accountID s
 		}
 	}
 	
-	balance := currentBalance.Float64
+	balance, err := money.FromNumeric(currentBalance, currencyCode)
+	if err != nil {
+		return &ValidationResult{
+			IsValid:       false,
+			ValidationType: "overdraft_prevention",
+			Message:       fmt.Sprintf("failed to read account balance: %v", err),
+			AccountID:     accountID,
+			Timestamp:     time.Now(),
+		}
+	}
+	overdraftLimit, err := money.Zero(currencyCode)
+	if err == nil && overdraftNum.Valid {
+		overdraftLimit, err = money.FromNumeric(overdraftNum, currencyCode)
+	}
+	if err != nil {
+		return &ValidationResult{
+			IsValid:       false,
+			ValidationType: "overdraft_prevention",
+			Message:       fmt.Sprintf("failed to read overdraft limit: %v", err),
+			AccountID:     accountID,
+			Timestamp:     time.Now(),
+		}
+	}
+	var maxTransaction *money.Money
+	if maxNum.Valid {
+		maxAmount, err := money.FromNumeric(maxNum, currencyCode)
+		if err != nil {
+			return &ValidationResult{
+				IsValid:       false,
+				ValidationType: "overdraft_prevention",
+				Message:       fmt.Sprintf("failed to read transaction limit: %v", err),
+				AccountID:     accountID,
+				Timestamp:     time.Now(),
+			}
+		}
+		maxTransaction = &maxAmount
+	}
 	acctType := accountType.String
 	
 	// Calculate what the new balance would be
-	var newBalance float64
+	var newBalance money.Money
 	switch entryType {
 	case "debit":
 		// Debit decreases balance for asset accounts, increases for liability/equity/revenue
 		if acctType == "asset" || acctType == "expense" {
-			newBalance = balance - amount
+			newBalance, err = balance.Sub(amount)
 		} else {
-			newBalance = balance + amount
+			newBalance, err = balance.Add(amount)
 		}
 	case "credit":
 		// Credit increases balance for asset accounts, decreases for liability/equity/revenue
 		if acctType == "asset" || acctType == "expense" {
-			newBalance = balance + amount
+			newBalance, err = balance.Add(amount)
 		} else {
-			newBalance = balance - amount
+			newBalance, err = balance.Sub(amount)
 		}
 	default:
 		return &ValidationResult{
@@ -383,16 +494,51 @@ func (v *Validator) ValidateOverdraftPrevention(ctx context.Context, accountID s
 		}
 	}
 	
-	// Check for overdraft (negative balance on accounts that shouldn't have negative balances)
-	const epsilon = 0.00000001
-	wouldOverdraw := newBalance < -epsilon
+	if err != nil {
+		return &ValidationResult{
+			IsValid:       false,
+			ValidationType: "overdraft_prevention",
+			Message:       fmt.Sprintf("failed to calculate new balance: %v", err),
+			AccountID:     accountID,
+			Timestamp:     time.Now(),
+		}
+	}
+	
+	details := map[string]interface{}{
+		"current_balance": balance.Amount(),
+		"transaction_amount": amount.Amount(),
+		"entry_type":      entryType,
+		"new_balance":     newBalance.Amount(),
+		"account_type":    acctType,
+		"currency_code":   currencyCode,
+		"overdraft_limit": overdraftLimit.Amount(),
+	}
+	if maxTransaction != nil {
+		details["max_transaction_amount"] = maxTransaction.Amount()
+	}
 	
-	if wouldOverdraw {
-		reason := fmt.Sprintf("transaction would cause overdraft: balance would be %.8f", newBalance)
+	// Check the per-transaction maximum on entries that reduce the balance
+	if newBalance.LessThan(balance) && maxTransaction != nil && amount.GreaterThan(*maxTransaction) {
+		return &ValidationResult{
+			IsValid:         false,
+			ValidationType:  "overdraft_prevention",
+			Message:         fmt.Sprintf("transaction of %s exceeds the account's transaction limit of %s", amount, maxTransaction),
+			AccountID:       accountID,
+			Timestamp:       time.Now(),
+			Details:         details,
+		}
+	}
+	
+	// Check for overdraft (balance below the account's overdraft limit, zero unless configured)
+	if overdraftNum.Valid && newBalance.LessThan(overdraftLimit.Neg()) {
+		reason := fmt.Sprintf("transaction would cause overdraft: balance would be %s", newBalance)
 		if acctType == "asset" {
-			reason = fmt.Sprintf("transaction would cause negative asset balance: %.8f", newBalance)
+			reason = fmt.Sprintf("transaction would cause negative asset balance: %s", newBalance)
 		} else if acctType == "liability" {
-			reason = fmt.Sprintf("transaction would cause negative liability balance: %.8f", newBalance)
+			reason = fmt.Sprintf("transaction would cause negative liability balance: %s", newBalance)
+		}
+		if overdraftLimit.IsPositive() {
+			reason = fmt.Sprintf("%s, beyond overdraft limit of %s", reason, overdraftLimit)
 		}
 		
 		return &ValidationResult{
@@ -401,29 +547,17 @@ func (v *Validator) ValidateOverdraftPrevention(ctx context.Context, accountID s
 			Message:         reason,
 			AccountID:       accountID,
 			Timestamp:       time.Now(),
-			Details: map[string]interface{}{
-				"current_balance": balance,
-				"transaction_amount": amount,
-				"entry_type":      entryType,
-				"new_balance":     newBalance,
-				"account_type":    acctType,
-			},
+			Details:         details,
 		}
 	}
 	
 	return &ValidationResult{
 		IsValid:         true,
 		ValidationType:  "overdraft_prevention",
-		Message:         fmt.Sprintf("transaction would not cause overdraft. New balance: %.8f", newBalance),
+		Message:         fmt.Sprintf("transaction would not cause overdraft. New balance: %s", newBalance),
 		AccountID:       accountID,
 		Timestamp:       time.Now(),
-		Details: map[string]interface{}{
-			"current_balance": balance,
-			"transaction_amount": amount,
-			"entry_type":      entryType,
-			"new_balance":     newBalance,
-			"account_type":    acctType,
-		},
+		Details:         details,
 	}
 }
 
@@ -493,7 +627,7 @@ func (v *Validator) ComprehensiveValidation(ctx context.Context, accountID strin
 		SELECT account_number, account_type, currency_code
 		FROM accounts
 		WHERE id = $1
-	`, accountID).Scan(&accountNumber, &accountType, ¤cyCode)
+	`, accountID).Scan(&accountNumber, &accountType, &currencyCode)
 	
 	if err != nil {
 		results = append(results, &ValidationResult{
@@ -0,0 +1,107 @@
+package money
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// fxRateScale is the number of decimal places an FXRate carries (NUMERIC(20, 10))
+const fxRateScale = 10
+
+// FXRate is an exact exchange rate: one unit of a base currency buys FXRate
+// units of a quote currency. It carries ten decimal places so that rates
+// between currencies of very different magnitude, such as JPY and KWD, stay
+// exact. Rate is too coarse for this.
+type FXRate struct {
+	v int64
+}
+
+// ParseFXRate converts a decimal string such as "1.0825" into an FXRate
+func ParseFXRate(s string) (FXRate, error) {
+	v, err := parseScaled(s, fxRateScale)
+	if err != nil {
+		return FXRate{}, err
+	}
+	return FXRate{v: v}, nil
+}
+
+// MustParseFXRate is like ParseFXRate but panics on error
+func MustParseFXRate(s string) FXRate {
+	r, err := ParseFXRate(s)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// FXRateFromNumeric converts a PostgreSQL NUMERIC exchange rate into an FXRate
+func FXRateFromNumeric(n pgtype.Numeric) (FXRate, error) {
+	v, err := scaledFromNumeric(n, fxRateScale)
+	if err != nil {
+		return FXRate{}, err
+	}
+	return FXRate{v: v}, nil
+}
+
+// IsZero reports whether the rate is zero
+func (r FXRate) IsZero() bool {
+	return r.v == 0
+}
+
+// Convert exchanges m, held in the rate's base currency, into currency,
+// rounding half away from zero to the nearest minor unit of currency
+func (r FXRate) Convert(m Money, currency string) (Money, error) {
+	exp, err := Exponent(currency)
+	if err != nil {
+		return Money{}, err
+	}
+
+	product := new(big.Int).Mul(big.NewInt(m.units), big.NewInt(r.v))
+	product.Mul(product, pow10(exp))
+	units := divRoundHalfAway(product, pow10(fxRateScale+m.Exponent()))
+	if !units.IsInt64() {
+		return Money{}, ErrOverflow
+	}
+	return Money{units: units.Int64(), currency: currency}, nil
+}
+
+// WithinSpread reports whether r deviates from market by no more than spread
+// of market, e.g. 0.0200 allows a quote up to 2% either side of the market rate
+func (r FXRate) WithinSpread(market FXRate, spread Rate) bool {
+	diff := new(big.Int).Sub(big.NewInt(r.v), big.NewInt(market.v))
+	diff.Abs(diff).Mul(diff, big.NewInt(basisPointsPerUnit))
+	limit := new(big.Int).Mul(big.NewInt(market.v), big.NewInt(spread.bp))
+	return diff.Cmp(limit) <= 0
+}
+
+// String returns the rate as a decimal, e.g. "1.0825000000"
+func (r FXRate) String() string {
+	return formatDecimal(big.NewInt(r.v), fxRateScale)
+}
+
+// NumericValue implements pgtype.NumericValuer
+func (r FXRate) NumericValue() (pgtype.Numeric, error) {
+	return pgtype.Numeric{Int: big.NewInt(r.v), Exp: -fxRateScale, Valid: true}, nil
+}
+
+// MarshalJSON encodes the rate as a decimal string
+func (r FXRate) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.String())
+}
+
+// UnmarshalJSON decodes a rate from a decimal string or number
+func (r *FXRate) UnmarshalJSON(data []byte) error {
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return fmt.Errorf("invalid exchange rate value: %w", err)
+	}
+	parsed, err := ParseFXRate(n.String())
+	if err != nil {
+		return err
+	}
+	*r = parsed
+	return nil
+}
@@ -0,0 +1,407 @@
+// Package money provides an exact, currency-aware monetary amount.
+//
+// Amounts are held as an integer count of minor units (cents, pence, fils)
+// together with an ISO 4217 currency code. The number of minor-unit digits
+// is taken from the currency, so 1234 USD minor units is 12.34 while 1234
+// JPY is 1234 and 1234 KWD is 1.234. No float64 is used anywhere on the
+// path between the API, the services and PostgreSQL.
+package money
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ErrCurrencyMismatch is returned when combining amounts in different currencies.
+var ErrCurrencyMismatch = errors.New("currency mismatch")
+
+// ErrOverflow is returned when a result does not fit in int64 minor units.
+var ErrOverflow = errors.New("amount overflows minor unit range")
+
+// currencyExponents maps ISO 4217 codes to their number of minor-unit digits.
+var currencyExponents = map[string]int{
+	"AED": 2, "AFN": 2, "ALL": 2, "AMD": 2, "ANG": 2, "AOA": 2, "ARS": 2, "AUD": 2,
+	"AWG": 2, "AZN": 2, "BAM": 2, "BBD": 2, "BDT": 2, "BGN": 2, "BHD": 3, "BIF": 0,
+	"BMD": 2, "BND": 2, "BOB": 2, "BRL": 2, "BSD": 2, "BTN": 2, "BWP": 2, "BYN": 2,
+	"BZD": 2, "CAD": 2, "CDF": 2, "CHF": 2, "CLF": 4, "CLP": 0, "CNY": 2, "COP": 2,
+	"CRC": 2, "CUP": 2, "CVE": 2, "CZK": 2, "DJF": 0, "DKK": 2, "DOP": 2, "DZD": 2,
+	"EGP": 2, "ERN": 2, "ETB": 2, "EUR": 2, "FJD": 2, "FKP": 2, "GBP": 2, "GEL": 2,
+	"GHS": 2, "GIP": 2, "GMD": 2, "GNF": 0, "GTQ": 2, "GYD": 2, "HKD": 2, "HNL": 2,
+	"HTG": 2, "HUF": 2, "IDR": 2, "ILS": 2, "INR": 2, "IQD": 3, "IRR": 2, "ISK": 0,
+	"JMD": 2, "JOD": 3, "JPY": 0, "KES": 2, "KGS": 2, "KHR": 2, "KMF": 0, "KPW": 2,
+	"KRW": 0, "KWD": 3, "KYD": 2, "KZT": 2, "LAK": 2, "LBP": 2, "LKR": 2, "LRD": 2,
+	"LSL": 2, "LYD": 3, "MAD": 2, "MDL": 2, "MGA": 2, "MKD": 2, "MMK": 2, "MNT": 2,
+	"MOP": 2, "MRU": 2, "MUR": 2, "MVR": 2, "MWK": 2, "MXN": 2, "MYR": 2, "MZN": 2,
+	"NAD": 2, "NGN": 2, "NIO": 2, "NOK": 2, "NPR": 2, "NZD": 2, "OMR": 3, "PAB": 2,
+	"PEN": 2, "PGK": 2, "PHP": 2, "PKR": 2, "PLN": 2, "PYG": 0, "QAR": 2, "RON": 2,
+	"RSD": 2, "RUB": 2, "RWF": 0, "SAR": 2, "SBD": 2, "SCR": 2, "SDG": 2, "SEK": 2,
+	"SGD": 2, "SHP": 2, "SLE": 2, "SOS": 2, "SRD": 2, "SSP": 2, "STN": 2, "SYP": 2,
+	"SZL": 2, "THB": 2, "TJS": 2, "TMT": 2, "TND": 3, "TOP": 2, "TRY": 2, "TTD": 2,
+	"TWD": 2, "TZS": 2, "UAH": 2, "UGX": 0, "USD": 2, "UYI": 0, "UYU": 2, "UYW": 4,
+	"UZS": 2, "VES": 2, "VND": 0, "VUV": 0, "WST": 2, "XAF": 0, "XCD": 2, "XOF": 0,
+	"XPF": 0, "YER": 2, "ZAR": 2, "ZMW": 2, "ZWL": 2,
+}
+
+// Exponent returns the number of minor-unit digits for an ISO 4217 currency
+func Exponent(currency string) (int, error) {
+	exp, ok := currencyExponents[currency]
+	if !ok {
+		return 0, fmt.Errorf("unsupported currency code: %q", currency)
+	}
+	return exp, nil
+}
+
+// Money is an exact amount of a single currency, stored in minor units
+type Money struct {
+	units    int64
+	currency string
+}
+
+// New creates a Money value from a count of minor units
+func New(minorUnits int64, currency string) (Money, error) {
+	if _, err := Exponent(currency); err != nil {
+		return Money{}, err
+	}
+	return Money{units: minorUnits, currency: currency}, nil
+}
+
+// Zero returns a zero amount in the given currency
+func Zero(currency string) (Money, error) {
+	return New(0, currency)
+}
+
+// MustNew is like New but panics on an unsupported currency
+func MustNew(minorUnits int64, currency string) Money {
+	m, err := New(minorUnits, currency)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// Parse converts a decimal string such as "12.34" into Money. Digits beyond the
+// currency's exponent are accepted only when they are zero; amounts are never rounded.
+func Parse(amount, currency string) (Money, error) {
+	exp, err := Exponent(currency)
+	if err != nil {
+		return Money{}, err
+	}
+
+	s := strings.TrimSpace(amount)
+	negative := false
+	switch {
+	case strings.HasPrefix(s, "-"):
+		negative = true
+		s = s[1:]
+	case strings.HasPrefix(s, "+"):
+		s = s[1:]
+	}
+
+	intPart, fracPart, hasPoint := strings.Cut(s, ".")
+	if intPart == "" && fracPart == "" || hasPoint && fracPart == "" || !isDigits(intPart) || !isDigits(fracPart) {
+		return Money{}, fmt.Errorf("invalid amount %q", amount)
+	}
+
+	if len(fracPart) > exp {
+		if strings.Trim(fracPart[exp:], "0") != "" {
+			return Money{}, fmt.Errorf("amount %q has more than %d decimal places for %s", amount, exp, currency)
+		}
+		fracPart = fracPart[:exp]
+	}
+	fracPart += strings.Repeat("0", exp-len(fracPart))
+
+	digits := strings.TrimLeft(intPart+fracPart, "0")
+	if digits == "" {
+		digits = "0"
+	}
+	n, ok := new(big.Int).SetString(digits, 10)
+	if !ok {
+		return Money{}, fmt.Errorf("invalid amount %q", amount)
+	}
+	if negative {
+		n.Neg(n)
+	}
+	if !n.IsInt64() {
+		return Money{}, ErrOverflow
+	}
+	return Money{units: n.Int64(), currency: currency}, nil
+}
+
+// MustParse is like Parse but panics on error. Intended for constants and tests.
+func MustParse(amount, currency string) Money {
+	m, err := Parse(amount, currency)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// FromNumeric converts a PostgreSQL NUMERIC value into Money. It fails rather than
+// rounds when the value carries more precision than the currency allows.
+func FromNumeric(n pgtype.Numeric, currency string) (Money, error) {
+	exp, err := Exponent(currency)
+	if err != nil {
+		return Money{}, err
+	}
+	if !n.Valid {
+		return Money{}, fmt.Errorf("cannot convert NULL numeric to money")
+	}
+	if n.NaN || n.InfinityModifier != pgtype.Finite {
+		return Money{}, fmt.Errorf("cannot convert non-finite numeric to money")
+	}
+
+	units := new(big.Int).Set(n.Int)
+	shift := int(n.Exp) + exp
+	if shift >= 0 {
+		units.Mul(units, pow10(shift))
+	} else {
+		var rem big.Int
+		units.QuoRem(units, pow10(-shift), &rem)
+		if rem.Sign() != 0 {
+			return Money{}, fmt.Errorf("numeric value has more than %d decimal places for %s", exp, currency)
+		}
+	}
+	if !units.IsInt64() {
+		return Money{}, ErrOverflow
+	}
+	return Money{units: units.Int64(), currency: currency}, nil
+}
+
+// MinorUnits returns the amount as an integer count of minor units
+func (m Money) MinorUnits() int64 {
+	return m.units
+}
+
+// Currency returns the ISO 4217 currency code
+func (m Money) Currency() string {
+	return m.currency
+}
+
+// Exponent returns the number of minor-unit digits for the amount's currency
+func (m Money) Exponent() int {
+	return currencyExponents[m.currency]
+}
+
+// Major returns the whole major units of the amount, truncated toward zero
+func (m Money) Major() int64 {
+	return m.units / pow10(m.Exponent()).Int64()
+}
+
+// IsZero reports whether the amount is zero
+func (m Money) IsZero() bool {
+	return m.units == 0
+}
+
+// IsPositive reports whether the amount is greater than zero
+func (m Money) IsPositive() bool {
+	return m.units > 0
+}
+
+// IsNegative reports whether the amount is less than zero
+func (m Money) IsNegative() bool {
+	return m.units < 0
+}
+
+// Neg returns the amount with its sign flipped
+func (m Money) Neg() Money {
+	return Money{units: -m.units, currency: m.currency}
+}
+
+// Abs returns the absolute value of the amount
+func (m Money) Abs() Money {
+	if m.units < 0 {
+		return m.Neg()
+	}
+	return m
+}
+
+// Add returns m + o. Both amounts must share a currency.
+func (m Money) Add(o Money) (Money, error) {
+	if err := m.sameCurrency(o); err != nil {
+		return Money{}, err
+	}
+	if (o.units > 0 && m.units > math.MaxInt64-o.units) || (o.units < 0 && m.units < math.MinInt64-o.units) {
+		return Money{}, ErrOverflow
+	}
+	return Money{units: m.units + o.units, currency: m.currency}, nil
+}
+
+// Sub returns m - o. Both amounts must share a currency.
+func (m Money) Sub(o Money) (Money, error) {
+	if o.units == math.MinInt64 {
+		return Money{}, ErrOverflow
+	}
+	return m.Add(o.Neg())
+}
+
+// Cmp compares m and o, returning -1, 0 or +1. Both amounts must share a currency.
+func (m Money) Cmp(o Money) (int, error) {
+	if err := m.sameCurrency(o); err != nil {
+		return 0, err
+	}
+	switch {
+	case m.units < o.units:
+		return -1, nil
+	case m.units > o.units:
+		return 1, nil
+	default:
+		return 0, nil
+	}
+}
+
+// LessThan reports whether m < o. Amounts in different currencies are never ordered.
+func (m Money) LessThan(o Money) bool {
+	c, err := m.Cmp(o)
+	return err == nil && c < 0
+}
+
+// GreaterThan reports whether m > o. Amounts in different currencies are never ordered.
+func (m Money) GreaterThan(o Money) bool {
+	c, err := m.Cmp(o)
+	return err == nil && c > 0
+}
+
+// Equal reports whether m and o have the same currency and amount
+func (m Money) Equal(o Money) bool {
+	return m.currency == o.currency && m.units == o.units
+}
+
+// MulRate multiplies the amount by a rate, rounding half away from zero to the
+// nearest minor unit.
+func (m Money) MulRate(r Rate) (Money, error) {
+	product := new(big.Int).Mul(big.NewInt(m.units), big.NewInt(r.bp))
+	units := divRoundHalfAway(product, big.NewInt(basisPointsPerUnit))
+	if !units.IsInt64() {
+		return Money{}, ErrOverflow
+	}
+	return Money{units: units.Int64(), currency: m.currency}, nil
+}
+
+// Prorate returns the share of the amount that part is of whole, rounding
+// half away from zero to the nearest minor unit. part and whole must share a
+// currency and whole must be positive.
+func (m Money) Prorate(part, whole Money) (Money, error) {
+	if err := part.sameCurrency(whole); err != nil {
+		return Money{}, err
+	}
+	if !whole.IsPositive() {
+		return Money{}, fmt.Errorf("cannot prorate over %s", whole)
+	}
+	product := new(big.Int).Mul(big.NewInt(m.units), big.NewInt(part.units))
+	units := divRoundHalfAway(product, big.NewInt(whole.units))
+	if !units.IsInt64() {
+		return Money{}, ErrOverflow
+	}
+	return Money{units: units.Int64(), currency: m.currency}, nil
+}
+
+// Amount returns the decimal representation without currency, e.g. "12.34"
+func (m Money) Amount() string {
+	return formatDecimal(big.NewInt(m.units), m.Exponent())
+}
+
+// String returns the amount followed by its currency code, e.g. "12.34 USD"
+func (m Money) String() string {
+	if m.currency == "" {
+		return m.Amount()
+	}
+	return m.Amount() + " " + m.currency
+}
+
+// NumericValue implements pgtype.NumericValuer so Money can be passed directly
+// as a query argument for NUMERIC columns.
+func (m Money) NumericValue() (pgtype.Numeric, error) {
+	return pgtype.Numeric{Int: big.NewInt(m.units), Exp: int32(-m.Exponent()), Valid: true}, nil
+}
+
+// Value implements driver.Valuer
+func (m Money) Value() (driver.Value, error) {
+	return m.Amount(), nil
+}
+
+// moneyJSON is the wire representation of Money. The amount is a decimal
+// string so that no JSON consumer ever parses it as a binary float.
+type moneyJSON struct {
+	Amount   json.Number `json:"amount"`
+	Currency string      `json:"currency"`
+}
+
+// MarshalJSON encodes Money as {"amount":"12.34","currency":"USD"}
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Amount   string `json:"amount"`
+		Currency string `json:"currency"`
+	}{Amount: m.Amount(), Currency: m.currency})
+}
+
+// UnmarshalJSON decodes Money from an object whose amount is a decimal string or number
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var raw moneyJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("invalid money value: %w", err)
+	}
+	parsed, err := Parse(raw.Amount.String(), raw.Currency)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+func (m Money) sameCurrency(o Money) error {
+	if m.currency != o.currency {
+		return fmt.Errorf("%w: %s vs %s", ErrCurrencyMismatch, m.currency, o.currency)
+	}
+	return nil
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+// divRoundHalfAway divides n by d (d > 0), rounding half away from zero
+func divRoundHalfAway(n, d *big.Int) *big.Int {
+	q, r := new(big.Int).QuoRem(n, d, new(big.Int))
+	twiceRem := new(big.Int).Abs(r)
+	twiceRem.Lsh(twiceRem, 1)
+	if twiceRem.Cmp(d) >= 0 {
+		if n.Sign() < 0 {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	return q
+}
+
+// formatDecimal renders an integer scaled by 10^-exp as a decimal string
+func formatDecimal(n *big.Int, exp int) string {
+	digits := new(big.Int).Abs(n).String()
+	sign := ""
+	if n.Sign() < 0 {
+		sign = "-"
+	}
+	if exp == 0 {
+		return sign + digits
+	}
+	if len(digits) <= exp {
+		digits = strings.Repeat("0", exp-len(digits)+1) + digits
+	}
+	return sign + digits[:len(digits)-exp] + "." + digits[len(digits)-exp:]
+}
@@ -0,0 +1,287 @@
+package money
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   string
+		currency string
+		units    int64
+		wantErr  bool
+	}{
+		{"dollars and cents", "12.34", "USD", 1234, false},
+		{"whole dollars", "10", "USD", 1000, false},
+		{"single fraction digit", "0.5", "EUR", 50, false},
+		{"trailing zeros from numeric", "100.00000000", "USD", 10000, false},
+		{"negative", "-7.25", "GBP", -725, false},
+		{"zero exponent currency", "1500", "JPY", 1500, false},
+		{"three digit exponent", "1.234", "KWD", 1234, false},
+		{"leading point", ".99", "USD", 99, false},
+		{"too precise for currency", "12.345", "USD", 0, true},
+		{"fraction on zero exponent", "10.5", "JPY", 0, true},
+		{"unknown currency", "1.00", "XYZ", 0, true},
+		{"not a number", "abc", "USD", 0, true},
+		{"empty", "", "USD", 0, true},
+		{"dangling point", "1.", "USD", 0, true},
+		{"exponent notation", "1e3", "USD", 0, true},
+		{"overflow", "999999999999999999999", "USD", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := Parse(tt.amount, tt.currency)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.units, m.MinorUnits())
+			assert.Equal(t, tt.currency, m.Currency())
+		})
+	}
+}
+
+func TestAmountFormatting(t *testing.T) {
+	assert.Equal(t, "12.34", MustNew(1234, "USD").Amount())
+	assert.Equal(t, "0.05", MustNew(5, "USD").Amount())
+	assert.Equal(t, "-0.05", MustNew(-5, "USD").Amount())
+	assert.Equal(t, "1234", MustNew(1234, "JPY").Amount())
+	assert.Equal(t, "1.234", MustNew(1234, "BHD").Amount())
+	assert.Equal(t, "12.34 USD", MustNew(1234, "USD").String())
+}
+
+func TestArithmetic(t *testing.T) {
+	a := MustParse("0.10", "USD")
+	b := MustParse("0.20", "USD")
+
+	sum, err := a.Add(b)
+	require.NoError(t, err)
+	assert.True(t, sum.Equal(MustParse("0.30", "USD")), "0.10 + 0.20 must be exactly 0.30")
+
+	diff, err := a.Sub(b)
+	require.NoError(t, err)
+	assert.Equal(t, int64(-10), diff.MinorUnits())
+	assert.True(t, diff.IsNegative())
+	assert.Equal(t, int64(10), diff.Abs().MinorUnits())
+
+	cmp, err := a.Cmp(b)
+	require.NoError(t, err)
+	assert.Equal(t, -1, cmp)
+	assert.True(t, a.LessThan(b))
+	assert.True(t, b.GreaterThan(a))
+
+	_, err = a.Add(MustParse("0.10", "EUR"))
+	assert.ErrorIs(t, err, ErrCurrencyMismatch)
+
+	_, err = MustNew(1<<62, "USD").Add(MustNew(1<<62, "USD"))
+	assert.ErrorIs(t, err, ErrOverflow)
+}
+
+func TestMulRate(t *testing.T) {
+	tests := []struct {
+		name   string
+		amount Money
+		rate   Rate
+		want   int64
+	}{
+		{"five percent", MustParse("1000.00", "USD"), MustParseRate("0.05"), 5000},
+		{"rounds half up", MustParse("0.10", "USD"), MustParseRate("0.05"), 1},
+		{"rounds down below half", MustParse("0.09", "USD"), MustParseRate("0.05"), 0},
+		{"negative rounds away from zero", MustParse("-0.10", "USD"), MustParseRate("0.05"), -1},
+		{"two and a half percent", MustParse("333.33", "EUR"), BasisPoints(250), 833},
+		{"zero exponent", MustParse("999", "JPY"), MustParseRate("0.02"), 20},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.amount.MulRate(tt.rate)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got.MinorUnits())
+			assert.Equal(t, tt.amount.Currency(), got.Currency())
+		})
+	}
+}
+
+func TestMulRateAccumulatesWithoutDrift(t *testing.T) {
+	// Each 5% of $0.30 rounds to $0.02; 10,000 updates must total exactly $200.00
+	total := MustNew(0, "USD")
+	amount := MustParse("0.30", "USD")
+	rate := MustParseRate("0.05")
+	for i := 0; i < 10000; i++ {
+		part, err := amount.MulRate(rate)
+		require.NoError(t, err)
+		total, err = total.Add(part)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, "200.00", total.Amount())
+}
+
+func TestProrate(t *testing.T) {
+	tests := []struct {
+		name   string
+		amount Money
+		part   Money
+		whole  Money
+		want   int64
+	}{
+		{"whole", MustParse("15.00", "USD"), MustParse("100.00", "USD"), MustParse("100.00", "USD"), 1500},
+		{"quarter", MustParse("15.00", "USD"), MustParse("25.00", "USD"), MustParse("100.00", "USD"), 375},
+		{"third rounds down", MustParse("15.00", "USD"), MustParse("1.00", "USD"), MustParse("3.00", "USD"), 500},
+		{"rounds half up", MustParse("0.05", "USD"), MustParse("1.00", "USD"), MustParse("2.00", "USD"), 3},
+		{"other currency", MustParse("15.00", "USD"), MustParse("500", "JPY"), MustParse("2000", "JPY"), 375},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.amount.Prorate(tt.part, tt.whole)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got.MinorUnits())
+			assert.Equal(t, tt.amount.Currency(), got.Currency())
+		})
+	}
+
+	_, err := MustParse("15.00", "USD").Prorate(MustParse("1.00", "USD"), MustParse("2.00", "EUR"))
+	assert.ErrorIs(t, err, ErrCurrencyMismatch)
+	_, err = MustParse("15.00", "USD").Prorate(MustParse("1.00", "USD"), MustNew(0, "USD"))
+	assert.Error(t, err)
+}
+
+func TestFromNumeric(t *testing.T) {
+	// 123.45000000 as stored in a NUMERIC(20, 8) column
+	n := pgtype.Numeric{Int: big.NewInt(12345000000), Exp: -8, Valid: true}
+	m, err := FromNumeric(n, "USD")
+	require.NoError(t, err)
+	assert.Equal(t, int64(12345), m.MinorUnits())
+
+	// 5E+2 with a positive exponent
+	m, err = FromNumeric(pgtype.Numeric{Int: big.NewInt(5), Exp: 2, Valid: true}, "JPY")
+	require.NoError(t, err)
+	assert.Equal(t, int64(500), m.MinorUnits())
+
+	// 0.001 cannot be represented in USD without rounding
+	_, err = FromNumeric(pgtype.Numeric{Int: big.NewInt(1), Exp: -3, Valid: true}, "USD")
+	assert.Error(t, err)
+
+	_, err = FromNumeric(pgtype.Numeric{}, "USD")
+	assert.Error(t, err)
+
+	_, err = FromNumeric(pgtype.Numeric{NaN: true, Valid: true}, "USD")
+	assert.Error(t, err)
+}
+
+func TestNumericValueRoundTrip(t *testing.T) {
+	original := MustParse("42.07", "USD")
+	n, err := original.NumericValue()
+	require.NoError(t, err)
+
+	back, err := FromNumeric(n, "USD")
+	require.NoError(t, err)
+	assert.True(t, original.Equal(back))
+}
+
+func TestJSON(t *testing.T) {
+	data, err := json.Marshal(MustParse("19.99", "USD"))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"amount":"19.99","currency":"USD"}`, string(data))
+
+	var m Money
+	require.NoError(t, json.Unmarshal([]byte(`{"amount":"19.99","currency":"USD"}`), &m))
+	assert.Equal(t, int64(1999), m.MinorUnits())
+
+	// Numeric literals are read exactly, never through float64
+	require.NoError(t, json.Unmarshal([]byte(`{"amount":0.3,"currency":"USD"}`), &m))
+	assert.Equal(t, int64(30), m.MinorUnits())
+
+	assert.Error(t, json.Unmarshal([]byte(`{"amount":"1.001","currency":"USD"}`), &m))
+	assert.Error(t, json.Unmarshal([]byte(`{"amount":"1.00","currency":"usd"}`), &m))
+}
+
+func TestRate(t *testing.T) {
+	r, err := ParseRate("0.05")
+	require.NoError(t, err)
+	assert.Equal(t, int64(500), r.BasisPoints())
+	assert.Equal(t, "0.0500", r.String())
+
+	_, err = ParseRate("0.00001")
+	assert.Error(t, err)
+	_, err = ParseRate("-0.05")
+	assert.Error(t, err)
+
+	n, err := r.NumericValue()
+	require.NoError(t, err)
+	back, err := RateFromNumeric(n)
+	require.NoError(t, err)
+	assert.Equal(t, r, back)
+
+	data, err := json.Marshal(r)
+	require.NoError(t, err)
+	assert.Equal(t, `"0.0500"`, string(data))
+}
+
+func TestFXRate(t *testing.T) {
+	r, err := ParseFXRate("0.92375")
+	require.NoError(t, err)
+	assert.Equal(t, "0.9237500000", r.String())
+
+	eur, err := r.Convert(MustParse("100.00", "USD"), "EUR")
+	require.NoError(t, err)
+	assert.Equal(t, MustParse("92.38", "EUR"), eur)
+
+	// currencies with different minor-unit exponents
+	jpy, err := MustParseFXRate("149.6234").Convert(MustParse("10.00", "USD"), "JPY")
+	require.NoError(t, err)
+	assert.Equal(t, MustParse("1496", "JPY"), jpy)
+	kwd, err := MustParseFXRate("0.0020512345").Convert(MustParse("10000", "JPY"), "KWD")
+	require.NoError(t, err)
+	assert.Equal(t, MustParse("20.512", "KWD"), kwd)
+
+	_, err = r.Convert(MustParse("1.00", "USD"), "ZZZ")
+	assert.Error(t, err)
+	_, err = ParseFXRate("0.00000000001")
+	assert.Error(t, err)
+
+	n, err := r.NumericValue()
+	require.NoError(t, err)
+	back, err := FXRateFromNumeric(n)
+	require.NoError(t, err)
+	assert.Equal(t, r, back)
+
+	var decoded FXRate
+	require.NoError(t, json.Unmarshal([]byte(`"0.92375"`), &decoded))
+	assert.Equal(t, r, decoded)
+}
+
+func TestFXRateWithinSpread(t *testing.T) {
+	market := MustParseFXRate("0.92")
+	spread := BasisPoints(200)
+
+	assert.True(t, market.WithinSpread(market, spread))
+	// exactly 2% either side is allowed
+	assert.True(t, MustParseFXRate("0.9384").WithinSpread(market, spread))
+	assert.True(t, MustParseFXRate("0.9016").WithinSpread(market, spread))
+	assert.False(t, MustParseFXRate("0.93841").WithinSpread(market, spread))
+	assert.False(t, MustParseFXRate("0.90159").WithinSpread(market, spread))
+	assert.False(t, MustParseFXRate("9.2").WithinSpread(market, spread))
+
+	// a zero spread only allows the market rate itself
+	assert.False(t, MustParseFXRate("0.9200000001").WithinSpread(market, Rate{}))
+}
+
+func TestExponent(t *testing.T) {
+	for currency, want := range map[string]int{"USD": 2, "JPY": 0, "KWD": 3, "CLF": 4} {
+		got, err := Exponent(currency)
+		require.NoError(t, err)
+		assert.Equal(t, want, got, currency)
+	}
+	_, err := Exponent("ZZZ")
+	assert.Error(t, err)
+}
@@ -0,0 +1,141 @@
+package money
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// basisPointsPerUnit is the number of basis points in a ratio of 1.0
+const basisPointsPerUnit = 10000
+
+// rateScale is the number of decimal places a Rate carries (NUMERIC(5, 4))
+const rateScale = 4
+
+// Rate is an exact ratio with four decimal places (basis point precision),
+// e.g. 0.0500 for a 5% reserve. It replaces float64 percentages.
+type Rate struct {
+	bp int64
+}
+
+// BasisPoints creates a Rate from a whole number of basis points (1bp = 0.0001)
+func BasisPoints(bp int64) Rate {
+	return Rate{bp: bp}
+}
+
+// ParseRate converts a decimal ratio string such as "0.05" into a Rate
+func ParseRate(s string) (Rate, error) {
+	bp, err := parseScaled(s, rateScale)
+	if err != nil {
+		return Rate{}, err
+	}
+	return Rate{bp: bp}, nil
+}
+
+// parseScaled converts a non-negative decimal string into an integer scaled by
+// 10^scale, rejecting any non-zero digits beyond scale decimal places
+func parseScaled(s string, scale int) (int64, error) {
+	intPart, fracPart, hasPoint := strings.Cut(strings.TrimSpace(s), ".")
+	if intPart == "" && fracPart == "" || hasPoint && fracPart == "" || !isDigits(intPart) || !isDigits(fracPart) {
+		return 0, fmt.Errorf("invalid rate %q", s)
+	}
+	if len(fracPart) > scale {
+		if strings.Trim(fracPart[scale:], "0") != "" {
+			return 0, fmt.Errorf("rate %q has more than %d decimal places", s, scale)
+		}
+		fracPart = fracPart[:scale]
+	}
+	fracPart += strings.Repeat("0", scale-len(fracPart))
+
+	digits := strings.TrimLeft(intPart+fracPart, "0")
+	if digits == "" {
+		digits = "0"
+	}
+	n, ok := new(big.Int).SetString(digits, 10)
+	if !ok || !n.IsInt64() {
+		return 0, fmt.Errorf("invalid rate %q", s)
+	}
+	return n.Int64(), nil
+}
+
+// MustParseRate is like ParseRate but panics on error
+func MustParseRate(s string) Rate {
+	r, err := ParseRate(s)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// RateFromNumeric converts a PostgreSQL NUMERIC ratio into a Rate
+func RateFromNumeric(n pgtype.Numeric) (Rate, error) {
+	bp, err := scaledFromNumeric(n, rateScale)
+	if err != nil {
+		return Rate{}, err
+	}
+	return Rate{bp: bp}, nil
+}
+
+// scaledFromNumeric converts a PostgreSQL NUMERIC into an integer scaled by 10^scale
+func scaledFromNumeric(n pgtype.Numeric, scale int) (int64, error) {
+	if !n.Valid || n.NaN || n.InfinityModifier != pgtype.Finite {
+		return 0, fmt.Errorf("cannot convert numeric to rate")
+	}
+	v := new(big.Int).Set(n.Int)
+	shift := int(n.Exp) + scale
+	if shift >= 0 {
+		v.Mul(v, pow10(shift))
+	} else {
+		var rem big.Int
+		v.QuoRem(v, pow10(-shift), &rem)
+		if rem.Sign() != 0 {
+			return 0, fmt.Errorf("numeric rate has more than %d decimal places", scale)
+		}
+	}
+	if !v.IsInt64() {
+		return 0, ErrOverflow
+	}
+	return v.Int64(), nil
+}
+
+// BasisPoints returns the rate in basis points
+func (r Rate) BasisPoints() int64 {
+	return r.bp
+}
+
+// IsZero reports whether the rate is zero
+func (r Rate) IsZero() bool {
+	return r.bp == 0
+}
+
+// String returns the rate as a decimal ratio, e.g. "0.0500"
+func (r Rate) String() string {
+	return formatDecimal(big.NewInt(r.bp), rateScale)
+}
+
+// NumericValue implements pgtype.NumericValuer
+func (r Rate) NumericValue() (pgtype.Numeric, error) {
+	return pgtype.Numeric{Int: big.NewInt(r.bp), Exp: -rateScale, Valid: true}, nil
+}
+
+// MarshalJSON encodes the rate as a decimal string
+func (r Rate) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.String())
+}
+
+// UnmarshalJSON decodes a rate from a decimal string or number
+func (r *Rate) UnmarshalJSON(data []byte) error {
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return fmt.Errorf("invalid rate value: %w", err)
+	}
+	parsed, err := ParseRate(n.String())
+	if err != nil {
+		return err
+	}
+	*r = parsed
+	return nil
+}
@@ -0,0 +1,58 @@
+// Package outbox is the transactional outbox of the ledger. Writers append an
+// event in the same database transaction as the change it describes, so an
+// event exists exactly when its change committed. Events are numbered once
+// committed (see PostgresStore.Sequence) and a Relay delivers them in that
+// order to a Sink, at least once, resuming from the consumer's saved offset.
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Event types
+const (
+	EventTransactionPosted    = "transaction.posted"
+	EventAccountCreated       = "account.created"
+	EventAccountStatusChanged = "account.status_changed"
+	EventDisputeTransitioned  = "dispute.transitioned"
+)
+
+// Event is a committed change. Sequence is given after commit, numbering the
+// committed events not yet sequenced by created_at, and is the offset
+// consumers resume from; ID is stable across redeliveries.
+type Event struct {
+	Sequence      int64           `json:"sequence"`
+	ID            string          `json:"id"`
+	EventType     string          `json:"event_type"`
+	AggregateType string          `json:"aggregate_type"`
+	AggregateID   string          `json:"aggregate_id"`
+	Payload       json.RawMessage `json:"payload"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
+
+// Execer runs a statement. Append is given the pgx.Tx of the change.
+type Execer interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+}
+
+// Append records an event within tx. The payload is stored as JSON.
+func Append(ctx context.Context, tx Execer, eventType, aggregateType, aggregateID string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event: %w", eventType, err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO outbox_events (event_type, aggregate_type, aggregate_id, payload)
+		VALUES ($1, $2, $3, $4)
+	`, eventType, aggregateType, aggregateID, data)
+	if err != nil {
+		return fmt.Errorf("failed to append %s event: %w", eventType, err)
+	}
+	return nil
+}
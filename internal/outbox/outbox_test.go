@@ -0,0 +1,243 @@
+package outbox
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSource holds events already sequenced
+type fakeSource struct {
+	mu     sync.Mutex
+	events []Event
+	fail   int
+}
+
+func (s *fakeSource) Sequence(ctx context.Context, limit int) (int, error) {
+	return 0, nil
+}
+
+func (s *fakeSource) Events(ctx context.Context, after int64, limit int) ([]Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fail > 0 {
+		s.fail--
+		return nil, errors.New("connection reset")
+	}
+	var out []Event
+	for _, e := range s.events {
+		if e.Sequence > after && len(out) < limit {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+type fakeOffsets struct {
+	mu      sync.Mutex
+	offsets map[string]int64
+}
+
+func (o *fakeOffsets) Offset(ctx context.Context, consumer string) (int64, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.offsets[consumer], nil
+}
+
+func (o *fakeOffsets) SaveOffset(ctx context.Context, consumer string, sequence int64) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.offsets[consumer] = sequence
+	return nil
+}
+
+func (o *fakeOffsets) get(consumer string) int64 {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.offsets[consumer]
+}
+
+// fakeSink records what it receives and fails the first fail batches
+type fakeSink struct {
+	mu       sync.Mutex
+	batches  [][]Event
+	fail     int
+	attempts int
+}
+
+func (s *fakeSink) Publish(ctx context.Context, events []Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attempts++
+	if s.fail > 0 {
+		s.fail--
+		return errors.New("sink unavailable")
+	}
+	s.batches = append(s.batches, events)
+	return nil
+}
+
+func (s *fakeSink) sequences() []int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var seqs []int64
+	for _, b := range s.batches {
+		for _, e := range b {
+			seqs = append(seqs, e.Sequence)
+		}
+	}
+	return seqs
+}
+
+func testEvents(n int) []Event {
+	types := []string{EventTransactionPosted, EventAccountCreated, EventDisputeTransitioned}
+	events := make([]Event, n)
+	for i := range events {
+		events[i] = Event{
+			Sequence:      int64(i + 1),
+			ID:            string(rune('a' + i)),
+			EventType:     types[i%len(types)],
+			AggregateType: "transaction",
+			AggregateID:   "tx",
+			Payload:       json.RawMessage(`{}`),
+			CreatedAt:     time.Date(2024, 1, 1, 0, 0, i, 0, time.UTC),
+		}
+	}
+	return events
+}
+
+// runRelay runs r until cond holds, then stops it
+func runRelay(t *testing.T, r *Relay, cond func() bool) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- r.Run(ctx) }()
+
+	assert.Eventually(t, cond, 2*time.Second, time.Millisecond)
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+}
+
+func TestRelayResumesFromOffset(t *testing.T) {
+	sink := &fakeSink{}
+	offsets := &fakeOffsets{offsets: map[string]int64{"warehouse": 2}}
+	r := &Relay{
+		Source:       &fakeSource{events: testEvents(7)},
+		Sink:         sink,
+		Offsets:      offsets,
+		Consumer:     "warehouse",
+		BatchSize:    2,
+		PollInterval: time.Millisecond,
+	}
+
+	runRelay(t, r, func() bool { return offsets.get("warehouse") == 7 })
+	assert.Equal(t, []int64{3, 4, 5, 6, 7}, sink.sequences())
+}
+
+func TestRelayRetriesFailedBatch(t *testing.T) {
+	sink := &fakeSink{fail: 2}
+	offsets := &fakeOffsets{offsets: map[string]int64{}}
+	r := &Relay{
+		Source:       &fakeSource{events: testEvents(3), fail: 1},
+		Sink:         sink,
+		Offsets:      offsets,
+		Consumer:     "risk",
+		PollInterval: time.Millisecond,
+		MaxBackoff:   2 * time.Millisecond,
+	}
+
+	runRelay(t, r, func() bool { return offsets.get("risk") == 3 })
+	assert.Equal(t, 3, sink.attempts)
+	// Nothing was acknowledged until the sink accepted the batch
+	assert.Equal(t, []int64{1, 2, 3}, sink.sequences())
+}
+
+func TestRelayFiltersEventTypes(t *testing.T) {
+	sink := &fakeSink{}
+	offsets := &fakeOffsets{offsets: map[string]int64{}}
+	r := &Relay{
+		Source:       &fakeSource{events: testEvents(6)},
+		Sink:         sink,
+		Offsets:      offsets,
+		Consumer:     "notifications",
+		EventTypes:   []string{EventDisputeTransitioned},
+		PollInterval: time.Millisecond,
+	}
+
+	runRelay(t, r, func() bool { return offsets.get("notifications") == 6 })
+	assert.Equal(t, []int64{3, 6}, sink.sequences())
+}
+
+func TestRelayWithoutOffsets(t *testing.T) {
+	sink := &fakeSink{}
+	r := &Relay{
+		Source:       &fakeSource{events: testEvents(4)},
+		Sink:         sink,
+		After:        1,
+		PollInterval: time.Millisecond,
+	}
+
+	runRelay(t, r, func() bool { return len(sink.sequences()) == 3 })
+	assert.Equal(t, []int64{2, 3, 4}, sink.sequences())
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	sink, err := NewFileSink(path)
+	require.NoError(t, err)
+
+	events := testEvents(3)
+	require.NoError(t, sink.Publish(context.Background(), events[:2]))
+	require.NoError(t, sink.Publish(context.Background(), events[2:]))
+	require.NoError(t, sink.Close())
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	var got []Event
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e Event
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &e))
+		got = append(got, e)
+	}
+	require.NoError(t, scanner.Err())
+	assert.Equal(t, events, got)
+}
+
+func TestWebhookSink(t *testing.T) {
+	var received []Event
+	status := http.StatusNoContent
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		var body struct {
+			Events []Event `json:"events"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		received = append(received, body.Events...)
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	sink := &WebhookSink{URL: srv.URL, Client: srv.Client()}
+	events := testEvents(2)
+	require.NoError(t, sink.Publish(context.Background(), events))
+	assert.Equal(t, events, received)
+
+	status = http.StatusServiceUnavailable
+	err := sink.Publish(context.Background(), events)
+	assert.ErrorContains(t, err, "503")
+}
@@ -0,0 +1,166 @@
+package outbox
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Default relay settings
+const (
+	DefaultBatchSize    = 100
+	DefaultPollInterval = time.Second
+	DefaultMaxBackoff   = time.Minute
+)
+
+// Source is where a Relay reads events from
+type Source interface {
+	// Sequence numbers newly committed events, up to limit of them
+	Sequence(ctx context.Context, limit int) (int, error)
+	// Events lists sequenced events after the given sequence, oldest first
+	Events(ctx context.Context, after int64, limit int) ([]Event, error)
+}
+
+// Offsets stores how far each consumer has got
+type Offsets interface {
+	Offset(ctx context.Context, consumer string) (int64, error)
+	SaveOffset(ctx context.Context, consumer string, sequence int64) error
+}
+
+// Sink receives events in sequence order. A batch that fails is sent again,
+// so a sink may see an event more than once and should deduplicate on ID.
+type Sink interface {
+	Publish(ctx context.Context, events []Event) error
+}
+
+// Relay delivers events from a Source to a Sink at least once. With Offsets
+// set it starts from, and saves, the Consumer's offset; otherwise it starts
+// after After and keeps its position in memory only.
+type Relay struct {
+	Source   Source
+	Sink     Sink
+	Offsets  Offsets
+	Consumer string
+	After    int64
+	// EventTypes, if set, restricts delivery to these types. Other events are
+	// skipped but still count towards the offset.
+	EventTypes   []string
+	BatchSize    int
+	PollInterval time.Duration
+	MaxBackoff   time.Duration
+}
+
+// Run delivers events until ctx is done, when it returns ctx.Err(). A batch
+// is only acknowledged once the sink accepts it; failures are retried with
+// exponential backoff.
+func (r *Relay) Run(ctx context.Context) error {
+	batchSize := r.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	poll := r.PollInterval
+	if poll <= 0 {
+		poll = DefaultPollInterval
+	}
+	maxBackoff := r.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = DefaultMaxBackoff
+	}
+
+	after := r.After
+	if r.Offsets != nil {
+		for {
+			offset, err := r.Offsets.Offset(ctx, r.Consumer)
+			if err == nil {
+				after = offset
+				break
+			}
+			log.Printf("outbox relay %s: %v", r.Consumer, err)
+			if err := sleep(ctx, poll); err != nil {
+				return err
+			}
+		}
+	}
+
+	backoff := poll
+	for {
+		next, full, err := r.deliver(ctx, after, batchSize)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			log.Printf("outbox relay %s: %v; retrying in %s", r.Consumer, err, backoff)
+			if err := sleep(ctx, backoff); err != nil {
+				return err
+			}
+			backoff = min(backoff*2, maxBackoff)
+			continue
+		}
+		backoff = poll
+
+		if next > after && r.Offsets != nil {
+			if err := r.Offsets.SaveOffset(ctx, r.Consumer, next); err != nil {
+				// The batch was delivered; if the offset is lost it is
+				// delivered again after a restart
+				log.Printf("outbox relay %s: %v", r.Consumer, err)
+			}
+		}
+		after = next
+
+		if full {
+			continue
+		}
+		if err := sleep(ctx, poll); err != nil {
+			return err
+		}
+	}
+}
+
+// deliver sends the next batch after the given sequence and returns the
+// sequence delivered up to and whether the batch was full
+func (r *Relay) deliver(ctx context.Context, after int64, limit int) (int64, bool, error) {
+	if _, err := r.Source.Sequence(ctx, limit); err != nil {
+		return after, false, err
+	}
+	events, err := r.Source.Events(ctx, after, limit)
+	if err != nil {
+		return after, false, err
+	}
+	if len(events) == 0 {
+		return after, false, nil
+	}
+
+	if wanted := r.filter(events); len(wanted) > 0 {
+		if err := r.Sink.Publish(ctx, wanted); err != nil {
+			return after, false, err
+		}
+	}
+	return events[len(events)-1].Sequence, len(events) == limit, nil
+}
+
+func (r *Relay) filter(events []Event) []Event {
+	if len(r.EventTypes) == 0 {
+		return events
+	}
+	var wanted []Event
+	for _, e := range events {
+		for _, t := range r.EventTypes {
+			if e.EventType == t {
+				wanted = append(wanted, e)
+				break
+			}
+		}
+	}
+	return wanted
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
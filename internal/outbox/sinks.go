@@ -0,0 +1,97 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// FileSink appends events to a file as JSON lines
+type FileSink struct {
+	mu sync.Mutex
+	f  *os.File
+}
+
+// NewFileSink opens path for appending, creating it if needed
+func NewFileSink(path string) (*FileSink, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o640)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open outbox file: %w", err)
+	}
+	return &FileSink{f: f}, nil
+}
+
+// Publish writes the events and syncs the file, so an acknowledged batch
+// survives a crash
+func (s *FileSink) Publish(ctx context.Context, events []Event) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, e := range events {
+		if err := enc.Encode(e); err != nil {
+			return fmt.Errorf("failed to encode event %s: %w", e.ID, err)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.f.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to write outbox file: %w", err)
+	}
+	if err := s.f.Sync(); err != nil {
+		return fmt.Errorf("failed to sync outbox file: %w", err)
+	}
+	return nil
+}
+
+// Close closes the file
+func (s *FileSink) Close() error {
+	return s.f.Close()
+}
+
+// webhookTimeout bounds a single webhook delivery when no Client is set
+const webhookTimeout = 30 * time.Second
+
+// WebhookSink POSTs each batch to URL as {"events": [...]}. Any response
+// other than 2xx fails the batch.
+type WebhookSink struct {
+	URL    string
+	Client *http.Client
+}
+
+// Publish sends the events
+func (s *WebhookSink) Publish(ctx context.Context, events []Event) error {
+	body, err := json.Marshal(struct {
+		Events []Event `json:"events"`
+	}{events})
+	if err != nil {
+		return fmt.Errorf("failed to encode events: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := s.Client
+	if client == nil {
+		client = &http.Client{Timeout: webhookTimeout}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to deliver events: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// sequenceLock is the advisory lock held while numbering events, so
+// concurrent sequencers cannot hand out the same number
+const sequenceLock int64 = 0x6f7574626f78
+
+// PostgresStore reads the outbox_events table and keeps consumer offsets in
+// outbox_offsets
+type PostgresStore struct {
+	Pool *pgxpool.Pool
+}
+
+// Sequence numbers up to limit committed events that have no sequence yet,
+// continuing from the highest number given so far, and returns how many it
+// numbered. Only committed events are visible here, and they are numbered in
+// created_at order rather than commit order. Since a sequence is only given
+// once the writing transaction has committed, a consumer that has read up to
+// N never later finds an event numbered N or below.
+func (s *PostgresStore) Sequence(ctx context.Context, limit int) (int, error) {
+	tx, err := s.Pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1)`, sequenceLock); err != nil {
+		return 0, fmt.Errorf("failed to lock outbox sequence: %w", err)
+	}
+
+	tag, err := tx.Exec(ctx, `
+		WITH last AS (
+			SELECT COALESCE(MAX(sequence), 0) AS sequence FROM outbox_events
+		), pending AS (
+			SELECT id, row_number() OVER (ORDER BY created_at, id) AS n
+			FROM outbox_events
+			WHERE sequence IS NULL
+			ORDER BY created_at, id
+			LIMIT $1
+		)
+		UPDATE outbox_events e
+		SET sequence = last.sequence + pending.n
+		FROM pending, last
+		WHERE e.id = pending.id
+	`, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to sequence outbox events: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+// Events lists up to limit sequenced events after the given sequence, oldest
+// first
+func (s *PostgresStore) Events(ctx context.Context, after int64, limit int) ([]Event, error) {
+	rows, err := s.Pool.Query(ctx, `
+		SELECT sequence, id, event_type, aggregate_type, aggregate_id, payload, created_at
+		FROM outbox_events
+		WHERE sequence > $1
+		ORDER BY sequence
+		LIMIT $2
+	`, after, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query outbox events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []Event
+	for rows.Next() {
+		var e Event
+		if err := rows.Scan(&e.Sequence, &e.ID, &e.EventType, &e.AggregateType, &e.AggregateID, &e.Payload, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan outbox event: %w", err)
+		}
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read outbox events: %w", err)
+	}
+	return events, nil
+}
+
+// Offset is the last sequence the consumer has processed, 0 for a new one
+func (s *PostgresStore) Offset(ctx context.Context, consumer string) (int64, error) {
+	var offset int64
+	err := s.Pool.QueryRow(ctx, `SELECT sequence FROM outbox_offsets WHERE consumer = $1`, consumer).Scan(&offset)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return 0, fmt.Errorf("failed to get offset of %s: %w", consumer, err)
+	}
+	return offset, nil
+}
+
+// SaveOffset records that the consumer has processed every event up to
+// sequence. Offsets never move backwards.
+func (s *PostgresStore) SaveOffset(ctx context.Context, consumer string, sequence int64) error {
+	_, err := s.Pool.Exec(ctx, `
+		INSERT INTO outbox_offsets (consumer, sequence)
+		VALUES ($1, $2)
+		ON CONFLICT (consumer) DO UPDATE
+		SET sequence = GREATEST(outbox_offsets.sequence, EXCLUDED.sequence),
+			updated_at = CURRENT_TIMESTAMP
+	`, consumer, sequence)
+	if err != nil {
+		return fmt.Errorf("failed to save offset of %s: %w", consumer, err)
+	}
+	return nil
+}
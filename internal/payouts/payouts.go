@@ -0,0 +1,281 @@
+// Package payouts settles merchant funds. Each merchant's funds accrue in a
+// payable account; a payout pays out its posted balance less pending
+// authorizations, active dispute holds and the merchant's fraud reserve, once
+// that reaches the merchant's minimum. Payouts are made daily, weekly or on
+// demand, less any payout fee. Creating a payout posts its legs; it then moves
+// from pending to sent to paid, and one that fails or is returned by the bank
+// is reversed in the ledger, refunding its fee.
+package payouts
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/example/pci-infra/internal/fees"
+	"github.com/example/pci-infra/internal/ledger"
+	"github.com/example/pci-infra/internal/money"
+)
+
+// Payout schedules. On-demand merchants are only paid out when a payout is
+// requested; scheduled merchants may request one too.
+const (
+	ScheduleDaily    = "daily"
+	ScheduleWeekly   = "weekly"
+	ScheduleOnDemand = "on_demand"
+)
+
+// Payout statuses. A payout is pending until sent to the bank and paid once
+// the bank confirms it. Failed is final: the payout was rejected or returned
+// and its postings reversed.
+const (
+	StatusPending = "pending"
+	StatusSent    = "sent"
+	StatusPaid    = "paid"
+	StatusFailed  = "failed"
+)
+
+// ReferenceTypePayout marks the legs of a payout; their ReferenceID is the
+// payout's ID
+const ReferenceTypePayout = "payout"
+
+// systemActor reverses failed payouts, approved by whoever reported the failure
+const systemActor = "payouts"
+
+// ErrInvalidPayout is returned when payout settings or a payout request are
+// malformed
+var ErrInvalidPayout = fmt.Errorf("%w: payout", ledger.ErrInvalidRequest)
+
+// ErrSettingsNotFound is returned when a merchant has no payout settings
+var ErrSettingsNotFound = errors.New("payout settings not found")
+
+// ErrPayoutNotFound is returned when a referenced payout does not exist
+var ErrPayoutNotFound = errors.New("payout not found")
+
+// ErrBelowMinimum is returned when a merchant's payable amount does not reach
+// its minimum payout
+var ErrBelowMinimum = errors.New("payable amount below minimum payout")
+
+// ErrInvalidPayoutTransition is returned when a payout cannot move to the
+// requested status
+var ErrInvalidPayoutTransition = errors.New("invalid payout transition")
+
+// Settings configure a merchant's payouts. Funds are paid out of the payable
+// (liability) account through the funding (asset) account, the bank account
+// payouts leave from. Weekday is the day weekly payouts are made, 0 for Sunday.
+type Settings struct {
+	MerchantID       string      `json:"merchant_id"`
+	PayableAccountID string      `json:"payable_account_id"`
+	FundingAccountID string      `json:"funding_account_id"`
+	Schedule         string      `json:"schedule"`
+	Weekday          *int        `json:"weekday,omitempty"`
+	MinimumAmount    money.Money `json:"minimum_amount"`
+	CurrencyCode     string      `json:"currency_code"`
+	Active           bool        `json:"active"`
+	CreatedAt        string      `json:"created_at,omitempty"`
+	UpdatedAt        string      `json:"updated_at,omitempty"`
+	UpdatedBy        string      `json:"updated_by"`
+}
+
+// Computation is how much a merchant can be paid out now: Payable is Posted
+// less Pending, Held and Reserve. Eligible is whether it reaches Minimum.
+type Computation struct {
+	MerchantID string      `json:"merchant_id"`
+	Posted     money.Money `json:"posted"`
+	Pending    money.Money `json:"pending"`
+	Held       money.Money `json:"held"`
+	Reserve    money.Money `json:"reserve"`
+	Payable    money.Money `json:"payable"`
+	Minimum    money.Money `json:"minimum"`
+	Eligible   bool        `json:"eligible"`
+}
+
+// Payout is a payout instruction and its progress. The balances it was
+// computed from are kept with it. Amount leaves the payable account; the bank
+// pays out Amount less FeeAmount. Trigger is the schedule that made it, or
+// on_demand.
+type Payout struct {
+	ID               string      `json:"id"`
+	MerchantID       string      `json:"merchant_id"`
+	PayableAccountID string      `json:"payable_account_id"`
+	FundingAccountID string      `json:"funding_account_id"`
+	Amount           money.Money `json:"amount"`
+	FeeAmount        money.Money `json:"fee_amount"`
+	FeeScheduleID    string      `json:"fee_schedule_id,omitempty"`
+	PostedBalance    money.Money `json:"posted_balance"`
+	PendingAmount    money.Money `json:"pending_amount"`
+	HeldAmount       money.Money `json:"held_amount"`
+	ReserveAmount    money.Money `json:"reserve_amount"`
+	Trigger          string      `json:"trigger"`
+	PayoutDate       string      `json:"payout_date"`
+	Status           string      `json:"status"`
+	TransactionID    string      `json:"transaction_id"`
+	ReversalID       string      `json:"reversal_id,omitempty"`
+	BankReference    string      `json:"bank_reference,omitempty"`
+	FailureReason    string      `json:"failure_reason,omitempty"`
+	CreatedBy        string      `json:"created_by"`
+	CreatedAt        string      `json:"created_at"`
+	SentAt           string      `json:"sent_at,omitempty"`
+	PaidAt           string      `json:"paid_at,omitempty"`
+	FailedAt         string      `json:"failed_at,omitempty"`
+	UpdatedBy        string      `json:"updated_by"`
+}
+
+// PayoutRequest represents the request for an on-demand payout of everything
+// payable to a merchant
+type PayoutRequest struct {
+	MerchantID string `json:"merchant_id"`
+	CreatedBy  string `json:"created_by"`
+}
+
+// TransitionRequest moves a payout on. BankReference is recorded when it is
+// sent; Reason is required when it fails.
+type TransitionRequest struct {
+	PayoutID      string `json:"payout_id"`
+	BankReference string `json:"bank_reference"`
+	Reason        string `json:"reason"`
+	ChangedBy     string `json:"changed_by"`
+}
+
+// PayoutFilter narrows a payout query. Empty fields match every payout.
+type PayoutFilter struct {
+	MerchantID string
+	Status     string
+	Limit      int
+	Offset     int
+}
+
+// validateSettings checks settings before the accounts are looked up
+func validateSettings(s Settings) error {
+	if s.MerchantID == "" {
+		return fmt.Errorf("%w: merchant ID is required", ErrInvalidPayout)
+	}
+	if s.PayableAccountID == "" || s.FundingAccountID == "" {
+		return fmt.Errorf("%w: payable and funding accounts are required", ErrInvalidPayout)
+	}
+	if s.PayableAccountID == s.FundingAccountID {
+		return fmt.Errorf("%w: payable and funding accounts must differ", ErrInvalidPayout)
+	}
+	if s.UpdatedBy == "" {
+		return fmt.Errorf("%w: updated_by is required", ErrInvalidPayout)
+	}
+	switch s.Schedule {
+	case ScheduleWeekly:
+		if s.Weekday == nil || *s.Weekday < 0 || *s.Weekday > 6 {
+			return fmt.Errorf("%w: weekly payouts need a weekday from 0 (Sunday) to 6", ErrInvalidPayout)
+		}
+	case ScheduleDaily, ScheduleOnDemand:
+		if s.Weekday != nil {
+			return fmt.Errorf("%w: weekday only applies to weekly payouts", ErrInvalidPayout)
+		}
+	default:
+		return fmt.Errorf("%w: unknown schedule %q", ErrInvalidPayout, s.Schedule)
+	}
+	if s.MinimumAmount.IsNegative() {
+		return fmt.Errorf("%w: minimum amount must not be negative", ErrInvalidPayout)
+	}
+	return nil
+}
+
+// checkSettingsAccounts checks that funds can be paid out of payable through
+// funding in the minimum's currency
+func checkSettingsAccounts(s Settings, payable, funding *ledger.Account) error {
+	if payable.AccountType != "liability" {
+		return fmt.Errorf("%w: payable account %s is %s, not liability", ErrInvalidPayout, payable.ID, payable.AccountType)
+	}
+	if funding.AccountType != "asset" {
+		return fmt.Errorf("%w: funding account %s is %s, not asset", ErrInvalidPayout, funding.ID, funding.AccountType)
+	}
+	if payable.CurrencyCode != funding.CurrencyCode {
+		return fmt.Errorf("%w: payable account is in %s, funding account in %s", ledger.ErrCurrencyMismatch, payable.CurrencyCode, funding.CurrencyCode)
+	}
+	if s.MinimumAmount.Currency() != payable.CurrencyCode {
+		return fmt.Errorf("%w: minimum amount is in %s, accounts in %s", ledger.ErrCurrencyMismatch, s.MinimumAmount.Currency(), payable.CurrencyCode)
+	}
+	return nil
+}
+
+// compute works out what can be paid out of balances, withholding reserve. A
+// balance that does not cover what is withheld leaves nothing payable.
+func compute(merchantID string, balances *ledger.AccountBalances, reserve, minimum money.Money) (*Computation, error) {
+	c := &Computation{
+		MerchantID: merchantID,
+		Posted:     balances.Posted,
+		Pending:    balances.Pending,
+		Held:       balances.Held,
+		Reserve:    reserve,
+		Minimum:    minimum,
+	}
+	payable := balances.Posted
+	for _, withheld := range []money.Money{balances.Pending, balances.Held, reserve} {
+		var err error
+		if payable, err = payable.Sub(withheld); err != nil {
+			return nil, fmt.Errorf("%w: %v", ledger.ErrCurrencyMismatch, err)
+		}
+	}
+	if payable.IsNegative() {
+		var err error
+		if payable, err = money.Zero(payable.Currency()); err != nil {
+			return nil, err
+		}
+	}
+	c.Payable = payable
+	c.Eligible = payable.IsPositive() && !payable.LessThan(minimum)
+	return c, nil
+}
+
+// dueOn reports whether a scheduled payout is due for s on day (UTC)
+func dueOn(s Settings, day time.Time) bool {
+	if !s.Active {
+		return false
+	}
+	switch s.Schedule {
+	case ScheduleDaily:
+		return true
+	case ScheduleWeekly:
+		return s.Weekday != nil && int(day.UTC().Weekday()) == *s.Weekday
+	}
+	return false
+}
+
+// payoutTransitions lists the statuses each status may move to. Paid payouts
+// can still fail when the bank returns them.
+var payoutTransitions = map[string][]string{
+	StatusPending: {StatusSent, StatusFailed},
+	StatusSent:    {StatusPaid, StatusFailed},
+	StatusPaid:    {StatusFailed},
+}
+
+// checkTransition reports whether a payout may move from one status to another
+func checkTransition(payoutID, from, to string) error {
+	for _, next := range payoutTransitions[from] {
+		if next == to {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: payout %s is %s, cannot become %s", ErrInvalidPayoutTransition, payoutID, from, to)
+}
+
+// payoutLegs are the legs posted for a payout: the merchant's payable is
+// debited, and the funding account credited what is paid out. A fee is
+// credited to its revenue account.
+func payoutLegs(p *Payout, fee *fees.Fee) ([]ledger.JournalEntry, error) {
+	if fee == nil || fee.Amount.IsZero() {
+		return []ledger.JournalEntry{
+			{AccountID: p.PayableAccountID, EntryType: "debit", Amount: p.Amount},
+			{AccountID: p.FundingAccountID, EntryType: "credit", Amount: p.Amount},
+		}, nil
+	}
+	net, err := p.Amount.Sub(fee.Amount)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ledger.ErrCurrencyMismatch, err)
+	}
+	if !net.IsPositive() {
+		return nil, fmt.Errorf("%w: payout fee %s leaves nothing of %s to pay out", ErrBelowMinimum, fee.Amount, p.Amount)
+	}
+	return []ledger.JournalEntry{
+		{AccountID: p.PayableAccountID, EntryType: "debit", Amount: p.Amount},
+		{AccountID: p.FundingAccountID, EntryType: "credit", Amount: net},
+		{AccountID: fee.RevenueAccountID, EntryType: "credit", Amount: fee.Amount},
+	}, nil
+}
@@ -0,0 +1,196 @@
+package payouts
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/pci-infra/internal/fees"
+	"github.com/example/pci-infra/internal/ledger"
+	"github.com/example/pci-infra/internal/money"
+)
+
+func TestCompute(t *testing.T) {
+	balances := func(posted, pending, held string) *ledger.AccountBalances {
+		return &ledger.AccountBalances{
+			Posted:  money.MustParse(posted, "USD"),
+			Pending: money.MustParse(pending, "USD"),
+			Held:    money.MustParse(held, "USD"),
+		}
+	}
+
+	tests := []struct {
+		name     string
+		balances *ledger.AccountBalances
+		reserve  string
+		minimum  string
+		payable  string
+		eligible bool
+	}{
+		{"nothing withheld", balances("1000.00", "0.00", "0.00"), "0.00", "100.00", "1000.00", true},
+		{"everything withheld", balances("1000.00", "150.00", "200.00"), "50.00", "100.00", "600.00", true},
+		{"exactly the minimum", balances("1000.00", "400.00", "400.00"), "100.00", "100.00", "100.00", true},
+		{"below the minimum", balances("1000.00", "400.00", "400.00"), "100.01", "100.00", "99.99", false},
+		{"withheld exceeds balance", balances("100.00", "80.00", "50.00"), "0.00", "0.00", "0.00", false},
+		{"empty account", balances("0.00", "0.00", "0.00"), "0.00", "0.00", "0.00", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := compute("m-1", tt.balances, money.MustParse(tt.reserve, "USD"), money.MustParse(tt.minimum, "USD"))
+			require.NoError(t, err)
+			assert.Equal(t, tt.payable, c.Payable.Amount())
+			assert.Equal(t, tt.eligible, c.Eligible)
+		})
+	}
+
+	_, err := compute("m-1", balances("100.00", "0.00", "0.00"), money.MustParse("10.00", "EUR"), money.MustParse("0.00", "USD"))
+	assert.True(t, errors.Is(err, ledger.ErrCurrencyMismatch))
+}
+
+func TestValidateSettings(t *testing.T) {
+	monday := 1
+	sunday := 0
+	late := 7
+	valid := Settings{
+		MerchantID:       "m-1",
+		PayableAccountID: "acc-1",
+		FundingAccountID: "acc-2",
+		Schedule:         ScheduleDaily,
+		MinimumAmount:    money.MustParse("25.00", "USD"),
+		UpdatedBy:        "ops",
+	}
+	assert.NoError(t, validateSettings(valid))
+
+	tests := []struct {
+		name   string
+		modify func(*Settings)
+		ok     bool
+	}{
+		{"weekly", func(s *Settings) { s.Schedule, s.Weekday = ScheduleWeekly, &monday }, true},
+		{"weekly on sunday", func(s *Settings) { s.Schedule, s.Weekday = ScheduleWeekly, &sunday }, true},
+		{"on demand", func(s *Settings) { s.Schedule = ScheduleOnDemand }, true},
+		{"weekly without weekday", func(s *Settings) { s.Schedule = ScheduleWeekly }, false},
+		{"weekday out of range", func(s *Settings) { s.Schedule, s.Weekday = ScheduleWeekly, &late }, false},
+		{"daily with weekday", func(s *Settings) { s.Weekday = &monday }, false},
+		{"unknown schedule", func(s *Settings) { s.Schedule = "monthly" }, false},
+		{"same accounts", func(s *Settings) { s.FundingAccountID = s.PayableAccountID }, false},
+		{"missing merchant", func(s *Settings) { s.MerchantID = "" }, false},
+		{"missing actor", func(s *Settings) { s.UpdatedBy = "" }, false},
+		{"negative minimum", func(s *Settings) { s.MinimumAmount = money.MustParse("-1.00", "USD") }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := valid
+			tt.modify(&s)
+			err := validateSettings(s)
+			if tt.ok {
+				assert.NoError(t, err)
+			} else {
+				assert.True(t, errors.Is(err, ErrInvalidPayout), "got %v", err)
+			}
+		})
+	}
+}
+
+func TestCheckSettingsAccounts(t *testing.T) {
+	s := Settings{MinimumAmount: money.MustParse("10.00", "USD")}
+	payable := &ledger.Account{ID: "acc-1", AccountType: "liability", CurrencyCode: "USD"}
+	funding := &ledger.Account{ID: "acc-2", AccountType: "asset", CurrencyCode: "USD"}
+	assert.NoError(t, checkSettingsAccounts(s, payable, funding))
+
+	assert.True(t, errors.Is(checkSettingsAccounts(s, funding, payable), ErrInvalidPayout))
+	euro := &ledger.Account{ID: "acc-3", AccountType: "asset", CurrencyCode: "EUR"}
+	assert.True(t, errors.Is(checkSettingsAccounts(s, payable, euro), ledger.ErrCurrencyMismatch))
+	s.MinimumAmount = money.MustParse("10.00", "EUR")
+	assert.True(t, errors.Is(checkSettingsAccounts(s, payable, funding), ledger.ErrCurrencyMismatch))
+}
+
+func TestDueOn(t *testing.T) {
+	monday := 1
+	// 2024-01-01 was a Monday
+	mon := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	tue := mon.AddDate(0, 0, 1)
+
+	daily := Settings{Schedule: ScheduleDaily, Active: true}
+	weekly := Settings{Schedule: ScheduleWeekly, Weekday: &monday, Active: true}
+	onDemand := Settings{Schedule: ScheduleOnDemand, Active: true}
+	inactive := Settings{Schedule: ScheduleDaily}
+
+	assert.True(t, dueOn(daily, tue))
+	assert.True(t, dueOn(weekly, mon))
+	assert.False(t, dueOn(weekly, tue))
+	// Late Sunday in New York is Monday in UTC
+	assert.True(t, dueOn(weekly, time.Date(2023, 12, 31, 22, 0, 0, 0, time.FixedZone("EST", -5*3600))))
+	assert.False(t, dueOn(onDemand, mon))
+	assert.False(t, dueOn(inactive, mon))
+}
+
+func TestCheckTransition(t *testing.T) {
+	tests := []struct {
+		from, to string
+		ok       bool
+	}{
+		{StatusPending, StatusSent, true},
+		{StatusPending, StatusFailed, true},
+		{StatusSent, StatusPaid, true},
+		{StatusSent, StatusFailed, true},
+		{StatusPaid, StatusFailed, true},
+		{StatusPending, StatusPaid, false},
+		{StatusPaid, StatusSent, false},
+		{StatusFailed, StatusPending, false},
+		{StatusFailed, StatusFailed, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.from+"->"+tt.to, func(t *testing.T) {
+			err := checkTransition("p-1", tt.from, tt.to)
+			if tt.ok {
+				assert.NoError(t, err)
+			} else {
+				assert.True(t, errors.Is(err, ErrInvalidPayoutTransition), "got %v", err)
+			}
+		})
+	}
+}
+
+func TestPayoutLegsBalance(t *testing.T) {
+	p := &Payout{PayableAccountID: "acc-1", FundingAccountID: "acc-2", Amount: money.MustParse("42.50", "USD")}
+	legs, err := payoutLegs(p, nil)
+	require.NoError(t, err)
+	require.Len(t, legs, 2)
+	assert.Equal(t, "acc-1", legs[0].AccountID)
+	assert.Equal(t, "debit", legs[0].EntryType)
+	assert.Equal(t, "acc-2", legs[1].AccountID)
+	assert.Equal(t, "credit", legs[1].EntryType)
+	assert.True(t, legs[0].Amount.Equal(legs[1].Amount))
+}
+
+func TestPayoutLegsWithFee(t *testing.T) {
+	p := &Payout{PayableAccountID: "acc-1", FundingAccountID: "acc-2", Amount: money.MustParse("42.50", "USD")}
+	fee := &fees.Fee{Amount: money.MustParse("2.50", "USD"), RevenueAccountID: "rev-1"}
+	legs, err := payoutLegs(p, fee)
+	require.NoError(t, err)
+	require.Len(t, legs, 3)
+	assert.Equal(t, "debit", legs[0].EntryType)
+	assert.Equal(t, "42.50", legs[0].Amount.Amount())
+	assert.Equal(t, "acc-2", legs[1].AccountID)
+	assert.Equal(t, "40.00", legs[1].Amount.Amount())
+	assert.Equal(t, "rev-1", legs[2].AccountID)
+	assert.Equal(t, "credit", legs[2].EntryType)
+	assert.Equal(t, "2.50", legs[2].Amount.Amount())
+
+	fee.Amount = money.MustParse("42.50", "USD")
+	_, err = payoutLegs(p, fee)
+	assert.True(t, errors.Is(err, ErrBelowMinimum))
+	fee.Amount = money.MustParse("2.50", "EUR")
+	_, err = payoutLegs(p, fee)
+	assert.True(t, errors.Is(err, ledger.ErrCurrencyMismatch))
+}
+
+func TestReversalIDIsStable(t *testing.T) {
+	id := "8a6e0804-2bd0-4672-b79d-d97027f9071a"
+	assert.Equal(t, reversalID(id), reversalID(id))
+	assert.NotEqual(t, reversalID(id), reversalID("1b4e28ba-2fa1-11d2-883f-0016d3cca427"))
+}
@@ -0,0 +1,47 @@
+package payouts
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// DefaultScheduleInterval is how often the scheduler looks for due payouts
+const DefaultScheduleInterval = time.Hour
+
+// schedulerActor creates scheduled payouts
+const schedulerActor = "payout-scheduler"
+
+// Scheduler creates scheduled payouts as they fall due. A day's payouts are
+// made on the scheduler's first run that day (UTC); merchants skipped for too
+// little payable are retried on later runs the same day. Several schedulers
+// may run at once.
+type Scheduler struct {
+	Service  *Service
+	Interval time.Duration
+}
+
+// Run creates due payouts now and every Interval until ctx is done
+func (s *Scheduler) Run(ctx context.Context) error {
+	interval := s.Interval
+	if interval <= 0 {
+		interval = DefaultScheduleInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		n, err := s.Service.RunScheduled(ctx, time.Now())
+		if err != nil && ctx.Err() == nil {
+			log.Printf("failed to run scheduled payouts: %v", err)
+		}
+		if n > 0 {
+			log.Printf("created %d scheduled payouts", n)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
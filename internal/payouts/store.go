@@ -0,0 +1,591 @@
+package payouts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/example/pci-infra/internal/fees"
+	"github.com/example/pci-infra/internal/ledger"
+	"github.com/example/pci-infra/internal/money"
+)
+
+// Default and largest number of payouts ListPayouts returns
+const (
+	defaultPayoutLimit = 50
+	maxPayoutLimit     = 500
+)
+
+const settingsColumns = `merchant_id, payable_account_id, funding_account_id, schedule, weekday,
+	minimum_amount, currency_code, active, created_at::text, updated_at::text, updated_by`
+
+const payoutColumns = `id, merchant_id, payable_account_id, funding_account_id, currency_code, amount,
+	fee_amount, COALESCE(fee_schedule_id::text, ''), posted_balance, pending_amount, held_amount, reserve_amount, trigger, payout_date::text, status,
+	transaction_id, COALESCE(reversal_id::text, ''), COALESCE(bank_reference, ''), COALESCE(failure_reason, ''),
+	created_by, created_at::text, COALESCE(sent_at::text, ''), COALESCE(paid_at::text, ''),
+	COALESCE(failed_at::text, ''), updated_by`
+
+// Ledger is the part of the ledger payouts post through
+type Ledger interface {
+	GetAccountByID(ctx context.Context, accountID string) (*ledger.Account, error)
+	GetAccountBalances(ctx context.Context, accountID string) (*ledger.AccountBalances, error)
+	PostTransaction(ctx context.Context, txn ledger.Transaction) (*ledger.Transaction, error)
+	ReverseTransaction(ctx context.Context, req ledger.ReverseRequest) (*ledger.Reversal, error)
+}
+
+// Fees quotes payout fees
+type Fees interface {
+	Quote(ctx context.Context, req fees.QuoteRequest) (*fees.Fee, error)
+}
+
+// Service manages payout settings and payouts. A payout's legs are posted
+// through Ledger; the payout itself is stored in Pool. Payout fees are quoted
+// by Fees; a nil Fees charges none.
+type Service struct {
+	Pool   *pgxpool.Pool
+	Ledger Ledger
+	Fees   Fees
+}
+
+// NewService creates a payout service over pool, posting through ls and
+// charging fees quoted by fs
+func NewService(pool *pgxpool.Pool, ls Ledger, fs Fees) *Service {
+	return &Service{Pool: pool, Ledger: ls, Fees: fs}
+}
+
+// SetSettings creates or replaces a merchant's payout settings. The payable
+// account must be a liability and the funding account an asset, both in the
+// currency of the minimum amount.
+func (s *Service) SetSettings(ctx context.Context, req Settings) (*Settings, error) {
+	if err := validateSettings(req); err != nil {
+		return nil, err
+	}
+	if !isUUID(req.MerchantID) {
+		return nil, fmt.Errorf("%w: merchant ID %q is not a UUID", ErrInvalidPayout, req.MerchantID)
+	}
+	payable, err := s.Ledger.GetAccountByID(ctx, req.PayableAccountID)
+	if err != nil {
+		return nil, err
+	}
+	funding, err := s.Ledger.GetAccountByID(ctx, req.FundingAccountID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkSettingsAccounts(req, payable, funding); err != nil {
+		return nil, err
+	}
+
+	settings, err := scanSettings(s.Pool.QueryRow(ctx, `
+		INSERT INTO merchant_payout_settings (
+			merchant_id, payable_account_id, funding_account_id, schedule, weekday,
+			minimum_amount, currency_code, active, updated_by
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (merchant_id) DO UPDATE SET
+			payable_account_id = EXCLUDED.payable_account_id,
+			funding_account_id = EXCLUDED.funding_account_id,
+			schedule = EXCLUDED.schedule,
+			weekday = EXCLUDED.weekday,
+			minimum_amount = EXCLUDED.minimum_amount,
+			currency_code = EXCLUDED.currency_code,
+			active = EXCLUDED.active,
+			updated_at = CURRENT_TIMESTAMP,
+			updated_by = EXCLUDED.updated_by
+		RETURNING `+settingsColumns,
+		req.MerchantID, req.PayableAccountID, req.FundingAccountID, req.Schedule, req.Weekday,
+		req.MinimumAmount, payable.CurrencyCode, req.Active, req.UpdatedBy))
+	if err != nil {
+		return nil, fmt.Errorf("failed to set payout settings: %w", err)
+	}
+	return settings, nil
+}
+
+// GetSettings gets a merchant's payout settings
+func (s *Service) GetSettings(ctx context.Context, merchantID string) (*Settings, error) {
+	return s.getSettings(ctx, s.Pool, merchantID, "")
+}
+
+// getSettings reads a merchant's settings through q, with lock appended to
+// the query
+func (s *Service) getSettings(ctx context.Context, q querier, merchantID, lock string) (*Settings, error) {
+	if !isUUID(merchantID) {
+		return nil, fmt.Errorf("%w: %s", ErrSettingsNotFound, merchantID)
+	}
+	settings, err := scanSettings(q.QueryRow(ctx, `
+		SELECT `+settingsColumns+`
+		FROM merchant_payout_settings
+		WHERE merchant_id = $1
+	`+lock, merchantID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrSettingsNotFound, merchantID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payout settings: %w", err)
+	}
+	return settings, nil
+}
+
+// Compute works out what a merchant could be paid out now
+func (s *Service) Compute(ctx context.Context, merchantID string) (*Computation, error) {
+	settings, err := s.GetSettings(ctx, merchantID)
+	if err != nil {
+		return nil, err
+	}
+	return s.compute(ctx, s.Pool, settings)
+}
+
+func (s *Service) compute(ctx context.Context, q querier, settings *Settings) (*Computation, error) {
+	balances, err := s.Ledger.GetAccountBalances(ctx, settings.PayableAccountID)
+	if err != nil {
+		return nil, err
+	}
+	reserve, err := s.reserve(ctx, q, settings.MerchantID, settings.CurrencyCode)
+	if err != nil {
+		return nil, err
+	}
+	return compute(settings.MerchantID, balances, reserve, settings.MinimumAmount)
+}
+
+// reserve is the fraud reserve withheld from a merchant's payouts: the larger
+// of the reserve built up and its minimum, or nothing without an active
+// reserve
+func (s *Service) reserve(ctx context.Context, q querier, merchantID, currencyCode string) (money.Money, error) {
+	var reserveCurrency string
+	var amount pgtype.Numeric
+	err := q.QueryRow(ctx, `
+		SELECT currency_code, GREATEST(current_reserve_amount, minimum_reserve_amount)
+		FROM fraud_reserves
+		WHERE merchant_id = $1 AND is_active
+	`, merchantID).Scan(&reserveCurrency, &amount)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return money.Zero(currencyCode)
+	}
+	if err != nil {
+		return money.Money{}, fmt.Errorf("failed to get fraud reserve: %w", err)
+	}
+	if reserveCurrency != currencyCode {
+		return money.Money{}, fmt.Errorf("%w: fraud reserve is in %s, payouts in %s", ledger.ErrCurrencyMismatch, reserveCurrency, currencyCode)
+	}
+	return money.FromNumeric(amount, reserveCurrency)
+}
+
+// RequestPayout pays a merchant out now, whatever its schedule. It fails with
+// ErrBelowMinimum when too little is payable.
+func (s *Service) RequestPayout(ctx context.Context, req PayoutRequest) (*Payout, error) {
+	if req.MerchantID == "" {
+		return nil, fmt.Errorf("%w: merchant ID is required", ErrInvalidPayout)
+	}
+	if req.CreatedBy == "" {
+		return nil, fmt.Errorf("%w: created_by is required", ErrInvalidPayout)
+	}
+	return s.createPayout(ctx, req.MerchantID, ScheduleOnDemand, time.Now(), req.CreatedBy)
+}
+
+// RunScheduled creates the payouts due at now: one per active daily merchant,
+// and one per weekly merchant on its weekday, unless the day's payout exists.
+// Merchants with too little payable are skipped until their next payout day.
+// It returns how many payouts it created.
+func (s *Service) RunScheduled(ctx context.Context, now time.Time) (int, error) {
+	rows, err := s.Pool.Query(ctx, `
+		SELECT `+settingsColumns+`
+		FROM merchant_payout_settings ps
+		WHERE active AND schedule <> 'on_demand'
+		AND NOT EXISTS (
+			SELECT 1 FROM payouts p
+			WHERE p.merchant_id = ps.merchant_id AND p.payout_date = $1::date AND p.trigger <> 'on_demand'
+		)
+		ORDER BY merchant_id
+	`, now.UTC().Format(ledger.DateLayout))
+	if err != nil {
+		return 0, fmt.Errorf("failed to list payout settings: %w", err)
+	}
+	var due []Settings
+	for rows.Next() {
+		settings, err := scanSettings(rows)
+		if err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan payout settings: %w", err)
+		}
+		if dueOn(*settings, now) {
+			due = append(due, *settings)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to list payout settings: %w", err)
+	}
+
+	created := 0
+	for _, settings := range due {
+		_, err := s.createPayout(ctx, settings.MerchantID, settings.Schedule, now, schedulerActor)
+		switch {
+		case err == nil:
+			created++
+		case errors.Is(err, ErrBelowMinimum), errors.Is(err, errPayoutExists):
+		case ctx.Err() != nil:
+			return created, ctx.Err()
+		default:
+			log.Printf("failed to pay out merchant %s: %v", settings.MerchantID, err)
+		}
+	}
+	return created, nil
+}
+
+// errPayoutExists is returned when a merchant's scheduled payout for the day
+// was created while the scheduler was working
+var errPayoutExists = errors.New("scheduled payout exists")
+
+// createPayout pays out what is payable to a merchant. The settings row is
+// locked while the payout is computed and posted, so a merchant's payouts are
+// made one at a time. If the payout cannot be stored once posted, the posting
+// is reversed.
+func (s *Service) createPayout(ctx context.Context, merchantID, trigger string, now time.Time, createdBy string) (*Payout, error) {
+	tx, err := s.Pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	settings, err := s.getSettings(ctx, tx, merchantID, "FOR UPDATE")
+	if err != nil {
+		return nil, err
+	}
+	payoutDate := now.UTC().Format(ledger.DateLayout)
+	if trigger != ScheduleOnDemand {
+		var exists bool
+		err := tx.QueryRow(ctx, `
+			SELECT EXISTS (SELECT 1 FROM payouts WHERE merchant_id = $1 AND payout_date = $2::date AND trigger <> 'on_demand')
+		`, merchantID, payoutDate).Scan(&exists)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check scheduled payouts: %w", err)
+		}
+		if exists {
+			return nil, fmt.Errorf("%w: merchant %s on %s", errPayoutExists, merchantID, payoutDate)
+		}
+	}
+
+	c, err := s.compute(ctx, tx, settings)
+	if err != nil {
+		return nil, err
+	}
+	if !c.Eligible {
+		return nil, fmt.Errorf("%w: merchant %s has %s payable, minimum %s", ErrBelowMinimum, merchantID, c.Payable, c.Minimum)
+	}
+
+	fee, err := s.fee(ctx, merchantID, c.Payable, now)
+	if err != nil {
+		return nil, err
+	}
+	p := &Payout{
+		ID:               uuid.New().String(),
+		MerchantID:       merchantID,
+		PayableAccountID: settings.PayableAccountID,
+		FundingAccountID: settings.FundingAccountID,
+		Amount:           c.Payable,
+		FeeAmount:        fee.Amount,
+		FeeScheduleID:    fee.ScheduleID,
+		PostedBalance:    c.Posted,
+		PendingAmount:    c.Pending,
+		HeldAmount:       c.Held,
+		ReserveAmount:    c.Reserve,
+		Trigger:          trigger,
+		PayoutDate:       payoutDate,
+		TransactionID:    uuid.New().String(),
+		CreatedBy:        createdBy,
+	}
+	legs, err := payoutLegs(p, fee)
+	if err != nil {
+		return nil, err
+	}
+	_, err = s.Ledger.PostTransaction(ctx, ledger.Transaction{
+		TransactionID: p.TransactionID,
+		Entries:       legs,
+		Description:   fmt.Sprintf("Payout %s to merchant %s", p.ID, merchantID),
+		ReferenceType: ReferenceTypePayout,
+		ReferenceID:   p.ID,
+		CreatedBy:     createdBy,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to post payout: %w", err)
+	}
+
+	stored, err := scanPayout(tx.QueryRow(ctx, `
+		INSERT INTO payouts (
+			id, merchant_id, payable_account_id, funding_account_id, amount, currency_code,
+			fee_amount, fee_schedule_id, posted_balance, pending_amount, held_amount, reserve_amount,
+			trigger, payout_date, transaction_id, created_by, updated_by
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, '')::uuid, $9, $10, $11, $12, $13, $14::date, $15, $16, $16)
+		RETURNING `+payoutColumns,
+		p.ID, p.MerchantID, p.PayableAccountID, p.FundingAccountID, p.Amount, settings.CurrencyCode,
+		p.FeeAmount, p.FeeScheduleID, p.PostedBalance, p.PendingAmount, p.HeldAmount, p.ReserveAmount,
+		p.Trigger, p.PayoutDate, p.TransactionID, p.CreatedBy))
+	if err == nil {
+		err = tx.Commit(ctx)
+	}
+	if err != nil {
+		s.unpost(p, createdBy, "payout could not be stored")
+		return nil, fmt.Errorf("failed to store payout: %w", err)
+	}
+	return stored, nil
+}
+
+// fee is the fee on paying out amount to a merchant at now. Without a payout
+// fee schedule the fee is zero.
+func (s *Service) fee(ctx context.Context, merchantID string, amount money.Money, now time.Time) (*fees.Fee, error) {
+	zero, err := money.Zero(amount.Currency())
+	if err != nil {
+		return nil, err
+	}
+	if s.Fees == nil {
+		return &fees.Fee{FeeType: fees.FeeTypePayout, Base: amount, Amount: zero}, nil
+	}
+	fee, err := s.Fees.Quote(ctx, fees.QuoteRequest{
+		FeeType:    fees.FeeTypePayout,
+		MerchantID: merchantID,
+		Amount:     amount,
+		At:         now,
+	})
+	if errors.Is(err, fees.ErrScheduleNotFound) {
+		return &fees.Fee{FeeType: fees.FeeTypePayout, Base: amount, Amount: zero}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to quote payout fee: %w", err)
+	}
+	return fee, nil
+}
+
+// unpost reverses the posting of a payout that was never stored. It uses its
+// own context so that a cancelled request does not leave the posting behind.
+func (s *Service) unpost(p *Payout, approvedBy, reason string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err := s.Ledger.ReverseTransaction(ctx, ledger.ReverseRequest{
+		TransactionID: p.TransactionID,
+		ReversalID:    reversalID(p.ID),
+		Reason:        reason,
+		ReversedBy:    systemActor,
+		ApprovedBy:    approvedBy,
+	})
+	if err != nil && !errors.Is(err, ledger.ErrAlreadyReversed) {
+		log.Printf("failed to reverse unstored payout %s (transaction %s): %v", p.ID, p.TransactionID, err)
+	}
+}
+
+// GetPayout gets a payout
+func (s *Service) GetPayout(ctx context.Context, id string) (*Payout, error) {
+	return s.getPayout(ctx, s.Pool, id, "")
+}
+
+func (s *Service) getPayout(ctx context.Context, q querier, id, lock string) (*Payout, error) {
+	if !isUUID(id) {
+		return nil, fmt.Errorf("%w: %s", ErrPayoutNotFound, id)
+	}
+	p, err := scanPayout(q.QueryRow(ctx, `
+		SELECT `+payoutColumns+`
+		FROM payouts
+		WHERE id = $1
+	`+lock, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrPayoutNotFound, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payout: %w", err)
+	}
+	return p, nil
+}
+
+// ListPayouts lists payouts, newest first
+func (s *Service) ListPayouts(ctx context.Context, filter PayoutFilter) ([]Payout, error) {
+	switch filter.Status {
+	case "", StatusPending, StatusSent, StatusPaid, StatusFailed:
+	default:
+		return nil, fmt.Errorf("%w: unknown payout status %q", ErrInvalidPayout, filter.Status)
+	}
+	if filter.MerchantID != "" && !isUUID(filter.MerchantID) {
+		return []Payout{}, nil
+	}
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultPayoutLimit
+	}
+	if limit > maxPayoutLimit {
+		limit = maxPayoutLimit
+	}
+	offset := filter.Offset
+	if offset < 0 {
+		offset = 0
+	}
+
+	rows, err := s.Pool.Query(ctx, `
+		SELECT `+payoutColumns+`
+		FROM payouts
+		WHERE ($1 = '' OR merchant_id::text = $1)
+		AND ($2 = '' OR status = $2)
+		ORDER BY created_at DESC, id
+		LIMIT $3 OFFSET $4
+	`, filter.MerchantID, filter.Status, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list payouts: %w", err)
+	}
+	defer rows.Close()
+
+	payouts := []Payout{}
+	for rows.Next() {
+		p, err := scanPayout(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan payout: %w", err)
+		}
+		payouts = append(payouts, *p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list payouts: %w", err)
+	}
+	return payouts, nil
+}
+
+// MarkSent records that a pending payout was sent to the bank
+func (s *Service) MarkSent(ctx context.Context, req TransitionRequest) (*Payout, error) {
+	return s.transition(ctx, req, StatusSent, `
+		UPDATE payouts
+		SET status = 'sent', sent_at = CURRENT_TIMESTAMP, bank_reference = NULLIF($2, ''), updated_by = $3
+		WHERE id = $1
+		RETURNING `+payoutColumns)
+}
+
+// MarkPaid records that the bank paid a sent payout
+func (s *Service) MarkPaid(ctx context.Context, req TransitionRequest) (*Payout, error) {
+	return s.transition(ctx, req, StatusPaid, `
+		UPDATE payouts
+		SET status = 'paid', paid_at = CURRENT_TIMESTAMP,
+			bank_reference = COALESCE(NULLIF($2, ''), bank_reference), updated_by = $3
+		WHERE id = $1
+		RETURNING `+payoutColumns)
+}
+
+// MarkFailed records that a payout was rejected or returned by the bank, and
+// reverses its posting, fee included, so the funds are payable to the
+// merchant again. It may be retried: the reversal is only posted once.
+func (s *Service) MarkFailed(ctx context.Context, req TransitionRequest) (*Payout, error) {
+	if req.Reason == "" {
+		return nil, fmt.Errorf("%w: reason is required", ErrInvalidPayout)
+	}
+	return s.transition(ctx, req, StatusFailed, `
+		UPDATE payouts
+		SET status = 'failed', failed_at = CURRENT_TIMESTAMP, failure_reason = $2, reversal_id = $4, updated_by = $3
+		WHERE id = $1
+		RETURNING `+payoutColumns)
+}
+
+// transition moves a payout to status with update, which is passed the payout
+// ID, the bank reference (or failure reason), the actor and, for failures, the
+// reversal ID
+func (s *Service) transition(ctx context.Context, req TransitionRequest, status, update string) (*Payout, error) {
+	if req.PayoutID == "" {
+		return nil, fmt.Errorf("%w: payout ID is required", ErrInvalidPayout)
+	}
+	if req.ChangedBy == "" {
+		return nil, fmt.Errorf("%w: changed_by is required", ErrInvalidPayout)
+	}
+
+	tx, err := s.Pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	current, err := s.getPayout(ctx, tx, req.PayoutID, "FOR UPDATE")
+	if err != nil {
+		return nil, err
+	}
+	if err := checkTransition(current.ID, current.Status, status); err != nil {
+		return nil, err
+	}
+
+	args := []any{current.ID, req.BankReference, req.ChangedBy}
+	if status == StatusFailed {
+		id := reversalID(current.ID)
+		_, err := s.Ledger.ReverseTransaction(ctx, ledger.ReverseRequest{
+			TransactionID: current.TransactionID,
+			ReversalID:    id,
+			Reason:        fmt.Sprintf("Payout %s failed: %s", current.ID, req.Reason),
+			ReversedBy:    systemActor,
+			ApprovedBy:    req.ChangedBy,
+		})
+		if err != nil && !errors.Is(err, ledger.ErrAlreadyReversed) {
+			return nil, fmt.Errorf("failed to reverse payout %s: %w", current.ID, err)
+		}
+		args = []any{current.ID, req.Reason, req.ChangedBy, id}
+	}
+
+	p, err := scanPayout(tx.QueryRow(ctx, update, args...))
+	if err != nil {
+		return nil, fmt.Errorf("failed to update payout: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit payout: %w", err)
+	}
+	return p, nil
+}
+
+// reversalID is the ID of the transaction reversing a payout's posting. It is
+// derived from the payout so a retried reversal is recognised as posted.
+func reversalID(payoutID string) string {
+	return uuid.NewSHA1(uuid.MustParse(payoutID), []byte("reversal")).String()
+}
+
+// querier is a pool or a transaction
+type querier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+func scanSettings(row pgx.Row) (*Settings, error) {
+	var st Settings
+	var weekday *int16
+	var minimum pgtype.Numeric
+	err := row.Scan(&st.MerchantID, &st.PayableAccountID, &st.FundingAccountID, &st.Schedule, &weekday,
+		&minimum, &st.CurrencyCode, &st.Active, &st.CreatedAt, &st.UpdatedAt, &st.UpdatedBy)
+	if err != nil {
+		return nil, err
+	}
+	if weekday != nil {
+		day := int(*weekday)
+		st.Weekday = &day
+	}
+	if st.MinimumAmount, err = money.FromNumeric(minimum, st.CurrencyCode); err != nil {
+		return nil, fmt.Errorf("failed to read minimum payout for merchant %s: %w", st.MerchantID, err)
+	}
+	return &st, nil
+}
+
+func scanPayout(row pgx.Row) (*Payout, error) {
+	var p Payout
+	var currencyCode string
+	var amount, fee, posted, pending, held, reserve pgtype.Numeric
+	err := row.Scan(&p.ID, &p.MerchantID, &p.PayableAccountID, &p.FundingAccountID, &currencyCode, &amount,
+		&fee, &p.FeeScheduleID, &posted, &pending, &held, &reserve, &p.Trigger, &p.PayoutDate, &p.Status,
+		&p.TransactionID, &p.ReversalID, &p.BankReference, &p.FailureReason,
+		&p.CreatedBy, &p.CreatedAt, &p.SentAt, &p.PaidAt, &p.FailedAt, &p.UpdatedBy)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range []struct {
+		dest *money.Money
+		n    pgtype.Numeric
+	}{{&p.Amount, amount}, {&p.FeeAmount, fee}, {&p.PostedBalance, posted}, {&p.PendingAmount, pending}, {&p.HeldAmount, held}, {&p.ReserveAmount, reserve}} {
+		if *f.dest, err = money.FromNumeric(f.n, currencyCode); err != nil {
+			return nil, fmt.Errorf("failed to read payout %s: %w", p.ID, err)
+		}
+	}
+	return &p, nil
+}
+
+func isUUID(s string) bool {
+	_, err := uuid.Parse(s)
+	return err == nil
+}
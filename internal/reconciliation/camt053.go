@@ -0,0 +1,158 @@
+package reconciliation
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/example/pci-infra/internal/money"
+)
+
+// camtDocument is the part of an ISO 20022 camt.053 bank to customer
+// statement the matcher needs. Elements are matched by local name, so any
+// camt.053 version is accepted.
+type camtDocument struct {
+	Statements []camtStatement `xml:"BkToCstmrStmt>Stmt"`
+}
+
+type camtStatement struct {
+	ID       string        `xml:"Id"`
+	Balances []camtBalance `xml:"Bal"`
+	Entries  []camtEntry   `xml:"Ntry"`
+}
+
+type camtAmount struct {
+	Value    string `xml:",chardata"`
+	Currency string `xml:"Ccy,attr"`
+}
+
+type camtDate struct {
+	Date     string `xml:"Dt"`
+	DateTime string `xml:"DtTm"`
+}
+
+type camtBalance struct {
+	Type      string     `xml:"Tp>CdOrPrtry>Cd"`
+	Amount    camtAmount `xml:"Amt"`
+	Indicator string     `xml:"CdtDbtInd"`
+}
+
+type camtEntry struct {
+	Reference         string          `xml:"NtryRef"`
+	Amount            camtAmount      `xml:"Amt"`
+	Indicator         string          `xml:"CdtDbtInd"`
+	BookingDate       camtDate        `xml:"BookgDt"`
+	ValueDate         camtDate        `xml:"ValDt"`
+	ServicerReference string          `xml:"AcctSvcrRef"`
+	Details           []camtTxDetails `xml:"NtryDtls>TxDtls"`
+	AdditionalInfo    string          `xml:"AddtlNtryInf"`
+}
+
+type camtTxDetails struct {
+	EndToEndID   string   `xml:"Refs>EndToEndId"`
+	CreditorRef  string   `xml:"RmtInf>Strd>CdtrRefInf>Ref"`
+	Unstructured []string `xml:"RmtInf>Ustrd"`
+}
+
+// parseCAMT053 reads a camt.053 file holding a single statement. Each Ntry
+// is a line; its reference is the end to end ID of its first transaction, or
+// failing that the creditor reference, entry reference or servicer reference.
+func parseCAMT053(r io.Reader) (*Statement, error) {
+	var doc camtDocument
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidStatement, err)
+	}
+	if len(doc.Statements) != 1 {
+		return nil, fmt.Errorf("%w: expected one Stmt, found %d", ErrInvalidStatement, len(doc.Statements))
+	}
+	cs := doc.Statements[0]
+	st := &Statement{Reference: strings.TrimSpace(cs.ID)}
+
+	for _, b := range cs.Balances {
+		amount, err := camtMoney(b.Amount, b.Indicator)
+		if err != nil {
+			return nil, fmt.Errorf("%w: balance %s: %v", ErrInvalidStatement, b.Type, err)
+		}
+		switch b.Type {
+		case "OPBD", "PRCD":
+			if st.OpeningBalance == nil {
+				st.OpeningBalance = &amount
+			}
+		case "CLBD":
+			st.ClosingBalance = &amount
+		default:
+			continue
+		}
+		st.CurrencyCode = amount.Currency()
+	}
+
+	for i, e := range cs.Entries {
+		amount, err := camtMoney(e.Amount, e.Indicator)
+		if err != nil {
+			return nil, fmt.Errorf("%w: entry %d: %v", ErrInvalidStatement, i+1, err)
+		}
+		if st.CurrencyCode == "" {
+			st.CurrencyCode = amount.Currency()
+		}
+
+		var line Line
+		line.Amount, line.Direction = signedAmount(amount)
+		if line.BookingDate, err = parseDate("BookgDt", e.BookingDate.day()); err != nil {
+			return nil, fmt.Errorf("entry %d: %w", i+1, err)
+		}
+		if v := e.ValueDate.day(); v != "" {
+			if line.ValueDate, err = parseDate("ValDt", v); err != nil {
+				return nil, fmt.Errorf("entry %d: %w", i+1, err)
+			}
+		}
+
+		var candidates []string
+		var description []string
+		if len(e.Details) > 0 {
+			d := e.Details[0]
+			candidates = append(candidates, d.EndToEndID, d.CreditorRef)
+			description = d.Unstructured
+		}
+		candidates = append(candidates, e.Reference, e.ServicerReference)
+		for _, ref := range candidates {
+			if ref = strings.TrimSpace(ref); ref != "" && ref != "NOTPROVIDED" {
+				line.Reference = ref
+				break
+			}
+		}
+		line.Description = strings.TrimSpace(strings.Join(description, " "))
+		if line.Description == "" {
+			line.Description = strings.TrimSpace(e.AdditionalInfo)
+		}
+		st.Lines = append(st.Lines, line)
+	}
+	return st, nil
+}
+
+// camtMoney reads an amount signed by its credit or debit indicator
+func camtMoney(a camtAmount, indicator string) (money.Money, error) {
+	amount, err := money.Parse(a.Value, a.Currency)
+	if err != nil {
+		return money.Money{}, err
+	}
+	switch indicator {
+	case "CRDT":
+		return amount, nil
+	case "DBIT":
+		return amount.Neg(), nil
+	default:
+		return money.Money{}, fmt.Errorf("unknown CdtDbtInd %q", indicator)
+	}
+}
+
+// day is the date part of a camt date or date time
+func (d camtDate) day() string {
+	if d.Date != "" {
+		return strings.TrimSpace(d.Date)
+	}
+	if s := strings.TrimSpace(d.DateTime); len(s) >= 10 {
+		return s[:10]
+	}
+	return ""
+}
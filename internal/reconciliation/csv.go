@@ -0,0 +1,101 @@
+package reconciliation
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/example/pci-infra/internal/money"
+)
+
+// parseCSV reads a statement exported as CSV. The header row names the
+// columns, in any order and case: booking_date (or date) and amount are
+// required; value_date, direction, currency, reference and description are
+// optional. Without a direction column a negative amount is a debit.
+func parseCSV(r io.Reader, currencyCode string) (*Statement, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%w: empty CSV", ErrInvalidStatement)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidStatement, err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "date" {
+			name = "booking_date"
+		}
+		columns[name] = i
+	}
+	for _, required := range []string{"booking_date", "amount"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("%w: CSV has no %s column", ErrInvalidStatement, required)
+		}
+	}
+
+	st := &Statement{CurrencyCode: currencyCode}
+	for row := 2; ; row++ {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidStatement, err)
+		}
+		field := func(name string) string {
+			i, ok := columns[name]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+		if strings.Join(record, "") == "" {
+			continue
+		}
+
+		currency := currencyCode
+		if c := field("currency"); c != "" {
+			currency = strings.ToUpper(c)
+		}
+		amount, err := money.Parse(field("amount"), currency)
+		if err != nil {
+			return nil, fmt.Errorf("%w: row %d: %v", ErrInvalidStatement, row, err)
+		}
+
+		var line Line
+		line.Amount, line.Direction = signedAmount(amount)
+		if _, ok := columns["direction"]; ok {
+			if amount.IsNegative() {
+				return nil, fmt.Errorf("%w: row %d: amount must be positive with a direction column", ErrInvalidStatement, row)
+			}
+			switch strings.ToUpper(field("direction")) {
+			case "CREDIT", "CRDT", "C", "CR":
+				line.Direction = DirectionCredit
+			case "DEBIT", "DBIT", "D", "DR":
+				line.Direction = DirectionDebit
+			default:
+				return nil, fmt.Errorf("%w: row %d: unknown direction %q", ErrInvalidStatement, row, field("direction"))
+			}
+		}
+
+		if line.BookingDate, err = parseDate("booking_date", field("booking_date")); err != nil {
+			return nil, fmt.Errorf("row %d: %w", row, err)
+		}
+		if v := field("value_date"); v != "" {
+			if line.ValueDate, err = parseDate("value_date", v); err != nil {
+				return nil, fmt.Errorf("row %d: %w", row, err)
+			}
+		}
+		line.Reference = field("reference")
+		line.Description = field("description")
+		st.Lines = append(st.Lines, line)
+	}
+	return st, nil
+}
@@ -0,0 +1,194 @@
+package reconciliation
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/example/pci-infra/internal/ledger"
+)
+
+// Rules tune the matcher. A line and an entry are only paired when their
+// amounts are equal, the entry moves the ledger account the same way the line
+// moved the external account, and the entry's effective date is within
+// DateWindow days of the line's booking or value date.
+type Rules struct {
+	DateWindow int
+}
+
+// DefaultRules allow for the settlement lag of card and bank transfers
+var DefaultRules = Rules{DateWindow: 3}
+
+// Match rules, strongest first
+const (
+	// RuleReference pairs agree on amount, date window and reference
+	RuleReference = "amount_date_reference"
+	// RuleAmountDate pairs agree on amount and date window only
+	RuleAmountDate = "amount_date"
+)
+
+// pair identifies a statement line and journal entry pairing
+type pair struct {
+	lineID  string
+	entryID string
+}
+
+// candidate is a line and entry that could be paired
+type candidate struct {
+	line      *Line
+	entry     *ledger.JournalEntry
+	reference bool
+	score     int
+}
+
+// matchLines pairs statement lines with journal entries, each at most once.
+// A line whose reference identifies exactly one candidate entry is matched
+// outright; any other candidate becomes a suggestion, best score first.
+// Rejected pairs are never proposed.
+func matchLines(lines []Line, entries []ledger.JournalEntry, rejected map[pair]bool, rules Rules) []Match {
+	var candidates []candidate
+	references := map[string]int{}
+	for i := range lines {
+		line := &lines[i]
+		for j := range entries {
+			entry := &entries[j]
+			if rejected[pair{line.ID, entry.ID}] {
+				continue
+			}
+			c, ok := compare(line, entry, rules)
+			if !ok {
+				continue
+			}
+			if c.reference {
+				references[line.ID]++
+			}
+			candidates = append(candidates, c)
+		}
+	}
+
+	// Outright matches claim their entries first, then the strongest and
+	// closest suggestions. Ties fall to the earlier line and entry.
+	outright := func(c candidate) bool {
+		return c.reference && references[c.line.ID] == 1
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if outright(a) != outright(b) {
+			return outright(a)
+		}
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		if a.line.LineNumber != b.line.LineNumber {
+			return a.line.LineNumber < b.line.LineNumber
+		}
+		return a.entry.EntryNumber < b.entry.EntryNumber
+	})
+
+	var matches []Match
+	usedLines, usedEntries := map[string]bool{}, map[string]bool{}
+	for _, c := range candidates {
+		if usedLines[c.line.ID] || usedEntries[c.entry.ID] {
+			continue
+		}
+		usedLines[c.line.ID], usedEntries[c.entry.ID] = true, true
+
+		m := Match{
+			StatementID:    c.line.StatementID,
+			StatementLine:  *c.line,
+			JournalEntryID: c.entry.ID,
+			JournalEntry:   c.entry,
+			Status:         MatchSuggested,
+			Rule:           RuleAmountDate,
+			Score:          c.score,
+		}
+		if c.reference {
+			m.Rule = RuleReference
+		}
+		if outright(c) {
+			m.Status = MatchMatched
+		}
+		matches = append(matches, m)
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].StatementLine.LineNumber < matches[j].StatementLine.LineNumber
+	})
+	return matches
+}
+
+// compare scores a line against an entry. Agreeing on amount, direction and
+// date window scores 50, a shared reference adds 40 and up to 10 more go to
+// entries dated closest to the line.
+func compare(line *Line, entry *ledger.JournalEntry, rules Rules) (candidate, bool) {
+	if entry.EntryType != ledgerEntryType(line.Direction) || !entry.Amount.Equal(line.Amount) {
+		return candidate{}, false
+	}
+
+	days := -1
+	for _, d := range []string{line.BookingDate, line.ValueDate} {
+		if n, ok := daysApart(d, entry.EffectiveDate); ok && (days < 0 || n < days) {
+			days = n
+		}
+	}
+	if days < 0 || days > rules.DateWindow {
+		return candidate{}, false
+	}
+
+	c := candidate{line: line, entry: entry, score: 50}
+	if sharesReference(line, entry) {
+		c.reference = true
+		c.score += 40
+	}
+	if closeness := 10 - 2*days; closeness > 0 {
+		c.score += closeness
+	}
+	return c, true
+}
+
+// ledgerEntryType is the entry type that moves the ledger account mirroring an
+// external account the way direction moved the external account. Money paid
+// into the bank debits the asset account that represents it.
+func ledgerEntryType(direction string) string {
+	if direction == DirectionCredit {
+		return "debit"
+	}
+	return "credit"
+}
+
+// sharesReference reports whether the line's reference names the entry, or
+// the entry's reference appears in the line's description. References are
+// compared without regard to case.
+func sharesReference(line *Line, entry *ledger.JournalEntry) bool {
+	if ref := strings.TrimSpace(line.Reference); ref != "" {
+		for _, id := range []string{entry.ReferenceID, entry.TransactionID, entry.EntryNumber} {
+			if strings.EqualFold(ref, id) {
+				return true
+			}
+		}
+		if strings.Contains(strings.ToLower(entry.Description), strings.ToLower(ref)) {
+			return true
+		}
+	}
+	if ref := strings.TrimSpace(entry.ReferenceID); ref != "" {
+		return strings.Contains(strings.ToLower(line.Description), strings.ToLower(ref))
+	}
+	return false
+}
+
+// daysApart is the number of whole days between two ledger.DateLayout dates
+func daysApart(a, b string) (int, bool) {
+	ta, err := time.Parse(ledger.DateLayout, a)
+	if err != nil {
+		return 0, false
+	}
+	tb, err := time.Parse(ledger.DateLayout, b)
+	if err != nil {
+		return 0, false
+	}
+	days := int(ta.Sub(tb).Hours() / 24)
+	if days < 0 {
+		days = -days
+	}
+	return days, true
+}
@@ -0,0 +1,196 @@
+package reconciliation
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/example/pci-infra/internal/money"
+)
+
+// mt940Field is a tagged field of an MT940 message with its continuation lines
+type mt940Field struct {
+	tag   string
+	value string
+}
+
+var (
+	mt940Tag = regexp.MustCompile(`^:([0-9]{2}[A-Z]?):(.*)$`)
+	// :61: value date, optional entry date, mark, optional funds code, amount,
+	// transaction type, customer reference and optional //bank reference
+	mt940Line = regexp.MustCompile(`^([0-9]{6})([0-9]{4})?(RC|RD|C|D)([A-Z])?([0-9]{1,12},[0-9]*)([NSF][A-Z0-9]{3})([^/]*)(?://(.*))?$`)
+	// :60a: and :62a: mark, date, currency and amount
+	mt940Balance = regexp.MustCompile(`^([CD])([0-9]{6})([A-Z]{3})([0-9]{1,12},[0-9]*)$`)
+)
+
+// parseMT940 reads a SWIFT MT940 customer statement holding a single
+// statement. Each :61: field is a line, described by the :86: field after it.
+// The reference is the :61: customer reference, or the bank reference when
+// the customer gave none.
+func parseMT940(r io.Reader) (*Statement, error) {
+	fields, err := mt940Fields(r)
+	if err != nil {
+		return nil, err
+	}
+
+	st := &Statement{}
+	var line *Line
+	var statementNumber string
+	for _, f := range fields {
+		switch f.tag {
+		case "20":
+			if st.Reference != "" {
+				return nil, fmt.Errorf("%w: expected one statement, found a second :20:", ErrInvalidStatement)
+			}
+			st.Reference = strings.TrimSpace(f.value)
+		case "28C", "28":
+			statementNumber = strings.TrimSpace(f.value)
+		case "60F", "60M":
+			balance, err := mt940Money(f)
+			if err != nil {
+				return nil, err
+			}
+			st.CurrencyCode = balance.Currency()
+			if st.OpeningBalance == nil {
+				st.OpeningBalance = &balance
+			}
+		case "62F", "62M":
+			balance, err := mt940Money(f)
+			if err != nil {
+				return nil, err
+			}
+			st.ClosingBalance = &balance
+		case "61":
+			if st.CurrencyCode == "" {
+				return nil, fmt.Errorf("%w: :61: before the opening balance", ErrInvalidStatement)
+			}
+			l, err := mt940StatementLine(f.value, st.CurrencyCode)
+			if err != nil {
+				return nil, err
+			}
+			st.Lines = append(st.Lines, l)
+			line = &st.Lines[len(st.Lines)-1]
+		case "86":
+			if line != nil && line.Description == "" {
+				line.Description = strings.Join(strings.Fields(f.value), " ")
+			}
+		}
+	}
+	if st.Reference != "" && statementNumber != "" {
+		st.Reference += "/" + statementNumber
+	}
+	return st, nil
+}
+
+// mt940Fields splits a message into its tagged fields, dropping any SWIFT
+// block wrapper and the end of text marker
+func mt940Fields(r io.Reader) ([]mt940Field, error) {
+	var fields []mt940Field
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		text := strings.TrimRight(scanner.Text(), "\r ")
+		if i := strings.Index(text, "{4:"); i >= 0 {
+			text = text[i+len("{4:"):]
+		}
+		if text == "" || text == "-" || text == "-}" || strings.HasPrefix(text, "{") {
+			continue
+		}
+		if m := mt940Tag.FindStringSubmatch(text); m != nil {
+			fields = append(fields, mt940Field{tag: m[1], value: m[2]})
+			continue
+		}
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("%w: MT940 must start with a tagged field", ErrInvalidStatement)
+		}
+		fields[len(fields)-1].value += "\n" + text
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidStatement, err)
+	}
+	return fields, nil
+}
+
+// mt940StatementLine reads a :61: field. Reversals of credits are debits and
+// reversals of debits are credits.
+func mt940StatementLine(value, currencyCode string) (Line, error) {
+	first, _, _ := strings.Cut(value, "\n")
+	m := mt940Line.FindStringSubmatch(first)
+	if m == nil {
+		return Line{}, fmt.Errorf("%w: malformed :61: %q", ErrInvalidStatement, first)
+	}
+
+	amount, err := mt940Amount(m[5], currencyCode)
+	if err != nil {
+		return Line{}, err
+	}
+	line := Line{Amount: amount, Direction: DirectionCredit}
+	if m[3] == "D" || m[3] == "RC" {
+		line.Direction = DirectionDebit
+	}
+
+	if line.ValueDate, err = parseDate("value date", mt940Date(m[1])); err != nil {
+		return Line{}, err
+	}
+	line.BookingDate = line.ValueDate
+	if m[2] != "" {
+		// The entry date has no year; it takes the value date's, moved a
+		// year when the two straddle new year
+		year := m[1][:2]
+		switch {
+		case m[1][2:4] == "12" && m[2][:2] == "01":
+			year = fmt.Sprintf("%02d", (atoi2(year)+1)%100)
+		case m[1][2:4] == "01" && m[2][:2] == "12":
+			year = fmt.Sprintf("%02d", (atoi2(year)+99)%100)
+		}
+		if line.BookingDate, err = parseDate("entry date", mt940Date(year+m[2])); err != nil {
+			return Line{}, err
+		}
+	}
+
+	line.Reference = strings.TrimSpace(m[7])
+	if line.Reference == "NONREF" {
+		line.Reference = ""
+	}
+	if line.Reference == "" {
+		line.Reference = strings.TrimSpace(m[8])
+	}
+	return line, nil
+}
+
+// mt940Money reads an opening or closing balance field
+func mt940Money(f mt940Field) (money.Money, error) {
+	m := mt940Balance.FindStringSubmatch(strings.TrimSpace(f.value))
+	if m == nil {
+		return money.Money{}, fmt.Errorf("%w: malformed :%s: %q", ErrInvalidStatement, f.tag, f.value)
+	}
+	amount, err := mt940Amount(m[4], m[3])
+	if err != nil {
+		return money.Money{}, err
+	}
+	if m[1] == "D" {
+		amount = amount.Neg()
+	}
+	return amount, nil
+}
+
+// mt940Amount reads an amount written with a decimal comma
+func mt940Amount(s, currencyCode string) (money.Money, error) {
+	s = strings.TrimSuffix(strings.Replace(s, ",", ".", 1), ".")
+	amount, err := money.Parse(s, currencyCode)
+	if err != nil {
+		return money.Money{}, fmt.Errorf("%w: %v", ErrInvalidStatement, err)
+	}
+	return amount, nil
+}
+
+// mt940Date turns YYMMDD into YYYY-MM-DD. Statements are taken to be from
+// this century.
+func mt940Date(s string) string {
+	return "20" + s[:2] + "-" + s[2:4] + "-" + s[4:6]
+}
+
+func atoi2(s string) int {
+	return int(s[0]-'0')*10 + int(s[1]-'0')
+}
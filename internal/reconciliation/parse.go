@@ -0,0 +1,75 @@
+package reconciliation
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/example/pci-infra/internal/ledger"
+	"github.com/example/pci-infra/internal/money"
+)
+
+// Parse reads a statement in the given format. currencyCode is the currency
+// of CSV statements, which do not state one; the other formats carry their
+// own. Lines are numbered from 1 in file order and the statement period runs
+// from the earliest to the latest booking or value date.
+func Parse(format string, r io.Reader, currencyCode string) (*Statement, error) {
+	var st *Statement
+	var err error
+	switch format {
+	case FormatCSV:
+		st, err = parseCSV(r, currencyCode)
+	case FormatCAMT053:
+		st, err = parseCAMT053(r)
+	case FormatMT940:
+		st, err = parseMT940(r)
+	default:
+		return nil, fmt.Errorf("%w: unknown format %q", ErrInvalidStatement, format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	st.Format = format
+
+	if len(st.Lines) == 0 {
+		return nil, fmt.Errorf("%w: no lines", ErrInvalidStatement)
+	}
+	for i := range st.Lines {
+		l := &st.Lines[i]
+		l.LineNumber = i + 1
+		if l.Amount.Currency() != st.CurrencyCode {
+			return nil, fmt.Errorf("%w: line %d is in %s, statement is in %s", ErrInvalidStatement, l.LineNumber, l.Amount.Currency(), st.CurrencyCode)
+		}
+		if !l.Amount.IsPositive() {
+			return nil, fmt.Errorf("%w: line %d amount must be positive", ErrInvalidStatement, l.LineNumber)
+		}
+		for _, d := range []string{l.BookingDate, l.ValueDate} {
+			if d == "" {
+				continue
+			}
+			if st.PeriodStart == "" || d < st.PeriodStart {
+				st.PeriodStart = d
+			}
+			if d > st.PeriodEnd {
+				st.PeriodEnd = d
+			}
+		}
+	}
+	return st, nil
+}
+
+// parseDate checks that s is a ledger.DateLayout date
+func parseDate(field, s string) (string, error) {
+	if _, err := time.Parse(ledger.DateLayout, s); err != nil {
+		return "", fmt.Errorf("%w: %s %q must be YYYY-MM-DD", ErrInvalidStatement, field, s)
+	}
+	return s, nil
+}
+
+// signedAmount splits a signed amount into its size and direction
+func signedAmount(m money.Money) (money.Money, string) {
+	if m.IsNegative() {
+		return m.Abs(), DirectionDebit
+	}
+	return m, DirectionCredit
+}
@@ -0,0 +1,654 @@
+// Package reconciliation imports bank and processor statements and matches
+// their lines against the journal entries of the ledger account that mirrors
+// the external account. Confident matches are recorded outright; the rest are
+// suggested for an operator to confirm or reject.
+package reconciliation
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/example/pci-infra/internal/ledger"
+	"github.com/example/pci-infra/internal/money"
+)
+
+// Statement formats accepted by ImportStatement
+const (
+	FormatCSV     = "csv"
+	FormatCAMT053 = "camt053"
+	FormatMT940   = "mt940"
+)
+
+// Line directions, from the statement holder's side
+const (
+	// DirectionCredit is money paid into the external account
+	DirectionCredit = "credit"
+	// DirectionDebit is money paid out of the external account
+	DirectionDebit = "debit"
+)
+
+// Statement line statuses
+const (
+	LineUnmatched = "unmatched"
+	LineSuggested = "suggested"
+	LineMatched   = "matched"
+)
+
+// Match statuses. Matched and confirmed pairs are final; suggested pairs wait
+// for an operator and rejected pairs are never suggested again.
+const (
+	MatchMatched   = "matched"
+	MatchSuggested = "suggested"
+	MatchConfirmed = "confirmed"
+	MatchRejected  = "rejected"
+)
+
+// ErrInvalidStatement is returned when a statement cannot be parsed
+var ErrInvalidStatement = fmt.Errorf("%w: statement", ledger.ErrInvalidRequest)
+
+// ErrStatementNotFound is returned when a referenced statement does not exist
+var ErrStatementNotFound = errors.New("statement not found")
+
+// ErrStatementExists is returned when a statement with the same reference was
+// already imported for the account
+var ErrStatementExists = errors.New("statement already imported")
+
+// ErrMatchNotFound is returned when a referenced match does not exist
+var ErrMatchNotFound = errors.New("match not found")
+
+// ErrMatchDecided is returned when confirming or rejecting a match that is not
+// a suggestion
+var ErrMatchDecided = errors.New("match already decided")
+
+// Statement is an imported bank or processor statement. Balances are signed,
+// negative when overdrawn, and absent when the format does not carry them.
+type Statement struct {
+	ID             string       `json:"id"`
+	AccountID      string       `json:"account_id"`
+	Format         string       `json:"format"`
+	Reference      string       `json:"reference"`
+	CurrencyCode   string       `json:"currency_code"`
+	OpeningBalance *money.Money `json:"opening_balance,omitempty"`
+	ClosingBalance *money.Money `json:"closing_balance,omitempty"`
+	PeriodStart    string       `json:"period_start"`
+	PeriodEnd      string       `json:"period_end"`
+	ImportedAt     string       `json:"imported_at,omitempty"`
+	ImportedBy     string       `json:"imported_by"`
+	Lines          []Line       `json:"lines"`
+}
+
+// Line is one booking on a statement. Amount is positive; Direction says which
+// way it moved. Dates are in ledger.DateLayout.
+type Line struct {
+	ID          string      `json:"id,omitempty"`
+	StatementID string      `json:"statement_id,omitempty"`
+	LineNumber  int         `json:"line_number"`
+	BookingDate string      `json:"booking_date"`
+	ValueDate   string      `json:"value_date,omitempty"`
+	Amount      money.Money `json:"amount"`
+	Direction   string      `json:"direction"`
+	Reference   string      `json:"reference"`
+	Description string      `json:"description"`
+	Status      string      `json:"status,omitempty"`
+}
+
+// Match pairs a statement line with a journal entry. Score runs from 0 to 100
+// and Rule names what the pair agreed on.
+type Match struct {
+	ID             string               `json:"id"`
+	StatementID    string               `json:"statement_id"`
+	StatementLine  Line                 `json:"statement_line"`
+	JournalEntryID string               `json:"journal_entry_id"`
+	JournalEntry   *ledger.JournalEntry `json:"journal_entry,omitempty"`
+	Status         string               `json:"status"`
+	Rule           string               `json:"rule"`
+	Score          int                  `json:"score"`
+	CreatedAt      string               `json:"created_at"`
+	DecidedAt      string               `json:"decided_at,omitempty"`
+	DecidedBy      string               `json:"decided_by,omitempty"`
+}
+
+// Result is the reconciliation state of a statement. Matched holds matched
+// and confirmed pairs. UnmatchedEntries are the account's entries effective in
+// the statement period that no line accounts for.
+type Result struct {
+	Statement        *Statement            `json:"statement"`
+	Matched          []Match               `json:"matched"`
+	Suggested        []Match               `json:"suggested"`
+	UnmatchedLines   []Line                `json:"unmatched_lines"`
+	UnmatchedEntries []ledger.JournalEntry `json:"unmatched_entries"`
+}
+
+// ImportRequest represents the request to import a statement against a ledger
+// account. Content is the statement file as text. Reference overrides the
+// statement's own identifier; when neither is present a digest of Content is
+// used, so the same file cannot be imported twice.
+type ImportRequest struct {
+	AccountID  string `json:"account_id"`
+	Format     string `json:"format"`
+	Content    string `json:"content"`
+	Reference  string `json:"reference"`
+	ImportedBy string `json:"imported_by"`
+}
+
+// DecisionRequest represents an operator confirming or rejecting a suggestion
+type DecisionRequest struct {
+	MatchID   string `json:"match_id"`
+	DecidedBy string `json:"decided_by"`
+}
+
+// Reconciler imports statements and matches them against the journal entries
+// of a PostgresLedger
+type Reconciler struct {
+	ledger *ledger.PostgresLedger
+	Rules  Rules
+}
+
+// NewReconciler creates a reconciler over pl using DefaultRules
+func NewReconciler(pl *ledger.PostgresLedger) *Reconciler {
+	return &Reconciler{ledger: pl, Rules: DefaultRules}
+}
+
+// ImportStatement parses a statement and stores it against the account. The
+// statement must be in the account's currency.
+func (r *Reconciler) ImportStatement(ctx context.Context, req ImportRequest) (*Statement, error) {
+	if req.AccountID == "" {
+		return nil, fmt.Errorf("%w: account ID is required", ledger.ErrInvalidRequest)
+	}
+	if req.ImportedBy == "" {
+		return nil, fmt.Errorf("%w: imported_by is required", ledger.ErrInvalidRequest)
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	var currencyCode string
+	err := r.ledger.Pool.QueryRow(queryCtx, `SELECT currency_code FROM accounts WHERE id = $1`, req.AccountID).Scan(&currencyCode)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ledger.ErrAccountNotFound, req.AccountID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get account: %w", err)
+	}
+
+	st, err := Parse(req.Format, strings.NewReader(req.Content), currencyCode)
+	if err != nil {
+		return nil, err
+	}
+	if st.CurrencyCode != currencyCode {
+		return nil, fmt.Errorf("%w: statement is in %s, account is in %s", ledger.ErrCurrencyMismatch, st.CurrencyCode, currencyCode)
+	}
+	st.AccountID = req.AccountID
+	st.ImportedBy = req.ImportedBy
+	if req.Reference != "" {
+		st.Reference = req.Reference
+	}
+	if st.Reference == "" {
+		sum := sha256.Sum256([]byte(req.Content))
+		st.Reference = "sha256:" + hex.EncodeToString(sum[:])
+	}
+
+	tx, err := r.ledger.Pool.Begin(queryCtx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(queryCtx)
+
+	err = tx.QueryRow(queryCtx, `
+		INSERT INTO bank_statements (
+			account_id, format, statement_reference, currency_code,
+			opening_balance, closing_balance, period_start, period_end, imported_by
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, imported_at::text
+	`, st.AccountID, st.Format, st.Reference, st.CurrencyCode,
+		st.OpeningBalance, st.ClosingBalance, st.PeriodStart, st.PeriodEnd, st.ImportedBy,
+	).Scan(&st.ID, &st.ImportedAt)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return nil, fmt.Errorf("%w: %s", ErrStatementExists, st.Reference)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert statement: %w", err)
+	}
+
+	for i := range st.Lines {
+		l := &st.Lines[i]
+		l.StatementID = st.ID
+		l.Status = LineUnmatched
+		var valueDate *string
+		if l.ValueDate != "" {
+			valueDate = &l.ValueDate
+		}
+		err := tx.QueryRow(queryCtx, `
+			INSERT INTO bank_statement_lines (
+				statement_id, line_number, booking_date, value_date, amount, direction, reference, description
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			RETURNING id
+		`, st.ID, l.LineNumber, l.BookingDate, valueDate, l.Amount, l.Direction, l.Reference, l.Description).Scan(&l.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to insert statement line %d: %w", l.LineNumber, err)
+		}
+	}
+
+	if err := tx.Commit(queryCtx); err != nil {
+		return nil, fmt.Errorf("failed to commit statement: %w", err)
+	}
+	return st, nil
+}
+
+// GetStatement returns a statement and its lines
+func (r *Reconciler) GetStatement(ctx context.Context, statementID string) (*Statement, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	return getStatement(queryCtx, r.ledger.Pool, statementID, false)
+}
+
+// Match runs the matcher over the statement's unmatched lines and the
+// account's entries that are not yet matched, records the matches and
+// suggestions it finds and returns the statement's reconciliation state
+func (r *Reconciler) Match(ctx context.Context, statementID string) (*Result, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	tx, err := r.ledger.Pool.Begin(queryCtx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(queryCtx)
+
+	// Locking the statement serialises matching runs over it
+	st, err := getStatement(queryCtx, tx, statementID, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []Line
+	for _, l := range st.Lines {
+		if l.Status == LineUnmatched {
+			lines = append(lines, l)
+		}
+	}
+	if len(lines) == 0 {
+		if err := tx.Commit(queryCtx); err != nil {
+			return nil, fmt.Errorf("failed to commit matching: %w", err)
+		}
+		return r.Result(ctx, statementID)
+	}
+
+	rejected, err := rejectedPairs(queryCtx, tx, statementID)
+	if err != nil {
+		return nil, err
+	}
+
+	window := r.Rules.DateWindow
+	entries, err := queryEntries(queryCtx, tx, `
+		WHERE je.account_id = $1
+		AND je.effective_date BETWEEN $2::date - $4::int AND $3::date + $4::int
+		AND NOT EXISTS (
+			SELECT 1 FROM reconciliation_matches rm
+			WHERE rm.journal_entry_id = je.id AND rm.status <> 'rejected'
+		)
+		ORDER BY je.effective_date, je.entry_number
+	`, st.AccountID, st.PeriodStart, st.PeriodEnd, window)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, m := range matchLines(lines, entries, rejected, r.Rules) {
+		_, err := tx.Exec(queryCtx, `
+			INSERT INTO reconciliation_matches (statement_line_id, journal_entry_id, status, rule, score)
+			VALUES ($1, $2, $3, $4, $5)
+		`, m.StatementLine.ID, m.JournalEntryID, m.Status, m.Rule, m.Score)
+		if err != nil {
+			return nil, fmt.Errorf("failed to record match for line %d: %w", m.StatementLine.LineNumber, err)
+		}
+		lineStatus := LineSuggested
+		if m.Status == MatchMatched {
+			lineStatus = LineMatched
+		}
+		if _, err := tx.Exec(queryCtx, `UPDATE bank_statement_lines SET status = $2 WHERE id = $1`, m.StatementLine.ID, lineStatus); err != nil {
+			return nil, fmt.Errorf("failed to update line %d: %w", m.StatementLine.LineNumber, err)
+		}
+	}
+
+	if err := tx.Commit(queryCtx); err != nil {
+		return nil, fmt.Errorf("failed to commit matching: %w", err)
+	}
+	return r.Result(ctx, statementID)
+}
+
+// Result returns the matched, suggested and unmatched sets of a statement
+func (r *Reconciler) Result(ctx context.Context, statementID string) (*Result, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	st, err := getStatement(queryCtx, r.ledger.Pool, statementID, false)
+	if err != nil {
+		return nil, err
+	}
+
+	matches, err := queryMatches(queryCtx, r.ledger.Pool, `WHERE l.statement_id = $1 AND rm.status <> 'rejected'`, statementID)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := queryEntries(queryCtx, r.ledger.Pool, `
+		WHERE je.account_id = $1
+		AND je.effective_date BETWEEN $2::date AND $3::date
+		AND NOT EXISTS (
+			SELECT 1 FROM reconciliation_matches rm
+			WHERE rm.journal_entry_id = je.id AND rm.status <> 'rejected'
+		)
+		ORDER BY je.effective_date, je.entry_number
+	`, st.AccountID, st.PeriodStart, st.PeriodEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &Result{
+		Statement:        st,
+		Matched:          []Match{},
+		Suggested:        []Match{},
+		UnmatchedLines:   []Line{},
+		UnmatchedEntries: entries,
+	}
+	if res.UnmatchedEntries == nil {
+		res.UnmatchedEntries = []ledger.JournalEntry{}
+	}
+	for _, m := range matches {
+		if m.Status == MatchSuggested {
+			res.Suggested = append(res.Suggested, m)
+		} else {
+			res.Matched = append(res.Matched, m)
+		}
+	}
+	for _, l := range st.Lines {
+		if l.Status == LineUnmatched {
+			res.UnmatchedLines = append(res.UnmatchedLines, l)
+		}
+	}
+	return res, nil
+}
+
+// ConfirmMatch accepts a suggested match, marking its line matched
+func (r *Reconciler) ConfirmMatch(ctx context.Context, req DecisionRequest) (*Match, error) {
+	return r.decide(ctx, req, MatchConfirmed, LineMatched)
+}
+
+// RejectMatch turns down a suggested match. Its line goes back to unmatched
+// and the pair is not suggested again.
+func (r *Reconciler) RejectMatch(ctx context.Context, req DecisionRequest) (*Match, error) {
+	return r.decide(ctx, req, MatchRejected, LineUnmatched)
+}
+
+func (r *Reconciler) decide(ctx context.Context, req DecisionRequest, status, lineStatus string) (*Match, error) {
+	if req.MatchID == "" {
+		return nil, fmt.Errorf("%w: match ID is required", ledger.ErrInvalidRequest)
+	}
+	if req.DecidedBy == "" {
+		return nil, fmt.Errorf("%w: decided_by is required", ledger.ErrInvalidRequest)
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	tx, err := r.ledger.Pool.Begin(queryCtx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(queryCtx)
+
+	var current, lineID string
+	err = tx.QueryRow(queryCtx, `
+		SELECT status, statement_line_id FROM reconciliation_matches WHERE id = $1 FOR UPDATE
+	`, req.MatchID).Scan(&current, &lineID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrMatchNotFound, req.MatchID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get match: %w", err)
+	}
+	if current != MatchSuggested {
+		return nil, fmt.Errorf("%w: %s is %s", ErrMatchDecided, req.MatchID, current)
+	}
+
+	_, err = tx.Exec(queryCtx, `
+		UPDATE reconciliation_matches
+		SET status = $2, decided_at = CURRENT_TIMESTAMP, decided_by = $3
+		WHERE id = $1
+	`, req.MatchID, status, req.DecidedBy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update match: %w", err)
+	}
+	if _, err := tx.Exec(queryCtx, `UPDATE bank_statement_lines SET status = $2 WHERE id = $1`, lineID, lineStatus); err != nil {
+		return nil, fmt.Errorf("failed to update statement line: %w", err)
+	}
+
+	matches, err := queryMatches(queryCtx, tx, `WHERE rm.id = $1`, req.MatchID)
+	if err != nil {
+		return nil, err
+	}
+	if len(matches) != 1 {
+		return nil, fmt.Errorf("%w: %s", ErrMatchNotFound, req.MatchID)
+	}
+	if err := tx.Commit(queryCtx); err != nil {
+		return nil, fmt.Errorf("failed to commit decision: %w", err)
+	}
+	return &matches[0], nil
+}
+
+// querier is satisfied by both the pool and an open transaction
+type querier interface {
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+// getStatement loads a statement and its lines, locking the statement row
+// when lock is set
+func getStatement(ctx context.Context, q querier, statementID string, lock bool) (*Statement, error) {
+	query := `
+		SELECT id, account_id, format, statement_reference, currency_code,
+			opening_balance, closing_balance, period_start::text, period_end::text,
+			imported_at::text, imported_by
+		FROM bank_statements
+		WHERE id = $1`
+	if lock {
+		query += ` FOR UPDATE`
+	}
+
+	var st Statement
+	var opening, closing pgtype.Numeric
+	err := q.QueryRow(ctx, query, statementID).Scan(
+		&st.ID, &st.AccountID, &st.Format, &st.Reference, &st.CurrencyCode,
+		&opening, &closing, &st.PeriodStart, &st.PeriodEnd,
+		&st.ImportedAt, &st.ImportedBy,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrStatementNotFound, statementID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get statement: %w", err)
+	}
+	for _, f := range []struct {
+		dst **money.Money
+		src pgtype.Numeric
+	}{
+		{&st.OpeningBalance, opening},
+		{&st.ClosingBalance, closing},
+	} {
+		if !f.src.Valid {
+			continue
+		}
+		m, err := money.FromNumeric(f.src, st.CurrencyCode)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read statement %s balance: %w", st.ID, err)
+		}
+		*f.dst = &m
+	}
+
+	rows, err := q.Query(ctx, `
+		SELECT id, statement_id, line_number, booking_date::text, COALESCE(value_date::text, ''),
+			amount, direction, reference, description, status
+		FROM bank_statement_lines
+		WHERE statement_id = $1
+		ORDER BY line_number
+	`, statementID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query statement lines: %w", err)
+	}
+	defer rows.Close()
+
+	st.Lines = []Line{}
+	for rows.Next() {
+		var l Line
+		var amount pgtype.Numeric
+		err := rows.Scan(
+			&l.ID, &l.StatementID, &l.LineNumber, &l.BookingDate, &l.ValueDate,
+			&amount, &l.Direction, &l.Reference, &l.Description, &l.Status,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan statement line: %w", err)
+		}
+		if l.Amount, err = money.FromNumeric(amount, st.CurrencyCode); err != nil {
+			return nil, fmt.Errorf("failed to read statement line %d: %w", l.LineNumber, err)
+		}
+		st.Lines = append(st.Lines, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read statement lines: %w", err)
+	}
+
+	return &st, nil
+}
+
+// rejectedPairs lists the line and entry pairs of a statement an operator has
+// turned down
+func rejectedPairs(ctx context.Context, q querier, statementID string) (map[pair]bool, error) {
+	rows, err := q.Query(ctx, `
+		SELECT rm.statement_line_id, rm.journal_entry_id
+		FROM reconciliation_matches rm
+		JOIN bank_statement_lines l ON l.id = rm.statement_line_id
+		WHERE l.statement_id = $1 AND rm.status = 'rejected'
+	`, statementID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query rejected matches: %w", err)
+	}
+	defer rows.Close()
+
+	rejected := map[pair]bool{}
+	for rows.Next() {
+		var p pair
+		if err := rows.Scan(&p.lineID, &p.entryID); err != nil {
+			return nil, fmt.Errorf("failed to scan rejected match: %w", err)
+		}
+		rejected[p] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read rejected matches: %w", err)
+	}
+	return rejected, nil
+}
+
+// entryColumns are the journal_entries columns, aliased je, read by queryEntries
+const entryColumns = `
+	je.id, je.entry_number, je.transaction_id, je.entry_type, je.account_id, je.account_type, je.amount,
+	je.description, COALESCE(je.reference_type, ''), COALESCE(je.reference_id, ''), je.currency_code,
+	je.effective_date::text, je.created_at::text, je.created_by`
+
+// queryEntries lists the journal entries, aliased je, selected by the rest of
+// the query
+func queryEntries(ctx context.Context, q querier, rest string, args ...interface{}) ([]ledger.JournalEntry, error) {
+	rows, err := q.Query(ctx, `SELECT `+entryColumns+` FROM journal_entries je `+rest, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query journal entries: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []ledger.JournalEntry
+	for rows.Next() {
+		e, err := scanEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read journal entries: %w", err)
+	}
+	return entries, nil
+}
+
+func scanEntry(row pgx.Row, extra ...interface{}) (ledger.JournalEntry, error) {
+	var e ledger.JournalEntry
+	var amount pgtype.Numeric
+	dest := []interface{}{
+		&e.ID, &e.EntryNumber, &e.TransactionID, &e.EntryType, &e.AccountID, &e.AccountType, &amount,
+		&e.Description, &e.ReferenceType, &e.ReferenceID, &e.CurrencyCode,
+		&e.EffectiveDate, &e.CreatedAt, &e.CreatedBy,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return e, fmt.Errorf("failed to scan journal entry: %w", err)
+	}
+	var err error
+	if e.Amount, err = money.FromNumeric(amount, e.CurrencyCode); err != nil {
+		return e, fmt.Errorf("failed to read journal entry %s: %w", e.ID, err)
+	}
+	return e, nil
+}
+
+// queryMatches lists matches with their line and entry, in line order,
+// filtered by where over reconciliation_matches rm and bank_statement_lines l
+func queryMatches(ctx context.Context, q querier, where string, args ...interface{}) ([]Match, error) {
+	rows, err := q.Query(ctx, `
+		SELECT `+entryColumns+`,
+			rm.id, rm.status, rm.rule, rm.score, rm.created_at::text,
+			COALESCE(rm.decided_at::text, ''), COALESCE(rm.decided_by, ''),
+			l.id, l.statement_id, l.line_number, l.booking_date::text, COALESCE(l.value_date::text, ''),
+			l.amount, l.direction, l.reference, l.description, l.status
+		FROM reconciliation_matches rm
+		JOIN bank_statement_lines l ON l.id = rm.statement_line_id
+		JOIN journal_entries je ON je.id = rm.journal_entry_id
+		`+where+`
+		ORDER BY l.line_number, rm.created_at
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query matches: %w", err)
+	}
+	defer rows.Close()
+
+	var matches []Match
+	for rows.Next() {
+		var m Match
+		var amount pgtype.Numeric
+		e, err := scanEntry(rows,
+			&m.ID, &m.Status, &m.Rule, &m.Score, &m.CreatedAt, &m.DecidedAt, &m.DecidedBy,
+			&m.StatementLine.ID, &m.StatementLine.StatementID, &m.StatementLine.LineNumber,
+			&m.StatementLine.BookingDate, &m.StatementLine.ValueDate,
+			&amount, &m.StatementLine.Direction, &m.StatementLine.Reference,
+			&m.StatementLine.Description, &m.StatementLine.Status,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if m.StatementLine.Amount, err = money.FromNumeric(amount, e.CurrencyCode); err != nil {
+			return nil, fmt.Errorf("failed to read statement line %d: %w", m.StatementLine.LineNumber, err)
+		}
+		m.StatementID = m.StatementLine.StatementID
+		m.JournalEntryID = e.ID
+		m.JournalEntry = &e
+		matches = append(matches, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read matches: %w", err)
+	}
+	return matches, nil
+}
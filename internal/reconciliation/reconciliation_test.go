@@ -0,0 +1,258 @@
+package reconciliation
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/pci-infra/internal/ledger"
+	"github.com/example/pci-infra/internal/money"
+)
+
+func eur(s string) money.Money {
+	return money.MustParse(s, "EUR")
+}
+
+func TestParseCSV(t *testing.T) {
+	content := `Date,Value_Date,Amount,Reference,Description
+2024-01-02,2024-01-03,150.00,INV-1001,"Payment, Acme Ltd"
+2024-01-05,,-20.50,,Bank fee
+
+2024-01-04,,75,INV-1002,
+`
+	st, err := Parse(FormatCSV, strings.NewReader(content), "EUR")
+	require.NoError(t, err)
+
+	assert.Equal(t, "EUR", st.CurrencyCode)
+	assert.Equal(t, "2024-01-02", st.PeriodStart)
+	assert.Equal(t, "2024-01-05", st.PeriodEnd)
+	require.Len(t, st.Lines, 3)
+	assert.Equal(t, Line{
+		LineNumber:  1,
+		BookingDate: "2024-01-02",
+		ValueDate:   "2024-01-03",
+		Amount:      eur("150.00"),
+		Direction:   DirectionCredit,
+		Reference:   "INV-1001",
+		Description: "Payment, Acme Ltd",
+	}, st.Lines[0])
+	assert.Equal(t, DirectionDebit, st.Lines[1].Direction)
+	assert.Equal(t, eur("20.50"), st.Lines[1].Amount)
+	assert.Equal(t, 3, st.Lines[2].LineNumber)
+
+	// an explicit direction column takes unsigned amounts
+	st, err = Parse(FormatCSV, strings.NewReader("booking_date,amount,direction\n2024-01-02,10.00,DBIT\n"), "EUR")
+	require.NoError(t, err)
+	assert.Equal(t, DirectionDebit, st.Lines[0].Direction)
+
+	for name, bad := range map[string]string{
+		"no amount column": "date,reference\n2024-01-02,x\n",
+		"bad date":         "date,amount\n02/01/2024,1.00\n",
+		"bad amount":       "date,amount\n2024-01-02,1.001\n",
+		"zero amount":      "date,amount\n2024-01-02,0\n",
+		"other currency":   "date,amount,currency\n2024-01-02,1.00,USD\n",
+		"no lines":         "date,amount\n",
+	} {
+		_, err := Parse(FormatCSV, strings.NewReader(bad), "EUR")
+		assert.True(t, errors.Is(err, ledger.ErrInvalidRequest), name)
+	}
+}
+
+func TestParseCAMT053(t *testing.T) {
+	content := `<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.02">
+  <BkToCstmrStmt>
+    <Stmt>
+      <Id>STMT-2024-01</Id>
+      <Bal>
+        <Tp><CdOrPrtry><Cd>OPBD</Cd></CdOrPrtry></Tp>
+        <Amt Ccy="EUR">1000.00</Amt>
+        <CdtDbtInd>CRDT</CdtDbtInd>
+      </Bal>
+      <Bal>
+        <Tp><CdOrPrtry><Cd>CLBD</Cd></CdOrPrtry></Tp>
+        <Amt Ccy="EUR">1129.50</Amt>
+        <CdtDbtInd>CRDT</CdtDbtInd>
+      </Bal>
+      <Ntry>
+        <NtryRef>NTRY-1</NtryRef>
+        <Amt Ccy="EUR">150.00</Amt>
+        <CdtDbtInd>CRDT</CdtDbtInd>
+        <BookgDt><Dt>2024-01-02</Dt></BookgDt>
+        <ValDt><Dt>2024-01-03</Dt></ValDt>
+        <NtryDtls><TxDtls>
+          <Refs><EndToEndId>INV-1001</EndToEndId></Refs>
+          <RmtInf><Ustrd>Invoice 1001</Ustrd><Ustrd>Acme Ltd</Ustrd></RmtInf>
+        </TxDtls></NtryDtls>
+      </Ntry>
+      <Ntry>
+        <NtryRef>NTRY-2</NtryRef>
+        <Amt Ccy="EUR">20.50</Amt>
+        <CdtDbtInd>DBIT</CdtDbtInd>
+        <BookgDt><DtTm>2024-01-05T09:30:00</DtTm></BookgDt>
+        <NtryDtls><TxDtls>
+          <Refs><EndToEndId>NOTPROVIDED</EndToEndId></Refs>
+        </TxDtls></NtryDtls>
+        <AddtlNtryInf>Bank fee</AddtlNtryInf>
+      </Ntry>
+    </Stmt>
+  </BkToCstmrStmt>
+</Document>`
+	st, err := Parse(FormatCAMT053, strings.NewReader(content), "")
+	require.NoError(t, err)
+
+	assert.Equal(t, "STMT-2024-01", st.Reference)
+	assert.Equal(t, "EUR", st.CurrencyCode)
+	require.NotNil(t, st.OpeningBalance)
+	assert.Equal(t, eur("1000.00"), *st.OpeningBalance)
+	assert.Equal(t, eur("1129.50"), *st.ClosingBalance)
+	require.Len(t, st.Lines, 2)
+	assert.Equal(t, "INV-1001", st.Lines[0].Reference)
+	assert.Equal(t, "Invoice 1001 Acme Ltd", st.Lines[0].Description)
+	assert.Equal(t, "2024-01-03", st.Lines[0].ValueDate)
+	assert.Equal(t, DirectionDebit, st.Lines[1].Direction)
+	assert.Equal(t, "2024-01-05", st.Lines[1].BookingDate)
+	assert.Equal(t, "NTRY-2", st.Lines[1].Reference)
+	assert.Equal(t, "Bank fee", st.Lines[1].Description)
+
+	_, err = Parse(FormatCAMT053, strings.NewReader("<Document><BkToCstmrStmt/></Document>"), "")
+	assert.True(t, errors.Is(err, ErrInvalidStatement))
+}
+
+func TestParseMT940(t *testing.T) {
+	content := `{1:F01BANKDEFFXXXX0000000000}{2:O9400000000000BANKDEFFXXXX00000000000000000000N}{4:
+:20:STMT240131
+:25:DE89370400440532013000
+:28C:00001/001
+:60F:C231229EUR1000,00
+:61:2312290102C150,00NTRFINV-1001//BANKREF1
+:86:Payment Acme Ltd
+ invoice 1001
+:61:240105D20,5NCHGNONREF//FEE-99
+:86:Bank fee
+:62F:C240105EUR1129,50
+-}`
+	st, err := Parse(FormatMT940, strings.NewReader(content), "")
+	require.NoError(t, err)
+
+	assert.Equal(t, "STMT240131/00001/001", st.Reference)
+	assert.Equal(t, "EUR", st.CurrencyCode)
+	assert.Equal(t, eur("1000.00"), *st.OpeningBalance)
+	assert.Equal(t, eur("1129.50"), *st.ClosingBalance)
+	require.Len(t, st.Lines, 2)
+
+	// the entry date rolls into the next year
+	assert.Equal(t, "2023-12-29", st.Lines[0].ValueDate)
+	assert.Equal(t, "2024-01-02", st.Lines[0].BookingDate)
+	assert.Equal(t, eur("150.00"), st.Lines[0].Amount)
+	assert.Equal(t, DirectionCredit, st.Lines[0].Direction)
+	assert.Equal(t, "INV-1001", st.Lines[0].Reference)
+	assert.Equal(t, "Payment Acme Ltd invoice 1001", st.Lines[0].Description)
+
+	assert.Equal(t, DirectionDebit, st.Lines[1].Direction)
+	assert.Equal(t, eur("20.50"), st.Lines[1].Amount)
+	assert.Equal(t, "FEE-99", st.Lines[1].Reference)
+	assert.Equal(t, "2023-12-29", st.PeriodStart)
+	assert.Equal(t, "2024-01-05", st.PeriodEnd)
+
+	_, err = Parse(FormatMT940, strings.NewReader(":20:X\n:61:240105D20,5NCHGNONREF\n"), "")
+	assert.True(t, errors.Is(err, ErrInvalidStatement))
+
+	_, err = Parse("ofx", strings.NewReader(content), "")
+	assert.True(t, errors.Is(err, ErrInvalidStatement))
+}
+
+func TestMatchLines(t *testing.T) {
+	line := func(n int, date, amount, direction, reference, description string) Line {
+		return Line{
+			ID:          "line-" + string(rune('0'+n)),
+			LineNumber:  n,
+			BookingDate: date,
+			Amount:      eur(amount),
+			Direction:   direction,
+			Reference:   reference,
+			Description: description,
+		}
+	}
+	entry := func(id, date, amount, entryType, referenceID string) ledger.JournalEntry {
+		return ledger.JournalEntry{
+			ID:            id,
+			EntryNumber:   "JE-" + id,
+			TransactionID: "txn-" + id,
+			EntryType:     entryType,
+			Amount:        eur(amount),
+			ReferenceID:   referenceID,
+			EffectiveDate: date,
+		}
+	}
+
+	lines := []Line{
+		line(1, "2024-01-02", "150.00", DirectionCredit, "INV-1001", ""),
+		line(2, "2024-01-05", "20.50", DirectionDebit, "", "Bank fee"),
+		line(3, "2024-01-06", "75.00", DirectionCredit, "", "Transfer ref inv-1002"),
+		line(4, "2024-01-20", "99.00", DirectionCredit, "", ""),
+		line(5, "2024-01-08", "40.00", DirectionCredit, "", ""),
+	}
+	entries := []ledger.JournalEntry{
+		entry("a", "2024-01-01", "150.00", "debit", "INV-1001"),
+		entry("b", "2024-01-05", "20.50", "credit", ""),
+		entry("c", "2024-01-04", "75.00", "debit", "INV-1002"),
+		entry("d", "2024-01-10", "99.00", "debit", ""),
+		// right amount, wrong direction
+		entry("e", "2024-01-08", "40.00", "credit", ""),
+		// two entries a line could be, the closer one wins
+		entry("f", "2024-01-08", "40.00", "debit", ""),
+		entry("g", "2024-01-06", "40.00", "debit", ""),
+	}
+
+	matches := matchLines(lines, entries, map[pair]bool{}, DefaultRules)
+	require.Len(t, matches, 4)
+
+	// reference on the line
+	assert.Equal(t, "line-1", matches[0].StatementLine.ID)
+	assert.Equal(t, "a", matches[0].JournalEntryID)
+	assert.Equal(t, MatchMatched, matches[0].Status)
+	assert.Equal(t, RuleReference, matches[0].Rule)
+	assert.Equal(t, 98, matches[0].Score)
+
+	// amount and date only is a suggestion
+	assert.Equal(t, "b", matches[1].JournalEntryID)
+	assert.Equal(t, MatchSuggested, matches[1].Status)
+	assert.Equal(t, RuleAmountDate, matches[1].Rule)
+	assert.Equal(t, 60, matches[1].Score)
+
+	// entry reference in the line's description
+	assert.Equal(t, "c", matches[2].JournalEntryID)
+	assert.Equal(t, MatchMatched, matches[2].Status)
+
+	// line 4 is outside the date window
+	assert.Equal(t, "line-5", matches[3].StatementLine.ID)
+	assert.Equal(t, "f", matches[3].JournalEntryID)
+	assert.Equal(t, MatchSuggested, matches[3].Status)
+
+	// a rejected pair is not suggested again; the next best is
+	matches = matchLines(lines[4:], entries, map[pair]bool{{"line-5", "f"}: true}, DefaultRules)
+	require.Len(t, matches, 1)
+	assert.Equal(t, "g", matches[0].JournalEntryID)
+
+	// an entry pairs with one line only
+	twin := line(6, "2024-01-02", "150.00", DirectionCredit, "", "")
+	matches = matchLines([]Line{lines[0], twin}, entries[:1], map[pair]bool{}, DefaultRules)
+	require.Len(t, matches, 1)
+	assert.Equal(t, "line-1", matches[0].StatementLine.ID)
+
+	// a reference shared by two candidates is only a suggestion
+	dup := entry("h", "2024-01-03", "150.00", "debit", "INV-1001")
+	matches = matchLines(lines[:1], []ledger.JournalEntry{entries[0], dup}, map[pair]bool{}, DefaultRules)
+	require.Len(t, matches, 1)
+	assert.Equal(t, MatchSuggested, matches[0].Status)
+	assert.Equal(t, "a", matches[0].JournalEntryID)
+
+	// a wider window reaches line 4
+	matches = matchLines(lines[3:4], entries, map[pair]bool{}, Rules{DateWindow: 10})
+	require.Len(t, matches, 1)
+	assert.Equal(t, "d", matches[0].JournalEntryID)
+}
@@ -0,0 +1,67 @@
+package reporting
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+)
+
+// WriteCSV writes the trial balance with one row per account, a totals row and
+// the check
+func (tb *TrialBalance) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"account_number", "name", "account_type", "period_debits", "period_credits", "debit_balance", "credit_balance"})
+	for _, l := range tb.Lines {
+		_ = cw.Write([]string{
+			l.AccountNumber, l.Name, l.AccountType,
+			l.PeriodDebits.Amount(), l.PeriodCredits.Amount(), l.DebitBalance.Amount(), l.CreditBalance.Amount(),
+		})
+	}
+	_ = cw.Write([]string{
+		"", "Total", "",
+		tb.TotalPeriodDebits.Amount(), tb.TotalPeriodCredits.Amount(), tb.TotalDebitBalance.Amount(), tb.TotalCreditBalance.Amount(),
+	})
+	writeCheck(cw, tb.Check)
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteCSV writes the balance sheet as section, account and amount rows with a
+// total after each section
+func (bs *BalanceSheet) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"section", "account_number", "name", "amount"})
+	writeSection(cw, "asset", "Total assets", bs.Assets)
+	writeSection(cw, "liability", "Total liabilities", bs.Liabilities)
+	writeSection(cw, "equity", "Total equity", bs.Equity)
+	_ = cw.Write([]string{"equity", "", "Retained earnings", bs.RetainedEarnings.Amount()})
+	_ = cw.Write([]string{"", "", "Total liabilities and equity", bs.TotalLiabilitiesAndEquity.Amount()})
+	writeCheck(cw, bs.Check)
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteCSV writes the income statement as section, account and amount rows
+// followed by net income
+func (is *IncomeStatement) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"section", "account_number", "name", "amount"})
+	writeSection(cw, "revenue", "Total revenue", is.Revenue)
+	writeSection(cw, "expense", "Total expenses", is.Expenses)
+	_ = cw.Write([]string{"", "", "Net income", is.NetIncome.Amount()})
+	writeCheck(cw, is.Check)
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeSection(cw *csv.Writer, name, totalLabel string, s Section) {
+	for _, l := range s.Lines {
+		_ = cw.Write([]string{name, l.AccountNumber, l.Name, l.Amount.Amount()})
+	}
+	_ = cw.Write([]string{name, "", totalLabel, s.Total.Amount()})
+}
+
+// writeCheck ends a report with whether it balances and by how much it is out
+func writeCheck(cw *csv.Writer, c Check) {
+	_ = cw.Write([]string{"check", "balanced", strconv.FormatBool(c.Balanced), c.Difference.Amount()})
+}
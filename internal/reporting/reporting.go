@@ -0,0 +1,482 @@
+// Package reporting builds month-end financial reports from the ledger: a
+// trial balance, a balance sheet and an income statement for one currency.
+// Every report carries a check that its totals balance.
+package reporting
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/example/pci-infra/internal/ledger"
+	"github.com/example/pci-infra/internal/money"
+)
+
+// Request selects the currency and period a report covers. The trial balance
+// and income statement cover entries effective in [From, To); the balance sheet
+// is as of To and ignores From.
+type Request struct {
+	CurrencyCode string    `json:"currency_code"`
+	From         time.Time `json:"from"`
+	To           time.Time `json:"to"`
+}
+
+// Check reports whether a report's totals agree. Difference is zero when they do.
+type Check struct {
+	Balanced   bool        `json:"balanced"`
+	Difference money.Money `json:"difference"`
+}
+
+// Line is one account's amount in a report section
+type Line struct {
+	AccountID     string      `json:"account_id"`
+	AccountNumber string      `json:"account_number"`
+	Name          string      `json:"name"`
+	Amount        money.Money `json:"amount"`
+}
+
+// Section is a group of accounts of one type with their total
+type Section struct {
+	Lines []Line      `json:"lines"`
+	Total money.Money `json:"total"`
+}
+
+// TrialBalanceLine is one account's movement in the period and its closing
+// balance on the debit or credit side
+type TrialBalanceLine struct {
+	AccountID     string      `json:"account_id"`
+	AccountNumber string      `json:"account_number"`
+	Name          string      `json:"name"`
+	AccountType   string      `json:"account_type"`
+	PeriodDebits  money.Money `json:"period_debits"`
+	PeriodCredits money.Money `json:"period_credits"`
+	DebitBalance  money.Money `json:"debit_balance"`
+	CreditBalance money.Money `json:"credit_balance"`
+}
+
+// TrialBalance lists every account with activity or a balance. It balances
+// when period debits equal period credits and debit balances equal credit
+// balances.
+type TrialBalance struct {
+	CurrencyCode       string             `json:"currency_code"`
+	From               string             `json:"from"`
+	To                 string             `json:"to"`
+	Lines              []TrialBalanceLine `json:"lines"`
+	TotalPeriodDebits  money.Money        `json:"total_period_debits"`
+	TotalPeriodCredits money.Money        `json:"total_period_credits"`
+	TotalDebitBalance  money.Money        `json:"total_debit_balance"`
+	TotalCreditBalance money.Money        `json:"total_credit_balance"`
+	Check              Check              `json:"check"`
+}
+
+// BalanceSheet states assets, liabilities and equity as of a point in time.
+// Revenue less expenses not yet closed into an equity account is reported as
+// retained earnings, so assets equal liabilities plus equity.
+type BalanceSheet struct {
+	CurrencyCode              string      `json:"currency_code"`
+	At                        string      `json:"at"`
+	Assets                    Section     `json:"assets"`
+	Liabilities               Section     `json:"liabilities"`
+	Equity                    Section     `json:"equity"`
+	RetainedEarnings          money.Money `json:"retained_earnings"`
+	TotalLiabilitiesAndEquity money.Money `json:"total_liabilities_and_equity"`
+	Check                     Check       `json:"check"`
+}
+
+// IncomeStatement states revenue and expenses for a period. Its check compares
+// net income with the period's movement in assets less liabilities and equity,
+// which double entry makes equal.
+type IncomeStatement struct {
+	CurrencyCode string      `json:"currency_code"`
+	From         string      `json:"from"`
+	To           string      `json:"to"`
+	Revenue      Section     `json:"revenue"`
+	Expenses     Section     `json:"expenses"`
+	NetIncome    money.Money `json:"net_income"`
+	Check        Check       `json:"check"`
+}
+
+// Reporter builds reports from the journal entries of a PostgresLedger
+type Reporter struct {
+	ledger *ledger.PostgresLedger
+}
+
+// NewReporter creates a reporter over pl
+func NewReporter(pl *ledger.PostgresLedger) *Reporter {
+	return &Reporter{ledger: pl}
+}
+
+// TrialBalance reports every account's movement in [From, To) and balance at To
+func (r *Reporter) TrialBalance(ctx context.Context, req Request) (*TrialBalance, error) {
+	if err := validatePeriod(req); err != nil {
+		return nil, err
+	}
+	accounts, err := r.activity(ctx, req.CurrencyCode, req.From, req.To)
+	if err != nil {
+		return nil, err
+	}
+	return buildTrialBalance(req, accounts)
+}
+
+// BalanceSheet reports assets, liabilities and equity as of To
+func (r *Reporter) BalanceSheet(ctx context.Context, req Request) (*BalanceSheet, error) {
+	if err := validateCurrency(req.CurrencyCode); err != nil {
+		return nil, err
+	}
+	if req.To.IsZero() {
+		return nil, fmt.Errorf("%w: at is required", ledger.ErrInvalidRequest)
+	}
+	accounts, err := r.activity(ctx, req.CurrencyCode, req.To, req.To)
+	if err != nil {
+		return nil, err
+	}
+	return buildBalanceSheet(req, accounts)
+}
+
+// IncomeStatement reports revenue and expenses effective in [From, To)
+func (r *Reporter) IncomeStatement(ctx context.Context, req Request) (*IncomeStatement, error) {
+	if err := validatePeriod(req); err != nil {
+		return nil, err
+	}
+	accounts, err := r.activity(ctx, req.CurrencyCode, req.From, req.To)
+	if err != nil {
+		return nil, err
+	}
+	return buildIncomeStatement(req, accounts)
+}
+
+func validateCurrency(currencyCode string) error {
+	if _, err := money.Exponent(currencyCode); err != nil {
+		return fmt.Errorf("%w: %v", ledger.ErrInvalidRequest, err)
+	}
+	return nil
+}
+
+func validatePeriod(req Request) error {
+	if err := validateCurrency(req.CurrencyCode); err != nil {
+		return err
+	}
+	if req.From.IsZero() || req.To.IsZero() {
+		return fmt.Errorf("%w: from and to are required", ledger.ErrInvalidRequest)
+	}
+	if !req.From.Before(req.To) {
+		return fmt.Errorf("%w: from must be before to", ledger.ErrInvalidRequest)
+	}
+	return nil
+}
+
+// activity is an account's debits and credits before a period and within it
+type activity struct {
+	AccountID      string
+	AccountNumber  string
+	AccountType    string
+	Name           string
+	OpeningDebits  money.Money
+	OpeningCredits money.Money
+	PeriodDebits   money.Money
+	PeriodCredits  money.Money
+}
+
+// activity totals the entries of every account in currencyCode effective
+// before from and in [from, to). Entries are dated by day, so from and to are
+// taken as UTC dates. Entries closing a period into retained earnings count
+// towards later periods' opening balances but not the period they close, so
+// the period's revenue and expense stay visible.
+func (r *Reporter) activity(ctx context.Context, currencyCode string, from, to time.Time) ([]activity, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	rows, err := r.ledger.Pool.Query(queryCtx, `
+		SELECT
+			a.id, a.account_number, a.account_type, a.name,
+			COALESCE(SUM(je.amount) FILTER (WHERE je.entry_type = 'debit' AND je.effective_date < $2::date), 0),
+			COALESCE(SUM(je.amount) FILTER (WHERE je.entry_type = 'credit' AND je.effective_date < $2::date), 0),
+			COALESCE(SUM(je.amount) FILTER (WHERE je.entry_type = 'debit' AND je.effective_date >= $2::date AND je.reference_type IS DISTINCT FROM $4), 0),
+			COALESCE(SUM(je.amount) FILTER (WHERE je.entry_type = 'credit' AND je.effective_date >= $2::date AND je.reference_type IS DISTINCT FROM $4), 0)
+		FROM accounts a
+		LEFT JOIN journal_entries je ON je.account_id = a.id AND je.effective_date < $3::date
+		WHERE a.currency_code = $1
+		GROUP BY a.id, a.account_number, a.account_type, a.name
+		ORDER BY a.account_number
+	`, currencyCode, from.UTC(), to.UTC(), ledger.ReferenceTypePeriodClose)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query account activity: %w", err)
+	}
+	defer rows.Close()
+
+	var accounts []activity
+	for rows.Next() {
+		var a activity
+		var openingDebits, openingCredits, periodDebits, periodCredits pgtype.Numeric
+		err := rows.Scan(
+			&a.AccountID, &a.AccountNumber, &a.AccountType, &a.Name,
+			&openingDebits, &openingCredits, &periodDebits, &periodCredits,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan account activity: %w", err)
+		}
+
+		for _, f := range []struct {
+			dst *money.Money
+			src pgtype.Numeric
+		}{
+			{&a.OpeningDebits, openingDebits},
+			{&a.OpeningCredits, openingCredits},
+			{&a.PeriodDebits, periodDebits},
+			{&a.PeriodCredits, periodCredits},
+		} {
+			if *f.dst, err = money.FromNumeric(f.src, currencyCode); err != nil {
+				return nil, fmt.Errorf("failed to read activity of account %s: %w", a.AccountNumber, err)
+			}
+		}
+		accounts = append(accounts, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query account activity: %w", err)
+	}
+
+	return accounts, nil
+}
+
+// debitNormal reports whether debits increase accounts of accountType
+func debitNormal(accountType string) bool {
+	return accountType == "asset" || accountType == "expense"
+}
+
+// normal nets debits and credits in the direction that increases accountType
+func normal(accountType string, debits, credits money.Money) (money.Money, error) {
+	if debitNormal(accountType) {
+		return debits.Sub(credits)
+	}
+	return credits.Sub(debits)
+}
+
+// closing is the account's balance at the end of the period in its normal direction
+func (a activity) closing() (money.Money, error) {
+	debits, err := a.OpeningDebits.Add(a.PeriodDebits)
+	if err != nil {
+		return money.Money{}, err
+	}
+	credits, err := a.OpeningCredits.Add(a.PeriodCredits)
+	if err != nil {
+		return money.Money{}, err
+	}
+	return normal(a.AccountType, debits, credits)
+}
+
+// movement is the account's change in the period in its normal direction
+func (a activity) movement() (money.Money, error) {
+	return normal(a.AccountType, a.PeriodDebits, a.PeriodCredits)
+}
+
+// total accumulates amounts, keeping the first error
+type total struct {
+	sum money.Money
+	err error
+}
+
+func newTotal(currencyCode string) *total {
+	sum, err := money.Zero(currencyCode)
+	return &total{sum: sum, err: err}
+}
+
+func (t *total) add(m money.Money) {
+	if t.err == nil {
+		t.sum, t.err = t.sum.Add(m)
+	}
+}
+
+func (t *total) sub(m money.Money) {
+	if t.err == nil {
+		t.sum, t.err = t.sum.Sub(m)
+	}
+}
+
+func check(left, right money.Money) (Check, error) {
+	difference, err := left.Sub(right)
+	if err != nil {
+		return Check{}, err
+	}
+	return Check{Balanced: difference.IsZero(), Difference: difference}, nil
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+func buildTrialBalance(req Request, accounts []activity) (*TrialBalance, error) {
+	tb := &TrialBalance{
+		CurrencyCode: req.CurrencyCode,
+		From:         formatTime(req.From),
+		To:           formatTime(req.To),
+		Lines:        []TrialBalanceLine{},
+	}
+	zero, err := money.Zero(req.CurrencyCode)
+	if err != nil {
+		return nil, err
+	}
+	periodDebits, periodCredits := newTotal(req.CurrencyCode), newTotal(req.CurrencyCode)
+	debitBalance, creditBalance := newTotal(req.CurrencyCode), newTotal(req.CurrencyCode)
+
+	for _, a := range accounts {
+		net := newTotal(req.CurrencyCode)
+		net.add(a.OpeningDebits)
+		net.add(a.PeriodDebits)
+		net.sub(a.OpeningCredits)
+		net.sub(a.PeriodCredits)
+		if net.err != nil {
+			return nil, fmt.Errorf("failed to total account %s: %w", a.AccountNumber, net.err)
+		}
+		if a.PeriodDebits.IsZero() && a.PeriodCredits.IsZero() && net.sum.IsZero() {
+			continue
+		}
+
+		line := TrialBalanceLine{
+			AccountID:     a.AccountID,
+			AccountNumber: a.AccountNumber,
+			Name:          a.Name,
+			AccountType:   a.AccountType,
+			PeriodDebits:  a.PeriodDebits,
+			PeriodCredits: a.PeriodCredits,
+			DebitBalance:  zero,
+			CreditBalance: zero,
+		}
+		if net.sum.IsNegative() {
+			line.CreditBalance = net.sum.Abs()
+		} else {
+			line.DebitBalance = net.sum
+		}
+		tb.Lines = append(tb.Lines, line)
+
+		periodDebits.add(line.PeriodDebits)
+		periodCredits.add(line.PeriodCredits)
+		debitBalance.add(line.DebitBalance)
+		creditBalance.add(line.CreditBalance)
+	}
+	for _, t := range []*total{periodDebits, periodCredits, debitBalance, creditBalance} {
+		if t.err != nil {
+			return nil, fmt.Errorf("failed to total trial balance: %w", t.err)
+		}
+	}
+
+	tb.TotalPeriodDebits = periodDebits.sum
+	tb.TotalPeriodCredits = periodCredits.sum
+	tb.TotalDebitBalance = debitBalance.sum
+	tb.TotalCreditBalance = creditBalance.sum
+
+	if tb.Check, err = check(tb.TotalDebitBalance, tb.TotalCreditBalance); err != nil {
+		return nil, err
+	}
+	tb.Check.Balanced = tb.Check.Balanced && tb.TotalPeriodDebits.Equal(tb.TotalPeriodCredits)
+	return tb, nil
+}
+
+// section collects the accounts of accountType with a non-zero amount
+func section(currencyCode, accountType string, accounts []activity, amount func(activity) (money.Money, error)) (Section, error) {
+	s := Section{Lines: []Line{}}
+	sum := newTotal(currencyCode)
+	for _, a := range accounts {
+		if a.AccountType != accountType {
+			continue
+		}
+		m, err := amount(a)
+		if err != nil {
+			return Section{}, fmt.Errorf("failed to total account %s: %w", a.AccountNumber, err)
+		}
+		if m.IsZero() {
+			continue
+		}
+		s.Lines = append(s.Lines, Line{AccountID: a.AccountID, AccountNumber: a.AccountNumber, Name: a.Name, Amount: m})
+		sum.add(m)
+	}
+	if sum.err != nil {
+		return Section{}, fmt.Errorf("failed to total %s accounts: %w", accountType, sum.err)
+	}
+	s.Total = sum.sum
+	return s, nil
+}
+
+func buildBalanceSheet(req Request, accounts []activity) (*BalanceSheet, error) {
+	bs := &BalanceSheet{CurrencyCode: req.CurrencyCode, At: formatTime(req.To)}
+
+	var err error
+	if bs.Assets, err = section(req.CurrencyCode, "asset", accounts, activity.closing); err != nil {
+		return nil, err
+	}
+	if bs.Liabilities, err = section(req.CurrencyCode, "liability", accounts, activity.closing); err != nil {
+		return nil, err
+	}
+	if bs.Equity, err = section(req.CurrencyCode, "equity", accounts, activity.closing); err != nil {
+		return nil, err
+	}
+	revenue, err := section(req.CurrencyCode, "revenue", accounts, activity.closing)
+	if err != nil {
+		return nil, err
+	}
+	expenses, err := section(req.CurrencyCode, "expense", accounts, activity.closing)
+	if err != nil {
+		return nil, err
+	}
+
+	if bs.RetainedEarnings, err = revenue.Total.Sub(expenses.Total); err != nil {
+		return nil, fmt.Errorf("failed to compute retained earnings: %w", err)
+	}
+	liabilitiesAndEquity := newTotal(req.CurrencyCode)
+	liabilitiesAndEquity.add(bs.Liabilities.Total)
+	liabilitiesAndEquity.add(bs.Equity.Total)
+	liabilitiesAndEquity.add(bs.RetainedEarnings)
+	if liabilitiesAndEquity.err != nil {
+		return nil, fmt.Errorf("failed to total liabilities and equity: %w", liabilitiesAndEquity.err)
+	}
+	bs.TotalLiabilitiesAndEquity = liabilitiesAndEquity.sum
+
+	if bs.Check, err = check(bs.Assets.Total, bs.TotalLiabilitiesAndEquity); err != nil {
+		return nil, err
+	}
+	return bs, nil
+}
+
+func buildIncomeStatement(req Request, accounts []activity) (*IncomeStatement, error) {
+	is := &IncomeStatement{
+		CurrencyCode: req.CurrencyCode,
+		From:         formatTime(req.From),
+		To:           formatTime(req.To),
+	}
+
+	var err error
+	if is.Revenue, err = section(req.CurrencyCode, "revenue", accounts, activity.movement); err != nil {
+		return nil, err
+	}
+	if is.Expenses, err = section(req.CurrencyCode, "expense", accounts, activity.movement); err != nil {
+		return nil, err
+	}
+	if is.NetIncome, err = is.Revenue.Total.Sub(is.Expenses.Total); err != nil {
+		return nil, fmt.Errorf("failed to compute net income: %w", err)
+	}
+
+	// Net assets can only move through revenue and expenses once equity
+	// contributions are taken out
+	netAssets := newTotal(req.CurrencyCode)
+	for _, a := range accounts {
+		m, err := a.movement()
+		if err != nil {
+			return nil, fmt.Errorf("failed to total account %s: %w", a.AccountNumber, err)
+		}
+		switch a.AccountType {
+		case "asset":
+			netAssets.add(m)
+		case "liability", "equity":
+			netAssets.sub(m)
+		}
+	}
+	if netAssets.err != nil {
+		return nil, fmt.Errorf("failed to total net assets: %w", netAssets.err)
+	}
+
+	if is.Check, err = check(is.NetIncome, netAssets.sum); err != nil {
+		return nil, err
+	}
+	return is, nil
+}
@@ -0,0 +1,162 @@
+package reporting
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/pci-infra/internal/money"
+)
+
+func usd(s string) money.Money {
+	return money.MustParse(s, "USD")
+}
+
+// testActivity is a month in which opening capital of 700 and payables of 300
+// fund 1000 of cash, then 400 of fees and 200 of costs are booked
+func testActivity() []activity {
+	return []activity{
+		{"cash", "1000", "asset", "Cash", usd("1000.00"), usd("0.00"), usd("500.00"), usd("200.00")},
+		{"idle", "1100", "asset", "Idle", usd("0.00"), usd("0.00"), usd("0.00"), usd("0.00")},
+		{"payables", "2000", "liability", "Payables", usd("0.00"), usd("300.00"), usd("0.00"), usd("100.00")},
+		{"capital", "3000", "equity", "Capital", usd("0.00"), usd("700.00"), usd("0.00"), usd("0.00")},
+		{"fees", "4000", "revenue", "Fees", usd("0.00"), usd("0.00"), usd("0.00"), usd("400.00")},
+		{"costs", "5000", "expense", "Costs", usd("0.00"), usd("0.00"), usd("200.00"), usd("0.00")},
+	}
+}
+
+func testRequest() Request {
+	return Request{
+		CurrencyCode: "USD",
+		From:         time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:           time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestBuildTrialBalance(t *testing.T) {
+	tb, err := buildTrialBalance(testRequest(), testActivity())
+	require.NoError(t, err)
+
+	// accounts without activity or a balance are left out
+	require.Len(t, tb.Lines, 5)
+	assert.Equal(t, "1000", tb.Lines[0].AccountNumber)
+	assert.Equal(t, "1300.00", tb.Lines[0].DebitBalance.Amount())
+	assert.True(t, tb.Lines[0].CreditBalance.IsZero())
+	assert.Equal(t, "400.00", tb.Lines[1].CreditBalance.Amount())
+
+	assert.Equal(t, "700.00", tb.TotalPeriodDebits.Amount())
+	assert.Equal(t, "700.00", tb.TotalPeriodCredits.Amount())
+	assert.Equal(t, "1500.00", tb.TotalDebitBalance.Amount())
+	assert.Equal(t, "1500.00", tb.TotalCreditBalance.Amount())
+	assert.True(t, tb.Check.Balanced)
+	assert.True(t, tb.Check.Difference.IsZero())
+	assert.Equal(t, "2024-01-01T00:00:00Z", tb.From)
+
+	// an entry without its other leg shows up in the check
+	accounts := testActivity()
+	accounts[0].PeriodDebits = usd("510.00")
+	tb, err = buildTrialBalance(testRequest(), accounts)
+	require.NoError(t, err)
+	assert.False(t, tb.Check.Balanced)
+	assert.Equal(t, "10.00", tb.Check.Difference.Amount())
+}
+
+func TestBuildBalanceSheet(t *testing.T) {
+	bs, err := buildBalanceSheet(testRequest(), testActivity())
+	require.NoError(t, err)
+
+	require.Len(t, bs.Assets.Lines, 1)
+	assert.Equal(t, "1300.00", bs.Assets.Total.Amount())
+	assert.Equal(t, "400.00", bs.Liabilities.Total.Amount())
+	assert.Equal(t, "700.00", bs.Equity.Total.Amount())
+	assert.Equal(t, "200.00", bs.RetainedEarnings.Amount())
+	assert.Equal(t, "1300.00", bs.TotalLiabilitiesAndEquity.Amount())
+	assert.True(t, bs.Check.Balanced)
+	assert.Equal(t, "2024-02-01T00:00:00Z", bs.At)
+}
+
+func TestBuildIncomeStatement(t *testing.T) {
+	is, err := buildIncomeStatement(testRequest(), testActivity())
+	require.NoError(t, err)
+
+	require.Len(t, is.Revenue.Lines, 1)
+	assert.Equal(t, "Fees", is.Revenue.Lines[0].Name)
+	assert.Equal(t, "400.00", is.Revenue.Total.Amount())
+	assert.Equal(t, "200.00", is.Expenses.Total.Amount())
+	assert.Equal(t, "200.00", is.NetIncome.Amount())
+	assert.True(t, is.Check.Balanced)
+
+	// a loss is negative net income
+	accounts := testActivity()
+	accounts[5].PeriodDebits = usd("600.00")
+	accounts[0].PeriodCredits = usd("600.00")
+	is, err = buildIncomeStatement(testRequest(), accounts)
+	require.NoError(t, err)
+	assert.Equal(t, "-200.00", is.NetIncome.Amount())
+	assert.True(t, is.Check.Balanced)
+}
+
+func TestValidatePeriod(t *testing.T) {
+	req := testRequest()
+	assert.NoError(t, validatePeriod(req))
+
+	bad := req
+	bad.CurrencyCode = "XXX"
+	assert.Error(t, validatePeriod(bad))
+
+	bad = req
+	bad.From = time.Time{}
+	assert.Error(t, validatePeriod(bad))
+
+	bad = req
+	bad.From, bad.To = req.To, req.From
+	assert.Error(t, validatePeriod(bad))
+}
+
+func TestWriteCSV(t *testing.T) {
+	tb, err := buildTrialBalance(testRequest(), testActivity())
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	require.NoError(t, tb.WriteCSV(&buf))
+	assert.Equal(t, `account_number,name,account_type,period_debits,period_credits,debit_balance,credit_balance
+1000,Cash,asset,500.00,200.00,1300.00,0.00
+2000,Payables,liability,0.00,100.00,0.00,400.00
+3000,Capital,equity,0.00,0.00,0.00,700.00
+4000,Fees,revenue,0.00,400.00,0.00,400.00
+5000,Costs,expense,200.00,0.00,200.00,0.00
+,Total,,700.00,700.00,1500.00,1500.00
+check,balanced,true,0.00
+`, buf.String())
+
+	bs, err := buildBalanceSheet(testRequest(), testActivity())
+	require.NoError(t, err)
+	buf.Reset()
+	require.NoError(t, bs.WriteCSV(&buf))
+	assert.Equal(t, `section,account_number,name,amount
+asset,1000,Cash,1300.00
+asset,,Total assets,1300.00
+liability,2000,Payables,400.00
+liability,,Total liabilities,400.00
+equity,3000,Capital,700.00
+equity,,Total equity,700.00
+equity,,Retained earnings,200.00
+,,Total liabilities and equity,1300.00
+check,balanced,true,0.00
+`, buf.String())
+
+	is, err := buildIncomeStatement(testRequest(), testActivity())
+	require.NoError(t, err)
+	buf.Reset()
+	require.NoError(t, is.WriteCSV(&buf))
+	assert.Equal(t, `section,account_number,name,amount
+revenue,4000,Fees,400.00
+revenue,,Total revenue,400.00
+expense,5000,Costs,200.00
+expense,,Total expenses,200.00
+,,Net income,200.00
+check,balanced,true,0.00
+`, buf.String())
+}
@@ -0,0 +1,461 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/example/pci-infra/internal/crypto"
+	"github.com/example/pci-infra/internal/outbox"
+)
+
+// Default delivery settings
+const (
+	DefaultMaxAttempts  = 8
+	DefaultBaseBackoff  = 30 * time.Second
+	DefaultMaxBackoff   = 6 * time.Hour
+	DefaultBatchSize    = 50
+	DefaultPollInterval = time.Second
+)
+
+// deliveryTimeout bounds a single attempt when no Client is set
+const deliveryTimeout = 30 * time.Second
+
+// claimLease is how long a claimed delivery is hidden from other deliverers.
+// It outlasts an attempt, so a delivery is only sent twice at once if its
+// deliverer died mid-attempt.
+const claimLease = 2 * deliveryTimeout
+
+// defaultDeliveryClient sends deliveries for Deliverers without a Client
+var defaultDeliveryClient = newDeliveryClient()
+
+// maxErrorBody is how much of a failed response is kept in the log
+const maxErrorBody = 512
+
+// Dispatcher is the outbox sink of the webhook subsystem: it queues a
+// delivery of each event to every active subscription whose owner owns an
+// account the event touches and whose filter matches
+type Dispatcher struct {
+	Pool *pgxpool.Pool
+}
+
+type dispatchTarget struct {
+	id     string
+	owner  string
+	filter Filter
+}
+
+// receives reports whether the target gets an event touching accounts, given
+// each account's owner
+func (t dispatchTarget) receives(e outbox.Event, accounts []string, owners map[string]string) bool {
+	return ownsAny(t.owner, accounts, owners) && t.filter.Matches(e)
+}
+
+// Publish queues the events. An event already queued for a subscription is
+// not queued again, so redelivery by the outbox relay is harmless.
+func (d *Dispatcher) Publish(ctx context.Context, events []outbox.Event) error {
+	rows, err := d.Pool.Query(ctx, `
+		SELECT id, owner, event_types, account_ids, dispute_states
+		FROM webhook_subscriptions
+		WHERE active
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to list subscriptions: %w", err)
+	}
+	var targets []dispatchTarget
+	for rows.Next() {
+		var t dispatchTarget
+		if err := rows.Scan(&t.id, &t.owner, &t.filter.EventTypes, &t.filter.AccountIDs, &t.filter.DisputeStates); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan subscription: %w", err)
+		}
+		targets = append(targets, t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to list subscriptions: %w", err)
+	}
+	if len(targets) == 0 {
+		return nil
+	}
+
+	accounts := make([][]string, len(events))
+	for i, e := range events {
+		if accounts[i], err = d.eventAccounts(ctx, e); err != nil {
+			return err
+		}
+	}
+	owners, err := d.accountOwners(ctx, accounts)
+	if err != nil {
+		return err
+	}
+
+	tx, err := d.Pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	for i, e := range events {
+		var body []byte
+		for _, t := range targets {
+			if !t.receives(e, accounts[i], owners) {
+				continue
+			}
+			if body == nil {
+				if body, err = payloadBody(e); err != nil {
+					return err
+				}
+			}
+			_, err := tx.Exec(ctx, `
+				INSERT INTO webhook_deliveries (subscription_id, event_id, event_sequence, event_type, payload, next_attempt_at)
+				VALUES ($1, $2, $3, $4, $5, CURRENT_TIMESTAMP)
+				ON CONFLICT (subscription_id, event_id) DO NOTHING
+			`, t.id, e.ID, e.Sequence, e.EventType, body)
+			if err != nil {
+				return fmt.Errorf("failed to queue delivery of %s: %w", e.ID, err)
+			}
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// eventAccounts lists the accounts an event touches. A dispute event touches
+// the account of the disputed journal entry.
+func (d *Dispatcher) eventAccounts(ctx context.Context, e outbox.Event) ([]string, error) {
+	if e.EventType != outbox.EventDisputeTransitioned {
+		return eventAccounts(e), nil
+	}
+	var accountID string
+	err := d.Pool.QueryRow(ctx, `
+		SELECT je.account_id::text
+		FROM disputes d
+		JOIN journal_entries je ON je.id = d.journal_entry_id
+		WHERE d.dispute_id = $1
+	`, e.AggregateID).Scan(&accountID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get account of dispute %s: %w", e.AggregateID, err)
+	}
+	return []string{accountID}, nil
+}
+
+// accountOwners maps each of accounts that has an owner to its owner
+func (d *Dispatcher) accountOwners(ctx context.Context, accounts [][]string) (map[string]string, error) {
+	var ids []string
+	for _, list := range accounts {
+		ids = append(ids, list...)
+	}
+	owners := make(map[string]string)
+	if len(ids) == 0 {
+		return owners, nil
+	}
+
+	rows, err := d.Pool.Query(ctx, `
+		SELECT id::text, owner
+		FROM accounts
+		WHERE id::text = ANY($1) AND owner IS NOT NULL
+	`, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get account owners: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, owner string
+		if err := rows.Scan(&id, &owner); err != nil {
+			return nil, fmt.Errorf("failed to scan account owner: %w", err)
+		}
+		owners[id] = owner
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get account owners: %w", err)
+	}
+	return owners, nil
+}
+
+// payloadBody is the request body for an event
+func payloadBody(e outbox.Event) ([]byte, error) {
+	body, err := json.Marshal(Payload{
+		ID:        e.ID,
+		Type:      e.EventType,
+		Sequence:  e.Sequence,
+		CreatedAt: e.CreatedAt.UTC().Format(time.RFC3339Nano),
+		Data:      e.Payload,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode event %s: %w", e.ID, err)
+	}
+	return body, nil
+}
+
+// Deliverer sends queued deliveries. A failed attempt is retried after
+// BaseBackoff, doubling up to MaxBackoff; after MaxAttempts failures the
+// delivery is dead-lettered. Several deliverers may run at once. Encryptor
+// decrypts the subscriptions' signing secrets. When Client is nil deliveries
+// are sent with a client that refuses loopback, private and link-local
+// addresses; a Client set here must apply the same restriction.
+type Deliverer struct {
+	Pool         *pgxpool.Pool
+	Encryptor    *crypto.AEADEncryptor
+	Client       *http.Client
+	MaxAttempts  int
+	BaseBackoff  time.Duration
+	MaxBackoff   time.Duration
+	BatchSize    int
+	PollInterval time.Duration
+}
+
+// claimedDelivery is a delivery due to be sent, with its endpoint. secret is
+// filled in from sealedSecret just before sending.
+type claimedDelivery struct {
+	id             string
+	subscriptionID string
+	eventID        string
+	eventType      string
+	attempts       int
+	payload        []byte
+	url            string
+	sealedSecret   crypto.EncryptedData
+	secret         string
+}
+
+// Run delivers due deliveries until ctx is done
+func (d *Deliverer) Run(ctx context.Context) error {
+	poll := d.PollInterval
+	if poll <= 0 {
+		poll = DefaultPollInterval
+	}
+
+	ticker := time.NewTicker(poll)
+	defer ticker.Stop()
+	for {
+		n, err := d.DeliverDue(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("failed to deliver webhooks: %v", err)
+		}
+		if err == nil && n == d.batchSize() {
+			// More may be due
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// DeliverDue makes one attempt at each of up to BatchSize due deliveries, in
+// parallel, and returns how many it attempted
+func (d *Deliverer) DeliverDue(ctx context.Context) (int, error) {
+	claimed, err := d.claim(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var wg sync.WaitGroup
+	for _, c := range claimed {
+		wg.Add(1)
+		go func(c claimedDelivery) {
+			defer wg.Done()
+			start := time.Now()
+			var status int
+			var sendErr error
+			if c.secret, sendErr = d.openSecret(ctx, c); sendErr == nil {
+				status, sendErr = d.send(ctx, c, start)
+			}
+			if err := d.record(ctx, c, status, sendErr, time.Since(start)); err != nil {
+				log.Printf("failed to record webhook delivery %s: %v", c.id, err)
+			}
+		}(c)
+	}
+	wg.Wait()
+	return len(claimed), nil
+}
+
+// claim leases due deliveries of active subscriptions to this deliverer
+func (d *Deliverer) claim(ctx context.Context) ([]claimedDelivery, error) {
+	rows, err := d.Pool.Query(ctx, `
+		WITH due AS (
+			SELECT d.id
+			FROM webhook_deliveries d
+			JOIN webhook_subscriptions s ON s.id = d.subscription_id
+			WHERE d.status = 'pending'
+			AND d.next_attempt_at <= CURRENT_TIMESTAMP
+			AND s.active
+			ORDER BY d.next_attempt_at
+			LIMIT $1
+			FOR UPDATE OF d SKIP LOCKED
+		)
+		UPDATE webhook_deliveries d
+		SET next_attempt_at = CURRENT_TIMESTAMP + $2 * interval '1 millisecond'
+		FROM due, webhook_subscriptions s
+		WHERE d.id = due.id AND s.id = d.subscription_id
+		RETURNING d.id, d.subscription_id, d.event_id, d.event_type, d.attempts, d.payload, s.url,
+			s.secret_ciphertext, s.secret_encrypted_key, s.secret_nonce, s.secret_key_id
+	`, d.batchSize(), claimLease.Milliseconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var claimed []claimedDelivery
+	for rows.Next() {
+		var c claimedDelivery
+		if err := rows.Scan(&c.id, &c.subscriptionID, &c.eventID, &c.eventType, &c.attempts, &c.payload, &c.url,
+			&c.sealedSecret.Ciphertext, &c.sealedSecret.EncryptedDataKey, &c.sealedSecret.Nonce, &c.sealedSecret.KeyID); err != nil {
+			return nil, fmt.Errorf("failed to scan delivery: %w", err)
+		}
+		claimed = append(claimed, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to claim deliveries: %w", err)
+	}
+	return claimed, nil
+}
+
+// openSecret decrypts the signing secret of a claimed delivery's subscription
+func (d *Deliverer) openSecret(ctx context.Context, c claimedDelivery) (string, error) {
+	if d.Encryptor == nil {
+		return "", errors.New("missing encryptor")
+	}
+	sealed := c.sealedSecret
+	sealed.AdditionalData = secretAAD(c.subscriptionID)
+	secret, err := d.Encryptor.Decrypt(ctx, &sealed)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt secret of subscription %s: %w", c.subscriptionID, err)
+	}
+	return string(secret), nil
+}
+
+// send POSTs a delivery and returns the response status. Any status other
+// than 2xx is an error, as is an endpoint host that is a forbidden address.
+func (d *Deliverer) send(ctx context.Context, c claimedDelivery, at time.Time) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(c.payload))
+	if err != nil {
+		return 0, fmt.Errorf("failed to build request: %w", err)
+	}
+	if err := checkHost(req.URL.Hostname()); err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventIDHeader, c.eventID)
+	req.Header.Set(EventTypeHeader, c.eventType)
+	req.Header.Set(SignatureHeader, Sign(c.secret, at, c.payload))
+
+	client := d.Client
+	if client == nil {
+		client = defaultDeliveryClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		if len(snippet) > 0 {
+			return resp.StatusCode, fmt.Errorf("endpoint returned %s: %s", resp.Status, snippet)
+		}
+		return resp.StatusCode, fmt.Errorf("endpoint returned %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// record logs an attempt and schedules the delivery's next step
+func (d *Deliverer) record(ctx context.Context, c claimedDelivery, status int, sendErr error, took time.Duration) error {
+	attempt := c.attempts + 1
+	errText := ""
+	if sendErr != nil {
+		errText = sendErr.Error()
+	}
+
+	tx, err := d.Pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO webhook_delivery_attempts (delivery_id, attempt, status_code, error, duration_ms)
+		VALUES ($1, $2, NULLIF($3, 0), $4, $5)
+	`, c.id, attempt, status, errText, took.Milliseconds())
+	if err != nil {
+		return fmt.Errorf("failed to log attempt: %w", err)
+	}
+
+	next, delay := nextStep(attempt, sendErr, d.maxAttempts(), d.BaseBackoff, d.MaxBackoff)
+	_, err = tx.Exec(ctx, `
+		UPDATE webhook_deliveries
+		SET status = $2,
+			attempts = $3,
+			last_status_code = NULLIF($4, 0),
+			last_error = NULLIF($5, ''),
+			next_attempt_at = CASE WHEN $2 = 'pending' THEN CURRENT_TIMESTAMP + $6 * interval '1 millisecond' END,
+			delivered_at = CASE WHEN $2 = 'delivered' THEN CURRENT_TIMESTAMP END,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+	`, c.id, next, attempt, status, errText, delay.Milliseconds())
+	if err != nil {
+		return fmt.Errorf("failed to update delivery: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+// nextStep is the status a delivery moves to after its attempt-th attempt,
+// and when pending, how long until the next attempt
+func nextStep(attempt int, sendErr error, maxAttempts int, base, max time.Duration) (string, time.Duration) {
+	if sendErr == nil {
+		return DeliveryDelivered, 0
+	}
+	if attempt >= maxAttempts {
+		return DeliveryDeadLetter, 0
+	}
+	if base <= 0 {
+		base = DefaultBaseBackoff
+	}
+	if max <= 0 {
+		max = DefaultMaxBackoff
+	}
+	delay := base
+	for i := 1; i < attempt && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+	return DeliveryPending, delay
+}
+
+func (d *Deliverer) batchSize() int {
+	if d.BatchSize > 0 {
+		return d.BatchSize
+	}
+	return DefaultBatchSize
+}
+
+func (d *Deliverer) maxAttempts() int {
+	if d.MaxAttempts > 0 {
+		return d.MaxAttempts
+	}
+	return DefaultMaxAttempts
+}
@@ -0,0 +1,396 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/example/pci-infra/internal/crypto"
+)
+
+// Default and largest number of deliveries ListDeliveries returns
+const (
+	defaultDeliveryLimit = 50
+	maxDeliveryLimit     = 500
+)
+
+const subscriptionColumns = `id, owner, url, description, event_types, account_ids, dispute_states,
+	active, created_at::text, updated_at::text`
+
+const deliveryColumns = `id, subscription_id, event_id, event_sequence, event_type, status, attempts,
+	COALESCE(next_attempt_at::text, ''), COALESCE(last_status_code, 0), COALESCE(last_error, ''),
+	COALESCE(delivered_at::text, ''), created_at::text`
+
+// Service manages subscriptions and the delivery log. Every call is scoped to
+// an owner: other clients' subscriptions and deliveries are not found.
+// Signing secrets are envelope-encrypted under the KMS master key KeyID.
+type Service struct {
+	Pool      *pgxpool.Pool
+	Encryptor *crypto.AEADEncryptor
+	KeyID     string
+}
+
+// NewService creates a webhook service over pool that encrypts signing
+// secrets with encryptor under master key keyID
+func NewService(pool *pgxpool.Pool, encryptor *crypto.AEADEncryptor, keyID string) *Service {
+	return &Service{Pool: pool, Encryptor: encryptor, KeyID: keyID}
+}
+
+// sealSecret encrypts a subscription's signing secret
+func (s *Service) sealSecret(ctx context.Context, subscriptionID, secret string) (*crypto.EncryptedData, error) {
+	if s.Encryptor == nil {
+		return nil, errors.New("missing encryptor")
+	}
+	enc, err := s.Encryptor.Encrypt(ctx, []byte(secret), s.KeyID, secretAAD(subscriptionID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt secret: %w", err)
+	}
+	return enc, nil
+}
+
+// CreateSubscription subscribes an endpoint. The returned subscription holds
+// its signing secret.
+func (s *Service) CreateSubscription(ctx context.Context, req CreateRequest) (*Subscription, error) {
+	if req.Owner == "" {
+		return nil, fmt.Errorf("%w: owner is required", ErrInvalidSubscription)
+	}
+	if err := validate(req.URL, req.Filter); err != nil {
+		return nil, err
+	}
+	if err := s.checkAccounts(ctx, req.Owner, req.AccountIDs); err != nil {
+		return nil, err
+	}
+	secret, err := newSecret()
+	if err != nil {
+		return nil, err
+	}
+	id := uuid.New().String()
+	enc, err := s.sealSecret(ctx, id, secret)
+	if err != nil {
+		return nil, err
+	}
+
+	sub, err := scanSubscription(s.Pool.QueryRow(ctx, `
+		INSERT INTO webhook_subscriptions (
+			id, owner, url, description, event_types, account_ids, dispute_states,
+			secret_ciphertext, secret_encrypted_key, secret_nonce, secret_key_id
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING `+subscriptionColumns,
+		id, req.Owner, req.URL, req.Description, orEmpty(req.EventTypes), orEmpty(req.AccountIDs), orEmpty(req.DisputeStates),
+		enc.Ciphertext, enc.EncryptedDataKey, enc.Nonce, enc.KeyID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create subscription: %w", err)
+	}
+	sub.Secret = secret
+	return sub, nil
+}
+
+// checkAccounts rejects a filter naming an account the owner does not own.
+// Unknown accounts and other clients' accounts are reported alike.
+func (s *Service) checkAccounts(ctx context.Context, owner string, accountIDs []string) error {
+	if len(accountIDs) == 0 {
+		return nil
+	}
+	rows, err := s.Pool.Query(ctx, `
+		SELECT id::text
+		FROM accounts
+		WHERE id::text = ANY($1) AND owner = $2
+	`, accountIDs, owner)
+	if err != nil {
+		return fmt.Errorf("failed to check subscription accounts: %w", err)
+	}
+	defer rows.Close()
+	var owned []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return fmt.Errorf("failed to scan subscription account: %w", err)
+		}
+		owned = append(owned, id)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to check subscription accounts: %w", err)
+	}
+	return checkOwnedAccounts(accountIDs, owned)
+}
+
+// ListSubscriptions lists the owner's subscriptions, oldest first
+func (s *Service) ListSubscriptions(ctx context.Context, owner string) ([]Subscription, error) {
+	rows, err := s.Pool.Query(ctx, `
+		SELECT `+subscriptionColumns+`
+		FROM webhook_subscriptions
+		WHERE owner = $1
+		ORDER BY created_at, id
+	`, owner)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	subs := []Subscription{}
+	for rows.Next() {
+		sub, err := scanSubscription(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan subscription: %w", err)
+		}
+		subs = append(subs, *sub)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list subscriptions: %w", err)
+	}
+	return subs, nil
+}
+
+// GetSubscription gets one of the owner's subscriptions
+func (s *Service) GetSubscription(ctx context.Context, owner, id string) (*Subscription, error) {
+	if !isUUID(id) {
+		return nil, fmt.Errorf("%w: %s", ErrSubscriptionNotFound, id)
+	}
+	sub, err := scanSubscription(s.Pool.QueryRow(ctx, `
+		SELECT `+subscriptionColumns+`
+		FROM webhook_subscriptions
+		WHERE id = $1 AND owner = $2
+	`, id, owner))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrSubscriptionNotFound, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get subscription: %w", err)
+	}
+	return sub, nil
+}
+
+// UpdateSubscription replaces a subscription's endpoint, description, filter
+// and active flag. Deliveries already queued keep their payloads; an inactive
+// subscription's are held until it is reactivated.
+func (s *Service) UpdateSubscription(ctx context.Context, req UpdateRequest) (*Subscription, error) {
+	if err := validate(req.URL, req.Filter); err != nil {
+		return nil, err
+	}
+	if !isUUID(req.ID) {
+		return nil, fmt.Errorf("%w: %s", ErrSubscriptionNotFound, req.ID)
+	}
+	if err := s.checkAccounts(ctx, req.Owner, req.AccountIDs); err != nil {
+		return nil, err
+	}
+
+	sub, err := scanSubscription(s.Pool.QueryRow(ctx, `
+		UPDATE webhook_subscriptions
+		SET url = $3, description = $4, event_types = $5, account_ids = $6, dispute_states = $7,
+			active = $8, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND owner = $2
+		RETURNING `+subscriptionColumns,
+		req.ID, req.Owner, req.URL, req.Description, orEmpty(req.EventTypes), orEmpty(req.AccountIDs), orEmpty(req.DisputeStates), req.Active))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrSubscriptionNotFound, req.ID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update subscription: %w", err)
+	}
+	return sub, nil
+}
+
+// DeleteSubscription removes a subscription along with its delivery log
+func (s *Service) DeleteSubscription(ctx context.Context, owner, id string) error {
+	if !isUUID(id) {
+		return fmt.Errorf("%w: %s", ErrSubscriptionNotFound, id)
+	}
+	tag, err := s.Pool.Exec(ctx, `DELETE FROM webhook_subscriptions WHERE id = $1 AND owner = $2`, id, owner)
+	if err != nil {
+		return fmt.Errorf("failed to delete subscription: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: %s", ErrSubscriptionNotFound, id)
+	}
+	return nil
+}
+
+// RotateSecret replaces a subscription's signing secret. Deliveries are signed
+// when sent, so retries of earlier events use the new secret too.
+func (s *Service) RotateSecret(ctx context.Context, owner, id string) (*Subscription, error) {
+	if !isUUID(id) {
+		return nil, fmt.Errorf("%w: %s", ErrSubscriptionNotFound, id)
+	}
+	secret, err := newSecret()
+	if err != nil {
+		return nil, err
+	}
+	enc, err := s.sealSecret(ctx, id, secret)
+	if err != nil {
+		return nil, err
+	}
+
+	sub, err := scanSubscription(s.Pool.QueryRow(ctx, `
+		UPDATE webhook_subscriptions
+		SET secret_ciphertext = $3, secret_encrypted_key = $4, secret_nonce = $5, secret_key_id = $6,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND owner = $2
+		RETURNING `+subscriptionColumns, id, owner, enc.Ciphertext, enc.EncryptedDataKey, enc.Nonce, enc.KeyID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrSubscriptionNotFound, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to rotate secret: %w", err)
+	}
+	sub.Secret = secret
+	return sub, nil
+}
+
+// ListDeliveries lists a subscription's deliveries, newest first. Filtering
+// on DeliveryDeadLetter lists its dead-letter queue.
+func (s *Service) ListDeliveries(ctx context.Context, filter DeliveryFilter) ([]Delivery, error) {
+	switch filter.Status {
+	case "", DeliveryPending, DeliveryDelivered, DeliveryDeadLetter:
+	default:
+		return nil, fmt.Errorf("%w: unknown delivery status %q", ErrInvalidSubscription, filter.Status)
+	}
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultDeliveryLimit
+	}
+	if limit > maxDeliveryLimit {
+		limit = maxDeliveryLimit
+	}
+	offset := filter.Offset
+	if offset < 0 {
+		offset = 0
+	}
+
+	if _, err := s.GetSubscription(ctx, filter.Owner, filter.SubscriptionID); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.Pool.Query(ctx, `
+		SELECT `+deliveryColumns+`
+		FROM webhook_deliveries
+		WHERE subscription_id = $1
+		AND ($2 = '' OR status = $2)
+		ORDER BY created_at DESC, id
+		LIMIT $3 OFFSET $4
+	`, filter.SubscriptionID, filter.Status, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []Delivery{}
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan delivery: %w", err)
+		}
+		deliveries = append(deliveries, *d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+// GetDelivery gets a delivery with its payload and attempt log
+func (s *Service) GetDelivery(ctx context.Context, owner, id string) (*Delivery, error) {
+	if !isUUID(id) {
+		return nil, fmt.Errorf("%w: %s", ErrDeliveryNotFound, id)
+	}
+
+	var payload []byte
+	d, err := scanDelivery(s.Pool.QueryRow(ctx, `
+		SELECT `+deliveryColumns+`, payload
+		FROM webhook_deliveries
+		WHERE id = $1
+		AND subscription_id IN (SELECT id FROM webhook_subscriptions WHERE owner = $2)
+	`, id, owner), &payload)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrDeliveryNotFound, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get delivery: %w", err)
+	}
+	d.Payload = payload
+
+	rows, err := s.Pool.Query(ctx, `
+		SELECT attempt, COALESCE(status_code, 0), error, duration_ms, attempted_at::text
+		FROM webhook_delivery_attempts
+		WHERE delivery_id = $1
+		ORDER BY attempted_at, id
+	`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list delivery attempts: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var a Attempt
+		if err := rows.Scan(&a.Attempt, &a.StatusCode, &a.Error, &a.DurationMS, &a.AttemptedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan delivery attempt: %w", err)
+		}
+		d.Log = append(d.Log, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list delivery attempts: %w", err)
+	}
+	return d, nil
+}
+
+// ReplayDelivery queues a delivered or dead-lettered delivery to be sent again
+// now, with a fresh set of attempts. The payload, and so the event ID, is
+// unchanged.
+func (s *Service) ReplayDelivery(ctx context.Context, owner, id string) (*Delivery, error) {
+	current, err := s.GetDelivery(ctx, owner, id)
+	if err != nil {
+		return nil, err
+	}
+	if current.Status == DeliveryPending {
+		return nil, fmt.Errorf("%w: %s", ErrDeliveryPending, id)
+	}
+
+	d, err := scanDelivery(s.Pool.QueryRow(ctx, `
+		UPDATE webhook_deliveries
+		SET status = 'pending', attempts = 0, next_attempt_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status <> 'pending'
+		RETURNING `+deliveryColumns, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrDeliveryPending, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to replay delivery: %w", err)
+	}
+	return d, nil
+}
+
+func scanSubscription(row pgx.Row) (*Subscription, error) {
+	var sub Subscription
+	err := row.Scan(&sub.ID, &sub.Owner, &sub.URL, &sub.Description,
+		&sub.EventTypes, &sub.AccountIDs, &sub.DisputeStates,
+		&sub.Active, &sub.CreatedAt, &sub.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+func scanDelivery(row pgx.Row, extra ...any) (*Delivery, error) {
+	var d Delivery
+	dest := append([]any{&d.ID, &d.SubscriptionID, &d.EventID, &d.EventSequence, &d.EventType, &d.Status, &d.Attempts,
+		&d.NextAttemptAt, &d.LastStatusCode, &d.LastError, &d.DeliveredAt, &d.CreatedAt}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func isUUID(s string) bool {
+	_, err := uuid.Parse(s)
+	return err == nil
+}
+
+// orEmpty stores an absent filter list as an empty array, not NULL
+func orEmpty(list []string) []string {
+	if list == nil {
+		return []string{}
+	}
+	return list
+}
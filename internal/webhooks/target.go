@@ -0,0 +1,79 @@
+package webhooks
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrForbiddenTarget is returned when an endpoint is, or resolves to, an
+// address webhooks may not be sent to
+var ErrForbiddenTarget = fmt.Errorf("%w: endpoint address not allowed", ErrInvalidSubscription)
+
+// sharedAddressSpace is the carrier-grade NAT range (RFC 6598), which is not
+// publicly routable but is not covered by netip.Addr.IsPrivate
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// forbiddenAddr reports whether addr is loopback, private, link-local or
+// otherwise not a public unicast address. Subscribers may not point webhooks
+// at the platform's own network.
+func forbiddenAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return !addr.IsValid() ||
+		addr.IsLoopback() ||
+		addr.IsPrivate() ||
+		addr.IsLinkLocalUnicast() ||
+		addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() ||
+		addr.IsMulticast() ||
+		addr.IsUnspecified() ||
+		sharedAddressSpace.Contains(addr)
+}
+
+// checkHost rejects an endpoint host that is a forbidden address literal or a
+// name reserved for the local machine. Other names are checked when a
+// delivery connects, against the addresses they resolve to then.
+func checkHost(host string) error {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("%w: %s", ErrForbiddenTarget, host)
+	}
+	if addr, err := netip.ParseAddr(host); err == nil && forbiddenAddr(addr) {
+		return fmt.Errorf("%w: %s", ErrForbiddenTarget, host)
+	}
+	return nil
+}
+
+// denyForbiddenAddrs is a net.Dialer Control that refuses connections to
+// forbidden addresses. It runs after name resolution, so a name that
+// resolves, or is later rebound, to an internal address is refused too.
+func denyForbiddenAddrs(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrForbiddenTarget, address)
+	}
+	if forbiddenAddr(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s", ErrForbiddenTarget, addrPort.Addr())
+	}
+	return nil
+}
+
+// newDeliveryClient is the client deliveries are sent with when a Deliverer
+// has none. It never uses a proxy, and refuses to connect to forbidden
+// addresses, including on redirects.
+func newDeliveryClient() *http.Client {
+	dialer := &net.Dialer{Timeout: 10 * time.Second, Control: denyForbiddenAddrs}
+	return &http.Client{
+		Timeout: deliveryTimeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: 10 * time.Second,
+			MaxIdleConns:        100,
+			IdleConnTimeout:     90 * time.Second,
+		},
+	}
+}
@@ -0,0 +1,284 @@
+// Package webhooks pushes ledger and dispute events to subscriber endpoints.
+// A Dispatcher consumes the outbox and records a delivery for every
+// subscription whose owner owns an account the event touches and whose filter
+// matches; a Deliverer POSTs pending deliveries,
+// signed with the subscription's secret, retrying with exponential backoff
+// until they succeed or are moved to the dead-letter queue. Every attempt is
+// logged, and any delivery can be replayed.
+package webhooks
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/example/pci-infra/internal/ledger"
+	"github.com/example/pci-infra/internal/outbox"
+)
+
+// Delivery statuses. Pending deliveries are waiting for their next attempt;
+// dead_letter ones failed MaxAttempts times and are only retried on replay.
+const (
+	DeliveryPending    = "pending"
+	DeliveryDelivered  = "delivered"
+	DeliveryDeadLetter = "dead_letter"
+)
+
+// EventTypes are the events a subscription can receive. transaction.posted
+// reports every balance change: each entry carries the account it moved.
+var EventTypes = []string{
+	outbox.EventTransactionPosted,
+	outbox.EventAccountCreated,
+	outbox.EventAccountStatusChanged,
+	outbox.EventDisputeTransitioned,
+}
+
+// DisputeStates are the states a DisputeStates filter may name, as in
+// disputes.DisputeState
+var DisputeStates = []string{"PENDING", "AUTHORIZED", "SETTLED", "DISPUTED", "REVERSED"}
+
+// ErrInvalidSubscription is returned when a subscription is malformed
+var ErrInvalidSubscription = fmt.Errorf("%w: subscription", ledger.ErrInvalidRequest)
+
+// ErrSubscriptionNotFound is returned when a referenced subscription does not
+// exist or belongs to another client
+var ErrSubscriptionNotFound = errors.New("webhook subscription not found")
+
+// ErrDeliveryNotFound is returned when a referenced delivery does not exist or
+// belongs to another client's subscription
+var ErrDeliveryNotFound = errors.New("webhook delivery not found")
+
+// ErrDeliveryPending is returned when replaying a delivery that is still
+// waiting to be sent
+var ErrDeliveryPending = errors.New("webhook delivery still pending")
+
+// Filter selects the events a subscription receives. Each list that is set
+// must match: EventTypes the event type, AccountIDs an account a ledger event
+// touches, and DisputeStates the state a dispute moved to. AccountIDs does not
+// apply to dispute events, nor DisputeStates to ledger events. Only accounts
+// of the subscription's owner may be named, and with no AccountIDs the
+// subscription receives events for all of them.
+type Filter struct {
+	EventTypes    []string `json:"event_types"`
+	AccountIDs    []string `json:"account_ids"`
+	DisputeStates []string `json:"dispute_states"`
+}
+
+// Subscription is an endpoint receiving events. Secret signs its payloads; it
+// is only returned when the subscription is created or the secret rotated.
+type Subscription struct {
+	ID          string `json:"id"`
+	Owner       string `json:"owner"`
+	URL         string `json:"url"`
+	Description string `json:"description"`
+	Filter
+	Active    bool   `json:"active"`
+	Secret    string `json:"secret,omitempty"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+// CreateRequest represents the request to subscribe an endpoint. Owner is the
+// client the subscription belongs to.
+type CreateRequest struct {
+	Owner       string `json:"owner"`
+	URL         string `json:"url"`
+	Description string `json:"description"`
+	Filter
+}
+
+// UpdateRequest replaces the endpoint, description, filter and active flag of
+// a subscription
+type UpdateRequest struct {
+	ID          string `json:"id"`
+	Owner       string `json:"owner"`
+	URL         string `json:"url"`
+	Description string `json:"description"`
+	Filter
+	Active bool `json:"active"`
+}
+
+// Delivery is one event sent, or to be sent, to one subscription. Payload is
+// the signed request body.
+type Delivery struct {
+	ID             string          `json:"id"`
+	SubscriptionID string          `json:"subscription_id"`
+	EventID        string          `json:"event_id"`
+	EventSequence  int64           `json:"event_sequence"`
+	EventType      string          `json:"event_type"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  string          `json:"next_attempt_at,omitempty"`
+	LastStatusCode int             `json:"last_status_code,omitempty"`
+	LastError      string          `json:"last_error,omitempty"`
+	DeliveredAt    string          `json:"delivered_at,omitempty"`
+	CreatedAt      string          `json:"created_at"`
+	Payload        json.RawMessage `json:"payload,omitempty"`
+	Log            []Attempt       `json:"log,omitempty"`
+}
+
+// Attempt is a logged delivery attempt. StatusCode is 0 when no response was
+// received.
+type Attempt struct {
+	Attempt     int    `json:"attempt"`
+	StatusCode  int    `json:"status_code,omitempty"`
+	Error       string `json:"error,omitempty"`
+	DurationMS  int64  `json:"duration_ms"`
+	AttemptedAt string `json:"attempted_at"`
+}
+
+// DeliveryFilter narrows a delivery log query. Status is empty for all.
+type DeliveryFilter struct {
+	SubscriptionID string
+	Owner          string
+	Status         string
+	Limit          int
+	Offset         int
+}
+
+// Payload is the body POSTed for an event. ID is the event's and is the same
+// on every attempt and replay, so receivers can deduplicate on it.
+type Payload struct {
+	ID        string          `json:"id"`
+	Type      string          `json:"type"`
+	Sequence  int64           `json:"sequence"`
+	CreatedAt string          `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// Matches reports whether an event passes the filter
+func (f Filter) Matches(e outbox.Event) bool {
+	if len(f.EventTypes) > 0 && !contains(f.EventTypes, e.EventType) {
+		return false
+	}
+
+	switch e.EventType {
+	case outbox.EventTransactionPosted, outbox.EventAccountCreated, outbox.EventAccountStatusChanged:
+		if len(f.AccountIDs) == 0 {
+			return true
+		}
+		for _, id := range eventAccounts(e) {
+			if contains(f.AccountIDs, id) {
+				return true
+			}
+		}
+		return false
+	case outbox.EventDisputeTransitioned:
+		if len(f.DisputeStates) == 0 {
+			return true
+		}
+		var transition struct {
+			ToState string `json:"to_state"`
+		}
+		if err := json.Unmarshal(e.Payload, &transition); err != nil {
+			return false
+		}
+		return contains(f.DisputeStates, transition.ToState)
+	}
+	return true
+}
+
+// eventAccounts lists the accounts a ledger event touches. Dispute events
+// carry no account; the dispatcher looks up the disputed entry's.
+func eventAccounts(e outbox.Event) []string {
+	switch e.EventType {
+	case outbox.EventTransactionPosted:
+		var posted struct {
+			Entries []struct {
+				AccountID string `json:"account_id"`
+			} `json:"entries"`
+		}
+		if err := json.Unmarshal(e.Payload, &posted); err != nil {
+			return nil
+		}
+		accounts := make([]string, 0, len(posted.Entries))
+		for _, entry := range posted.Entries {
+			if !contains(accounts, entry.AccountID) {
+				accounts = append(accounts, entry.AccountID)
+			}
+		}
+		return accounts
+	case outbox.EventAccountCreated, outbox.EventAccountStatusChanged:
+		return []string{e.AggregateID}
+	}
+	return nil
+}
+
+// ownsAny reports whether owner owns any of accounts, given each account's
+// owner. Accounts without an owner belong to no subscriber.
+func ownsAny(owner string, accounts []string, owners map[string]string) bool {
+	if owner == "" {
+		return false
+	}
+	for _, id := range accounts {
+		if owners[id] == owner {
+			return true
+		}
+	}
+	return false
+}
+
+// checkOwnedAccounts rejects the first of accountIDs missing from owned
+func checkOwnedAccounts(accountIDs, owned []string) error {
+	for _, id := range accountIDs {
+		if !contains(owned, id) {
+			return fmt.Errorf("%w: account %q is not one of the owner's accounts", ErrInvalidSubscription, id)
+		}
+	}
+	return nil
+}
+
+// validate checks the endpoint and filter of a subscription
+func validate(endpoint string, f Filter) error {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("%w: url %q is not absolute", ErrInvalidSubscription, endpoint)
+	}
+	if u.Scheme != "https" {
+		return fmt.Errorf("%w: url must use https", ErrInvalidSubscription)
+	}
+	if u.User != nil {
+		return fmt.Errorf("%w: url must not carry credentials", ErrInvalidSubscription)
+	}
+	if err := checkHost(u.Hostname()); err != nil {
+		return err
+	}
+	for _, t := range f.EventTypes {
+		if !contains(EventTypes, t) {
+			return fmt.Errorf("%w: unknown event type %q", ErrInvalidSubscription, t)
+		}
+	}
+	for _, s := range f.DisputeStates {
+		if !contains(DisputeStates, s) {
+			return fmt.Errorf("%w: unknown dispute state %q", ErrInvalidSubscription, s)
+		}
+	}
+	return nil
+}
+
+// secretAAD binds a signing secret's ciphertext to its subscription, so a
+// secret copied onto another subscription does not decrypt
+func secretAAD(subscriptionID string) []byte {
+	return []byte("webhook_secret:" + subscriptionID)
+}
+
+// newSecret returns a random signing secret
+func newSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate secret: %w", err)
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}